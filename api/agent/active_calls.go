@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+	pool "github.com/fnproject/fn/api/runnerpool"
+)
+
+// ActiveCall is a call an agent is executing
+type ActiveCall struct {
+	ID        string    `json:"id"`
+	AppID     string    `json:"app_id"`
+	FnID      string    `json:"fn_id"`
+	Path      string    `json:"path"`
+	StartedAt time.Time `json:"started_at"`
+	// Runner is the address of the runner an lb placed the call on, if any
+	Runner string `json:"runner,omitempty"`
+}
+
+// CallCanceler is implemented by agents that can list the calls they are
+// executing and cancel them.
+type CallCanceler interface {
+	// ActiveCalls returns the calls the agent is executing, oldest first.
+	ActiveCalls() []ActiveCall
+
+	// CancelCall cancels the call callID, which then fails with
+	// models.ErrCallCanceled, killing its container rather than reusing it.
+	// Returns models.ErrCallNotActive if the call is not executing, e.g. if
+	// it completed. A call may still complete if it was about to as it is
+	// cancelled.
+	CancelCall(callID string) error
+}
+
+// activeCalls tracks the calls an agent is executing, to cancel them
+type activeCalls struct {
+	mu    sync.Mutex
+	calls map[string]*activeCall
+}
+
+type activeCall struct {
+	call    *call
+	started time.Time
+	cancel  context.CancelFunc
+}
+
+func newActiveCalls() *activeCalls {
+	return &activeCalls{calls: make(map[string]*activeCall)}
+}
+
+// add tracks c until the returned func is called, returning the context to
+// execute it with, which CancelCall cancels
+func (ac *activeCalls) add(ctx context.Context, c *call) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	ac.mu.Lock()
+	ac.calls[c.ID] = &activeCall{call: c, started: time.Now(), cancel: cancel}
+	ac.mu.Unlock()
+
+	return ctx, func() {
+		ac.mu.Lock()
+		delete(ac.calls, c.ID)
+		ac.mu.Unlock()
+		cancel()
+	}
+}
+
+// ActiveCalls implements CallCanceler
+func (ac *activeCalls) ActiveCalls() []ActiveCall {
+	ac.mu.Lock()
+	calls := make([]ActiveCall, 0, len(ac.calls))
+	for _, a := range ac.calls {
+		info := ActiveCall{
+			ID:        a.call.ID,
+			AppID:     a.call.AppID,
+			FnID:      a.call.FnID,
+			StartedAt: a.started,
+			Runner:    a.call.runner(),
+		}
+		if a.call.req != nil {
+			info.Path = a.call.req.URL.Path
+		}
+		calls = append(calls, info)
+	}
+	ac.mu.Unlock()
+
+	sort.Slice(calls, func(i, j int) bool {
+		if calls[i].StartedAt.Equal(calls[j].StartedAt) {
+			return calls[i].ID < calls[j].ID
+		}
+		return calls[i].StartedAt.Before(calls[j].StartedAt)
+	})
+	return calls
+}
+
+// CancelCall implements CallCanceler
+func (ac *activeCalls) CancelCall(callID string) error {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	a, ok := ac.calls[callID]
+	if !ok {
+		return models.ErrCallNotActive
+	}
+	atomic.StoreInt32(&a.call.canceled, 1)
+	a.cancel()
+	return nil
+}
+
+// isCanceled returns whether the call was cancelled with CancelCall
+func (c *call) isCanceled() bool {
+	return atomic.LoadInt32(&c.canceled) == 1
+}
+
+func (c *call) runner() string {
+	if addr, ok := c.runnerAddr.Load().(string); ok {
+		return addr
+	}
+	return ""
+}
+
+// setCallRunner records the address of the runner an lb is placing rc on
+func setCallRunner(rc pool.RunnerCall, addr string) {
+	if c, ok := rc.(*call); ok {
+		c.runnerAddr.Store(addr)
+	}
+}
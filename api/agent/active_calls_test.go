@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestActiveCalls(t *testing.T) {
+	calls := newActiveCalls()
+
+	first := &call{Call: &models.Call{ID: "1", AppID: "app", FnID: "fn"}, req: httptest.NewRequest("POST", "/invoke/fn", nil)}
+	second := &call{Call: &models.Call{ID: "2", AppID: "app", FnID: "fn"}, req: httptest.NewRequest("POST", "/t/app/hook", nil)}
+	firstCtx, untrackFirst := calls.add(context.Background(), first)
+	_, untrackSecond := calls.add(context.Background(), second)
+	setCallRunner(second, "runner-0:9190")
+
+	active := calls.ActiveCalls()
+	if len(active) != 2 || active[0].ID != "1" || active[1].ID != "2" {
+		t.Fatalf("expected calls 1 and 2 oldest first, got %+v", active)
+	}
+	if active[0].Path != "/invoke/fn" || active[0].Runner != "" || active[1].Runner != "runner-0:9190" {
+		t.Fatalf("expected the paths and runners of the calls, got %+v", active)
+	}
+
+	if err := calls.CancelCall("1"); err != nil {
+		t.Fatal(err)
+	}
+	if firstCtx.Err() != context.Canceled || !first.isCanceled() {
+		t.Fatal("expected call 1 to be cancelled")
+	}
+	if second.isCanceled() {
+		t.Fatal("expected call 2 not to be cancelled")
+	}
+
+	// completed calls can't be cancelled
+	untrackFirst()
+	untrackSecond()
+	if err := calls.CancelCall("2"); err != models.ErrCallNotActive {
+		t.Fatalf("expected a completed call not to be active, got %v", err)
+	}
+	if active := calls.ActiveCalls(); len(active) != 0 {
+		t.Fatalf("expected no active calls, got %+v", active)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -78,6 +79,12 @@ type Agent interface {
 	AddCallListener(fnext.CallListener)
 }
 
+// ImageValidator is implemented by agents that can check whether a function
+// image can be resolved before any fn is deployed with it.
+type ImageValidator interface {
+	ValidateImage(ctx context.Context, image string) (*drivers.ImageInfo, error)
+}
+
 type agent struct {
 	cfg           Config
 	callListeners []fnext.CallListener
@@ -99,8 +106,23 @@ type agent struct {
 	// additional options to configure each call
 	callOpts []CallOpt
 
+	// resolves the secrets config values reference, if interpolation is enabled
+	secrets SecretSource
+
 	// deferred actions to call at end of initialisation
 	onStartup []func()
+
+	// the calls being executed, to list and cancel them
+	calls *activeCalls
+
+	// the fns idle containers are kept warm for
+	warm warmPool
+
+	// bounds the containers starting at once
+	coldStarts *coldStartLimiter
+
+	// rejects calls while the CPU of the node is saturated
+	cpu *cpuAdmission
 }
 
 // Option configures an agent at startup
@@ -110,6 +132,11 @@ type Option func(*agent) error
 /* #nosec */
 const RegistryToken = "FN_REGISTRY_TOKEN"
 
+// RegistryAuth is a reserved call extensions key passing the registry
+// credentials of the app of a call, see models.AppRegistryAuthAnnotation, from
+// LB nodes to the runner of the call
+const RegistryAuth = "FN_APP_REGISTRY_AUTH"
+
 // New creates an Agent that executes functions locally as Docker containers.
 func New(options ...Option) Agent {
 
@@ -124,6 +151,7 @@ func New(options ...Option) Agent {
 
 	a.shutWg = common.NewWaitGroup()
 	a.slotMgr = NewSlotQueueMgr()
+	a.calls = newActiveCalls()
 	a.evictor = NewEvictor()
 
 	// Allow overriding config
@@ -136,6 +164,10 @@ func New(options ...Option) Agent {
 
 	logrus.Infof("agent starting cfg=%+v", a.cfg)
 
+	if a.cfg.EnableConfigInterpolation && a.secrets == nil {
+		a.secrets = newSecretSource(a.cfg.ConfigSecretsFile)
+	}
+
 	if a.driver == nil {
 		d, err := NewDockerDriver(&a.cfg)
 		if err != nil {
@@ -145,6 +177,9 @@ func New(options ...Option) Agent {
 	}
 
 	a.resources = NewResourceTracker(&a.cfg)
+	a.coldStarts = newColdStartLimiter(a.cfg.MaxConcurrentColdStarts)
+	a.cpu = newCPUAdmission(a.cfg.MaxCPUPercent)
+	go a.cpu.run(a.shutWg.Closer())
 
 	for _, sup := range a.onStartup {
 		sup()
@@ -234,6 +269,15 @@ func (a *agent) Close() error {
 	return err
 }
 
+// ValidateImage inspects image using the agent's driver, if the driver supports it.
+func (a *agent) ValidateImage(ctx context.Context, image string) (*drivers.ImageInfo, error) {
+	inspector, ok := a.driver.(drivers.ImageInspector)
+	if !ok {
+		return nil, models.ErrImageValidationUnsupported
+	}
+	return inspector.InspectImage(ctx, image)
+}
+
 func (a *agent) Submit(callI Call) error {
 	call := callI.(*call)
 
@@ -277,21 +321,37 @@ func (a *agent) endStateTrackers(ctx context.Context, call *call) {
 func (a *agent) submit(ctx context.Context, call *call) error {
 	statsCalls(ctx)
 
+	// LBs place the call on another runner
+	if !a.cpu.admit() {
+		statsTooBusy(ctx)
+		return models.ErrCallTimeoutServerBusy
+	}
+
 	if !a.shutWg.AddSession(1) {
 		statsTooBusy(ctx)
 		return models.ErrCallTimeoutServerBusy
 	}
 	defer a.shutWg.DoneSession()
 
+	ctx, untrack := a.calls.add(ctx, call)
+	defer untrack()
+
 	statsEnqueue(ctx)
 
 	a.startStateTrackers(ctx, call)
 	defer a.endStateTrackers(ctx, call)
 
+	queueStart := time.Now()
 	slot, err := a.getSlot(ctx, call)
+	call.timings.Queue = time.Since(queueStart)
 	if err != nil {
 		return a.handleCallEnd(ctx, call, slot, err, false)
 	}
+	if hs, ok := slot.(*hotSlot); ok {
+		call.timings.ColdStart = hs.coldStart
+	}
+
+	statsCallStart(ctx, call, slot)
 
 	err = call.Start(ctx)
 	if err != nil {
@@ -306,7 +366,9 @@ func (a *agent) submit(ctx context.Context, call *call) error {
 	defer cancel()
 
 	// Pass this error (nil or otherwise) to end directly, to store status, etc.
+	execStart := time.Now()
 	err = slot.exec(slotCtx, call)
+	call.timings.Exec = time.Since(execStart)
 	return a.handleCallEnd(ctx, call, slot, err, true)
 }
 
@@ -316,6 +378,11 @@ func (a *agent) handleCallEnd(ctx context.Context, call *call, slot Slot, err er
 		slot.Close()
 	}
 
+	// whatever the cancel interrupted, the call failed as it was cancelled
+	if err != nil && call.isCanceled() {
+		err = models.ErrCallCanceled
+	}
+
 	// This means call was routed (executed)
 	if isStarted {
 		call.End(ctx, err)
@@ -334,7 +401,7 @@ func (a *agent) handleCallEnd(ctx context.Context, call *call, slot Slot, err er
 		}
 	}
 
-	if err == context.Canceled {
+	if err == context.Canceled || err == models.ErrCallCanceled {
 		statsCanceled(ctx)
 	} else if err != nil {
 		statsErrors(ctx)
@@ -342,6 +409,18 @@ func (a *agent) handleCallEnd(ctx context.Context, call *call, slot Slot, err er
 	return err
 }
 
+// ActiveCalls implements CallCanceler
+func (a *agent) ActiveCalls() []ActiveCall {
+	return a.calls.ActiveCalls()
+}
+
+// CancelCall implements CallCanceler
+func (a *agent) CancelCall(callID string) error {
+	return a.calls.CancelCall(callID)
+}
+
+var _ CallCanceler = &agent{}
+
 // getSlot returns a Slot (or error) for the request to run. This will wait
 // for other containers to become idle or it may wait for resources to become
 // available to launch a new container.
@@ -357,7 +436,7 @@ func (a *agent) getSlot(ctx context.Context, call *call) (Slot, error) {
 		call.slotHashId = getSlotQueueKey(call, slotExtns)
 	}
 
-	call.slots, isNew = a.slotMgr.getSlotQueue(call.slotHashId)
+	call.slots, isNew = a.slotMgr.getSlotQueue(call.slotHashId, call.FnID)
 	call.requestState.UpdateState(ctx, RequestStateWait, call.slots)
 
 	// setup slot caller with a ctx that gets cancelled once waitHot() is completed.
@@ -570,6 +649,7 @@ type hotSlot struct {
 	container     *container // TODO mask this
 	cfg           *Config
 	containerSpan trace.SpanContext
+	coldStart     time.Duration // time the container took to start, if this is its first slot
 }
 
 func (s *hotSlot) SetError(err error) {
@@ -658,13 +738,20 @@ func (s *hotSlot) dispatch(ctx context.Context, call *call) error {
 	defer swapBack()
 
 	req := createUDSRequest(ctx, call)
+	if call.upgrader != nil {
+		setUpgradeHeaders(req, call.upgrade)
+	}
 
 	var resp *http.Response
 	var err error
 	{ // don't leak ctx scope
 		ctx, span := trace.StartSpan(ctx, "agent_dispatch_uds_do")
 		req = req.WithContext(ctx)
-		resp, err = s.container.udsClient.Do(req)
+		if call.upgrader != nil {
+			resp, err = s.container.udsUpgrade.RoundTrip(req)
+		} else {
+			resp, err = s.container.udsClient.Do(req)
+		}
 		span.End()
 	}
 
@@ -684,6 +771,10 @@ func (s *hotSlot) dispatch(ctx context.Context, call *call) error {
 
 	common.Logger(ctx).WithField("resp", resp).Debug("Got resp from UDS socket")
 
+	if call.upgrader != nil && resp.StatusCode == http.StatusSwitchingProtocols {
+		return proxyUpgrade(ctx, call, resp)
+	}
+
 	ioErrChan := make(chan error, 1)
 	go func() {
 		ioErrChan <- s.writeResp(ctx, s.cfg.MaxResponseSize, resp, call.respWriter)
@@ -693,7 +784,7 @@ func (s *hotSlot) dispatch(ctx context.Context, call *call) error {
 	case ioErr := <-ioErrChan:
 		return ioErr
 	case <-ctx.Done():
-		if ctx.Err() == context.DeadlineExceeded {
+		if ctx.Err() == context.DeadlineExceeded || call.isCanceled() {
 			// IMPORTANT: Container contract: If http-uds timeout, container cannot continue
 			s.SetError(ctx.Err())
 		}
@@ -727,6 +818,12 @@ func (s *hotSlot) writeResp(ctx context.Context, max uint64, resp *http.Response
 
 	rw = newSizerRespWriter(max, rw)
 
+	// the trailers declared by the function, whose values are only read with the body
+	declared := make([]string, 0, len(resp.Trailer))
+	for k := range resp.Trailer {
+		declared = append(declared, k)
+	}
+
 	// remove transport headers before copying to client response
 	common.StripHopHeaders(resp.Header)
 
@@ -739,10 +836,25 @@ func (s *hotSlot) writeResp(ctx context.Context, max uint64, resp *http.Response
 			rw.Header().Add(k, v)
 		}
 	}
+	if len(declared) > 0 {
+		sort.Strings(declared)
+		rw.Header().Set("Trailer", strings.Join(declared, ", "))
+	}
 	rw.WriteHeader(http.StatusOK)
 
 	_, ioErr := io.Copy(rw, resp.Body)
-	return ioErr
+	if ioErr != nil {
+		return ioErr
+	}
+
+	// prefixed, so that the trailers are sent after the body even if the
+	// response is buffered before its header is written
+	for _, k := range declared {
+		for _, v := range resp.Trailer[k] {
+			rw.Header().Add(http.TrailerPrefix+k, v)
+		}
+	}
+	return nil
 }
 
 // XXX(reed): this is a remnant of old io.pipe plumbing, we need to get rid of
@@ -852,6 +964,14 @@ func (a *agent) runHot(ctx context.Context, caller slotCaller, call *call, tok R
 		authToken = call.slots.getAuthToken()
 	}
 
+	// past the max concurrent cold starts, wait for other containers to start
+	releaseColdStart, err := a.coldStarts.acquire(ctx, a.shutWg.Closer())
+	if err != nil {
+		runHotFailure(ctx, err, caller)
+		return
+	}
+	defer releaseColdStart()
+
 	container = newHotContainer(ctx, a.evictor, &caller, call, &a.cfg, id, authToken, udsWait)
 	if container == nil {
 		return
@@ -942,6 +1062,13 @@ func (a *agent) runHot(ctx context.Context, caller slotCaller, call *call, tok R
 		}
 
 		timer.Stop() // no longer needed
+		releaseColdStart()
+
+		statsContainerWarm(ctx, call, 1)
+		defer statsContainerWarm(ctx, call, -1)
+
+		// only the first call of the container is a cold start
+		coldStart := time.Since(ctrCreatePrepStart)
 
 		for ctx.Err() == nil {
 			slot := &hotSlot{
@@ -949,7 +1076,9 @@ func (a *agent) runHot(ctx context.Context, caller slotCaller, call *call, tok R
 				container:     container,
 				cfg:           &a.cfg,
 				containerSpan: trace.FromContext(ctx).SpanContext(),
+				coldStart:     coldStart,
 			}
+			coldStart = 0
 
 			if !a.runHotReq(ctx, call, state, logger, cookie, slot, container) {
 				return
@@ -1176,6 +1305,9 @@ type container struct {
 	stderr io.Writer
 
 	udsClient http.Client
+	// udsUpgrade is the transport of udsClient without tracing, whose
+	// response bodies are writable when the container switches protocols
+	udsUpgrade http.RoundTripper
 
 	// swapMu protects the stats swapping
 	swapMu sync.Mutex
@@ -1245,7 +1377,11 @@ func newHotContainer(ctx context.Context, evictor Evictor, caller *slotCaller, c
 		},
 	}
 
-	env := cloneStrMap(call.Config) // clone to avoid data race
+	conf := call.Config
+	if call.secretConfig != nil {
+		conf = call.secretConfig
+	}
+	env := cloneStrMap(conf) // clone to avoid data race
 
 	// Debug info exposed to FDK/Container
 	if cfg.EnableFDKDebugInfo {
@@ -1268,7 +1404,7 @@ func newHotContainer(ctx context.Context, evictor Evictor, caller *slotCaller, c
 		pendingSignals: cfg.MaxPendingSignals,
 		messageQueue:   cfg.MaxMessageQueue,
 		tmpFsSize:      uint64(call.TmpFsSize),
-		disableNet:     call.disableNet,
+		disableNet:     call.disableNet || egressDenied(call.Call),
 		iofs:           iofs,
 		dockerAuth:     call.dockerAuth,
 		authToken:      authToken,
@@ -1289,6 +1425,7 @@ func newHotContainer(ctx context.Context, evictor Evictor, caller *slotCaller, c
 				// NOTE: the global trace sampler will be used, this is what we want for now at least
 			},
 		},
+		udsUpgrade: baseTransport,
 		evictor:    evictor,
 		beforeCall: func(context.Context, *models.Call, drivers.CallExtensions) error { return nil },
 		afterCall:  func(context.Context, *models.Call, drivers.CallExtensions) error { return nil },
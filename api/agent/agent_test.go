@@ -278,11 +278,145 @@ func TestGetCallFromModelRoundTripACall(t *testing.T) {
 	}
 }
 
+func TestCallRegistryAuthAnnotation(t *testing.T) {
+	app := &models.App{ID: id.New().String()}
+	app.Annotations, _ = app.Annotations.With(models.AppRegistryAuthAnnotation, map[string]interface{}{
+		"auths": map[string]interface{}{"my.registry.com": map[string]string{"auth": "Y29jbzpjaGVlc2UK"}},
+	})
+	fn := &models.Fn{ID: id.New().String(), Image: "my.registry.com/fn/hello"}
+
+	req, err := http.NewRequest("GET", "http://127.0.0.1:8080/invoke/"+fn.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var c call
+	if err := FromHTTPFnRequest(app, fn, req)(&c); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Model().Annotations.Get(models.AppRegistryAuthAnnotation); ok {
+		t.Fatal("registry auth annotation should not be copied to the call")
+	}
+	if c.dockerAuth == nil {
+		t.Fatal("expected call to have docker auth from app annotation")
+	}
+	auth, err := c.dockerAuth.DockerAuth(context.Background(), fn.Image)
+	if err != nil || auth == nil || auth.Username != "coco" {
+		t.Fatalf("expected app registry auth for image, got err %v", err)
+	}
+
+	// runners get them from LB nodes as an extension of the call
+	var placed call
+	if err := WithExtensions(c.Extensions())(&placed); err != nil {
+		t.Fatal(err)
+	}
+	if err := withExtensionsRegistryAuth()(&placed); err != nil {
+		t.Fatal(err)
+	}
+	if placed.dockerAuth == nil {
+		t.Fatal("expected placed call to have docker auth from the call extensions")
+	}
+	auth, err = placed.dockerAuth.DockerAuth(context.Background(), fn.Image)
+	if err != nil || auth == nil || auth.Username != "coco" {
+		t.Fatalf("expected app registry auth for image on the runner, got err %v", err)
+	}
+
+	app.Annotations, _ = app.Annotations.With(models.AppRegistryAuthAnnotation, "nope")
+	if err := FromHTTPFnRequest(app, fn, req)(&c); err != models.ErrAppsInvalidRegistryAuth {
+		t.Fatalf("expected invalid registry auth error, got %v", err)
+	}
+}
+
+func TestCallDisableLogsAnnotation(t *testing.T) {
+	app := &models.App{ID: id.New().String()}
+	syslogURL := "tcp://syslog.example.com:514"
+	app.SyslogURL = &syslogURL
+	fn := &models.Fn{ID: id.New().String(), Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 1, IdleTimeout: 1}}
+
+	a := New()
+	defer checkClose(t, a)
+
+	for i, disabled := range []bool{false, true} {
+		if disabled {
+			app.Annotations, _ = app.Annotations.With(models.AppDisableLogsAnnotation, true)
+		}
+		req, err := http.NewRequest("GET", "http://127.0.0.1:8080/invoke/"+fn.ID, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		callI, err := a.GetCall(FromHTTPFnRequest(app, fn, req))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := callI.(*call)
+		if got := c.Model().SyslogURL != ""; got == disabled {
+			t.Errorf("Test %d: expected syslog url set %v, got %q", i, !disabled, c.Model().SyslogURL)
+		}
+		if _, noop := c.stderr.(common.NoopReadWriteCloser); noop != disabled {
+			t.Errorf("Test %d: expected logs captured %v", i, !disabled)
+		}
+		c.stderr.Close()
+	}
+}
+
+func TestIdleTimeoutAnnotation(t *testing.T) {
+	for i, test := range []struct {
+		annotation    interface{}
+		serverDefault uint64
+		expected      int32
+	}{
+		{nil, 0, 30},
+		{nil, 120, 120},
+		{300, 120, 300},
+		{0, 0, 1},
+		{86400, 0, models.MaxIdleTimeout},
+		// values which are not a number of seconds are ignored
+		{"5m", 0, 30},
+	} {
+		c := &models.Call{IdleTimeout: 30}
+		if test.annotation != nil {
+			c.Annotations, _ = c.Annotations.With(models.AppIdleTimeoutAnnotation, test.annotation)
+		}
+		if got := idleTimeout(c, test.serverDefault); got != test.expected {
+			t.Errorf("Test %d: expected idle timeout %d, got %d", i, test.expected, got)
+		}
+	}
+}
+
+func TestEgressAllowConfig(t *testing.T) {
+	for i, test := range []struct {
+		annotation interface{}
+		expected   string
+		set        bool
+		denied     bool
+	}{
+		{nil, "", false, false},
+		{"none", models.EgressAllowNone, true, true},
+		{"API.example.com, 10.0.0.0/8", "api.example.com,10.0.0.0/8", true, false},
+	} {
+		app := &models.App{ID: "app_id", Name: "myapp"}
+		if test.annotation != nil {
+			app.Annotations, _ = app.Annotations.With(models.AppEgressAllowAnnotation, test.annotation)
+		}
+		// fns can't set the allowlist through their config
+		fn := &models.Fn{ID: "fn_id", Config: models.Config{models.EgressAllowEnv: "*"}}
+
+		conf := buildConfig(app, fn)
+		if v, ok := conf[models.EgressAllowEnv]; ok != test.set || v != test.expected {
+			t.Errorf("Test %d: expected %s to be %q (set %v), got %q (set %v)", i, models.EgressAllowEnv, test.expected, test.set, v, ok)
+		}
+		if denied := egressDenied(&models.Call{Config: conf}); denied != test.denied {
+			t.Errorf("Test %d: expected egress denied %v, got %v", i, test.denied, denied)
+		}
+	}
+}
+
 func TestLoggerIsStringerAndWorks(t *testing.T) {
 	// TODO test limit writer, logrus writer, etc etc
 
 	var call models.Call
-	logger := setupLogger(context.Background(), 1*1024*1024, true, &call)
+	logger := setupLogger(context.Background(), 1*1024*1024, true, false, &call)
 
 	if _, ok := logger.(fmt.Stringer); !ok {
 		// NOTE: if you are reading, maybe what you've done is ok, but be aware we were relying on this for optimization...
@@ -303,10 +437,45 @@ func TestLoggerIsStringerAndWorks(t *testing.T) {
 	// TODO we could check for the toilet to flush here to logrus
 }
 
+func TestLoggerTimestamps(t *testing.T) {
+	var call models.Call
+	logger := setupLogger(context.Background(), 1*1024*1024, true, true, &call)
+	logger.(*rwc).WriteCloser.(*lineWriter).w.(*timestampWriter).now = func() time.Time {
+		return time.Date(2018, 1, 2, 3, 4, 5, 6000000, time.FixedZone("", 3600))
+	}
+
+	logger.Write([]byte("ERROR: boom\nlevel=warn msg=\"slow\"\n{\"level\":\"debug\"}\nplain\n"))
+	expected := "2018-01-02T02:04:05.006Z ERROR ERROR: boom\n" +
+		"2018-01-02T02:04:05.006Z WARN level=warn msg=\"slow\"\n" +
+		"2018-01-02T02:04:05.006Z DEBUG {\"level\":\"debug\"}\n" +
+		"2018-01-02T02:04:05.006Z - plain\n"
+	if strGot := logger.(fmt.Stringer).String(); strGot != expected {
+		t.Fatalf("expected log lines to be prefixed with their time and level, got %q", strGot)
+	}
+	logger.Close()
+}
+
+func TestInferLogLevel(t *testing.T) {
+	for line, expected := range map[string]string{
+		"[info] started":                       "INFO",
+		"Warning: disk almost full":            "WARN",
+		"  fatal error: all goroutines asleep": "FATAL",
+		"ts=1 lvl=error msg=failed":            "ERROR",
+		`{"msg":"failed","severity":"ERROR"}`:  "ERROR",
+		"level=unknown info":                   "-",
+		"hello world":                          "-",
+		"":                                     "-",
+	} {
+		if level := inferLogLevel([]byte(line)); level != expected {
+			t.Errorf("expected level %s for %q, got %s", expected, line, level)
+		}
+	}
+}
+
 func TestLoggerTooBig(t *testing.T) {
 
 	var call models.Call
-	logger := setupLogger(context.Background(), 10, true, &call)
+	logger := setupLogger(context.Background(), 10, true, false, &call)
 
 	str := fmt.Sprintf("0 line\n1 l\n-----max log size 10 bytes exceeded, truncating log-----\n")
 
@@ -678,6 +847,40 @@ func TestHTTPWithoutContentLengthWorks(t *testing.T) {
 	}
 }
 
+func TestWriteRespTrailers(t *testing.T) {
+	// a function which streams its body and fails partway
+	fdk := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "Fn-Stream-Status")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("partial"))
+		w.Header().Set("Fn-Stream-Status", "error")
+	}))
+	defer fdk.Close()
+
+	resp, err := http.Get(fdk.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	rec := httptest.NewRecorder()
+	s := &hotSlot{}
+	if err := s.writeResp(context.Background(), 0, resp, rec); err != nil {
+		t.Fatal(err)
+	}
+
+	res := rec.Result()
+	if rec.Body.String() != "partial" {
+		t.Fatalf("expected the body, got %q", rec.Body.String())
+	}
+	if res.Header.Get("Trailer") != "Fn-Stream-Status" || res.Header.Get("Fn-Stream-Status") != "" {
+		t.Fatalf("expected the trailer to be declared, got %v", res.Header)
+	}
+	if res.Trailer.Get("Fn-Stream-Status") != "error" {
+		t.Fatalf("expected the trailing status, got %v", res.Trailer)
+	}
+}
+
 func TestGetCallReturnsResourceImpossibility(t *testing.T) {
 	call := &models.Call{
 		AppID:       id.New().String(),
@@ -1,13 +1,16 @@
 package agent
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/fnproject/fn/api/agent/drivers/docker"
@@ -69,7 +72,8 @@ func FromHTTPFnRequest(app *models.App, fn *models.Fn, req *http.Request) CallOp
 			Config:      buildConfig(app, fn),
 			// TODO - this wasn't really the intention here (that annotations would naturally cascade
 			// but seems to be necessary for some runner behaviour
-			Annotations: app.Annotations.MergeChange(fn.Annotations),
+			// registry credentials are kept on the call (below) and never on the model, nor are signing keys
+			Annotations: app.Annotations.MergeChange(fn.Annotations).Without(models.AppRegistryAuthAnnotation).Without(models.AppSigningKeyAnnotation),
 			Headers:     req.Header,
 			CreatedAt:   common.DateTime(time.Now()),
 			URL:         reqURL(req),
@@ -80,11 +84,67 @@ func FromHTTPFnRequest(app *models.App, fn *models.Fn, req *http.Request) CallOp
 			SyslogURL:   syslogURL,
 		}
 
+		if auth, ok := app.Annotations.Get(models.AppRegistryAuthAnnotation); ok {
+			auther, err := docker.NewRegistryAuther(bytes.NewReader(auth))
+			if err != nil {
+				// don't wrap err, it may quote the credentials
+				return models.ErrAppsInvalidRegistryAuth
+			}
+			c.dockerAuth = auther
+
+			// LB nodes pass them on to the runner of the call
+			if c.extensions == nil {
+				c.extensions = make(map[string]string)
+			}
+			c.extensions[RegistryAuth] = string(auth)
+		}
+
 		c.req = req
 		return nil
 	}
 }
 
+// logsDisabled returns whether the app or fn of a call set
+// models.AppDisableLogsAnnotation. The annotations are those cached with the
+// app and fn, so this is cheap to check per call.
+func logsDisabled(c *models.Call) bool {
+	v, ok := c.Annotations.Get(models.AppDisableLogsAnnotation)
+	if !ok {
+		return false
+	}
+	var disabled bool
+	return json.Unmarshal(v, &disabled) == nil && disabled
+}
+
+// idleTimeout returns the idle timeout of the hot containers of a call, from
+// models.AppIdleTimeoutAnnotation, bounded to between 1 and
+// models.MaxIdleTimeout seconds, or else from the server wide default, if set,
+// or else the idle timeout of its fn.
+func idleTimeout(c *models.Call, serverDefault uint64) int32 {
+	v, ok := c.Annotations.Get(models.AppIdleTimeoutAnnotation)
+	var timeout int64
+	if ok && json.Unmarshal(v, &timeout) == nil {
+		if timeout < 1 {
+			return 1
+		}
+		if timeout > int64(models.MaxIdleTimeout) {
+			return models.MaxIdleTimeout
+		}
+		return int32(timeout)
+	}
+	if serverDefault > 0 {
+		return int32(serverDefault)
+	}
+	return c.IdleTimeout
+}
+
+// egressDenied returns whether the app of a call denies all egress, see
+// models.AppEgressAllowAnnotation, in which case its containers have no
+// network. It is read from the config of the call, which runners receive.
+func egressDenied(c *models.Call) bool {
+	return c.Config[models.EgressAllowEnv] == models.EgressAllowNone
+}
+
 func buildConfig(app *models.App, fn *models.Fn) models.Config {
 	conf := make(models.Config, 8+len(app.Config)+len(fn.Config))
 	for k, v := range app.Config {
@@ -101,6 +161,18 @@ func buildConfig(app *models.App, fn *models.Fn) models.Config {
 	conf["FN_FN_ID"] = fn.ID
 	conf["FN_APP_ID"] = app.ID
 
+	// only the allowlist of the app may be passed, so that fns can't loosen it
+	delete(conf, models.EgressAllowEnv)
+	if allow, ok, err := models.AppEgressAllow(app); ok {
+		switch {
+		case err != nil || len(allow) == 0:
+			// stored apps are validated, but fail closed
+			conf[models.EgressAllowEnv] = models.EgressAllowNone
+		default:
+			conf[models.EgressAllowEnv] = strings.Join(allow, ",")
+		}
+	}
+
 	return conf
 }
 
@@ -204,6 +276,25 @@ func WithExtensions(extensions map[string]string) CallOpt {
 	}
 }
 
+// withExtensionsRegistryAuth pulls the image of a call with the registry
+// credentials of its app passed as the RegistryAuth extension, if any. It
+// must come after WithExtensions.
+func withExtensionsRegistryAuth() CallOpt {
+	return func(c *call) error {
+		auth, ok := c.extensions[RegistryAuth]
+		if !ok {
+			return nil
+		}
+		auther, err := docker.NewRegistryAuther(strings.NewReader(auth))
+		if err != nil {
+			// don't wrap err, it may quote the credentials
+			return models.ErrAppsInvalidRegistryAuth
+		}
+		c.dockerAuth = auther
+		return nil
+	}
+}
+
 // WithDockerAuth configures a call to retrieve credentials for an image pull
 func WithDockerAuth(auth docker.Auther) CallOpt {
 	return func(c *call) error {
@@ -243,6 +334,16 @@ func (a *agent) GetCall(opts ...CallOpt) (Call, error) {
 		c.extensions = ext
 	}
 
+	// apps may opt out of having the logs of their calls captured
+	if logsDisabled(c.Call) {
+		c.Call.SyslogURL = ""
+		if c.stderr == nil {
+			c.stderr = common.NoopReadWriteCloser{}
+		}
+	}
+
+	c.Call.IdleTimeout = idleTimeout(c.Call, a.cfg.ContainerIdleTimeout)
+
 	mem := c.Memory + uint64(c.TmpFsSize)
 	if !a.resources.IsResourcePossible(mem, c.CPUs) {
 		return nil, models.ErrCallResourceTooBig
@@ -255,13 +356,22 @@ func (a *agent) GetCall(opts ...CallOpt) (Call, error) {
 	c.Call.Config["FN_FORMAT"] = "http-stream" // TODO: remove this after fdk's forget what it means
 	// TODO we could set type here too, for now, or anything else not based in fn/app/trigger config
 
+	// secrets are only resolved for the container, the model keeps the references
+	if a.secrets != nil {
+		conf, err := interpolateConfig(c.Call.Config, a.secrets, allowedSecrets(c.Call))
+		if err != nil {
+			return nil, err
+		}
+		c.secretConfig = conf
+	}
+
 	setupCtx(&c)
 
 	c.ct = a
 	if c.stderr == nil {
 		// TODO(reed): is line writer is vulnerable to attack?
 		// XXX(reed): forcing this as default is not great / configuring it isn't great either. reconsider.
-		c.stderr = setupLogger(c.req.Context(), a.cfg.MaxLogSize, !a.cfg.DisableDebugUserLogs, c.Call)
+		c.stderr = setupLogger(c.req.Context(), a.cfg.MaxLogSize, !a.cfg.DisableDebugUserLogs, a.cfg.LogTimestamps, c.Call)
 	}
 	if c.respWriter == nil {
 		// send function output to logs if no writer given (TODO no longer need w/o async?)
@@ -282,6 +392,8 @@ type call struct {
 	*models.Call
 
 	respWriter   io.Writer
+	upgrader     http.Hijacker
+	upgrade      string
 	req          *http.Request
 	stderr       io.ReadWriteCloser
 	ct           callTrigger
@@ -290,6 +402,7 @@ type call struct {
 	slotHashId   string
 	disableNet   bool
 	dockerAuth   docker.Auther // pull config function
+	secretConfig models.Config // config with its secrets resolved, never log it
 
 	// amount of time attributed to user-code execution
 	userExecTime *time.Duration
@@ -306,6 +419,15 @@ type call struct {
 	// Init wait start timestamp for goroutine in runHot
 	initStartTime int64
 
+	// time spent in each phase, see GetCallTimings
+	timings CallTimings
+
+	// set if the call was cancelled with CancelCall
+	canceled int32
+
+	// address of the runner an lb placed the call on
+	runnerAddr atomic.Value
+
 	// LB & Pure Runner Extra Config
 	extensions map[string]string
 }
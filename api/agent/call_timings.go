@@ -0,0 +1,24 @@
+package agent
+
+import "time"
+
+// CallTimings is how long a call spent in each phase of its execution, as
+// measured by the agent. The phases a call did not go through are zero.
+type CallTimings struct {
+	// Queue is the time the call waited for a container, including ColdStart
+	Queue time.Duration
+	// ColdStart is the time the container of the call took to start, if it
+	// was started for the call
+	ColdStart time.Duration
+	// Exec is the time the call ran in its container
+	Exec time.Duration
+}
+
+// GetCallTimings returns the timings of a call, once it was submitted. Calls
+// run on runners through a load balancer do not report their ColdStart.
+func GetCallTimings(c Call) CallTimings {
+	if call, ok := c.(*call); ok {
+		return call.timings
+	}
+	return CallTimings{}
+}
@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+	"go.opencensus.io/stats"
+)
+
+// coldStartLimiter bounds the containers starting at once, from creating them,
+// pulling their image if need be, to their init, see
+// Config.MaxConcurrentColdStarts. Calls to warm containers never wait on it.
+// A nil limiter doesn't bound them.
+type coldStartLimiter struct {
+	sem chan struct{}
+}
+
+func newColdStartLimiter(max uint64) *coldStartLimiter {
+	if max == 0 {
+		return nil
+	}
+	return &coldStartLimiter{sem: make(chan struct{}, max)}
+}
+
+// acquire waits for a container to be allowed to start, until ctx is done or
+// the agent shuts down (closer), and returns the func to call once it started,
+// or failed to, which may be called more than once
+func (l *coldStartLimiter) acquire(ctx context.Context, closer <-chan struct{}) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	start := time.Now()
+	select {
+	case l.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-closer:
+		return nil, models.ErrCallTimeoutServerBusy
+	}
+	stats.Record(ctx, coldStartWaitMeasure.M(int64(time.Since(start)/time.Millisecond)))
+
+	var once sync.Once
+	return func() { once.Do(func() { <-l.sem }) }, nil
+}
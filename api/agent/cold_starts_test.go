@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestColdStartLimiter(t *testing.T) {
+	const max = 3
+	l := newColdStartLimiter(max)
+	closer := make(chan struct{})
+
+	var starting, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := l.acquire(context.Background(), closer)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			n := atomic.AddInt32(&starting, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&starting, -1)
+			release()
+			release() // no-op
+		}()
+	}
+	wg.Wait()
+
+	if peak > max {
+		t.Fatalf("expected at most %d containers to start at once, got %d", max, peak)
+	}
+
+	// past the max, cold starts give up with their ctx or the agent
+	releases := make([]func(), 0, max)
+	for i := 0; i < max; i++ {
+		release, err := l.acquire(context.Background(), closer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		releases = append(releases, release)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, closer); err != context.DeadlineExceeded {
+		t.Fatalf("expected the cold start to wait until its ctx is done, got %v", err)
+	}
+	close(closer)
+	if _, err := l.acquire(context.Background(), closer); err == nil {
+		t.Fatal("expected the cold start to give up on agent shutdown")
+	}
+	for _, release := range releases {
+		release()
+	}
+
+	// no limiter, no limit
+	var none *coldStartLimiter
+	if release, err := none.acquire(context.Background(), closer); err != nil {
+		t.Fatal(err)
+	} else {
+		release()
+	}
+}
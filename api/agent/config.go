@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/fnproject/fn/api/models"
 )
 
 // Config specifies various settings for an agent
@@ -42,6 +44,7 @@ type Config struct {
 	MaxTmpFsInodes                uint64        `json:"max_tmpfs_inodes"`
 	DisableReadOnlyRootFs         bool          `json:"disable_readonly_rootfs"`
 	DisableDebugUserLogs          bool          `json:"disable_debug_user_logs"`
+	LogTimestamps                 bool          `json:"log_timestamps"`
 	IOFSEnableTmpfs               bool          `json:"iofs_enable_tmpfs"`
 	EnableFDKDebugInfo            bool          `json:"enable_fdk_debug_info"`
 	IOFSAgentPath                 string        `json:"iofs_path"`
@@ -50,6 +53,12 @@ type Config struct {
 	ImageCleanMaxSize             uint64        `json:"image_clean_max_size"`
 	ImageCleanExemptTags          string        `json:"image_clean_exempt_tags"`
 	ImageEnableVolume             bool          `json:"image_enable_volume"`
+	EnableConfigInterpolation     bool          `json:"enable_config_interpolation"`
+	ConfigSecretsFile             string        `json:"config_secrets_file"`
+	ContainerIdleTimeout          uint64        `json:"container_idle_timeout_secs"`
+	MaxWarmContainers             uint64        `json:"max_warm_containers"`
+	MaxConcurrentColdStarts       uint64        `json:"max_concurrent_cold_starts"`
+	MaxCPUPercent                 uint64        `json:"max_cpu_percent"`
 }
 
 const (
@@ -122,6 +131,10 @@ const (
 	EnvDisableReadOnlyRootFs = "FN_DISABLE_READONLY_ROOTFS"
 	// EnvDisableDebugUserLogs disables user function logs being logged at level debug. wise to enable for production.
 	EnvDisableDebugUserLogs = "FN_DISABLE_DEBUG_USER_LOGS"
+	// EnvLogTimestamps prefixes each line functions log with the time it was logged, in RFC 3339, and its level
+	// when it can be told from the line, e.g. 2006-01-02T15:04:05.999999999Z ERROR <line>. Lines are kept as is
+	// by default.
+	EnvLogTimestamps = "FN_LOG_TIMESTAMPS"
 
 	// EnvIOFSEnableTmpfs enables creating a per-container tmpfs mount for the IOFS
 	EnvIOFSEnableTmpfs = "FN_IOFS_TMPFS"
@@ -132,6 +145,33 @@ const (
 	// EnvIOFSOpts are the options to set when mounting the iofs directory for unix socket files
 	EnvIOFSOpts = "FN_IOFS_OPTS"
 
+	// EnvEnableConfigInterpolation replaces the references to secrets in config values, as ${secret:NAME},
+	// with their values when passing config to containers, so that secrets need not be stored in the datastore.
+	// Apps may only reference the secrets listed in their fn.config-secrets annotation, which operators set.
+	EnvEnableConfigInterpolation = "FN_ENABLE_CONFIG_INTERPOLATION"
+	// EnvConfigSecretsFile is a file of NAME=value lines config secrets are read from, they are otherwise
+	// read from the env vars FN_SECRET_NAME
+	EnvConfigSecretsFile = "FN_CONFIG_SECRETS_FILE"
+	// EnvContainerIdleTimeout is the time in seconds hot containers are kept warm while idle, taking precedence
+	// over the idle_timeout of fns. The fn.idle-timeout annotation of apps takes precedence over it.
+	EnvContainerIdleTimeout = "FN_CONTAINER_IDLE_TIMEOUT"
+	// EnvMaxWarmContainers caps the idle containers kept warm for the fns of apps with the fn.min-warm
+	// annotation, across apps. Defaults to 0, keeping none warm. Each holds the memory of its fn while
+	// idle, so the cap times the memory of the fns should leave room for the calls. Warm containers
+	// still idle out after the idle timeout, or get evicted for calls of other fns, and are then
+	// replaced within a second, so a short idle timeout churns them.
+	EnvMaxWarmContainers = "FN_MAX_WARM_CONTAINERS"
+	// EnvMaxConcurrentColdStarts caps the containers starting at once, from their creation and image pull to
+	// their init, so that a burst of cold calls doesn't saturate image pulls and CPU. Containers past it wait
+	// for others to start. Calls to warm containers are never held up. Defaults to 0, no cap.
+	EnvMaxConcurrentColdStarts = "FN_MAX_CONCURRENT_COLD_STARTS"
+	// EnvMaxCPUPercent rejects the calls submitted to the agent as too busy, a 503 that LBs retry on other runners,
+	// while the CPU utilization of the node, averaged over about 10 seconds, is over this percentage of all its
+	// CPUs. It is sampled from /proc/stat every second, so it is only enforced on linux, and covers all the
+	// processes of the node, not only containers. Calls are first admitted on it, then have to get the memory
+	// and CPU reserved for their container, if a container needs starting. Defaults to 0, no limit.
+	EnvMaxCPUPercent = "FN_MAX_CPU_PERCENT"
+
 	// EnvDetachedHeadroom is the extra room we want to give to a detached function to run.
 	EnvDetachedHeadroom = "FN_EXECUTION_HEADROOM"
 
@@ -202,9 +242,16 @@ func NewConfig() (*Config, error) {
 	err = setEnvBool(err, EnvEnableNBResourceTracker, &cfg.EnableNBResourceTracker)
 	err = setEnvBool(err, EnvDisableReadOnlyRootFs, &cfg.DisableReadOnlyRootFs)
 	err = setEnvBool(err, EnvDisableDebugUserLogs, &cfg.DisableDebugUserLogs)
+	err = setEnvBool(err, EnvLogTimestamps, &cfg.LogTimestamps)
 	err = setEnvUint(err, EnvImageCleanMaxSize, &cfg.ImageCleanMaxSize, nil)
 	err = setEnvStr(err, EnvImageCleanExemptTags, &cfg.ImageCleanExemptTags)
 	err = setEnvBool(err, EnvImageEnableVolume, &cfg.ImageEnableVolume)
+	err = setEnvBool(err, EnvEnableConfigInterpolation, &cfg.EnableConfigInterpolation)
+	err = setEnvStr(err, EnvConfigSecretsFile, &cfg.ConfigSecretsFile)
+	err = setEnvUint(err, EnvContainerIdleTimeout, &cfg.ContainerIdleTimeout, nil)
+	err = setEnvUint(err, EnvMaxWarmContainers, &cfg.MaxWarmContainers, nil)
+	err = setEnvUint(err, EnvMaxConcurrentColdStarts, &cfg.MaxConcurrentColdStarts, nil)
+	err = setEnvUint(err, EnvMaxCPUPercent, &cfg.MaxCPUPercent, nil)
 
 	if err != nil {
 		return cfg, err
@@ -215,6 +262,14 @@ func NewConfig() (*Config, error) {
 		return cfg, fmt.Errorf("error invalid %s %v > %v", EnvMaxLogSize, cfg.MaxLogSize, math.MaxInt64)
 	}
 
+	if cfg.MaxCPUPercent > 100 {
+		return cfg, fmt.Errorf("error invalid %s %v > 100", EnvMaxCPUPercent, cfg.MaxCPUPercent)
+	}
+
+	if cfg.ContainerIdleTimeout > uint64(models.MaxIdleTimeout) {
+		return cfg, fmt.Errorf("error invalid %s %v > %v", EnvContainerIdleTimeout, cfg.ContainerIdleTimeout, models.MaxIdleTimeout)
+	}
+
 	return cfg, nil
 }
 
@@ -0,0 +1,171 @@
+package agent
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+// secretEnvPrefix prefixes the env vars secrets are read from, when no
+// secrets file is set, so that config can't read other settings of the node
+const secretEnvPrefix = "FN_SECRET_"
+
+// configSecretRef matches the references to secrets in config values
+var configSecretRef = regexp.MustCompile(`\$\{secret:([A-Za-z0-9_.-]+)\}`)
+
+// SecretSource resolves the secrets config values reference as ${secret:NAME}
+type SecretSource interface {
+	Secret(name string) (string, bool)
+}
+
+// WithSecretSource sets the source the secrets referenced by config values
+// are resolved from, when config interpolation is enabled. It defaults to
+// the file of EnvConfigSecretsFile if set, or env vars prefixed with
+// FN_SECRET_ otherwise.
+func WithSecretSource(src SecretSource) Option {
+	return func(a *agent) error {
+		a.secrets = src
+		return nil
+	}
+}
+
+func newSecretSource(path string) SecretSource {
+	if path != "" {
+		return &fileSecrets{path: path}
+	}
+	return envSecrets{}
+}
+
+// envSecrets reads secret NAME from env var FN_SECRET_NAME
+type envSecrets struct{}
+
+func (envSecrets) Secret(name string) (string, bool) {
+	return os.LookupEnv(secretEnvPrefix + name)
+}
+
+// fileSecrets reads secrets from a file of NAME=value lines, again whenever
+// it changes
+type fileSecrets struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	secrets map[string]string
+}
+
+func (f *fileSecrets) Secret(name string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fi, err := os.Stat(f.path)
+	if err != nil {
+		logrus.WithError(err).WithField("secrets_file", f.path).Error("Failed to read config secrets")
+		return "", false
+	}
+	if !fi.ModTime().Equal(f.modTime) || f.secrets == nil {
+		secrets, err := readSecretsFile(f.path)
+		if err != nil {
+			logrus.WithError(err).WithField("secrets_file", f.path).Error("Failed to read config secrets")
+			return "", false
+		}
+		f.secrets, f.modTime = secrets, fi.ModTime()
+	}
+	v, ok := f.secrets[name]
+	return v, ok
+}
+
+func readSecretsFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	secrets := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			// never quote the line, it holds a secret
+			return nil, fmt.Errorf("line %d is not NAME=value", n)
+		}
+		secrets[strings.TrimSpace(line[:i])] = line[i+1:]
+	}
+	return secrets, scanner.Err()
+}
+
+// allowedSecrets returns the names of the secrets the config of a call may
+// reference, from models.AppConfigSecretsAnnotation, none if it has none
+func allowedSecrets(c *models.Call) map[string]bool {
+	allowed := make(map[string]bool)
+	list, err := c.Annotations.GetString(models.AppConfigSecretsAnnotation)
+	if err != nil {
+		return allowed
+	}
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			allowed[name] = true
+		}
+	}
+	return allowed
+}
+
+// interpolateConfig returns a copy of conf with the references to secrets
+// replaced by their values, or nil if it references none. Only the secrets
+// named in allowed may be referenced. The values must only be passed to
+// containers, never logged nor stored on the call model.
+func interpolateConfig(conf models.Config, secrets SecretSource, allowed map[string]bool) (models.Config, error) {
+	var out models.Config
+	for k, v := range conf {
+		if !strings.Contains(v, "${secret:") {
+			continue
+		}
+		if strings.Contains(configSecretRef.ReplaceAllString(v, ""), "${secret:") {
+			return nil, models.NewAPIError(http.StatusBadRequest,
+				fmt.Errorf("Config %s has an invalid secret reference, it must be ${secret:NAME}", k))
+		}
+		var denied, missing string
+		resolved := configSecretRef.ReplaceAllStringFunc(v, func(ref string) string {
+			name := configSecretRef.FindStringSubmatch(ref)[1]
+			if !allowed[name] {
+				if denied == "" {
+					denied = name
+				}
+				return ""
+			}
+			secret, ok := secrets.Secret(name)
+			if !ok && missing == "" {
+				missing = name
+			}
+			return secret
+		})
+		if denied != "" {
+			return nil, models.NewAPIError(http.StatusBadRequest,
+				fmt.Errorf("Config %s references secret %s which is not in the %s annotation of the app", k, denied, models.AppConfigSecretsAnnotation))
+		}
+		if missing != "" {
+			return nil, models.NewAPIError(http.StatusBadRequest,
+				fmt.Errorf("Config %s references secret %s which is not set on this server", k, missing))
+		}
+		if out == nil {
+			out = make(models.Config, len(conf))
+			for k, v := range conf {
+				out[k] = v
+			}
+		}
+		out[k] = resolved
+	}
+	return out, nil
+}
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+type mapSecrets map[string]string
+
+func (m mapSecrets) Secret(name string) (string, bool) {
+	v, ok := m[name]
+	return v, ok
+}
+
+func TestInterpolateConfig(t *testing.T) {
+	secrets := mapSecrets{"DB_PASS": "hunter2", "TOKEN": "${secret:DB_PASS}", "OTHER_APP": "hunter3"}
+	annotations, _ := models.Annotations{}.With(models.AppConfigSecretsAnnotation, "DB_PASS, TOKEN,NOPE")
+	allowed := allowedSecrets(&models.Call{Annotations: annotations})
+
+	for i, test := range []struct {
+		conf     models.Config
+		expected models.Config
+		err      string
+	}{
+		{models.Config{"A": "plain"}, nil, ""},
+		{
+			models.Config{"A": "plain", "DB_URL": "postgres://fn:${secret:DB_PASS}@db/${secret:DB_PASS}"},
+			models.Config{"A": "plain", "DB_URL": "postgres://fn:hunter2@db/hunter2"},
+			"",
+		},
+		// secret values are not interpolated again
+		{models.Config{"T": "${secret:TOKEN}"}, models.Config{"T": "${secret:DB_PASS}"}, ""},
+		{models.Config{"A": "${secret:NOPE}"}, nil, "Config A references secret NOPE which is not set on this server"},
+		{models.Config{"A": "${secret:bad name}"}, nil, "Config A has an invalid secret reference"},
+		// set on the server, but not for this app
+		{models.Config{"A": "${secret:OTHER_APP}"}, nil, "Config A references secret OTHER_APP which is not in the fn.config-secrets annotation of the app"},
+	} {
+		conf, err := interpolateConfig(test.conf, secrets, allowed)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("Test %d: expected error %q, got %v", i, test.err, err)
+			} else if strings.Contains(err.Error(), "hunter") {
+				t.Errorf("Test %d: error leaks a secret: %v", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: unexpected error %v", i, err)
+		}
+		if !reflect.DeepEqual(conf, test.expected) {
+			t.Errorf("Test %d: expected %v, got %v", i, test.expected, conf)
+		}
+	}
+}
+
+func TestFileSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fn-secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "secrets")
+
+	if err := ioutil.WriteFile(path, []byte("# db\nDB_PASS=hunter2=x\n\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	secrets := newSecretSource(path)
+	if v, ok := secrets.Secret("DB_PASS"); !ok || v != "hunter2=x" {
+		t.Fatalf("expected DB_PASS to be hunter2=x, got %q %v", v, ok)
+	}
+
+	// rotated secrets are read again
+	if err := ioutil.WriteFile(path, []byte("DB_PASS=hunter3\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := secrets.Secret("DB_PASS"); !ok || v != "hunter3" {
+		t.Fatalf("expected DB_PASS to be hunter3, got %q %v", v, ok)
+	}
+	if _, ok := secrets.Secret("NOPE"); ok {
+		t.Fatal("expected NOPE not to be set")
+	}
+}
@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+)
+
+const (
+	// cpuSampleInterval is how often the CPU utilization of the node is
+	// sampled, see Config.MaxCPUPercent
+	cpuSampleInterval = time.Second
+
+	// cpuSmoothing is the weight of each sample in the average utilization
+	// calls are admitted on, which so follows a sustained change within about
+	// 10 seconds, while a spike of a second or two rejects no call
+	cpuSmoothing = 0.2
+)
+
+// cpuAdmission rejects the calls submitted to the agent while the smoothed
+// CPU utilization of the node is over a threshold, see Config.MaxCPUPercent.
+// A nil cpuAdmission admits every call.
+type cpuAdmission struct {
+	maxPercent float64
+	sample     func() (busy, total uint64, err error)
+
+	// math.Float64bits of the smoothed utilization, in percent
+	percent uint64
+
+	// the last sample and whether percent was set from any sample yet, only
+	// touched by the sampling loop
+	lastBusy, lastTotal uint64
+	sampled, averaged   bool
+}
+
+// newCPUAdmission returns the admission control of the agent, or nil if
+// maxPercent is 0 or the utilization of the node can't be read, i.e. on other
+// systems than linux
+func newCPUAdmission(maxPercent uint64) *cpuAdmission {
+	if maxPercent == 0 {
+		return nil
+	}
+	c := &cpuAdmission{maxPercent: float64(maxPercent), sample: readProcStat}
+	busy, total, err := c.sample()
+	if err != nil {
+		logrus.WithError(err).Error("cannot read the CPU utilization of the node, calls are not admitted on it")
+		return nil
+	}
+	c.lastBusy, c.lastTotal, c.sampled = busy, total, true
+	return c
+}
+
+// admit returns whether a call may be submitted
+func (c *cpuAdmission) admit() bool {
+	return c == nil || c.utilization() <= c.maxPercent
+}
+
+// utilization returns the smoothed CPU utilization of the node, in percent
+func (c *cpuAdmission) utilization() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&c.percent))
+}
+
+// run samples the CPU utilization every cpuSampleInterval until closer is
+// closed
+func (c *cpuAdmission) run(closer <-chan struct{}) {
+	if c == nil {
+		return
+	}
+	ticker := time.NewTicker(cpuSampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.update()
+		case <-closer:
+			return
+		}
+	}
+}
+
+func (c *cpuAdmission) update() {
+	busy, total, err := c.sample()
+	if err != nil {
+		logrus.WithError(err).Error("cannot read the CPU utilization of the node")
+		return
+	}
+	lastBusy, lastTotal, sampled := c.lastBusy, c.lastTotal, c.sampled
+	c.lastBusy, c.lastTotal, c.sampled = busy, total, true
+	if !sampled || total <= lastTotal || busy < lastBusy {
+		return
+	}
+
+	cur := 100 * float64(busy-lastBusy) / float64(total-lastTotal)
+	avg := cur
+	if c.averaged {
+		avg = c.utilization()
+		avg += cpuSmoothing * (cur - avg)
+	}
+	c.averaged = true
+	atomic.StoreUint64(&c.percent, math.Float64bits(avg))
+	stats.Record(context.Background(), cpuUtilizationMeasure.M(int64(math.Round(avg))))
+}
+
+var errCantReadProcStat = errors.New("Didn't find the cpu line of /proc/stat")
+
+// readProcStat returns the time the CPUs of the node were busy for, and in
+// total, since boot, in jiffies. Idle and iowait time is not busy.
+func readProcStat() (busy, total uint64, err error) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// expect form:
+		// cpu  user nice system idle iowait irq softirq steal guest guest_nice
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+		// guest time is also counted in user time
+		if len(fields) > 9 {
+			fields = fields[:9]
+		}
+		var idle uint64
+		for i, v := range fields[1:] {
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return 0, 0, errors.New("Could not parse the cpu line of /proc/stat: " + scanner.Text())
+			}
+			total += n
+			if i == 3 || i == 4 { // idle, iowait
+				idle += n
+			}
+		}
+		return total - idle, total, nil
+	}
+	return 0, 0, errCantReadProcStat
+}
@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"math"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestCPUAdmission(t *testing.T) {
+	var busy, total uint64
+	c := &cpuAdmission{
+		maxPercent: 80,
+		sample:     func() (uint64, uint64, error) { return busy, total, nil },
+	}
+	tick := func(b, t uint64) {
+		busy += b
+		total += t
+		c.update()
+	}
+
+	// the first sample only sets the baseline
+	tick(100, 100)
+	if u := c.utilization(); u != 0 || !c.admit() {
+		t.Fatalf("expected no utilization before 2 samples, got %v", u)
+	}
+
+	// the first utilization is taken as is
+	tick(90, 100)
+	if u := c.utilization(); math.Abs(u-90) > 0.001 || c.admit() {
+		t.Fatalf("expected 90%% utilization not admitted, got %v", u)
+	}
+
+	// a second at 0% brings the average under the threshold
+	tick(0, 100)
+	if u := c.utilization(); math.Abs(u-72) > 0.001 || !c.admit() {
+		t.Fatalf("expected 72%% utilization admitted, got %v", u)
+	}
+
+	// a spike of a second is smoothed
+	tick(100, 100)
+	if u := c.utilization(); math.Abs(u-77.6) > 0.001 || !c.admit() {
+		t.Fatalf("expected 77.6%% utilization admitted, got %v", u)
+	}
+
+	// a failed or bogus sample leaves the average as is
+	c.sample = func() (uint64, uint64, error) { return 0, 0, errors.New("boom") }
+	c.update()
+	c.sample = func() (uint64, uint64, error) { return busy, total, nil }
+	tick(0, 0)
+	if u := c.utilization(); math.Abs(u-77.6) > 0.001 {
+		t.Fatalf("expected 77.6%% utilization after bogus samples, got %v", u)
+	}
+}
+
+func TestCPUAdmissionIdle(t *testing.T) {
+	var busy, total uint64
+	c := &cpuAdmission{
+		maxPercent: 50,
+		sample:     func() (uint64, uint64, error) { return busy, total, nil },
+	}
+	tick := func(b, t uint64) {
+		busy += b
+		total += t
+		c.update()
+	}
+
+	// an idle node averages 0%, yet a busy second after it is still smoothed
+	tick(0, 100)
+	tick(0, 100)
+	tick(0, 100)
+	tick(100, 100)
+	if u := c.utilization(); math.Abs(u-20) > 0.001 || !c.admit() {
+		t.Fatalf("expected 20%% utilization admitted, got %v", u)
+	}
+}
+
+func TestCPUAdmissionCloseAfterReject(t *testing.T) {
+	a := &agent{
+		shutWg: common.NewWaitGroup(),
+		cpu:    &cpuAdmission{maxPercent: 50, percent: math.Float64bits(90)},
+	}
+
+	err := a.submit(context.Background(), &call{})
+	if err != models.ErrCallTimeoutServerBusy {
+		t.Fatalf("expected the call rejected as too busy, got %v", err)
+	}
+
+	closed := make(chan error)
+	go func() { closed <- a.Close() }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent didn't close after rejecting a call")
+	}
+}
+
+func TestCPUAdmissionDisabled(t *testing.T) {
+	if c := newCPUAdmission(0); c != nil {
+		t.Fatal("expected no admission control without a max CPU percent")
+	}
+	var c *cpuAdmission
+	if !c.admit() {
+		t.Fatal("expected nil admission control to admit calls")
+	}
+}
+
+func TestReadProcStat(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("/proc/stat is only read on linux")
+	}
+	busy, total, err := readProcStat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total == 0 || busy > total {
+		t.Fatalf("bad sample busy=%v total=%v", busy, total)
+	}
+}
@@ -2,11 +2,17 @@ package agent
 
 import (
 	"context"
+	"strings"
 	"time"
 
+	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/models"
 	"github.com/golang/groupcache/singleflight"
 	"github.com/patrickmn/go-cache"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
 	"go.opencensus.io/trace"
 )
 
@@ -59,106 +65,183 @@ func (m *metricda) GetFnByID(ctx context.Context, fnID string) (*models.Fn, erro
 	return m.rda.GetFnByID(ctx, fnID)
 }
 
+// DefaultDataCacheTTL is how long NewCachedDataAccess caches entries for by default
+const DefaultDataCacheTTL = 5 * time.Second
+
+var (
+	cacheKindKey = common.MakeKey("kind")
+
+	dataCacheHitsMeasure   = common.MakeMeasure("data_cache_hits", "Reads served from the data access cache", "")
+	dataCacheMissesMeasure = common.MakeMeasure("data_cache_misses", "Reads not found in the data access cache", "")
+)
+
+// RegisterDataAccessViews creates and registers the views of the data access cache
+func RegisterDataAccessViews(tagKeys []string) {
+	tags := []tag.Key{cacheKindKey}
+	for _, key := range tagKeys {
+		if key != cacheKindKey.Name() {
+			tags = append(tags, common.MakeKey(key))
+		}
+	}
+
+	err := view.Register(
+		common.CreateViewWithTags(dataCacheHitsMeasure, view.Count(), tags),
+		common.CreateViewWithTags(dataCacheMissesMeasure, view.Count(), tags),
+	)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot register view")
+	}
+}
+
+// DataCache is implemented by read data accesses which cache their results, so
+// that entries can be dropped as soon as they are known to be stale rather
+// than when they expire.
+type DataCache interface {
+	// InvalidateApp drops the cached app, by ID and by name, and its triggers
+	InvalidateApp(appID string)
+	// InvalidateFn drops the cached fn
+	InvalidateFn(fnID string)
+	// InvalidateTriggers drops the cached triggers of the app, or of all apps
+	// if appID is empty
+	InvalidateTriggers(appID string)
+}
+
+// CachedDataAccessOption configures a cached data access, see NewCachedDataAccess
+type CachedDataAccessOption func(*cachedDataAccess)
+
+// WithDataCacheTTL sets how long entries are cached for. A ttl of 0 or less
+// disables caching, concurrent reads of the same entry are still coalesced.
+func WithDataCacheTTL(ttl time.Duration) CachedDataAccessOption {
+	return func(da *cachedDataAccess) {
+		da.ttl = ttl
+	}
+}
+
 // CachedDataAccess wraps a DataAccess and caches the results of GetApp.
 type cachedDataAccess struct {
 	ReadDataAccess
 
+	ttl          time.Duration
 	cache        *cache.Cache
 	singleflight singleflight.Group
 }
 
-// NewCachedDataAccess is a wrapper that caches entries temporarily
-func NewCachedDataAccess(da ReadDataAccess) ReadDataAccess {
+// NewCachedDataAccess is a wrapper that caches entries temporarily, the
+// returned ReadDataAccess is also a DataCache.
+func NewCachedDataAccess(da ReadDataAccess, opts ...CachedDataAccessOption) ReadDataAccess {
 	cda := &cachedDataAccess{
 		ReadDataAccess: da,
-		cache:          cache.New(5*time.Second, 1*time.Minute),
+		ttl:            DefaultDataCacheTTL,
+	}
+	for _, opt := range opts {
+		opt(cda)
 	}
+	cda.cache = cache.New(cda.ttl, 1*time.Minute)
 	return cda
 }
 
+const trigSourceCacheKeyPrefix = "t:"
+
 func appIDCacheKey(appID string) string     { return "a:" + appID }
 func appNameCacheKey(appName string) string { return "n:" + appName }
 func fnCacheKey(fnID string) string         { return "f:" + fnID }
 func trigSourceCacheKey(app, typ, source string) string {
-	return "t:" + app + string('\x00') + typ + string('\x00') + source
+	return trigSourceCacheKeyPrefix + app + string('\x00') + typ + string('\x00') + source
 }
 
-func (da *cachedDataAccess) GetAppID(ctx context.Context, appName string) (string, error) {
-	key := appNameCacheKey(appName)
-	app, ok := da.cache.Get(key)
-	if ok {
-		return app.(string), nil
+// get returns the entry cached at key, or the result of fetch which is then
+// cached. kind tags the hit and miss metrics.
+func (da *cachedDataAccess) get(ctx context.Context, kind, key string, fetch func() (interface{}, error)) (interface{}, error) {
+	ctx, err := tag.New(ctx, tag.Upsert(cacheKindKey, kind))
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot create tag for data cache metrics")
+	}
+
+	if v, ok := da.cache.Get(key); ok {
+		stats.Record(ctx, dataCacheHitsMeasure.M(0))
+		return v, nil
+	}
+	stats.Record(ctx, dataCacheMissesMeasure.M(0))
+
+	v, err := da.singleflight.Do(key, fetch)
+	if err != nil {
+		return nil, err
+	}
+	if da.ttl > 0 {
+		da.cache.Set(key, v, cache.DefaultExpiration)
 	}
+	return v, nil
+}
 
-	resp, err := da.singleflight.Do(key,
+func (da *cachedDataAccess) GetAppID(ctx context.Context, appName string) (string, error) {
+	app, err := da.get(ctx, "app_id", appNameCacheKey(appName),
 		func() (interface{}, error) {
 			return da.ReadDataAccess.GetAppID(ctx, appName)
 		})
-
 	if err != nil {
 		return "", err
 	}
-	app = resp.(string)
-	da.cache.Set(key, app, cache.DefaultExpiration)
 	return app.(string), nil
 }
 
 func (da *cachedDataAccess) GetAppByID(ctx context.Context, appID string) (*models.App, error) {
-	key := appIDCacheKey(appID)
-	app, ok := da.cache.Get(key)
-	if ok {
-		return app.(*models.App), nil
-	}
-
-	resp, err := da.singleflight.Do(key,
+	app, err := da.get(ctx, "app", appIDCacheKey(appID),
 		func() (interface{}, error) {
 			return da.ReadDataAccess.GetAppByID(ctx, appID)
 		})
-
 	if err != nil {
 		return nil, err
 	}
-	app = resp.(*models.App)
-	da.cache.Set(key, app, cache.DefaultExpiration)
 	return app.(*models.App), nil
 }
 
 func (da *cachedDataAccess) GetTriggerBySource(ctx context.Context, appID string, triggerType, source string) (*models.Trigger, error) {
-	key := trigSourceCacheKey(appID, triggerType, source)
-	trigger, ok := da.cache.Get(key)
-	if ok {
-		return trigger.(*models.Trigger), nil
-	}
-
-	resp, err := da.singleflight.Do(key,
+	trigger, err := da.get(ctx, "trigger", trigSourceCacheKey(appID, triggerType, source),
 		func() (interface{}, error) {
 			return da.ReadDataAccess.GetTriggerBySource(ctx, appID, triggerType, source)
 		})
-
 	if err != nil {
 		return nil, err
 	}
-	trigger = resp.(*models.Trigger)
-	da.cache.Set(key, trigger, cache.DefaultExpiration)
 	return trigger.(*models.Trigger), nil
 }
 
 func (da *cachedDataAccess) GetFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
-	key := fnCacheKey(fnID)
-	fn, ok := da.cache.Get(key)
-	if ok {
-		return fn.(*models.Fn), nil
-	}
-
-	resp, err := da.singleflight.Do(key,
+	fn, err := da.get(ctx, "fn", fnCacheKey(fnID),
 		func() (interface{}, error) {
 			return da.ReadDataAccess.GetFnByID(ctx, fnID)
 		})
-
 	if err != nil {
 		return nil, err
 	}
-	fn = resp.(*models.Fn)
-	da.cache.Set(key, fn, cache.DefaultExpiration)
 	return fn.(*models.Fn), nil
 }
+
+// InvalidateApp implements DataCache
+func (da *cachedDataAccess) InvalidateApp(appID string) {
+	da.cache.Delete(appIDCacheKey(appID))
+	for key, item := range da.cache.Items() {
+		if strings.HasPrefix(key, appNameCacheKey("")) && item.Object == appID {
+			da.cache.Delete(key)
+		}
+	}
+	da.InvalidateTriggers(appID)
+}
+
+// InvalidateFn implements DataCache
+func (da *cachedDataAccess) InvalidateFn(fnID string) {
+	da.cache.Delete(fnCacheKey(fnID))
+}
+
+// InvalidateTriggers implements DataCache
+func (da *cachedDataAccess) InvalidateTriggers(appID string) {
+	prefix := trigSourceCacheKeyPrefix
+	if appID != "" {
+		prefix += appID + string('\x00')
+	}
+	for key := range da.cache.Items() {
+		if strings.HasPrefix(key, prefix) {
+			da.cache.Delete(key)
+		}
+	}
+}
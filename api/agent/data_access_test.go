@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// countingDataAccess counts the reads that reach it
+type countingDataAccess struct {
+	ReadDataAccess
+	reads int
+}
+
+func (da *countingDataAccess) GetAppID(ctx context.Context, appName string) (string, error) {
+	da.reads++
+	return "app_id", nil
+}
+
+func (da *countingDataAccess) GetAppByID(ctx context.Context, appID string) (*models.App, error) {
+	da.reads++
+	return &models.App{ID: appID, Name: "app"}, nil
+}
+
+func (da *countingDataAccess) GetTriggerBySource(ctx context.Context, appID string, triggerType, source string) (*models.Trigger, error) {
+	da.reads++
+	return &models.Trigger{ID: "trigger_id", AppID: appID, Type: triggerType, Source: source}, nil
+}
+
+func (da *countingDataAccess) GetFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
+	da.reads++
+	return &models.Fn{ID: fnID}, nil
+}
+
+func TestCachedDataAccess(t *testing.T) {
+	ctx := context.Background()
+
+	readAll := func(da ReadDataAccess) {
+		da.GetAppID(ctx, "app")
+		da.GetAppByID(ctx, "app_id")
+		da.GetTriggerBySource(ctx, "app_id", "http", "/src")
+		da.GetFnByID(ctx, "fn_id")
+	}
+
+	inner := &countingDataAccess{}
+	da := NewCachedDataAccess(inner)
+	readAll(da)
+	readAll(da)
+	if inner.reads != 4 {
+		t.Errorf("expected 4 reads to reach the data access, got %d", inner.reads)
+	}
+
+	cache := da.(DataCache)
+	cache.InvalidateFn("fn_id")
+	cache.InvalidateApp("app_id")
+	readAll(da)
+	if inner.reads != 8 {
+		t.Errorf("expected the invalidated entries to be read again, got %d reads", inner.reads)
+	}
+
+	cache.InvalidateTriggers("other_app_id")
+	readAll(da)
+	if inner.reads != 8 {
+		t.Errorf("expected the triggers of other apps to stay cached, got %d reads", inner.reads)
+	}
+	cache.InvalidateTriggers("")
+	readAll(da)
+	if inner.reads != 9 {
+		t.Errorf("expected all triggers to be invalidated, got %d reads", inner.reads)
+	}
+
+	inner = &countingDataAccess{}
+	da = NewCachedDataAccess(inner, WithDataCacheTTL(0))
+	readAll(da)
+	readAll(da)
+	if inner.reads != 8 {
+		t.Errorf("expected no caching with a ttl of 0, got %d reads", inner.reads)
+	}
+
+	inner = &countingDataAccess{}
+	da = NewCachedDataAccess(inner, WithDataCacheTTL(10*time.Millisecond))
+	readAll(da)
+	time.Sleep(20 * time.Millisecond)
+	readAll(da)
+	if inner.reads != 8 {
+		t.Errorf("expected entries to expire after the ttl, got %d reads", inner.reads)
+	}
+}
@@ -423,6 +423,48 @@ func (drv *DockerDriver) GetSlotKeyExtensions(extn map[string]string) string {
 	return ""
 }
 
+var _ drivers.ImageInspector = &DockerDriver{}
+
+// InspectImage implements drivers.ImageInspector. Images already present on the
+// docker host are described from the local inspect, otherwise the registry is
+// asked for the image manifest and config using the registry auths configured
+// on this node, no layers are pulled.
+func (drv *DockerDriver) InspectImage(ctx context.Context, image string) (*drivers.ImageInfo, error) {
+	ctx, span := trace.StartSpan(ctx, "docker_inspect_image")
+	defer span.End()
+
+	img, err := drv.docker.InspectImage(ctx, image)
+	if err == nil {
+		info := &drivers.ImageInfo{
+			Image:     image,
+			Reachable: true,
+			Local:     true,
+			Size:      img.Size,
+		}
+		if len(img.RepoDigests) > 0 {
+			info.Digest = img.RepoDigests[0]
+		}
+		if img.Config != nil {
+			info.Entrypoint = img.Config.Entrypoint
+			info.Cmd = img.Config.Cmd
+		}
+		return info, nil
+	}
+	if err != docker.ErrNoSuchImage {
+		return nil, err
+	}
+
+	reg, repo, tag := drivers.ParseImage(image)
+	rc := newRegistryClient(http.DefaultClient, reg, repo, findRegistryConfig(reg, drv.auths))
+
+	info, err := rc.inspect(ctx, tag)
+	if err != nil {
+		return &drivers.ImageInfo{Image: image, Error: err.Error()}, nil
+	}
+	info.Image = image
+	return info, nil
+}
+
 // Run executes the docker container. If task runs, drivers.RunResult will be returned. If something fails outside the task (ie: Docker), it will return error.
 // The docker driver will attempt to cast the task to a Auther. If that succeeds, private image support is available. See the Auther interface for how to implement this.
 func (drv *DockerDriver) run(ctx context.Context, container string, task drivers.ContainerTask) (drivers.WaitResult, error) {
@@ -1,14 +1,23 @@
 package docker
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"strings"
 
+	"github.com/fnproject/fn/api/agent/drivers"
 	"github.com/fsouza/go-dockerclient"
 	"github.com/sirupsen/logrus"
 )
 
+// EnvRegistryAuth is the path to a docker config.json holding the registry
+// credentials used for image pulls. FN_DOCKER_AUTH, if set, takes precedence,
+// and the docker config of the user running fn is used if neither is set.
+const EnvRegistryAuth = "FN_REGISTRY_AUTH"
+
 var (
 	defaultPrivateRegistries = []string{"hub.docker.com", "index.docker.io"}
 )
@@ -18,6 +27,12 @@ func registryFromEnv() (map[string]driverAuthConfig, error) {
 	var err error
 	if reg := os.Getenv("FN_DOCKER_AUTH"); reg != "" {
 		auths, err = docker.NewAuthConfigurations(strings.NewReader(reg))
+	} else if path := os.Getenv(EnvRegistryAuth); path != "" {
+		// an explicitly configured file must be usable, don't fall back silently
+		auths, err = registryFromFile(path)
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		auths, err = docker.NewAuthConfigurationsFromDockerCfg()
 	}
@@ -30,6 +45,45 @@ func registryFromEnv() (map[string]driverAuthConfig, error) {
 	return preprocessAuths(auths)
 }
 
+func registryFromFile(path string) (*docker.AuthConfigurations, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	auths, err := docker.NewAuthConfigurations(f)
+	if err != nil {
+		// the parse error may quote the file, which holds credentials
+		return nil, fmt.Errorf("could not parse registry auth file %s", path)
+	}
+	return auths, nil
+}
+
+// NewRegistryAuther returns an Auther answering from the registry credentials
+// in r, which is read in the docker config.json format. Images from a
+// registry that r has no credentials for are left to the driver's own
+// registry auth.
+func NewRegistryAuther(r io.Reader) (Auther, error) {
+	auths, err := docker.NewAuthConfigurations(r)
+	if err != nil {
+		return nil, err
+	}
+	configs, err := preprocessAuths(auths)
+	if err != nil {
+		return nil, err
+	}
+	return registryAuther(configs), nil
+}
+
+type registryAuther map[string]driverAuthConfig
+
+// DockerAuth implements Auther
+func (ra registryAuther) DockerAuth(ctx context.Context, image string) (*docker.AuthConfiguration, error) {
+	reg, _, _ := drivers.ParseImage(image)
+	return matchRegistryConfig(reg, ra), nil
+}
+
 func preprocessAuths(auths *docker.AuthConfigurations) (map[string]driverAuthConfig, error) {
 	drvAuths := make(map[string]driverAuthConfig)
 
@@ -73,23 +127,25 @@ func getSubdomains(hostname string) map[string]bool {
 }
 
 func findRegistryConfig(reg string, configs map[string]driverAuthConfig) *docker.AuthConfiguration {
-	var config docker.AuthConfiguration
+	if res := matchRegistryConfig(reg, configs); res != nil {
+		return res
+	}
+	return &docker.AuthConfiguration{}
+}
 
+// matchRegistryConfig returns the configured auth for reg, or nil if there is none
+func matchRegistryConfig(reg string, configs map[string]driverAuthConfig) *docker.AuthConfiguration {
 	if reg != "" {
+		return lookupRegistryConfig(reg, configs)
+	}
+
+	for _, reg := range defaultPrivateRegistries {
 		res := lookupRegistryConfig(reg, configs)
 		if res != nil {
 			return res
 		}
-	} else {
-		for _, reg := range defaultPrivateRegistries {
-			res := lookupRegistryConfig(reg, configs)
-			if res != nil {
-				return res
-			}
-		}
 	}
-
-	return &config
+	return nil
 }
 
 func lookupRegistryConfig(reg string, configs map[string]driverAuthConfig) *docker.AuthConfiguration {
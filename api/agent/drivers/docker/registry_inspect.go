@@ -0,0 +1,338 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fsouza/go-dockerclient"
+)
+
+const (
+	// registry used by docker when an image does not name one
+	defaultRegistryHost = "registry-1.docker.io"
+
+	mediaTypeManifestV2   = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeOCIManifest  = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOCIIndex     = "application/vnd.oci.image.index.v1+json"
+
+	// manifests and image configs are small, don't let a bad registry hand us a layer
+	maxRegistryDocumentSize = 4 * 1024 * 1024
+)
+
+var challengeParamRegex = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// privateNetworks are the ranges, besides loopback and link local ones, of
+// addresses not routed on the internet
+var privateNetworks = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7"} {
+		_, n, _ := net.ParseCIDR(cidr)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+type registryManifest struct {
+	MediaType string `json:"mediaType"`
+	Config    struct {
+		Digest string `json:"digest"`
+		Size   int64  `json:"size"`
+	} `json:"config"`
+	Layers []struct {
+		Size int64 `json:"size"`
+	} `json:"layers"`
+	// only set on manifest lists / indexes
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform struct {
+			Architecture string `json:"architecture"`
+			OS           string `json:"os"`
+		} `json:"platform"`
+	} `json:"manifests"`
+}
+
+type registryImageConfig struct {
+	Config struct {
+		Entrypoint []string `json:"Entrypoint"`
+		Cmd        []string `json:"Cmd"`
+	} `json:"config"`
+}
+
+// registryClient speaks just enough of the docker registry v2 api to read
+// manifests and image configs, it never downloads layers.
+type registryClient struct {
+	http  *http.Client
+	base  string
+	repo  string
+	auth  *docker.AuthConfiguration
+	token string
+}
+
+func newRegistryClient(hc *http.Client, reg, repo string, auth *docker.AuthConfiguration) *registryClient {
+	if reg == "" || reg == "docker.io" || reg == "index.docker.io" {
+		reg = defaultRegistryHost
+	}
+	return &registryClient{
+		http: hc,
+		base: "https://" + reg,
+		repo: repo,
+		auth: auth,
+	}
+}
+
+// inspect resolves the manifest for tag (which may also be a digest) and the
+// image config it refers to.
+func (rc *registryClient) inspect(ctx context.Context, tag string) (*drivers.ImageInfo, error) {
+	manifest, digest, err := rc.manifest(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(manifest.Manifests) > 0 {
+		ref := ""
+		for _, m := range manifest.Manifests {
+			if m.Platform.OS == "linux" && m.Platform.Architecture == runtime.GOARCH {
+				ref = m.Digest
+				break
+			}
+		}
+		if ref == "" {
+			return nil, fmt.Errorf("no linux/%s image found in manifest list", runtime.GOARCH)
+		}
+		manifest, digest, err = rc.manifest(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	info := &drivers.ImageInfo{
+		Reachable: true,
+		Digest:    digest,
+	}
+	for _, l := range manifest.Layers {
+		info.Size += l.Size
+	}
+
+	if manifest.Config.Digest != "" {
+		var cfg registryImageConfig
+		if err := rc.getJSON(ctx, "/v2/"+rc.repo+"/blobs/"+manifest.Config.Digest, "", &cfg); err != nil {
+			return nil, err
+		}
+		info.Entrypoint = cfg.Config.Entrypoint
+		info.Cmd = cfg.Config.Cmd
+	}
+	return info, nil
+}
+
+func (rc *registryClient) manifest(ctx context.Context, ref string) (*registryManifest, string, error) {
+	accept := strings.Join([]string{mediaTypeManifestV2, mediaTypeManifestList, mediaTypeOCIManifest, mediaTypeOCIIndex}, ", ")
+
+	resp, err := rc.get(ctx, "/v2/"+rc.repo+"/manifests/"+ref, accept)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	var m registryManifest
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxRegistryDocumentSize)).Decode(&m); err != nil {
+		return nil, "", err
+	}
+	return &m, resp.Header.Get("Docker-Content-Digest"), nil
+}
+
+func (rc *registryClient) getJSON(ctx context.Context, path, accept string, dst interface{}) error {
+	resp, err := rc.get(ctx, path, accept)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(io.LimitReader(resp.Body, maxRegistryDocumentSize)).Decode(dst)
+}
+
+// get performs a GET against the registry, answering a single auth challenge
+// using the configured credentials.
+func (rc *registryClient) get(ctx context.Context, path, accept string) (*http.Response, error) {
+	resp, err := rc.do(ctx, path, accept)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("Www-Authenticate")
+		drainBody(resp)
+		if err := rc.authorize(ctx, challenge); err != nil {
+			return nil, err
+		}
+		resp, err = rc.do(ctx, path, accept)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		drainBody(resp)
+		return nil, fmt.Errorf("registry returned %d for %s", resp.StatusCode, path)
+	}
+	return resp, nil
+}
+
+func (rc *registryClient) do(ctx context.Context, path, accept string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, rc.base+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	switch {
+	case rc.token != "":
+		req.Header.Set("Authorization", "Bearer "+rc.token)
+	case rc.auth != nil && rc.auth.RegistryToken != "":
+		req.Header.Set("Authorization", "Bearer "+rc.auth.RegistryToken)
+	case rc.auth != nil && rc.auth.Username != "":
+		req.SetBasicAuth(rc.auth.Username, rc.auth.Password)
+	}
+	return rc.http.Do(req)
+}
+
+// authorize obtains a bearer token for a registry challenge, basic auth
+// challenges are already answered in do when credentials exist.
+func (rc *registryClient) authorize(ctx context.Context, challenge string) error {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return errors.New("registry requires authentication")
+	}
+
+	params := make(map[string]string)
+	for _, m := range challengeParamRegex.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+	realm := params["realm"]
+	if realm == "" {
+		return errors.New("registry auth challenge has no realm")
+	}
+
+	realmURL, err := rc.checkRealm(ctx, realm)
+	if err != nil {
+		return err
+	}
+
+	q := realmURL.Query()
+	if svc := params["service"]; svc != "" {
+		q.Set("service", svc)
+	}
+	if scope := params["scope"]; scope != "" {
+		q.Set("scope", scope)
+	} else {
+		q.Set("scope", "repository:"+rc.repo+":pull")
+	}
+
+	realmURL.RawQuery = q.Encode()
+	req, err := http.NewRequest(http.MethodGet, realmURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if rc.auth != nil && rc.auth.Username != "" {
+		req.SetBasicAuth(rc.auth.Username, rc.auth.Password)
+	}
+
+	// redirects could lead anywhere checkRealm wouldn't
+	hc := *rc.http
+	hc.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer drainBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry token request returned %d", resp.StatusCode)
+	}
+
+	var tok struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxRegistryDocumentSize)).Decode(&tok); err != nil {
+		return err
+	}
+	rc.token = tok.Token
+	if rc.token == "" {
+		rc.token = tok.AccessToken
+	}
+	if rc.token == "" {
+		return errors.New("registry token response has no token")
+	}
+	return nil
+}
+
+// checkRealm parses the realm of an auth challenge, the URL of the token
+// server of the registry, which the registry, and so whoever pushed the image
+// names it, chooses. It must be on the host of the registry, or be https on a
+// host with public addresses only, so that registries can't have the server
+// make requests, with the registry credentials, to hosts of its networks.
+func (rc *registryClient) checkRealm(ctx context.Context, realm string) (*url.URL, error) {
+	u, err := url.Parse(realm)
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry auth realm: %v", err)
+	}
+	if base, err := url.Parse(rc.base); err == nil && u.Scheme == base.Scheme && u.Host == base.Host {
+		return u, nil
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("registry auth realm %s is not https", realm)
+	}
+
+	host := u.Hostname()
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = append(ips, ip)
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+	for _, ip := range ips {
+		if !isPublicIP(ip) {
+			return nil, fmt.Errorf("registry auth realm %s is not on a public address", realm)
+		}
+	}
+	return u, nil
+}
+
+// isPublicIP returns whether ip is routed on the internet
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, n := range privateNetworks {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func drainBody(resp *http.Response) {
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxRegistryDocumentSize))
+	resp.Body.Close()
+}
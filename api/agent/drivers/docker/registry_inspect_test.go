@@ -0,0 +1,132 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+func TestRegistryInspect(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			user, pass, _ := r.BasicAuth()
+			if user != "user" || pass != "pass" || r.URL.Query().Get("scope") != "repository:fn/hello:pull" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, `{"token":"tok"}`)
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.Header().Set("Www-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:fn/hello:pull"`, ts.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v2/fn/hello/manifests/0.0.1":
+			w.Header().Set("Docker-Content-Digest", "sha256:abc")
+			fmt.Fprint(w, `{"mediaType":"`+mediaTypeManifestV2+`","config":{"digest":"sha256:cfg","size":10},"layers":[{"size":100},{"size":23}]}`)
+		case "/v2/fn/hello/blobs/sha256:cfg":
+			fmt.Fprint(w, `{"config":{"Entrypoint":["./func"],"Cmd":["arg"]}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	newClient := func(auth *docker.AuthConfiguration) *registryClient {
+		rc := newRegistryClient(ts.Client(), "", "fn/hello", auth)
+		rc.base = ts.URL
+		return rc
+	}
+
+	info, err := newClient(&docker.AuthConfiguration{Username: "user", Password: "pass"}).inspect(context.Background(), "0.0.1")
+	if err != nil {
+		t.Fatalf("unexpected error inspecting image: %v", err)
+	}
+	if !info.Reachable || info.Digest != "sha256:abc" || info.Size != 123 {
+		t.Fatalf("unexpected image info: %+v", info)
+	}
+	if !reflect.DeepEqual(info.Entrypoint, []string{"./func"}) || !reflect.DeepEqual(info.Cmd, []string{"arg"}) {
+		t.Fatalf("unexpected image entrypoint: %+v", info)
+	}
+
+	if _, err := newClient(&docker.AuthConfiguration{}).inspect(context.Background(), "0.0.1"); err == nil {
+		t.Fatal("expected error inspecting image without credentials")
+	}
+
+	if _, err := newClient(&docker.AuthConfiguration{Username: "user", Password: "pass"}).inspect(context.Background(), "missing"); err == nil {
+		t.Fatal("expected error inspecting missing tag")
+	}
+}
+
+func TestRegistryAuthRealm(t *testing.T) {
+	tokenRequests := 0
+	var ts *httptest.Server
+	var realm string
+	ts = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			tokenRequests++
+			fmt.Fprint(w, `{"token":"tok"}`)
+			return
+		}
+		w.Header().Set("Www-Authenticate", fmt.Sprintf(`Bearer realm="%s",service="test"`, realm))
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	for _, r := range []string{
+		// the realm of a registry may not be on another host of the network
+		"https://127.0.0.1:1/token",
+		"https://localhost:" + u.Port() + "/token",
+		"http://registry.example.com/token",
+		"https://10.0.0.1/token",
+		"https://[::1]/token",
+	} {
+		realm = r
+		rc := newRegistryClient(ts.Client(), "", "fn/hello", &docker.AuthConfiguration{Username: "user", Password: "pass"})
+		rc.base = ts.URL
+		if _, err := rc.inspect(context.Background(), "0.0.1"); err == nil || !strings.Contains(err.Error(), "realm") {
+			t.Errorf("expected the realm %s to be rejected, got %v", r, err)
+		}
+	}
+	if tokenRequests != 0 {
+		t.Fatalf("expected no token requests, got %d", tokenRequests)
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for _, test := range []struct {
+		ip       string
+		expected bool
+	}{
+		{"8.8.8.8", true},
+		{"2001:4860:4860::8888", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+	} {
+		if got := isPublicIP(net.ParseIP(test.ip)); got != test.expected {
+			t.Errorf("expected isPublicIP(%s) to be %v, got %v", test.ip, test.expected, got)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package docker
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -115,3 +119,62 @@ func TestRegistryEnv(t *testing.T) {
 		t.Fatalf("rawregistry.com registry should pickup rawregistry.com cfg %v", res)
 	}
 }
+
+func TestRegistryAuthFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fn-registry-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.json")
+	err = ioutil.WriteFile(path, []byte(`{"auths":{"my.registry.com":{"auth":"Y29jbzpjaGVlc2UK"}}}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Setenv("FN_DOCKER_AUTH", os.Getenv("FN_DOCKER_AUTH"))
+	defer os.Setenv(EnvRegistryAuth, os.Getenv(EnvRegistryAuth))
+	os.Setenv("FN_DOCKER_AUTH", "")
+	os.Setenv(EnvRegistryAuth, path)
+
+	drvAuths, err := registryFromEnv()
+	if err != nil {
+		t.Fatalf("reading registry auth file failed: %s", err)
+	}
+	res := findRegistryConfig("my.registry.com", drvAuths)
+	if res.ServerAddress != "my.registry.com" || res.Username != "coco" {
+		t.Fatalf("my.registry.com registry should pickup cfg from file %v", res.ServerAddress)
+	}
+
+	os.Setenv(EnvRegistryAuth, filepath.Join(dir, "missing.json"))
+	if _, err := registryFromEnv(); err == nil {
+		t.Fatal("expected error for missing registry auth file")
+	}
+
+	err = ioutil.WriteFile(path, []byte(`{"auths":{"my.registry.com":{"auth":"secret`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv(EnvRegistryAuth, path)
+	if _, err := registryFromEnv(); err == nil || strings.Contains(err.Error(), "secret") {
+		t.Fatalf("expected error without file contents for bad registry auth file, got %v", err)
+	}
+}
+
+func TestRegistryAuther(t *testing.T) {
+	auther, err := NewRegistryAuther(strings.NewReader(`{"auths":{"my.registry.com":{"auth":"Y29jbzpjaGVlc2UK"}}}`))
+	if err != nil {
+		t.Fatalf("parsing app registry auth failed: %s", err)
+	}
+
+	res, err := auther.DockerAuth(context.Background(), "my.registry.com/fn/hello:0.0.1")
+	if err != nil || res == nil || res.ServerAddress != "my.registry.com" {
+		t.Fatalf("my.registry.com image should pickup app cfg %v %v", res, err)
+	}
+
+	res, err = auther.DockerAuth(context.Background(), "fnproject/hello")
+	if err != nil || res != nil {
+		t.Fatalf("docker hub image should be left to the driver cfg %v %v", res, err)
+	}
+}
@@ -83,6 +83,27 @@ type Driver interface {
 	Close() error
 }
 
+// ImageInspector may be implemented by a Driver that is able to describe an
+// image without pulling its layers or running it.
+type ImageInspector interface {
+	// InspectImage returns what could be learned about the image. An image that
+	// cannot be reached is reported via ImageInfo.Reachable and ImageInfo.Error,
+	// a non-nil error is only returned if the inspection could not be attempted.
+	InspectImage(ctx context.Context, image string) (*ImageInfo, error)
+}
+
+// ImageInfo is the result of inspecting an image via an ImageInspector
+type ImageInfo struct {
+	Image      string   `json:"image"`
+	Reachable  bool     `json:"reachable"`
+	Local      bool     `json:"local"`
+	Digest     string   `json:"digest,omitempty"`
+	Size       int64    `json:"size,omitempty"`
+	Entrypoint []string `json:"entrypoint,omitempty"`
+	Cmd        []string `json:"cmd,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
 // RunResult indicates only the final state of the task.
 type RunResult interface {
 	// Error is an actionable/checkable error from the container, nil if
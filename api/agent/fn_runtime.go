@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"time"
+)
+
+// fnRuntimeWindow is the window the cold start rate of fns is measured over
+const fnRuntimeWindow = 5 * time.Minute
+
+// FnRuntimeStater is implemented by agents that run hot containers, reporting
+// the containers and calls of a fn on this node
+type FnRuntimeStater interface {
+	// FnRuntimeStats returns the live stats of the fn, all 0 if it has no
+	// container or call on this node
+	FnRuntimeStats(fnID string) FnRuntimeStats
+}
+
+// FnRuntimeStats are the hot containers and the calls of a fn on a node, at
+// the time they are read, and its recent cold start rate
+type FnRuntimeStats struct {
+	// WarmContainers are idle, or paused, ready for a call
+	WarmContainers uint64 `json:"warm_containers"`
+	// BusyContainers are executing a call
+	BusyContainers uint64 `json:"busy_containers"`
+	// StartingContainers are starting, or waiting for the resources to
+	StartingContainers uint64 `json:"starting_containers"`
+	// InFlight calls are executing in a container
+	InFlight uint64 `json:"in_flight"`
+	// Queued calls are waiting for a container
+	Queued uint64 `json:"queued"`
+	// ColdStartsPerMinute is the rate containers started at over the last
+	// ColdStartWindow
+	ColdStartsPerMinute float64 `json:"cold_starts_per_minute"`
+	ColdStartWindow     string  `json:"cold_start_window"`
+}
+
+var _ FnRuntimeStater = &agent{}
+
+// FnRuntimeStats implements FnRuntimeStater. A fn has a slot queue for each
+// of its configs containers were started for, e.g. before and after an
+// update, which are summed.
+func (a *agent) FnRuntimeStats(fnID string) FnRuntimeStats {
+	out := FnRuntimeStats{ColdStartWindow: fnRuntimeWindow.String()}
+	now := time.Now()
+	var coldStarts int
+	for _, slots := range a.slotMgr.fnSlotQueues(fnID) {
+		slots.statsLock.Lock()
+		cur := slots.stats
+		coldStarts += len(slots.pruneColdStarts(now))
+		slots.statsLock.Unlock()
+
+		out.WarmContainers += cur.containerStates[ContainerStateIdle] + cur.containerStates[ContainerStatePaused]
+		out.BusyContainers += cur.containerStates[ContainerStateBusy]
+		out.StartingContainers += cur.containerStates[ContainerStateStart] + cur.containerStates[ContainerStateWait]
+		out.InFlight += cur.requestStates[RequestStateExec]
+		out.Queued += cur.requestStates[RequestStateWait]
+	}
+	out.ColdStartsPerMinute = float64(coldStarts) / fnRuntimeWindow.Minutes()
+	return out
+}
+
+// fnSlotQueues returns the slot queues of the calls of a fn
+func (a *slotQueueMgr) fnSlotQueues(fnID string) []*slotQueue {
+	var out []*slotQueue
+	a.hMu.Lock()
+	for _, slots := range a.hot {
+		if slots.fnID == fnID {
+			out = append(out, slots)
+		}
+	}
+	a.hMu.Unlock()
+	return out
+}
+
+// pruneColdStarts drops the cold starts older than fnRuntimeWindow, and
+// returns the ones left. a.statsLock must be held.
+func (a *slotQueue) pruneColdStarts(now time.Time) []time.Time {
+	i := 0
+	for i < len(a.coldStarts) && now.Sub(a.coldStarts[i]) >= fnRuntimeWindow {
+		i++
+	}
+	a.coldStarts = a.coldStarts[i:]
+	return a.coldStarts
+}
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFnRuntimeStats(t *testing.T) {
+	a := &agent{slotMgr: NewSlotQueueMgr()}
+
+	// two configs of fn1, e.g. before and after an update, and another fn
+	old, _ := a.slotMgr.getSlotQueue("old", "fn1")
+	cur, _ := a.slotMgr.getSlotQueue("cur", "fn1")
+	other, _ := a.slotMgr.getSlotQueue("other", "fn2")
+
+	old.enterContainerState(ContainerStateStart)
+	old.exitContainerState(ContainerStateStart)
+	old.enterContainerState(ContainerStateIdle)
+
+	cur.enterContainerState(ContainerStateStart)
+	cur.exitContainerState(ContainerStateStart)
+	cur.enterContainerState(ContainerStateBusy)
+	cur.enterContainerState(ContainerStateStart)
+	cur.enterRequestState(RequestStateExec)
+	cur.enterRequestState(RequestStateWait)
+
+	other.enterContainerState(ContainerStateStart)
+	other.enterRequestState(RequestStateExec)
+
+	// a cold start out of the window
+	cur.statsLock.Lock()
+	cur.coldStarts = append([]time.Time{time.Now().Add(-fnRuntimeWindow - time.Second)}, cur.coldStarts...)
+	cur.statsLock.Unlock()
+
+	stats := a.FnRuntimeStats("fn1")
+	expected := FnRuntimeStats{
+		WarmContainers:      1,
+		BusyContainers:      1,
+		StartingContainers:  1,
+		InFlight:            1,
+		Queued:              1,
+		ColdStartsPerMinute: 3 / fnRuntimeWindow.Minutes(),
+		ColdStartWindow:     fnRuntimeWindow.String(),
+	}
+	if stats != expected {
+		t.Fatalf("expected %+v, got %+v", expected, stats)
+	}
+
+	if stats := a.FnRuntimeStats("fn3"); stats.WarmContainers != 0 || stats.ColdStartsPerMinute != 0 {
+		t.Fatalf("expected no stats for a fn without containers, got %+v", stats)
+	}
+}
@@ -5,7 +5,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"regexp"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/models"
@@ -20,12 +23,13 @@ var (
 // setupLogger returns a ReadWriteCloser that may have:
 // * [always] writes bytes to a size limited buffer, that can be read from using io.Reader
 // * [always] writes bytes per line to stderr as DEBUG
+// * [timestamps] prefixes each line with the time it was written and its level, see timestampWriter
 //
 // To prevent write failures from failing the call or any other writes,
 // multiWriteCloser ignores errors. Close will flush the line writers
 // appropriately.  The returned io.ReadWriteCloser is not safe for use after
 // calling Close.
-func setupLogger(ctx context.Context, maxSize uint64, debug bool, c *models.Call) io.ReadWriteCloser {
+func setupLogger(ctx context.Context, maxSize uint64, debug, timestamps bool, c *models.Call) io.ReadWriteCloser {
 	lbuf := bufPool.Get().(*bytes.Buffer)
 	dbuf := logPool.Get().(*bytes.Buffer)
 
@@ -55,6 +59,9 @@ func setupLogger(ctx context.Context, maxSize uint64, debug bool, c *models.Call
 	}
 
 	mw = append(mw, limitw, &fCloser{close})
+	if timestamps {
+		return &rwc{newLineWriter(&timestampWriter{w: mw, now: time.Now}), dbuf}
+	}
 	return &rwc{mw, dbuf}
 }
 
@@ -118,6 +125,72 @@ func (l *logWriter) Write(b []byte) (int, error) {
 	return len(b), nil
 }
 
+// timestampWriter prefixes every call to Write, which should be a line, with
+// the time it is written in RFC 3339 in UTC and the level of the line, and
+// writes it to w. It should be wrapped with a lineWriter. The lines stay as
+// readable as the function wrote them:
+//
+//	2006-01-02T15:04:05.999999999Z ERROR <line>
+//
+// The level is parsed from the line with inferLogLevel, and is - if the line
+// has none.
+type timestampWriter struct {
+	w   io.WriteCloser
+	now func() time.Time
+}
+
+func (t *timestampWriter) Write(b []byte) (int, error) {
+	line := make([]byte, 0, len(b)+40)
+	line = t.now().UTC().AppendFormat(line, time.RFC3339Nano)
+	line = append(line, ' ')
+	line = append(line, inferLogLevel(b)...)
+	line = append(line, ' ')
+	line = append(line, b...)
+	if _, err := t.w.Write(line); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (t *timestampWriter) Close() error { return t.w.Close() }
+
+var (
+	logLevels = map[string]string{
+		"trace":    "TRACE",
+		"debug":    "DEBUG",
+		"info":     "INFO",
+		"notice":   "INFO",
+		"warn":     "WARN",
+		"warning":  "WARN",
+		"error":    "ERROR",
+		"err":      "ERROR",
+		"fatal":    "FATAL",
+		"critical": "FATAL",
+		"panic":    "FATAL",
+	}
+	// level=error, "level":"error", and their lvl and severity variants
+	logLevelField = regexp.MustCompile(`(?i)"?\b(?:level|lvl|severity)"?\s*[=:]\s*"?([a-z]+)`)
+)
+
+// inferLogLevel returns the level of a log line, best-effort: from a level
+// field of logfmt or JSON lines, or from their first word, such as ERROR,
+// [warn] or Info:. It returns - if the line has none of these.
+func inferLogLevel(line []byte) string {
+	if m := logLevelField.FindSubmatch(line); m != nil {
+		if level, ok := logLevels[strings.ToLower(string(m[1]))]; ok {
+			return level
+		}
+	}
+	fields := bytes.Fields(line)
+	if len(fields) > 0 {
+		word := strings.ToLower(strings.Trim(string(fields[0]), "[]<>():|-"))
+		if level, ok := logLevels[word]; ok {
+			return level
+		}
+	}
+	return "-"
+}
+
 // lineWriter buffers all calls to Write and will call Write
 // on the underlying writer once per new line. Close must
 // be called to ensure that the buffer is flushed, and a newline
@@ -6,6 +6,7 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
@@ -17,20 +18,97 @@ import (
 	"github.com/fnproject/fn/api/agent"
 	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
 	"go.opencensus.io/plugin/ochttp/propagation/b3"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
 	"go.opencensus.io/trace"
 )
 
+// DefaultRetryMaxElapsed is how long requests to the API are retried for by default
+const DefaultRetryMaxElapsed = 5 * time.Second
+
+var (
+	methodKey = common.MakeKey("method")
+
+	retriesMeasure = common.MakeMeasure("hybrid_client_retries", "Number of retried requests to the API by the hybrid client", "")
+)
+
+// RegisterViews creates and registers views with provided tag keys
+func RegisterViews(tagKeys []string) {
+	tags := []tag.Key{methodKey}
+	for _, key := range tagKeys {
+		if key != methodKey.Name() {
+			tags = append(tags, common.MakeKey(key))
+		}
+	}
+
+	err := view.Register(common.CreateViewWithTags(retriesMeasure, view.Count(), tags))
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot register view")
+	}
+}
+
 // TODO(reed): this should use the fn_go bindings now, most likely, but get trigger by source
 // needs to be dealt with before that can happen.
 
 // client implements agent.DataAccess
 type client struct {
-	base string
-	http *http.Client
+	base            string
+	http            *http.Client
+	dialer          *net.Dialer
+	retryMaxElapsed time.Duration
+	token           string
+}
+
+// ClientOption configures the client created by NewClient
+type ClientOption func(*client) error
+
+// WithRetryMaxElapsed sets how long an idempotent request to the API is retried
+// for, with a jittered exponential backoff, when it fails with a server or
+// connection error. A max of 0 or less disables retries.
+func WithRetryMaxElapsed(max time.Duration) ClientOption {
+	return func(cl *client) error {
+		cl.retryMaxElapsed = max
+		return nil
+	}
+}
+
+// WithResolver resolves the host of the API with resolver, rather than the
+// default resolver of Go
+func WithResolver(resolver *net.Resolver) ClientOption {
+	return func(cl *client) error {
+		cl.dialer.Resolver = resolver
+		return nil
+	}
+}
+
+// WithTLSConfig sets the TLS config of the connections to the API, e.g. to
+// present the certificate of the node to API nodes requiring runner API mTLS
+func WithTLSConfig(cfg *tls.Config) ClientOption {
+	return func(cl *client) error {
+		cfg = cfg.Clone()
+		if cfg.ClientSessionCache == nil {
+			cfg.ClientSessionCache = tls.NewLRUClientSessionCache(8096)
+		}
+		cl.http.Transport.(*http.Transport).TLSClientConfig = cfg
+		return nil
+	}
+}
+
+// WithAPIToken sends token as the bearer token of the requests to the API, as
+// API nodes with API token auth require
+func WithAPIToken(token string) ClientOption {
+	return func(cl *client) error {
+		cl.token = token
+		return nil
+	}
 }
 
-func NewClient(u string) (agent.DataAccess, error) {
+// NewClient creates a client for the API at u, for nodes that can't access
+// the datastore directly.
+func NewClient(u string, opts ...ClientOption) (agent.DataAccess, error) {
 	uri, err := url.Parse(u)
 	if err != nil {
 		return nil, err
@@ -44,14 +122,15 @@ func NewClient(u string) (agent.DataAccess, error) {
 	}
 	host := uri.Scheme + "://" + uri.Host + "/v2/"
 
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
 	httpClient := &http.Client{
 		Timeout: 60 * time.Second,
 		Transport: &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
-			Dial: (&net.Dialer{
-				Timeout:   30 * time.Second,
-				KeepAlive: 30 * time.Second,
-			}).Dial,
+			Proxy:               http.ProxyFromEnvironment,
+			DialContext:         dialer.DialContext,
 			MaxIdleConns:        512,
 			MaxIdleConnsPerHost: 128,
 			IdleConnTimeout:     90 * time.Second,
@@ -63,10 +142,18 @@ func NewClient(u string) (agent.DataAccess, error) {
 		},
 	}
 
-	return &client{
-		base: host,
-		http: httpClient,
-	}, nil
+	cl := &client{
+		base:            host,
+		http:            httpClient,
+		dialer:          dialer,
+		retryMaxElapsed: DefaultRetryMaxElapsed,
+	}
+	for _, opt := range opts {
+		if err := opt(cl); err != nil {
+			return nil, err
+		}
+	}
+	return cl, nil
 }
 
 var noQuery = map[string]string{}
@@ -93,7 +180,8 @@ func (cl *client) GetAppByID(ctx context.Context, appID string) (*models.App, er
 	defer span.End()
 
 	var a models.App
-	err := cl.do(ctx, nil, &a, "GET", noQuery, "apps", appID)
+	// the runner API returns the annotations of the app holding secrets
+	err := cl.do(ctx, nil, &a, "GET", noQuery, "runner", "apps", appID)
 	return &a, err
 }
 
@@ -123,19 +211,43 @@ type httpErr struct {
 	error
 }
 
+// retriesExhaustedError is returned when a request still fails once it can no
+// longer be retried
+type retriesExhaustedError struct {
+	attempts int
+	elapsed  time.Duration
+	err      error
+}
+
+func (e *retriesExhaustedError) Error() string {
+	return fmt.Sprintf("API request failed after %d attempts in %s: %v", e.attempts, e.elapsed, e.err)
+}
+
+// isIdempotent reports whether requests with method may be retried safely
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
 func (cl *client) do(ctx context.Context, request, result interface{}, method string, query map[string]string, url ...string) error {
 
-	// Sequence of 25..75  25..175  25..200  25..725  25..1575
+	// Sequence of 25..75  25..175  25..200  25..725  25..1575 ... capped at 25..2000,
+	// for as long as retryMaxElapsed allows
 	backoff := common.NewBackOff(common.BackOffConfig{
-		MaxRetries: 5,
+		MaxRetries: common.RetryForever,
 		Interval:   50,
 		MinDelay:   25,
+		MaxDelay:   2000,
 	})
 
 	timer := common.NewTimer(25 * time.Millisecond)
 	defer timer.Stop()
 
-	for {
+	start := time.Now()
+	for attempts := 1; ; attempts++ {
 		// TODO this isn't re-using buffers very efficiently, but retries should be rare...
 		err := cl.once(ctx, request, result, method, query, url...)
 		switch err := err.(type) {
@@ -150,12 +262,20 @@ func (cl *client) do(ctx context.Context, request, result interface{}, method st
 			// this error wasn't from us [most likely], probably a conn refused/timeout, just retry it out
 		}
 
-		common.Logger(ctx).WithError(err).Error("error from API server, retrying")
-		delay, ok := backoff.NextBackOff()
-		if !ok {
+		if !isIdempotent(method) || cl.retryMaxElapsed <= 0 {
 			return err
 		}
 
+		delay, _ := backoff.NextBackOff()
+		elapsed := time.Since(start)
+		if elapsed+delay > cl.retryMaxElapsed {
+			return &retriesExhaustedError{attempts: attempts, elapsed: elapsed, err: err}
+		}
+
+		common.Logger(ctx).WithError(err).Error("error from API server, retrying")
+		retryCtx, _ := tag.New(ctx, tag.Upsert(methodKey, method))
+		stats.Record(retryCtx, retriesMeasure.M(0))
+
 		timer.Reset(delay)
 
 		select {
@@ -185,6 +305,9 @@ func (cl *client) once(ctx context.Context, request, result interface{}, method
 	// shove the span headers in so that the server will continue this span
 	var xxx b3.HTTPFormat
 	xxx.SpanContextToRequest(span.SpanContext(), req)
+	if cl.token != "" {
+		req.Header.Set("Authorization", "Bearer "+cl.token)
+	}
 
 	resp, err := cl.http.Do(req)
 	if err != nil {
@@ -0,0 +1,83 @@
+package hybrid
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientRetries(t *testing.T) {
+	for i, test := range []struct {
+		method           string
+		status           int
+		failures         int32
+		maxElapsed       time.Duration
+		expectedAttempts int32
+		expectErr        bool
+		expectExhausted  bool
+	}{
+		{http.MethodGet, http.StatusInternalServerError, 2, time.Minute, 3, false, false},
+		{http.MethodGet, http.StatusNotFound, 2, time.Minute, 1, true, false},
+		{http.MethodGet, http.StatusInternalServerError, 1000, 200 * time.Millisecond, 0, true, true},
+		{http.MethodGet, http.StatusInternalServerError, 2, 0, 1, true, false},
+		{http.MethodPost, http.StatusInternalServerError, 2, time.Minute, 1, true, false},
+	} {
+		var attempts int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&attempts, 1) <= test.failures {
+				w.WriteHeader(test.status)
+				w.Write([]byte(`{"message":"nope"}`))
+				return
+			}
+			w.Write([]byte(`{}`))
+		}))
+
+		da, err := NewClient(srv.URL, WithRetryMaxElapsed(test.maxElapsed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cl := da.(*client)
+
+		err = cl.do(context.Background(), nil, nil, test.method, noQuery, "apps")
+		srv.Close()
+
+		if (err != nil) != test.expectErr {
+			t.Errorf("Test %d: expected error %v, got %v", i, test.expectErr, err)
+		}
+		if _, ok := err.(*retriesExhaustedError); ok != test.expectExhausted {
+			t.Errorf("Test %d: expected retries exhausted %v, got %v", i, test.expectExhausted, err)
+		}
+		if n := atomic.LoadInt32(&attempts); test.expectedAttempts > 0 && n != test.expectedAttempts {
+			t.Errorf("Test %d: expected %d attempts, got %d", i, test.expectedAttempts, n)
+		}
+	}
+}
+
+func TestClientResolver(t *testing.T) {
+	var lookups int32
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			atomic.AddInt32(&lookups, 1)
+			return nil, errors.New("no DNS here")
+		},
+	}
+
+	da, err := NewClient("http://fn-api.invalid:8080", WithResolver(resolver), WithRetryMaxElapsed(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl := da.(*client)
+
+	if err := cl.do(context.Background(), nil, nil, http.MethodGet, noQuery, "apps"); err == nil {
+		t.Fatal("expected the API host not to resolve")
+	}
+	if atomic.LoadInt32(&lookups) == 0 {
+		t.Fatal("expected the API host to be resolved with the configured resolver")
+	}
+}
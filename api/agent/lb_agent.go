@@ -26,6 +26,7 @@ type lbAgent struct {
 	callOverrider CallOverrider
 	shutWg        *common.WaitGroup
 	callOpts      []CallOpt
+	calls         *activeCalls
 }
 
 type DetachedResponseWriter struct {
@@ -108,6 +109,7 @@ func NewLBAgent(rp pool.RunnerPool, p pool.Placer, options ...LBAgentOption) (Ag
 		rp:     rp,
 		placer: p,
 		shutWg: common.NewWaitGroup(),
+		calls:  newActiveCalls(),
 	}
 
 	// Allow overriding config
@@ -213,6 +215,9 @@ func (a *lbAgent) Submit(callI Call) error {
 	}
 	defer a.shutWg.DoneSession()
 
+	ctx, untrack := a.calls.add(ctx, call)
+	defer untrack()
+
 	statsEnqueue(ctx)
 
 	// pre-read and buffer request body if already not done based
@@ -319,6 +324,11 @@ func (a *lbAgent) Enqueue(context.Context, *models.Call) error {
 }
 
 func (a *lbAgent) handleCallEnd(ctx context.Context, call *call, err error, isForwarded bool) error {
+	// whatever the cancel interrupted, the call failed as it was cancelled
+	if err != nil && call.isCanceled() {
+		err = models.ErrCallCanceled
+	}
+
 	if isForwarded {
 		call.End(ctx, err)
 		statsStopRun(ctx)
@@ -347,11 +357,11 @@ func (a *lbAgent) handleCallEnd(ctx context.Context, call *call, err error, isFo
 		}
 	}
 
-	if err == models.ErrCallTimeoutServerBusy {
+	if err == models.ErrCallTimeoutServerBusy || err == models.ErrCallQueueFull {
 		statsTooBusy(ctx)
 		recordCallLatency(ctx, call, serverBusyMetricName)
-		return models.ErrCallTimeoutServerBusy
-	} else if err == context.Canceled {
+		return err
+	} else if err == context.Canceled || err == models.ErrCallCanceled {
 		statsCanceled(ctx)
 		recordCallLatency(ctx, call, canceledMetricName)
 	} else if err != nil {
@@ -361,6 +371,19 @@ func (a *lbAgent) handleCallEnd(ctx context.Context, call *call, err error, isFo
 	return err
 }
 
+// ActiveCalls implements CallCanceler. The runner of each call is the one it
+// was last placed on.
+func (a *lbAgent) ActiveCalls() []ActiveCall {
+	return a.calls.ActiveCalls()
+}
+
+// CancelCall implements CallCanceler. The runner sees the cancel as the
+// client of the call disconnecting, so the container of the call is only
+// killed when cancelling it on the runner.
+func (a *lbAgent) CancelCall(callID string) error {
+	return a.calls.CancelCall(callID)
+}
+
 func recordCallLatency(ctx context.Context, call *call, status string) {
 
 	start := time.Time(call.StartedAt)
@@ -397,4 +420,5 @@ func recordCallLatency(ctx context.Context, call *call, status string) {
 }
 
 var _ Agent = &lbAgent{}
+var _ CallCanceler = &lbAgent{}
 var _ callTrigger = &lbAgent{}
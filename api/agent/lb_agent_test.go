@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	pb "github.com/fnproject/fn/api/agent/grpc"
 	"github.com/fnproject/fn/api/models"
 	pool "github.com/fnproject/fn/api/runnerpool"
 )
@@ -295,3 +296,19 @@ func TestWithLBCallOptions(t *testing.T) {
 		t.Fatalf("Expected %s got %s", expected, actualType)
 	}
 }
+
+func TestRunnerCallTimings(t *testing.T) {
+	c := &call{Call: &models.Call{ID: "call_id"}}
+	recordFinishStats(context.Background(), &pb.CallFinished{
+		SchedulerDuration: int64(3 * time.Millisecond),
+		ExecutionDuration: int64(40 * time.Millisecond),
+	}, c)
+
+	expected := CallTimings{Queue: 3 * time.Millisecond, Exec: 40 * time.Millisecond}
+	if timings := GetCallTimings(c); timings != expected {
+		t.Fatalf("expected the timings of the runner %+v, got %+v", expected, timings)
+	}
+	if c.ExecutionDuration != 40*time.Millisecond {
+		t.Fatalf("expected the execution duration of the runner, got %v", c.ExecutionDuration)
+	}
+}
@@ -631,6 +631,8 @@ type pureRunner struct {
 	callHandleLock sync.Mutex
 	enableDetach   bool
 	configFunc     func(context.Context, *runner.ConfigMsg) (*runner.ConfigStatus, error)
+	// how long Close waits for the calls in flight to complete, 0 is forever
+	shutdownTimeout time.Duration
 }
 
 // implements Agent
@@ -643,10 +645,26 @@ func (pr *pureRunner) Submit(Call) error {
 	return errors.New("Submit cannot be called directly in a Pure Runner.")
 }
 
+// ActiveCalls implements CallCanceler
+func (pr *pureRunner) ActiveCalls() []ActiveCall {
+	if cc, ok := pr.a.(CallCanceler); ok {
+		return cc.ActiveCalls()
+	}
+	return nil
+}
+
+// CancelCall implements CallCanceler
+func (pr *pureRunner) CancelCall(callID string) error {
+	if cc, ok := pr.a.(CallCanceler); ok {
+		return cc.CancelCall(callID)
+	}
+	return models.ErrCallCancelUnsupported
+}
+
 // implements Agent
 func (pr *pureRunner) Close() error {
-	// First stop accepting requests
-	pr.gRPCServer.GracefulStop()
+	// First stop accepting requests, letting those in flight complete
+	pr.drain()
 	// Then let the agent finish
 	err := pr.a.Close()
 	if err != nil {
@@ -655,6 +673,49 @@ func (pr *pureRunner) Close() error {
 	return nil
 }
 
+// drain stops the gRPC server gracefully, so that it takes no new calls and
+// waits for those in flight, for up to the shutdown timeout if there is one.
+// Past it, the server is stopped, cancelling the calls still in flight. It
+// returns how many calls in flight completed, and how many were cancelled.
+func (pr *pureRunner) drain() (completed, terminated int32) {
+	inflight := atomic.LoadInt32(&pr.status.inflight)
+	log := logrus.WithFields(logrus.Fields{"inflight": inflight, "shutdown_timeout": pr.shutdownTimeout})
+	log.Info("Draining pure runner calls")
+
+	stopped := make(chan struct{})
+	go func() {
+		pr.gRPCServer.GracefulStop()
+		close(stopped)
+	}()
+
+	var timeout <-chan time.Time
+	if pr.shutdownTimeout > 0 {
+		timer := time.NewTimer(pr.shutdownTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-stopped:
+	case <-timeout:
+		terminated = atomic.LoadInt32(&pr.status.inflight)
+		pr.gRPCServer.Stop()
+		<-stopped
+	}
+
+	// calls may have come in before the server stopped taking them
+	if completed = inflight - terminated; completed < 0 {
+		completed = 0
+	}
+	log = log.WithFields(logrus.Fields{"completed": completed, "terminated": terminated})
+	if terminated > 0 {
+		log.Warn("Pure runner calls still in flight at the shutdown timeout were terminated")
+	} else {
+		log.Info("Pure runner calls drained")
+	}
+	return completed, terminated
+}
+
 // implements Agent
 func (pr *pureRunner) AddCallListener(cl fnext.CallListener) {
 	pr.a.AddCallListener(cl)
@@ -719,6 +780,7 @@ func (pr *pureRunner) handleTryCall(tc *runner.TryCall, state *callHandle) error
 		WithWriter(state),
 		WithContext(state.sctx),
 		WithExtensions(tc.GetExtensions()),
+		withExtensionsRegistryAuth(),
 	)
 	if err != nil {
 		state.enqueueCallResponse(err)
@@ -859,14 +921,15 @@ func (pr *pureRunner) AfterCall(ctx context.Context, call *models.Call) error {
 	return nil
 }
 
-func DefaultPureRunner(cancel context.CancelFunc, addr string, tlsCfg *tls.Config) (Agent, error) {
+func DefaultPureRunner(cancel context.CancelFunc, addr string, tlsCfg *tls.Config, options ...PureRunnerOption) (Agent, error) {
 	agent := New()
 
+	options = append([]PureRunnerOption{PureRunnerWithAgent(agent)}, options...)
 	// WARNING: SSL creds are optional.
-	if tlsCfg == nil {
-		return NewPureRunner(cancel, addr, PureRunnerWithAgent(agent))
+	if tlsCfg != nil {
+		options = append(options, PureRunnerWithSSL(tlsCfg))
 	}
-	return NewPureRunner(cancel, addr, PureRunnerWithAgent(agent), PureRunnerWithSSL(tlsCfg))
+	return NewPureRunner(cancel, addr, options...)
 }
 
 type PureRunnerOption func(*pureRunner) error
@@ -966,6 +1029,16 @@ func PureRunnerWithKdumpsOnDisk(numKdumps uint64) PureRunnerOption {
 	}
 }
 
+// PureRunnerWithShutdownTimeout returns a PureRunnerOption that bounds how long
+// closing the PureRunner waits for the calls in flight to complete, before
+// cancelling them. 0, the default, waits for as long as they take.
+func PureRunnerWithShutdownTimeout(timeout time.Duration) PureRunnerOption {
+	return func(pr *pureRunner) error {
+		pr.shutdownTimeout = timeout
+		return nil
+	}
+}
+
 func PureRunnerWithDetached() PureRunnerOption {
 	return func(pr *pureRunner) error {
 		pr.AddCallListener(pr)
@@ -1024,3 +1097,4 @@ func NewPureRunner(cancel context.CancelFunc, addr string, options ...PureRunner
 
 var _ runner.RunnerProtocolServer = &pureRunner{}
 var _ Agent = &pureRunner{}
+var _ CallCanceler = &pureRunner{}
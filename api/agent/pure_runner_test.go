@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	runner "github.com/fnproject/fn/api/agent/grpc"
+	"google.golang.org/grpc"
+)
+
+// startDrainTestRunner serves a pure runner on a local port, with a call in
+// flight, engaged and waiting for its try message, which cancel ends
+func startDrainTestRunner(t *testing.T, shutdownTimeout time.Duration) (pr *pureRunner, cancel context.CancelFunc, conn *grpc.ClientConn) {
+	pr = &pureRunner{
+		a:               new(MockAgent),
+		status:          NewStatusTracker(),
+		callHandleMap:   make(map[string]*callHandle),
+		gRPCServer:      grpc.NewServer(),
+		shutdownTimeout: shutdownTimeout,
+	}
+	runner.RegisterRunnerProtocolServer(pr.gRPCServer, pr)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go pr.gRPCServer.Serve(lis)
+
+	conn, err = grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := runner.NewRunnerProtocolClient(conn).Engage(ctx); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&pr.status.inflight) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the call to be in flight")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return pr, cancel, conn
+}
+
+func TestPureRunnerDrainCompletesCalls(t *testing.T) {
+	pr, cancel, conn := startDrainTestRunner(t, 5*time.Second)
+	defer conn.Close()
+	defer cancel()
+
+	// the call completes while the runner drains
+	time.AfterFunc(50*time.Millisecond, cancel)
+	completed, terminated := pr.drain()
+	if completed != 1 || terminated != 0 {
+		t.Fatalf("expected the call in flight to complete, got %d completed and %d terminated", completed, terminated)
+	}
+}
+
+func TestPureRunnerDrainTerminatesCalls(t *testing.T) {
+	pr, cancel, conn := startDrainTestRunner(t, 50*time.Millisecond)
+	defer conn.Close()
+	defer cancel()
+
+	start := time.Now()
+	completed, terminated := pr.drain()
+	if completed != 0 || terminated != 1 {
+		t.Fatalf("expected the call in flight to be terminated, got %d completed and %d terminated", completed, terminated)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected the runner to stop at the shutdown timeout, took %v", elapsed)
+	}
+}
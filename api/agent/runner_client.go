@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"time"
 
@@ -58,7 +59,13 @@ func NewgRPCRunner(addr string, tlsConf *tls.Config, dialOpts ...grpc.DialOption
 }
 
 func NewgRPCRunnerWithTimeout(addr string, tlsConf *tls.Config, timeout time.Duration, dialOpts ...grpc.DialOption) (pool.Runner, error) {
-	conn, client, err := runnerConnection(addr, tlsConf, timeout, dialOpts...)
+	return newgRPCRunner(addr, tlsConf, timeout, nil, dialOpts...)
+}
+
+// newgRPCRunner connects to the runner at addr, resolving its host with
+// resolver if not nil
+func newgRPCRunner(addr string, tlsConf *tls.Config, timeout time.Duration, resolver *net.Resolver, dialOpts ...grpc.DialOption) (pool.Runner, error) {
+	conn, client, err := runnerConnection(addr, tlsConf, timeout, resolver, dialOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -72,7 +79,7 @@ func NewgRPCRunnerWithTimeout(addr string, tlsConf *tls.Config, timeout time.Dur
 
 }
 
-func runnerConnection(address string, tlsConf *tls.Config, timeout time.Duration, dialOpts ...grpc.DialOption) (*grpc.ClientConn, pb.RunnerProtocolClient, error) {
+func runnerConnection(address string, tlsConf *tls.Config, timeout time.Duration, resolver *net.Resolver, dialOpts ...grpc.DialOption) (*grpc.ClientConn, pb.RunnerProtocolClient, error) {
 
 	ctx := context.Background()
 	logger := common.Logger(ctx).WithField("runner_addr", address)
@@ -84,7 +91,7 @@ func runnerConnection(address string, tlsConf *tls.Config, timeout time.Duration
 	}
 
 	// we want to set a very short timeout to fail-fast if something goes wrong
-	conn, err := grpcutil.DialWithBackoff(ctx, address, creds, timeout, grpc.DefaultBackoffConfig, dialOpts...)
+	conn, err := grpcutil.DialWithResolver(ctx, address, creds, timeout, grpc.DefaultBackoffConfig, resolver, dialOpts...)
 	if err != nil {
 		logger.WithError(err).Error("Unable to connect to runner node")
 	}
@@ -186,6 +193,8 @@ func (r *gRPCRunner) TryExec(ctx context.Context, call pool.RunnerCall) (bool, e
 	}
 	defer r.shutWg.DoneSession()
 
+	setCallRunner(call, r.address)
+
 	// extract the call's model data to pass on to the pure runner
 	modelJSON, err := json.Marshal(call.Model())
 	if err != nil {
@@ -347,6 +356,10 @@ func recordFinishStats(ctx context.Context, msg *pb.CallFinished, c pool.RunnerC
 		statsLBAgentRunnerExecLatency(ctx, runnerExecLatency)
 		c.AddUserExecutionTime(runnerExecLatency)
 	}
+	if call, ok := c.(*call); ok {
+		call.timings.Queue = runnerSchedLatency
+		call.timings.Exec = runnerExecLatency
+	}
 }
 
 func cloneHeaders(src http.Header) http.Header {
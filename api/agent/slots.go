@@ -9,6 +9,7 @@ import (
 	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
@@ -53,12 +54,15 @@ type slotCaller struct {
 // with runner/waiter tracking for agent
 type slotQueue struct {
 	key       string
+	fnID      string
 	cond      *sync.Cond
 	slots     []*slotToken
 	nextId    uint64
 	signaller chan *slotCaller
-	statsLock sync.Mutex // protects stats below
+	statsLock sync.Mutex // protects stats and coldStarts below
 	stats     slotQueueStats
+	// the times containers started at within fnRuntimeWindow, oldest first
+	coldStarts []time.Time
 
 	authLock  sync.Mutex
 	authToken string
@@ -239,6 +243,10 @@ func (a *slotQueue) enterContainerState(conType ContainerStateType) {
 	if conType > ContainerStateNone && conType < ContainerStateMax {
 		a.statsLock.Lock()
 		a.stats.containerStates[conType] += 1
+		if conType == ContainerStateStart {
+			now := time.Now()
+			a.coldStarts = append(a.pruneColdStarts(now), now)
+		}
 		a.statsLock.Unlock()
 	}
 }
@@ -267,12 +275,13 @@ func (a *slotQueue) getAuthToken() string {
 
 // getSlot must ensure that if it receives a slot, it will be returned, otherwise
 // a container will be locked up forever waiting for slot to free.
-func (a *slotQueueMgr) getSlotQueue(key string) (*slotQueue, bool) {
+func (a *slotQueueMgr) getSlotQueue(key, fnID string) (*slotQueue, bool) {
 
 	a.hMu.Lock()
 	slots, ok := a.hot[key]
 	if !ok {
 		slots = NewSlotQueue(key)
+		slots.fnID = fnID
 		a.hot[key] = slots
 	}
 	a.hMu.Unlock()
@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/sirupsen/logrus"
+)
+
+// lookupSRV resolves DNS SRV names, replaced in tests
+var lookupSRV = net.DefaultResolver.LookupSRV
+
+// ResolveRunnerSRV returns the sorted addresses of the runners a DNS SRV name
+// points to, e.g. _fn-runner._tcp.runners.svc for the headless service of
+// the runners of a kubernetes cluster.
+func ResolveRunnerSRV(ctx context.Context, name string) ([]string, error) {
+	_, records, err := lookupSRV(ctx, "", "", name)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(records))
+	addrs := make([]string, 0, len(records))
+	for _, r := range records {
+		addr := net.JoinHostPort(strings.TrimSuffix(r.Target, "."), strconv.Itoa(int(r.Port)))
+		if !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Strings(addrs)
+	return addrs, nil
+}
+
+// WatchRunnerSRV resolves a DNS SRV name every interval until ctx is done,
+// and sets the runner addresses of rp to the ones it points to whenever they
+// change from addrs, the ones rp started with. Failed lookups are logged and
+// keep the last addresses resolved, so that DNS outages don't empty the pool.
+func WatchRunnerSRV(ctx context.Context, rp RunnerAddressSetter, name string, addrs []string, interval time.Duration) {
+	log := common.Logger(ctx).WithField("runner_srv", name)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		resolved, err := ResolveRunnerSRV(ctx, name)
+		if err != nil {
+			log.WithError(err).Warn("Failed to resolve runners, keeping the last ones")
+			continue
+		}
+
+		added, removed := diffAddresses(addrs, resolved)
+		if len(added) == 0 && len(removed) == 0 {
+			continue
+		}
+		log.WithFields(logrus.Fields{"added": added, "removed": removed}).Info("Runners changed")
+		rp.SetRunnerAddresses(ctx, resolved)
+		addrs = resolved
+	}
+}
+
+// diffAddresses returns the addresses of next not in prev, and of prev not in next
+func diffAddresses(prev, next []string) (added, removed []string) {
+	in := make(map[string]bool, len(prev))
+	for _, a := range prev {
+		in[a] = true
+	}
+	for _, a := range next {
+		if in[a] {
+			delete(in, a)
+		} else {
+			added = append(added, a)
+		}
+	}
+	for _, a := range prev {
+		if in[a] {
+			removed = append(removed, a)
+		}
+	}
+	return added, removed
+}
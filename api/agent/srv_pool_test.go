@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testAddressSetter struct {
+	mu   sync.Mutex
+	sets [][]string
+}
+
+func (s *testAddressSetter) SetRunnerAddresses(ctx context.Context, runnerAddresses []string) {
+	s.mu.Lock()
+	s.sets = append(s.sets, runnerAddresses)
+	s.mu.Unlock()
+}
+
+func (s *testAddressSetter) get() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]string(nil), s.sets...)
+}
+
+func TestWatchRunnerSRV(t *testing.T) {
+	defer func(f func(context.Context, string, string, string) (string, []*net.SRV, error)) { lookupSRV = f }(lookupSRV)
+
+	var mu sync.Mutex
+	answers := []struct {
+		records []*net.SRV
+		err     error
+	}{
+		{[]*net.SRV{{Target: "runner-1.runners.svc.", Port: 9190}, {Target: "runner-0.runners.svc.", Port: 9190}}, nil},
+		// unchanged, then a DNS outage, both keep the runners
+		{[]*net.SRV{{Target: "runner-0.runners.svc.", Port: 9190}, {Target: "runner-1.runners.svc.", Port: 9190}}, nil},
+		{nil, errors.New("no such host")},
+		{[]*net.SRV{{Target: "runner-1.runners.svc.", Port: 9190}, {Target: "runner-1.runners.svc.", Port: 9190}}, nil},
+	}
+	lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		if name != "_fn-runner._tcp.runners.svc" {
+			t.Errorf("expected the SRV name to be looked up, got %q", name)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(answers) == 0 {
+			return "", nil, errors.New("no more answers")
+		}
+		a := answers[0]
+		answers = answers[1:]
+		return "", a.records, a.err
+	}
+
+	addrs, err := ResolveRunnerSRV(context.Background(), "_fn-runner._tcp.runners.svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"runner-0.runners.svc:9190", "runner-1.runners.svc:9190"}
+	if !reflect.DeepEqual(addrs, expected) {
+		t.Fatalf("expected %v, got %v", expected, addrs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	setter := new(testAddressSetter)
+	done := make(chan struct{})
+	go func() {
+		WatchRunnerSRV(ctx, setter, "_fn-runner._tcp.runners.svc", addrs, time.Millisecond)
+		close(done)
+	}()
+
+	// only the last answer changes the runners
+	deadline := time.Now().Add(5 * time.Second)
+	for len(setter.get()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	// only the change is set, once the same addresses were resolved and a lookup failed
+	if sets := setter.get(); !reflect.DeepEqual(sets, [][]string{{"runner-1.runners.svc:9190"}}) {
+		t.Fatalf("expected the runners to be set once to runner-1, got %v", sets)
+	}
+}
+
+func TestDiffAddresses(t *testing.T) {
+	added, removed := diffAddresses([]string{"a:1", "b:1"}, []string{"b:1", "c:1"})
+	if !reflect.DeepEqual(added, []string{"c:1"}) || !reflect.DeepEqual(removed, []string{"a:1"}) {
+		t.Fatalf("expected c:1 added and a:1 removed, got %v %v", added, removed)
+	}
+}
@@ -3,6 +3,8 @@ package agent
 import (
 	"context"
 	"crypto/tls"
+	"net"
+	"sync"
 
 	pool "github.com/fnproject/fn/api/runnerpool"
 
@@ -11,8 +13,19 @@ import (
 	"google.golang.org/grpc"
 )
 
+// RunnerAddressSetter is implemented by runner pools whose runners can be
+// replaced while the pool is in use.
+type RunnerAddressSetter interface {
+	SetRunnerAddresses(ctx context.Context, runnerAddresses []string)
+}
+
 // manages a single set of runners ignoring lb groups
 type staticRunnerPool struct {
+	tlsConf  *tls.Config
+	resolver *net.Resolver
+	dialOpts []grpc.DialOption
+
+	mu      sync.RWMutex
 	runners []pool.Runner
 }
 
@@ -21,30 +34,89 @@ func DefaultStaticRunnerPool(runnerAddresses []string) pool.RunnerPool {
 }
 
 func NewStaticRunnerPool(runnerAddresses []string, tlsConf *tls.Config, dialOpts ...grpc.DialOption) pool.RunnerPool {
+	return NewStaticRunnerPoolWithResolver(runnerAddresses, tlsConf, nil, dialOpts...)
+}
+
+// NewStaticRunnerPoolWithResolver is NewStaticRunnerPool, resolving the hosts
+// of the runners with resolver rather than the default resolver of Go, if not
+// nil
+func NewStaticRunnerPoolWithResolver(runnerAddresses []string, tlsConf *tls.Config, resolver *net.Resolver, dialOpts ...grpc.DialOption) pool.RunnerPool {
 	logrus.WithField("runners", runnerAddresses).Info("Starting static runner pool")
+	rp := &staticRunnerPool{
+		tlsConf:  tlsConf,
+		resolver: resolver,
+		dialOpts: append(dialOpts, grpc.WithStatsHandler(new(ocgrpc.ClientHandler))),
+	}
+	for _, addr := range runnerAddresses {
+		if r := rp.newRunner(addr); r != nil {
+			rp.runners = append(rp.runners, r)
+		}
+	}
+	return rp
+}
+
+func (rp *staticRunnerPool) newRunner(addr string) pool.Runner {
+	r, err := newgRPCRunner(addr, rp.tlsConf, DefaultConnectTimeout, rp.resolver, rp.dialOpts...)
+	if err != nil {
+		logrus.WithError(err).WithField("runner_addr", addr).Warn("Invalid runner")
+		return nil
+	}
+	logrus.WithField("runner_addr", addr).Debug("Adding runner to pool")
+	return r
+}
+
+// SetRunnerAddresses implements RunnerAddressSetter. Runners still in the
+// list are kept, while the ones left out are closed once the calls they may
+// be running are done. Calls must not be concurrent.
+func (rp *staticRunnerPool) SetRunnerAddresses(ctx context.Context, runnerAddresses []string) {
+	rp.mu.RLock()
+	current := make(map[string]pool.Runner, len(rp.runners))
+	for _, r := range rp.runners {
+		current[r.Address()] = r
+	}
+	rp.mu.RUnlock()
+
+	// dial new runners without holding up calls
 	var runners []pool.Runner
-	dialOpts = append(dialOpts, grpc.WithStatsHandler(new(ocgrpc.ClientHandler)))
 	for _, addr := range runnerAddresses {
-		r, err := NewgRPCRunner(addr, tlsConf, dialOpts...)
-		if err != nil {
-			logrus.WithError(err).WithField("runner_addr", addr).Warn("Invalid runner")
-			continue
+		if r, ok := current[addr]; ok {
+			runners = append(runners, r)
+			delete(current, addr)
+		} else if r := rp.newRunner(addr); r != nil {
+			runners = append(runners, r)
 		}
-		logrus.WithField("runner_addr", addr).Debug("Adding runner to pool")
-		runners = append(runners, r)
 	}
-	return &staticRunnerPool{
-		runners: runners,
+
+	rp.mu.Lock()
+	rp.runners = runners
+	rp.mu.Unlock()
+
+	logrus.WithField("runners", runnerAddresses).Info("Updated static runner pool")
+	for _, r := range current {
+		go func(r pool.Runner) {
+			if err := r.Close(ctx); err != nil {
+				logrus.WithError(err).WithField("runner_addr", r.Address()).Error("Error closing runner")
+			}
+		}(r)
 	}
 }
 
 func (rp *staticRunnerPool) Runners(ctx context.Context, call pool.RunnerCall) ([]pool.Runner, error) {
+	rp.mu.RLock()
+	defer rp.mu.RUnlock()
 	r := make([]pool.Runner, len(rp.runners))
 	copy(r, rp.runners)
 	return r, nil
 }
 
+// ListRunners implements pool.RunnerLister
+func (rp *staticRunnerPool) ListRunners(ctx context.Context) ([]pool.Runner, error) {
+	return rp.Runners(ctx, nil)
+}
+
 func (rp *staticRunnerPool) Shutdown(ctx context.Context) error {
+	rp.mu.RLock()
+	defer rp.mu.RUnlock()
 	var retErr error
 	for _, r := range rp.runners {
 		err := r.Close(ctx)
@@ -46,3 +46,27 @@ func TestEmptyPool(t *testing.T) {
 		t.Fatalf("Unexpected error from shutdown %v", err)
 	}
 }
+
+func TestSetRunnerAddresses(t *testing.T) {
+	// TEST-NET-1 unreachable
+	np := setupStaticPool([]string{"192.0.2.255:8080", "192.0.2.255:8081"})
+	before, _ := np.Runners(context.Background(), nil)
+
+	np.(RunnerAddressSetter).SetRunnerAddresses(context.Background(), []string{"192.0.2.255:8081", "192.0.2.255:8082"})
+
+	runners, err := np.Runners(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Failed to list runners %v", err)
+	}
+	if len(runners) != 2 || runners[0].Address() != "192.0.2.255:8081" || runners[1].Address() != "192.0.2.255:8082" {
+		t.Fatalf("Unexpected runners after update %v", runners)
+	}
+	if runners[0] != before[1] {
+		t.Fatalf("Expected runner kept in the update to be reused")
+	}
+
+	err = np.Shutdown(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error from shutdown %v", err)
+	}
+}
@@ -17,6 +17,7 @@ var (
 	containerStateKey    = common.MakeKey("container_state")
 	callStatusKey        = common.MakeKey("call_status")
 	containerUDSStateKey = common.MakeKey("container_uds_state")
+	callStartKey         = common.MakeKey("call_start")
 
 	// tri-state values below: error/true/false
 	statusCallCacheKey    = common.MakeKey("cached")
@@ -106,6 +107,41 @@ func statsContainerEvicted(ctx context.Context, containerState string) {
 	stats.Record(ctx, containerEvictedMeasure.M(0))
 }
 
+// statsCallStart records whether a call got a slot of a container started for
+// it (cold) or of a container which already ran calls (warm), and how long a
+// cold container took to start
+func statsCallStart(ctx context.Context, call *call, slot Slot) {
+	start, coldStart := "warm", time.Duration(0)
+	if s, ok := slot.(*hotSlot); ok && s.coldStart > 0 {
+		start, coldStart = "cold", s.coldStart
+	}
+
+	ctx, err := tag.New(ctx,
+		tag.Upsert(callStartKey, start),
+		tag.Upsert(AppIDMetricKey, call.AppID),
+		tag.Upsert(FnIDMetricKey, call.FnID),
+	)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	stats.Record(ctx, callStartsMeasure.M(1))
+	if coldStart > 0 {
+		stats.Record(ctx, coldStartLatencyMeasure.M(int64(coldStart/time.Millisecond)))
+	}
+}
+
+func statsContainerWarm(ctx context.Context, call *call, delta int64) {
+	ctx, err := tag.New(ctx,
+		tag.Upsert(AppIDMetricKey, call.AppID),
+	)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	stats.Record(ctx, containerWarmMeasure.M(delta))
+}
+
 func statsUtilization(ctx context.Context, util ResourceUtilization) {
 	stats.Record(ctx, utilCpuUsedMeasure.M(int64(util.CpuUsed)))
 	stats.Record(ctx, utilCpuAvailMeasure.M(int64(util.CpuAvail)))
@@ -170,6 +206,13 @@ const (
 	errorsMetricName     = "errors"
 	serverBusyMetricName = "server_busy"
 
+	callStartsMetricName       = "call_starts"
+	coldStartLatencyMetricName = "cold_start_latency"
+	coldStartWaitMetricName    = "cold_start_wait"
+	containerWarmMetricName    = "containers_warm"
+	warmPoolMetricName         = "warm_pool_containers"
+	warmPoolTargetMetricName   = "warm_pool_target"
+
 	containerEvictedMetricName        = "container_evictions"
 	containerUDSInitLatencyMetricName = "container_uds_init_latency"
 
@@ -178,6 +221,8 @@ const (
 	utilMemUsedMetricName  = "util_mem_used"
 	utilMemAvailMetricName = "util_mem_avail"
 
+	cpuUtilizationMetricName = "cpu_utilization"
+
 	// Reported By LB
 	runnerSchedLatencyMetricName = "lb_runner_sched_latency"
 	runnerExecLatencyMetricName  = "lb_runner_exec_latency"
@@ -196,6 +241,12 @@ var (
 	timedoutMeasure                = common.MakeMeasure(timedoutMetricName, "calls timed out in agent", "")
 	errorsMeasure                  = common.MakeMeasure(errorsMetricName, "calls errored in agent", "")
 	serverBusyMeasure              = common.MakeMeasure(serverBusyMetricName, "calls where server was too busy in agent", "")
+	callStartsMeasure              = common.MakeMeasure(callStartsMetricName, "calls started in agent, on cold or warm containers", "")
+	coldStartLatencyMeasure        = common.MakeMeasure(coldStartLatencyMetricName, "time to start containers for cold calls", "msecs")
+	coldStartWaitMeasure           = common.MakeMeasure(coldStartWaitMetricName, "time containers waited to start, past the max concurrent cold starts", "msecs")
+	containerWarmMeasure           = common.MakeMeasure(containerWarmMetricName, "hot containers currently started in agent", "")
+	warmPoolMeasure                = common.MakeMeasure(warmPoolMetricName, "idle or starting hot containers of fns kept warm by agent", "")
+	warmPoolTargetMeasure          = common.MakeMeasure(warmPoolTargetMetricName, "idle hot containers agent keeps warm for fns", "")
 	dockerMeasures                 = initDockerMeasures()
 	containerGaugeMeasures         = initContainerGaugeMeasures()
 	containerTimeMeasures          = initContainerTimeMeasures()
@@ -203,6 +254,7 @@ var (
 	utilCpuAvailMeasure            = common.MakeMeasure(utilCpuAvailMetricName, "agent cpu available", "")
 	utilMemUsedMeasure             = common.MakeMeasure(utilMemUsedMetricName, "agent memory in use", "By")
 	utilMemAvailMeasure            = common.MakeMeasure(utilMemAvailMetricName, "agent memory available", "By")
+	cpuUtilizationMeasure          = common.MakeMeasure(cpuUtilizationMetricName, "CPU utilization of the node averaged over about 10 seconds, sampled if FN_MAX_CPU_PERCENT is set", "%")
 	containerEvictedMeasure        = common.MakeMeasure(containerEvictedMetricName, "containers evicted", "")
 	containerUDSInitLatencyMeasure = common.MakeMeasure(containerUDSInitLatencyMetricName, "container UDS Init-Wait Latency", "msecs")
 
@@ -251,8 +303,59 @@ func RegisterAgentViews(tagKeys []string, latencyDist []float64) {
 		common.CreateView(utilCpuAvailMeasure, view.LastValue(), tagKeys),
 		common.CreateView(utilMemUsedMeasure, view.LastValue(), tagKeys),
 		common.CreateView(utilMemAvailMeasure, view.LastValue(), tagKeys),
+		common.CreateView(cpuUtilizationMeasure, view.LastValue(), tagKeys),
+		common.CreateView(coldStartWaitMeasure, view.Distribution(latencyDist...), tagKeys),
 	)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot register view")
+	}
 
+	// add call start, app and fn tags for cold and warm starts
+	callStartTags := make([]string, 0, len(tagKeys)+3)
+	callStartTags = append(callStartTags, "call_start", "app_id", "fn_id")
+	for _, key := range tagKeys {
+		if key != "call_start" && key != "app_id" && key != "fn_id" {
+			callStartTags = append(callStartTags, key)
+		}
+	}
+
+	err = view.Register(
+		common.CreateView(callStartsMeasure, view.Count(), callStartTags),
+		common.CreateView(coldStartLatencyMeasure, view.Distribution(latencyDist...), callStartTags),
+	)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot register view")
+	}
+
+	// add app tag for warm containers
+	warmTags := make([]string, 0, len(tagKeys)+1)
+	warmTags = append(warmTags, "app_id")
+	for _, key := range tagKeys {
+		if key != "app_id" {
+			warmTags = append(warmTags, key)
+		}
+	}
+
+	err = view.Register(
+		common.CreateView(containerWarmMeasure, view.Sum(), warmTags),
+	)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot register view")
+	}
+
+	// add app and fn tags for the fns kept warm
+	warmPoolTags := make([]string, 0, len(tagKeys)+2)
+	warmPoolTags = append(warmPoolTags, "app_id", "fn_id")
+	for _, key := range tagKeys {
+		if key != "app_id" && key != "fn_id" {
+			warmPoolTags = append(warmPoolTags, key)
+		}
+	}
+
+	err = view.Register(
+		common.CreateView(warmPoolMeasure, view.LastValue(), warmPoolTags),
+		common.CreateView(warmPoolTargetMeasure, view.LastValue(), warmPoolTags),
+	)
 	if err != nil {
 		logrus.WithError(err).Fatal("cannot register view")
 	}
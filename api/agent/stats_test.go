@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+
+	"go.opencensus.io/stats/view"
+)
+
+var registerViews sync.Once
+
+func registerAgentViews() {
+	registerViews.Do(func() { RegisterAgentViews(nil, []float64{1, 10, 100, 1000}) })
+}
+
+func callStarts(t *testing.T, start string) int64 {
+	rows, err := view.RetrieveData(callStartsMetricName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		for _, tag := range row.Tags {
+			if tag.Key == callStartKey && tag.Value == start {
+				return row.Data.(*view.CountData).Value
+			}
+		}
+	}
+	return 0
+}
+
+func TestStatsCallStart(t *testing.T) {
+	registerAgentViews()
+
+	call := &call{Call: &models.Call{AppID: "app_id", FnID: "fn_id"}}
+	ctx := context.Background()
+
+	// a container's first slot is cold, the following ones are warm
+	statsCallStart(ctx, call, &hotSlot{coldStart: 250 * time.Millisecond})
+	if cold, warm := callStarts(t, "cold"), callStarts(t, "warm"); cold != 1 || warm != 0 {
+		t.Fatalf("expected a cold start, got %d cold and %d warm", cold, warm)
+	}
+	statsCallStart(ctx, call, &hotSlot{})
+	if cold, warm := callStarts(t, "cold"), callStarts(t, "warm"); cold != 1 || warm != 1 {
+		t.Fatalf("expected a warm start, got %d cold and %d warm", cold, warm)
+	}
+
+	rows, err := view.RetrieveData(coldStartLatencyMetricName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Data.(*view.DistributionData).Count != 1 {
+		t.Fatalf("expected one cold start latency, got %v", rows)
+	}
+}
+
+func TestStatsContainerWarm(t *testing.T) {
+	registerAgentViews()
+	call := &call{Call: &models.Call{AppID: "app_id"}}
+	ctx := context.Background()
+
+	statsContainerWarm(ctx, call, 1)
+	statsContainerWarm(ctx, call, 1)
+	statsContainerWarm(ctx, call, -1)
+
+	rows, err := view.RetrieveData(containerWarmMetricName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Data.(*view.SumData).Value != 1 {
+		t.Fatalf("expected one warm container, got %v", rows)
+	}
+}
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+// WithUpgrade lets the call switch the connection of its client to protocol,
+// e.g. websocket, if its function answers with a 101 Switching Protocols.
+// The connection is then hijacked from hj and piped as is to and from the
+// container until either side closes it or the call times out. Only hot
+// containers of full nodes can be upgraded to, runners ignore this.
+func WithUpgrade(protocol string, hj http.Hijacker) CallOpt {
+	return func(c *call) error {
+		c.upgrade = protocol
+		c.upgrader = hj
+		return nil
+	}
+}
+
+// setUpgradeHeaders turns the request of a call to a container into an
+// upgrade request, as the hop headers of the client were stripped from it
+func setUpgradeHeaders(req *http.Request, protocol string) {
+	req.Method = http.MethodGet
+	req.Body = nil
+	req.GetBody = nil
+	req.ContentLength = 0
+
+	// the handshake headers of trigger calls are prefixed, as all others,
+	// but servers expect them as is
+	for k, vs := range req.Header {
+		if strings.HasPrefix(k, "Fn-Http-H-Sec-Websocket-") {
+			req.Header[strings.TrimPrefix(k, "Fn-Http-H-")] = vs
+		}
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", protocol)
+}
+
+// proxyUpgrade writes the 101 response of a container to the client and
+// pipes their connections, without buffering, until one of them closes or
+// ctx is done
+func proxyUpgrade(ctx context.Context, call *call, resp *http.Response) error {
+	backend, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		return models.ErrFunctionInvalidResponse
+	}
+
+	conn, brw, err := call.upgrader.Hijack()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// the deadlines of the server don't hold for the upgraded connection,
+	// the timeout of the call does
+	conn.SetDeadline(time.Time{})
+
+	resp.Header.Set("Fn-Call-Id", call.ID)
+	fmt.Fprintf(brw, "HTTP/1.1 %s\r\n", resp.Status)
+	resp.Header.Write(brw)
+	brw.WriteString("\r\n")
+	if err := brw.Flush(); err != nil {
+		backend.Close()
+		return err
+	}
+
+	errc := make(chan error, 2)
+	go func() {
+		// read from brw, the client may have sent frames with its request
+		_, err := io.Copy(backend, brw)
+		errc <- err
+	}()
+	go func() {
+		_, err := io.Copy(conn, backend)
+		errc <- err
+	}()
+
+	pending := 2
+	select {
+	case err = <-errc:
+		pending--
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	// unblock the other side
+	conn.Close()
+	backend.Close()
+	for ; pending > 0; pending-- {
+		<-errc
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		// either side going away ends the call, it is not an error of the function
+		common.Logger(ctx).WithError(err).Debug("upgraded connection closed")
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+type testHijacker struct {
+	conn net.Conn
+}
+
+func (h *testHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return h.conn, bufio.NewReadWriter(bufio.NewReader(h.conn), bufio.NewWriter(h.conn)), nil
+}
+
+func TestProxyUpgrade(t *testing.T) {
+	client, server := net.Pipe()
+	container, backend := net.Pipe()
+	defer client.Close()
+	defer container.Close()
+
+	c := &call{Call: &models.Call{ID: "call_id"}, upgrader: &testHijacker{server}}
+	resp := &http.Response{
+		Status:     "101 Switching Protocols",
+		StatusCode: http.StatusSwitchingProtocols,
+		Header:     http.Header{"Upgrade": {"websocket"}, "Connection": {"Upgrade"}},
+		Body:       backend,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	errc := make(chan error, 1)
+	go func() { errc <- proxyUpgrade(ctx, c, resp) }()
+
+	br := bufio.NewReader(client)
+	switched, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if switched.StatusCode != http.StatusSwitchingProtocols || switched.Header.Get("Fn-Call-Id") != "call_id" {
+		t.Fatalf("expected a 101 for call_id, got %d %v", switched.StatusCode, switched.Header)
+	}
+
+	go client.Write([]byte("ping"))
+	b := make([]byte, 4)
+	if _, err := io.ReadFull(container, b); err != nil || string(b) != "ping" {
+		t.Fatalf("expected the container to read ping, got %q %v", b, err)
+	}
+	go container.Write([]byte("pong"))
+	if _, err := io.ReadFull(br, b); err != nil || string(b) != "pong" {
+		t.Fatalf("expected the client to read pong, got %q %v", b, err)
+	}
+
+	client.Close()
+	if err := <-errc; err != nil {
+		t.Fatalf("expected the proxy to end once the client left, got %v", err)
+	}
+}
+
+func TestProxyUpgradeTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	container, backend := net.Pipe()
+	defer client.Close()
+	defer container.Close()
+	go io.Copy(ioutil.Discard, client)
+
+	c := &call{Call: &models.Call{ID: "call_id"}, upgrader: &testHijacker{server}}
+	resp := &http.Response{
+		Status:     "101 Switching Protocols",
+		StatusCode: http.StatusSwitchingProtocols,
+		Header:     http.Header{},
+		Body:       backend,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := proxyUpgrade(ctx, c, resp); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
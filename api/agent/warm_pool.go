@@ -0,0 +1,169 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+// how often the idle containers of the warm targets are counted, and
+// replenished if they are under target
+const warmPoolInterval = time.Second
+
+// MinWarmer is implemented by agents that keep idle hot containers warm for
+// fns, see models.AppMinWarmAnnotation
+type MinWarmer interface {
+	// SetMinWarm replaces the fns kept warm
+	SetMinWarm(targets []WarmTarget)
+}
+
+// WarmTarget is a fn, and the number of idle hot containers kept warm for it
+type WarmTarget struct {
+	App *models.App
+	Fn  *models.Fn
+	Min int
+}
+
+var _ MinWarmer = &agent{}
+
+type warmPool struct {
+	mu      sync.Mutex
+	targets []WarmTarget
+	once    sync.Once
+}
+
+func (p *warmPool) get() []WarmTarget {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.targets
+}
+
+// SetMinWarm implements MinWarmer. Idle containers are kept warm for the
+// targets, in order, until FN_MAX_WARM_CONTAINERS of them are, starting them
+// as resources allow. They are otherwise like any hot container: they serve
+// any call of their fn, and idle out or get evicted as others do, and are then
+// replaced.
+func (a *agent) SetMinWarm(targets []WarmTarget) {
+	if a.cfg.MaxWarmContainers == 0 {
+		return
+	}
+
+	a.warm.mu.Lock()
+	kept := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		kept[t.Fn.ID] = true
+	}
+	for _, t := range a.warm.targets {
+		if !kept[t.Fn.ID] {
+			statsWarmPool(context.Background(), t.App.ID, t.Fn.ID, 0, 0)
+		}
+	}
+	a.warm.targets = targets
+	a.warm.mu.Unlock()
+
+	a.warm.once.Do(func() { go a.warmPoolLoop() })
+}
+
+func (a *agent) warmPoolLoop() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ticker := time.NewTicker(warmPoolInterval)
+	defer ticker.Stop()
+	for {
+		a.replenishWarm(ctx)
+		select {
+		case <-ticker.C:
+		case <-a.shutWg.Closer():
+			return
+		}
+	}
+}
+
+// replenishWarm starts containers for the warm targets with fewer idle, or
+// starting, containers than their min
+func (a *agent) replenishWarm(ctx context.Context) {
+	budget := int(a.cfg.MaxWarmContainers)
+	for _, t := range a.warm.get() {
+		min := t.Min
+		if min > budget {
+			min = budget
+		}
+		budget -= min
+
+		call, err := a.warmCall(t)
+		if err != nil {
+			common.Logger(ctx).WithError(err).WithFields(logrus.Fields{"app_id": t.App.ID, "fn_id": t.Fn.ID}).Error("cannot keep containers warm for fn")
+			continue
+		}
+
+		cur := call.slots.getStats()
+		warm := int(cur.containerStates[ContainerStateIdle] + cur.containerStates[ContainerStatePaused] +
+			cur.containerStates[ContainerStateStart] + cur.containerStates[ContainerStateWait])
+		statsWarmPool(ctx, t.App.ID, t.Fn.ID, warm, min)
+
+		for ; warm < min; warm++ {
+			if !a.launchWarm(ctx, call) {
+				break
+			}
+		}
+	}
+}
+
+// warmCall returns a call to the fn of t, with the slot queue its calls get
+func (a *agent) warmCall(t WarmTarget) (*call, error) {
+	req, err := http.NewRequest(http.MethodPost, "/invoke/"+t.Fn.ID, nil)
+	if err != nil {
+		return nil, err
+	}
+	ci, err := a.GetCall(FromHTTPFnRequest(t.App, t.Fn, req), WithLogger(common.NoopReadWriteCloser{}))
+	if err != nil {
+		return nil, err
+	}
+	c := ci.(*call)
+
+	c.slotHashId = getSlotQueueKey(c, a.driver.GetSlotKeyExtensions(c.Extensions()))
+	var isNew bool
+	c.slots, isNew = a.slotMgr.getSlotQueue(c.slotHashId, c.FnID)
+	if isNew {
+		// launches containers for the calls of the fn, as getSlot does
+		go a.hotLauncher(context.Background(), c, &slotCaller{id: c.ID})
+	}
+	return c, nil
+}
+
+// launchWarm starts a container for call if there are the resources for it,
+// without evicting others, and returns whether it did
+func (a *agent) launchWarm(ctx context.Context, call *call) bool {
+	tok := a.resources.GetResourceTokenNB(ctx, call.Memory+uint64(call.TmpFsSize), call.CPUs)
+	if tok.Error() != nil || !a.shutWg.AddSession(1) {
+		tok.Close()
+		return false
+	}
+
+	state := NewContainerState()
+	state.UpdateState(ctx, ContainerStateWait, call)
+	go func() {
+		a.runHot(ctx, slotCaller{id: call.ID}, call, tok, state)
+		a.shutWg.DoneSession()
+	}()
+	return true
+}
+
+func statsWarmPool(ctx context.Context, appID, fnID string, warm, min int) {
+	ctx, err := tag.New(ctx,
+		tag.Upsert(AppIDMetricKey, appID),
+		tag.Upsert(FnIDMetricKey, fnID),
+	)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	stats.Record(ctx, warmPoolMeasure.M(int64(warm)), warmPoolTargetMeasure.M(int64(min)))
+}
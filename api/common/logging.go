@@ -1,6 +1,10 @@
 package common
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/url"
 	"os"
 	"strings"
@@ -46,10 +50,51 @@ func SetLogLevel(ll string) {
 	}
 }
 
+// SetLogDest sends logs to the destination to, see SetLogDests
 func SetLogDest(to, prefix string) {
+	SetLogDests([]string{to}, prefix)
+}
+
+// SetLogDests sends logs to every destination of dsts, e.g. to both a file
+// and syslog. Destinations that can't be set up are skipped, logs go to
+// stderr if none can. Each destination is written to independently: one
+// failing, e.g. a full disk or an unreachable syslog, is reported on stderr
+// without dropping the lines sent to the others.
+func SetLogDests(dsts []string, prefix string) {
 	logrus.SetOutput(os.Stderr) // in case logrus changes their mind...
+
+	var writers []io.Writer
+	var hooks int
+	for _, to := range dsts {
+		if to == "" {
+			continue
+		}
+		w, hook := openLogDest(to, prefix)
+		if w != nil {
+			writers = append(writers, w)
+		}
+		if hook != nil {
+			logrus.AddHook(isolatedHook{hook})
+			hooks++
+		}
+	}
+
+	switch {
+	case len(writers) == 1:
+		logrus.SetOutput(writers[0])
+	case len(writers) > 1:
+		logrus.SetOutput(&fanOutWriter{writers: writers})
+	case hooks > 0:
+		// syslog only
+		logrus.SetOutput(ioutil.Discard)
+	}
+}
+
+// openLogDest returns the writer or the hook to send logs to the destination
+// to, or neither if it can't be set up
+func openLogDest(to, prefix string) (io.Writer, logrus.Hook) {
 	if to == "stderr" {
-		return
+		return os.Stderr, nil
 	}
 
 	// possible schemes: { udp, tcp, file }
@@ -65,33 +110,69 @@ func SetLogDest(to, prefix string) {
 		parsed, err = url.Parse(to)
 	}
 	if err != nil {
-		logrus.WithError(err).WithFields(logrus.Fields{"to": to}).Error("could not parse logging URI, defaulting to stderr")
-		return
+		logrus.WithError(err).WithFields(logrus.Fields{"to": to}).Error("could not parse logging URI, skipping it")
+		return nil, nil
 	}
 
 	// File URL must contain only `url.Path`. Syslog location must contain only `url.Host`
 	if (parsed.Host == "" && parsed.Path == "") || (parsed.Host != "" && parsed.Path != "") {
-		logrus.WithFields(logrus.Fields{"to": to, "uri": parsed}).Error("invalid logging location, defaulting to stderr")
-		return
+		logrus.WithFields(logrus.Fields{"to": to, "uri": parsed}).Error("invalid logging location, skipping it")
+		return nil, nil
 	}
 
 	switch parsed.Scheme {
 	case "udp", "tcp":
-		err = NewSyslogHook(parsed, prefix)
+		hook, err := newSyslogHook(parsed, prefix)
 		if err != nil {
-			logrus.WithFields(logrus.Fields{"uri": parsed, "to": to}).WithError(err).Error("unable to connect to syslog, defaulting to stderr")
-			return
+			logrus.WithFields(logrus.Fields{"uri": parsed, "to": to}).WithError(err).Error("unable to connect to syslog, skipping it")
+			return nil, nil
 		}
+		return nil, hook
 	case "file":
 		f, err := os.OpenFile(parsed.Path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
 		if err != nil {
-			logrus.WithError(err).WithFields(logrus.Fields{"to": to, "path": parsed.Path}).Error("cannot open file, defaulting to stderr")
-			return
+			logrus.WithError(err).WithFields(logrus.Fields{"to": to, "path": parsed.Path}).Error("cannot open file, skipping it")
+			return nil, nil
 		}
-		logrus.SetOutput(f)
+		return f, nil
 	default:
-		logrus.WithFields(logrus.Fields{"scheme": parsed.Scheme, "to": to}).Error("unknown logging location scheme, defaulting to stderr")
+		logrus.WithFields(logrus.Fields{"scheme": parsed.Scheme, "to": to}).Error("unknown logging location scheme, skipping it")
+		return nil, nil
+	}
+}
+
+// fanOutWriter writes each log line to all of its writers, carrying on past
+// the ones that fail
+type fanOutWriter struct {
+	writers []io.Writer
+}
+
+func (f *fanOutWriter) Write(p []byte) (int, error) {
+	var failed int
+	for _, w := range f.writers {
+		if _, err := w.Write(p); err != nil {
+			// logrus holds its lock while writing, it can't log this
+			fmt.Fprintf(os.Stderr, "Failed to write to log destination: %v\n", err)
+			failed++
+		}
+	}
+	if failed == len(f.writers) {
+		return 0, errors.New("failed to write to every log destination")
+	}
+	return len(p), nil
+}
+
+// isolatedHook reports the errors of a hook on stderr rather than returning
+// them, as logrus stops firing the hooks of a line at the first error
+type isolatedHook struct {
+	logrus.Hook
+}
+
+func (h isolatedHook) Fire(entry *logrus.Entry) error {
+	if err := h.Hook.Fire(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write to log destination: %v\n", err)
 	}
+	return nil
 }
 
 // MaskPassword returns a stringified URL without its password visible
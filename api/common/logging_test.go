@@ -0,0 +1,62 @@
+package common
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestSetLogDests(t *testing.T) {
+	defer logrus.SetOutput(os.Stderr)
+
+	dir, err := ioutil.TempDir("", "fn-logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	first, second := filepath.Join(dir, "first.log"), filepath.Join(dir, "second.log")
+
+	// the destination that can't be set up doesn't stop the others
+	SetLogDests([]string{"file://" + first, "bogus://nope", "file://" + second}, "")
+	logrus.Info("to both files")
+	logrus.SetOutput(os.Stderr)
+
+	for _, path := range []string{first, second} {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(b), "to both files") {
+			t.Errorf("expected %s to have the log line, got %q", path, b)
+		}
+	}
+}
+
+func TestFanOutWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &fanOutWriter{writers: []io.Writer{failingWriter{}, &buf}}
+
+	// a failing destination doesn't drop the lines of the others
+	if n, err := w.Write([]byte("line\n")); err != nil || n != 5 {
+		t.Fatalf("expected the line to be written, got %d %v", n, err)
+	}
+	if buf.String() != "line\n" {
+		t.Fatalf("expected the line to be written to the working destination, got %q", buf.String())
+	}
+
+	w = &fanOutWriter{writers: []io.Writer{failingWriter{}, failingWriter{}}}
+	if _, err := w.Write([]byte("line\n")); err == nil {
+		t.Fatal("expected an error when every destination fails")
+	}
+}
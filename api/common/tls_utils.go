@@ -8,6 +8,10 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 // NewTLSSimple creates a new tls config with the given cert and key file paths
@@ -83,6 +87,133 @@ func AddCA(tlsConf *tls.Config, caPath string) error {
 	return nil
 }
 
+// NewTLSReloading creates a new tls config with the given cert and key file
+// paths which, if clientCAPath isn't empty, also requires client certs issued
+// by the CA of clientCAPath. The files are loaded again on the first handshake
+// after any of them changes on disk, so that rotated certs are used by new
+// handshakes without a restart. If the files can't be loaded, e.g. as the cert
+// was replaced but not yet its key, the previous ones are used until they can.
+func NewTLSReloading(certPath, keyPath, clientCAPath string) (*tls.Config, error) {
+	for _, path := range []string{certPath, keyPath, clientCAPath} {
+		if path == "" {
+			continue
+		}
+		if err := checkFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	r := &tlsReloader{certPath: certPath, keyPath: keyPath, clientCAPath: clientCAPath}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	tlsConf := &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			return cert, nil
+		},
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			return cert, nil
+		},
+	}
+	if clientCAPath != "" {
+		// the client certs are verified against the current CA below, rather
+		// than against a ClientCAs pool fixed for the life of the config
+		tlsConf.ClientAuth = tls.RequireAnyClientCert
+		tlsConf.VerifyPeerCertificate = r.verifyClientCert
+	}
+	return tlsConf, nil
+}
+
+type tlsReloader struct {
+	certPath, keyPath, clientCAPath string
+
+	mu        sync.Mutex
+	modTimes  [3]time.Time
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+// current returns the cert and client CAs to use, loading them again if
+// their files changed
+func (r *tlsReloader) current() (*tls.Certificate, *x509.CertPool) {
+	if err := r.load(); err != nil {
+		logrus.WithError(err).WithField("cert", r.certPath).Error("Failed to reload TLS certificates, using the previous ones")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cert, r.clientCAs
+}
+
+func (r *tlsReloader) load() error {
+	var modTimes [3]time.Time
+	for i, path := range []string{r.certPath, r.keyPath, r.clientCAPath} {
+		if path == "" {
+			continue
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		modTimes[i] = fi.ModTime()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cert != nil && modTimes == r.modTimes {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return fmt.Errorf("Could not load server key pair: %s", err)
+	}
+	var clientCAs *x509.CertPool
+	if r.clientCAPath != "" {
+		authority, err := ioutil.ReadFile(filepath.Clean(r.clientCAPath))
+		if err != nil {
+			return fmt.Errorf("Could not read client CA (%s) certificate: %s", r.clientCAPath, err)
+		}
+		clientCAs = x509.NewCertPool()
+		if ok := clientCAs.AppendCertsFromPEM(authority); !ok {
+			return errors.New("Failed to append client certs")
+		}
+	}
+
+	r.cert, r.clientCAs, r.modTimes = &cert, clientCAs, modTimes
+	return nil
+}
+
+// verifyClientCert verifies the chain of a client cert against the current
+// client CAs, as tls.RequireAndVerifyClientCert does with ClientCAs
+func (r *tlsReloader) verifyClientCert(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return errors.New("no client certificate")
+	}
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse client certificate: %v", err)
+		}
+		certs[i] = cert
+	}
+
+	_, clientCAs := r.current()
+	opts := x509.VerifyOptions{
+		Roots:         clientCAs,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(opts)
+	return err
+}
+
 func checkFile(path string) error {
 	absPath, err := filepath.Abs(path)
 	if err != nil {
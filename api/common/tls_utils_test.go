@@ -0,0 +1,153 @@
+package common
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	certPEM []byte
+	keyPEM  []byte
+	key     *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, serial int64, parent *testCert, usage x509.ExtKeyUsage) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "fn test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{
+		cert:    cert,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		key:     key,
+	}
+}
+
+func writeTestFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTLSReloading(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fn-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certPath, keyPath, caPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), filepath.Join(dir, "ca.pem")
+
+	ca := newTestCert(t, 1, nil, x509.ExtKeyUsageAny)
+	first := newTestCert(t, 2, ca, x509.ExtKeyUsageServerAuth)
+	second := newTestCert(t, 3, ca, x509.ExtKeyUsageServerAuth)
+	client := newTestCert(t, 4, ca, x509.ExtKeyUsageClientAuth)
+
+	now := time.Now()
+	writeTestFile(t, certPath, first.certPEM, now)
+	writeTestFile(t, keyPath, first.keyPEM, now)
+	writeTestFile(t, caPath, ca.certPEM, now)
+
+	serverConf, err := NewTLSReloading(certPath, keyPath, caPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := tls.Listen("tcp", "127.0.0.1:0", serverConf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientCert, err := tls.X509KeyPair(client.certPEM, client.keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handshake := func(certs ...tls.Certificate) (int64, error) {
+		conn, err := tls.Dial("tcp", l.Addr().String(), &tls.Config{RootCAs: roots, Certificates: certs})
+		if err != nil {
+			return 0, err
+		}
+		defer conn.Close()
+		// the server only rejects the client cert after the client handshake completes
+		if _, err := conn.Read(make([]byte, 1)); err != nil && err != io.EOF {
+			return 0, err
+		}
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64(), nil
+	}
+
+	if serial, err := handshake(clientCert); err != nil || serial != 2 {
+		t.Fatalf("expected the first cert, got %d %v", serial, err)
+	}
+	if _, err := handshake(); err == nil {
+		t.Fatal("expected a handshake with no client cert to fail")
+	}
+
+	// half way through a rotation, the previous cert keeps being used
+	later := now.Add(time.Minute)
+	writeTestFile(t, certPath, second.certPEM, later)
+	if serial, err := handshake(clientCert); err != nil || serial != 2 {
+		t.Fatalf("expected the first cert, got %d %v", serial, err)
+	}
+
+	writeTestFile(t, keyPath, second.keyPEM, later)
+	if serial, err := handshake(clientCert); err != nil || serial != 3 {
+		t.Fatalf("expected the rotated cert, got %d %v", serial, err)
+	}
+}
@@ -11,12 +11,15 @@ import (
 )
 
 func NewSyslogHook(url *url.URL, prefix string) error {
-	syslog, err := logrus_syslog.NewSyslogHook(url.Scheme, url.Host, 0, prefix)
+	syslog, err := newSyslogHook(url, prefix)
 	if err != nil {
 		return err
 	}
 	logrus.AddHook(syslog)
-	// TODO we could support multiple destinations...
 	logrus.SetOutput(ioutil.Discard)
 	return nil
 }
+
+func newSyslogHook(url *url.URL, prefix string) (logrus.Hook, error) {
+	return logrus_syslog.NewSyslogHook(url.Scheme, url.Host, 0, prefix)
+}
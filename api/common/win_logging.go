@@ -5,8 +5,14 @@ package common
 import (
 	"errors"
 	"net/url"
+
+	"github.com/sirupsen/logrus"
 )
 
 func NewSyslogHook(url *url.URL, prefix string) error {
 	return errors.New("Syslog not supported on this system.")
 }
+
+func newSyslogHook(url *url.URL, prefix string) (logrus.Hook, error) {
+	return nil, errors.New("Syslog not supported on this system.")
+}
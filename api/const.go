@@ -9,6 +9,8 @@ const (
 
 	// TriggerID is the url path parameter for trigger id
 	TriggerID string = "trigger_id"
+	// TokenID is the url path parameter for API token id
+	TokenID string = "token_id"
 	// CallID is the url path parameter for call id
 	CallID string = "call_id"
 	// FnID is the url path parameter for fn id
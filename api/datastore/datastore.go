@@ -33,6 +33,15 @@ func Wrap(ds models.Datastore) models.Datastore {
 	return datastoreutil.MetricDS(datastoreutil.NewValidator(ds))
 }
 
+// ValidateInsertApp, ValidateInsertFn and ValidateInsertTrigger make the
+// checks the datastores returned by Wrap make on the arguments of their
+// inserts, without inserting anything, e.g. to validate dry runs.
+var (
+	ValidateInsertApp     = datastoreutil.ValidateInsertApp
+	ValidateInsertFn      = datastoreutil.ValidateInsertFn
+	ValidateInsertTrigger = datastoreutil.ValidateInsertTrigger
+)
+
 // Provider is a datastore provider
 type Provider interface {
 	fmt.Stringer
@@ -0,0 +1,63 @@
+package datastore
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/fnproject/fn/api/models"
+	"go.opencensus.io/trace"
+)
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []*trace.SpanData
+}
+
+func (r *spanRecorder) ExportSpan(s *trace.SpanData) {
+	r.mu.Lock()
+	r.spans = append(r.spans, s)
+	r.mu.Unlock()
+}
+
+func (r *spanRecorder) find(name string) *trace.SpanData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func TestWrapTracesOperations(t *testing.T) {
+	recorder := new(spanRecorder)
+	trace.RegisterExporter(recorder)
+	defer trace.UnregisterExporter(recorder)
+
+	app := &models.App{ID: "app_id", Name: "myapp", Config: models.Config{"SECRET": "hunter2"}}
+	ds := Wrap(NewMockInit([]*models.App{app}))
+
+	ctx, parent := trace.StartSpan(context.Background(), "request", trace.WithSampler(trace.AlwaysSample()))
+	if _, err := ds.GetAppByID(ctx, app.ID); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	span := recorder.find("ds_get_app_by_id")
+	if span == nil {
+		t.Fatal("expected a span for the datastore call")
+	}
+	if span.ParentSpanID != parent.SpanContext().SpanID || span.TraceID != parent.SpanContext().TraceID {
+		t.Fatalf("expected the span to be a child of the request span, got parent %v", span.ParentSpanID)
+	}
+	if span.Attributes["fn.app_id"] != app.ID {
+		t.Fatalf("expected the app ID on the span, got %v", span.Attributes)
+	}
+	for k, v := range span.Attributes {
+		if v == "hunter2" {
+			t.Fatalf("expected no config on the span, got %s", k)
+		}
+	}
+}
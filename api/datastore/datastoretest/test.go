@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"log"
 	"math/rand"
+	"reflect"
 	"sort"
 	"testing"
 	"time"
@@ -409,6 +410,34 @@ func RunAppsTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
 			t.Fatalf("expected app list to contain app %s, got %#v", a1.Name, apps)
 		})
 
+		t.Run("List apps of a tenant", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+
+			tenantApp := rp.ValidApp()
+			tenantApp.TenantID = "tenant-a"
+			a1 := h.GivenAppInDb(tenantApp)
+			h.GivenAppInDb(rp.ValidApp())
+
+			if a1.TenantID != "tenant-a" {
+				t.Fatalf("expected the app to be stored with its tenant, got %q", a1.TenantID)
+			}
+			apps, err := ds.GetApps(ctx, &models.AppFilter{PerPage: 100, TenantID: "tenant-a"})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if len(apps.Items) != 1 || apps.Items[0].ID != a1.ID || apps.Items[0].TenantID != "tenant-a" {
+				t.Fatalf("expected only the app of the tenant, got %#v", apps.Items)
+			}
+			apps, err = ds.GetApps(ctx, &models.AppFilter{PerPage: 100, TenantID: "tenant-b"})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if len(apps.Items) != 0 {
+				t.Fatalf("expected no apps of another tenant, got %#v", apps.Items)
+			}
+		})
+
 		t.Run("Simple Pagination", func(t *testing.T) {
 			h := NewHarness(t, ctx, ds)
 			defer h.Cleanup()
@@ -1233,5 +1262,92 @@ func RunAllTests(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
 	RunFnsTest(t, dsf, rp)
 	RunTriggersTest(t, dsf, rp)
 	RunTriggerBySourceTests(t, dsf, rp)
+	RunTokensTest(t, dsf, rp)
+
+}
+
+// RunTokensTest tests the models.TokenDatastore of datastores which store API tokens
+func RunTokensTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+	ds := dsf(t)
+	ctx := rp.DefaultCtx()
+
+	t.Run("tokens", func(t *testing.T) {
+		tokens, err := models.GetTokenDatastore(ds)
+		if err == nil {
+			_, err = tokens.GetTokens(ctx, &models.TokenFilter{})
+		}
+		if err == models.ErrTokensUnsupported {
+			t.Skip("datastore does not store API tokens")
+		}
+
+		t.Run("insert invalid token fails", func(t *testing.T) {
+			_, err := tokens.InsertToken(ctx, &models.APIToken{Name: "ci", Permission: "admin", Hash: "x"})
+			if err != models.ErrTokenInvalidPermission {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrTokenInvalidPermission, err)
+			}
+		})
 
+		t.Run("insert, get, list and remove tokens", func(t *testing.T) {
+			secret, hash, err := models.NewAPITokenSecret()
+			if err != nil {
+				t.Fatal(err)
+			}
+			inserted, err := tokens.InsertToken(ctx, &models.APIToken{
+				Name:       "ci",
+				AppIDs:     models.TokenApps{"app1", "app2"},
+				Permission: models.TokenPermissionRead,
+				Token:      secret,
+				Hash:       hash,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if inserted.ID == "" || time.Time(inserted.CreatedAt).IsZero() || inserted.Token != "" {
+				t.Fatalf("expected the token to get an ID and creation time, and not keep its secret, got %#v", inserted)
+			}
+
+			byHash, err := tokens.GetTokenByHash(ctx, models.HashAPIToken(secret))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if byHash.ID != inserted.ID || !reflect.DeepEqual(byHash.AppIDs, inserted.AppIDs) || byHash.Permission != inserted.Permission {
+				t.Fatalf("expected token %#v, got %#v", inserted, byHash)
+			}
+
+			other, err := tokens.InsertToken(ctx, &models.APIToken{Name: "admin", Permission: models.TokenPermissionWrite, Hash: "other"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := tokens.GetTokenByID(ctx, other.ID); err != nil || len(got.AppIDs) != 0 {
+				t.Fatalf("expected token %#v, got %#v %v", other, got, err)
+			}
+
+			page, err := tokens.GetTokens(ctx, &models.TokenFilter{PerPage: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(page.Items) != 1 || page.NextCursor == "" {
+				t.Fatalf("expected a first page of 1 token, got %#v", page)
+			}
+			page, err = tokens.GetTokens(ctx, &models.TokenFilter{PerPage: 1, Cursor: page.NextCursor})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(page.Items) != 1 {
+				t.Fatalf("expected a second page of 1 token, got %#v", page)
+			}
+
+			for _, id := range []string{inserted.ID, other.ID} {
+				if err := tokens.RemoveToken(ctx, id); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := tokens.GetTokenByID(ctx, inserted.ID); err != models.ErrTokenNotFound {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrTokenNotFound, err)
+			}
+			if err := tokens.RemoveToken(ctx, inserted.ID); err != models.ErrTokenNotFound {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrTokenNotFound, err)
+			}
+		})
+	})
 }
@@ -15,51 +15,82 @@ type metricds struct {
 	ds models.Datastore
 }
 
+// addAttributes adds the IDs and names an operation is made on to its span,
+// as key and value pairs, leaving out empty values. Secrets, such as the
+// hashes of tokens or config, must never be added.
+func addAttributes(span *trace.Span, kvs ...string) {
+	attrs := make([]trace.Attribute, 0, len(kvs)/2)
+	for i := 0; i+1 < len(kvs); i += 2 {
+		if kvs[i+1] != "" {
+			attrs = append(attrs, trace.StringAttribute(kvs[i], kvs[i+1]))
+		}
+	}
+	if len(attrs) > 0 {
+		span.AddAttributes(attrs...)
+	}
+}
+
 func (m *metricds) GetTriggerBySource(ctx context.Context, appId string, triggerType, source string) (*models.Trigger, error) {
 	ctx, span := trace.StartSpan(ctx, "ds_get_trigger_by_source")
 	defer span.End()
+	addAttributes(span, "fn.app_id", appId, "fn.trigger_type", triggerType, "fn.trigger_source", source)
 	return m.ds.GetTriggerBySource(ctx, appId, triggerType, source)
 }
 
 func (m *metricds) GetAppID(ctx context.Context, appName string) (string, error) {
 	ctx, span := trace.StartSpan(ctx, "ds_get_app_id")
 	defer span.End()
+	addAttributes(span, "fn.app_name", appName)
 	return m.ds.GetAppID(ctx, appName)
 }
 
 func (m *metricds) GetAppByID(ctx context.Context, appID string) (*models.App, error) {
 	ctx, span := trace.StartSpan(ctx, "ds_get_app_by_id")
 	defer span.End()
+	addAttributes(span, "fn.app_id", appID)
 	return m.ds.GetAppByID(ctx, appID)
 }
 
 func (m *metricds) GetApps(ctx context.Context, filter *models.AppFilter) (*models.AppList, error) {
 	ctx, span := trace.StartSpan(ctx, "ds_get_apps")
 	defer span.End()
+	if filter != nil {
+		addAttributes(span, "fn.app_name", filter.Name)
+	}
 	return m.ds.GetApps(ctx, filter)
 }
 
 func (m *metricds) InsertApp(ctx context.Context, app *models.App) (*models.App, error) {
 	ctx, span := trace.StartSpan(ctx, "ds_insert_app")
 	defer span.End()
+	if app != nil {
+		addAttributes(span, "fn.app_name", app.Name)
+	}
 	return m.ds.InsertApp(ctx, app)
 }
 
 func (m *metricds) UpdateApp(ctx context.Context, app *models.App) (*models.App, error) {
 	ctx, span := trace.StartSpan(ctx, "ds_update_app")
 	defer span.End()
+	if app != nil {
+		addAttributes(span, "fn.app_id", app.ID)
+	}
 	return m.ds.UpdateApp(ctx, app)
 }
 
 func (m *metricds) RemoveApp(ctx context.Context, appID string) error {
 	ctx, span := trace.StartSpan(ctx, "ds_remove_app")
 	defer span.End()
+	addAttributes(span, "fn.app_id", appID)
 	return m.ds.RemoveApp(ctx, appID)
 }
 
 func (m *metricds) InsertTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
 	ctx, span := trace.StartSpan(ctx, "ds_insert_trigger")
 	defer span.End()
+	if trigger != nil {
+		addAttributes(span, "fn.app_id", trigger.AppID, "fn.fn_id", trigger.FnID)
+	}
 	return m.ds.InsertTrigger(ctx, trigger)
 
 }
@@ -67,57 +98,134 @@ func (m *metricds) InsertTrigger(ctx context.Context, trigger *models.Trigger) (
 func (m *metricds) UpdateTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
 	ctx, span := trace.StartSpan(ctx, "ds_update_trigger")
 	defer span.End()
+	if trigger != nil {
+		addAttributes(span, "fn.app_id", trigger.AppID, "fn.fn_id", trigger.FnID, "fn.trigger_id", trigger.ID)
+	}
 	return m.ds.UpdateTrigger(ctx, trigger)
 }
 
 func (m *metricds) RemoveTrigger(ctx context.Context, triggerID string) error {
 	ctx, span := trace.StartSpan(ctx, "ds_remove_trigger")
 	defer span.End()
+	addAttributes(span, "fn.trigger_id", triggerID)
 	return m.ds.RemoveTrigger(ctx, triggerID)
 }
 
 func (m *metricds) GetTriggerByID(ctx context.Context, triggerID string) (*models.Trigger, error) {
 	ctx, span := trace.StartSpan(ctx, "ds_get_trigger_by_id")
 	defer span.End()
+	addAttributes(span, "fn.trigger_id", triggerID)
 	return m.ds.GetTriggerByID(ctx, triggerID)
 }
 
 func (m *metricds) GetTriggers(ctx context.Context, filter *models.TriggerFilter) (*models.TriggerList, error) {
 	ctx, span := trace.StartSpan(ctx, "ds_get_triggers")
 	defer span.End()
+	if filter != nil {
+		addAttributes(span, "fn.app_id", filter.AppID, "fn.fn_id", filter.FnID)
+	}
 	return m.ds.GetTriggers(ctx, filter)
 }
 
 func (m *metricds) InsertFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
 	ctx, span := trace.StartSpan(ctx, "ds_insert_func")
 	defer span.End()
+	if fn != nil {
+		addAttributes(span, "fn.app_id", fn.AppID, "fn.fn_name", fn.Name)
+	}
 	return m.ds.InsertFn(ctx, fn)
 }
 
 func (m *metricds) UpdateFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
-	ctx, span := trace.StartSpan(ctx, "ds_insert_func")
+	ctx, span := trace.StartSpan(ctx, "ds_update_func")
 	defer span.End()
+	if fn != nil {
+		addAttributes(span, "fn.app_id", fn.AppID, "fn.fn_id", fn.ID)
+	}
 	return m.ds.UpdateFn(ctx, fn)
 }
 
 func (m *metricds) GetFns(ctx context.Context, filter *models.FnFilter) (*models.FnList, error) {
 	ctx, span := trace.StartSpan(ctx, "ds_get_funcs")
 	defer span.End()
+	if filter != nil {
+		addAttributes(span, "fn.app_id", filter.AppID, "fn.fn_name", filter.Name)
+	}
 	return m.ds.GetFns(ctx, filter)
 }
 
 func (m *metricds) GetFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
 	ctx, span := trace.StartSpan(ctx, "ds_get_func")
 	defer span.End()
+	addAttributes(span, "fn.fn_id", fnID)
 	return m.ds.GetFnByID(ctx, fnID)
 }
 
 func (m *metricds) RemoveFn(ctx context.Context, fnID string) error {
 	ctx, span := trace.StartSpan(ctx, "ds_remove_func")
 	defer span.End()
+	addAttributes(span, "fn.fn_id", fnID)
 	return m.ds.RemoveFn(ctx, fnID)
 }
 
+func (m *metricds) MigrationStatus(ctx context.Context) (*models.MigrationStatus, error) {
+	ctx, span := trace.StartSpan(ctx, "ds_migration_status")
+	defer span.End()
+	return models.GetMigrationStatus(ctx, m.ds)
+}
+
+func (m *metricds) InsertToken(ctx context.Context, token *models.APIToken) (*models.APIToken, error) {
+	ctx, span := trace.StartSpan(ctx, "ds_insert_token")
+	defer span.End()
+	tokens, err := models.GetTokenDatastore(m.ds)
+	if err != nil {
+		return nil, err
+	}
+	return tokens.InsertToken(ctx, token)
+}
+
+func (m *metricds) GetTokenByID(ctx context.Context, tokenID string) (*models.APIToken, error) {
+	ctx, span := trace.StartSpan(ctx, "ds_get_token")
+	defer span.End()
+	addAttributes(span, "fn.token_id", tokenID)
+	tokens, err := models.GetTokenDatastore(m.ds)
+	if err != nil {
+		return nil, err
+	}
+	return tokens.GetTokenByID(ctx, tokenID)
+}
+
+func (m *metricds) GetTokenByHash(ctx context.Context, hash string) (*models.APIToken, error) {
+	ctx, span := trace.StartSpan(ctx, "ds_get_token_by_hash")
+	defer span.End()
+	tokens, err := models.GetTokenDatastore(m.ds)
+	if err != nil {
+		return nil, err
+	}
+	return tokens.GetTokenByHash(ctx, hash)
+}
+
+func (m *metricds) GetTokens(ctx context.Context, filter *models.TokenFilter) (*models.TokenList, error) {
+	ctx, span := trace.StartSpan(ctx, "ds_get_tokens")
+	defer span.End()
+	tokens, err := models.GetTokenDatastore(m.ds)
+	if err != nil {
+		return nil, err
+	}
+	return tokens.GetTokens(ctx, filter)
+}
+
+func (m *metricds) RemoveToken(ctx context.Context, tokenID string) error {
+	ctx, span := trace.StartSpan(ctx, "ds_remove_token")
+	defer span.End()
+	addAttributes(span, "fn.token_id", tokenID)
+	tokens, err := models.GetTokenDatastore(m.ds)
+	if err != nil {
+		return err
+	}
+	return tokens.RemoveToken(ctx, tokenID)
+}
+
 // Close calls Close on the underlying Datastore
 func (m *metricds) Close() error {
 	return m.ds.Close()
@@ -31,15 +31,20 @@ func (v *validator) GetAppByID(ctx context.Context, appID string) (*models.App,
 	return v.Datastore.GetAppByID(ctx, appID)
 }
 
-// app and app.Name will never be nil/empty.
-func (v *validator) InsertApp(ctx context.Context, app *models.App) (*models.App, error) {
+// ValidateInsertApp makes the checks of InsertApp on app, without inserting it.
+func ValidateInsertApp(app *models.App) error {
 	if app == nil {
-		return nil, models.ErrDatastoreEmptyApp
+		return models.ErrDatastoreEmptyApp
 	}
 	if app.ID != "" {
-		return nil, models.ErrAppIDProvided
+		return models.ErrAppIDProvided
 	}
-	if err := app.Validate(); err != nil {
+	return app.Validate()
+}
+
+// app and app.Name will never be nil/empty.
+func (v *validator) InsertApp(ctx context.Context, app *models.App) (*models.App, error) {
+	if err := ValidateInsertApp(app); err != nil {
 		return nil, err
 	}
 
@@ -67,17 +72,25 @@ func (v *validator) RemoveApp(ctx context.Context, appID string) error {
 	return v.Datastore.RemoveApp(ctx, appID)
 }
 
-func (v *validator) InsertTrigger(ctx context.Context, t *models.Trigger) (*models.Trigger, error) {
-
+// ValidateInsertTrigger makes the checks of InsertTrigger on t, without
+// inserting it.
+func ValidateInsertTrigger(t *models.Trigger) error {
 	if t.ID != "" {
-		return nil, models.ErrTriggerIDProvided
+		return models.ErrTriggerIDProvided
 	}
 
 	if !time.Time(t.CreatedAt).IsZero() {
-		return nil, models.ErrCreatedAtProvided
+		return models.ErrCreatedAtProvided
 	}
 	if !time.Time(t.UpdatedAt).IsZero() {
-		return nil, models.ErrUpdatedAtProvided
+		return models.ErrUpdatedAtProvided
+	}
+	return nil
+}
+
+func (v *validator) InsertTrigger(ctx context.Context, t *models.Trigger) (*models.Trigger, error) {
+	if err := ValidateInsertTrigger(t); err != nil {
+		return nil, err
 	}
 
 	return v.Datastore.InsertTrigger(ctx, t)
@@ -99,18 +112,26 @@ func (v *validator) RemoveTrigger(ctx context.Context, triggerID string) error {
 	return v.Datastore.RemoveTrigger(ctx, triggerID)
 }
 
-func (v *validator) InsertFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+// ValidateInsertFn makes the checks of InsertFn on fn, without inserting it.
+func ValidateInsertFn(fn *models.Fn) error {
 	if fn == nil {
-		return nil, models.ErrDatastoreEmptyFn
+		return models.ErrDatastoreEmptyFn
 	}
 	if fn.ID != "" {
-		return nil, models.ErrFnsIDProvided
+		return models.ErrFnsIDProvided
 	}
 	if fn.AppID == "" {
-		return nil, models.ErrFnsMissingAppID
+		return models.ErrFnsMissingAppID
 	}
 	if fn.Name == "" {
-		return nil, models.ErrFnsMissingName
+		return models.ErrFnsMissingName
+	}
+	return nil
+}
+
+func (v *validator) InsertFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+	if err := ValidateInsertFn(fn); err != nil {
+		return nil, err
 	}
 	return v.Datastore.InsertFn(ctx, fn)
 }
@@ -138,3 +159,47 @@ func (v *validator) RemoveFn(ctx context.Context, fnID string) error {
 	}
 	return v.Datastore.RemoveFn(ctx, fnID)
 }
+
+func (v *validator) MigrationStatus(ctx context.Context) (*models.MigrationStatus, error) {
+	return models.GetMigrationStatus(ctx, v.Datastore)
+}
+
+func (v *validator) InsertToken(ctx context.Context, token *models.APIToken) (*models.APIToken, error) {
+	tokens, err := models.GetTokenDatastore(v.Datastore)
+	if err != nil {
+		return nil, err
+	}
+	return tokens.InsertToken(ctx, token)
+}
+
+func (v *validator) GetTokenByID(ctx context.Context, tokenID string) (*models.APIToken, error) {
+	tokens, err := models.GetTokenDatastore(v.Datastore)
+	if err != nil {
+		return nil, err
+	}
+	return tokens.GetTokenByID(ctx, tokenID)
+}
+
+func (v *validator) GetTokenByHash(ctx context.Context, hash string) (*models.APIToken, error) {
+	tokens, err := models.GetTokenDatastore(v.Datastore)
+	if err != nil {
+		return nil, err
+	}
+	return tokens.GetTokenByHash(ctx, hash)
+}
+
+func (v *validator) GetTokens(ctx context.Context, filter *models.TokenFilter) (*models.TokenList, error) {
+	tokens, err := models.GetTokenDatastore(v.Datastore)
+	if err != nil {
+		return nil, err
+	}
+	return tokens.GetTokens(ctx, filter)
+}
+
+func (v *validator) RemoveToken(ctx context.Context, tokenID string) error {
+	tokens, err := models.GetTokenDatastore(v.Datastore)
+	if err != nil {
+		return err
+	}
+	return tokens.RemoveToken(ctx, tokenID)
+}
@@ -0,0 +1,501 @@
+// Package memory provides a datastore keeping apps, fns, triggers and tokens in
+// memory, for tests and ephemeral nodes. It is used with the memory:// url,
+// and everything in it is lost when the process exits.
+package memory
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+)
+
+type memoryDsProvider int
+
+func (memoryDsProvider) Supports(u *url.URL) bool {
+	return u.Scheme == "memory"
+}
+
+func (memoryDsProvider) New(ctx context.Context, u *url.URL) (models.Datastore, error) {
+	return New(), nil
+}
+
+func (memoryDsProvider) String() string {
+	return "memory"
+}
+
+func init() {
+	datastore.Register(memoryDsProvider(0))
+}
+
+type store struct {
+	mu       sync.RWMutex
+	apps     map[string]*models.App
+	fns      map[string]*models.Fn
+	triggers map[string]*models.Trigger
+	tokens   map[string]*models.APIToken
+}
+
+var (
+	_ models.Datastore      = new(store)
+	_ models.TokenDatastore = new(store)
+)
+
+// New returns an empty in memory datastore, safe for concurrent use. Like the
+// sql datastores, it expects to be wrapped by the validator.
+func New() models.Datastore {
+	return &store{
+		apps:     make(map[string]*models.App),
+		fns:      make(map[string]*models.Fn),
+		triggers: make(map[string]*models.Trigger),
+		tokens:   make(map[string]*models.APIToken),
+	}
+}
+
+// decodeCursor returns the name a page starts after
+func decodeCursor(cursor string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+	name, err := base64.RawURLEncoding.DecodeString(cursor)
+	return string(name), err
+}
+
+// page sorts the n matching objects by name and returns the indexes of those
+// after cursor, up to perPage of them, with the cursor of the next page.
+func page(n int, name func(i int) string, cursor string, perPage int) ([]int, string) {
+	idx := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		if cursor == "" || name(i) > cursor {
+			idx = append(idx, i)
+		}
+	}
+	sort.Slice(idx, func(a, b int) bool { return name(idx[a]) < name(idx[b]) })
+	if perPage > 0 && len(idx) > perPage {
+		idx = idx[:perPage]
+	}
+
+	var next string
+	if len(idx) > 0 && len(idx) == perPage {
+		next = base64.RawURLEncoding.EncodeToString([]byte(name(idx[len(idx)-1])))
+	}
+	return idx, next
+}
+
+func (s *store) GetAppID(ctx context.Context, appName string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, a := range s.apps {
+		if a.Name == appName {
+			return a.ID, nil
+		}
+	}
+	return "", models.ErrAppsNotFound
+}
+
+func (s *store) GetAppByID(ctx context.Context, appID string) (*models.App, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, ok := s.apps[appID]
+	if !ok {
+		return nil, models.ErrAppsNotFound
+	}
+	return a.Clone(), nil
+}
+
+func (s *store) GetApps(ctx context.Context, filter *models.AppFilter) (*models.AppList, error) {
+	cursor, err := decodeCursor(filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var matched []*models.App
+	for _, a := range s.apps {
+		if (filter.Name == "" || filter.Name == a.Name) && (filter.TenantID == "" || filter.TenantID == a.TenantID) {
+			matched = append(matched, a)
+		}
+	}
+
+	idx, next := page(len(matched), func(i int) string { return matched[i].Name }, cursor, filter.PerPage)
+	res := &models.AppList{Items: make([]*models.App, 0, len(idx)), NextCursor: next}
+	for _, i := range idx {
+		res.Items = append(res.Items, matched[i].Clone())
+	}
+	return res, nil
+}
+
+func (s *store) InsertApp(ctx context.Context, newApp *models.App) (*models.App, error) {
+	app := newApp.Clone()
+	app.CreatedAt = common.DateTime(time.Now())
+	app.UpdatedAt = app.CreatedAt
+	app.ID = id.New().String()
+	if app.Config == nil {
+		// keeps the JSON from being nil
+		app.Config = map[string]string{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range s.apps {
+		if a.Name == app.Name {
+			return nil, models.ErrAppsAlreadyExists
+		}
+	}
+	s.apps[app.ID] = app
+	return app.Clone(), nil
+}
+
+func (s *store) UpdateApp(ctx context.Context, newApp *models.App) (*models.App, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.apps[newApp.ID]
+	if !ok {
+		return nil, models.ErrAppsNotFound
+	}
+	if newApp.Name != "" && a.Name != newApp.Name {
+		return nil, models.ErrAppsNameImmutable
+	}
+
+	app := a.Clone()
+	app.Update(newApp)
+	if err := app.Validate(); err != nil {
+		return nil, err
+	}
+	s.apps[app.ID] = app
+	return app.Clone(), nil
+}
+
+func (s *store) RemoveApp(ctx context.Context, appID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.apps[appID]; !ok {
+		return models.ErrAppsNotFound
+	}
+	delete(s.apps, appID)
+	for fnID, fn := range s.fns {
+		if fn.AppID == appID {
+			delete(s.fns, fnID)
+		}
+	}
+	for triggerID, t := range s.triggers {
+		if t.AppID == appID {
+			delete(s.triggers, triggerID)
+		}
+	}
+	return nil
+}
+
+func (s *store) InsertFn(ctx context.Context, newFn *models.Fn) (*models.Fn, error) {
+	fn := newFn.Clone()
+	fn.ID = id.New().String()
+	fn.CreatedAt = common.DateTime(time.Now())
+	fn.UpdatedAt = fn.CreatedAt
+	if err := newFn.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.apps[fn.AppID]; !ok {
+		return nil, models.ErrAppsNotFound
+	}
+	for _, f := range s.fns {
+		if f.AppID == fn.AppID && f.Name == fn.Name {
+			return nil, models.ErrFnsExists
+		}
+	}
+	s.fns[fn.ID] = fn
+	return fn.Clone(), nil
+}
+
+func (s *store) UpdateFn(ctx context.Context, newFn *models.Fn) (*models.Fn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.fns[newFn.ID]
+	if !ok {
+		return nil, models.ErrFnsNotFound
+	}
+
+	fn := f.Clone()
+	fn.Update(newFn)
+	if err := fn.Validate(); err != nil {
+		return nil, err
+	}
+	for _, other := range s.fns {
+		if other.ID != fn.ID && other.AppID == fn.AppID && other.Name == fn.Name {
+			return nil, models.ErrFnsExists
+		}
+	}
+	s.fns[fn.ID] = fn
+	return fn.Clone(), nil
+}
+
+func (s *store) GetFns(ctx context.Context, filter *models.FnFilter) (*models.FnList, error) {
+	if filter == nil {
+		filter = new(models.FnFilter)
+	}
+	cursor, err := decodeCursor(filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var matched []*models.Fn
+	for _, f := range s.fns {
+		if (filter.AppID == "" || filter.AppID == f.AppID) && (filter.Name == "" || filter.Name == f.Name) {
+			matched = append(matched, f)
+		}
+	}
+
+	idx, next := page(len(matched), func(i int) string { return matched[i].Name }, cursor, filter.PerPage)
+	res := &models.FnList{Items: make([]*models.Fn, 0, len(idx)), NextCursor: next}
+	for _, i := range idx {
+		res.Items = append(res.Items, matched[i].Clone())
+	}
+	return res, nil
+}
+
+func (s *store) GetFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f, ok := s.fns[fnID]
+	if !ok {
+		return nil, models.ErrFnsNotFound
+	}
+	return f.Clone(), nil
+}
+
+func (s *store) RemoveFn(ctx context.Context, fnID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.fns[fnID]; !ok {
+		return models.ErrFnsNotFound
+	}
+	delete(s.fns, fnID)
+	for triggerID, t := range s.triggers {
+		if t.FnID == fnID {
+			delete(s.triggers, triggerID)
+		}
+	}
+	return nil
+}
+
+// triggerConflict returns the error for a trigger clashing with another by
+// name or source, if any
+func (s *store) triggerConflict(trigger *models.Trigger) error {
+	for _, t := range s.triggers {
+		if t.ID == trigger.ID || t.AppID != trigger.AppID {
+			continue
+		}
+		if t.Type == trigger.Type && t.Source == trigger.Source {
+			return models.ErrTriggerSourceExists
+		}
+		if t.FnID == trigger.FnID && t.Name == trigger.Name {
+			return models.ErrTriggerExists
+		}
+	}
+	return nil
+}
+
+func (s *store) InsertTrigger(ctx context.Context, newTrigger *models.Trigger) (*models.Trigger, error) {
+	trigger := newTrigger.Clone()
+	trigger.CreatedAt = common.DateTime(time.Now())
+	trigger.UpdatedAt = trigger.CreatedAt
+	trigger.ID = id.New().String()
+	if err := trigger.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.apps[trigger.AppID]; !ok {
+		return nil, models.ErrAppsNotFound
+	}
+	fn, ok := s.fns[trigger.FnID]
+	if !ok {
+		return nil, models.ErrFnsNotFound
+	}
+	if fn.AppID != trigger.AppID {
+		return nil, models.ErrTriggerFnIDNotSameApp
+	}
+	if err := s.triggerConflict(trigger); err != nil {
+		return nil, err
+	}
+	s.triggers[trigger.ID] = trigger
+	return trigger.Clone(), nil
+}
+
+func (s *store) UpdateTrigger(ctx context.Context, newTrigger *models.Trigger) (*models.Trigger, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.triggers[newTrigger.ID]
+	if !ok {
+		return nil, models.ErrTriggerNotFound
+	}
+
+	trigger := t.Clone()
+	trigger.Update(newTrigger)
+	if err := trigger.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.triggerConflict(trigger); err != nil {
+		return nil, err
+	}
+	s.triggers[trigger.ID] = trigger
+	return trigger.Clone(), nil
+}
+
+func (s *store) GetTrigger(ctx context.Context, appID, fnID, triggerName string) (*models.Trigger, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, t := range s.triggers {
+		if t.AppID == appID && t.FnID == fnID && t.Name == triggerName {
+			return t.Clone(), nil
+		}
+	}
+	return nil, models.ErrTriggerNotFound
+}
+
+func (s *store) GetTriggerByID(ctx context.Context, triggerID string) (*models.Trigger, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.triggers[triggerID]
+	if !ok {
+		return nil, models.ErrTriggerNotFound
+	}
+	return t.Clone(), nil
+}
+
+func (s *store) GetTriggerBySource(ctx context.Context, appID string, triggerType, source string) (*models.Trigger, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, t := range s.triggers {
+		if t.AppID == appID && t.Type == triggerType && t.Source == source {
+			return t.Clone(), nil
+		}
+	}
+	return nil, models.ErrTriggerNotFound
+}
+
+func (s *store) GetTriggers(ctx context.Context, filter *models.TriggerFilter) (*models.TriggerList, error) {
+	if filter == nil {
+		filter = new(models.TriggerFilter)
+	}
+	cursor, err := decodeCursor(filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var matched []*models.Trigger
+	for _, t := range s.triggers {
+		if t.AppID == filter.AppID &&
+			(filter.FnID == "" || filter.FnID == t.FnID) &&
+			(filter.Name == "" || filter.Name == t.Name) {
+			matched = append(matched, t)
+		}
+	}
+
+	idx, next := page(len(matched), func(i int) string { return matched[i].Name }, cursor, filter.PerPage)
+	res := &models.TriggerList{Items: make([]*models.Trigger, 0, len(idx)), NextCursor: next}
+	for _, i := range idx {
+		res.Items = append(res.Items, matched[i].Clone())
+	}
+	return res, nil
+}
+
+func (s *store) RemoveTrigger(ctx context.Context, triggerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.triggers[triggerID]; !ok {
+		return models.ErrTriggerNotFound
+	}
+	delete(s.triggers, triggerID)
+	return nil
+}
+
+func (s *store) InsertToken(ctx context.Context, newToken *models.APIToken) (*models.APIToken, error) {
+	token := newToken.Clone()
+	token.Token = ""
+	token.CreatedAt = common.DateTime(time.Now())
+	token.ID = id.New().String()
+	if err := token.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[token.ID] = token
+	return token.Clone(), nil
+}
+
+func (s *store) GetTokenByID(ctx context.Context, tokenID string) (*models.APIToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.tokens[tokenID]
+	if !ok {
+		return nil, models.ErrTokenNotFound
+	}
+	return t.Clone(), nil
+}
+
+func (s *store) GetTokenByHash(ctx context.Context, hash string) (*models.APIToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, t := range s.tokens {
+		if t.Hash == hash {
+			return t.Clone(), nil
+		}
+	}
+	return nil, models.ErrTokenNotFound
+}
+
+func (s *store) GetTokens(ctx context.Context, filter *models.TokenFilter) (*models.TokenList, error) {
+	if filter == nil {
+		filter = new(models.TokenFilter)
+	}
+	cursor, err := decodeCursor(filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	matched := make([]*models.APIToken, 0, len(s.tokens))
+	for _, t := range s.tokens {
+		matched = append(matched, t)
+	}
+
+	idx, next := page(len(matched), func(i int) string { return matched[i].ID }, cursor, filter.PerPage)
+	res := &models.TokenList{Items: make([]*models.APIToken, 0, len(idx)), NextCursor: next}
+	for _, i := range idx {
+		res.Items = append(res.Items, matched[i].Clone())
+	}
+	return res, nil
+}
+
+func (s *store) RemoveToken(ctx context.Context, tokenID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tokens[tokenID]; !ok {
+		return models.ErrTokenNotFound
+	}
+	delete(s.tokens, tokenID)
+	return nil
+}
+
+// Close implements models.Datastore, there is nothing to release
+func (s *store) Close() error {
+	return nil
+}
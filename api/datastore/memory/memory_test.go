@@ -0,0 +1,22 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/datastore/datastoretest"
+	"github.com/fnproject/fn/api/datastore/internal/datastoreutil"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestDatastore(t *testing.T) {
+	f := func(t *testing.T) models.Datastore {
+		ds, err := datastore.New(context.Background(), "memory://")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return datastoreutil.NewValidator(ds)
+	}
+	datastoretest.RunAllTests(t, f, datastoretest.NewBasicResourceProvider())
+}
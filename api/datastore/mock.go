@@ -105,6 +105,9 @@ func (m *mock) GetApps(ctx context.Context, filter *models.AppFilter) (*models.A
 			if filter.Name != "" && filter.Name != a.Name {
 				continue
 			}
+			if filter.TenantID != "" && filter.TenantID != a.TenantID {
+				continue
+			}
 			apps = append(apps, a.Clone())
 		}
 	}
@@ -0,0 +1,81 @@
+package datastore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+)
+
+// DefaultMaxIdleConns is the number of idle connections to the db datastores
+// keep by default
+const DefaultMaxIdleConns = 256
+
+// PoolConfig sizes the connection pool of datastores backed by a SQL db, see
+// sql.DB. MaxOpenConns of 0 means no limit, and ConnMaxLifetime of 0 means
+// connections are reused forever.
+//
+// Every node connects with a pool of its own, so MaxOpenConns times the number
+// of API and LB nodes should stay below the max_connections of the db server,
+// leaving room for migrations and operators. Queries past MaxOpenConns wait
+// for a connection to be released, which the wait metrics report.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+type poolConfigKey struct{}
+
+// WithPoolConfig returns a context that makes New size the pools of the
+// datastores it creates with cfg. Providers that don't pool connections
+// ignore it.
+func WithPoolConfig(ctx context.Context, cfg PoolConfig) context.Context {
+	return context.WithValue(ctx, poolConfigKey{}, cfg)
+}
+
+// PoolConfigFromContext returns the pool config set with WithPoolConfig, or
+// DefaultMaxIdleConns and no other limit if there is none
+func PoolConfigFromContext(ctx context.Context) PoolConfig {
+	if cfg, ok := ctx.Value(poolConfigKey{}).(PoolConfig); ok {
+		return cfg
+	}
+	return PoolConfig{MaxIdleConns: DefaultMaxIdleConns}
+}
+
+var (
+	dbOpenConnsMeasure    = common.MakeMeasure("db/open_connections", "Number of connections to the db, in use and idle", stats.UnitDimensionless)
+	dbInUseConnsMeasure   = common.MakeMeasure("db/in_use_connections", "Number of connections to the db in use", stats.UnitDimensionless)
+	dbIdleConnsMeasure    = common.MakeMeasure("db/idle_connections", "Number of idle connections to the db", stats.UnitDimensionless)
+	dbWaitCountMeasure    = common.MakeMeasure("db/wait_count", "Total number of queries that waited for a connection to the db", stats.UnitDimensionless)
+	dbWaitDurationMeasure = common.MakeMeasure("db/wait_duration", "Total time queries waited for a connection to the db", stats.UnitMilliseconds)
+)
+
+// RegisterPoolViews registers the views for the connection pool of the db
+func RegisterPoolViews(tagKeys []string) {
+	err := view.Register(
+		common.CreateView(dbOpenConnsMeasure, view.LastValue(), tagKeys),
+		common.CreateView(dbInUseConnsMeasure, view.LastValue(), tagKeys),
+		common.CreateView(dbIdleConnsMeasure, view.LastValue(), tagKeys),
+		common.CreateView(dbWaitCountMeasure, view.LastValue(), tagKeys),
+		common.CreateView(dbWaitDurationMeasure, view.LastValue(), tagKeys),
+	)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot register view")
+	}
+}
+
+// RecordPoolStats records a sample of the stats of the connection pool of a db
+func RecordPoolStats(ctx context.Context, s sql.DBStats) {
+	stats.Record(ctx,
+		dbOpenConnsMeasure.M(int64(s.OpenConnections)),
+		dbInUseConnsMeasure.M(int64(s.InUse)),
+		dbIdleConnsMeasure.M(int64(s.Idle)),
+		dbWaitCountMeasure.M(s.WaitCount),
+		dbWaitDurationMeasure.M(int64(s.WaitDuration/time.Millisecond)),
+	)
+}
@@ -0,0 +1,46 @@
+package datastore
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"go.opencensus.io/stats/view"
+)
+
+func TestPoolConfig(t *testing.T) {
+	ctx := context.Background()
+	if cfg := PoolConfigFromContext(ctx); cfg != (PoolConfig{MaxIdleConns: DefaultMaxIdleConns}) {
+		t.Fatalf("expected the default pool config, got %+v", cfg)
+	}
+
+	expected := PoolConfig{MaxOpenConns: 20, MaxIdleConns: 10, ConnMaxLifetime: time.Minute}
+	if cfg := PoolConfigFromContext(WithPoolConfig(ctx, expected)); cfg != expected {
+		t.Fatalf("expected %+v, got %+v", expected, cfg)
+	}
+}
+
+func TestRecordPoolStats(t *testing.T) {
+	RegisterPoolViews(nil)
+	defer view.Unregister(view.Find("db/in_use_connections"), view.Find("db/open_connections"),
+		view.Find("db/idle_connections"), view.Find("db/wait_count"), view.Find("db/wait_duration"))
+
+	RecordPoolStats(context.Background(), sql.DBStats{OpenConnections: 5, InUse: 3, Idle: 2, WaitCount: 7, WaitDuration: 2 * time.Second})
+
+	// views are aggregated in the background
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rows, err := view.RetrieveData("db/wait_duration")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) == 1 && rows[0].Data.(*view.LastValueData).Value == 2000 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a wait duration of 2000ms, got %v", rows)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"os"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/fnproject/fn/api/common"
@@ -36,6 +37,7 @@ var tables = [...]string{
 	`CREATE TABLE IF NOT EXISTS apps (
 	id varchar(256) NOT NULL PRIMARY KEY,
 	name varchar(256) NOT NULL UNIQUE,
+	tenant_id varchar(256) NOT NULL DEFAULT '',
 	config text NOT NULL,
 	annotations text NOT NULL,
 	syslog_url text,
@@ -70,10 +72,19 @@ var tables = [...]string{
 	updated_at varchar(256) NOT NULL,
     CONSTRAINT name_app_id_unique UNIQUE (app_id, name)
 );`,
+
+	`CREATE TABLE IF NOT EXISTS tokens (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	name varchar(256) NOT NULL,
+	app_ids text NOT NULL,
+	permission varchar(256) NOT NULL,
+	hash varchar(256) NOT NULL UNIQUE,
+	created_at varchar(256) NOT NULL
+);`,
 }
 
 const (
-	appIDSelector     = `SELECT id, name, config, annotations, syslog_url, created_at, updated_at FROM apps WHERE id=?`
+	appIDSelector     = `SELECT id, name, tenant_id, config, annotations, syslog_url, created_at, updated_at FROM apps WHERE id=?`
 	ensureAppSelector = `SELECT id FROM apps WHERE name=?`
 
 	fnSelector   = `SELECT id,name,app_id,image,memory,timeout,idle_timeout,config,annotations,created_at,updated_at FROM fns`
@@ -84,17 +95,26 @@ const (
 
 	triggerIDSourceSelector = triggerSelector + ` WHERE app_id=? AND type=? AND source=?`
 
+	tokenSelector     = `SELECT id,name,app_ids,permission,hash,created_at FROM tokens`
+	tokenIDSelector   = tokenSelector + ` WHERE id=?`
+	tokenHashSelector = tokenSelector + ` WHERE hash=?`
+
 	EnvDBPingMaxRetries = "FN_DS_DB_PING_MAX_RETRIES"
+
+	poolStatsInterval = 10 * time.Second
 )
 
 var ( // compiler will yell nice things about our upbringing as a child
-	_ models.Datastore = new(SQLStore)
+	_ models.Datastore      = new(SQLStore)
+	_ models.TokenDatastore = new(SQLStore)
 )
 
 // SQLStore implements models.Datastore
 type SQLStore struct {
-	helper dbhelper.Helper
-	db     *sqlx.DB
+	helper    dbhelper.Helper
+	db        *sqlx.DB
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 type sqlDsProvider int
@@ -153,16 +173,24 @@ func newDS(ctx context.Context, url *url.URL) (*SQLStore, error) {
 		return nil, err
 	}
 
-	maxIdleConns := 256 // TODO we need to strip this out of the URL probably
-	db.SetMaxIdleConns(maxIdleConns)
-	log.WithFields(logrus.Fields{"max_idle_connections": maxIdleConns, "datastore": driver}).Info("datastore dialed")
+	// helpers may override these in PostCreate, sqlite only allows 1 open connection
+	pool := datastore.PoolConfigFromContext(ctx)
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	log.WithFields(logrus.Fields{
+		"max_open_connections": pool.MaxOpenConns,
+		"max_idle_connections": pool.MaxIdleConns,
+		"conn_max_lifetime":    pool.ConnMaxLifetime,
+		"datastore":            driver,
+	}).Info("datastore dialed")
 
 	db, err = helper.PostCreate(db)
 	if err != nil {
 		log.WithError(err).Error("couldn't initialize db")
 		return nil, err
 	}
-	sdb := &SQLStore{db: db, helper: helper}
+	sdb := &SQLStore{db: db, helper: helper, done: make(chan struct{})}
 
 	// NOTE: runMigrations happens before we create all the tables, so that it
 	// can detect whether the db did not exist and insert the latest version of
@@ -196,9 +224,24 @@ func newDS(ctx context.Context, url *url.URL) (*SQLStore, error) {
 		return nil, err
 	}
 
+	go sdb.recordPoolStats(common.BackgroundContext(ctx))
 	return sdb, nil
 }
 
+// recordPoolStats samples the stats of the connection pool until the store is closed
+func (ds *SQLStore) recordPoolStats(ctx context.Context) {
+	ticker := time.NewTicker(poolStatsInterval)
+	defer ticker.Stop()
+	for {
+		datastore.RecordPoolStats(ctx, ds.db.Stats())
+		select {
+		case <-ticker.C:
+		case <-ds.done:
+			return
+		}
+	}
+}
+
 func pingWithRetry(ctx context.Context, db *sqlx.DB) (err error) {
 
 	attempts := int64(10)
@@ -265,6 +308,21 @@ func latestVersion(migs []migratex.Migration) int64 {
 	return highest
 }
 
+// MigrationStatus returns the schema version of the db, against the latest
+// migration of this binary
+func (ds *SQLStore) MigrationStatus(ctx context.Context) (*models.MigrationStatus, error) {
+	status := &models.MigrationStatus{Expected: latestVersion(migrations.Migrations)}
+	err := ds.Tx(func(tx *sqlx.Tx) error {
+		var err error
+		status.Current, status.Dirty, err = migratex.Version(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
 // clear is for tests only, be careful, it deletes all records.
 func (ds *SQLStore) clear() error {
 	return ds.Tx(func(tx *sqlx.Tx) error {
@@ -283,6 +341,12 @@ func (ds *SQLStore) clear() error {
 
 		query = tx.Rebind(`DELETE FROM fns`)
 		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM tokens`)
+		_, err = tx.Exec(query)
 		return err
 	})
 }
@@ -317,6 +381,7 @@ func (ds *SQLStore) InsertApp(ctx context.Context, newApp *models.App) (*models.
 	query := ds.db.Rebind(`INSERT INTO apps (
 		id,
 		name,
+		tenant_id,
 		config,
 		annotations,
 		syslog_url,
@@ -326,6 +391,7 @@ func (ds *SQLStore) InsertApp(ctx context.Context, newApp *models.App) (*models.
 	VALUES (
 		:id,
 		:name,
+		:tenant_id,
 		:config,
 		:annotations,
 		:syslog_url,
@@ -445,7 +511,7 @@ func (ds *SQLStore) GetApps(ctx context.Context, filter *models.AppFilter) (*mod
 		return nil, err
 	}
 	/* #nosec */
-	query = ds.db.Rebind(fmt.Sprintf("SELECT DISTINCT id, name, config, annotations, syslog_url, created_at, updated_at FROM apps %s", query))
+	query = ds.db.Rebind(fmt.Sprintf("SELECT DISTINCT id, name, tenant_id, config, annotations, syslog_url, created_at, updated_at FROM apps %s", query))
 	rows, err := ds.db.QueryxContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
@@ -696,6 +762,7 @@ func buildFilterAppQuery(filter *models.AppFilter) (string, []interface{}, error
 	if filter.Name != "" {
 		args = where(&b, args, "name=?", filter.Name)
 	}
+	args = where(&b, args, "tenant_id=?", filter.TenantID)
 
 	fmt.Fprintf(&b, ` ORDER BY name ASC`) // TODO assert this is indexed
 	fmt.Fprintf(&b, ` LIMIT ?`)
@@ -1028,8 +1095,126 @@ func (ds *SQLStore) GetTriggerBySource(ctx context.Context, appId string, trigge
 	return &trigger, nil
 }
 
+func (ds *SQLStore) InsertToken(ctx context.Context, newToken *models.APIToken) (*models.APIToken, error) {
+	token := newToken.Clone()
+	token.Token = ""
+	token.CreatedAt = common.DateTime(time.Now())
+	token.ID = id.New().String()
+
+	if err := token.Validate(); err != nil {
+		return nil, err
+	}
+
+	query := ds.db.Rebind(`INSERT INTO tokens (
+		id,
+		name,
+		app_ids,
+		permission,
+		hash,
+		created_at
+	)
+	VALUES (
+		:id,
+		:name,
+		:app_ids,
+		:permission,
+		:hash,
+		:created_at
+	);`)
+	if _, err := ds.db.NamedExecContext(ctx, query, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (ds *SQLStore) getToken(ctx context.Context, selector, arg string) (*models.APIToken, error) {
+	var token models.APIToken
+	row := ds.db.QueryRowxContext(ctx, ds.db.Rebind(selector), arg)
+
+	err := row.StructScan(&token)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrTokenNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (ds *SQLStore) GetTokenByID(ctx context.Context, tokenID string) (*models.APIToken, error) {
+	return ds.getToken(ctx, tokenIDSelector, tokenID)
+}
+
+func (ds *SQLStore) GetTokenByHash(ctx context.Context, hash string) (*models.APIToken, error) {
+	return ds.getToken(ctx, tokenHashSelector, hash)
+}
+
+func (ds *SQLStore) GetTokens(ctx context.Context, filter *models.TokenFilter) (*models.TokenList, error) {
+	res := &models.TokenList{Items: []*models.APIToken{}}
+	if filter == nil {
+		filter = new(models.TokenFilter)
+	}
+
+	var b bytes.Buffer
+	var args []interface{}
+	b.WriteString(tokenSelector)
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(` WHERE id > ?`)
+		args = append(args, string(s))
+	}
+	b.WriteString(` ORDER BY id ASC`)
+	if filter.PerPage > 0 {
+		b.WriteString(` LIMIT ?`)
+		args = append(args, filter.PerPage)
+	}
+
+	rows, err := ds.db.QueryxContext(ctx, ds.db.Rebind(b.String()), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var token models.APIToken
+		if err := rows.StructScan(&token); err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, &token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+	return res, nil
+}
+
+func (ds *SQLStore) RemoveToken(ctx context.Context, tokenID string) error {
+	query := ds.db.Rebind(`DELETE FROM tokens WHERE id=?`)
+	res, err := ds.db.ExecContext(ctx, query, tokenID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrTokenNotFound
+	}
+	return nil
+}
+
 // Close closes the database, releasing any open resources.
 func (ds *SQLStore) Close() error {
+	ds.closeOnce.Do(func() { close(ds.done) })
 	return ds.db.Close()
 }
 
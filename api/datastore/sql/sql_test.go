@@ -137,6 +137,43 @@ func TestDatastore(t *testing.T) {
 
 }
 
+func TestMigrationStatus(t *testing.T) {
+	ctx := context.Background()
+	defer os.RemoveAll("sqlite_test_dir")
+	os.RemoveAll("sqlite_test_dir")
+	u, err := url.Parse("sqlite3://sqlite_test_dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds, err := newDS(ctx, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ds.Close()
+
+	status, err := datastoreutil.MetricDS(ds).(models.MigrationReporter).MigrationStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.InSync() || status.Current != latestVersion(migrations.Migrations) {
+		t.Fatalf("expected a fresh db to be on the latest version, got %+v", status)
+	}
+
+	err = ds.Tx(func(tx *sqlx.Tx) error {
+		return migratex.SetVersion(ctx, tx, status.Expected-1, false)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err = ds.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.InSync() || status.Current != status.Expected-1 {
+		t.Fatalf("expected a migration to be pending, got %+v", status)
+	}
+}
+
 func TestClose(t *testing.T) {
 	ctx := context.Background()
 	defer os.RemoveAll("sqlite_test_dir")
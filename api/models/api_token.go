@@ -0,0 +1,231 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// MaxLengthTokenName is the maximum length of the name of an API token
+const MaxLengthTokenName = 255
+
+// apiTokenPrefix prefixes the secrets of API tokens, so that leaked ones are
+// easy to scan for
+const apiTokenPrefix = "fn_"
+
+// TokenPermission is what an API token may do to the resources it is scoped to
+type TokenPermission string
+
+const (
+	// TokenPermissionRead only allows reading resources, with GET and HEAD requests
+	TokenPermissionRead TokenPermission = "read"
+	// TokenPermissionWrite allows reading, creating, updating and deleting resources
+	TokenPermissionWrite TokenPermission = "write"
+)
+
+// TokenApps is the IDs of the apps an API token is scoped to
+type TokenApps []string
+
+// Value implements sql.Valuer, storing the app IDs comma separated
+func (a TokenApps) Value() (driver.Value, error) {
+	return driver.Value(strings.Join(a, ",")), nil
+}
+
+// Scan implements sql.Scanner
+func (a *TokenApps) Scan(value interface{}) error {
+	bv, err := driver.String.ConvertValue(value)
+	if err != nil {
+		return fmt.Errorf("token apps invalid db format: %T value, err: %v", value, err)
+	}
+	var s string
+	switch x := bv.(type) {
+	case []byte:
+		s = string(x)
+	case string:
+		s = x
+	}
+	*a = nil
+	if s != "" {
+		*a = strings.Split(s, ",")
+	}
+	return nil
+}
+
+// APIToken authorizes requests to the /v2 API made with its secret as a
+// bearer token. Tokens scoped to apps only reach those apps and their fns and
+// triggers, tokens with no apps reach every resource, including other tokens.
+type APIToken struct {
+	ID         string          `json:"id" db:"id"`
+	Name       string          `json:"name" db:"name"`
+	AppIDs     TokenApps       `json:"app_ids,omitempty" db:"app_ids"`
+	Permission TokenPermission `json:"permission" db:"permission"`
+	// Token is the secret of the token, only ever returned when it is created
+	Token string `json:"token,omitempty" db:"-"`
+	// Hash is the SHA-256 of the secret, which tokens are looked up by
+	Hash      string          `json:"-" db:"hash"`
+	CreatedAt common.DateTime `json:"created_at,omitempty" db:"created_at"`
+}
+
+// NewAPITokenSecret returns a new random secret for an API token, and its hash
+func NewAPITokenSecret() (secret, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret = apiTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return secret, HashAPIToken(secret), nil
+}
+
+// HashAPIToken returns the hash API tokens are stored and looked up by
+func HashAPIToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Clone returns a deep copy of t
+func (t *APIToken) Clone() *APIToken {
+	clone := new(APIToken)
+	*clone = *t
+	if t.AppIDs != nil {
+		clone.AppIDs = append(TokenApps(nil), t.AppIDs...)
+	}
+	return clone
+}
+
+// Validate checks that the token has valid data for inserting into a store
+func (t *APIToken) Validate() error {
+	if t.Name == "" {
+		return ErrTokenMissingName
+	}
+	if len(t.Name) > MaxLengthTokenName {
+		return ErrTokenTooLongName
+	}
+	if t.Permission != TokenPermissionRead && t.Permission != TokenPermissionWrite {
+		return ErrTokenInvalidPermission
+	}
+	for _, appID := range t.AppIDs {
+		if appID == "" || strings.Contains(appID, ",") {
+			return ErrTokenInvalidAppID
+		}
+	}
+	if t.Hash == "" {
+		return ErrTokenMissingHash
+	}
+	return nil
+}
+
+// Allows returns whether the token may make a request to appID, or to no
+// app if appID is empty, which changes resources if write is set
+func (t *APIToken) Allows(appID string, write bool) bool {
+	if write && t.Permission != TokenPermissionWrite {
+		return false
+	}
+	if len(t.AppIDs) == 0 {
+		return true
+	}
+	for _, id := range t.AppIDs {
+		if appID != "" && id == appID {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenFilter is a search criteria on API tokens
+type TokenFilter struct {
+	Cursor  string
+	PerPage int
+}
+
+// TokenList is a container of API tokens returned by search, optionally indicating the next page cursor
+type TokenList struct {
+	NextCursor string      `json:"next_cursor,omitempty"`
+	Items      []*APIToken `json:"items"`
+}
+
+// TokenDatastore is implemented by datastores which store API tokens
+type TokenDatastore interface {
+	// InsertToken inserts an API token, setting its ID and creation time.
+	InsertToken(ctx context.Context, token *APIToken) (*APIToken, error)
+
+	// GetTokenByID gets an API token by ID.
+	// Returns ErrTokenNotFound if no token is found.
+	GetTokenByID(ctx context.Context, tokenID string) (*APIToken, error)
+
+	// GetTokenByHash gets an API token by the hash of its secret, see HashAPIToken.
+	// Returns ErrTokenNotFound if no token is found.
+	GetTokenByHash(ctx context.Context, hash string) (*APIToken, error)
+
+	// GetTokens gets a list of API tokens, ordered by ID, and a cursor.
+	GetTokens(ctx context.Context, filter *TokenFilter) (*TokenList, error)
+
+	// RemoveToken removes an API token.
+	// Returns ErrTokenNotFound if no token is found.
+	RemoveToken(ctx context.Context, tokenID string) error
+}
+
+// GetTokenDatastore returns the TokenDatastore of ds, or ErrTokensUnsupported
+// if it does not store API tokens. Datastore wrappers call it to pass
+// TokenDatastore through.
+func GetTokenDatastore(ds Datastore) (TokenDatastore, error) {
+	if tokens, ok := ds.(TokenDatastore); ok {
+		return tokens, nil
+	}
+	return nil, ErrTokensUnsupported
+}
+
+var (
+	//ErrTokensUnsupported - the datastore does not store API tokens
+	ErrTokensUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("The datastore of this server does not store API tokens")}
+	//ErrTokenIDProvided - an ID was specified on token creation
+	ErrTokenIDProvided = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("ID cannot be provided for Token creation")}
+	//ErrTokenMissingName - name not specified on a token
+	ErrTokenMissingName = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing name on Token")}
+	//ErrTokenTooLongName - name exceeds maximum permitted name
+	ErrTokenTooLongName = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Token name must be %v characters or less", MaxLengthTokenName)}
+	//ErrTokenInvalidPermission - permission is not read nor write
+	ErrTokenInvalidPermission = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid Token permission, must be read or write")}
+	//ErrTokenInvalidAppID - an app ID of the token is empty or malformed
+	ErrTokenInvalidAppID = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid App ID on Token")}
+	//ErrTokenMissingHash - no secret was generated for the token
+	ErrTokenMissingHash = err{
+		code:  http.StatusInternalServerError,
+		error: errors.New("Missing hash on Token")}
+	//ErrTokenNotFound - token not found
+	ErrTokenNotFound = err{
+		code:  http.StatusNotFound,
+		error: errors.New("Token not found")}
+	//ErrAPITokenRequired - the request has no bearer token
+	ErrAPITokenRequired = err{
+		code:  http.StatusUnauthorized,
+		error: errors.New("An API token is required, as Authorization: Bearer <token>")}
+	//ErrAPITokenInvalid - the bearer token of the request is not a token
+	ErrAPITokenInvalid = err{
+		code:  http.StatusUnauthorized,
+		error: errors.New("Invalid API token")}
+	//ErrAPITokenForbidden - the token is read only, or not scoped to the resource
+	ErrAPITokenForbidden = err{
+		code:  http.StatusForbidden,
+		error: errors.New("The API token does not allow this request")}
+)
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -54,11 +55,167 @@ var (
 		code:  http.StatusNotFound,
 		error: errors.New("App not found"),
 	}
+	ErrAppsInvalidRegistryAuth = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid registry auth annotation on app"),
+	}
+	ErrAppsInvalidEgressAllow = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid egress allowlist annotation on app, must be a string of comma separated hostnames, *.domain wildcards, IP addresses or CIDR ranges, each optionally with a :port"),
+	}
+	ErrAppsInvalidMinWarm = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Invalid min warm annotation on app, must be a number of containers from 0 to %d", MaxMinWarm),
+	}
+	ErrAppsInvalidMaxBody = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid max body annotation on app, must be a number of bytes greater than 0"),
+	}
+	ErrAppsInvalidSigningKey = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Invalid signing key annotation on app, must be a string of at least %d bytes", MinSigningKeyLength),
+	}
+	ErrAppsTooManyFns = err{
+		code:  http.StatusConflict,
+		error: errors.New("App has reached the maximum number of functions"),
+	}
+	ErrAppsTooManyTriggers = err{
+		code:  http.StatusConflict,
+		error: errors.New("App has reached the maximum number of triggers"),
+	}
+	ErrTenantRequired = err{
+		code:  http.StatusUnauthorized,
+		error: errors.New("The request has no tenant"),
+	}
 )
 
+// AppRegistryAuthAnnotation is the app annotation holding registry credentials
+// used to pull the images of the app's functions, in the same format as a
+// docker config.json, see AppRegistryAuth. Credentials for a registry here
+// take precedence over the server wide registry auth. It is never returned by
+// the API, see App.Redacted.
+const AppRegistryAuthAnnotation = "fn.registry-auth"
+
+// AppInvokeCORSOriginsAnnotation is the app annotation holding a comma
+// separated list of origins (or "*") allowed to invoke the app's functions
+// from a browser. When set, it replaces the server wide invoke CORS origins
+// for the app.
+const AppInvokeCORSOriginsAnnotation = "fn.invoke-cors-origins"
+
+// AppVerifyBodyChecksumAnnotation is the app annotation which, when set to
+// true, makes the server verify the Content-MD5 and X-Fn-Content-Sha256
+// headers of calls to the app's functions against their bodies.
+const AppVerifyBodyChecksumAnnotation = "fn.verify-body-checksum"
+
+// AppRateLimitAnnotation is the app annotation holding the number of calls
+// per second (a JSON number) the app's functions may be invoked at on each
+// node, over which calls are rejected with a 429. It replaces the server wide
+// default for the app, and 0 means no limit. Only operators may set it through
+// the API, as for the other quotas AppMinWarmAnnotation and
+// AppMaxBodyAnnotation.
+const AppRateLimitAnnotation = "fn.rate-limit"
+
+// AppConfigSecretsAnnotation is the app annotation holding a comma separated
+// list of the names of the secrets of the server the config of the app's
+// functions may reference, as ${secret:NAME}, when config interpolation is
+// enabled. Config referencing any other secret is rejected, so that apps only
+// read the secrets meant for them. Only operators may set it through the API.
+// It can also be set on a single fn.
+const AppConfigSecretsAnnotation = "fn.config-secrets"
+
+// AppDecompressRequestsAnnotation is the app annotation which, when set to
+// true or false, turns on or off the decompression of the gzip or deflate
+// encoded bodies of calls to the app's functions, whatever the server wide
+// setting.
+const AppDecompressRequestsAnnotation = "fn.decompress-requests"
+
+// AppDisableLogsAnnotation is the app annotation which, when set to true,
+// stops the logs of the calls of the app's functions from being captured,
+// neither logged by the agent nor sent to the syslog URL of the app. Call
+// metadata, such as stats and listeners, is still recorded. It can also be
+// set on a single fn.
+const AppDisableLogsAnnotation = "fn.disable-logs"
+
+// AppInvokeContentTypesAnnotation is the app annotation holding a comma
+// separated list of the content types, e.g. "application/json,text/*", which
+// may be sent to the app's functions. When set, it replaces the server wide
+// list for the app, and "*/*" allows any content type.
+const AppInvokeContentTypesAnnotation = "fn.invoke-content-types"
+
+// AppIdleTimeoutAnnotation is the app annotation holding the time (in
+// seconds) the hot containers of the app's functions are kept warm while
+// idle, before they are shut down. It takes precedence over the idle_timeout
+// of the app's fns and over the server wide default, and is bounded to
+// between 1 and MaxIdleTimeout seconds. It can also be set on a single fn.
+const AppIdleTimeoutAnnotation = "fn.idle-timeout"
+
+// AppEgressAllowAnnotation is the app annotation holding a comma separated
+// list of the hosts the app's functions may reach, see ParseEgressAllow. It is
+// passed to the containers of the app's functions as EgressAllowEnv, for
+// network policies or egress proxies outside fn to enforce. The docker driver
+// itself only enforces EgressAllowNone, which denies all egress by running
+// the containers with no network; other lists are not enforced by fn.
+const AppEgressAllowAnnotation = "fn.egress-allow"
+
+// AppMinWarmAnnotation is the app annotation holding the number of idle hot
+// containers kept warm for each of the app's functions, on every node running
+// functions, even with no traffic, so that their calls don't wait for a
+// container to start. Containers are started for them when the node starts
+// and when they are used or idle out, up to FN_MAX_WARM_CONTAINERS per node.
+// Each holds the memory of its function while idle. It is bounded to
+// MaxMinWarm.
+const AppMinWarmAnnotation = "fn.min-warm"
+
+// MaxMinWarm is the highest AppMinWarmAnnotation
+const MaxMinWarm = 20
+
+// AppMinWarm returns the number of idle containers kept warm for each of the
+// functions of app, from AppMinWarmAnnotation, 0 if it has none
+func AppMinWarm(app *App) (int, error) {
+	v, ok := app.Annotations.Get(AppMinWarmAnnotation)
+	if !ok {
+		return 0, nil
+	}
+	var n int
+	if err := json.Unmarshal(v, &n); err != nil || n < 0 || n > MaxMinWarm {
+		return 0, ErrAppsInvalidMinWarm
+	}
+	return n, nil
+}
+
+// AppMaxBodyAnnotation is the app annotation holding the largest body (a
+// JSON number of bytes) of the calls to the app's functions on the trigger
+// and invoke endpoints, over which calls are rejected with a 413. It replaces
+// the server wide FN_MAX_REQUEST_SIZE for the app, higher or lower, but is
+// clamped to the ceiling of the server, FN_MAX_APP_REQUEST_SIZE.
+const AppMaxBodyAnnotation = "fn.max-body"
+
+// AppMaxBody returns the largest body of the calls to the functions of app,
+// from AppMaxBodyAnnotation, 0 if it has none
+func AppMaxBody(app *App) (int64, error) {
+	v, ok := app.Annotations.Get(AppMaxBodyAnnotation)
+	if !ok {
+		return 0, nil
+	}
+	var n int64
+	if err := json.Unmarshal(v, &n); err != nil || n <= 0 {
+		return 0, ErrAppsInvalidMaxBody
+	}
+	return n, nil
+}
+
+// AppSigningKeyAnnotation is the app annotation holding the key the URLs of
+// the app's HTTP triggers are signed with, see SignTriggerURL. When set, calls
+// through the app's triggers, or to its functions' invoke endpoints, are
+// rejected with a 403 unless their URL has a valid signature, which has not
+// expired. It is at least MinSigningKeyLength bytes long, is never passed to
+// the app's functions, and is never returned by the API, see App.Redacted.
+const AppSigningKeyAnnotation = "fn.signing-key"
+
 type App struct {
 	ID          string          `json:"id" db:"id"`
 	Name        string          `json:"name" db:"name"`
+	TenantID    string          `json:"tenant_id,omitempty" db:"tenant_id"`
 	Config      Config          `json:"config,omitempty" db:"config"`
 	Annotations Annotations     `json:"annotations,omitempty" db:"annotations"`
 	SyslogURL   *string         `json:"syslog_url,omitempty" db:"syslog_url"`
@@ -76,6 +233,26 @@ func (a *App) Validate() error {
 		return err
 	}
 
+	if _, _, err := AppEgressAllow(a); err != nil {
+		return err
+	}
+
+	if _, err := AppMinWarm(a); err != nil {
+		return err
+	}
+
+	if _, err := AppMaxBody(a); err != nil {
+		return err
+	}
+
+	if _, err := AppSigningKey(a); err != nil {
+		return err
+	}
+
+	if _, err := AppRegistryAuth(a); err != nil {
+		return err
+	}
+
 	if a.SyslogURL != nil && *a.SyslogURL != "" {
 		url, err := url.Parse(strings.TrimSpace(*a.SyslogURL))
 		if err == nil {
@@ -126,6 +303,26 @@ func (a *App) Clone() *App {
 	return clone
 }
 
+// secretAppAnnotations are the annotations of apps holding secrets, which the
+// API accepts but never returns
+var secretAppAnnotations = []string{AppRegistryAuthAnnotation, AppSigningKeyAnnotation}
+
+// Redacted returns a, or a copy of it without the annotations holding
+// secrets if it has any, to be returned by the API
+func (a *App) Redacted() *App {
+	redacted := a
+	for _, key := range secretAppAnnotations {
+		if _, ok := a.Annotations.Get(key); !ok {
+			continue
+		}
+		if redacted == a {
+			redacted = a.Clone()
+		}
+		redacted.Annotations = redacted.Annotations.Without(key)
+	}
+	return redacted
+}
+
 func (a1 *App) Equals(a2 *App) bool {
 	// start off equal, check equivalence of each field.
 	// the RHS of && won't eval if eq==false so config checking is lazy
@@ -133,6 +330,7 @@ func (a1 *App) Equals(a2 *App) bool {
 	eq := true
 	eq = eq && a1.ID == a2.ID
 	eq = eq && a1.Name == a2.Name
+	eq = eq && a1.TenantID == a2.TenantID
 	eq = eq && a1.Config.Equals(a2.Config)
 	eq = eq && a1.SyslogURL == a2.SyslogURL
 	eq = eq && a1.Annotations.Equals(a2.Annotations)
@@ -201,9 +399,11 @@ func (e ErrInvalidSyslog) Error() string { return string(e) }
 
 // AppFilter is the filter used for querying apps
 type AppFilter struct {
-	Name    string
-	PerPage int
-	Cursor  string
+	Name string
+	// TenantID only matches the apps of a tenant, if set
+	TenantID string
+	PerPage  int
+	Cursor   string
 }
 
 type AppList struct {
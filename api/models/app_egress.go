@@ -0,0 +1,90 @@
+package models
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	// EgressAllowEnv is the env var the egress allowlist of an app is passed
+	// to its containers in, comma separated, see AppEgressAllowAnnotation. It
+	// is only set if the app has an allowlist, and is EgressAllowNone if the
+	// app denies all egress.
+	EgressAllowEnv = "FN_EGRESS_ALLOW"
+
+	// EgressAllowNone is the egress allowlist which denies all egress, as
+	// empty annotations can't be stored
+	EgressAllowNone = "none"
+)
+
+// AppEgressAllow returns the egress allowlist of app, from
+// AppEgressAllowAnnotation, and whether it has one. Returns
+// ErrAppsInvalidEgressAllow if the annotation is not a valid list.
+func AppEgressAllow(app *App) ([]string, bool, error) {
+	if _, ok := app.Annotations.Get(AppEgressAllowAnnotation); !ok {
+		return nil, false, nil
+	}
+	v, err := app.Annotations.GetString(AppEgressAllowAnnotation)
+	if err != nil {
+		return nil, true, ErrAppsInvalidEgressAllow
+	}
+	allow, err := ParseEgressAllow(v)
+	return allow, true, err
+}
+
+// ParseEgressAllow parses a comma separated egress allowlist, of hostnames,
+// e.g. api.example.com, wildcards of the subdomains of a domain, e.g.
+// *.example.com, IP addresses or CIDR ranges, each optionally followed by a
+// port, e.g. api.example.com:443. EgressAllowNone allows no egress, and is
+// returned as an empty list. The entries are returned lower cased.
+func ParseEgressAllow(list string) ([]string, error) {
+	allow := []string{}
+	if strings.TrimSpace(list) == EgressAllowNone {
+		return allow, nil
+	}
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if !validEgressEntry(entry) {
+			return nil, ErrAppsInvalidEgressAllow
+		}
+		allow = append(allow, entry)
+	}
+	return allow, nil
+}
+
+func validEgressEntry(entry string) bool {
+	host := entry
+	if h, port, err := net.SplitHostPort(entry); err == nil {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return false
+		}
+		host = h
+	}
+
+	if strings.Contains(host, "/") {
+		_, _, err := net.ParseCIDR(host)
+		return err == nil
+	}
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	return validEgressHostname(strings.TrimPrefix(host, "*."))
+}
+
+func validEgressHostname(host string) bool {
+	if host == "" || len(host) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// registryAuthEntry is the entry of a registry in a docker config.json
+type registryAuthEntry struct {
+	Auth string `json:"auth"`
+}
+
+// AppRegistryAuth returns the registry credentials of app, from
+// AppRegistryAuthAnnotation, nil if it has none. They are in the docker
+// config.json format, either {"auths": {"<registry>": {"auth": "..."}}} or
+// the older {"<registry>": {"auth": "..."}}, where auth is the base64 of
+// <user>:<password>, and have credentials for at least one registry.
+func AppRegistryAuth(app *App) ([]byte, error) {
+	v, ok := app.Annotations.Get(AppRegistryAuthAnnotation)
+	if !ok {
+		return nil, nil
+	}
+
+	var wrapper struct {
+		Auths map[string]registryAuthEntry `json:"auths"`
+	}
+	var entries map[string]registryAuthEntry
+	if err := json.Unmarshal(v, &wrapper); err == nil && len(wrapper.Auths) > 0 {
+		entries = wrapper.Auths
+	} else if err := json.Unmarshal(v, &entries); err != nil {
+		// don't wrap err, it may quote the credentials
+		return nil, ErrAppsInvalidRegistryAuth
+	}
+
+	valid := 0
+	for _, e := range entries {
+		if e.Auth == "" {
+			// entries with no auth are ignored for image pulls
+			continue
+		}
+		userpass, err := base64.StdEncoding.DecodeString(e.Auth)
+		if err != nil || !strings.Contains(string(userpass), ":") {
+			return nil, ErrAppsInvalidRegistryAuth
+		}
+		valid++
+	}
+	if valid == 0 {
+		return nil, ErrAppsInvalidRegistryAuth
+	}
+	return v, nil
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestAppRegistryAuth(t *testing.T) {
+	cocoAuth := base64.StdEncoding.EncodeToString([]byte("coco:cheese"))
+	for i, test := range []struct {
+		value interface{}
+		valid bool
+	}{
+		{map[string]interface{}{"auths": map[string]interface{}{"my.registry.com": map[string]string{"auth": cocoAuth}}}, true},
+		{map[string]interface{}{"my.registry.com": map[string]string{"auth": cocoAuth}}, true},
+		{map[string]interface{}{"auths": map[string]interface{}{}}, false},
+		{map[string]interface{}{"my.registry.com": map[string]string{"auth": "not base64"}}, false},
+		{map[string]interface{}{"my.registry.com": map[string]string{"auth": base64.StdEncoding.EncodeToString([]byte("coco"))}}, false},
+		{map[string]interface{}{"my.registry.com": map[string]string{"email": "coco@example.com"}}, false},
+		{"nope", false},
+	} {
+		app := &App{Name: "myapp"}
+		app.Annotations, _ = app.Annotations.With(AppRegistryAuthAnnotation, test.value)
+
+		_, err := AppRegistryAuth(app)
+		if test.valid && err != nil {
+			t.Errorf("Test %d: expected valid registry auth, got %v", i, err)
+		}
+		if !test.valid && err != ErrAppsInvalidRegistryAuth {
+			t.Errorf("Test %d: expected %v, got %v", i, ErrAppsInvalidRegistryAuth, err)
+		}
+		if verr := app.Validate(); verr != err {
+			t.Errorf("Test %d: expected Validate to return %v, got %v", i, err, verr)
+		}
+	}
+}
+
+func TestAppRedacted(t *testing.T) {
+	app := &App{Name: "myapp"}
+	if app.Redacted() != app {
+		t.Error("expected an app without secrets to be returned as is")
+	}
+
+	app.Annotations, _ = app.Annotations.With("team", "web")
+	app.Annotations, _ = app.Annotations.With(AppRegistryAuthAnnotation, map[string]interface{}{
+		"my.registry.com": map[string]string{"auth": base64.StdEncoding.EncodeToString([]byte("coco:cheese"))},
+	})
+	app.Annotations, _ = app.Annotations.With(AppSigningKeyAnnotation, "0123456789abcdef0123456789abcdef")
+	redacted := app.Redacted()
+	if _, ok := redacted.Annotations.Get(AppRegistryAuthAnnotation); ok {
+		t.Error("expected the registry auth to be redacted")
+	}
+	if _, ok := redacted.Annotations.Get(AppSigningKeyAnnotation); ok {
+		t.Error("expected the signing key to be redacted")
+	}
+	if _, ok := redacted.Annotations.Get("team"); !ok {
+		t.Error("expected other annotations to be kept")
+	}
+	if _, ok := app.Annotations.Get(AppRegistryAuthAnnotation); !ok {
+		t.Error("expected the app itself to be left as is")
+	}
+}
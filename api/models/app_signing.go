@@ -0,0 +1,66 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// SignatureExpiresParam is the query parameter of a signed trigger URL
+	// holding the time it expires at, in seconds since the unix epoch
+	SignatureExpiresParam = "expires"
+	// SignatureParam is the query parameter of a signed trigger URL holding
+	// its signature, see TriggerURLSignature
+	SignatureParam = "signature"
+
+	// MinSigningKeyLength is the length of the shortest AppSigningKeyAnnotation
+	MinSigningKeyLength = 32
+)
+
+// AppSigningKey returns the key the trigger URLs of app are signed with, from
+// AppSigningKeyAnnotation, "" if it has none
+func AppSigningKey(app *App) (string, error) {
+	if _, ok := app.Annotations.Get(AppSigningKeyAnnotation); !ok {
+		return "", nil
+	}
+	key, err := app.Annotations.GetString(AppSigningKeyAnnotation)
+	if err != nil || len(key) < MinSigningKeyLength {
+		return "", ErrAppsInvalidSigningKey
+	}
+	return key, nil
+}
+
+// TriggerURLSignature returns the signature of a trigger URL path, expiring
+// at expires (in seconds since the unix epoch), with key: the lower case hex
+// encoded HMAC-SHA256, keyed with key, of the path, a newline, and expires in
+// decimal. path is the path of the URL as the server receives it, e.g.
+// /t/myapp/hello, including the base path of the server if it has one, before
+// any escaping, and without the query.
+func TriggerURLSignature(key, path string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(path))
+	mac.Write([]byte("\n"))
+	mac.Write([]byte(strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignTriggerURL returns rawURL, the URL of a trigger of an app with
+// AppSigningKeyAnnotation, signed with key to be valid until expires, with
+// the SignatureExpiresParam and SignatureParam query parameters set. Other
+// query parameters are not signed, so they may be changed.
+func SignTriggerURL(rawURL, key string, expires time.Time) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	exp := expires.Unix()
+	q := u.Query()
+	q.Set(SignatureExpiresParam, strconv.FormatInt(exp, 10))
+	q.Set(SignatureParam, TriggerURLSignature(key, u.Path, exp))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
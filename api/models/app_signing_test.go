@@ -0,0 +1,57 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSigningKey = "0123456789abcdef0123456789abcdef"
+
+func TestAppSigningKey(t *testing.T) {
+	for i, test := range []struct {
+		annotation interface{}
+		expected   string
+		err        error
+	}{
+		{nil, "", nil},
+		{testSigningKey, testSigningKey, nil},
+		{testSigningKey[1:], "", ErrAppsInvalidSigningKey},
+		{12345, "", ErrAppsInvalidSigningKey},
+	} {
+		app := &App{Name: "app"}
+		if test.annotation != nil {
+			app.Annotations, _ = app.Annotations.With(AppSigningKeyAnnotation, test.annotation)
+		}
+		key, err := AppSigningKey(app)
+		if err != test.err || key != test.expected {
+			t.Errorf("Test %d: expected %q %v, got %q %v", i, test.expected, test.err, key, err)
+		}
+		if err := app.Validate(); err != test.err {
+			t.Errorf("Test %d: expected app validation error %v, got %v", i, test.err, err)
+		}
+	}
+}
+
+func TestTriggerURLSignature(t *testing.T) {
+	// as computed by printf '/t/myapp/hello\n1700000000' | openssl dgst -sha256 -hmac <key>
+	expected := "6e9bc19786f725762f2c48c2439d3f5dca8e5439bb32aec1f5e1135cb6d9b27a"
+	if sig := TriggerURLSignature(testSigningKey, "/t/myapp/hello", 1700000000); sig != expected {
+		t.Fatalf("expected signature %s, got %s", expected, sig)
+	}
+
+	signed, err := SignTriggerURL("https://fn.example.com/t/myapp/hello?name=bob", testSigningKey, time.Unix(1700000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(signed, "https://fn.example.com/t/myapp/hello?") ||
+		!strings.Contains(signed, "name=bob") ||
+		!strings.Contains(signed, "expires=1700000000") ||
+		!strings.Contains(signed, "signature="+expected) {
+		t.Fatalf("unexpected signed URL %s", signed)
+	}
+
+	if _, err := SignTriggerURL("://bad", testSigningKey, time.Now()); err == nil {
+		t.Fatal("expected an invalid URL not to be signed")
+	}
+}
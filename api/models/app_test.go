@@ -42,6 +42,7 @@ func appFieldGenerators(t *testing.T) map[string]gopter.Gen {
 	fieldGens := make(map[string]gopter.Gen)
 	fieldGens["ID"] = gen.AlphaString()
 	fieldGens["Name"] = gen.AlphaString()
+	fieldGens["TenantID"] = gen.AlphaString()
 	fieldGens["Config"] = configGenerator()
 	fieldGens["Annotations"] = annotationGenerator()
 	fieldGens["SyslogURL"] = gen.AlphaString().Map(func(s string) *string {
@@ -197,3 +198,91 @@ func TestValidateApp(t *testing.T) {
 		}
 	}
 }
+
+func TestAppEgressAllow(t *testing.T) {
+	for i, test := range []struct {
+		annotation interface{}
+		expected   []string
+		ok         bool
+		err        error
+	}{
+		{nil, nil, false, nil},
+		{"none", []string{}, true, nil},
+		{"API.example.com, *.example.org:443,10.0.0.0/8,192.0.2.1,[2001:db8::1]:8080", []string{"api.example.com", "*.example.org:443", "10.0.0.0/8", "192.0.2.1", "[2001:db8::1]:8080"}, true, nil},
+		{"example.com,", nil, true, ErrAppsInvalidEgressAllow},
+		{"example.com:0", nil, true, ErrAppsInvalidEgressAllow},
+		{"http://example.com", nil, true, ErrAppsInvalidEgressAllow},
+		{"-example.com", nil, true, ErrAppsInvalidEgressAllow},
+		{"10.0.0.0/33", nil, true, ErrAppsInvalidEgressAllow},
+		{[]string{"example.com"}, nil, true, ErrAppsInvalidEgressAllow},
+	} {
+		app := &App{Name: "app"}
+		if test.annotation != nil {
+			app.Annotations, _ = app.Annotations.With(AppEgressAllowAnnotation, test.annotation)
+		}
+		allow, ok, err := AppEgressAllow(app)
+		if err != test.err || ok != test.ok || !reflect.DeepEqual(allow, test.expected) {
+			t.Errorf("Test %d: expected %v %v %v, got %v %v %v", i, test.expected, test.ok, test.err, allow, ok, err)
+		}
+		if err := app.Validate(); err != test.err {
+			t.Errorf("Test %d: expected app validation error %v, got %v", i, test.err, err)
+		}
+	}
+}
+
+func TestAppMinWarm(t *testing.T) {
+	for i, test := range []struct {
+		annotation interface{}
+		expected   int
+		err        error
+	}{
+		{nil, 0, nil},
+		{0, 0, nil},
+		{3, 3, nil},
+		{MaxMinWarm, MaxMinWarm, nil},
+		{MaxMinWarm + 1, 0, ErrAppsInvalidMinWarm},
+		{-1, 0, ErrAppsInvalidMinWarm},
+		{"3", 0, ErrAppsInvalidMinWarm},
+		{1.5, 0, ErrAppsInvalidMinWarm},
+	} {
+		app := &App{Name: "app"}
+		if test.annotation != nil {
+			app.Annotations, _ = app.Annotations.With(AppMinWarmAnnotation, test.annotation)
+		}
+		min, err := AppMinWarm(app)
+		if err != test.err || min != test.expected {
+			t.Errorf("Test %d: expected %v %v, got %v %v", i, test.expected, test.err, min, err)
+		}
+		if err := app.Validate(); err != test.err {
+			t.Errorf("Test %d: expected app validation error %v, got %v", i, test.err, err)
+		}
+	}
+}
+
+func TestAppMaxBody(t *testing.T) {
+	for i, test := range []struct {
+		annotation interface{}
+		expected   int64
+		err        error
+	}{
+		{nil, 0, nil},
+		{1, 1, nil},
+		{200 << 20, 200 << 20, nil},
+		{0, 0, ErrAppsInvalidMaxBody},
+		{-1, 0, ErrAppsInvalidMaxBody},
+		{"1024", 0, ErrAppsInvalidMaxBody},
+		{1.5, 0, ErrAppsInvalidMaxBody},
+	} {
+		app := &App{Name: "app"}
+		if test.annotation != nil {
+			app.Annotations, _ = app.Annotations.With(AppMaxBodyAnnotation, test.annotation)
+		}
+		max, err := AppMaxBody(app)
+		if err != test.err || max != test.expected {
+			t.Errorf("Test %d: expected %v %v, got %v %v", i, test.expected, test.err, max, err)
+		}
+		if err := app.Validate(); err != test.err {
+			t.Errorf("Test %d: expected app validation error %v, got %v", i, test.err, err)
+		}
+	}
+}
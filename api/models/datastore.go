@@ -76,3 +76,33 @@ type Datastore interface {
 	// implements io.Closer to shutdown
 	io.Closer
 }
+
+// MigrationStatus is the schema version of a datastore, against the version
+// the running binary migrates it to
+type MigrationStatus struct {
+	Current  int64 `json:"current_version"`
+	Expected int64 `json:"expected_version"`
+	// Dirty is set when a migration failed part way through
+	Dirty bool `json:"dirty"`
+}
+
+// InSync returns whether the schema is at the version the binary expects
+func (m *MigrationStatus) InSync() bool {
+	return !m.Dirty && m.Current == m.Expected
+}
+
+// MigrationReporter is implemented by datastores with schema migrations
+type MigrationReporter interface {
+	// MigrationStatus returns the schema version of the datastore
+	MigrationStatus(ctx context.Context) (*MigrationStatus, error)
+}
+
+// GetMigrationStatus returns the schema version of ds, or
+// ErrMigrationsUnsupported if it has no schema migrations. Datastore
+// wrappers call it to pass MigrationReporter through.
+func GetMigrationStatus(ctx context.Context, ds Datastore) (*MigrationStatus, error) {
+	if m, ok := ds.(MigrationReporter); ok {
+		return m.MigrationStatus(ctx)
+	}
+	return nil, ErrMigrationsUnsupported
+}
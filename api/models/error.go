@@ -25,6 +25,14 @@ var (
 		code:  http.StatusBadRequest,
 		error: errors.New("Invalid JSON"),
 	}
+	ErrJSONTooDeep = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("JSON is nested deeper than the limit of this server"),
+	}
+	ErrJSONTooManyTokens = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("JSON has more values than the limit of this server"),
+	}
 	ErrClientCancel = err{
 		// The special custom error code to close connection without any response
 		code:  444,
@@ -34,6 +42,26 @@ var (
 		code:  http.StatusServiceUnavailable,
 		error: errors.New("Timed out - server too busy"),
 	}
+	ErrCallQueueFull = err{
+		code:  http.StatusTooManyRequests,
+		error: errors.New("Too many calls waiting for runners - server too busy"),
+	}
+	ErrServerOverloaded = err{
+		code:  http.StatusServiceUnavailable,
+		error: errors.New("Server is overloaded, retry later"),
+	}
+	ErrInvokeRateLimited = err{
+		code:  http.StatusTooManyRequests,
+		error: errors.New("Too many calls to the functions of this app, retry later"),
+	}
+	ErrAPIRequestTimeout = err{
+		code:  http.StatusGatewayTimeout,
+		error: errors.New("Timed out processing the request"),
+	}
+	ErrRunnerAPIUnauthorized = err{
+		code:  http.StatusUnauthorized,
+		error: errors.New("A valid node certificate, or the API root token, is required to call the runner API"),
+	}
 	ErrUnsupportedMediaType = err{
 		code:  http.StatusUnsupportedMediaType,
 		error: errors.New("Content Type not supported")}
@@ -140,6 +168,26 @@ var (
 		code:  http.StatusBadRequest,
 		error: fmt.Errorf("Invalid annotation change, new key(s) exceed maximum permitted number of annotations keys (%d)", maxAnnotationsKeys),
 	}
+	ErrReservedAnnotationKey = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid annotation key, annotation keys with a reserved prefix may only be set by the platform"),
+	}
+	ErrOperatorAnnotationKey = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid annotation key, this annotation may only be set by operators, with the API root token"),
+	}
+	ErrAnnotationKeyNotAllowed = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid annotation key, annotation keys must start with one of the allowed prefixes"),
+	}
+	ErrConfigTooManyKeys = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Config has more keys than the maximum permitted"),
+	}
+	ErrConfigTooLarge = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Config keys and values are larger than the maximum permitted size"),
+	}
 	ErrTooManyRequests = err{
 		code:  http.StatusTooManyRequests,
 		error: errors.New("Too many requests submitted"),
@@ -154,6 +202,106 @@ var (
 		error: errors.New("Detach call functions are not supported on this server"),
 	}
 
+	ErrImageValidationUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("Image validation is not supported on this server"),
+	}
+
+	ErrCallCancelUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("Listing and cancelling calls is not supported on this server"),
+	}
+
+	ErrCallNotActive = err{
+		code:  http.StatusNotFound,
+		error: errors.New("Call is not executing on this server, it may have completed"),
+	}
+
+	ErrCallCanceled = err{
+		code:  http.StatusInternalServerError,
+		error: errors.New("Call was cancelled by an operator"),
+	}
+
+	ErrAdminTokenInvalid = err{
+		code:  http.StatusUnauthorized,
+		error: errors.New("An admin token is required, as Authorization: Bearer <token>"),
+	}
+
+	ErrHTTPSRequired = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("This server only accepts requests over HTTPS"),
+	}
+
+	ErrAppStatsUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("App stats are not supported on this server, as it does not run calls"),
+	}
+
+	ErrTriggerSignatureInvalid = err{
+		code:  http.StatusForbidden,
+		error: errors.New("The URL of this call must be signed, and its signature is missing or invalid"),
+	}
+
+	ErrTriggerSignatureExpired = err{
+		code:  http.StatusForbidden,
+		error: errors.New("The signed URL of this call has expired"),
+	}
+
+	ErrFnRuntimeUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("Fn runtime stats are not supported on this server, as it does not run containers"),
+	}
+
+	ErrWebSocketUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("WebSocket calls are not supported on this server, as it does not run calls"),
+	}
+
+	ErrInvalidStatsWindow = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid stats window, must be one of 1m, 5m, 15m, 1h, 6h or 24h"),
+	}
+
+	ErrInvalidBodyChecksum = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid body checksum header, Content-MD5 must be a base64 MD5 and X-Fn-Content-Sha256 a hex SHA-256 digest"),
+	}
+
+	ErrBodyChecksumMismatch = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Request body does not match its checksum header"),
+	}
+
+	ErrIdempotencyKeyTooLong = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Idempotency-Key header is too long"),
+	}
+
+	ErrIdempotencyKeyInUse = err{
+		code:  http.StatusConflict,
+		error: errors.New("A call with the same Idempotency-Key is still running"),
+	}
+
+	ErrIdempotencyStoreFull = err{
+		code:  http.StatusServiceUnavailable,
+		error: errors.New("Too many Idempotency-Keys are held by this server, try again later"),
+	}
+
+	ErrMigrationsUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("The datastore of this server does not have schema migrations"),
+	}
+
+	ErrInvalidDebugCapture = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("A debug capture requires an app_id, and a duration of at most 1h"),
+	}
+
+	ErrDebugCaptureNotActive = err{
+		code:  http.StatusNotFound,
+		error: errors.New("No debug capture is active for this app on this server, it may have expired"),
+	}
+
 	ErrCallHandlerNotFound = err{
 		code:  http.StatusInternalServerError,
 		error: errors.New("Unable to find the call handle"),
@@ -204,6 +352,10 @@ var (
 		code:  http.StatusGatewayTimeout,
 		error: errors.New("Timed out"),
 	}
+	ErrCallTimeoutServerMax = ferr{
+		code:  http.StatusGatewayTimeout,
+		error: errors.New("Timed out, the call exceeded this server's maximum timeout for synchronous calls, which is lower than the function's timeout"),
+	}
 	ErrContainerInitFail = ferr{
 		code:  http.StatusBadGateway,
 		error: errors.New("Container failed to initialize, please ensure you are using the latest fdk and check the logs"),
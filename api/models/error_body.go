@@ -1,6 +1,7 @@
 package models
 
 type Error struct {
+	Code    string `json:"code,omitempty"`
 	Message string `json:"message,omitempty"`
 	Fields  string `json:"fields,omitempty"`
 }
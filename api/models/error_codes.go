@@ -0,0 +1,341 @@
+package models
+
+import (
+	"net/http"
+	"reflect"
+)
+
+// Error codes are stable, machine readable identifiers for the errors returned
+// by the API, sent as the code of an error response alongside its message.
+// Messages may be reworded between releases, codes will not: a code may be
+// added, but never changed or reused for a different error.
+const (
+	// generic codes, for errors that have no more specific code
+	ErrorCodeBadRequest           = "bad_request"
+	ErrorCodeNotFound             = "not_found"
+	ErrorCodeMethodNotAllowed     = "method_not_allowed"
+	ErrorCodeConflict             = "conflict"
+	ErrorCodeGone                 = "gone"
+	ErrorCodeRequestTooLarge      = "request_too_large"
+	ErrorCodeUnsupportedMediaType = "unsupported_media_type"
+	ErrorCodeTooManyRequests      = "too_many_requests"
+	ErrorCodeClientCancelled      = "client_cancelled"
+	ErrorCodeInternal             = "internal_error"
+	ErrorCodeNotImplemented       = "not_implemented"
+	ErrorCodeBadGateway           = "bad_gateway"
+	ErrorCodeServiceUnavailable   = "service_unavailable"
+	ErrorCodeTimeout              = "timeout"
+
+	ErrorCodeInvalidJSON                = "invalid_json"
+	ErrorCodeJSONTooDeep                = "json_too_deep"
+	ErrorCodeJSONTooManyTokens          = "json_too_many_tokens"
+	ErrorCodeMissingID                  = "missing_id"
+	ErrorCodeMissingAppID               = "missing_app_id"
+	ErrorCodeMissingFnID                = "missing_fn_id"
+	ErrorCodeMissingName                = "missing_name"
+	ErrorCodeCreatedAtProvided          = "created_at_provided"
+	ErrorCodeUpdatedAtProvided          = "updated_at_provided"
+	ErrorCodeInvalidPayload             = "invalid_payload"
+	ErrorCodeInvalidPath                = "invalid_path"
+	ErrorCodePathNotFound               = "path_not_found"
+	ErrorCodeInvalidTime                = "invalid_time"
+	ErrorCodeInvalidMemory              = "invalid_memory"
+	ErrorCodeInvalidCPUs                = "invalid_cpus"
+	ErrorCodeInvalidAnnotation          = "invalid_annotation"
+	ErrorCodeTooManyAnnotations         = "too_many_annotations"
+	ErrorCodeConfigTooManyKeys          = "config_too_many_keys"
+	ErrorCodeConfigTooLarge             = "config_too_large"
+	ErrorCodeServerBusy                 = "server_busy"
+	ErrorCodeCallQueueFull              = "call_queue_full"
+	ErrorCodeServerOverloaded           = "server_overloaded"
+	ErrorCodeInvokeRateLimited          = "invoke_rate_limited"
+	ErrorCodeAPIRequestTimeout          = "api_request_timeout"
+	ErrorCodeRunnerAPIUnauthorized      = "runner_api_unauthorized"
+	ErrorCodeRequestLimitExceeded       = "request_limit_exceeded"
+	ErrorCodeAsyncUnsupported           = "async_unsupported"
+	ErrorCodeDetachUnsupported          = "detach_unsupported"
+	ErrorCodeImageValidationUnsupported = "image_validation_unsupported"
+	ErrorCodeCallCancelUnsupported      = "call_cancel_unsupported"
+	ErrorCodeCallNotActive              = "call_not_active"
+	ErrorCodeCallCanceled               = "call_canceled"
+	ErrorCodeAdminTokenInvalid          = "admin_token_invalid"
+	ErrorCodeHTTPSRequired              = "https_required"
+	ErrorCodeAppStatsUnsupported        = "app_stats_unsupported"
+	ErrorCodeFnRuntimeUnsupported       = "fn_runtime_unsupported"
+	ErrorCodeTriggerSignatureInvalid    = "trigger_signature_invalid"
+	ErrorCodeTriggerSignatureExpired    = "trigger_signature_expired"
+	ErrorCodeWebSocketUnsupported       = "websocket_unsupported"
+	ErrorCodeInvalidStatsWindow         = "invalid_stats_window"
+	ErrorCodeInvalidBodyChecksum        = "invalid_body_checksum"
+	ErrorCodeBodyChecksumMismatch       = "body_checksum_mismatch"
+	ErrorCodeIdempotencyKeyTooLong      = "idempotency_key_too_long"
+	ErrorCodeIdempotencyKeyInUse        = "idempotency_key_in_use"
+	ErrorCodeIdempotencyStoreFull       = "idempotency_store_full"
+	ErrorCodeMigrationsUnsupported      = "migrations_unsupported"
+	ErrorCodeInvalidDebugCapture        = "invalid_debug_capture"
+	ErrorCodeDebugCaptureNotActive      = "debug_capture_not_active"
+	ErrorCodeCallNotFound               = "call_not_found"
+	ErrorCodeCallLogNotFound            = "call_log_not_found"
+	ErrorCodeCallResourceTooBig         = "call_resource_too_big"
+	ErrorCodeCallTimeout                = "call_timeout"
+	ErrorCodeCallTimeoutServerMax       = "call_timeout_server_max"
+	ErrorCodeImagePullTimeout           = "image_pull_timeout"
+	ErrorCodeRequestContentTooBig       = "request_content_too_big"
+	ErrorCodeFunctionResponseTooBig     = "function_response_too_big"
+	ErrorCodeFunctionResponse           = "function_response_error"
+	ErrorCodeFunctionFailed             = "function_failed"
+	ErrorCodeFunctionInvalidResponse    = "function_invalid_response"
+	ErrorCodeFunctionPrematureWrite     = "function_premature_write"
+	ErrorCodeFunctionWriteRequest       = "function_write_request"
+	ErrorCodeContainerInitFailed        = "container_init_failed"
+	ErrorCodeContainerInitTimeout       = "container_init_timeout"
+	ErrorCodeSyslogUnavailable          = "syslog_unavailable"
+
+	ErrorCodeAppIDProvided          = "app_id_provided"
+	ErrorCodeAppIDMismatch          = "app_id_mismatch"
+	ErrorCodeInvalidAppName         = "invalid_app_name"
+	ErrorCodeAppExists              = "app_exists"
+	ErrorCodeAppNameImmutable       = "app_name_immutable"
+	ErrorCodeAppNotFound            = "app_not_found"
+	ErrorCodeInvalidAppRegistryAuth = "invalid_app_registry_auth"
+	ErrorCodeInvalidAppEgressAllow  = "invalid_app_egress_allow"
+	ErrorCodeInvalidAppMinWarm      = "invalid_app_min_warm"
+	ErrorCodeInvalidAppMaxBody      = "invalid_app_max_body"
+	ErrorCodeInvalidAppSigningKey   = "invalid_app_signing_key"
+	ErrorCodeAppTooManyFns          = "app_too_many_fns"
+	ErrorCodeAppTooManyTriggers     = "app_too_many_triggers"
+
+	ErrorCodeFnIDProvided       = "fn_id_provided"
+	ErrorCodeFnIDMismatch       = "fn_id_mismatch"
+	ErrorCodeInvalidFnName      = "invalid_fn_name"
+	ErrorCodeMissingImage       = "missing_image"
+	ErrorCodeInvalidImage       = "invalid_image"
+	ErrorCodeInvalidTimeout     = "invalid_timeout"
+	ErrorCodeInvalidIdleTimeout = "invalid_idle_timeout"
+	ErrorCodeFnNotFound         = "fn_not_found"
+	ErrorCodeFnExists           = "fn_exists"
+
+	ErrorCodeTriggerIDProvided           = "trigger_id_provided"
+	ErrorCodeTriggerIDMismatch           = "trigger_id_mismatch"
+	ErrorCodeInvalidTriggerName          = "invalid_trigger_name"
+	ErrorCodeTriggerFnNotInApp           = "trigger_fn_not_in_app"
+	ErrorCodeInvalidTriggerType          = "invalid_trigger_type"
+	ErrorCodeMissingTriggerSource        = "missing_trigger_source"
+	ErrorCodeInvalidTriggerPath          = "invalid_trigger_path"
+	ErrorCodeTriggerNotFound             = "trigger_not_found"
+	ErrorCodeTriggerExists               = "trigger_exists"
+	ErrorCodeTriggerSourceExists         = "trigger_source_exists"
+	ErrorCodeInvalidInputSchema          = "invalid_input_schema"
+	ErrorCodeInvalidResponseStatusHeader = "invalid_response_status_header"
+	ErrorCodeInvalidTriggerCanary        = "invalid_trigger_canary"
+	ErrorCodeInvalidTriggerCacheTTL      = "invalid_trigger_cache_ttl"
+
+	ErrorCodeTokensUnsupported      = "tokens_unsupported"
+	ErrorCodeTokenIDProvided        = "token_id_provided"
+	ErrorCodeInvalidTokenName       = "invalid_token_name"
+	ErrorCodeInvalidTokenPermission = "invalid_token_permission"
+	ErrorCodeInvalidTokenAppID      = "invalid_token_app_id"
+	ErrorCodeTokenNotFound          = "token_not_found"
+	ErrorCodeAPITokenRequired       = "api_token_required"
+	ErrorCodeAPITokenInvalid        = "api_token_invalid"
+	ErrorCodeAPITokenForbidden      = "api_token_forbidden"
+	ErrorCodeTenantRequired         = "tenant_required"
+)
+
+var errorCodes = map[error]string{
+	ErrMethodNotAllowed:             ErrorCodeMethodNotAllowed,
+	ErrInvalidJSON:                  ErrorCodeInvalidJSON,
+	ErrJSONTooDeep:                  ErrorCodeJSONTooDeep,
+	ErrJSONTooManyTokens:            ErrorCodeJSONTooManyTokens,
+	ErrClientCancel:                 ErrorCodeClientCancelled,
+	ErrCallTimeoutServerBusy:        ErrorCodeServerBusy,
+	ErrCallQueueFull:                ErrorCodeCallQueueFull,
+	ErrServerOverloaded:             ErrorCodeServerOverloaded,
+	ErrInvokeRateLimited:            ErrorCodeInvokeRateLimited,
+	ErrAPIRequestTimeout:            ErrorCodeAPIRequestTimeout,
+	ErrRunnerAPIUnauthorized:        ErrorCodeRunnerAPIUnauthorized,
+	ErrUnsupportedMediaType:         ErrorCodeUnsupportedMediaType,
+	ErrMissingID:                    ErrorCodeMissingID,
+	ErrMissingAppID:                 ErrorCodeMissingAppID,
+	ErrMissingFnID:                  ErrorCodeMissingFnID,
+	ErrMissingName:                  ErrorCodeMissingName,
+	ErrCreatedAtProvided:            ErrorCodeCreatedAtProvided,
+	ErrUpdatedAtProvided:            ErrorCodeUpdatedAtProvided,
+	ErrDatastoreEmptyApp:            ErrorCodeBadRequest,
+	ErrDatastoreEmptyCallID:         ErrorCodeMissingID,
+	ErrDatastoreEmptyFn:             ErrorCodeBadRequest,
+	ErrDatastoreEmptyFnID:           ErrorCodeMissingFnID,
+	ErrInvalidPayload:               ErrorCodeInvalidPayload,
+	ErrFoundDynamicURL:              ErrorCodeInvalidPath,
+	ErrPathMalformed:                ErrorCodeInvalidPath,
+	ErrInvalidToTime:                ErrorCodeInvalidTime,
+	ErrInvalidFromTime:              ErrorCodeInvalidTime,
+	ErrInvalidMemory:                ErrorCodeInvalidMemory,
+	ErrCallResourceTooBig:           ErrorCodeCallResourceTooBig,
+	ErrCallNotFound:                 ErrorCodeCallNotFound,
+	ErrInvalidCPUs:                  ErrorCodeInvalidCPUs,
+	ErrCallLogNotFound:              ErrorCodeCallLogNotFound,
+	ErrPathNotFound:                 ErrorCodePathNotFound,
+	ErrInvalidAnnotationKey:         ErrorCodeInvalidAnnotation,
+	ErrInvalidAnnotationKeyLength:   ErrorCodeInvalidAnnotation,
+	ErrInvalidAnnotationValue:       ErrorCodeInvalidAnnotation,
+	ErrInvalidAnnotationValueLength: ErrorCodeInvalidAnnotation,
+	ErrTooManyAnnotationKeys:        ErrorCodeTooManyAnnotations,
+	ErrReservedAnnotationKey:        ErrorCodeInvalidAnnotation,
+	ErrAnnotationKeyNotAllowed:      ErrorCodeInvalidAnnotation,
+	ErrOperatorAnnotationKey:        ErrorCodeInvalidAnnotation,
+	ErrConfigTooManyKeys:            ErrorCodeConfigTooManyKeys,
+	ErrConfigTooLarge:               ErrorCodeConfigTooLarge,
+	ErrTooManyRequests:              ErrorCodeTooManyRequests,
+	ErrAsyncUnsupported:             ErrorCodeAsyncUnsupported,
+	ErrDetachUnsupported:            ErrorCodeDetachUnsupported,
+	ErrImageValidationUnsupported:   ErrorCodeImageValidationUnsupported,
+	ErrCallCancelUnsupported:        ErrorCodeCallCancelUnsupported,
+	ErrCallNotActive:                ErrorCodeCallNotActive,
+	ErrCallCanceled:                 ErrorCodeCallCanceled,
+	ErrAdminTokenInvalid:            ErrorCodeAdminTokenInvalid,
+	ErrHTTPSRequired:                ErrorCodeHTTPSRequired,
+	ErrAppStatsUnsupported:          ErrorCodeAppStatsUnsupported,
+	ErrFnRuntimeUnsupported:         ErrorCodeFnRuntimeUnsupported,
+	ErrTriggerSignatureInvalid:      ErrorCodeTriggerSignatureInvalid,
+	ErrTriggerSignatureExpired:      ErrorCodeTriggerSignatureExpired,
+	ErrWebSocketUnsupported:         ErrorCodeWebSocketUnsupported,
+	ErrInvalidStatsWindow:           ErrorCodeInvalidStatsWindow,
+	ErrInvalidBodyChecksum:          ErrorCodeInvalidBodyChecksum,
+	ErrBodyChecksumMismatch:         ErrorCodeBodyChecksumMismatch,
+	ErrIdempotencyKeyTooLong:        ErrorCodeIdempotencyKeyTooLong,
+	ErrIdempotencyKeyInUse:          ErrorCodeIdempotencyKeyInUse,
+	ErrIdempotencyStoreFull:         ErrorCodeIdempotencyStoreFull,
+	ErrMigrationsUnsupported:        ErrorCodeMigrationsUnsupported,
+	ErrInvalidDebugCapture:          ErrorCodeInvalidDebugCapture,
+	ErrDebugCaptureNotActive:        ErrorCodeDebugCaptureNotActive,
+	ErrCallHandlerNotFound:          ErrorCodeInternal,
+	ErrServiceReservationFailure:    ErrorCodeInternal,
+	ErrDockerPullTimeout:            ErrorCodeImagePullTimeout,
+	ErrFunctionResponseTooBig:       ErrorCodeFunctionResponseTooBig,
+	ErrFunctionResponseHdrTooBig:    ErrorCodeFunctionResponseTooBig,
+	ErrFunctionResponse:             ErrorCodeFunctionResponse,
+	ErrFunctionFailed:               ErrorCodeFunctionFailed,
+	ErrFunctionInvalidResponse:      ErrorCodeFunctionInvalidResponse,
+	ErrFunctionPrematureWrite:       ErrorCodeFunctionPrematureWrite,
+	ErrFunctionWriteRequest:         ErrorCodeFunctionWriteRequest,
+	ErrRequestContentTooBig:         ErrorCodeRequestContentTooBig,
+	ErrCallTimeout:                  ErrorCodeCallTimeout,
+	ErrCallTimeoutServerMax:         ErrorCodeCallTimeoutServerMax,
+	ErrContainerInitFail:            ErrorCodeContainerInitFailed,
+	ErrContainerInitTimeout:         ErrorCodeContainerInitTimeout,
+	ErrSyslogUnavailable:            ErrorCodeSyslogUnavailable,
+	ErrRequestLimitExceeded:         ErrorCodeRequestLimitExceeded,
+
+	ErrAppsMissingID:           ErrorCodeMissingAppID,
+	ErrAppIDProvided:           ErrorCodeAppIDProvided,
+	ErrAppsIDMismatch:          ErrorCodeAppIDMismatch,
+	ErrAppsMissingName:         ErrorCodeMissingName,
+	ErrAppsTooLongName:         ErrorCodeInvalidAppName,
+	ErrAppsInvalidName:         ErrorCodeInvalidAppName,
+	ErrAppsAlreadyExists:       ErrorCodeAppExists,
+	ErrAppsMissingNew:          ErrorCodeBadRequest,
+	ErrAppsNameImmutable:       ErrorCodeAppNameImmutable,
+	ErrAppsNotFound:            ErrorCodeAppNotFound,
+	ErrAppsInvalidRegistryAuth: ErrorCodeInvalidAppRegistryAuth,
+	ErrAppsInvalidEgressAllow:  ErrorCodeInvalidAppEgressAllow,
+	ErrAppsInvalidMinWarm:      ErrorCodeInvalidAppMinWarm,
+	ErrAppsInvalidMaxBody:      ErrorCodeInvalidAppMaxBody,
+	ErrAppsInvalidSigningKey:   ErrorCodeInvalidAppSigningKey,
+	ErrAppsTooManyFns:          ErrorCodeAppTooManyFns,
+	ErrAppsTooManyTriggers:     ErrorCodeAppTooManyTriggers,
+
+	ErrFnsIDMismatch:         ErrorCodeFnIDMismatch,
+	ErrFnsIDProvided:         ErrorCodeFnIDProvided,
+	ErrFnsMissingID:          ErrorCodeMissingFnID,
+	ErrFnsMissingName:        ErrorCodeMissingName,
+	ErrFnsInvalidName:        ErrorCodeInvalidFnName,
+	ErrFnsTooLongName:        ErrorCodeInvalidFnName,
+	ErrFnsMissingAppID:       ErrorCodeMissingAppID,
+	ErrFnsMissingImage:       ErrorCodeMissingImage,
+	ErrFnsInvalidImage:       ErrorCodeInvalidImage,
+	ErrFnsInvalidTimeout:     ErrorCodeInvalidTimeout,
+	ErrFnsInvalidIdleTimeout: ErrorCodeInvalidIdleTimeout,
+	ErrFnsNotFound:           ErrorCodeFnNotFound,
+	ErrFnsExists:             ErrorCodeFnExists,
+
+	ErrTriggerIDProvided:                  ErrorCodeTriggerIDProvided,
+	ErrTriggerIDMismatch:                  ErrorCodeTriggerIDMismatch,
+	ErrTriggerMissingName:                 ErrorCodeMissingName,
+	ErrTriggerTooLongName:                 ErrorCodeInvalidTriggerName,
+	ErrTriggerInvalidName:                 ErrorCodeInvalidTriggerName,
+	ErrTriggerMissingAppID:                ErrorCodeMissingAppID,
+	ErrTriggerMissingFnID:                 ErrorCodeMissingFnID,
+	ErrTriggerFnIDNotSameApp:              ErrorCodeTriggerFnNotInApp,
+	ErrTriggerTypeUnknown:                 ErrorCodeInvalidTriggerType,
+	ErrTriggerMissingSource:               ErrorCodeMissingTriggerSource,
+	ErrTriggerMissingSourcePrefix:         ErrorCodeInvalidTriggerPath,
+	ErrTriggerNotFound:                    ErrorCodeTriggerNotFound,
+	ErrTriggerExists:                      ErrorCodeTriggerExists,
+	ErrTriggerSourceExists:                ErrorCodeTriggerSourceExists,
+	ErrTriggerInvalidInputSchema:          ErrorCodeInvalidInputSchema,
+	ErrTriggerInvalidResponseStatusHeader: ErrorCodeInvalidResponseStatusHeader,
+	ErrTriggerInvalidCanary:               ErrorCodeInvalidTriggerCanary,
+	ErrTriggerInvalidCacheTTL:             ErrorCodeInvalidTriggerCacheTTL,
+
+	ErrTokensUnsupported:      ErrorCodeTokensUnsupported,
+	ErrTokenIDProvided:        ErrorCodeTokenIDProvided,
+	ErrTokenMissingName:       ErrorCodeMissingName,
+	ErrTokenTooLongName:       ErrorCodeInvalidTokenName,
+	ErrTokenInvalidPermission: ErrorCodeInvalidTokenPermission,
+	ErrTokenInvalidAppID:      ErrorCodeInvalidTokenAppID,
+	ErrTokenMissingHash:       ErrorCodeInternal,
+	ErrTokenNotFound:          ErrorCodeTokenNotFound,
+	ErrAPITokenRequired:       ErrorCodeAPITokenRequired,
+	ErrAPITokenInvalid:        ErrorCodeAPITokenInvalid,
+	ErrAPITokenForbidden:      ErrorCodeAPITokenForbidden,
+	ErrTenantRequired:         ErrorCodeTenantRequired,
+}
+
+var statusErrorCodes = map[int]string{
+	http.StatusBadRequest:            ErrorCodeBadRequest,
+	http.StatusNotFound:              ErrorCodeNotFound,
+	http.StatusMethodNotAllowed:      ErrorCodeMethodNotAllowed,
+	http.StatusConflict:              ErrorCodeConflict,
+	http.StatusGone:                  ErrorCodeGone,
+	http.StatusRequestEntityTooLarge: ErrorCodeRequestTooLarge,
+	http.StatusUnsupportedMediaType:  ErrorCodeUnsupportedMediaType,
+	http.StatusTooManyRequests:       ErrorCodeTooManyRequests,
+	http.StatusNotImplemented:        ErrorCodeNotImplemented,
+	http.StatusBadGateway:            ErrorCodeBadGateway,
+	http.StatusServiceUnavailable:    ErrorCodeServiceUnavailable,
+	http.StatusGatewayTimeout:        ErrorCodeTimeout,
+}
+
+// ErrorCode returns the code for err, which is sent with it as the status
+// code. Errors without a specific code get a generic one for their status.
+func ErrorCode(err error, status int) string {
+	for err != nil {
+		// errors of an uncomparable type can't be map keys, and aren't ours
+		if !reflect.TypeOf(err).Comparable() {
+			break
+		}
+		if code, ok := errorCodes[err]; ok {
+			return code
+		}
+		// look through wrappers that defer to the error they wrap
+		switch e := err.(type) {
+		case apiErrorWrapper:
+			err = e.APIError
+		case *apiErrorWrapper:
+			err = e.APIError
+		case ferr:
+			err = e.error
+		default:
+			err = nil
+		}
+	}
+
+	if code, ok := statusErrorCodes[status]; ok {
+		return code
+	}
+	if status >= 400 && status < 500 {
+		return ErrorCodeBadRequest
+	}
+	return ErrorCodeInternal
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestErrorCode(t *testing.T) {
+	for i, test := range []struct {
+		err      error
+		status   int
+		expected string
+	}{
+		{ErrAppsNotFound, ErrAppsNotFound.Code(), ErrorCodeAppNotFound},
+		{ErrTriggerMissingSourcePrefix, ErrTriggerMissingSourcePrefix.Code(), ErrorCodeInvalidTriggerPath},
+		{NewFuncError(ErrAppsNotFound), ErrAppsNotFound.Code(), ErrorCodeAppNotFound},
+		{NewAPIErrorWrapper(ErrFnsNotFound, errors.New("root")), ErrFnsNotFound.Code(), ErrorCodeFnNotFound},
+		{NewAPIError(http.StatusConflict, errors.New("custom")), http.StatusConflict, ErrorCodeConflict},
+		{NewAPIError(http.StatusPaymentRequired, errors.New("custom")), http.StatusPaymentRequired, ErrorCodeBadRequest},
+		{errors.New("internal"), http.StatusInternalServerError, ErrorCodeInternal},
+		{uncomparableError{}, http.StatusBadGateway, ErrorCodeBadGateway},
+	} {
+		if code := ErrorCode(test.err, test.status); code != test.expected {
+			t.Errorf("Test %d: expected code %s for `%v`, got %s", i, test.expected, test.err, code)
+		}
+	}
+}
+
+type uncomparableError struct{ causes []error }
+
+func (uncomparableError) Error() string { return "uncomparable" }
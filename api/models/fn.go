@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -79,6 +80,15 @@ var (
 // FnInvokeEndpointAnnotation is the annotation that exposes the fn invoke endpoint For want of a better place to put this it's here
 const FnInvokeEndpointAnnotation = "fnproject.io/fn/invokeEndpoint"
 
+const (
+	// AppDefaultMemoryAnnotation is an app annotation holding the memory (in MB)
+	// applied to fns created under the app that do not specify their own.
+	AppDefaultMemoryAnnotation = "fn.default-memory"
+	// AppDefaultTimeoutAnnotation is an app annotation holding the timeout (in
+	// seconds) applied to fns created under the app that do not specify their own.
+	AppDefaultTimeoutAnnotation = "fn.default-timeout"
+)
+
 // Fn contains information about a function configuration.
 type Fn struct {
 	// ID is the generated resource id.
@@ -143,6 +153,39 @@ func (f *Fn) SetDefaults() {
 	}
 }
 
+// SetAppDefaults sets zeroed resource fields from the app's default annotations,
+// see AppDefaultMemoryAnnotation and AppDefaultTimeoutAnnotation. This must be
+// called before SetDefaults, so that an explicit fn value takes precedence over
+// the app default, which takes precedence over the system default. Defaults
+// that are not a valid json number for their field are ignored.
+func (f *Fn) SetAppDefaults(app *App) {
+	if app == nil {
+		return
+	}
+
+	if f.Memory == 0 {
+		var memory uint64
+		if getAnnotationNumber(app.Annotations, AppDefaultMemoryAnnotation, &memory) {
+			f.Memory = memory
+		}
+	}
+
+	if f.Timeout == 0 {
+		var timeout int32
+		if getAnnotationNumber(app.Annotations, AppDefaultTimeoutAnnotation, &timeout) {
+			f.Timeout = timeout
+		}
+	}
+}
+
+func getAnnotationNumber(annotations Annotations, key string, dst interface{}) bool {
+	v, ok := annotations.Get(key)
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(v, dst) == nil
+}
+
 // Validate validates all field values, returning the first error, if any.
 func (f *Fn) Validate() error {
 
@@ -183,6 +183,46 @@ func TestValidateFn(t *testing.T) {
 	}
 }
 
+func TestFnSetAppDefaults(t *testing.T) {
+	app := &App{Name: "app"}
+	app.Annotations, _ = app.Annotations.With(AppDefaultMemoryAnnotation, 256)
+	app.Annotations, _ = app.Annotations.With(AppDefaultTimeoutAnnotation, 60)
+
+	badApp := &App{Name: "app"}
+	badApp.Annotations, _ = badApp.Annotations.With(AppDefaultMemoryAnnotation, "lots")
+
+	type test struct {
+		Fn          Fn
+		App         *App
+		WantMemory  uint64
+		WantTimeout int32
+	}
+
+	for i, tc := range []test{
+		// explicit fn values win over app defaults
+		{Fn{ResourceConfig: ResourceConfig{Memory: 512, Timeout: 10}}, app, 512, 10},
+		// app defaults fill in missing values
+		{Fn{}, app, 256, 60},
+		{Fn{ResourceConfig: ResourceConfig{Memory: 512}}, app, 512, 60},
+		// system defaults apply without app defaults
+		{Fn{}, &App{Name: "app"}, DefaultMemory, DefaultTimeout},
+		{Fn{}, nil, DefaultMemory, DefaultTimeout},
+		// invalid app defaults are ignored
+		{Fn{}, badApp, DefaultMemory, DefaultTimeout},
+	} {
+		fn := tc.Fn
+		fn.SetAppDefaults(tc.App)
+		fn.SetDefaults()
+
+		if fn.Memory != tc.WantMemory {
+			t.Errorf("Test %d: expected memory %d, got %d", i, tc.WantMemory, fn.Memory)
+		}
+		if fn.Timeout != tc.WantTimeout {
+			t.Errorf("Test %d: expected timeout %d, got %d", i, tc.WantTimeout, fn.Timeout)
+		}
+	}
+}
+
 // Generate an Fn structure which passes validation
 func generateValidFn() Fn {
 	return Fn{
@@ -0,0 +1,200 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// InputSchema is the subset of JSON schema calls can be validated against.
+// The supported keywords are type (a type name or a list of them), enum,
+// minimum, maximum, minLength, maxLength, properties, required,
+// additionalProperties (as a boolean), items (as a single schema), minItems
+// and maxItems. Other keywords are ignored.
+type InputSchema struct {
+	Type                 schemaTypes             `json:"type,omitempty"`
+	Enum                 []interface{}           `json:"enum,omitempty"`
+	Minimum              *float64                `json:"minimum,omitempty"`
+	Maximum              *float64                `json:"maximum,omitempty"`
+	MinLength            *int                    `json:"minLength,omitempty"`
+	MaxLength            *int                    `json:"maxLength,omitempty"`
+	Properties           map[string]*InputSchema `json:"properties,omitempty"`
+	Required             []string                `json:"required,omitempty"`
+	AdditionalProperties *bool                   `json:"additionalProperties,omitempty"`
+	Items                *InputSchema            `json:"items,omitempty"`
+	MinItems             *int                    `json:"minItems,omitempty"`
+	MaxItems             *int                    `json:"maxItems,omitempty"`
+}
+
+var schemaTypeNames = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true, "number": true, "integer": true, "string": true,
+}
+
+// schemaTypes is the type keyword of a schema, either a name or a list of them
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err == nil {
+		*t = schemaTypes{name}
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(b, &names); err != nil {
+		return err
+	}
+	*t = names
+	return nil
+}
+
+// ParseInputSchema parses a JSON schema, checking the types it names
+func ParseInputSchema(b []byte) (*InputSchema, error) {
+	var s InputSchema
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *InputSchema) check() error {
+	for _, t := range s.Type {
+		if !schemaTypeNames[t] {
+			return fmt.Errorf("unknown type %q", t)
+		}
+	}
+	for _, p := range s.Properties {
+		if p == nil {
+			continue
+		}
+		if err := p.check(); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.check()
+	}
+	return nil
+}
+
+// Validate checks a value, as decoded by encoding/json, against the schema and
+// returns the violations found, prefixed with the path of the value in error.
+func (s *InputSchema) Validate(v interface{}) []string {
+	return s.validate("$", v, nil)
+}
+
+func (s *InputSchema) validate(path string, v interface{}, errs []string) []string {
+	if s == nil {
+		return errs
+	}
+	if len(s.Type) > 0 && !s.hasType(v) {
+		return append(errs, fmt.Sprintf("%s must be of type %s", path, joinTypes(s.Type)))
+	}
+	if len(s.Enum) > 0 && !inEnum(v, s.Enum) {
+		errs = append(errs, fmt.Sprintf("%s must be one of the enum values", path))
+	}
+
+	switch v := v.(type) {
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			errs = append(errs, fmt.Sprintf("%s must be at least %v", path, *s.Minimum))
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			errs = append(errs, fmt.Sprintf("%s must be at most %v", path, *s.Maximum))
+		}
+	case string:
+		n := len([]rune(v))
+		if s.MinLength != nil && n < *s.MinLength {
+			errs = append(errs, fmt.Sprintf("%s must be at least %d characters long", path, *s.MinLength))
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			errs = append(errs, fmt.Sprintf("%s must be at most %d characters long", path, *s.MaxLength))
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			errs = append(errs, fmt.Sprintf("%s must have at least %d items", path, *s.MinItems))
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			errs = append(errs, fmt.Sprintf("%s must have at most %d items", path, *s.MaxItems))
+		}
+		for i, item := range v {
+			errs = s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+		}
+	case map[string]interface{}:
+		for _, key := range s.Required {
+			if _, ok := v[key]; !ok {
+				errs = append(errs, fmt.Sprintf("%s.%s is required", path, key))
+			}
+		}
+		// sorted, for the errors to be stable
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if p, ok := s.Properties[key]; ok {
+				errs = p.validate(path+"."+key, v[key], errs)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				errs = append(errs, fmt.Sprintf("%s.%s is not allowed", path, key))
+			}
+		}
+	}
+	return errs
+}
+
+func (s *InputSchema) hasType(v interface{}) bool {
+	for _, t := range s.Type {
+		switch v := v.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case float64:
+			if t == "number" || (t == "integer" && v == math.Trunc(v)) {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case []interface{}:
+			if t == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if t == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func inEnum(v interface{}, enum []interface{}) bool {
+	b, _ := json.Marshal(v)
+	for _, e := range enum {
+		if eb, _ := json.Marshal(e); string(eb) == string(b) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinTypes(types []string) string {
+	if len(types) == 1 {
+		return types[0]
+	}
+	s := types[0]
+	for _, t := range types[1 : len(types)-1] {
+		s += ", " + t
+	}
+	return s + " or " + types[len(types)-1]
+}
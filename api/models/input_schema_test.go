@@ -0,0 +1,54 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestInputSchema(t *testing.T) {
+	schema, err := ParseInputSchema([]byte(`{
+		"type": "object",
+		"required": ["name"],
+		"additionalProperties": false,
+		"properties": {
+			"name": {"type": "string", "minLength": 1, "maxLength": 5},
+			"age": {"type": "integer", "minimum": 0, "maximum": 150},
+			"size": {"enum": ["s", "m", "l"]},
+			"tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}},
+			"note": {"type": ["string", "null"]}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		input    string
+		expected []string
+	}{
+		{`{"name": "bob"}`, nil},
+		{`{"name": "bob", "age": 30, "size": "m", "tags": ["a"], "note": null}`, nil},
+		{`[]`, []string{"$ must be of type object"}},
+		{`{}`, []string{"$.name is required"}},
+		{`{"name": "", "age": 1.5}`, []string{"$.age must be of type integer", "$.name must be at least 1 characters long"}},
+		{`{"name": "robert", "age": -1}`, []string{"$.age must be at least 0", "$.name must be at most 5 characters long"}},
+		{`{"name": "bob", "size": "xl", "extra": 1}`, []string{"$.extra is not allowed", "$.size must be one of the enum values"}},
+		{`{"name": "bob", "tags": ["a", 1, "c"]}`, []string{"$.tags must have at most 2 items", "$.tags[1] must be of type string"}},
+		{`{"name": "bob", "note": 1}`, []string{"$.note must be of type string or null"}},
+	} {
+		var input interface{}
+		if err := json.Unmarshal([]byte(test.input), &input); err != nil {
+			t.Fatal(err)
+		}
+		if errs := schema.Validate(input); !reflect.DeepEqual(errs, test.expected) {
+			t.Errorf("Test %d: expected errors %q, got %q", i, test.expected, errs)
+		}
+	}
+
+	for i, invalid := range []string{`"object"`, `{"type": "thing"}`, `{"properties": {"a": {"type": ["string", "str"]}}}`, `{"items": {"type": 1}}`} {
+		if _, err := ParseInputSchema([]byte(invalid)); err == nil {
+			t.Errorf("Test %d: expected schema %s to be invalid", i, invalid)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -14,6 +15,43 @@ import (
 // TriggerHTTPEndpointAnnotation is the annotation that exposes the HTTP trigger endpoint For want of a better place to put this it's here
 const TriggerHTTPEndpointAnnotation = "fnproject.io/trigger/httpEndpoint"
 
+// TriggerInputSchemaAnnotation is the trigger annotation holding a JSON
+// schema, see InputSchema, the bodies of the calls made through the trigger
+// must conform to. Calls with other bodies are rejected before they run. As
+// any annotation value, the schema is limited to 512 bytes.
+const TriggerInputSchemaAnnotation = "fn.input-schema"
+
+// TriggerResponseStatusHeaderAnnotation is the trigger annotation naming a
+// response header, e.g. "X-Status", whose value the functions of the trigger
+// may set to the status of their response. The header is removed from the
+// response, and its value, if it is a status from 200 to 599, takes precedence
+// over the Fn-Http-Status the function responds with. Errors of the platform
+// keep their status.
+const TriggerResponseStatusHeaderAnnotation = "fn.response-status-header"
+
+// TriggerCanaryAnnotation is the trigger annotation splitting the calls made
+// through the trigger between its fn and other fns of its app, e.g. a new
+// version of it, such as {"<fn_id>": 10} for 10% of the calls to go to the fn
+// of fn_id. The weights are percentages of the calls, picked at random for
+// each call. Whatever is left up to 100 goes to the fn of the trigger, which
+// may be listed too, so weights that sum to less than 100 send the rest of the
+// calls to it, and weights that sum to more than 100 are rejected. Calls meant
+// for a fn that no longer exists, or is not of the app, go to the fn of the
+// trigger.
+const TriggerCanaryAnnotation = "fn.canary"
+
+// TriggerCacheTTLAnnotation is the trigger annotation making the responses of
+// the GET calls made through the trigger cacheable, for a duration such as
+// "30s", from 1s to MaxTriggerCacheTTL. Successful responses are cached per
+// app, path and query for the duration, and served to the same calls without
+// running the fn. Clients sending Cache-Control: no-cache bypass the cache,
+// and fns may respond with Cache-Control: no-store or private for a response
+// not to be cached.
+const TriggerCacheTTLAnnotation = "fn.cache-ttl"
+
+// MaxTriggerCacheTTL is the longest TriggerCacheTTLAnnotation
+const MaxTriggerCacheTTL = time.Hour
+
 // Trigger represents a binding between a Function and an external event source
 type Trigger struct {
 	ID          string          `json:"id" db:"id"`
@@ -136,6 +174,24 @@ var (
 	ErrTriggerSourceExists = err{
 		code:  http.StatusConflict,
 		error: errors.New("Trigger with the same type and source exists on this app")}
+	//ErrTriggerInvalidInputSchema - the input schema annotation is not a supported JSON schema
+	ErrTriggerInvalidInputSchema = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid trigger input schema, must be a JSON schema object")}
+	//ErrTriggerInvalidResponseStatusHeader - the response status header annotation is not a header name
+	ErrTriggerInvalidResponseStatusHeader = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid trigger response status header, must be a string naming an HTTP header")}
+
+	//ErrTriggerInvalidCacheTTL - the cache ttl annotation is not a duration in bounds
+	ErrTriggerInvalidCacheTTL = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid trigger cache ttl, must be a duration string from 1s to 1h, e.g. \"30s\"")}
+
+	//ErrTriggerInvalidCanary - the canary annotation is not weights of fns
+	ErrTriggerInvalidCanary = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid trigger canary, must be an object of fn ids to weights from 0 to 100 summing to at most 100")}
 )
 
 //Validate checks that trigger has valid data for inserting into a store
@@ -169,9 +225,106 @@ func (t *Trigger) Validate() error {
 		return err
 	}
 
+	if _, err := t.InputSchema(); err != nil {
+		return err
+	}
+
+	if _, err := t.ResponseStatusHeader(); err != nil {
+		return err
+	}
+
+	if _, err := t.Canary(); err != nil {
+		return err
+	}
+
+	if _, err := t.CacheTTL(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// InputSchema returns the schema of TriggerInputSchemaAnnotation, or nil if
+// the trigger has none
+func (t *Trigger) InputSchema() (*InputSchema, error) {
+	v, ok := t.Annotations.Get(TriggerInputSchemaAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	schema, err := ParseInputSchema(v)
+	if err != nil {
+		return nil, ErrTriggerInvalidInputSchema
+	}
+	return schema, nil
+}
+
+// ResponseStatusHeader returns the canonical name of the header of
+// TriggerResponseStatusHeaderAnnotation, or "" if the trigger has none
+func (t *Trigger) ResponseStatusHeader() (string, error) {
+	if _, ok := t.Annotations.Get(TriggerResponseStatusHeaderAnnotation); !ok {
+		return "", nil
+	}
+	v, err := t.Annotations.GetString(TriggerResponseStatusHeaderAnnotation)
+	if err != nil || !validHeaderName(v) {
+		return "", ErrTriggerInvalidResponseStatusHeader
+	}
+	return http.CanonicalHeaderKey(v), nil
+}
+
+// validHeaderName returns whether name is a token, as header names must be
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if c > unicode.MaxASCII || !(unicode.IsLetter(c) || unicode.IsDigit(c) || strings.ContainsRune("!#$%&'*+-.^_`|~", c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Canary returns the weights of the fns of TriggerCanaryAnnotation, in
+// percentages of the calls of the trigger, or nil if the trigger has none
+func (t *Trigger) Canary() (map[string]int, error) {
+	v, ok := t.Annotations.Get(TriggerCanaryAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	var weights map[string]int
+	if err := json.Unmarshal(v, &weights); err != nil || len(weights) == 0 {
+		return nil, ErrTriggerInvalidCanary
+	}
+	sum := 0
+	for fnID, weight := range weights {
+		if fnID == "" || weight < 0 || weight > 100 {
+			return nil, ErrTriggerInvalidCanary
+		}
+		sum += weight
+	}
+	if sum > 100 {
+		return nil, ErrTriggerInvalidCanary
+	}
+	return weights, nil
+}
+
+// CacheTTL returns how long the responses of the trigger are cached, from
+// TriggerCacheTTLAnnotation, or 0 if the trigger has none
+func (t *Trigger) CacheTTL() (time.Duration, error) {
+	if _, ok := t.Annotations.Get(TriggerCacheTTLAnnotation); !ok {
+		return 0, nil
+	}
+	v, err := t.Annotations.GetString(TriggerCacheTTLAnnotation)
+	if err != nil {
+		return 0, ErrTriggerInvalidCacheTTL
+	}
+	ttl, err := time.ParseDuration(v)
+	if err != nil || ttl < time.Second || ttl > MaxTriggerCacheTTL {
+		return 0, ErrTriggerInvalidCacheTTL
+	}
+	return ttl, nil
+}
+
 func (t *Trigger) ValidateName() error {
 	if t.Name == "" {
 		return ErrTriggerMissingName
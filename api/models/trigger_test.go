@@ -215,6 +215,46 @@ func TestValidateTrigger(t *testing.T) {
 	testCases =
 		append(testCases, test{testTrigger, ErrTriggerMissingSourcePrefix})
 
+	testTrigger = generateValidTrigger()
+	testTrigger.Annotations, _ = EmptyAnnotations().With(TriggerResponseStatusHeaderAnnotation, "X-Status")
+	testCases = append(testCases, test{testTrigger, nil})
+
+	testTrigger = generateValidTrigger()
+	testTrigger.Annotations, _ = EmptyAnnotations().With(TriggerResponseStatusHeaderAnnotation, "X Status")
+	testCases = append(testCases, test{testTrigger, ErrTriggerInvalidResponseStatusHeader})
+
+	testTrigger = generateValidTrigger()
+	testTrigger.Annotations, _ = EmptyAnnotations().With(TriggerResponseStatusHeaderAnnotation, 422)
+	testCases = append(testCases, test{testTrigger, ErrTriggerInvalidResponseStatusHeader})
+
+	testTrigger = generateValidTrigger()
+	testTrigger.Annotations, _ = EmptyAnnotations().With(TriggerCanaryAnnotation, map[string]int{"fn1": 10, "fn2": 90})
+	testCases = append(testCases, test{testTrigger, nil})
+
+	testTrigger = generateValidTrigger()
+	testTrigger.Annotations, _ = EmptyAnnotations().With(TriggerCanaryAnnotation, map[string]int{"fn1": 60, "fn2": 50})
+	testCases = append(testCases, test{testTrigger, ErrTriggerInvalidCanary})
+
+	testTrigger = generateValidTrigger()
+	testTrigger.Annotations, _ = EmptyAnnotations().With(TriggerCanaryAnnotation, map[string]int{"fn1": -1})
+	testCases = append(testCases, test{testTrigger, ErrTriggerInvalidCanary})
+
+	testTrigger = generateValidTrigger()
+	testTrigger.Annotations, _ = EmptyAnnotations().With(TriggerCanaryAnnotation, "fn1")
+	testCases = append(testCases, test{testTrigger, ErrTriggerInvalidCanary})
+
+	testTrigger = generateValidTrigger()
+	testTrigger.Annotations, _ = EmptyAnnotations().With(TriggerCacheTTLAnnotation, "30s")
+	testCases = append(testCases, test{testTrigger, nil})
+
+	testTrigger = generateValidTrigger()
+	testTrigger.Annotations, _ = EmptyAnnotations().With(TriggerCacheTTLAnnotation, "2h")
+	testCases = append(testCases, test{testTrigger, ErrTriggerInvalidCacheTTL})
+
+	testTrigger = generateValidTrigger()
+	testTrigger.Annotations, _ = EmptyAnnotations().With(TriggerCacheTTLAnnotation, 30)
+	testCases = append(testCases, test{testTrigger, ErrTriggerInvalidCacheTTL})
+
 	for _, testCase := range testCases {
 		got := testCase.Trigger.Validate()
 
@@ -60,6 +60,9 @@ func (p *chPlacer) PlaceCall(ctx context.Context, rp RunnerPool, call RunnerCall
 		}
 	}
 
+	if state.IsQueueFull() {
+		return models.ErrCallQueueFull
+	}
 	if runnerPoolErr != nil {
 		// If we haven't been able to place the function and we got an error
 		// from the runner pool, return that error (since we don't have
@@ -0,0 +1,271 @@
+package runnerpool
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// CircuitState is the state of the circuit breaker of a runner
+type CircuitState int
+
+const (
+	// CircuitClosed runners are tried by placers
+	CircuitClosed CircuitState = iota
+	// CircuitHalfOpen runners are tried by a single probe call, closing the
+	// circuit if it does not fail and opening it again otherwise
+	CircuitHalfOpen
+	// CircuitOpen runners are skipped by placers until the cooldown elapses
+	CircuitOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitHalfOpen:
+		return "half-open"
+	case CircuitOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+var (
+	runnerKey = common.MakeKey("runner")
+
+	circuitStateMeasure = common.MakeMeasure("lb_runner_circuit_state", "LB Runner Circuit State (0 closed, 1 half-open, 2 open)", "")
+)
+
+// RegisterCircuitBreakerViews creates and registers the views of the runner
+// circuit breakers
+func RegisterCircuitBreakerViews(tagKeys []string) {
+	tags := []tag.Key{runnerKey}
+	for _, key := range tagKeys {
+		if key != runnerKey.Name() {
+			tags = append(tags, common.MakeKey(key))
+		}
+	}
+
+	err := view.Register(
+		common.CreateViewWithTags(circuitStateMeasure, view.LastValue(), tags),
+	)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot create view")
+	}
+}
+
+// CircuitBreakerConfig configures the circuit breakers of a CircuitBreakerPool
+type CircuitBreakerConfig struct {
+	// Failure rate of the attempts on a runner within a window that opens its circuit
+	FailureThreshold float64 `json:"failure_threshold"`
+
+	// Minimum number of attempts on a runner within a window before its failure rate is considered
+	MinAttempts int `json:"min_attempts"`
+
+	// Duration of the windows failure rates are computed over
+	Window time.Duration `json:"window"`
+
+	// Amount of time a runner is skipped once its circuit opens, before it is probed
+	Cooldown time.Duration `json:"cooldown"`
+}
+
+func NewCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		FailureThreshold: 0.5,
+		MinAttempts:      5,
+		Window:           10 * time.Second,
+		Cooldown:         30 * time.Second,
+	}
+}
+
+// CircuitStater is implemented by runner pools tracking the circuit state of
+// their runners.
+type CircuitStater interface {
+	CircuitState(addr string) CircuitState
+}
+
+// CircuitBreakerPool is a RunnerPool keeping a circuit breaker per runner of
+// the pool it wraps. Runners failing to run calls, other than by being too
+// busy, are left out of the runners returned to placers while their circuit
+// is open.
+type CircuitBreakerPool struct {
+	RunnerPool
+
+	cfg      CircuitBreakerConfig
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+// NewCircuitBreakerPool wraps rp with circuit breakers configured by cfg
+func NewCircuitBreakerPool(rp RunnerPool, cfg CircuitBreakerConfig) *CircuitBreakerPool {
+	return &CircuitBreakerPool{
+		RunnerPool: rp,
+		cfg:        cfg,
+		breakers:   make(map[string]*circuitBreaker),
+	}
+}
+
+func (rp *CircuitBreakerPool) breakerFor(r Runner) *circuitBreaker {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	b, ok := rp.breakers[r.Address()]
+	if !ok {
+		b = &circuitBreaker{cfg: &rp.cfg, addr: r.Address()}
+		rp.breakers[r.Address()] = b
+	}
+	b.runner = r
+	return b
+}
+
+// Runners implements RunnerPool. Runners with an open circuit are left out,
+// and a runner with a half-open circuit is only returned for its probe call.
+func (rp *CircuitBreakerPool) Runners(ctx context.Context, call RunnerCall) ([]Runner, error) {
+	runners, err := rp.RunnerPool.Runners(ctx, call)
+	now := time.Now()
+	allowed := make([]Runner, 0, len(runners))
+	for _, r := range runners {
+		b := rp.breakerFor(r)
+		if b.allow(ctx, now) {
+			allowed = append(allowed, &circuitRunner{Runner: r, breaker: b})
+		}
+	}
+	return allowed, err
+}
+
+// ListRunners implements RunnerLister. If the wrapped pool is not a
+// RunnerLister, the runners are those it returned for calls so far.
+func (rp *CircuitBreakerPool) ListRunners(ctx context.Context) ([]Runner, error) {
+	if lister, ok := rp.RunnerPool.(RunnerLister); ok {
+		return lister.ListRunners(ctx)
+	}
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	runners := make([]Runner, 0, len(rp.breakers))
+	for _, b := range rp.breakers {
+		runners = append(runners, b.runner)
+	}
+	return runners, nil
+}
+
+// CircuitState implements CircuitStater
+func (rp *CircuitBreakerPool) CircuitState(addr string) CircuitState {
+	rp.mu.Lock()
+	b, ok := rp.breakers[addr]
+	rp.mu.Unlock()
+	if !ok {
+		return CircuitClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+type circuitBreaker struct {
+	cfg    *CircuitBreakerConfig
+	addr   string
+	runner Runner // guarded by the mutex of the pool
+
+	mu          sync.Mutex
+	state       CircuitState
+	windowStart time.Time
+	attempts    int
+	failures    int
+	openedAt    time.Time
+	probing     bool
+	probedAt    time.Time
+}
+
+// allow returns whether the runner may be tried, moving an open circuit whose
+// cooldown elapsed to half-open and reserving its probe.
+func (b *circuitBreaker) allow(ctx context.Context, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if now.Sub(b.openedAt) < b.cfg.Cooldown {
+			return false
+		}
+		b.setState(ctx, CircuitHalfOpen)
+	case CircuitHalfOpen:
+		// a probe handed to a placer may never be tried, so it is given up
+		// on after a cooldown
+		if b.probing && now.Sub(b.probedAt) < b.cfg.Cooldown {
+			return false
+		}
+	default:
+		return true
+	}
+	b.probing, b.probedAt = true, now
+	return true
+}
+
+// record updates the circuit with the outcome of an attempt
+func (b *circuitBreaker) record(ctx context.Context, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	switch b.state {
+	case CircuitHalfOpen:
+		b.probing = false
+		if failed {
+			b.open(ctx, now)
+		} else {
+			b.setState(ctx, CircuitClosed)
+			b.windowStart, b.attempts, b.failures = now, 0, 0
+		}
+		return
+	case CircuitOpen:
+		// an attempt started before the circuit opened
+		return
+	}
+
+	if now.Sub(b.windowStart) >= b.cfg.Window {
+		b.windowStart, b.attempts, b.failures = now, 0, 0
+	}
+	b.attempts++
+	if failed {
+		b.failures++
+	}
+	if b.attempts >= b.cfg.MinAttempts && float64(b.failures) >= b.cfg.FailureThreshold*float64(b.attempts) {
+		b.open(ctx, now)
+	}
+}
+
+func (b *circuitBreaker) open(ctx context.Context, now time.Time) {
+	b.openedAt = now
+	b.setState(ctx, CircuitOpen)
+	common.Logger(ctx).WithField("runner_addr", b.addr).Warn("Opened circuit of runner")
+}
+
+func (b *circuitBreaker) setState(ctx context.Context, state CircuitState) {
+	b.state = state
+	ctx, err := tag.New(ctx, tag.Upsert(runnerKey, b.addr))
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot create tag for circuit breaker metrics")
+	}
+	stats.Record(ctx, circuitStateMeasure.M(int64(state)))
+}
+
+type circuitRunner struct {
+	Runner
+	breaker *circuitBreaker
+}
+
+// TryExec implements Runner. A runner too busy to take the call is not
+// failing, nor is one whose call was cancelled by the client.
+func (r *circuitRunner) TryExec(ctx context.Context, call RunnerCall) (bool, error) {
+	placed, err := r.Runner.TryExec(ctx, call)
+	failed := !placed && err != nil && err != models.ErrCallTimeoutServerBusy && ctx.Err() == nil
+	r.breaker.record(ctx, failed)
+	return placed, err
+}
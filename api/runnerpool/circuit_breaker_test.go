@@ -0,0 +1,82 @@
+package runnerpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// implements Runner
+type flakyRunner struct {
+	addrRunner
+	execErr error
+}
+
+func (r *flakyRunner) TryExec(ctx context.Context, call RunnerCall) (bool, error) {
+	return r.execErr == nil, r.execErr
+}
+
+func TestCircuitBreakerPool(t *testing.T) {
+	ctx := context.Background()
+	call := &dummyCall{}
+
+	flaky := &flakyRunner{addrRunner: addrRunner{addr: "a:9190"}, execErr: errors.New("connection refused")}
+	busy := &flakyRunner{addrRunner: addrRunner{addr: "b:9190"}, execErr: models.ErrCallTimeoutServerBusy}
+
+	inner := &dummyPool{}
+	inner.On("Runners", ctx, call).Return([]Runner{flaky, busy}, nil)
+
+	cfg := NewCircuitBreakerConfig()
+	cfg.MinAttempts = 2
+	cfg.Cooldown = 50 * time.Millisecond
+	rp := NewCircuitBreakerPool(inner, cfg)
+
+	tryAll := func() []string {
+		runners, err := rp.Runners(ctx, call)
+		assert.NoError(t, err)
+		var addrs []string
+		for _, r := range runners {
+			addrs = append(addrs, r.Address())
+			r.TryExec(ctx, call)
+		}
+		return addrs
+	}
+
+	// too busy runners are not failing
+	assert.Equal(t, []string{"a:9190", "b:9190"}, tryAll())
+	assert.Equal(t, []string{"a:9190", "b:9190"}, tryAll())
+	assert.Equal(t, CircuitOpen, rp.CircuitState("a:9190"))
+	assert.Equal(t, CircuitClosed, rp.CircuitState("b:9190"))
+	assert.Equal(t, []string{"b:9190"}, tryAll())
+
+	// a failed probe opens the circuit again
+	time.Sleep(cfg.Cooldown)
+	assert.Equal(t, []string{"a:9190", "b:9190"}, tryAll())
+	assert.Equal(t, CircuitOpen, rp.CircuitState("a:9190"))
+	assert.Equal(t, []string{"b:9190"}, tryAll())
+
+	// a half-open runner is only handed out for a single probe
+	time.Sleep(cfg.Cooldown)
+	runners, err := rp.Runners(ctx, call)
+	assert.NoError(t, err)
+	assert.Len(t, runners, 2)
+	assert.Equal(t, CircuitHalfOpen, rp.CircuitState("a:9190"))
+	more, err := rp.Runners(ctx, call)
+	assert.NoError(t, err)
+	assert.Len(t, more, 1)
+
+	// a successful probe closes the circuit
+	flaky.execErr = nil
+	runners[0].TryExec(ctx, call)
+	assert.Equal(t, CircuitClosed, rp.CircuitState("a:9190"))
+	assert.Equal(t, []string{"a:9190", "b:9190"}, tryAll())
+
+	infos, err := NewTrackedRunnerPool(rp).InspectRunners(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, infos, 2)
+	assert.Equal(t, "closed", infos[0].Circuit)
+}
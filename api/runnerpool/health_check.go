@@ -0,0 +1,98 @@
+package runnerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	// HealthCheckGRPC probes runners with their gRPC Status call
+	HealthCheckGRPC = "grpc"
+	// HealthCheckHTTP probes runners with an HTTP GET, e.g. to a sidecar
+	HealthCheckHTTP = "http"
+)
+
+// HealthChecker probes the health of the runners of a pool. It returns the
+// status of the runner, if the probe reports one, and an error if the runner
+// is unhealthy.
+type HealthChecker interface {
+	CheckHealth(ctx context.Context, r Runner) (*RunnerStatus, error)
+}
+
+// GRPCHealthChecker probes runners with their Status call, which fails if
+// the runner can't be reached or reports a failed status
+type GRPCHealthChecker struct{}
+
+// CheckHealth implements HealthChecker
+func (GRPCHealthChecker) CheckHealth(ctx context.Context, r Runner) (*RunnerStatus, error) {
+	status, err := r.Status(ctx)
+	switch {
+	case err != nil:
+		return nil, err
+	case status == nil:
+		return nil, errors.New("no status returned")
+	case status.StatusFailed:
+		return status, errors.New(status.ErrorStr)
+	}
+	return status, nil
+}
+
+// HTTPHealthChecker probes runners with an HTTP GET of Path on the host of the
+// runner, and Port, or the port of the runner if empty. Runners are healthy
+// if the response has ExpectedStatus, or 200 if 0.
+type HTTPHealthChecker struct {
+	Path           string
+	Port           string
+	ExpectedStatus int
+	Client         *http.Client
+}
+
+// NewHTTPHealthChecker returns a HTTPHealthChecker using the default client
+func NewHTTPHealthChecker(path, port string, expectedStatus int) *HTTPHealthChecker {
+	return &HTTPHealthChecker{Path: path, Port: port, ExpectedStatus: expectedStatus, Client: http.DefaultClient}
+}
+
+// CheckHealth implements HealthChecker
+func (hc *HTTPHealthChecker) CheckHealth(ctx context.Context, r Runner) (*RunnerStatus, error) {
+	host, port, err := net.SplitHostPort(r.Address())
+	if err != nil {
+		return nil, err
+	}
+	if hc.Port != "" {
+		port = hc.Port
+	}
+	path := hc.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+net.JoinHostPort(host, port)+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := hc.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	expected := hc.ExpectedStatus
+	if expected == 0 {
+		expected = http.StatusOK
+	}
+	if resp.StatusCode != expected {
+		return nil, fmt.Errorf("health check returned status %d, expected %d", resp.StatusCode, expected)
+	}
+	return nil, nil
+}
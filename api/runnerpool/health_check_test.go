@@ -0,0 +1,89 @@
+package runnerpool
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGRPCHealthChecker(t *testing.T) {
+	ctx := context.Background()
+
+	status, err := GRPCHealthChecker{}.CheckHealth(ctx, &addrRunner{addr: "a:9190", status: &RunnerStatus{ActiveRequestCount: 2}})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), status.ActiveRequestCount)
+
+	_, err = GRPCHealthChecker{}.CheckHealth(ctx, &addrRunner{addr: "a:9190", err: errors.New("connection refused")})
+	assert.EqualError(t, err, "connection refused")
+
+	_, err = GRPCHealthChecker{}.CheckHealth(ctx, &addrRunner{addr: "a:9190", status: &RunnerStatus{StatusFailed: true, ErrorStr: "disk full"}})
+	assert.EqualError(t, err, "disk full")
+}
+
+func TestHTTPHealthChecker(t *testing.T) {
+	ctx := context.Background()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the probe is made on the host of the runner, and the port of the sidecar
+	runner := &addrRunner{addr: net.JoinHostPort(host, "9190"), err: errors.New("status must not be called")}
+	_, err = NewHTTPHealthChecker("healthz", port, http.StatusNoContent).CheckHealth(ctx, runner)
+	assert.NoError(t, err)
+
+	_, err = NewHTTPHealthChecker("/health", port, http.StatusNoContent).CheckHealth(ctx, runner)
+	assert.EqualError(t, err, "health check returned status 503, expected 204")
+
+	_, err = NewHTTPHealthChecker("/healthz", port, 0).CheckHealth(ctx, runner)
+	assert.EqualError(t, err, "health check returned status 204, expected 200")
+
+	// with no port, the port of the runner is probed
+	_, err = NewHTTPHealthChecker("/healthz", "", http.StatusNoContent).CheckHealth(ctx, &addrRunner{addr: srv.Listener.Addr().String()})
+	assert.NoError(t, err)
+
+	srv.Close()
+	_, err = NewHTTPHealthChecker("/healthz", port, http.StatusNoContent).CheckHealth(ctx, runner)
+	assert.Error(t, err)
+}
+
+func TestTrackedRunnerPoolHealthChecker(t *testing.T) {
+	ctx := context.Background()
+	call := &dummyCall{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	up := &addrRunner{addr: srv.Listener.Addr().String(), placed: true}
+	down := &addrRunner{addr: "127.0.0.1:1", placed: true}
+
+	inner := &dummyPool{}
+	inner.On("Runners", ctx, call).Return([]Runner{up, down}, nil)
+	rp := NewTrackedRunnerPoolWithHealthChecker(inner, NewHTTPHealthChecker("/", "", 0))
+	_, err := rp.Runners(ctx, call)
+	assert.NoError(t, err)
+
+	infos, err := rp.InspectRunners(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, infos, 2)
+	for _, info := range infos {
+		if healthy := info.Address == up.addr; info.Healthy != healthy || (info.Error == "") != healthy {
+			t.Errorf("expected %s to be healthy %v, got %v with error %q", info.Address, healthy, info.Healthy, info.Error)
+		}
+	}
+}
@@ -51,6 +51,9 @@ func (sp *naivePlacer) PlaceCall(ctx context.Context, rp RunnerPool, call Runner
 		}
 	}
 
+	if state.IsQueueFull() {
+		return models.ErrCallQueueFull
+	}
 	if runnerPoolErr != nil {
 		// If we haven't been able to place the function and we got an error
 		// from the runner pool, return that error (since we don't have
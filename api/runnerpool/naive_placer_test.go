@@ -227,3 +227,61 @@ func TestNaivePlacer_SimpleList_NoError(t *testing.T) {
 	// should not be more than 1
 	assert.True(t, math.Abs(float64(r1Count)-float64(r2Count)) <= float64(1), "runner hit count inbalance")
 }
+
+// Runners busy with a full queue, should be rejected after one pass over the runners
+func TestNaivePlacer_SimpleList_QueueFull(t *testing.T) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(2*time.Second))
+	defer cancel()
+
+	cfg := NewPlacerConfig()
+	cfg.PlacerTimeout = time.Duration(500 * time.Millisecond)
+	cfg.MaxQueueDepth = 1
+	placer := NewNaivePlacer(&cfg)
+
+	// another call is waiting already
+	cfg.queue.enter(0)
+
+	pool := &dummyPool{}
+	call := &dummyCall{}
+
+	runner1 := &dummyRunner{}
+	runner1.On("TryExec", mock.AnythingOfType("*context.cancelCtx"), call).Return(false, models.ErrCallTimeoutServerBusy)
+	pool.On("Runners", ctx, call).Return([]Runner{runner1}, nil)
+
+	// we should get 429
+	assert.Equal(t, models.ErrCallQueueFull, placer.PlaceCall(ctx, pool, call))
+
+	pCount := CallCount(&pool.Mock, "Runners")
+	assert.True(t, pCount == 1, "should not be spinning, hit count %d", pCount)
+	assert.Equal(t, int64(1), cfg.queue.depth)
+
+	// the waiting call leaves, this one can wait for runners now
+	cfg.queue.leave()
+	assert.Equal(t, models.ErrCallTimeoutServerBusy, placer.PlaceCall(ctx, pool, call))
+	assert.Equal(t, int64(0), cfg.queue.depth)
+}
+
+// Runners busy and the client goes away, should leave the queue without waiting for the placer timeout
+func TestNaivePlacer_SimpleList_QueueCancel(t *testing.T) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(50*time.Millisecond))
+	defer cancel()
+
+	cfg := NewPlacerConfig()
+	cfg.PlacerTimeout = time.Duration(5 * time.Second)
+	cfg.MaxQueueDepth = 1
+	placer := NewNaivePlacer(&cfg)
+
+	pool := &dummyPool{}
+	call := &dummyCall{}
+
+	runner1 := &dummyRunner{}
+	runner1.On("TryExec", mock.AnythingOfType("*context.cancelCtx"), call).Return(false, models.ErrCallTimeoutServerBusy)
+	pool.On("Runners", ctx, call).Return([]Runner{runner1}, nil)
+
+	start := time.Now()
+	assert.Equal(t, models.ErrCallTimeoutServerBusy, placer.PlaceCall(ctx, pool, call))
+	assert.True(t, time.Since(start) < time.Second, "should not wait for the placer timeout")
+	assert.Equal(t, int64(0), cfg.queue.depth)
+}
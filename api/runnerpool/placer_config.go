@@ -1,6 +1,7 @@
 package runnerpool
 
 import (
+	"sync/atomic"
 	"time"
 )
 
@@ -14,6 +15,13 @@ type PlacerConfig struct {
 
 	// Maximum amount of time a placer can hold an ack sync request during runner attempts
 	DetachedPlacerTimeout time.Duration `json:"detached_placer_timeout"`
+
+	// Maximum number of calls waiting for runners to free up at once, calls beyond it are
+	// rejected rather than held. 0 is unbounded.
+	MaxQueueDepth int `json:"max_queue_depth"`
+
+	// queue counts the calls waiting for runners, shared by the copies of the config
+	queue *placerQueue
 }
 
 func NewPlacerConfig() PlacerConfig {
@@ -21,5 +29,27 @@ func NewPlacerConfig() PlacerConfig {
 		RetryAllDelay:         10 * time.Millisecond,
 		PlacerTimeout:         360 * time.Second,
 		DetachedPlacerTimeout: 30 * time.Second,
+		queue:                 new(placerQueue),
 	}
 }
+
+// placerQueue counts the calls a placer holds while all runners are busy
+type placerQueue struct {
+	depth int64
+}
+
+// enter adds a call to the queue unless it holds max calls already, it
+// returns the new depth or -1 if the queue is full
+func (q *placerQueue) enter(max int) int64 {
+	depth := atomic.AddInt64(&q.depth, 1)
+	if max > 0 && depth > int64(max) {
+		atomic.AddInt64(&q.depth, -1)
+		return -1
+	}
+	return depth
+}
+
+// leave removes a call from the queue and returns the new depth
+func (q *placerQueue) leave() int64 {
+	return atomic.AddInt64(&q.depth, -1)
+}
@@ -0,0 +1,60 @@
+package runnerpool
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// PlacerNaive is the name of the placer of NewNaivePlacer, the default
+	PlacerNaive = "naive"
+	// PlacerCH is the name of the placer of NewCHPlacer
+	PlacerCH = "ch"
+)
+
+// PlacerFactory creates a placer from the config of the lb, which it should
+// keep and return from GetPlacerConfig. The config sets how long calls may
+// be held while placing them, see PlacerConfig.
+type PlacerFactory func(cfg *PlacerConfig) Placer
+
+var (
+	placersMu sync.RWMutex
+	placers   = map[string]PlacerFactory{
+		PlacerNaive: NewNaivePlacer,
+		PlacerCH:    NewCHPlacer,
+	}
+)
+
+// RegisterPlacer globally registers a placer by name, which lb nodes use
+// when FN_PLACER is set to it. It replaces any placer registered under the
+// same name, including the built-in naive and ch placers. It should be called
+// before the server is created, e.g. from the init of an extension.
+func RegisterPlacer(name string, factory PlacerFactory) {
+	placersMu.Lock()
+	defer placersMu.Unlock()
+	placers[name] = factory
+}
+
+// NewPlacer creates the placer registered as name, or the naive placer if
+// name is empty. Returns an error if no placer is registered as name.
+func NewPlacer(name string, cfg *PlacerConfig) (Placer, error) {
+	if name == "" {
+		name = PlacerNaive
+	}
+
+	placersMu.RLock()
+	factory, ok := placers[name]
+	names := make([]string, 0, len(placers))
+	for n := range placers {
+		names = append(names, n)
+	}
+	placersMu.RUnlock()
+
+	if !ok {
+		sort.Strings(names)
+		return nil, fmt.Errorf("no placer registered as %q, registered placers are %s", name, strings.Join(names, ", "))
+	}
+	return factory(cfg), nil
+}
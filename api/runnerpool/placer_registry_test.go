@@ -0,0 +1,51 @@
+package runnerpool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type firstRunnerPlacer struct {
+	cfg PlacerConfig
+}
+
+func (p *firstRunnerPlacer) GetPlacerConfig() PlacerConfig { return p.cfg }
+
+func (p *firstRunnerPlacer) PlaceCall(ctx context.Context, rp RunnerPool, call RunnerCall) error {
+	runners, err := rp.Runners(ctx, call)
+	if err != nil {
+		return err
+	}
+	_, err = runners[0].TryExec(ctx, call)
+	return err
+}
+
+func TestRegisterPlacer(t *testing.T) {
+	RegisterPlacer("first", func(cfg *PlacerConfig) Placer { return &firstRunnerPlacer{cfg: *cfg} })
+
+	cfg := NewPlacerConfig()
+	cfg.PlacerTimeout = time.Second
+	placer, err := NewPlacer("first", &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := placer.(*firstRunnerPlacer); !ok {
+		t.Fatalf("expected the registered placer, got %T", placer)
+	}
+	if placer.GetPlacerConfig().PlacerTimeout != time.Second {
+		t.Fatalf("expected the placer to be created with the config, got %+v", placer.GetPlacerConfig())
+	}
+
+	placer, err = NewPlacer("", &cfg)
+	if _, ok := placer.(*naivePlacer); err != nil || !ok {
+		t.Fatalf("expected the naive placer by default, got %T %v", placer, err)
+	}
+	placer, err = NewPlacer(PlacerCH, &cfg)
+	if _, ok := placer.(*chPlacer); err != nil || !ok {
+		t.Fatalf("expected the ch placer, got %T %v", placer, err)
+	}
+	if _, err := NewPlacer("gpu", &cfg); err == nil {
+		t.Fatal("expected an unregistered placer to be an error")
+	}
+}
@@ -25,6 +25,9 @@ var (
 	retryTooBusyCountMeasure = common.MakeMeasure("lb_placer_retry_busy_count", "LB Placer Retry Count - Too Busy", "")
 	retryErrorCountMeasure   = common.MakeMeasure("lb_placer_retry_error_count", "LB Placer Retry Count - Errors", "")
 	placerLatencyMeasure     = common.MakeMeasure("lb_placer_latency", "LB Placer Latency", "msecs")
+	queueDepthMeasure        = common.MakeMeasure("lb_placer_queue_depth", "LB Placer Calls Waiting For Runners", "")
+	queueWaitMeasure         = common.MakeMeasure("lb_placer_queue_wait", "LB Placer Time Waiting For Runners", "msecs")
+	queueFullCountMeasure    = common.MakeMeasure("lb_placer_queue_full_count", "LB Placer Rejected Call Count - Queue Full", "")
 )
 
 // Helper struct for tracking LB Placer latency and attempt counts
@@ -81,6 +84,9 @@ func RegisterPlacerViews(tagKeys []string, latencyDist []float64) {
 		common.CreateView(retryTooBusyCountMeasure, view.Count(), tagKeys),
 		common.CreateView(retryErrorCountMeasure, view.Count(), tagKeys),
 		common.CreateView(placerLatencyMeasure, view.Distribution(latencyDist...), tagKeys),
+		common.CreateView(queueDepthMeasure, view.LastValue(), tagKeys),
+		common.CreateView(queueWaitMeasure, view.Distribution(latencyDist...), tagKeys),
+		common.CreateView(queueFullCountMeasure, view.Count(), tagKeys),
 	)
 	if err != nil {
 		logrus.WithError(err).Fatal("cannot create view")
@@ -2,6 +2,7 @@ package runnerpool
 
 import (
 	"context"
+	"time"
 
 	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/models"
@@ -16,6 +17,8 @@ type placerTracker struct {
 	cancel     context.CancelFunc
 	tracker    *attemptTracker
 	isPlaced   bool
+	queuedAt   time.Time
+	queueFull  bool
 }
 
 func NewPlacerTracker(requestCtx context.Context, cfg *PlacerConfig, call RunnerCall) *placerTracker {
@@ -107,10 +110,39 @@ func (tr *placerTracker) HandleDone() {
 		stats.Record(tr.requestCtx, placerTimeoutMeasure.M(0))
 	}
 
+	if !tr.queuedAt.IsZero() {
+		stats.Record(tr.requestCtx,
+			queueDepthMeasure.M(tr.cfg.queue.leave()),
+			queueWaitMeasure.M(int64(time.Since(tr.queuedAt)/time.Millisecond)))
+	}
+
 	tr.tracker.finalizeAttempts(tr.isPlaced)
 	tr.cancel()
 }
 
+// enterQueue adds the call to the queue of the placer, once, and returns
+// false if the queue is full
+func (tr *placerTracker) enterQueue() bool {
+	if tr.cfg.queue == nil || !tr.queuedAt.IsZero() {
+		return true
+	}
+	depth := tr.cfg.queue.enter(tr.cfg.MaxQueueDepth)
+	if depth < 0 {
+		tr.queueFull = true
+		stats.Record(tr.requestCtx, queueFullCountMeasure.M(0))
+		return false
+	}
+	tr.queuedAt = time.Now()
+	stats.Record(tr.requestCtx, queueDepthMeasure.M(depth))
+	return true
+}
+
+// IsQueueFull returns whether the call was rejected as too many calls wait
+// for runners already
+func (tr *placerTracker) IsQueueFull() bool {
+	return tr.queueFull
+}
+
 // RetryAllBackoff blocks until it is time to try the runner list again. Returns
 // false if the placer should stop trying.
 func (tr *placerTracker) RetryAllBackoff(numOfRunners int, err error) bool {
@@ -136,6 +168,11 @@ func (tr *placerTracker) RetryAllBackoff(numOfRunners int, err error) bool {
 		}
 	}
 
+	// all runners are busy, wait for one in the queue
+	if !tr.enterQueue() {
+		return false
+	}
+
 	t := common.NewTimer(tr.cfg.RetryAllDelay)
 	defer t.Stop()
 
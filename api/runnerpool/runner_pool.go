@@ -11,9 +11,22 @@ import (
 )
 
 // Placer implements a placement strategy for calls that are load-balanced
-// across runners in a pool
+// across runners in a pool. Custom placers are registered with RegisterPlacer.
 type Placer interface {
+	// PlaceCall tries the runners of rp, in the order of the strategy, until
+	// one of them runs the call, i.e. TryExec returns true, and then returns
+	// the error TryExec returned, nil if the call succeeded. Runners which
+	// return false are busy or failed before running the call, and the next
+	// should be tried. It may retry the runners until the placer timeout of
+	// its config, or ctx, is done, and then returns
+	// models.ErrCallTimeoutServerBusy, or the error of rp if it failed.
+	// Calls are run with the context of the request, which ctx is, never one
+	// bound to the placer timeout. NewPlacerTracker implements the timeouts,
+	// queue depth and metrics of the built-in placers, which custom placers
+	// should use too. It is called concurrently.
 	PlaceCall(ctx context.Context, rp RunnerPool, call RunnerCall) error
+
+	// GetPlacerConfig returns the config the placer was created with.
 	GetPlacerConfig() PlacerConfig
 }
 
@@ -0,0 +1,209 @@
+package runnerpool
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// runnerStatusTimeout bounds the Status calls made to inspect runners
+	runnerStatusTimeout = 5 * time.Second
+
+	// trackedPruneInterval is how often the runners gone from a RunnerLister
+	// pool are dropped as calls are placed, along with their counts
+	trackedPruneInterval = time.Minute
+)
+
+// RunnerLister is implemented by runner pools that can list all of their
+// runners, rather than those for a given call.
+type RunnerLister interface {
+	ListRunners(ctx context.Context) ([]Runner, error)
+}
+
+// RunnerInfo is the state of a runner of a pool, as returned by InspectRunners.
+// Attempts and Placed count the calls tried and placed on the runner since the
+// pool was created. Circuit is the state of the circuit breaker of the runner,
+// if the wrapped pool is a CircuitStater.
+type RunnerInfo struct {
+	Address        string `json:"address"`
+	Healthy        bool   `json:"healthy"`
+	Error          string `json:"error,omitempty"`
+	ActiveRequests int32  `json:"active_requests"`
+	Attempts       uint64 `json:"attempts"`
+	Placed         uint64 `json:"placed"`
+	Circuit        string `json:"circuit,omitempty"`
+}
+
+type runnerCounts struct {
+	attempts uint64
+	placed   uint64
+}
+
+// TrackedRunnerPool is a RunnerPool counting the calls tried and placed on each
+// of the runners of the pool it wraps, so that they can be inspected along with
+// the health of the runners. If the wrapped pool is a RunnerLister, the runners
+// it no longer lists are dropped, e.g. as runners are scaled in or replaced.
+type TrackedRunnerPool struct {
+	RunnerPool
+
+	health     HealthChecker
+	mu         sync.Mutex
+	runners    map[string]Runner
+	counts     map[string]*runnerCounts
+	lastPruned time.Time
+}
+
+// NewTrackedRunnerPool wraps rp to track its runners, probing their health
+// with their gRPC Status call
+func NewTrackedRunnerPool(rp RunnerPool) *TrackedRunnerPool {
+	return NewTrackedRunnerPoolWithHealthChecker(rp, GRPCHealthChecker{})
+}
+
+// NewTrackedRunnerPoolWithHealthChecker wraps rp to track its runners,
+// probing their health with hc
+func NewTrackedRunnerPoolWithHealthChecker(rp RunnerPool, hc HealthChecker) *TrackedRunnerPool {
+	return &TrackedRunnerPool{
+		RunnerPool: rp,
+		health:     hc,
+		runners:    make(map[string]Runner),
+		counts:     make(map[string]*runnerCounts),
+	}
+}
+
+func (rp *TrackedRunnerPool) countsFor(r Runner) *runnerCounts {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.runners[r.Address()] = r
+	c, ok := rp.counts[r.Address()]
+	if !ok {
+		c = new(runnerCounts)
+		rp.counts[r.Address()] = c
+	}
+	return c
+}
+
+// prune drops the runners missing from listed, the runners of the pool
+func (rp *TrackedRunnerPool) prune(listed []Runner) {
+	addrs := make(map[string]bool, len(listed))
+	for _, r := range listed {
+		addrs[r.Address()] = true
+	}
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	for addr := range rp.runners {
+		if !addrs[addr] {
+			delete(rp.runners, addr)
+			delete(rp.counts, addr)
+		}
+	}
+	rp.lastPruned = time.Now()
+}
+
+// pruneListed drops the runners gone from the wrapped pool, if it is a
+// RunnerLister and they were not pruned within trackedPruneInterval
+func (rp *TrackedRunnerPool) pruneListed(ctx context.Context) {
+	lister, ok := rp.RunnerPool.(RunnerLister)
+	if !ok {
+		return
+	}
+	rp.mu.Lock()
+	due := time.Since(rp.lastPruned) >= trackedPruneInterval
+	if due {
+		// other calls don't prune meanwhile
+		rp.lastPruned = time.Now()
+	}
+	rp.mu.Unlock()
+	if !due {
+		return
+	}
+
+	if listed, err := lister.ListRunners(ctx); err == nil {
+		rp.prune(listed)
+	}
+}
+
+// Runners implements RunnerPool
+func (rp *TrackedRunnerPool) Runners(ctx context.Context, call RunnerCall) ([]Runner, error) {
+	rp.pruneListed(ctx)
+	runners, err := rp.RunnerPool.Runners(ctx, call)
+	tracked := make([]Runner, len(runners))
+	for i, r := range runners {
+		tracked[i] = &trackedRunner{Runner: r, counts: rp.countsFor(r)}
+	}
+	return tracked, err
+}
+
+// InspectRunners returns the runners of the pool, sorted by address, with
+// their health as probed by the health checker of the pool. If the wrapped pool is not a
+// RunnerLister, the runners are those it returned for calls so far.
+func (rp *TrackedRunnerPool) InspectRunners(ctx context.Context) ([]RunnerInfo, error) {
+	var runners []Runner
+	if lister, ok := rp.RunnerPool.(RunnerLister); ok {
+		var err error
+		runners, err = lister.ListRunners(ctx)
+		if err != nil {
+			return nil, err
+		}
+		rp.prune(runners)
+	} else {
+		rp.mu.Lock()
+		for _, r := range rp.runners {
+			runners = append(runners, r)
+		}
+		rp.mu.Unlock()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, runnerStatusTimeout)
+	defer cancel()
+
+	infos := make([]RunnerInfo, len(runners))
+	var wg sync.WaitGroup
+	for i, r := range runners {
+		counts := rp.countsFor(r)
+		infos[i] = RunnerInfo{
+			Address:  r.Address(),
+			Attempts: atomic.LoadUint64(&counts.attempts),
+			Placed:   atomic.LoadUint64(&counts.placed),
+		}
+		if cs, ok := rp.RunnerPool.(CircuitStater); ok {
+			infos[i].Circuit = cs.CircuitState(r.Address()).String()
+		}
+
+		wg.Add(1)
+		go func(info *RunnerInfo, r Runner) {
+			defer wg.Done()
+			status, err := rp.health.CheckHealth(ctx, r)
+			if status != nil {
+				info.ActiveRequests = status.ActiveRequestCount
+			}
+			if err != nil {
+				info.Error = err.Error()
+			} else {
+				info.Healthy = true
+			}
+		}(&infos[i], r)
+	}
+	wg.Wait()
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Address < infos[j].Address })
+	return infos, nil
+}
+
+type trackedRunner struct {
+	Runner
+	counts *runnerCounts
+}
+
+// TryExec implements Runner
+func (r *trackedRunner) TryExec(ctx context.Context, call RunnerCall) (bool, error) {
+	atomic.AddUint64(&r.counts.attempts, 1)
+	placed, err := r.Runner.TryExec(ctx, call)
+	if placed {
+		atomic.AddUint64(&r.counts.placed, 1)
+	}
+	return placed, err
+}
@@ -0,0 +1,107 @@
+package runnerpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// implements Runner
+type addrRunner struct {
+	addr   string
+	placed bool
+	status *RunnerStatus
+	err    error
+}
+
+func (r *addrRunner) Status(ctx context.Context) (*RunnerStatus, error) { return r.status, r.err }
+func (r *addrRunner) Close(ctx context.Context) error                   { return nil }
+func (r *addrRunner) Address() string                                   { return r.addr }
+func (r *addrRunner) TryExec(ctx context.Context, call RunnerCall) (bool, error) {
+	return r.placed, nil
+}
+
+func TestTrackedRunnerPool(t *testing.T) {
+	ctx := context.Background()
+	call := &dummyCall{}
+
+	busy := &addrRunner{addr: "b:9190", status: &RunnerStatus{ActiveRequestCount: 3}}
+	ok := &addrRunner{addr: "a:9190", placed: true, status: &RunnerStatus{ActiveRequestCount: 1}}
+	down := &addrRunner{addr: "c:9190", err: errors.New("connection refused")}
+
+	inner := &dummyPool{}
+	inner.On("Runners", ctx, call).Return([]Runner{busy, ok, down}, nil)
+	rp := NewTrackedRunnerPool(inner)
+
+	for i := 0; i < 2; i++ {
+		runners, err := rp.Runners(ctx, call)
+		assert.NoError(t, err)
+		for _, r := range runners {
+			if placed, _ := r.TryExec(ctx, call); placed {
+				break
+			}
+		}
+	}
+
+	infos, err := rp.InspectRunners(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []RunnerInfo{
+		{Address: "a:9190", Healthy: true, ActiveRequests: 1, Attempts: 2, Placed: 2},
+		{Address: "b:9190", Healthy: true, ActiveRequests: 3, Attempts: 2, Placed: 0},
+		{Address: "c:9190", Error: "connection refused"},
+	}, infos)
+}
+
+// implements RunnerPool and RunnerLister
+type listerPool struct {
+	runners []Runner
+}
+
+func (p *listerPool) Shutdown(ctx context.Context) error { return nil }
+func (p *listerPool) Runners(ctx context.Context, call RunnerCall) ([]Runner, error) {
+	return p.runners, nil
+}
+func (p *listerPool) ListRunners(ctx context.Context) ([]Runner, error) { return p.runners, nil }
+
+func TestTrackedRunnerPoolPrune(t *testing.T) {
+	ctx := context.Background()
+	call := &dummyCall{}
+
+	a := &addrRunner{addr: "a:9190", status: &RunnerStatus{}}
+	b := &addrRunner{addr: "b:9190", status: &RunnerStatus{}}
+	inner := &listerPool{runners: []Runner{a, b}}
+	rp := NewTrackedRunnerPool(inner)
+
+	runners, err := rp.Runners(ctx, call)
+	assert.NoError(t, err)
+	for _, r := range runners {
+		r.TryExec(ctx, call)
+	}
+
+	// b is replaced by c, b is dropped once inspected
+	c := &addrRunner{addr: "c:9190", status: &RunnerStatus{}}
+	inner.runners = []Runner{a, c}
+	_, err = rp.Runners(ctx, call)
+	assert.NoError(t, err)
+	assert.Len(t, rp.counts, 3, "runners are pruned at most every trackedPruneInterval")
+
+	infos, err := rp.InspectRunners(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []RunnerInfo{
+		{Address: "a:9190", Healthy: true, Attempts: 1},
+		{Address: "c:9190", Healthy: true},
+	}, infos)
+	assert.Len(t, rp.runners, 2)
+	assert.Len(t, rp.counts, 2)
+
+	// and as calls are placed
+	inner.runners = []Runner{c}
+	rp.lastPruned = time.Time{}
+	_, err = rp.Runners(ctx, call)
+	assert.NoError(t, err)
+	assert.Len(t, rp.counts, 1)
+	assert.Contains(t, rp.counts, "c:9190")
+}
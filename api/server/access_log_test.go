@@ -0,0 +1,61 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAccessLogSampling(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	request := func(rate float64, rid string, status int) (started, completed bool) {
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			c.Request = c.Request.WithContext(common.WithRequestID(c.Request.Context(), rid))
+		}, accessLogWrap(rate))
+		r.GET("/", func(c *gin.Context) { c.Status(status) })
+
+		buf.Reset()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		return strings.Contains(buf.String(), "request started"), strings.Contains(buf.String(), "request completed")
+	}
+
+	if started, completed := request(1, "rid", http.StatusOK); !started || !completed {
+		t.Errorf("expected every request to be logged at rate 1, got started %v completed %v", started, completed)
+	}
+	if started, completed := request(0, "rid", http.StatusOK); started || completed {
+		t.Errorf("expected no successful request to be logged at rate 0, got started %v completed %v", started, completed)
+	}
+	if _, completed := request(0, "rid", http.StatusInternalServerError); !completed {
+		t.Error("expected errors to be logged at rate 0")
+	}
+
+	var sampled int
+	for i := 0; i < 1000; i++ {
+		rid := fmt.Sprintf("rid-%d", i)
+		started, completed := request(0.25, rid, http.StatusOK)
+		if started != completed {
+			t.Fatalf("expected request %s to log both its start and end or neither, got started %v completed %v", rid, started, completed)
+		}
+		if again, _ := request(0.25, rid, http.StatusOK); again != started {
+			t.Fatalf("expected the sampling of request %s to be consistent", rid)
+		}
+		if started {
+			sampled++
+		}
+	}
+	if sampled < 150 || sampled > 350 {
+		t.Errorf("expected about 250 of 1000 requests to be sampled at rate 0.25, got %d", sampled)
+	}
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// WithAdminToken sets the token the admin server requires, as
+// Authorization: Bearer <token>, on the endpoints that change running calls:
+// GET /debug/calls lists the calls the agent of the node is executing, and
+// DELETE /debug/calls/:call_id cancels one of them. It also requires it on
+// GET /debug/runners, the runners of an LB node, POST /cache/invalidate, the
+// invalidations of the data cache other nodes post, POST /debug/trace, which
+// changes the trace sample rate of the node, and GET /debug/migrations, the
+// schema version of the datastore. The API server also
+// requires it on GET /v2/fns/:fn_id/runtime, the live stats of the containers
+// of a fn. They are not served when token is empty.
+func WithAdminToken(token string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.adminToken = token
+		return nil
+	}
+}
+
+func adminAuthWrap(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := bearerToken(c.Request)
+		if secret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="fn-admin"`)
+			handleErrorResponse(c, models.ErrAdminTokenInvalid)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+type activeCallsResponse struct {
+	NodeType string             `json:"node_type"`
+	Calls    []agent.ActiveCall `json:"calls"`
+}
+
+type cancelCallResponse struct {
+	ID       string `json:"id"`
+	Canceled bool   `json:"canceled"`
+}
+
+// handleActiveCallList lists the calls the agent of this node is executing,
+// or placing on runners for an LB node
+func (s *Server) handleActiveCallList(c *gin.Context) {
+	canceler, ok := s.agent.(agent.CallCanceler)
+	if !ok {
+		handleErrorResponse(c, models.ErrCallCancelUnsupported)
+		return
+	}
+
+	calls := canceler.ActiveCalls()
+	if calls == nil {
+		calls = []agent.ActiveCall{}
+	}
+	c.JSON(http.StatusOK, activeCallsResponse{NodeType: s.nodeType.String(), Calls: calls})
+}
+
+// handleActiveCallCancel cancels a call the agent of this node is executing,
+// 404ing if it already completed
+func (s *Server) handleActiveCallCancel(c *gin.Context) {
+	canceler, ok := s.agent.(agent.CallCanceler)
+	if !ok {
+		handleErrorResponse(c, models.ErrCallCancelUnsupported)
+		return
+	}
+
+	callID := c.Param(api.CallID)
+	if err := canceler.CancelCall(callID); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	common.Logger(c.Request.Context()).WithField("call_id", callID).Info("Call cancelled by an operator")
+	c.JSON(http.StatusOK, cancelCallResponse{ID: callID, Canceled: true})
+}
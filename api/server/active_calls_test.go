@@ -0,0 +1,80 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type cancelerAgent struct {
+	agent.MockAgent
+	calls []agent.ActiveCall
+}
+
+func (a *cancelerAgent) ActiveCalls() []agent.ActiveCall { return a.calls }
+
+func (a *cancelerAgent) CancelCall(callID string) error {
+	for i, c := range a.calls {
+		if c.ID == callID {
+			a.calls = append(a.calls[:i], a.calls[i+1:]...)
+			return nil
+		}
+	}
+	return models.ErrCallNotActive
+}
+
+func TestActiveCalls(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	started := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	ag := &cancelerAgent{calls: []agent.ActiveCall{{ID: "call1", AppID: "app1", FnID: "fn1", Path: "/invoke/fn1", StartedAt: started}}}
+	ag.On("AddCallListener", mock.Anything)
+
+	// not served without an admin token
+	srv := testServer(datastore.NewMock(), ag, ServerTypeFull)
+	if _, rec := routerRequest(t, srv.AdminRouter, http.MethodGet, "/debug/calls", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status code 404 but was %d: %s", rec.Code, rec.Body.String())
+	}
+
+	srv = testServer(datastore.NewMock(), ag, ServerTypeFull, WithAdminToken("s3cret"))
+	for i, test := range []struct {
+		method       string
+		path         string
+		token        string
+		expectedCode int
+		expectedBody string
+	}{
+		{http.MethodGet, "/debug/calls", "", http.StatusUnauthorized, models.ErrorCodeAdminTokenInvalid},
+		{http.MethodDelete, "/debug/calls/call1", "wrong", http.StatusUnauthorized, models.ErrorCodeAdminTokenInvalid},
+		{http.MethodGet, "/debug/calls", "s3cret", http.StatusOK,
+			`{"node_type":"full","calls":[{"id":"call1","app_id":"app1","fn_id":"fn1","path":"/invoke/fn1","started_at":"2020-01-02T03:04:05Z"}]}`},
+		{http.MethodDelete, "/debug/calls/call1", "s3cret", http.StatusOK, `{"id":"call1","canceled":true}`},
+		// the call completed, or was already cancelled
+		{http.MethodDelete, "/debug/calls/call1", "s3cret", http.StatusNotFound, models.ErrorCodeCallNotActive},
+		{http.MethodGet, "/debug/calls", "s3cret", http.StatusOK, `{"node_type":"full","calls":[]}`},
+	} {
+		req := createRequest(t, test.method, test.path, nil)
+		if test.token != "" {
+			req.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		_, rec := routerRequest2(t, srv.AdminRouter, req)
+
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+		if body := rec.Body.String(); !strings.Contains(body, test.expectedBody) {
+			t.Errorf("Test %d: expected body to contain %s, got %s", i, test.expectedBody, body)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultReservedAnnotationPrefixes are the annotation key prefixes clients
+// may not set unless WithReservedAnnotationPrefixes says otherwise
+var defaultReservedAnnotationPrefixes = []string{"fn."}
+
+// defaultOperatorAnnotationKeys are the annotations of the platform only
+// operators may set, unless WithOperatorAnnotationKeys says otherwise: those
+// of the quotas of apps, which clients could otherwise lift themselves, and
+// the secrets apps may read
+var defaultOperatorAnnotationKeys = []string{
+	models.AppRateLimitAnnotation,
+	models.AppMinWarmAnnotation,
+	models.AppMaxBodyAnnotation,
+	models.AppConfigSecretsAnnotation,
+}
+
+// clientAnnotationKeys are the annotations of the platform which clients set
+// to configure their apps and triggers, and may set whatever the reserved
+// prefixes, unless they are operator keys
+var clientAnnotationKeys = map[string]bool{
+	models.AppRegistryAuthAnnotation:             true,
+	models.AppInvokeCORSOriginsAnnotation:        true,
+	models.AppInvokeContentTypesAnnotation:       true,
+	models.AppVerifyBodyChecksumAnnotation:       true,
+	models.AppDecompressRequestsAnnotation:       true,
+	models.AppDisableLogsAnnotation:              true,
+	models.AppIdleTimeoutAnnotation:              true,
+	models.AppEgressAllowAnnotation:              true,
+	models.AppDefaultMemoryAnnotation:            true,
+	models.AppDefaultTimeoutAnnotation:           true,
+	models.AppSigningKeyAnnotation:               true,
+	models.TriggerInputSchemaAnnotation:          true,
+	models.TriggerCanaryAnnotation:               true,
+	models.TriggerCacheTTLAnnotation:             true,
+	models.TriggerResponseStatusHeaderAnnotation: true,
+}
+
+// WithReservedAnnotationPrefixes replaces the annotation key prefixes
+// reserved for the platform, "fn." by default, which clients may not set on
+// apps, fns and triggers through the API. The documented annotations clients
+// configure apps with, e.g. models.AppDefaultMemoryAnnotation, are exempt.
+// An empty list reserves none.
+func WithReservedAnnotationPrefixes(prefixes []string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.reservedAnnotations = prefixes
+		return nil
+	}
+}
+
+// WithOperatorAnnotationKeys replaces the annotation keys only operators may
+// set or remove through the API, such as those of quotas. Requests made with
+// the API root token of WithAPITokenAuth are the operators', others get a 400,
+// whatever the reserved and allowed prefixes. If the API has neither API
+// token auth nor tenant scoping, any request is.
+func WithOperatorAnnotationKeys(keys []string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.operatorAnnotations = keySet(keys)
+		return nil
+	}
+}
+
+// WithAllowedAnnotationPrefixes only lets clients set annotations whose keys
+// start with one of the prefixes, besides the documented annotations clients
+// configure apps with. By default any key not reserved is allowed.
+func WithAllowedAnnotationPrefixes(prefixes []string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.allowedAnnotations = prefixes
+		return nil
+	}
+}
+
+// checkAnnotations checks the keys of the annotations set, or removed, by a
+// create or update request against the operator keys, and the reserved and
+// allowed prefixes.
+func (s *Server) checkAnnotations(c *gin.Context, annotations models.Annotations) error {
+	for key := range annotations {
+		if s.operatorAnnotations[key] {
+			if !s.isOperatorRequest(c) {
+				return models.ErrOperatorAnnotationKey
+			}
+			continue
+		}
+		if clientAnnotationKeys[key] {
+			continue
+		}
+		if hasAnyPrefix(key, s.reservedAnnotations) {
+			return models.ErrReservedAnnotationKey
+		}
+		if len(s.allowedAnnotations) > 0 && !hasAnyPrefix(key, s.allowedAnnotations) {
+			return models.ErrAnnotationKeyNotAllowed
+		}
+	}
+	return nil
+}
+
+// isOperatorRequest returns whether a request to the /v2 API is made by an
+// operator, see WithOperatorAnnotationKeys
+func (s *Server) isOperatorRequest(c *gin.Context) bool {
+	if s.apiRootToken == "" && s.tenantResolver == nil {
+		return true
+	}
+	return c.GetBool(apiRootTokenKey)
+}
+
+func keySet(keys []string) map[string]bool {
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		set[key] = true
+	}
+	return set
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/datastore/memory"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestAnnotationPolicy(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	fn := &models.Fn{ID: "fn_id", AppID: app.ID, Name: "myfn", Image: "fnproject/fn-test-utils"}
+	trigger := &models.Trigger{ID: "trigger_id", AppID: app.ID, FnID: fn.ID, Name: "mytrigger", Type: models.TriggerTypeHTTP, Source: "/src"}
+
+	for i, test := range []struct {
+		opts          []Option
+		method        string
+		path          string
+		body          string
+		expectedError error
+	}{
+		{nil, http.MethodPost, "/v2/apps", `{"name": "a", "annotations": {"fn.secret": "x"}}`, models.ErrReservedAnnotationKey},
+		{nil, http.MethodPost, "/v2/apps", `{"name": "a", "annotations": {"fn.default-memory": 256}}`, nil},
+		{nil, http.MethodPost, "/v2/apps", `{"name": "a", "annotations": {"team.owner": "x"}}`, nil},
+		{nil, http.MethodPut, "/v2/apps/app_id", `{"annotations": {"fn.secret": ""}}`, models.ErrReservedAnnotationKey},
+		{nil, http.MethodPost, "/v2/fns", `{"app_id": "app_id", "name": "f", "image": "fnproject/fn-test-utils", "annotations": {"fn.secret": "x"}}`, models.ErrReservedAnnotationKey},
+		{nil, http.MethodPut, "/v2/fns/fn_id", `{"annotations": {"fn.secret": "x"}}`, models.ErrReservedAnnotationKey},
+		{nil, http.MethodPost, "/v2/triggers", `{"app_id": "app_id", "fn_id": "fn_id", "name": "t", "type": "http", "source": "/t", "annotations": {"fn.secret": "x"}}`, models.ErrReservedAnnotationKey},
+		{nil, http.MethodPut, "/v2/triggers/trigger_id", `{"annotations": {"fn.secret": "x"}}`, models.ErrReservedAnnotationKey},
+		{[]Option{WithReservedAnnotationPrefixes(nil)}, http.MethodPost, "/v2/apps", `{"name": "a", "annotations": {"fn.secret": "x"}}`, nil},
+		{[]Option{WithReservedAnnotationPrefixes([]string{"corp."})}, http.MethodPost, "/v2/apps", `{"name": "a", "annotations": {"corp.tier": "x"}}`, models.ErrReservedAnnotationKey},
+		{[]Option{WithAllowedAnnotationPrefixes([]string{"team."})}, http.MethodPost, "/v2/apps", `{"name": "a", "annotations": {"team.owner": "x"}}`, nil},
+		{[]Option{WithAllowedAnnotationPrefixes([]string{"team."})}, http.MethodPost, "/v2/apps", `{"name": "a", "annotations": {"owner": "x"}}`, models.ErrAnnotationKeyNotAllowed},
+		{[]Option{WithAllowedAnnotationPrefixes([]string{"team."})}, http.MethodPost, "/v2/apps", `{"name": "a", "annotations": {"fn.default-timeout": 10}}`, nil},
+	} {
+		ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn}, []*models.Trigger{trigger})
+		srv := testServer(ds, nil, ServerTypeAPI, test.opts...)
+
+		_, rec := routerRequest(t, srv.Router, test.method, test.path, bytes.NewBufferString(test.body))
+
+		if test.expectedError == nil {
+			if rec.Code != http.StatusOK {
+				t.Errorf("Test %d: expected status code 200 but was %d: %s", i, rec.Code, rec.Body.String())
+			}
+			continue
+		}
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Test %d: expected status code 400 but was %d", i, rec.Code)
+		}
+		resp := getErrorResponse(t, rec)
+		if resp == nil || resp.Message != test.expectedError.Error() {
+			t.Errorf("Test %d: expected error `%s`, got %s", i, test.expectedError, rec.Body.String())
+		}
+	}
+}
+
+func TestOperatorAnnotationKeys(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ctx := context.Background()
+	ds := memory.New()
+	app, err := ds.InsertApp(ctx, &models.App{Name: "myapp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := testServer(ds, nil, ServerTypeAPI, WithAPITokenAuth("root"), WithOperatorAnnotationKeys([]string{"fn.quota", "team.tier"}))
+	defaults := testServer(ds, nil, ServerTypeAPI, WithAPITokenAuth("root"))
+
+	request := func(token, method, path, body string) (int, *bytes.Buffer) {
+		req := createRequest(t, method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		_, rec := routerRequest2(t, srv.Router, req)
+		return rec.Code, rec.Body
+	}
+	code, resp := request("root", http.MethodPost, "/v2/tokens", `{"name": "admin", "permission": "write"}`)
+	if code != http.StatusOK {
+		t.Fatalf("expected status code 200 creating a token but was %d: %s", code, resp)
+	}
+	var admin models.APIToken
+	if err := json.NewDecoder(resp).Decode(&admin); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		token         string
+		body          string
+		expectedError error
+	}{
+		{"root", `{"annotations": {"fn.quota": 10, "team.tier": "gold"}}`, nil},
+		{admin.Token, `{"annotations": {"fn.quota": 20}}`, models.ErrOperatorAnnotationKey},
+		// removing an operator key is setting it
+		{admin.Token, `{"annotations": {"fn.quota": ""}}`, models.ErrOperatorAnnotationKey},
+		{admin.Token, `{"annotations": {"team.tier": "free"}}`, models.ErrOperatorAnnotationKey},
+		{admin.Token, `{"annotations": {"team.owner": "x"}}`, nil},
+		{admin.Token, `{"annotations": {"fn.default-memory": 256}}`, nil},
+	} {
+		code, resp := request(test.token, http.MethodPut, "/v2/apps/"+app.ID, test.body)
+		if test.expectedError == nil {
+			if code != http.StatusOK {
+				t.Errorf("Test %d: expected status code 200 but was %d: %s", i, code, resp)
+			}
+			continue
+		}
+		if code != http.StatusBadRequest || !strings.Contains(resp.String(), test.expectedError.Error()) {
+			t.Errorf("Test %d: expected status code 400 and error `%s`, got %d: %s", i, test.expectedError, code, resp)
+		}
+	}
+
+	// the quotas of apps are operator keys by default
+	for _, key := range []string{models.AppRateLimitAnnotation, models.AppMinWarmAnnotation, models.AppMaxBodyAnnotation} {
+		body := `{"annotations": {"` + key + `": 1}}`
+		for _, test := range []struct {
+			token        string
+			expectedCode int
+		}{
+			{admin.Token, http.StatusBadRequest},
+			{"root", http.StatusOK},
+		} {
+			req := createRequest(t, http.MethodPut, "/v2/apps/"+app.ID, bytes.NewBufferString(body))
+			req.Header.Set("Authorization", "Bearer "+test.token)
+			_, rec := routerRequest2(t, defaults.Router, req)
+			if rec.Code != test.expectedCode {
+				t.Errorf("Setting %s: expected status code %d but was %d: %s", key, test.expectedCode, rec.Code, rec.Body.String())
+			}
+		}
+	}
+
+	app, err = ds.GetAppByID(ctx, app.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quota, _ := app.Annotations.Get("fn.quota"); string(quota) != "10" {
+		t.Errorf("expected the operator annotation to be kept, got %s", quota)
+	}
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// apiRootTokenKey is the gin context key set on requests to the /v2 API made
+// with the API root token
+const apiRootTokenKey = "fn_api_root_token"
+
+// WithAPITokenAuth requires requests to the /v2 API to carry an API token, as
+// Authorization: Bearer <token>, and serves the /v2/tokens endpoints to manage
+// them. rootToken is allowed every request, to create the first tokens; the
+// others are stored in the datastore, scoped to apps and read only or read
+// and write. An empty rootToken disables API token auth.
+//
+// LB nodes must then send rootToken to the API nodes, with hybrid.WithAPIToken
+// (FN_RUNNER_API_TOKEN), both to look up apps and fns in the /v2 API and to
+// call the runner API, which no other token is allowed.
+func WithAPITokenAuth(rootToken string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.apiRootToken = rootToken
+		return nil
+	}
+}
+
+// authorizeAPIToken checks that the bearer token of a request to the /v2 API
+// allows it, or aborts it with a 401 or 403
+func (s *Server) authorizeAPIToken(c *gin.Context) bool {
+	ctx := c.Request.Context()
+
+	secret := bearerToken(c.Request)
+	if secret == "" {
+		s.abortAPIToken(c, models.ErrAPITokenRequired)
+		return false
+	}
+	token, err := s.lookupAPIToken(ctx, secret)
+	if err != nil {
+		s.abortAPIToken(c, err)
+		return false
+	}
+	if token == nil {
+		// the root token
+		c.Set(apiRootTokenKey, true)
+		return true
+	}
+
+	var appID string
+	if len(token.AppIDs) > 0 {
+		appID, err = s.requestAppID(c)
+		if err != nil {
+			s.abortAPIToken(c, err)
+			return false
+		}
+	}
+	write := c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead
+	if !token.Allows(appID, write) {
+		common.Logger(ctx).WithField("token_id", token.ID).Info("API token not allowed to make request")
+		s.abortAPIToken(c, models.ErrAPITokenForbidden)
+		return false
+	}
+	return true
+}
+
+// lookupAPIToken returns the API token of secret, or nil if it is the root
+// token, or ErrAPITokenInvalid if it is not a token
+func (s *Server) lookupAPIToken(ctx context.Context, secret string) (*models.APIToken, error) {
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(s.apiRootToken)) == 1 {
+		return nil, nil
+	}
+
+	tokens, err := models.GetTokenDatastore(s.datastore)
+	var token *models.APIToken
+	if err == nil {
+		token, err = tokens.GetTokenByHash(ctx, models.HashAPIToken(secret))
+	}
+	if err == models.ErrTokenNotFound || err == models.ErrTokensUnsupported {
+		err = models.ErrAPITokenInvalid
+	}
+	return token, err
+}
+
+func (s *Server) abortAPIToken(c *gin.Context, err error) {
+	if err == models.ErrAPITokenRequired || err == models.ErrAPITokenInvalid {
+		c.Header("WWW-Authenticate", `Bearer realm="fn"`)
+	}
+	handleErrorResponse(c, err)
+	c.Abort()
+}
+
+func bearerToken(req *http.Request) string {
+	auth := req.Header.Get("Authorization")
+	if len(auth) > len("Bearer ") && strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(auth[len("Bearer "):])
+	}
+	return ""
+}
+
+// requestAppID returns the ID of the app a request to the /v2 API is made to,
+// or an empty one for requests not to a single app, such as listing apps.
+// The app of fns and triggers in the path is looked up, rather than trusting
+// an app_id in the query or body.
+func (s *Server) requestAppID(c *gin.Context) (string, error) {
+	ctx := c.Request.Context()
+	if appID := c.Param(api.AppID); appID != "" {
+		return appID, nil
+	}
+	if fnID := c.Param(api.FnID); fnID != "" {
+		fn, err := s.datastore.GetFnByID(ctx, fnID)
+		if err != nil {
+			return "", err
+		}
+		return fn.AppID, nil
+	}
+	if triggerID := c.Param(api.TriggerID); triggerID != "" {
+		trigger, err := s.datastore.GetTriggerByID(ctx, triggerID)
+		if err != nil {
+			return "", err
+		}
+		return trigger.AppID, nil
+	}
+	if appID := c.Query("app_id"); appID != "" {
+		return appID, nil
+	}
+
+	// fns and triggers are created with the app_id of their body
+	if c.Request.Method != http.MethodPost || c.Request.Body == nil {
+		return "", nil
+	}
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return "", err
+	}
+	c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	var resource struct {
+		AppID string `json:"app_id"`
+	}
+	// invalid bodies are left to the handler to reject
+	json.Unmarshal(body, &resource)
+	return resource.AppID, nil
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore/memory"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestAPITokenAuth(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ctx := context.Background()
+	ds := memory.New()
+	app1, err := ds.InsertApp(ctx, &models.App{Name: "team1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app2, err := ds.InsertApp(ctx, &models.App{Name: "team2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn2 := &models.Fn{Name: "fn", AppID: app2.ID, Image: "fnproject/hello"}
+	fn2.SetDefaults()
+	fn2, err = ds.InsertFn(ctx, fn2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := testServer(ds, nil, ServerTypeAPI, WithAPITokenAuth("root"))
+
+	request := func(token, method, path, body string) (int, *bytes.Buffer) {
+		req := createRequest(t, method, path, bytes.NewBufferString(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		_, rec := routerRequest2(t, srv.Router, req)
+		return rec.Code, rec.Body
+	}
+	createToken := func(body string) *models.APIToken {
+		code, resp := request("root", http.MethodPost, "/v2/tokens", body)
+		if code != http.StatusOK {
+			t.Fatalf("expected status code 200 creating a token but was %d: %s", code, resp)
+		}
+		var token models.APIToken
+		if err := json.NewDecoder(resp).Decode(&token); err != nil {
+			t.Fatal(err)
+		}
+		if token.Token == "" {
+			t.Fatal("expected the token secret to be returned on creation")
+		}
+		return &token
+	}
+
+	read := createToken(`{"name": "team1-read", "app_ids": ["` + app1.ID + `"], "permission": "read"}`)
+	write := createToken(`{"name": "team1-write", "app_ids": ["` + app1.ID + `"], "permission": "write"}`)
+	admin := createToken(`{"name": "admin", "permission": "write"}`)
+
+	for i, test := range []struct {
+		token        string
+		method       string
+		path         string
+		body         string
+		expectedCode int
+	}{
+		{"", http.MethodGet, "/v2/apps", "", http.StatusUnauthorized},
+		{"nope", http.MethodGet, "/v2/apps", "", http.StatusUnauthorized},
+		{"root", http.MethodGet, "/v2/apps", "", http.StatusOK},
+		{admin.Token, http.MethodGet, "/v2/apps/" + app2.ID, "", http.StatusOK},
+		{admin.Token, http.MethodGet, "/v2/tokens", "", http.StatusOK},
+
+		{read.Token, http.MethodGet, "/v2/apps/" + app1.ID, "", http.StatusOK},
+		{read.Token, http.MethodGet, "/v2/fns?app_id=" + app1.ID, "", http.StatusOK},
+		{read.Token, http.MethodPut, "/v2/apps/" + app1.ID, `{"config": {"A": "a"}}`, http.StatusForbidden},
+		{read.Token, http.MethodGet, "/v2/apps/" + app2.ID, "", http.StatusForbidden},
+		{read.Token, http.MethodGet, "/v2/apps", "", http.StatusForbidden},
+		{read.Token, http.MethodGet, "/v2/tokens", "", http.StatusForbidden},
+
+		{write.Token, http.MethodPut, "/v2/apps/" + app1.ID, `{"config": {"A": "a"}}`, http.StatusOK},
+		{write.Token, http.MethodPost, "/v2/fns", `{"name": "fn", "app_id": "` + app1.ID + `", "image": "fnproject/hello"}`, http.StatusOK},
+		{write.Token, http.MethodPost, "/v2/fns", `{"name": "fn2", "app_id": "` + app2.ID + `", "image": "fnproject/hello"}`, http.StatusForbidden},
+		// the app of the fn is looked up, not taken from the query
+		{write.Token, http.MethodDelete, "/v2/fns/" + fn2.ID + "?app_id=" + app1.ID, "", http.StatusForbidden},
+		{write.Token, http.MethodPost, "/v2/tokens", `{"name": "escalate", "permission": "write"}`, http.StatusForbidden},
+	} {
+		code, resp := request(test.token, test.method, test.path, test.body)
+		if code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d: %s", i, test.expectedCode, code, resp)
+		}
+	}
+
+	if code, _ := request("root", http.MethodDelete, "/v2/tokens/"+read.ID, ""); code != http.StatusNoContent {
+		t.Fatalf("expected status code 204 deleting a token but was %d", code)
+	}
+	if code, _ := request(read.Token, http.MethodGet, "/v2/apps/"+app1.ID, ""); code != http.StatusUnauthorized {
+		t.Errorf("expected a deleted token to be rejected, got status code %d", code)
+	}
+}
+
+func TestAPITokenAuthDisabled(t *testing.T) {
+	srv := testServer(memory.New(), nil, ServerTypeAPI)
+
+	_, rec := routerRequest(t, srv.Router, http.MethodGet, "/v2/apps", nil)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status code 200 without API token auth but was %d", rec.Code)
+	}
+	_, rec = routerRequest(t, srv.Router, http.MethodGet, "/v2/tokens", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected no tokens endpoints without API token auth, got status code %d", rec.Code)
+	}
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMaxAppRequestSize is the ceiling of the limits apps set on the bodies
+// of the calls to their functions, unless set otherwise with
+// WithMaxAppRequestSize
+const DefaultMaxAppRequestSize = 100 << 20
+
+// WithMaxAppRequestSize sets the ceiling in bytes of the limits apps set on
+// the bodies of the calls to their functions with the
+// models.AppMaxBodyAnnotation annotation, so that apps may raise the limit of
+// the server (see LimitRequestBody) for their calls, e.g. for uploads, but no
+// higher than max. Apps may lower it whatever the ceiling. A max of 0 or less
+// means DefaultMaxAppRequestSize.
+func WithMaxAppRequestSize(max int64) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.maxAppRequestSize = max
+		return nil
+	}
+}
+
+// callBodyLimit returns the largest body of the calls to the functions of app:
+// that of its annotation clamped to the ceiling if it has one, else that of
+// the server, 0 for no limit. The app is read through the data cache, so this
+// costs no datastore read.
+func (s *Server) callBodyLimit(app *models.App) int64 {
+	// the annotation was validated when the app was stored
+	max, _ := models.AppMaxBody(app)
+	if max == 0 {
+		return s.maxRequestSize
+	}
+	ceiling := s.maxAppRequestSize
+	if ceiling <= 0 {
+		ceiling = DefaultMaxAppRequestSize
+	}
+	if max > ceiling {
+		return ceiling
+	}
+	return max
+}
+
+// limitCallBody rejects a call to a function of app with a 413 if its
+// Content-Length is over the limit of the app, and limits the bytes read of
+// its body to it otherwise, as limitRequestBody does for other requests
+func (s *Server) limitCallBody(c *gin.Context, app *models.App) error {
+	max := s.callBodyLimit(app)
+	if max <= 0 {
+		return nil
+	}
+	if cl := c.Request.ContentLength; cl > max {
+		return errTooBig{cl, max}
+	}
+	c.Request.Body = &maxBytesBody{http.MaxBytesReader(c.Writer, c.Request.Body, max), max, 0}
+	return nil
+}
+
+// maxBytesBody is the http.MaxBytesReader of the body of a call, whose error
+// once over the limit is an errTooBig, so that the handlers reading the body,
+// e.g. to buffer it, reject chunked bodies over the limit with a 413 rather
+// than a 500
+type maxBytesBody struct {
+	io.ReadCloser
+	max, n int64
+}
+
+func (b *maxBytesBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if err != nil && err != io.EOF && b.n >= b.max {
+		// http.MaxBytesReader reads up to max, then fails
+		return n, errTooBig{b.n + 1, b.max}
+	}
+	return n, err
+}
+
+// bufferCallBody reads r, the body of the call req or a reader of it, to its
+// end and makes the bytes read the body of req, which it returns. The bytes
+// are not pooled, detached calls may still read the body once the handler
+// returned, and GetBody spares the agent buffering them again.
+func bufferCallBody(req *http.Request, r io.Reader) ([]byte, error) {
+	body := new(bytes.Buffer)
+	if _, err := body.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	b := body.Bytes()
+	req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(b)), nil
+	}
+	return b, nil
+}
+
+// limitNonCallRequestBody is limitRequestBody for the requests which are not
+// calls to functions, whose limit depends on their app, see limitCallBody
+func (s *Server) limitNonCallRequestBody(max int64) func(c *gin.Context) {
+	limit := limitRequestBody(max)
+	return func(c *gin.Context) {
+		if (s.nodeType == ServerTypeFull || s.nodeType == ServerTypeLB) &&
+			isInvokePath(strings.TrimPrefix(c.Request.URL.Path, s.basePath)) {
+			c.Next()
+			return
+		}
+		limit(c)
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAppMaxBody(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	newApp := func(id string, maxBody interface{}) *models.App {
+		app := &models.App{ID: id, Name: id}
+		if maxBody != nil {
+			app.Annotations, _ = app.Annotations.With(models.AppMaxBodyAnnotation, maxBody)
+		}
+		return app
+	}
+	apps := []*models.App{
+		newApp("default", nil),
+		newApp("raised", 50),
+		newApp("clamped", 1000),
+		newApp("lowered", 5),
+	}
+	var fns []*models.Fn
+	var triggers []*models.Trigger
+	for _, app := range apps {
+		fns = append(fns, &models.Fn{ID: app.ID + "_fn", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}})
+		triggers = append(triggers, &models.Trigger{ID: app.ID + "_trigger", Name: "mytrigger", AppID: app.ID, FnID: app.ID + "_fn", Type: models.TriggerTypeHTTP, Source: "/src"})
+	}
+	ds := datastore.NewMockInit(apps, fns, triggers)
+
+	rnr := new(agent.MockAgent)
+	rnr.On("AddCallListener", mock.Anything)
+	rnr.On("GetCall", mock.Anything)
+	rnr.On("Submit", mock.Anything).Return(nil)
+
+	srv := testServer(ds, rnr, ServerTypeFull, LimitRequestBody(10), WithMaxAppRequestSize(100))
+
+	for i, test := range []struct {
+		path         string
+		size         int
+		expectedCode int
+	}{
+		{"/invoke/default_fn", 10, http.StatusOK},
+		{"/invoke/default_fn", 20, http.StatusRequestEntityTooLarge},
+		{"/t/default/src", 20, http.StatusRequestEntityTooLarge},
+		{"/invoke/raised_fn", 50, http.StatusOK},
+		{"/t/raised/src", 50, http.StatusOK},
+		{"/invoke/raised_fn", 51, http.StatusRequestEntityTooLarge},
+		// the annotation is clamped to the ceiling of the server
+		{"/invoke/clamped_fn", 100, http.StatusOK},
+		{"/t/clamped/src", 150, http.StatusRequestEntityTooLarge},
+		{"/invoke/lowered_fn", 8, http.StatusRequestEntityTooLarge},
+		{"/t/lowered/src", 5, http.StatusOK},
+		// requests other than calls keep the limit of the server
+		{"/v2/apps", 20, http.StatusRequestEntityTooLarge},
+	} {
+		req := createRequest(t, http.MethodPost, test.path, strings.NewReader(strings.Repeat("a", test.size)))
+		_, rec := routerRequest2(t, srv.Router, req)
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d for %d bytes to %s, got %d", i, test.expectedCode, test.size, test.path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// appLocks serializes the creation of fns and triggers within an app, so that
+// the per app limits are checked and the new resource inserted atomically. Apps
+// share a fixed set of locks, creations in different apps may wait on each
+// other but never on more than one lock. Locks are local to the server, when
+// several API nodes share a datastore, concurrent creations in the same app on
+// different nodes may still go over the limits.
+type appLocks [64]sync.Mutex
+
+func (l *appLocks) lock(appID string) func() {
+	h := fnv.New32a()
+	h.Write([]byte(appID))
+	mu := &l[h.Sum32()%uint32(len(l))]
+	mu.Lock()
+	return mu.Unlock
+}
+
+// reserveFn checks that a fn can be added to the app without going over
+// s.maxFnsPerApp. Unless it returns an error, the returned func must be called
+// once the fn is inserted (or failed to be), until then no other fn may be
+// reserved in the app.
+func (s *Server) reserveFn(ctx context.Context, appID string) (func(), error) {
+	if s.maxFnsPerApp <= 0 {
+		return func() {}, nil
+	}
+
+	unlock := s.appLocks.lock(appID)
+	fns, err := s.datastore.GetFns(ctx, &models.FnFilter{AppID: appID, PerPage: s.maxFnsPerApp})
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	if len(fns.Items) >= s.maxFnsPerApp {
+		unlock()
+		return nil, models.ErrAppsTooManyFns
+	}
+	return unlock, nil
+}
+
+// reserveTrigger is reserveFn for triggers and s.maxTriggersPerApp.
+func (s *Server) reserveTrigger(ctx context.Context, appID string) (func(), error) {
+	if s.maxTriggersPerApp <= 0 {
+		return func() {}, nil
+	}
+
+	unlock := s.appLocks.lock(appID)
+	triggers, err := s.datastore.GetTriggers(ctx, &models.TriggerFilter{AppID: appID, PerPage: s.maxTriggersPerApp})
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	if len(triggers.Items) >= s.maxTriggersPerApp {
+		unlock()
+		return nil, models.ErrAppsTooManyTriggers
+	}
+	return unlock, nil
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestAppLimits(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	a := &models.App{ID: "appid", Name: "app"}
+	fn := &models.Fn{ID: "fnid", Name: "fn", AppID: a.ID, Image: "fnproject/fn-test-utils"}
+	trigger := &models.Trigger{ID: "triggerid", Name: "trigger", AppID: a.ID, FnID: fn.ID, Type: "http", Source: "/src"}
+
+	for i, test := range []struct {
+		opts          []Option
+		path          string
+		body          string
+		expectedCode  int
+		expectedError error
+	}{
+		{nil, "/v2/fns", `{ "app_id": "appid", "name": "newfn", "image": "fnproject/fn-test-utils" }`, http.StatusOK, nil},
+		{[]Option{WithMaxFnsPerApp(2)}, "/v2/fns", `{ "app_id": "appid", "name": "newfn", "image": "fnproject/fn-test-utils" }`, http.StatusOK, nil},
+		{[]Option{WithMaxFnsPerApp(1)}, "/v2/fns", `{ "app_id": "appid", "name": "newfn", "image": "fnproject/fn-test-utils" }`, http.StatusConflict, models.ErrAppsTooManyFns},
+		{[]Option{WithMaxFnsPerApp(1)}, "/v2/fns?dry_run=true", `{ "app_id": "appid", "name": "newfn", "image": "fnproject/fn-test-utils" }`, http.StatusConflict, models.ErrAppsTooManyFns},
+		{[]Option{WithMaxTriggersPerApp(1)}, "/v2/fns", `{ "app_id": "appid", "name": "newfn", "image": "fnproject/fn-test-utils" }`, http.StatusOK, nil},
+
+		{nil, "/v2/triggers", `{ "name": "newtrigger", "app_id": "appid", "fn_id": "fnid", "type": "http", "source": "/newsrc" }`, http.StatusOK, nil},
+		{[]Option{WithMaxTriggersPerApp(2)}, "/v2/triggers", `{ "name": "newtrigger", "app_id": "appid", "fn_id": "fnid", "type": "http", "source": "/newsrc" }`, http.StatusOK, nil},
+		{[]Option{WithMaxTriggersPerApp(1)}, "/v2/triggers", `{ "name": "newtrigger", "app_id": "appid", "fn_id": "fnid", "type": "http", "source": "/newsrc" }`, http.StatusConflict, models.ErrAppsTooManyTriggers},
+		{[]Option{WithMaxTriggersPerApp(1)}, "/v2/triggers?dry_run=true", `{ "name": "newtrigger", "app_id": "appid", "fn_id": "fnid", "type": "http", "source": "/newsrc" }`, http.StatusConflict, models.ErrAppsTooManyTriggers},
+		{[]Option{WithMaxFnsPerApp(1)}, "/v2/triggers", `{ "name": "newtrigger", "app_id": "appid", "fn_id": "fnid", "type": "http", "source": "/newsrc" }`, http.StatusOK, nil},
+	} {
+		ds := datastore.NewMockInit([]*models.App{a}, []*models.Fn{fn}, []*models.Trigger{trigger})
+		srv := testServer(ds, nil, ServerTypeAPI, test.opts...)
+
+		_, rec := routerRequest(t, srv.Router, http.MethodPost, test.path, bytes.NewBufferString(test.body))
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+		if test.expectedError != nil {
+			resp := getErrorResponse(t, rec)
+			if resp.Message != test.expectedError.Error() {
+				t.Errorf("Test %d: expected error `%s` but got `%s`", i, test.expectedError, resp.Message)
+			}
+		}
+	}
+}
+
+func TestAppLimitsConcurrentCreates(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	a := &models.App{ID: "appid", Name: "app"}
+	ds := datastore.NewMockInit([]*models.App{a})
+	srv := testServer(ds, nil, ServerTypeAPI, WithMaxFnsPerApp(3))
+
+	var wg sync.WaitGroup
+	codes := make([]int, 10)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body := fmt.Sprintf(`{ "app_id": "appid", "name": "fn%d", "image": "fnproject/fn-test-utils" }`, i)
+			_, rec := routerRequest(t, srv.Router, http.MethodPost, "/v2/fns", bytes.NewBufferString(body))
+			codes[i] = rec.Code
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for _, code := range codes {
+		if code == http.StatusOK {
+			created++
+		} else if code != http.StatusConflict {
+			t.Errorf("expected status code 200 or 409 but was %d", code)
+		}
+	}
+	fns, _ := ds.GetFns(context.Background(), &models.FnFilter{AppID: a.ID, PerPage: 100})
+	if created != 3 || len(fns.Items) != 3 {
+		t.Errorf("expected 3 fns to be created, got %d created and %d in the datastore", created, len(fns.Items))
+	}
+}
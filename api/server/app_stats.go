@@ -0,0 +1,225 @@
+package server
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// appStatsResolution is the span of the buckets calls are counted in
+	appStatsResolution = time.Minute
+	// appStatsRetention is how long calls are counted for, the largest window
+	appStatsRetention = 24 * time.Hour
+	// defaultAppStatsWindow is the window of app stats if none is requested
+	defaultAppStatsWindow = "1h"
+)
+
+// appStatsWindows are the windows app stats can be requested for
+var appStatsWindows = map[string]time.Duration{
+	"1m":  time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"1h":  time.Hour,
+	"6h":  6 * time.Hour,
+	"24h": appStatsRetention,
+}
+
+// appStatsLatencyBounds are the upper bounds, in msecs, of the latency
+// histogram buckets percentiles are estimated from
+var appStatsLatencyBounds = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000, 120000, 300000}
+
+// appStats are the aggregate stats of the calls of an app completed within a
+// window. Latencies are in msecs, from the start to the completion of calls,
+// and estimated from a histogram.
+type appStats struct {
+	AppID      string           `json:"app_id"`
+	Window     string           `json:"window"`
+	Calls      int64            `json:"calls"`
+	ByStatus   map[string]int64 `json:"by_status"`
+	ErrorRate  float64          `json:"error_rate"`
+	LatencyP50 float64          `json:"latency_p50_ms"`
+	LatencyP95 float64          `json:"latency_p95_ms"`
+}
+
+type callStatsBucket struct {
+	start    time.Time
+	byStatus map[string]int64
+	latency  []int64 // count per appStatsLatencyBounds, and above them
+}
+
+// appStatsCollector is a CallListener counting the calls completed by the
+// agent of this server per app, in buckets of appStatsResolution kept for
+// appStatsRetention. The buckets of all apps are pruned every
+// appStatsResolution, so that apps no longer called, or deleted, are dropped.
+type appStatsCollector struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	apps      map[string][]*callStatsBucket // oldest first
+	lastSweep time.Time
+}
+
+func newAppStatsCollector() *appStatsCollector {
+	return &appStatsCollector{
+		now:  time.Now,
+		apps: make(map[string][]*callStatsBucket),
+	}
+}
+
+// BeforeCall implements fnext.CallListener
+func (sc *appStatsCollector) BeforeCall(ctx context.Context, call *models.Call) error {
+	return nil
+}
+
+// AfterCall implements fnext.CallListener
+func (sc *appStatsCollector) AfterCall(ctx context.Context, call *models.Call) error {
+	start := time.Time(call.StartedAt)
+	if start.IsZero() {
+		start = time.Time(call.CreatedAt)
+	}
+	latency := float64(time.Time(call.CompletedAt).Sub(start)) / float64(time.Millisecond)
+	sc.record(call.AppID, call.Status, latency)
+	return nil
+}
+
+func (sc *appStatsCollector) record(appID, status string, latencyMs float64) {
+	now := sc.now()
+	start := now.Truncate(appStatsResolution)
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if now.Sub(sc.lastSweep) >= appStatsResolution {
+		for id := range sc.apps {
+			sc.prune(id, now)
+		}
+		sc.lastSweep = now
+	}
+
+	buckets := sc.prune(appID, now)
+	if len(buckets) == 0 || buckets[len(buckets)-1].start.Before(start) {
+		buckets = append(buckets, &callStatsBucket{
+			start:    start,
+			byStatus: make(map[string]int64),
+			latency:  make([]int64, len(appStatsLatencyBounds)+1),
+		})
+		sc.apps[appID] = buckets
+	}
+
+	b := buckets[len(buckets)-1]
+	b.byStatus[status]++
+	i := 0
+	for i < len(appStatsLatencyBounds) && latencyMs > appStatsLatencyBounds[i] {
+		i++
+	}
+	b.latency[i]++
+}
+
+// prune drops the buckets of an app older than appStatsRetention, and
+// returns the ones left. sc.mu must be held.
+func (sc *appStatsCollector) prune(appID string, now time.Time) []*callStatsBucket {
+	buckets := sc.apps[appID]
+	i := 0
+	for i < len(buckets) && now.Sub(buckets[i].start) >= appStatsRetention+appStatsResolution {
+		i++
+	}
+	buckets = buckets[i:]
+	if len(buckets) == 0 {
+		delete(sc.apps, appID)
+	} else {
+		sc.apps[appID] = buckets
+	}
+	return buckets
+}
+
+// stats aggregates the buckets of an app within window. As calls are
+// counted per bucket, the window is rounded up to appStatsResolution.
+func (sc *appStatsCollector) stats(appID string, window time.Duration) appStats {
+	now := sc.now()
+	since := now.Truncate(appStatsResolution).Add(-window + appStatsResolution)
+
+	stats := appStats{AppID: appID, ByStatus: make(map[string]int64)}
+	latency := make([]int64, len(appStatsLatencyBounds)+1)
+
+	sc.mu.Lock()
+	for _, b := range sc.prune(appID, now) {
+		if b.start.Before(since) {
+			continue
+		}
+		for status, n := range b.byStatus {
+			stats.ByStatus[status] += n
+			stats.Calls += n
+		}
+		for i, n := range b.latency {
+			latency[i] += n
+		}
+	}
+	sc.mu.Unlock()
+
+	if stats.Calls > 0 {
+		stats.ErrorRate = float64(stats.Calls-stats.ByStatus["success"]) / float64(stats.Calls)
+		stats.LatencyP50 = latencyPercentile(latency, stats.Calls, 0.5)
+		stats.LatencyP95 = latencyPercentile(latency, stats.Calls, 0.95)
+	}
+	return stats
+}
+
+// latencyPercentile estimates the p percentile of a latency histogram by
+// linear interpolation within the bucket it falls in. Latencies above the
+// last bound are estimated as the last bound.
+func latencyPercentile(counts []int64, total int64, p float64) float64 {
+	rank := p * float64(total)
+	var seen int64
+	for i, n := range counts {
+		if n == 0 || float64(seen+n) < rank {
+			seen += n
+			continue
+		}
+		if i == len(appStatsLatencyBounds) {
+			break
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = appStatsLatencyBounds[i-1]
+		}
+		// round to a microsecond, as estimates are no more precise
+		return math.Round((lower+(appStatsLatencyBounds[i]-lower)*(rank-float64(seen))/float64(n))*1000) / 1000
+	}
+	return appStatsLatencyBounds[len(appStatsLatencyBounds)-1]
+}
+
+// handleAppStats returns the stats of the calls of an app completed by this
+// server within the requested window. Only servers running calls, i.e. full
+// nodes, count them.
+func (s *Server) handleAppStats(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if s.appStats == nil {
+		handleErrorResponse(c, models.ErrAppStatsUnsupported)
+		return
+	}
+
+	windowParam := c.DefaultQuery("window", defaultAppStatsWindow)
+	window, ok := appStatsWindows[windowParam]
+	if !ok {
+		handleErrorResponse(c, models.ErrInvalidStatsWindow)
+		return
+	}
+
+	app, err := s.datastore.GetAppByID(ctx, c.Param(api.AppID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	stats := s.appStats.stats(app.ID, window)
+	stats.Window = windowParam
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAppStats(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ds := datastore.NewMockInit([]*models.App{{ID: "appid", Name: "app"}})
+	rnr := new(agent.MockAgent)
+	rnr.On("AddCallListener", mock.Anything)
+	srv := testServer(ds, rnr, ServerTypeFull)
+
+	now := time.Date(2018, 1, 1, 12, 30, 30, 0, time.UTC)
+	srv.appStats.now = func() time.Time { return now }
+
+	call := func(status string, latency time.Duration) *models.Call {
+		return &models.Call{
+			AppID:       "appid",
+			Status:      status,
+			StartedAt:   common.DateTime(now.Add(-latency)),
+			CompletedAt: common.DateTime(now),
+		}
+	}
+
+	// calls from two hours ago are only within the 24h window
+	now = now.Add(-2 * time.Hour)
+	srv.appStats.AfterCall(context.Background(), call("error", 3*time.Second))
+	now = now.Add(2 * time.Hour)
+	for i := 0; i < 8; i++ {
+		srv.appStats.AfterCall(context.Background(), call("success", 20*time.Millisecond))
+	}
+	srv.appStats.AfterCall(context.Background(), call("timeout", 40*time.Second))
+	srv.appStats.AfterCall(context.Background(), call("error", 200*time.Millisecond))
+
+	for i, test := range []struct {
+		path         string
+		expectedCode int
+		expectedErr  error
+		expected     appStats
+	}{
+		{"/v2/apps/appid/stats", http.StatusOK, nil, appStats{
+			AppID: "appid", Window: "1h", Calls: 10,
+			ByStatus:  map[string]int64{"success": 8, "timeout": 1, "error": 1},
+			ErrorRate: 0.2, LatencyP50: 19.375, LatencyP95: 45000,
+		}},
+		{"/v2/apps/appid/stats?window=24h", http.StatusOK, nil, appStats{
+			AppID: "appid", Window: "24h", Calls: 11,
+			ByStatus:  map[string]int64{"success": 8, "timeout": 1, "error": 2},
+			ErrorRate: 3.0 / 11, LatencyP50: 20.313, LatencyP95: 43500,
+		}},
+		{"/v2/apps/appid/stats?window=2d", http.StatusBadRequest, models.ErrInvalidStatsWindow, appStats{}},
+		{"/v2/apps/nope/stats", http.StatusNotFound, models.ErrAppsNotFound, appStats{}},
+	} {
+		_, rec := routerRequest(t, srv.Router, http.MethodGet, test.path, nil)
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d", i, test.expectedCode, rec.Code)
+			continue
+		}
+		if test.expectedErr != nil {
+			if resp := getErrorResponse(t, rec); resp.Message != test.expectedErr.Error() {
+				t.Errorf("Test %d: expected error %q, got %q", i, test.expectedErr, resp.Message)
+			}
+			continue
+		}
+
+		var stats appStats
+		if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(stats, test.expected) {
+			t.Errorf("Test %d: expected %+v, got %+v", i, test.expected, stats)
+		}
+	}
+}
+
+func TestAppStatsUnsupported(t *testing.T) {
+	ds := datastore.NewMockInit([]*models.App{{ID: "appid", Name: "app"}})
+	srv := testServer(ds, nil, ServerTypeAPI)
+
+	_, rec := routerRequest(t, srv.Router, http.MethodGet, "/v2/apps/appid/stats", nil)
+	if rec.Code != http.StatusNotImplemented {
+		t.Fatalf("expected status code 501 but was %d", rec.Code)
+	}
+}
+
+func TestAppStatsSweep(t *testing.T) {
+	sc := newAppStatsCollector()
+	now := time.Date(2018, 1, 1, 12, 30, 30, 0, time.UTC)
+	sc.now = func() time.Time { return now }
+
+	sc.record("idle", "success", 10)
+	now = now.Add(appStatsRetention + 2*appStatsResolution)
+	sc.record("busy", "success", 10)
+
+	if _, ok := sc.apps["idle"]; ok {
+		t.Error("expected the stats of an app no longer called to be dropped")
+	}
+	if _, ok := sc.apps["busy"]; !ok {
+		t.Error("expected the stats of an app called to be kept")
+	}
+}
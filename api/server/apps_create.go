@@ -12,7 +12,7 @@ func (s *Server) handleAppCreate(c *gin.Context) {
 
 	app := &models.App{}
 
-	err := c.BindJSON(app)
+	err := s.bindJSON(c, app)
 	if err != nil {
 		if models.IsAPIError(err) {
 			handleErrorResponse(c, err)
@@ -22,11 +22,29 @@ func (s *Server) handleAppCreate(c *gin.Context) {
 		return
 	}
 
-	app, err = s.datastore.InsertApp(ctx, app)
+	if tenant := requestTenant(c); tenant != "" {
+		app.TenantID = tenant
+	}
+
+	if err := s.checkAnnotations(c, app.Annotations); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	if err := s.checkConfig(app.Config); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	if isDryRun(c) {
+		app, err = s.dryRunInsertApp(ctx, app)
+	} else {
+		app, err = s.datastore.InsertApp(ctx, app)
+	}
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, app)
+	c.JSON(http.StatusOK, app.Redacted())
 }
@@ -1,8 +1,6 @@
 package server
 
 import (
-	"net/http"
-
 	"github.com/fnproject/fn/api/models"
 	"github.com/gin-gonic/gin"
 )
@@ -15,6 +13,7 @@ func (s *Server) handleAppList(c *gin.Context) {
 	filter.Cursor, filter.PerPage = pageParams(c)
 
 	filter.Name = c.Query("name")
+	filter.TenantID = requestTenant(c)
 
 	apps, err := s.datastore.GetApps(ctx, filter)
 	if err != nil {
@@ -22,5 +21,5 @@ func (s *Server) handleAppList(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, apps)
+	writeListResponse(c, apps.NextCursor, len(apps.Items), func(i int) interface{} { return apps.Items[i].Redacted() })
 }
@@ -13,7 +13,7 @@ func (s *Server) handleAppUpdate(c *gin.Context) {
 
 	app := &models.App{}
 
-	err := c.BindJSON(app)
+	err := s.bindJSON(c, app)
 	if err != nil {
 		if models.IsAPIError(err) {
 			handleErrorResponse(c, err)
@@ -23,6 +23,11 @@ func (s *Server) handleAppUpdate(c *gin.Context) {
 		return
 	}
 
+	if err := s.checkAnnotations(c, app.Annotations); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
 	id := c.Param(api.AppID)
 
 	if app.ID == "" {
@@ -32,11 +37,21 @@ func (s *Server) handleAppUpdate(c *gin.Context) {
 		handleErrorResponse(c, models.ErrAppsIDMismatch)
 		return
 	}
+	// if the app can't be found here, UpdateApp will report it
+	if app.Config != nil && s.configLimited() {
+		if current, err := s.datastore.GetAppByID(ctx, app.ID); err == nil {
+			if err := s.checkConfigUpdate(current.Config, app.Config); err != nil {
+				handleErrorResponse(c, err)
+				return
+			}
+		}
+	}
+
 	app, err = s.datastore.UpdateApp(ctx, app)
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, app)
+	c.JSON(http.StatusOK, app.Redacted())
 }
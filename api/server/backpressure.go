@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+const (
+	// backpressureRetryAfter is the Retry-After, in seconds, of calls rejected
+	// while the server is overloaded
+	backpressureRetryAfter = 1
+
+	// backpressureMemoryInterval is how often the memory in use is sampled,
+	// as reading it stops the world
+	backpressureMemoryInterval = time.Second
+)
+
+var (
+	backpressureReasonKey = common.MakeKey("reason")
+
+	backpressureMeasure = common.MakeMeasure("server/backpressure_rejected", "Number of calls rejected while the server was overloaded", stats.UnitDimensionless)
+)
+
+// RegisterBackpressureViews registers the views for calls rejected by WithBackpressure
+func RegisterBackpressureViews(tagKeys []string) {
+	tags := []tag.Key{backpressureReasonKey}
+	for _, key := range tagKeys {
+		if key != backpressureReasonKey.Name() {
+			tags = append(tags, common.MakeKey(key))
+		}
+	}
+
+	err := view.Register(common.CreateViewWithTags(backpressureMeasure, view.Count(), tags))
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot register view")
+	}
+}
+
+// WithBackpressure rejects new calls to the trigger and invoke endpoints with a
+// 503 and a Retry-After header while maxInFlight calls are running on the
+// node, or while the heap of the server holds more than maxMemory bytes,
+// so that clients and load balancers back off rather than the node degrading.
+// A limit of 0 or less means no limit. The management API is never rejected.
+//
+// The ping endpoint at / and the admin server keep answering while calls are
+// rejected, so that readiness probes don't take an overloaded node out of
+// rotation and restart the calls it is still running elsewhere; the node
+// sheds load through the Retry-After of the calls alone.
+func WithBackpressure(maxInFlight int, maxMemory int64) Option {
+	return func(ctx context.Context, s *Server) error {
+		if maxInFlight <= 0 && maxMemory <= 0 {
+			s.backpressure = nil
+			return nil
+		}
+		s.backpressure = &backpressure{
+			maxInFlight: int64(maxInFlight),
+			maxMemory:   uint64(maxMemory),
+			readMemory:  readHeapInUse,
+		}
+		return nil
+	}
+}
+
+type backpressure struct {
+	maxInFlight int64
+	maxMemory   uint64
+	inFlight    int64
+
+	readMemory func() uint64
+	memMu      sync.Mutex
+	memAt      time.Time
+	memInUse   uint64
+}
+
+func readHeapInUse() uint64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapInuse
+}
+
+// overloaded returns the threshold the server is over, if any
+func (b *backpressure) overloaded() string {
+	if b.maxInFlight > 0 && atomic.LoadInt64(&b.inFlight) >= b.maxInFlight {
+		return "inflight"
+	}
+	if b.maxMemory > 0 && b.memory() > b.maxMemory {
+		return "memory"
+	}
+	return ""
+}
+
+func (b *backpressure) memory() uint64 {
+	b.memMu.Lock()
+	defer b.memMu.Unlock()
+	if now := time.Now(); now.Sub(b.memAt) >= backpressureMemoryInterval {
+		b.memInUse, b.memAt = b.readMemory(), now
+	}
+	return b.memInUse
+}
+
+// backpressureWrap counts the calls in flight and rejects new ones while the
+// server is overloaded, see WithBackpressure
+func (s *Server) backpressureWrap(c *gin.Context) {
+	b := s.backpressure
+	if reason := b.overloaded(); reason != "" {
+		ctx, err := tag.New(c.Request.Context(), tag.Upsert(backpressureReasonKey, reason))
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		stats.Record(ctx, backpressureMeasure.M(1))
+
+		c.Header("Retry-After", strconv.Itoa(backpressureRetryAfter))
+		handleErrorResponse(c, models.ErrServerOverloaded)
+		c.Abort()
+		return
+	}
+
+	atomic.AddInt64(&b.inFlight, 1)
+	defer atomic.AddInt64(&b.inFlight, -1)
+	c.Next()
+}
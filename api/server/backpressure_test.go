@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/gin-gonic/gin"
+)
+
+func TestBackpressure(t *testing.T) {
+	srv := testServer(datastore.NewMock(), nil, ServerTypeAPI)
+	if err := WithBackpressure(2, 1<<20)(context.Background(), srv); err != nil {
+		t.Fatal(err)
+	}
+	memory := uint64(0)
+	srv.backpressure.readMemory = func() uint64 { return memory }
+
+	router := gin.New()
+	router.Use(srv.backpressureWrap)
+	var inFlight int64
+	router.POST("/invoke/:fn_id", func(c *gin.Context) {
+		inFlight = atomic.LoadInt64(&srv.backpressure.inFlight)
+		c.Status(http.StatusOK)
+	})
+
+	for i, test := range []struct {
+		inFlight     int64
+		memory       uint64
+		expectedCode int
+	}{
+		{0, 0, http.StatusOK},
+		{1, 1 << 20, http.StatusOK},
+		{2, 0, http.StatusServiceUnavailable},
+		{1, 1<<20 + 1, http.StatusServiceUnavailable},
+	} {
+		atomic.StoreInt64(&srv.backpressure.inFlight, test.inFlight)
+		memory = test.memory
+		// sample the memory again
+		srv.backpressure.memAt = srv.backpressure.memAt.AddDate(-1, 0, 0)
+
+		_, rec := routerRequest(t, router, http.MethodPost, "/invoke/fn", nil)
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d", i, test.expectedCode, rec.Code)
+		}
+		if test.expectedCode == http.StatusOK {
+			if inFlight != test.inFlight+1 {
+				t.Errorf("Test %d: expected the call to be counted in flight, got %d", i, inFlight)
+			}
+		} else if rec.Header().Get("Retry-After") == "" {
+			t.Errorf("Test %d: expected a Retry-After header", i)
+		}
+		if n := atomic.LoadInt64(&srv.backpressure.inFlight); n != test.inFlight {
+			t.Errorf("Test %d: expected %d calls in flight after the call, got %d", i, test.inFlight, n)
+		}
+	}
+
+	if err := WithBackpressure(0, 0)(context.Background(), srv); err != nil {
+		t.Fatal(err)
+	}
+	if srv.backpressure != nil {
+		t.Fatal("expected no limits to disable backpressure")
+	}
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestBasePath(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	fn := &models.Fn{ID: "fn_id", AppID: app.ID, Name: "myfn", Image: "fnproject/fn-test-utils"}
+	trigger := &models.Trigger{ID: "trigger_id", AppID: app.ID, FnID: fn.ID, Name: "mytrigger", Type: models.TriggerTypeHTTP, Source: "/src"}
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn}, []*models.Trigger{trigger})
+
+	ctx, halt := context.WithCancel(context.Background())
+	defer halt()
+	srv := testServer(ds, nil, ServerTypeAPI, WithBasePath("fn/"), EnableShutdownEndpoint(ctx, halt))
+
+	for i, test := range []struct {
+		router       http.Handler
+		path         string
+		expectedCode int
+	}{
+		{srv.Router, "/fn/", http.StatusOK},
+		{srv.Router, "/fn/v2/apps", http.StatusOK},
+		{srv.AdminRouter, "/fn/version", http.StatusOK},
+		{srv.Router, "/", http.StatusNotFound},
+		{srv.Router, "/v2/apps", http.StatusNotFound},
+		{srv.Router, "/fn/nope", http.StatusNotFound},
+		{srv.Router, "/shutdown", http.StatusNotFound},
+		{srv.Router, "/fn/shutdown", http.StatusOK},
+	} {
+		req, rec := newRouterRequest(t, http.MethodGet, test.path, nil)
+		test.router.ServeHTTP(rec, req)
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d for %s but was %d", i, test.expectedCode, test.path, rec.Code)
+		}
+		if test.expectedCode == http.StatusNotFound {
+			if resp := getErrorResponse(t, rec); !strings.HasPrefix(resp.Message, models.ErrPathNotFound.Error()) {
+				t.Errorf("Test %d: expected a path not found error, got %q", i, resp.Message)
+			}
+		}
+	}
+
+	_, rec := routerRequest(t, srv.Router, http.MethodGet, "/fn/v2/triggers/trigger_id", nil)
+	var got models.Trigger
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	endpoint, err := got.Annotations.GetString(models.TriggerHTTPEndpointAnnotation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "http://127.0.0.1:8080/fn/t/myapp/src"; endpoint != expected {
+		t.Errorf("expected trigger endpoint %s, got %s", expected, endpoint)
+	}
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+const (
+	// contentMD5Header holds the base64 encoded MD5 digest of a body, as in RFC 1864
+	contentMD5Header = "Content-MD5"
+	// contentSHA256Header holds the hex encoded SHA-256 digest of a body
+	contentSHA256Header = "X-Fn-Content-Sha256"
+)
+
+// bodyChecksum is a digest a request body is expected to hash to
+type bodyChecksum struct {
+	hash   hash.Hash
+	digest []byte
+}
+
+// verifyBodyChecksum checks the body of a call to a function of app against
+// its checksum headers, if the app has AppVerifyBodyChecksumAnnotation set to
+// true. The supported headers are:
+//
+//   - Content-MD5, the base64 encoded MD5 digest of the body
+//   - X-Fn-Content-Sha256, the hex encoded SHA-256 digest of the body
+//
+// If both are set, both are checked. Requests with neither are not checked.
+// The body is hashed as it is read, and kept to be passed on to the function,
+// so it is only held in memory once.
+func verifyBodyChecksum(req *http.Request, app *models.App) error {
+	if !verifyBodyChecksumEnabled(app) {
+		return nil
+	}
+
+	var checksums []bodyChecksum
+	if v := req.Header.Get(contentMD5Header); v != "" {
+		digest, err := base64.StdEncoding.DecodeString(strings.TrimSpace(v))
+		if err != nil || len(digest) != md5.Size {
+			return models.ErrInvalidBodyChecksum
+		}
+		checksums = append(checksums, bodyChecksum{md5.New(), digest})
+	}
+	if v := req.Header.Get(contentSHA256Header); v != "" {
+		digest, err := hex.DecodeString(strings.TrimSpace(v))
+		if err != nil || len(digest) != sha256.Size {
+			return models.ErrInvalidBodyChecksum
+		}
+		checksums = append(checksums, bodyChecksum{sha256.New(), digest})
+	}
+	if len(checksums) == 0 {
+		return nil
+	}
+
+	hashes := make([]io.Writer, len(checksums))
+	for i, c := range checksums {
+		hashes[i] = c.hash
+	}
+
+	if body := req.Body; body != nil {
+		_, err := bufferCallBody(req, io.TeeReader(body, io.MultiWriter(hashes...)))
+		body.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, c := range checksums {
+		if subtle.ConstantTimeCompare(c.hash.Sum(nil), c.digest) != 1 {
+			return models.ErrBodyChecksumMismatch
+		}
+	}
+	return nil
+}
+
+func verifyBodyChecksumEnabled(app *models.App) bool {
+	v, ok := app.Annotations.Get(models.AppVerifyBodyChecksumAnnotation)
+	if !ok {
+		return false
+	}
+	var enabled bool
+	return json.Unmarshal(v, &enabled) == nil && enabled
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBodyChecksum(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	annotations, err := models.Annotations{}.With(models.AppVerifyBodyChecksumAnnotation, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &models.App{ID: "app_id", Name: "myapp", Annotations: annotations}
+	otherApp := &models.App{ID: "other_app_id", Name: "otherapp"}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}}
+	otherFn := &models.Fn{ID: "other_fn_id", Name: "otherfn", AppID: otherApp.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}}
+	trigger := &models.Trigger{ID: "trigger_id", Name: "mytrigger", AppID: app.ID, FnID: fn.ID, Type: models.TriggerTypeHTTP, Source: "/src"}
+	ds := datastore.NewMockInit([]*models.App{app, otherApp}, []*models.Fn{fn, otherFn}, []*models.Trigger{trigger})
+
+	body := `{"hello": "world"}`
+	md5Sum := md5.Sum([]byte(body))
+	sha256Sum := sha256.Sum256([]byte(body))
+	goodMD5 := base64.StdEncoding.EncodeToString(md5Sum[:])
+	goodSHA256 := hex.EncodeToString(sha256Sum[:])
+	badSHA256 := strings.Repeat("0", sha256.Size*2)
+
+	for i, test := range []struct {
+		path          string
+		headers       map[string]string
+		expectedError error
+	}{
+		{"/invoke/fn_id", nil, nil},
+		{"/invoke/fn_id", map[string]string{contentMD5Header: goodMD5}, nil},
+		{"/invoke/fn_id", map[string]string{contentSHA256Header: goodSHA256}, nil},
+		{"/invoke/fn_id", map[string]string{contentMD5Header: goodMD5, contentSHA256Header: goodSHA256}, nil},
+		{"/invoke/fn_id", map[string]string{contentSHA256Header: badSHA256}, models.ErrBodyChecksumMismatch},
+		{"/invoke/fn_id", map[string]string{contentMD5Header: goodMD5, contentSHA256Header: badSHA256}, models.ErrBodyChecksumMismatch},
+		{"/invoke/fn_id", map[string]string{contentMD5Header: "not base64"}, models.ErrInvalidBodyChecksum},
+		{"/invoke/fn_id", map[string]string{contentSHA256Header: goodSHA256[:10]}, models.ErrInvalidBodyChecksum},
+		{"/t/myapp/src", map[string]string{contentSHA256Header: goodSHA256}, nil},
+		{"/t/myapp/src", map[string]string{contentSHA256Header: badSHA256}, models.ErrBodyChecksumMismatch},
+		{"/invoke/other_fn_id", map[string]string{contentSHA256Header: badSHA256}, nil},
+	} {
+		submitted := false
+		rnr := new(agent.MockAgent)
+		rnr.On("AddCallListener", mock.Anything)
+		rnr.On("GetCall", mock.Anything)
+		rnr.On("Submit", mock.Anything).Run(func(args mock.Arguments) {
+			submitted = true
+		}).Return(nil)
+
+		srv := testServer(ds, rnr, ServerTypeFull)
+
+		req := createRequest(t, http.MethodPost, test.path, strings.NewReader(body))
+		for k, v := range test.headers {
+			req.Header.Set(k, v)
+		}
+		_, rec := routerRequest2(t, srv.Router, req)
+
+		if test.expectedError == nil {
+			if rec.Code != http.StatusOK {
+				t.Errorf("Test %d: expected status code 200 but was %d: %s", i, rec.Code, rec.Body.String())
+			}
+			if !submitted {
+				t.Errorf("Test %d: expected call to be submitted", i)
+			}
+			continue
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Test %d: expected status code 400 but was %d", i, rec.Code)
+		}
+		if submitted {
+			t.Errorf("Test %d: expected call not to be submitted", i)
+		}
+		resp := getErrorResponse(t, rec)
+		if resp == nil || resp.Message != test.expectedError.Error() {
+			t.Errorf("Test %d: expected error `%s`, got %s", i, test.expectedError, rec.Body.String())
+		}
+	}
+}
+
+func TestBodyChecksumChunkedTooLarge(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	annotations, err := models.Annotations{}.With(models.AppVerifyBodyChecksumAnnotation, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &models.App{ID: "app_id", Name: "myapp", Annotations: annotations}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}}
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn})
+
+	rnr := new(agent.MockAgent)
+	rnr.On("AddCallListener", mock.Anything)
+	srv := testServer(ds, rnr, ServerTypeFull, LimitRequestBody(8))
+
+	body := `{"hello": "world"}`
+	sha256Sum := sha256.Sum256([]byte(body))
+	req := createRequest(t, http.MethodPost, "/invoke/fn_id", strings.NewReader(body))
+	req.Header.Set(contentSHA256Header, hex.EncodeToString(sha256Sum[:]))
+	// chunked, so only the read of the body is over the limit
+	req.ContentLength = -1
+	_, rec := routerRequest2(t, srv.Router, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status code 413 but was %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+const (
+	// callMemoryHeader overrides the memory, in MB, of a single call
+	callMemoryHeader = "X-Fn-Memory"
+	// callTimeoutHeader overrides the timeout, in seconds, of a single call
+	callTimeoutHeader = "X-Fn-Timeout"
+	// callOverrideTokenHeader holds the API token a call overriding its
+	// memory or timeout is made with
+	callOverrideTokenHeader = "X-Fn-Override-Token"
+)
+
+// WithCallOverrides caps the memory, in MB, privileged calls may override the
+// memory of their function with. A max of 0 caps it at models.MaxMemory.
+//
+// IMPORTANT: overrides are privileged. A call may only override the memory
+// and timeout of its function, with the X-Fn-Memory and X-Fn-Timeout headers,
+// if it carries the root API token, or an API token which may write to the
+// app of the function, in the X-Fn-Override-Token header. API token auth must
+// be enabled, see WithAPITokenAuth. The headers of any other call are
+// ignored. The override headers are never passed on to functions. The timeout
+// is capped at the sync call max timeout, see WithSyncCallMaxTimeout, or else
+// models.MaxTimeout.
+func WithCallOverrides(maxMemory uint64) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.overrideMaxMemory = maxMemory
+		return nil
+	}
+}
+
+// applyCallOverrides returns fn with the memory and timeout overridden by the
+// headers of req, if it is privileged to, see WithCallOverrides. The override
+// headers are removed from req.
+func (s *Server) applyCallOverrides(req *http.Request, app *models.App, fn *models.Fn) (*models.Fn, error) {
+	memory := req.Header.Get(callMemoryHeader)
+	timeout := req.Header.Get(callTimeoutHeader)
+	secret := req.Header.Get(callOverrideTokenHeader)
+	req.Header.Del(callMemoryHeader)
+	req.Header.Del(callTimeoutHeader)
+	req.Header.Del(callOverrideTokenHeader)
+
+	if memory == "" && timeout == "" {
+		return fn, nil
+	}
+	if !s.callOverridesAllowed(req.Context(), secret, app) {
+		return fn, nil
+	}
+
+	fn = fn.Clone()
+	if memory != "" {
+		mem, err := strconv.ParseUint(memory, 10, 64)
+		if err != nil || mem == 0 {
+			return nil, models.NewAPIError(http.StatusBadRequest, fmt.Errorf("Invalid %s header, it must be a number of MB", callMemoryHeader))
+		}
+		max := s.overrideMaxMemory
+		if max == 0 || max > models.MaxMemory {
+			max = models.MaxMemory
+		}
+		if mem > max {
+			mem = max
+		}
+		fn.Memory = mem
+	}
+	if timeout != "" {
+		secs, err := strconv.ParseInt(timeout, 10, 32)
+		if err != nil || secs <= 0 {
+			return nil, models.NewAPIError(http.StatusBadRequest, fmt.Errorf("Invalid %s header, it must be a number of seconds", callTimeoutHeader))
+		}
+		max := s.syncCallMaxTimeout
+		if max == 0 {
+			max = models.MaxTimeout
+		}
+		if int32(secs) > max {
+			secs = int64(max)
+		}
+		fn.Timeout = int32(secs)
+	}
+
+	common.Logger(req.Context()).WithField("memory", fn.Memory).WithField("timeout", fn.Timeout).Info("Call resources overridden")
+	return fn, nil
+}
+
+// callOverridesAllowed returns whether secret is the root API token, or an
+// API token which may write to app
+func (s *Server) callOverridesAllowed(ctx context.Context, secret string, app *models.App) bool {
+	if s.apiRootToken == "" || secret == "" {
+		return false
+	}
+	token, err := s.lookupAPIToken(ctx, secret)
+	if err != nil {
+		common.Logger(ctx).WithError(err).Info("Call overrides ignored, invalid API token")
+		return false
+	}
+	return token == nil || token.Allows(app.ID, true)
+}
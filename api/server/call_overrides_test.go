@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore/memory"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCallOverrides(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ctx := context.Background()
+	ds := memory.New()
+	app, err := ds.InsertApp(ctx, &models.App{Name: "myapp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := &models.Fn{Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}}
+	fn.SetDefaults()
+	fn, err = ds.InsertFn(ctx, fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.InsertTrigger(ctx, &models.Trigger{Name: "mytrigger", AppID: app.ID, FnID: fn.ID, Type: models.TriggerTypeHTTP, Source: "/src"}); err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := models.GetTokenDatastore(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []*models.APIToken{
+		{Name: "write", AppIDs: models.TokenApps{app.ID}, Permission: models.TokenPermissionWrite, Hash: models.HashAPIToken("write")},
+		{Name: "read", AppIDs: models.TokenApps{app.ID}, Permission: models.TokenPermissionRead, Hash: models.HashAPIToken("read")},
+		{Name: "other", AppIDs: models.TokenApps{"other_app_id"}, Permission: models.TokenPermissionWrite, Hash: models.HashAPIToken("other")},
+	} {
+		if _, err := tokens.InsertToken(ctx, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, test := range []struct {
+		path            string
+		token           string
+		memory          string
+		timeout         string
+		expectedCode    int
+		expectedMemory  uint64
+		expectedTimeout int32
+	}{
+		{"/invoke/" + fn.ID, "", "", "", http.StatusOK, 128, 30},
+		{"/invoke/" + fn.ID, "root", "512", "60", http.StatusOK, 512, 60},
+		{"/invoke/" + fn.ID, "write", "512", "", http.StatusOK, 512, 30},
+		{"/t/myapp/src", "write", "", "60", http.StatusOK, 128, 60},
+		// clamped to the maximums
+		{"/invoke/" + fn.ID, "root", "4096", "600", http.StatusOK, 1024, 120},
+		// unprivileged calls have the headers ignored
+		{"/invoke/" + fn.ID, "", "512", "60", http.StatusOK, 128, 30},
+		{"/invoke/" + fn.ID, "read", "512", "60", http.StatusOK, 128, 30},
+		{"/invoke/" + fn.ID, "other", "512", "60", http.StatusOK, 128, 30},
+		{"/invoke/" + fn.ID, "nope", "512", "60", http.StatusOK, 128, 30},
+		{"/invoke/" + fn.ID, "root", "lots", "", http.StatusBadRequest, 0, 0},
+		{"/invoke/" + fn.ID, "root", "", "-1", http.StatusBadRequest, 0, 0},
+	} {
+		var called *models.Call
+		rnr := new(agent.MockAgent)
+		rnr.On("AddCallListener", mock.Anything)
+		rnr.On("GetCall", mock.Anything)
+		rnr.On("Submit", mock.Anything).Run(func(args mock.Arguments) {
+			called = args.Get(0).(agent.Call).Model()
+		}).Return(nil)
+
+		srv := testServer(ds, rnr, ServerTypeFull, WithAPITokenAuth("root"), WithCallOverrides(1024), WithSyncCallMaxTimeout(120*time.Second))
+
+		req := createRequest(t, http.MethodPost, test.path, strings.NewReader(`{}`))
+		if test.token != "" {
+			req.Header.Set(callOverrideTokenHeader, test.token)
+		}
+		if test.memory != "" {
+			req.Header.Set(callMemoryHeader, test.memory)
+		}
+		if test.timeout != "" {
+			req.Header.Set(callTimeoutHeader, test.timeout)
+		}
+		_, rec := routerRequest2(t, srv.Router, req)
+
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+			continue
+		}
+		if test.expectedCode != http.StatusOK {
+			continue
+		}
+		if called.Memory != test.expectedMemory || called.Timeout != test.expectedTimeout {
+			t.Errorf("Test %d: expected memory %d and timeout %d, got %d and %d", i, test.expectedMemory, test.expectedTimeout, called.Memory, called.Timeout)
+		}
+		for k := range called.Headers {
+			if strings.Contains(k, "X-Fn-") {
+				t.Errorf("Test %d: expected the override headers not to reach the function, got %s", i, k)
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+var (
+	clientCancelledMeasure = common.MakeMeasure("server/client_cancelled_calls", "Number of sync calls cancelled by their client disconnecting", stats.UnitDimensionless)
+)
+
+// RegisterClientCancelViews registers the views for calls cancelled by their client
+func RegisterClientCancelViews(tagKeys []string) {
+	tags := []tag.Key{agent.AppIDMetricKey}
+	for _, key := range tagKeys {
+		if key != agent.AppIDMetricKey.Name() {
+			tags = append(tags, common.MakeKey(key))
+		}
+	}
+
+	err := view.Register(common.CreateViewWithTags(clientCancelledMeasure, view.Count(), tags))
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot register view")
+	}
+}
+
+// clientCancelled returns whether the client of a call disconnected before
+// its response was written, and records it if so. The call was then cancelled
+// too, as the agent runs it with the context of the request, which stops the
+// container work on the runner.
+func clientCancelled(ctx context.Context, appID string) bool {
+	if ctx.Err() != context.Canceled {
+		return false
+	}
+
+	common.Logger(ctx).Info("call cancelled, client disconnected")
+	ctx, err := tag.New(ctx, tag.Upsert(agent.AppIDMetricKey, appID))
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	stats.Record(ctx, clientCancelledMeasure.M(1))
+	return true
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+	"go.opencensus.io/stats/view"
+)
+
+func TestClientCancelledCall(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+	RegisterClientCancelViews(nil)
+	before := clientCancelledCalls(t, "app_id")
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}}
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn})
+
+	started := make(chan struct{})
+	ended := make(chan error, 1)
+	var reqCtx context.Context
+
+	rnr := new(agent.MockAgent)
+	rnr.On("AddCallListener", mock.Anything)
+	rnr.On("GetCall", mock.Anything)
+	rnr.On("Submit", mock.Anything).Run(func(args mock.Arguments) {
+		// as the agent sending the body to the container and running the call,
+		// with the context of the request
+		body := args.Get(0).(interface{ RequestBody() io.ReadCloser }).RequestBody()
+		ioutil.ReadAll(body)
+		close(started)
+		select {
+		case <-reqCtx.Done():
+			ended <- reqCtx.Err()
+		case <-time.After(5 * time.Second):
+			ended <- nil
+		}
+	}).Return(context.Canceled)
+
+	srv := testServer(ds, rnr, ServerTypeFull)
+	srv.AddRootMiddlewareFunc(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reqCtx = r.Context()
+			next.ServeHTTP(w, r)
+		})
+	})
+	ts := httptest.NewServer(srv.Router)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/invoke/fn_id", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		<-started
+		cancel() // the client disconnects
+	}()
+	if _, err := http.DefaultClient.Do(req.WithContext(ctx)); err == nil {
+		t.Fatal("expected the request to be cancelled")
+	}
+
+	if err := <-ended; err != context.Canceled {
+		t.Fatalf("expected the call to be cancelled, got %v", err)
+	}
+
+	// the handler records the cancellation after the call returns
+	deadline := time.Now().Add(5 * time.Second)
+	for clientCancelledCalls(t, app.ID) != before+1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a client cancelled call of the app, got %d", clientCancelledCalls(t, app.ID)-before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func clientCancelledCalls(t *testing.T, appID string) int64 {
+	rows, err := view.RetrieveData("server/client_cancelled_calls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if len(row.Tags) > 0 && row.Tags[0].Value == appID {
+			return row.Data.(*view.CountData).Value
+		}
+	}
+	return 0
+}
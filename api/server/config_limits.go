@@ -0,0 +1,48 @@
+package server
+
+import (
+	"github.com/fnproject/fn/api/models"
+)
+
+// configLimited returns whether configs need checking at all, so that handlers
+// can skip the reads checkConfigUpdate needs.
+func (s *Server) configLimited() bool {
+	return s.maxConfigKeys > 0 || s.maxConfigBytes > 0
+}
+
+// checkConfig checks a config against s.maxConfigKeys and s.maxConfigBytes.
+func (s *Server) checkConfig(config models.Config) error {
+	if s.maxConfigKeys > 0 && len(config) > s.maxConfigKeys {
+		return models.ErrConfigTooManyKeys
+	}
+	if s.maxConfigBytes > 0 {
+		size := 0
+		for k, v := range config {
+			size += len(k) + len(v)
+		}
+		if size > s.maxConfigBytes {
+			return models.ErrConfigTooLarge
+		}
+	}
+	return nil
+}
+
+// checkConfigUpdate checks the config resulting from applying patch to config,
+// as the Update methods of the models do: empty values remove their key.
+func (s *Server) checkConfigUpdate(config, patch models.Config) error {
+	if patch == nil {
+		return nil
+	}
+	merged := make(models.Config, len(config)+len(patch))
+	for k, v := range config {
+		merged[k] = v
+	}
+	for k, v := range patch {
+		if v == "" {
+			delete(merged, k)
+		} else {
+			merged[k] = v
+		}
+	}
+	return s.checkConfig(merged)
+}
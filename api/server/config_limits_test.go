@@ -0,0 +1,73 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestConfigLimits(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	limits := []Option{WithMaxConfig(2, 6)}
+
+	for i, test := range []struct {
+		opts          []Option
+		method        string
+		path          string
+		body          string
+		expectedCode  int
+		expectedError error
+	}{
+		{nil, http.MethodPost, "/v2/apps", `{ "name": "newapp", "config": { "a": "1", "b": "2", "c": "3" } }`, http.StatusOK, nil},
+		{limits, http.MethodPost, "/v2/apps", `{ "name": "newapp", "config": { "ab": "cd", "e": "f" } }`, http.StatusOK, nil},
+		{limits, http.MethodPost, "/v2/apps", `{ "name": "newapp", "config": { "a": "1", "b": "2", "c": "3" } }`, http.StatusBadRequest, models.ErrConfigTooManyKeys},
+		{limits, http.MethodPost, "/v2/apps", `{ "name": "newapp", "config": { "abc": "def" } }`, http.StatusOK, nil},
+		{limits, http.MethodPost, "/v2/apps", `{ "name": "newapp", "config": { "abc": "defg" } }`, http.StatusBadRequest, models.ErrConfigTooLarge},
+		{limits, http.MethodPost, "/v2/apps?dry_run=true", `{ "name": "newapp", "config": { "abc": "defg" } }`, http.StatusBadRequest, models.ErrConfigTooLarge},
+
+		// updates are checked against the resulting config, the app has { "A": "12" }
+		{limits, http.MethodPut, "/v2/apps/appid", `{ "config": { "x": "y" } }`, http.StatusOK, nil},
+		{limits, http.MethodPut, "/v2/apps/appid", `{ "config": { "x": "y", "z": "w" } }`, http.StatusBadRequest, models.ErrConfigTooManyKeys},
+		{limits, http.MethodPut, "/v2/apps/appid", `{ "config": { "A": "", "x": "y", "z": "w" } }`, http.StatusOK, nil},
+		{limits, http.MethodPut, "/v2/apps/appid", `{ "config": { "A": "12345" } }`, http.StatusOK, nil},
+		{limits, http.MethodPut, "/v2/apps/appid", `{ "config": { "A": "123456" } }`, http.StatusBadRequest, models.ErrConfigTooLarge},
+		{limits, http.MethodPut, "/v2/apps/appid", `{ "annotations": { "x": "y" } }`, http.StatusOK, nil},
+
+		{nil, http.MethodPost, "/v2/fns", `{ "app_id": "appid", "name": "newfn", "image": "fnproject/fn-test-utils", "config": { "a": "1", "b": "2", "c": "3" } }`, http.StatusOK, nil},
+		{limits, http.MethodPost, "/v2/fns", `{ "app_id": "appid", "name": "newfn", "image": "fnproject/fn-test-utils", "config": { "ab": "cd", "e": "f" } }`, http.StatusOK, nil},
+		{limits, http.MethodPost, "/v2/fns", `{ "app_id": "appid", "name": "newfn", "image": "fnproject/fn-test-utils", "config": { "a": "1", "b": "2", "c": "3" } }`, http.StatusBadRequest, models.ErrConfigTooManyKeys},
+		{limits, http.MethodPost, "/v2/fns", `{ "app_id": "appid", "name": "newfn", "image": "fnproject/fn-test-utils", "config": { "abc": "defg" } }`, http.StatusBadRequest, models.ErrConfigTooLarge},
+
+		// the fn has { "B": "34" }
+		{limits, http.MethodPut, "/v2/fns/fnid", `{ "config": { "x": "y" } }`, http.StatusOK, nil},
+		{limits, http.MethodPut, "/v2/fns/fnid", `{ "config": { "x": "y", "z": "w" } }`, http.StatusBadRequest, models.ErrConfigTooManyKeys},
+		{limits, http.MethodPut, "/v2/fns/fnid", `{ "config": { "B": "12345" } }`, http.StatusOK, nil},
+		{limits, http.MethodPut, "/v2/fns/fnid", `{ "config": { "B": "123456" } }`, http.StatusBadRequest, models.ErrConfigTooLarge},
+	} {
+		a := &models.App{ID: "appid", Name: "app", Config: models.Config{"A": "12"}}
+		fn := &models.Fn{ID: "fnid", Name: "fn", AppID: a.ID, Image: "fnproject/fn-test-utils", Config: models.Config{"B": "34"}}
+		fn.SetDefaults()
+		ds := datastore.NewMockInit([]*models.App{a}, []*models.Fn{fn})
+		srv := testServer(ds, nil, ServerTypeAPI, test.opts...)
+
+		_, rec := routerRequest(t, srv.Router, test.method, test.path, bytes.NewBufferString(test.body))
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+		if test.expectedError != nil {
+			resp := getErrorResponse(t, rec)
+			if resp.Message != test.expectedError.Error() {
+				t.Errorf("Test %d: expected error `%s` but got `%s`", i, test.expectedError, resp.Message)
+			}
+		}
+	}
+}
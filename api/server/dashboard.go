@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+type dashboardAsset struct {
+	contentType string
+	body        []byte
+}
+
+var dashboardAssets = map[string]dashboardAsset{
+	"/":              {"text/html; charset=utf-8", []byte(dashboardIndexHTML)},
+	"/index.html":    {"text/html; charset=utf-8", []byte(dashboardIndexHTML)},
+	"/dashboard.css": {"text/css; charset=utf-8", []byte(dashboardCSS)},
+	"/dashboard.js":  {"application/javascript; charset=utf-8", []byte(dashboardJS)},
+}
+
+// WithDashboard makes the admin server serve a read-only dashboard at /ui/,
+// listing the apps and the fns and triggers of each. It is a static page the
+// browser renders from the /v2 API, at the API URL and with the API token
+// entered in it. It is only served when an admin token is set (see
+// WithAdminToken), which browsers prompt for as the password.
+func WithDashboard() Option {
+	return func(ctx context.Context, s *Server) error {
+		s.dashboard = true
+		return nil
+	}
+}
+
+// dashboardAuthWrap is adminAuthWrap, also taking the token as the password
+// of basic auth, which browsers prompt for
+func dashboardAuthWrap(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := bearerToken(c.Request)
+		if secret == "" {
+			_, secret, _ = c.Request.BasicAuth()
+		}
+		if secret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Basic realm="fn-admin"`)
+			handleErrorResponse(c, models.ErrAdminTokenInvalid)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func handleDashboardAsset(c *gin.Context) {
+	asset, ok := dashboardAssets[c.Param("asset")]
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	// the page holds the API token, it is not to be framed or cached
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Cache-Control", "no-store")
+	if strings.HasPrefix(asset.contentType, "text/html") {
+		c.Header("Content-Security-Policy", "default-src 'self'; connect-src *")
+	}
+	c.Data(http.StatusOK, asset.contentType, asset.body)
+}
@@ -0,0 +1,201 @@
+package server
+
+// the static assets of the dashboard served at /ui/ on the admin server, see
+// WithDashboard. It is a single page listing apps, and the fns and triggers
+// of the app picked, read from the /v2 API with the browser's fetch.
+
+const dashboardIndexHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>fn dashboard</title>
+  <link rel="stylesheet" href="dashboard.css">
+</head>
+<body>
+  <header>
+    <h1>fn</h1>
+    <form id="settings">
+      <label>API URL <input id="api-url" type="url" placeholder="http://localhost:8080"></label>
+      <label>API token <input id="api-token" type="password" autocomplete="off"></label>
+      <button type="submit">Load</button>
+    </form>
+  </header>
+  <p id="error" hidden></p>
+  <main>
+    <section>
+      <h2>Apps</h2>
+      <ul id="apps"></ul>
+    </section>
+    <section>
+      <h2>Fns <span id="app-name"></span></h2>
+      <table>
+        <thead><tr><th>Name</th><th>ID</th><th>Image</th><th>Memory</th><th>Timeout</th></tr></thead>
+        <tbody id="fns"></tbody>
+      </table>
+      <h2>Triggers</h2>
+      <table>
+        <thead><tr><th>Name</th><th>Type</th><th>Source</th><th>Endpoint</th></tr></thead>
+        <tbody id="triggers"></tbody>
+      </table>
+    </section>
+  </main>
+  <script src="dashboard.js"></script>
+</body>
+</html>
+`
+
+const dashboardCSS = `body {
+  font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif;
+  margin: 0;
+  color: #222;
+}
+header {
+  display: flex;
+  align-items: center;
+  justify-content: space-between;
+  padding: 0 1em;
+  background: #2a2a2a;
+  color: #fff;
+}
+header label {
+  margin-right: 1em;
+}
+main {
+  display: flex;
+}
+section {
+  padding: 0 1em;
+}
+section:first-child {
+  min-width: 15em;
+  border-right: 1px solid #ddd;
+}
+#apps {
+  list-style: none;
+  padding: 0;
+}
+#apps li {
+  padding: 0.3em 0;
+  cursor: pointer;
+}
+#apps li.selected {
+  font-weight: bold;
+}
+table {
+  border-collapse: collapse;
+}
+th, td {
+  padding: 0.3em 1em 0.3em 0;
+  text-align: left;
+  border-bottom: 1px solid #eee;
+}
+#error {
+  margin: 1em;
+  color: #b00;
+}
+`
+
+const dashboardJS = `(function () {
+  "use strict";
+
+  var settings = {
+    url: sessionStorage.getItem("fn.api-url") || window.location.origin,
+    token: sessionStorage.getItem("fn.api-token") || ""
+  };
+
+  function byId(id) {
+    return document.getElementById(id);
+  }
+
+  function showError(err) {
+    var el = byId("error");
+    el.textContent = err ? String(err) : "";
+    el.hidden = !err;
+  }
+
+  // get returns the items of a list of the /v2 API, following its cursors
+  function get(path, items) {
+    items = items || [];
+    var headers = {};
+    if (settings.token) {
+      headers.Authorization = "Bearer " + settings.token;
+    }
+    return fetch(settings.url.replace(/\/+$/, "") + "/v2" + path, {headers: headers}).then(function (resp) {
+      return resp.json().then(function (body) {
+        if (!resp.ok) {
+          throw new Error(resp.status + ": " + (body.message || resp.statusText));
+        }
+        items = items.concat(body.items || []);
+        if (!body.next_cursor) {
+          return items;
+        }
+        var sep = path.indexOf("?") < 0 ? "?" : "&";
+        return get(path.replace(/[?&]cursor=[^&]*/, "") + sep + "cursor=" + encodeURIComponent(body.next_cursor), items);
+      });
+    });
+  }
+
+  function row(tbody, values) {
+    var tr = document.createElement("tr");
+    values.forEach(function (v) {
+      var td = document.createElement("td");
+      td.textContent = v === undefined ? "" : v;
+      tr.appendChild(td);
+    });
+    tbody.appendChild(tr);
+  }
+
+  function clear(el) {
+    while (el.firstChild) {
+      el.removeChild(el.firstChild);
+    }
+  }
+
+  function showApp(app, li) {
+    Array.prototype.forEach.call(byId("apps").children, function (el) {
+      el.classList.toggle("selected", el === li);
+    });
+    byId("app-name").textContent = "of " + app.name;
+    var query = "?app_id=" + encodeURIComponent(app.id);
+    Promise.all([get("/fns" + query), get("/triggers" + query)]).then(function (lists) {
+      var fns = byId("fns"), triggers = byId("triggers");
+      clear(fns);
+      clear(triggers);
+      lists[0].forEach(function (fn) {
+        row(fns, [fn.name, fn.id, fn.image, fn.memory, fn.timeout]);
+      });
+      lists[1].forEach(function (t) {
+        row(triggers, [t.name, t.type, t.source, (t.annotations || {})["fnproject.io/trigger/httpEndpoint"]]);
+      });
+      showError(null);
+    }).catch(showError);
+  }
+
+  function load() {
+    get("/apps").then(function (apps) {
+      var ul = byId("apps");
+      clear(ul);
+      apps.forEach(function (app) {
+        var li = document.createElement("li");
+        li.textContent = app.name;
+        li.addEventListener("click", function () { showApp(app, li); });
+        ul.appendChild(li);
+      });
+      showError(null);
+    }).catch(showError);
+  }
+
+  byId("api-url").value = settings.url;
+  byId("api-token").value = settings.token;
+  byId("settings").addEventListener("submit", function (e) {
+    e.preventDefault();
+    settings.url = byId("api-url").value || window.location.origin;
+    settings.token = byId("api-token").value;
+    sessionStorage.setItem("fn.api-url", settings.url);
+    sessionStorage.setItem("fn.api-token", settings.token);
+    load();
+  });
+  load();
+})();
+`
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDashboard(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ag := &cancelerAgent{}
+	ag.On("AddCallListener", mock.Anything)
+
+	// not served unless enabled
+	srv := testServer(datastore.NewMock(), ag, ServerTypeFull, WithAdminToken("s3cret"))
+	if _, rec := routerRequest(t, srv.AdminRouter, http.MethodGet, "/ui/", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status code 404 but was %d", rec.Code)
+	}
+
+	srv = testServer(datastore.NewMock(), ag, ServerTypeFull, WithAdminToken("s3cret"), WithDashboard())
+	for i, test := range []struct {
+		path                string
+		auth                string
+		expectedCode        int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{"/ui/", "", http.StatusUnauthorized, "application/json", models.ErrorCodeAdminTokenInvalid},
+		{"/ui/", "Basic dXNlcjp3cm9uZw==", http.StatusUnauthorized, "application/json", models.ErrorCodeAdminTokenInvalid},
+		{"/ui", "", http.StatusMovedPermanently, "", ""},
+		{"/ui/", "Bearer s3cret", http.StatusOK, "text/html; charset=utf-8", "<!DOCTYPE html>"},
+		{"/ui/index.html", "Basic dXNlcjpzM2NyZXQ=", http.StatusOK, "text/html; charset=utf-8", "<!DOCTYPE html>"},
+		{"/ui/dashboard.js", "Bearer s3cret", http.StatusOK, "application/javascript; charset=utf-8", "/v2"},
+		{"/ui/dashboard.css", "Bearer s3cret", http.StatusOK, "text/css; charset=utf-8", "body {"},
+		{"/ui/missing.js", "Bearer s3cret", http.StatusNotFound, "", ""},
+	} {
+		req := createRequest(t, http.MethodGet, test.path, nil)
+		if test.auth != "" {
+			req.Header.Set("Authorization", test.auth)
+		}
+		_, rec := routerRequest2(t, srv.AdminRouter, req)
+
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d", i, test.expectedCode, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, test.expectedContentType) {
+			t.Errorf("Test %d: expected content type %s, got %s", i, test.expectedContentType, ct)
+		}
+		if body := rec.Body.String(); !strings.Contains(body, test.expectedBody) {
+			t.Errorf("Test %d: expected body to contain %s, got %s", i, test.expectedBody, body)
+		}
+	}
+	if _, rec := routerRequest(t, srv.AdminRouter, http.MethodGet, "/ui/", nil); rec.Header().Get("WWW-Authenticate") != `Basic realm="fn-admin"` {
+		t.Errorf("expected browsers to be asked for the admin token, got %v", rec.Header())
+	}
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/fnext"
+)
+
+// Kinds of DataCacheInvalidation
+const (
+	InvalidateApp      = "app"
+	InvalidateFn       = "fn"
+	InvalidateTriggers = "triggers"
+)
+
+// DataCacheInvalidation identifies the entries of a data cache made stale by a
+// change to an app, fn or trigger.
+type DataCacheInvalidation struct {
+	Kind string `json:"kind"`
+	// ID is the ID of the app or fn, or for triggers the ID of their app, if
+	// empty the triggers of all apps are invalidated
+	ID string `json:"id,omitempty"`
+}
+
+func (inv DataCacheInvalidation) apply(cache agent.DataCache) error {
+	switch inv.Kind {
+	case InvalidateApp:
+		cache.InvalidateApp(inv.ID)
+	case InvalidateFn:
+		cache.InvalidateFn(inv.ID)
+	case InvalidateTriggers:
+		cache.InvalidateTriggers(inv.ID)
+	default:
+		return fmt.Errorf("unknown invalidation kind %q", inv.Kind)
+	}
+	return nil
+}
+
+// InvalidationPublisher sends the invalidations for the changes made through
+// the API of a server to other nodes, which apply them to their own cache with
+// InvalidateDataCache. Publish is called after each change, it should not block.
+type InvalidationPublisher interface {
+	Publish(ctx context.Context, inv DataCacheInvalidation) error
+}
+
+type nopInvalidationPublisher struct{}
+
+func (nopInvalidationPublisher) Publish(ctx context.Context, inv DataCacheInvalidation) error {
+	return nil
+}
+
+// InvalidateDataCache drops the entries of the read data access cache of the
+// server identified by inv, for invalidations published by other nodes.
+func (s *Server) InvalidateDataCache(inv DataCacheInvalidation) error {
+	if s.dataCache == nil {
+		return nil
+	}
+	return inv.apply(s.dataCache)
+}
+
+// dataCacheInvalidator drops the entries of the read data access cache made
+// stale by changes through the API of this server, and publishes them for
+// other nodes. Creations are ignored, as failed reads are not cached.
+type dataCacheInvalidator struct {
+	cache     agent.DataCache
+	publisher InvalidationPublisher
+}
+
+var (
+	_ fnext.AppListener     = new(dataCacheInvalidator)
+	_ fnext.FnListener      = new(dataCacheInvalidator)
+	_ fnext.TriggerListener = new(dataCacheInvalidator)
+)
+
+// invalidate never fails, the change is made by the time it's called
+func (d *dataCacheInvalidator) invalidate(ctx context.Context, inv DataCacheInvalidation) error {
+	if d.cache != nil {
+		inv.apply(d.cache)
+	}
+	if err := d.publisher.Publish(ctx, inv); err != nil {
+		common.Logger(ctx).WithError(err).WithField("invalidation", inv).Error("failed to publish data cache invalidation")
+	}
+	return nil
+}
+
+func (d *dataCacheInvalidator) BeforeAppCreate(ctx context.Context, app *models.App) error {
+	return nil
+}
+
+func (d *dataCacheInvalidator) AfterAppCreate(ctx context.Context, app *models.App) error {
+	return nil
+}
+
+func (d *dataCacheInvalidator) BeforeAppUpdate(ctx context.Context, app *models.App) error {
+	return nil
+}
+
+func (d *dataCacheInvalidator) AfterAppUpdate(ctx context.Context, app *models.App) error {
+	return d.invalidate(ctx, DataCacheInvalidation{Kind: InvalidateApp, ID: app.ID})
+}
+
+func (d *dataCacheInvalidator) BeforeAppDelete(ctx context.Context, app *models.App) error {
+	return nil
+}
+
+func (d *dataCacheInvalidator) AfterAppDelete(ctx context.Context, app *models.App) error {
+	// RemoveApp only knows the ID of the app, which it passes as the name
+	return d.invalidate(ctx, DataCacheInvalidation{Kind: InvalidateApp, ID: app.Name})
+}
+
+func (d *dataCacheInvalidator) BeforeAppGet(ctx context.Context, appID string) error {
+	return nil
+}
+
+func (d *dataCacheInvalidator) AfterAppGet(ctx context.Context, app *models.App) error {
+	return nil
+}
+
+func (d *dataCacheInvalidator) BeforeAppsList(ctx context.Context, filter *models.AppFilter) error {
+	return nil
+}
+
+func (d *dataCacheInvalidator) AfterAppsList(ctx context.Context, apps []*models.App) error {
+	return nil
+}
+
+func (d *dataCacheInvalidator) BeforeFnCreate(ctx context.Context, fn *models.Fn) error {
+	return nil
+}
+
+func (d *dataCacheInvalidator) AfterFnCreate(ctx context.Context, fn *models.Fn) error {
+	return nil
+}
+
+func (d *dataCacheInvalidator) BeforeFnUpdate(ctx context.Context, fn *models.Fn) error {
+	return nil
+}
+
+func (d *dataCacheInvalidator) AfterFnUpdate(ctx context.Context, fn *models.Fn) error {
+	return d.invalidate(ctx, DataCacheInvalidation{Kind: InvalidateFn, ID: fn.ID})
+}
+
+func (d *dataCacheInvalidator) BeforeFnDelete(ctx context.Context, fnID string) error {
+	return nil
+}
+
+func (d *dataCacheInvalidator) AfterFnDelete(ctx context.Context, fnID string) error {
+	return d.invalidate(ctx, DataCacheInvalidation{Kind: InvalidateFn, ID: fnID})
+}
+
+func (d *dataCacheInvalidator) BeforeTriggerCreate(ctx context.Context, trigger *models.Trigger) error {
+	return nil
+}
+
+func (d *dataCacheInvalidator) AfterTriggerCreate(ctx context.Context, trigger *models.Trigger) error {
+	return nil
+}
+
+func (d *dataCacheInvalidator) BeforeTriggerUpdate(ctx context.Context, trigger *models.Trigger) error {
+	return nil
+}
+
+func (d *dataCacheInvalidator) AfterTriggerUpdate(ctx context.Context, trigger *models.Trigger) error {
+	// the trigger may be cached under its previous source, which is gone by now
+	return d.invalidate(ctx, DataCacheInvalidation{Kind: InvalidateTriggers, ID: trigger.AppID})
+}
+
+func (d *dataCacheInvalidator) BeforeTriggerDelete(ctx context.Context, triggerID string) error {
+	return nil
+}
+
+func (d *dataCacheInvalidator) AfterTriggerDelete(ctx context.Context, triggerID string) error {
+	// triggers are cached by source, without the app of the trigger drop them all
+	return d.invalidate(ctx, DataCacheInvalidation{Kind: InvalidateTriggers})
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestDataCacheInvalidation(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ctx := context.Background()
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils"}
+	fn.SetDefaults()
+	trigger := &models.Trigger{ID: "trigger_id", Name: "trigger", AppID: app.ID, FnID: fn.ID, Type: "http", Source: "/src"}
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn}, []*models.Trigger{trigger})
+
+	srv := testServer(ds, nil, ServerTypeAPI, WithDataCacheTTL(time.Hour))
+	rda := srv.lbReadAccess
+
+	if _, err := rda.GetFnByID(ctx, fn.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rda.GetTriggerBySource(ctx, app.ID, "http", "/src"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, rec := routerRequest(t, srv.Router, http.MethodPut, "/v2/fns/fn_id", bytes.NewBufferString(`{ "image": "fnproject/other" }`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status code 200 but was %d: %s", rec.Code, rec.Body.String())
+	}
+	got, err := rda.GetFnByID(ctx, fn.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Image != "fnproject/other" {
+		t.Errorf("expected the updated fn to be read, got image %s", got.Image)
+	}
+
+	_, rec = routerRequest(t, srv.Router, http.MethodDelete, "/v2/triggers/trigger_id", nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status code 204 but was %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := rda.GetTriggerBySource(ctx, app.ID, "http", "/src"); err != models.ErrTriggerNotFound {
+		t.Errorf("expected the deleted trigger not to be found, got %v", err)
+	}
+}
+
+func TestDataCacheInvalidationWebhook(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ctx := context.Background()
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils"}
+	fn.SetDefaults()
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn})
+
+	// the subscriber caches reads of the datastore the publisher changes
+	sub := testServer(ds, nil, ServerTypeAPI, WithDataCacheTTL(time.Hour), WithAdminToken("s3cret"))
+	hook := httptest.NewServer(sub.AdminRouter)
+	defer hook.Close()
+	pub := testServer(ds, nil, ServerTypeAPI, WithDataCacheTTL(0),
+		WithInvalidationPublisher(NewWebhookInvalidationPublisher([]string{hook.URL + "/cache/invalidate"}, "s3cret")))
+
+	if _, err := sub.lbReadAccess.GetFnByID(ctx, fn.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	_, rec := routerRequest(t, pub.Router, http.MethodPut, "/v2/fns/fn_id", bytes.NewBufferString(`{ "image": "fnproject/other" }`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status code 200 but was %d: %s", rec.Code, rec.Body.String())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, err := sub.lbReadAccess.GetFnByID(ctx, fn.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Image == "fnproject/other" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the subscriber to read the updated fn, got image %s", got.Image)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	_, rec = routerRequest(t, sub.AdminRouter, http.MethodPost, "/cache/invalidate", bytes.NewBufferString(`{ "kind": "fn", "id": "fn_id" }`))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status code 401 without the admin token but was %d: %s", rec.Code, rec.Body.String())
+	}
+
+	req := createRequest(t, http.MethodPost, "/cache/invalidate", bytes.NewBufferString(`{ "kind": "route" }`))
+	req.Header.Set("Authorization", "Bearer s3cret")
+	_, rec = routerRequest2(t, sub.AdminRouter, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status code 400 for an unknown kind but was %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,337 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultDebugCaptureMaxBody is the number of bytes of the request and
+	// response bodies kept per captured call, past which they are truncated
+	DefaultDebugCaptureMaxBody = 64 * 1024
+
+	defaultDebugCaptureDuration = 5 * time.Minute
+	maxDebugCaptureDuration     = time.Hour
+	// the calls kept per app, the oldest are dropped past it
+	maxDebugCaptureCalls = 100
+
+	redactedHeaderValue = "[REDACTED]"
+)
+
+// the headers always redacted from the captured calls, on top of those
+// configured with WithDebugCapture
+var defaultDebugCaptureRedactHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"Fn-Invoke-Token",
+}
+
+// WithDebugCapture configures the debug captures of the calls of an app,
+// which the admin server starts with POST /debug/capture and serves with
+// GET /debug/capture/:app_id, when an admin token is set (see
+// WithAdminToken). The request and response bodies of captured calls are
+// truncated to maxBody bytes (0 means DefaultDebugCaptureMaxBody), and the
+// values of redactHeaders, on top of Authorization, Cookie and the like, are
+// replaced with [REDACTED], whether or not they are prefixed with Fn-Http-H-
+// as those of trigger calls are.
+//
+// Captures hold the bodies of calls, which may carry personal data or
+// secrets, in the memory of this node, in clear, until they expire. They are
+// never on unless an operator starts one, and expire after at most an hour.
+func WithDebugCapture(maxBody int, redactHeaders []string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.debugCaptures = newDebugCaptures(maxBody, redactHeaders)
+		return nil
+	}
+}
+
+// DebugCapturedCall is a call captured for debugging, its request and
+// response bodies truncated and its sensitive headers redacted
+type DebugCapturedCall struct {
+	CallID                string      `json:"call_id,omitempty"`
+	StartedAt             time.Time   `json:"started_at"`
+	Method                string      `json:"method"`
+	URL                   string      `json:"url"`
+	RequestHeaders        http.Header `json:"request_headers"`
+	RequestBody           string      `json:"request_body"`
+	RequestBodyTruncated  bool        `json:"request_body_truncated,omitempty"`
+	Status                int         `json:"status"`
+	ResponseHeaders       http.Header `json:"response_headers,omitempty"`
+	ResponseBody          string      `json:"response_body,omitempty"`
+	ResponseBodyTruncated bool        `json:"response_body_truncated,omitempty"`
+	Error                 string      `json:"error,omitempty"`
+}
+
+type debugCaptureRequest struct {
+	AppID    string `json:"app_id"`
+	Duration string `json:"duration"`
+}
+
+type debugCaptureResponse struct {
+	AppID     string               `json:"app_id"`
+	ExpiresAt time.Time            `json:"expires_at"`
+	Calls     []*DebugCapturedCall `json:"calls,omitempty"`
+}
+
+type appDebugCapture struct {
+	expiresAt time.Time
+	timer     *time.Timer
+	calls     []*DebugCapturedCall
+}
+
+// debugCaptures holds the calls captured per app, until their capture expires
+type debugCaptures struct {
+	maxBody int
+	redact  map[string]bool
+
+	mu   sync.Mutex
+	apps map[string]*appDebugCapture
+}
+
+func newDebugCaptures(maxBody int, redactHeaders []string) *debugCaptures {
+	if maxBody <= 0 {
+		maxBody = DefaultDebugCaptureMaxBody
+	}
+	redact := make(map[string]bool)
+	for _, h := range append(defaultDebugCaptureRedactHeaders, redactHeaders...) {
+		redact[http.CanonicalHeaderKey(h)] = true
+	}
+	return &debugCaptures{maxBody: maxBody, redact: redact, apps: make(map[string]*appDebugCapture)}
+}
+
+// start captures the calls of appID until d elapsed, extending or shortening a
+// capture already active, and returns when it expires
+func (d *debugCaptures) start(appID string, dur time.Duration) time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	capture, ok := d.apps[appID]
+	if !ok {
+		capture = &appDebugCapture{}
+		d.apps[appID] = capture
+	} else {
+		capture.timer.Stop()
+	}
+	capture.expiresAt = time.Now().Add(dur)
+	// the captured calls are dropped as soon as the capture expires, not on the next read
+	capture.timer = time.AfterFunc(dur, func() { d.expire(appID, capture) })
+	return capture.expiresAt
+}
+
+// stop drops the capture of appID and its calls, returning false if there was none
+func (d *debugCaptures) stop(appID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	capture, ok := d.apps[appID]
+	if ok {
+		capture.timer.Stop()
+		delete(d.apps, appID)
+	}
+	return ok
+}
+
+func (d *debugCaptures) expire(appID string, capture *appDebugCapture) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// unless the capture was stopped, or restarted, since
+	if d.apps[appID] == capture && !time.Now().Before(capture.expiresAt) {
+		delete(d.apps, appID)
+	}
+}
+
+// get returns a copy of the capture of appID, or nil if there is none
+func (d *debugCaptures) get(appID string) *debugCaptureResponse {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	capture, ok := d.apps[appID]
+	if !ok || !time.Now().Before(capture.expiresAt) {
+		return nil
+	}
+	return &debugCaptureResponse{
+		AppID:     appID,
+		ExpiresAt: capture.expiresAt,
+		Calls:     append([]*DebugCapturedCall(nil), capture.calls...),
+	}
+}
+
+// begin returns the capture of a call to appID, or nil if its calls aren't
+// captured, teeing the request body into it as the function reads it
+func (d *debugCaptures) begin(appID string, req *http.Request) *pendingDebugCapture {
+	if d == nil || d.get(appID) == nil {
+		return nil
+	}
+	p := &pendingDebugCapture{
+		captures: d,
+		appID:    appID,
+		call: &DebugCapturedCall{
+			StartedAt:      time.Now(),
+			Method:         req.Method,
+			URL:            req.URL.String(),
+			RequestHeaders: d.redacted(req.Header),
+		},
+		reqBody: &cappedBuffer{max: d.maxBody},
+	}
+	if req.Body != nil {
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.TeeReader(req.Body, p.reqBody), req.Body}
+	}
+	return p
+}
+
+func (d *debugCaptures) add(appID string, call *DebugCapturedCall) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// the capture may have expired while the call ran
+	capture, ok := d.apps[appID]
+	if !ok || !time.Now().Before(capture.expiresAt) {
+		return
+	}
+	if len(capture.calls) >= maxDebugCaptureCalls {
+		capture.calls = capture.calls[1:]
+	}
+	capture.calls = append(capture.calls, call)
+}
+
+// redacted returns a copy of h with the values of the headers to redact
+// replaced, including those of trigger calls, which are prefixed
+func (d *debugCaptures) redacted(h http.Header) http.Header {
+	redacted := h.Clone()
+	for k, vs := range redacted {
+		if d.redact[strings.TrimPrefix(http.CanonicalHeaderKey(k), "Fn-Http-H-")] {
+			for i := range vs {
+				vs[i] = redactedHeaderValue
+			}
+		}
+	}
+	return redacted
+}
+
+// pendingDebugCapture is the capture of a call in progress. Its methods are
+// no-ops on a nil capture, for calls that aren't captured.
+type pendingDebugCapture struct {
+	captures *debugCaptures
+	appID    string
+	call     *DebugCapturedCall
+	reqBody  *cappedBuffer
+}
+
+func (p *pendingDebugCapture) setCallID(id string) {
+	if p != nil {
+		p.call.CallID = id
+	}
+}
+
+// setResponse captures the response of the call, before it is written out
+func (p *pendingDebugCapture) setResponse(status int, h http.Header, body []byte) {
+	if p == nil {
+		return
+	}
+	p.call.Status = status
+	p.call.ResponseHeaders = p.captures.redacted(h)
+	if len(body) > p.captures.maxBody {
+		body = body[:p.captures.maxBody]
+		p.call.ResponseBodyTruncated = true
+	}
+	p.call.ResponseBody = string(body)
+}
+
+// finish adds the call to the capture of its app, with its error if it failed
+func (p *pendingDebugCapture) finish(err error) {
+	if p == nil {
+		return
+	}
+	p.call.RequestBody = p.reqBody.String()
+	p.call.RequestBodyTruncated = p.reqBody.truncated
+	if err != nil {
+		p.call.Status = models.GetAPIErrorCode(err)
+		p.call.Error = err.Error()
+	}
+	p.captures.add(p.appID, p.call)
+}
+
+// cappedBuffer keeps the first max bytes written to it, discarding the rest
+type cappedBuffer struct {
+	bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.max - b.Len(); len(p) > remaining {
+		b.truncated = true
+		if remaining > 0 {
+			b.Buffer.Write(p[:remaining])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+// handleDebugCaptureStart starts capturing the calls of an app on this node,
+// for the duration requested, 5m by default
+func (s *Server) handleDebugCaptureStart(c *gin.Context) {
+	var body debugCaptureRequest
+	if err := c.BindJSON(&body); err != nil {
+		handleErrorResponse(c, models.ErrInvalidJSON)
+		return
+	}
+
+	dur := defaultDebugCaptureDuration
+	if body.Duration != "" {
+		var err error
+		if dur, err = time.ParseDuration(body.Duration); err != nil {
+			handleErrorResponse(c, models.ErrInvalidDebugCapture)
+			return
+		}
+	}
+	if body.AppID == "" || dur <= 0 || dur > maxDebugCaptureDuration {
+		handleErrorResponse(c, models.ErrInvalidDebugCapture)
+		return
+	}
+
+	expiresAt := s.debugCaptures.start(body.AppID, dur)
+	common.Logger(c.Request.Context()).WithFields(logrus.Fields{"app_id": body.AppID, "expires_at": expiresAt}).
+		Warn("Capturing the calls of an app for debugging, bodies included")
+	c.JSON(http.StatusOK, debugCaptureResponse{AppID: body.AppID, ExpiresAt: expiresAt})
+}
+
+// handleDebugCaptureGet returns the calls captured for an app on this node
+func (s *Server) handleDebugCaptureGet(c *gin.Context) {
+	capture := s.debugCaptures.get(c.Param(api.AppID))
+	if capture == nil {
+		handleErrorResponse(c, models.ErrDebugCaptureNotActive)
+		return
+	}
+	c.JSON(http.StatusOK, capture)
+}
+
+// handleDebugCaptureStop stops capturing the calls of an app on this node,
+// dropping those captured
+func (s *Server) handleDebugCaptureStop(c *gin.Context) {
+	appID := c.Param(api.AppID)
+	if !s.debugCaptures.stop(appID) {
+		handleErrorResponse(c, models.ErrDebugCaptureNotActive)
+		return
+	}
+	common.Logger(c.Request.Context()).WithField("app_id", appID).Info("Stopped capturing the calls of an app")
+	c.Status(http.StatusNoContent)
+}
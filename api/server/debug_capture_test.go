@@ -0,0 +1,204 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDebugCaptureCalls(t *testing.T) {
+	captures := newDebugCaptures(5, []string{"x-api-key"})
+
+	req := createRequest(t, http.MethodPost, "/invoke/fn1", bytes.NewBufferString("hello world"))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Api-Key", "secret")
+	req.Header.Set("Content-Type", "text/plain")
+
+	// not captured until started
+	if p := captures.begin("app1", req); p != nil {
+		t.Fatal("expected calls not to be captured by default")
+	}
+
+	captures.start("app1", time.Minute)
+	p := captures.begin("app1", req)
+	p.setCallID("call1")
+	if _, err := ioutil.ReadAll(req.Body); err != nil {
+		t.Fatal(err)
+	}
+	p.setResponse(http.StatusOK, http.Header{"Set-Cookie": {"session=1"}}, []byte("hi"))
+	p.finish(nil)
+
+	p = captures.begin("app1", createRequest(t, http.MethodGet, "/invoke/fn1", nil))
+	p.finish(models.ErrCallTimeout)
+
+	capture := captures.get("app1")
+	if capture == nil || len(capture.Calls) != 2 {
+		t.Fatalf("expected 2 captured calls, got %+v", capture)
+	}
+	call := capture.Calls[0]
+	if call.CallID != "call1" || call.RequestBody != "hello" || !call.RequestBodyTruncated || call.ResponseBody != "hi" || call.Status != http.StatusOK {
+		t.Errorf("unexpected captured call %+v", call)
+	}
+	if call.RequestHeaders.Get("Authorization") != redactedHeaderValue || call.RequestHeaders.Get("X-Api-Key") != redactedHeaderValue ||
+		call.RequestHeaders.Get("Content-Type") != "text/plain" || call.ResponseHeaders.Get("Set-Cookie") != redactedHeaderValue {
+		t.Errorf("expected sensitive headers to be redacted, got %v %v", call.RequestHeaders, call.ResponseHeaders)
+	}
+	// the request itself keeps its headers
+	if req.Header.Get("Authorization") != "Bearer secret" {
+		t.Errorf("expected the request headers to be left as they are, got %v", req.Header)
+	}
+	if call := capture.Calls[1]; call.Status != http.StatusGatewayTimeout || call.Error != models.ErrCallTimeout.Error() {
+		t.Errorf("expected the failed call to be captured with its error, got %+v", call)
+	}
+
+	// expired captures are dropped, calls included
+	captures.start("app1", time.Millisecond)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		captures.mu.Lock()
+		_, ok := captures.apps["app1"]
+		captures.mu.Unlock()
+		if !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the expired capture to be dropped")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if captures.begin("app1", req) != nil {
+		t.Fatal("expected calls not to be captured once the capture expired")
+	}
+
+	var nilCapture *pendingDebugCapture
+	nilCapture.setCallID("call1")
+	nilCapture.setResponse(http.StatusOK, nil, nil)
+	nilCapture.finish(errors.New("boom"))
+}
+
+func TestDebugCaptureTriggerCalls(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}}
+	trigger := &models.Trigger{ID: "trigger_id", Name: "trigger", AppID: app.ID, FnID: fn.ID, Type: models.TriggerTypeHTTP, Source: "/src"}
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn}, []*models.Trigger{trigger})
+
+	rnr := new(agent.MockAgent)
+	rnr.On("AddCallListener", mock.Anything)
+	rnr.On("GetCall", mock.Anything)
+	rnr.On("Submit", mock.Anything).Return(nil)
+
+	srv := testServer(ds, rnr, ServerTypeFull, WithDebugCapture(0, []string{"X-Api-Key"}))
+	srv.debugCaptures.start(app.ID, time.Minute)
+
+	// the headers of trigger calls are prefixed by the time they are captured
+	req := createRequest(t, http.MethodPost, "/t/myapp/src", bytes.NewBufferString("hello"))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Cookie", "session=1")
+	req.Header.Set("X-Api-Key", "secret")
+	req.Header.Set("X-Other", "kept")
+	if _, rec := routerRequest2(t, srv.Router, req); rec.Code != http.StatusOK {
+		t.Fatalf("expected status code 200 but was %d: %s", rec.Code, rec.Body.String())
+	}
+
+	capture := srv.debugCaptures.get(app.ID)
+	if capture == nil || len(capture.Calls) != 1 {
+		t.Fatalf("expected 1 captured call, got %+v", capture)
+	}
+	h := capture.Calls[0].RequestHeaders
+	for _, k := range []string{"Fn-Http-H-Authorization", "Fn-Http-H-Cookie", "Fn-Http-H-X-Api-Key"} {
+		if h.Get(k) != redactedHeaderValue {
+			t.Errorf("expected %s to be redacted, got %v", k, h)
+		}
+	}
+	if h.Get("Fn-Http-H-X-Other") != "kept" {
+		t.Errorf("expected other headers to be captured, got %v", h)
+	}
+
+	redacted := srv.debugCaptures.redacted(http.Header{"Fn-Http-H-Set-Cookie": {"session=1"}})
+	if redacted.Get("Fn-Http-H-Set-Cookie") != redactedHeaderValue {
+		t.Errorf("expected the prefixed response cookie to be redacted, got %v", redacted)
+	}
+}
+
+func TestDebugCaptureEndpoints(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ag := &cancelerAgent{}
+	ag.On("AddCallListener", mock.Anything)
+
+	// not served without an admin token
+	srv := testServer(datastore.NewMock(), ag, ServerTypeFull)
+	if _, rec := routerRequest(t, srv.AdminRouter, http.MethodPost, "/debug/capture", bytes.NewBufferString(`{"app_id":"app1"}`)); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status code 404 but was %d: %s", rec.Code, rec.Body.String())
+	}
+
+	srv = testServer(datastore.NewMock(), ag, ServerTypeFull, WithAdminToken("s3cret"))
+	for i, test := range []struct {
+		method       string
+		path         string
+		body         string
+		token        string
+		expectedCode int
+		expectedBody string
+	}{
+		{http.MethodPost, "/debug/capture", `{"app_id":"app1"}`, "", http.StatusUnauthorized, models.ErrorCodeAdminTokenInvalid},
+		{http.MethodGet, "/debug/capture/app1", "", "wrong", http.StatusUnauthorized, models.ErrorCodeAdminTokenInvalid},
+		{http.MethodGet, "/debug/capture/app1", "", "s3cret", http.StatusNotFound, models.ErrorCodeDebugCaptureNotActive},
+		{http.MethodPost, "/debug/capture", `{"duration":"1m"}`, "s3cret", http.StatusBadRequest, models.ErrorCodeInvalidDebugCapture},
+		{http.MethodPost, "/debug/capture", `{"app_id":"app1","duration":"2h"}`, "s3cret", http.StatusBadRequest, models.ErrorCodeInvalidDebugCapture},
+		{http.MethodPost, "/debug/capture", `{"app_id":"app1","duration":"soon"}`, "s3cret", http.StatusBadRequest, models.ErrorCodeInvalidDebugCapture},
+		{http.MethodPost, "/debug/capture", `{"app_id":"app1","duration":"10m"}`, "s3cret", http.StatusOK, `"app_id":"app1"`},
+		{http.MethodGet, "/debug/capture/app1", "", "s3cret", http.StatusOK, `"expires_at"`},
+		{http.MethodDelete, "/debug/capture/app1", "", "s3cret", http.StatusNoContent, ""},
+		{http.MethodGet, "/debug/capture/app1", "", "s3cret", http.StatusNotFound, models.ErrorCodeDebugCaptureNotActive},
+	} {
+		var body io.Reader
+		if test.body != "" {
+			body = bytes.NewBufferString(test.body)
+		}
+		req := createRequest(t, test.method, test.path, body)
+		if test.token != "" {
+			req.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		_, rec := routerRequest2(t, srv.AdminRouter, req)
+
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+		if body := rec.Body.String(); !strings.Contains(body, test.expectedBody) {
+			t.Errorf("Test %d: expected body to contain %s, got %s", i, test.expectedBody, body)
+		}
+		if test.method == http.MethodPost && rec.Code == http.StatusOK {
+			var resp debugCaptureResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if d := time.Until(resp.ExpiresAt); d <= 9*time.Minute || d > 10*time.Minute {
+				t.Errorf("Test %d: expected the capture to expire in 10m, got %v", i, resp.ExpiresAt)
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/sirupsen/logrus"
+)
+
+// dependencyBackOff spaces the attempts to connect to a dependency on startup
+var dependencyBackOff = common.BackOffConfig{
+	MaxRetries: common.RetryForever,
+	Interval:   500,
+	MinDelay:   500,
+	MaxDelay:   10000,
+}
+
+// WithDependencyWaitTimeout retries connecting to the dependencies of the
+// server on startup, for up to timeout, so that it does not crash loop when
+// they start slower than it does, such as the db in a compose file. A timeout
+// of 0 fails on the first error. It must be set before WithDBURL.
+func WithDependencyWaitTimeout(timeout time.Duration) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.dependencyWait = timeout
+		return nil
+	}
+}
+
+// waitForDependency calls connect until it succeeds, backing off in between,
+// or returns its last error once the dependency wait timeout is up
+func (s *Server) waitForDependency(ctx context.Context, name string, connect func() error) error {
+	deadline := time.Now().Add(s.dependencyWait)
+	backoff := common.NewBackOff(dependencyBackOff)
+	log := logrus.WithField("dependency", name)
+
+	for attempt := 1; ; attempt++ {
+		err := connect()
+		if err == nil || s.dependencyWait <= 0 {
+			return err
+		}
+
+		delay, _ := backoff.NextBackOff()
+		if time.Now().Add(delay).After(deadline) {
+			log.WithError(err).WithField("attempt", attempt).Error("Gave up waiting for dependency")
+			return err
+		}
+		log.WithError(err).WithFields(logrus.Fields{"attempt": attempt, "retry_in": delay}).Warn("Failed to connect to dependency, retrying")
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
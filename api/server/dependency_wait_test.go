@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+func TestWaitForDependency(t *testing.T) {
+	defer func(cfg common.BackOffConfig) { dependencyBackOff = cfg }(dependencyBackOff)
+	dependencyBackOff = common.BackOffConfig{MaxRetries: common.RetryForever, Interval: 1, MinDelay: 1, MaxDelay: 5}
+
+	errDown := errors.New("connection refused")
+	for i, test := range []struct {
+		timeout          time.Duration
+		failures         int
+		expectedAttempts int
+		expectErr        bool
+	}{
+		// fails fast by default
+		{0, 2, 1, true},
+		{0, 0, 1, false},
+		{time.Minute, 2, 3, false},
+		{20 * time.Millisecond, 1000000, 0, true},
+	} {
+		s := &Server{dependencyWait: test.timeout}
+		attempts := 0
+		err := s.waitForDependency(context.Background(), "db", func() error {
+			attempts++
+			if attempts <= test.failures {
+				return errDown
+			}
+			return nil
+		})
+
+		if (err != nil) != test.expectErr {
+			t.Errorf("Test %d: expected error %v, got %v", i, test.expectErr, err)
+		}
+		if err != nil && err != errDown {
+			t.Errorf("Test %d: expected the last error of the dependency, got %v", i, err)
+		}
+		if test.expectedAttempts > 0 && attempts != test.expectedAttempts {
+			t.Errorf("Test %d: expected %d attempts, got %d", i, test.expectedAttempts, attempts)
+		}
+	}
+}
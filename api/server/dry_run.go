@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// dryRunParam is the query parameter asking a create handler to validate a
+// resource and return it without persisting it.
+const dryRunParam = "dry_run"
+
+func isDryRun(c *gin.Context) bool {
+	dryRun, _ := strconv.ParseBool(c.Query(dryRunParam))
+	return dryRun
+}
+
+// The dryRunInsert functions make the same checks as the datastore inserts,
+// those on the arguments with the validator of the datastore, and those of the
+// stores themselves using only reads, as well as the per app limits, and
+// return the object the insert would have created, less its ID and
+// timestamps. Listeners are not fired.
+
+func (s *Server) dryRunInsertApp(ctx context.Context, app *models.App) (*models.App, error) {
+	if err := datastore.ValidateInsertApp(app); err != nil {
+		return nil, err
+	}
+
+	_, err := s.datastore.GetAppID(ctx, app.Name)
+	if err == nil {
+		return nil, models.ErrAppsAlreadyExists
+	}
+	if err != models.ErrAppsNotFound {
+		return nil, err
+	}
+
+	app = app.Clone()
+	if app.Config == nil {
+		app.Config = map[string]string{}
+	}
+	return app, nil
+}
+
+func (s *Server) dryRunInsertFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+	if err := datastore.ValidateInsertFn(fn); err != nil {
+		return nil, err
+	}
+	if err := fn.Validate(); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.datastore.GetAppByID(ctx, fn.AppID); err != nil {
+		return nil, err
+	}
+
+	fns, err := s.datastore.GetFns(ctx, &models.FnFilter{AppID: fn.AppID, Name: fn.Name, PerPage: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(fns.Items) > 0 {
+		return nil, models.ErrFnsExists
+	}
+
+	release, err := s.reserveFn(ctx, fn.AppID)
+	if err != nil {
+		return nil, err
+	}
+	release()
+
+	return fn.Clone(), nil
+}
+
+func (s *Server) dryRunInsertTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
+	if err := datastore.ValidateInsertTrigger(trigger); err != nil {
+		return nil, err
+	}
+	if err := trigger.Validate(); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.datastore.GetAppByID(ctx, trigger.AppID); err != nil {
+		return nil, err
+	}
+	fn, err := s.datastore.GetFnByID(ctx, trigger.FnID)
+	if err != nil {
+		return nil, err
+	}
+	if fn.AppID != trigger.AppID {
+		return nil, models.ErrTriggerFnIDNotSameApp
+	}
+
+	_, err = s.datastore.GetTriggerBySource(ctx, trigger.AppID, trigger.Type, trigger.Source)
+	if err == nil {
+		return nil, models.ErrTriggerSourceExists
+	}
+	if err != models.ErrTriggerNotFound {
+		return nil, err
+	}
+
+	triggers, err := s.datastore.GetTriggers(ctx, &models.TriggerFilter{AppID: trigger.AppID, FnID: trigger.FnID, Name: trigger.Name, PerPage: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(triggers.Items) > 0 {
+		return nil, models.ErrTriggerExists
+	}
+
+	release, err := s.reserveTrigger(ctx, trigger.AppID)
+	if err != nil {
+		return nil, err
+	}
+	release()
+
+	return trigger.Clone(), nil
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestCreateDryRun(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	a := &models.App{ID: "appid", Name: "app"}
+	a2 := &models.App{ID: "appid2", Name: "app2"}
+	fn := &models.Fn{ID: "fnid", Name: "fn", AppID: a.ID}
+	fn.SetDefaults()
+	trigger := &models.Trigger{ID: "triggerid", Name: "trigger", AppID: a.ID, FnID: fn.ID, Type: "http", Source: "/src"}
+
+	for i, test := range []struct {
+		path          string
+		body          string
+		expectedCode  int
+		expectedError error
+	}{
+		{"/v2/apps", `{ "name": "newapp" }`, http.StatusOK, nil},
+		{"/v2/apps", `{ "name": "app" }`, http.StatusConflict, models.ErrAppsAlreadyExists},
+		{"/v2/apps", `{ "name": "&&%@!#$#@$" }`, http.StatusBadRequest, models.ErrAppsInvalidName},
+
+		{"/v2/fns", `{ "app_id": "appid", "name": "newfn", "image": "fnproject/fn-test-utils" }`, http.StatusOK, nil},
+		{"/v2/fns", `{ "app_id": "appid", "name": "fn", "image": "fnproject/fn-test-utils" }`, http.StatusConflict, models.ErrFnsExists},
+		{"/v2/fns", `{ "app_id": "missing", "name": "newfn", "image": "fnproject/fn-test-utils" }`, http.StatusNotFound, models.ErrAppsNotFound},
+		{"/v2/fns", `{ "app_id": "appid", "name": "newfn" }`, http.StatusBadRequest, models.ErrFnsMissingImage},
+
+		{"/v2/triggers", `{ "name": "newtrigger", "app_id": "appid", "fn_id": "fnid", "type": "http", "source": "/newsrc" }`, http.StatusOK, nil},
+		{"/v2/triggers", `{ "name": "newtrigger", "app_id": "appid", "fn_id": "fnid", "type": "http", "source": "/src" }`, http.StatusConflict, models.ErrTriggerSourceExists},
+		{"/v2/triggers", `{ "name": "trigger", "app_id": "appid", "fn_id": "fnid", "type": "http", "source": "/newsrc" }`, http.StatusConflict, models.ErrTriggerExists},
+		{"/v2/triggers", `{ "name": "newtrigger", "app_id": "appid2", "fn_id": "fnid", "type": "http", "source": "/newsrc" }`, http.StatusBadRequest, models.ErrTriggerFnIDNotSameApp},
+		{"/v2/triggers", `{ "name": "newtrigger", "app_id": "appid", "fn_id": "fnid", "type": "http", "source": "newsrc" }`, http.StatusBadRequest, models.ErrTriggerMissingSourcePrefix},
+	} {
+		ds := datastore.NewMockInit([]*models.App{a, a2}, []*models.Fn{fn}, []*models.Trigger{trigger})
+		srv := testServer(ds, nil, ServerTypeAPI)
+
+		_, rec := routerRequest(t, srv.Router, http.MethodPost, test.path+"?dry_run=true", bytes.NewBufferString(test.body))
+		if rec.Code != test.expectedCode {
+			t.Fatalf("Test %d: expected status code %d but was %d: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+
+		if test.expectedError != nil {
+			resp := getErrorResponse(t, rec)
+			if !strings.Contains(resp.Message, test.expectedError.Error()) {
+				t.Errorf("Test %d: expected error message to have `%s` but got `%s`", i, test.expectedError, resp.Message)
+			}
+		} else if !strings.Contains(rec.Body.String(), `"name":"new`) {
+			t.Errorf("Test %d: expected dry run to return the resource, got %s", i, rec.Body.String())
+		}
+
+		ctx := context.Background()
+		apps, _ := ds.GetApps(ctx, &models.AppFilter{PerPage: 100})
+		fns, _ := ds.GetFns(ctx, &models.FnFilter{AppID: a.ID, PerPage: 100})
+		triggers, _ := ds.GetTriggers(ctx, &models.TriggerFilter{AppID: a.ID, PerPage: 100})
+		if len(apps.Items) != 2 || len(fns.Items) != 1 || len(triggers.Items) != 1 {
+			t.Errorf("Test %d: expected dry run not to modify the datastore, got %d apps %d fns %d triggers",
+				i, len(apps.Items), len(fns.Items), len(triggers.Items))
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"runtime/debug"
 
@@ -17,12 +18,22 @@ import (
 // ErrInternalServerError returned when something exceptional happens.
 var ErrInternalServerError = errors.New("internal server error")
 
-func simpleError(err error) *models.Error {
-	return &models.Error{Message: err.Error()}
+func simpleError(err error, statuscode int) *models.Error {
+	return &models.Error{Code: models.ErrorCode(err, statuscode), Message: err.Error()}
 }
 
+// ErrorCodeHeader holds the code of an error written as plain text, which has
+// no room for it in the body.
+const ErrorCodeHeader = "Fn-Error-Code"
+
+type plainTextErrorsKey struct{}
+
 func handleErrorResponse(c *gin.Context, err error) {
-	HandleErrorResponse(c.Request.Context(), c.Writer, err)
+	ctx := c.Request.Context()
+	if c.NegotiateFormat(gin.MIMEJSON, gin.MIMEPlain) == gin.MIMEPlain {
+		ctx = context.WithValue(ctx, plainTextErrorsKey{}, true)
+	}
+	HandleErrorResponse(ctx, c.Writer, err)
 }
 
 // HandleErrorResponse used to handle response errors in the same way.
@@ -38,6 +49,12 @@ func HandleErrorResponse(ctx context.Context, w http.ResponseWriter, err error)
 		return
 	}
 
+	if _, ok := err.(models.APIError); !ok && ctx.Err() == context.DeadlineExceeded {
+		// the request ran out of time, see WithAPIRequestTimeout, whatever failed as a result
+		log.WithError(err).Info("request timed out")
+		err = models.ErrAPIRequestTimeout
+	}
+
 	var statuscode int
 	if e, ok := err.(models.APIError); ok {
 		if e.Code() >= 500 {
@@ -61,12 +78,27 @@ func HandleErrorResponse(ctx context.Context, w http.ResponseWriter, err error)
 	WriteError(ctx, w, statuscode, err)
 }
 
-// WriteError easy way to do standard error response, but can set statuscode and error message easier than handleErrorResponse
+// WriteError easy way to do standard error response, but can set statuscode and error message easier than handleErrorResponse.
+// Errors are written as JSON, unless the client only asked for text/plain, in which case the message
+// is written as is and its code is set in the ErrorCodeHeader header.
 func WriteError(ctx context.Context, w http.ResponseWriter, statuscode int, err error) {
 	log := common.Logger(ctx)
+	body := simpleError(err, statuscode)
+	if plain, _ := ctx.Value(plainTextErrorsKey{}).(bool); plain {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if body.Code != "" {
+			w.Header().Set(ErrorCodeHeader, body.Code)
+		}
+		w.WriteHeader(statuscode)
+		if _, err := io.WriteString(w, body.Message+"\n"); err != nil {
+			log.WithError(err).Errorln("error writing error text")
+		}
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(statuscode)
-	err = json.NewEncoder(w).Encode(simpleError(err))
+	err = json.NewEncoder(w).Encode(body)
 	if err != nil {
 		log.WithError(err).Errorln("error encoding error json")
 	}
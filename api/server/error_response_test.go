@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestErrorResponseContentNegotiation(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	srv := testServer(datastore.NewMock(), nil, ServerTypeAPI)
+
+	for i, test := range []struct {
+		accept      string
+		contentType string
+		body        string
+		codeHeader  string
+	}{
+		{"", "application/json", `"message":"` + models.ErrAppsNotFound.Error() + `"`, ""},
+		{"*/*", "application/json", `"code":"` + models.ErrorCodeAppNotFound + `"`, ""},
+		{"application/json, text/plain", "application/json", `"message":"` + models.ErrAppsNotFound.Error() + `"`, ""},
+		{"text/plain", "text/plain", models.ErrAppsNotFound.Error() + "\n", models.ErrorCodeAppNotFound},
+		{"text/plain;q=0.9, application/json;q=0.8", "text/plain", models.ErrAppsNotFound.Error() + "\n", models.ErrorCodeAppNotFound},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/v2/apps/missing", nil)
+		if test.accept != "" {
+			req.Header.Set("Accept", test.accept)
+		}
+		rec := httptest.NewRecorder()
+		srv.Router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Test %d: expected status code 404 but was %d", i, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, test.contentType) {
+			t.Errorf("Test %d: expected content type %s but was %s", i, test.contentType, ct)
+		}
+		if !strings.Contains(rec.Body.String(), test.body) {
+			t.Errorf("Test %d: expected body to contain %q but was %q", i, test.body, rec.Body.String())
+		}
+		if code := rec.Header().Get(ErrorCodeHeader); code != test.codeHeader {
+			t.Errorf("Test %d: expected %s header %q but was %q", i, ErrorCodeHeader, test.codeHeader, code)
+		}
+	}
+}
@@ -15,7 +15,7 @@ func (s *Server) apiHandlerWrapperFn(apiHandler fnext.APIHandler) gin.HandlerFun
 
 // AddEndpoint adds an endpoint to /v2/x
 func (s *Server) AddEndpoint(method, path string, handler fnext.APIHandler) {
-	v2 := s.Router.Group("/v2")
+	v2 := s.Router.Group(s.basePath + "/v2")
 	v2.Handle(method, path, s.apiHandlerWrapperFn(handler))
 }
 
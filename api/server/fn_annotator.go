@@ -2,6 +2,7 @@ package server
 
 import (
 	"fmt"
+	"net"
 	"strings"
 
 	"github.com/fnproject/fn/api/models"
@@ -14,7 +15,9 @@ type FnAnnotator interface {
 	AnnotateFn(ctx *gin.Context, a *models.App, fn *models.Fn) (*models.Fn, error)
 }
 
-type requestBasedFnAnnotator struct{}
+type requestBasedFnAnnotator struct {
+	trustedProxies []*net.IPNet
+}
 
 func annotateFnWithBaseURL(baseURL string, app *models.App, fn *models.Fn) (*models.Fn, error) {
 
@@ -32,19 +35,14 @@ func annotateFnWithBaseURL(baseURL string, app *models.App, fn *models.Fn) (*mod
 }
 
 func (tp *requestBasedFnAnnotator) AnnotateFn(ctx *gin.Context, app *models.App, t *models.Fn) (*models.Fn, error) {
-
-	//No, I don't feel good about myself either
-	scheme := "http"
-	if ctx.Request.TLS != nil {
-		scheme = "https"
-	}
-
-	return annotateFnWithBaseURL(fmt.Sprintf("%s://%s", scheme, ctx.Request.Host), app, t)
+	return annotateFnWithBaseURL(requestBaseURL(ctx.Request, tp.trustedProxies)+ctx.GetString(basePathKey), app, t)
 }
 
 //NewRequestBasedFnAnnotator creates a FnAnnotator that inspects the incoming request host and port, and uses this to generate fn invoke endpoint URLs based on those
-func NewRequestBasedFnAnnotator() FnAnnotator {
-	return &requestBasedFnAnnotator{}
+//
+//X-Forwarded-Proto, X-Forwarded-Host and X-Forwarded-Port headers are used instead when the request comes from one of trustedProxies
+func NewRequestBasedFnAnnotator(trustedProxies ...*net.IPNet) FnAnnotator {
+	return &requestBasedFnAnnotator{trustedProxies: trustedProxies}
 }
 
 type staticURLFnAnnotator struct {
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"sync"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// otherFnsLabel is the fn_id label of the invocations of the fns past the
+// cardinality cap of WithPerFunctionMetrics
+const otherFnsLabel = "__other__"
+
+var (
+	fnResultKey = common.MakeKey("result")
+
+	fnInvocationsMeasure = common.MakeMeasure("server/fn_invocations", "Number of invocations of each fn, see WithPerFunctionMetrics", stats.UnitDimensionless)
+)
+
+// RegisterFunctionViews registers the views for the per fn invocation counts
+// of WithPerFunctionMetrics
+func RegisterFunctionViews(tagKeys []string) {
+	tags := []tag.Key{agent.FnIDMetricKey, fnResultKey}
+	for _, key := range tagKeys {
+		if key != agent.FnIDMetricKey.Name() && key != fnResultKey.Name() {
+			tags = append(tags, common.MakeKey(key))
+		}
+	}
+
+	err := view.Register(common.CreateViewWithTags(fnInvocationsMeasure, view.Count(), tags))
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot register view")
+	}
+}
+
+// WithPerFunctionMetrics counts the invocations of each fn, by fn_id and
+// result, success or error, for up to maxCardinality fns. Each fn is a time
+// series of its own, which prometheus keeps in memory and every query over
+// them scans, so the first maxCardinality fns invoked since the node started
+// get their own fn_id label, and the invocations of any other fn are counted
+// under fn_id __other__. Which fns get a label then varies across nodes and
+// restarts: sum by fn_id across nodes, and set the cap above the number of
+// fns of the cluster for exact counts. 0 disables the counts.
+func WithPerFunctionMetrics(maxCardinality int) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.fnMetrics = nil
+		if maxCardinality > 0 {
+			s.fnMetrics = newFnMetricLabels(maxCardinality)
+		}
+		return nil
+	}
+}
+
+// fnMetricLabels hands out fn_id labels to the first max fns
+type fnMetricLabels struct {
+	max int
+
+	mu  sync.RWMutex
+	fns map[string]struct{}
+}
+
+func newFnMetricLabels(max int) *fnMetricLabels {
+	return &fnMetricLabels{max: max, fns: make(map[string]struct{})}
+}
+
+// label returns the fn_id label of fnID, otherFnsLabel once max other fns
+// have a label
+func (l *fnMetricLabels) label(fnID string) string {
+	l.mu.RLock()
+	_, ok := l.fns[fnID]
+	l.mu.RUnlock()
+	if ok {
+		return fnID
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.fns[fnID]; ok {
+		return fnID
+	}
+	if len(l.fns) >= l.max {
+		return otherFnsLabel
+	}
+	l.fns[fnID] = struct{}{}
+	return fnID
+}
+
+// recordFnInvocation counts an invocation of fnID, if per fn metrics are on
+func (s *Server) recordFnInvocation(ctx context.Context, fnID string, err error) {
+	if s.fnMetrics == nil {
+		return
+	}
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	ctx, err = tag.New(ctx, tag.Upsert(agent.FnIDMetricKey, s.fnMetrics.label(fnID)), tag.Upsert(fnResultKey, result))
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	stats.Record(ctx, fnInvocationsMeasure.M(1))
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opencensus.io/stats/view"
+)
+
+func TestPerFunctionMetrics(t *testing.T) {
+	RegisterFunctionViews(nil)
+	defer view.Unregister(view.Find("server/fn_invocations"))
+
+	s := &Server{}
+	if err := WithPerFunctionMetrics(2)(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	s.recordFnInvocation(ctx, "fn1", nil)
+	s.recordFnInvocation(ctx, "fn2", errors.New("boom"))
+	s.recordFnInvocation(ctx, "fn3", nil)
+	s.recordFnInvocation(ctx, "fn1", nil)
+	s.recordFnInvocation(ctx, "fn4", nil)
+
+	expected := map[string]int64{
+		"fn1/success":       2,
+		"fn2/error":         1,
+		"__other__/success": 2,
+	}
+	// views are aggregated in the background
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		counts := fnInvocationCounts(t)
+		if len(counts) == len(expected) {
+			ok := true
+			for k, v := range expected {
+				ok = ok && counts[k] == v
+			}
+			if ok {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected invocation counts %v, got %v", expected, counts)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// disabled, nothing is recorded
+	s = &Server{}
+	if err := WithPerFunctionMetrics(0)(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+	s.recordFnInvocation(ctx, "fn5", nil)
+	if _, ok := fnInvocationCounts(t)["fn5/success"]; ok {
+		t.Fatal("expected no invocation counts with per function metrics disabled")
+	}
+}
+
+func fnInvocationCounts(t *testing.T) map[string]int64 {
+	rows, err := view.RetrieveData("server/fn_invocations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]int64)
+	for _, row := range rows {
+		var fnID, result string
+		for _, tag := range row.Tags {
+			switch tag.Key.Name() {
+			case "fn_id":
+				fnID = tag.Value
+			case "result":
+				result = tag.Value
+			}
+		}
+		counts[fnID+"/"+result] = row.Data.(*view.CountData).Value
+	}
+	return counts
+}
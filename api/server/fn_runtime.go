@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+type fnRuntimeResponse struct {
+	FnID     string `json:"fn_id"`
+	NodeType string `json:"node_type"`
+	agent.FnRuntimeStats
+}
+
+// handleFnRuntime returns the live stats of the hot containers and the calls
+// of a fn on this node, e.g. to tell whether it has warm containers before
+// sending it traffic. Only full nodes run containers, so the stats are those
+// of this node alone; API nodes answer with a 501, and LB nodes don't serve
+// the /v2 API.
+func (s *Server) handleFnRuntime(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	stater, ok := s.agent.(agent.FnRuntimeStater)
+	if !ok {
+		handleErrorResponse(c, models.ErrFnRuntimeUnsupported)
+		return
+	}
+
+	fn, err := s.datastore.GetFnByID(ctx, c.Param(api.FnID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, fnRuntimeResponse{
+		FnID:           fn.ID,
+		NodeType:       s.nodeType.String(),
+		FnRuntimeStats: stater.FnRuntimeStats(fn.ID),
+	})
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type runtimeStaterAgent struct {
+	agent.MockAgent
+	stats map[string]agent.FnRuntimeStats
+}
+
+func (a *runtimeStaterAgent) FnRuntimeStats(fnID string) agent.FnRuntimeStats {
+	return a.stats[fnID]
+}
+
+func TestFnRuntime(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils"}
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn})
+
+	ag := &runtimeStaterAgent{stats: map[string]agent.FnRuntimeStats{
+		"fn_id": {WarmContainers: 2, BusyContainers: 1, InFlight: 1, ColdStartsPerMinute: 0.6, ColdStartWindow: "5m0s"},
+	}}
+	ag.On("AddCallListener", mock.Anything)
+
+	// not served without an admin token
+	srv := testServer(ds, ag, ServerTypeFull)
+	if _, rec := routerRequest(t, srv.Router, http.MethodGet, "/v2/fns/fn_id/runtime", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status code 404 but was %d: %s", rec.Code, rec.Body.String())
+	}
+
+	srv = testServer(ds, ag, ServerTypeFull, WithAdminToken("s3cret"))
+	for i, test := range []struct {
+		path         string
+		token        string
+		expectedCode int
+		expectedBody string
+	}{
+		{"/v2/fns/fn_id/runtime", "", http.StatusUnauthorized, models.ErrorCodeAdminTokenInvalid},
+		{"/v2/fns/fn_id/runtime", "wrong", http.StatusUnauthorized, models.ErrorCodeAdminTokenInvalid},
+		{"/v2/fns/fn_id/runtime", "s3cret", http.StatusOK,
+			`{"fn_id":"fn_id","node_type":"full","warm_containers":2,"busy_containers":1,"starting_containers":0,"in_flight":1,"queued":0,"cold_starts_per_minute":0.6,"cold_start_window":"5m0s"}`},
+		{"/v2/fns/missing/runtime", "s3cret", http.StatusNotFound, models.ErrFnsNotFound.Error()},
+	} {
+		req := createRequest(t, http.MethodGet, test.path, nil)
+		if test.token != "" {
+			req.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		_, rec := routerRequest2(t, srv.Router, req)
+
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+		if body := rec.Body.String(); !strings.Contains(body, test.expectedBody) {
+			t.Errorf("Test %d: expected body to contain %s, got %s", i, test.expectedBody, body)
+		}
+	}
+
+	// agents without containers don't report them
+	mockAgent := new(agent.MockAgent)
+	mockAgent.On("AddCallListener", mock.Anything)
+	srv = testServer(ds, mockAgent, ServerTypeFull, WithAdminToken("s3cret"))
+	req := createRequest(t, http.MethodGet, "/v2/fns/fn_id/runtime", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	if _, rec := routerRequest2(t, srv.Router, req); rec.Code != http.StatusNotImplemented {
+		t.Errorf("expected status code 501 but was %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -13,7 +13,7 @@ func (s *Server) handleFnCreate(c *gin.Context) {
 	log := common.Logger(ctx)
 
 	fn := &models.Fn{}
-	err := c.BindJSON(fn)
+	err := s.bindJSON(c, fn)
 	if err != nil {
 		if !models.IsAPIError(err) {
 			err = models.ErrInvalidJSON
@@ -22,8 +22,42 @@ func (s *Server) handleFnCreate(c *gin.Context) {
 		return
 	}
 
+	if err := s.checkAnnotations(c, fn.Annotations); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	// resolve app defaults at create time so the stored fn is explicit; if the
+	// app can't be found here, InsertFn will report it.
+	if fn.AppID != "" {
+		if app, err := s.datastore.GetAppByID(ctx, fn.AppID); err == nil {
+			fn.SetAppDefaults(app)
+		}
+	}
 	fn.SetDefaults()
+
+	if err := s.checkConfig(fn.Config); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	if isDryRun(c) {
+		fnValid, err := s.dryRunInsertFn(ctx, fn)
+		if err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, fnValid)
+		return
+	}
+
+	release, err := s.reserveFn(ctx, fn.AppID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
 	fnCreated, err := s.datastore.InsertFn(ctx, fn)
+	release()
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
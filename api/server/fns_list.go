@@ -2,7 +2,6 @@ package server
 
 import (
 	"fmt"
-	"net/http"
 
 	"github.com/fnproject/fn/api/models"
 	"github.com/gin-gonic/gin"
@@ -16,6 +15,12 @@ func (s *Server) handleFnList(c *gin.Context) {
 	filter.AppID = c.Query("app_id")
 	filter.Name = c.Query("name")
 
+	// fns are listed per app, which tenant scoping checks
+	if filter.AppID == "" {
+		handleErrorResponse(c, models.ErrFnsMissingAppID)
+		return
+	}
+
 	fns, err := s.datastore.GetFns(ctx, &filter)
 	if err != nil {
 		handleErrorResponse(c, err)
@@ -47,5 +52,5 @@ func (s *Server) handleFnList(c *gin.Context) {
 		fns.Items[idx] = newF
 	}
 
-	c.JSON(http.StatusOK, fns)
+	writeListResponse(c, fns.NextCursor, len(fns.Items), func(i int) interface{} { return fns.Items[i] })
 }
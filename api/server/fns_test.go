@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -10,9 +11,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/agent/drivers"
 	"github.com/fnproject/fn/api/datastore"
 	"github.com/fnproject/fn/api/id"
 	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/fnext"
 )
 
 type funcTestCase struct {
@@ -110,6 +114,110 @@ func TestFnCreate(t *testing.T) {
 	}
 }
 
+func TestFnCreateAppDefaults(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	a := &models.App{Name: "a", ID: "aid"}
+	a.Annotations, _ = a.Annotations.With(models.AppDefaultMemoryAnnotation, 256)
+	a.Annotations, _ = a.Annotations.With(models.AppDefaultTimeoutAnnotation, 60)
+	plain := &models.App{Name: "b", ID: "bid"}
+
+	for i, test := range []struct {
+		body        string
+		wantMemory  uint64
+		wantTimeout int32
+	}{
+		{fmt.Sprintf(`{ "app_id": "%s", "name": "explicit", "image": "fnproject/fn-test-utils", "memory": 512, "timeout": 10 }`, a.ID), 512, 10},
+		{fmt.Sprintf(`{ "app_id": "%s", "name": "inherited", "image": "fnproject/fn-test-utils" }`, a.ID), 256, 60},
+		{fmt.Sprintf(`{ "app_id": "%s", "name": "system", "image": "fnproject/fn-test-utils" }`, plain.ID), models.DefaultMemory, models.DefaultTimeout},
+	} {
+		ds := datastore.NewMockInit([]*models.App{a, plain})
+		srv := testServer(ds, nil, ServerTypeAPI)
+
+		_, rec := routerRequest(t, srv.Router, http.MethodPost, "/v2/fns", bytes.NewBufferString(test.body))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Test %d: expected status code 200 but was %d: %s", i, rec.Code, rec.Body.String())
+		}
+
+		var fn models.Fn
+		if err := json.NewDecoder(rec.Body).Decode(&fn); err != nil {
+			t.Fatalf("Test %d: error decoding fn: %v", i, err)
+		}
+		if fn.Memory != test.wantMemory || fn.Timeout != test.wantTimeout {
+			t.Errorf("Test %d: expected memory=%d timeout=%d, got memory=%d timeout=%d",
+				i, test.wantMemory, test.wantTimeout, fn.Memory, fn.Timeout)
+		}
+	}
+}
+
+type imageValidatorAgent struct {
+	agent.Agent
+}
+
+func (a *imageValidatorAgent) AddCallListener(fnext.CallListener) {}
+
+func (a *imageValidatorAgent) ValidateImage(ctx context.Context, image string) (*drivers.ImageInfo, error) {
+	if image == "fnproject/missing" {
+		return &drivers.ImageInfo{Image: image, Error: "registry returned 404"}, nil
+	}
+	return &drivers.ImageInfo{Image: image, Reachable: true, Digest: "sha256:abc"}, nil
+}
+
+func TestFnValidateImage(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ds := datastore.NewMockInit()
+	for i, test := range []struct {
+		rnr           agent.Agent
+		body          string
+		expectedCode  int
+		expectedError error
+		reachable     bool
+	}{
+		{&imageValidatorAgent{}, `{ "image": "fnproject/fn-test-utils" }`, http.StatusOK, nil, true},
+		{&imageValidatorAgent{}, `{ "image": "fnproject/missing" }`, http.StatusOK, nil, false},
+		{&imageValidatorAgent{}, `{ }`, http.StatusBadRequest, models.ErrFnsMissingImage, false},
+		{&imageValidatorAgent{}, `{ "image": `, http.StatusBadRequest, models.ErrInvalidJSON, false},
+		{nil, `{ "image": "fnproject/fn-test-utils" }`, http.StatusNotImplemented, models.ErrImageValidationUnsupported, false},
+	} {
+		nodeType := ServerTypeFull
+		if test.rnr == nil {
+			nodeType = ServerTypeAPI
+		}
+		srv := testServer(ds, test.rnr, nodeType)
+		_, rec := routerRequest(t, srv.Router, http.MethodPost, "/v2/fns/validate-image", bytes.NewBufferString(test.body))
+		if rec.Code != test.expectedCode {
+			t.Fatalf("Test %d: expected status code %d but was %d: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+
+		if test.expectedError != nil {
+			resp := getErrorResponse(t, rec)
+			if resp == nil || resp.Message != test.expectedError.Error() {
+				t.Errorf("Test %d: expected error `%s`, got %s", i, test.expectedError, rec.Body.String())
+			}
+			continue
+		}
+
+		var info drivers.ImageInfo
+		if err := json.NewDecoder(rec.Body).Decode(&info); err != nil {
+			t.Fatalf("Test %d: error decoding image info: %v", i, err)
+		}
+		if info.Reachable != test.reachable {
+			t.Errorf("Test %d: expected reachable=%v, got %+v", i, test.reachable, info)
+		}
+	}
+}
+
 func TestFnUpdate(t *testing.T) {
 	buf := setLogBuffer()
 
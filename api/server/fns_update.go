@@ -12,7 +12,7 @@ func (s *Server) handleFnUpdate(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	fn := &models.Fn{}
-	err := c.BindJSON(fn)
+	err := s.bindJSON(c, fn)
 	if err != nil {
 		if !models.IsAPIError(err) {
 			err = models.ErrInvalidJSON
@@ -21,6 +21,11 @@ func (s *Server) handleFnUpdate(c *gin.Context) {
 		return
 	}
 
+	if err := s.checkAnnotations(c, fn.Annotations); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
 	pathFnID := c.Param(api.FnID)
 
 	if fn.ID == "" {
@@ -31,6 +36,16 @@ func (s *Server) handleFnUpdate(c *gin.Context) {
 		}
 	}
 
+	// if the fn can't be found here, UpdateFn will report it
+	if fn.Config != nil && s.configLimited() {
+		if current, err := s.datastore.GetFnByID(ctx, fn.ID); err == nil {
+			if err := s.checkConfigUpdate(current.Config, fn.Config); err != nil {
+				handleErrorResponse(c, err)
+				return
+			}
+		}
+	}
+
 	fnUpdated, err := s.datastore.UpdateFn(ctx, fn)
 	if err != nil {
 		handleErrorResponse(c, err)
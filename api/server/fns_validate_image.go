@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// registry lookups are on the request path, don't let a slow registry hold it open
+const validateImageTimeout = 10 * time.Second
+
+type validateImageRequest struct {
+	Image string `json:"image"`
+}
+
+func (s *Server) handleFnValidateImage(c *gin.Context) {
+	var req validateImageRequest
+	err := c.BindJSON(&req)
+	if err != nil {
+		if !models.IsAPIError(err) {
+			err = models.ErrInvalidJSON
+		}
+		handleErrorResponse(c, err)
+		return
+	}
+	if req.Image == "" {
+		handleErrorResponse(c, models.ErrFnsMissingImage)
+		return
+	}
+
+	validator, ok := s.agent.(agent.ImageValidator)
+	if !ok {
+		handleErrorResponse(c, models.ErrImageValidationUnsupported)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), validateImageTimeout)
+	defer cancel()
+
+	info, err := validator.ValidateImage(ctx, req.Image)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
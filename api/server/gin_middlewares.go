@@ -5,6 +5,7 @@ package server
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"strconv"
 	"strings"
 	"time"
@@ -38,7 +39,7 @@ const (
 	fnFdkVersionHeader = "Fn-Fdk-Version"
 )
 
-func optionalCorsWrap(r *gin.Engine) {
+func optionalCorsWrap(r *gin.Engine, basePath string) {
 	// By default no CORS are allowed unless one
 	// or more Origins are defined by the API_CORS
 	// environment variable.
@@ -63,7 +64,26 @@ func optionalCorsWrap(r *gin.Engine) {
 
 		logrus.Infof("CORS enabled for domains: %s", origins)
 
-		r.Use(cors.New(corsConfig))
+		apiCors := cors.New(corsConfig)
+		r.Use(func(c *gin.Context) {
+			// the trigger and invoke endpoints have their own CORS settings, see invokeCORSWrap
+			if isInvokePath(strings.TrimPrefix(c.Request.URL.Path, basePath)) {
+				c.Next()
+				return
+			}
+			apiCors(c)
+		})
+	}
+}
+
+// apiRequestTimeoutWrap sets a deadline on the context of the request, so that
+// whatever is done on its behalf is cancelled once it passes.
+func apiRequestTimeoutWrap(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
 	}
 }
 
@@ -214,6 +234,58 @@ func loggerWrap(c *gin.Context) {
 	c.Next()
 }
 
+// accessLogWrap logs the start and end of each request, keeping only about
+// sampleRate of them. The decision is made from the request ID, so that a
+// sampled request logs both lines, and requests ending with a non 2xx status
+// log their end regardless.
+func accessLogWrap(sampleRate float64) gin.HandlerFunc {
+	threshold := uint64(sampleRate * (1 << 32))
+	return func(c *gin.Context) {
+		sampled := sampleRate >= 1
+		if !sampled && sampleRate > 0 {
+			if rid := common.RequestIDFromContext(c.Request.Context()); rid != "" {
+				sampled = uint64(requestIDHash(rid)) < threshold
+			} else {
+				sampled = rand.Float64() < sampleRate
+			}
+		}
+
+		start := time.Now()
+		if sampled {
+			common.Logger(c.Request.Context()).WithFields(logrus.Fields{"method": c.Request.Method, "path": c.Request.URL.Path}).Info("request started")
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		if sampled || status < 200 || status >= 300 {
+			common.Logger(c.Request.Context()).WithFields(logrus.Fields{
+				"method":   c.Request.Method,
+				"path":     c.Request.URL.Path,
+				"status":   status,
+				"duration": time.Since(start),
+			}).Info("request completed")
+		}
+	}
+}
+
+// requestIDHash hashes s with FNV-1a without converting it to a byte slice, as hash/fnv would.
+// The result is finalized as in murmur3 so that similar request IDs are evenly
+// spread over the whole range.
+func requestIDHash(s string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= 16777619
+	}
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
 type ctxFnIDKey string
 
 func ContextWithFnID(ctx context.Context, fnID string) context.Context {
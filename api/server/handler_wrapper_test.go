@@ -0,0 +1,33 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+)
+
+func TestHandlerWrapper(t *testing.T) {
+	var seen []string
+	wrapper := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = append(seen, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	srv := testServer(datastore.NewMock(), nil, ServerTypeAPI, WithHandlerWrapper(wrapper("first")), WithHandlerWrapper(wrapper("second")))
+
+	rec := httptest.NewRecorder()
+	srv.wrapHandler(srv.Router).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status code 200 but was %d", rec.Code)
+	}
+	if got := strings.Join(seen, ","); got != "first,second" {
+		t.Errorf("expected wrappers to run in registration order, got %s", got)
+	}
+}
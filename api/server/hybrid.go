@@ -1,16 +1,87 @@
 package server
 
 import (
-	"strings"
-
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
 	"github.com/gin-gonic/gin"
 )
 
+// runnerNodeKey is the gin context key set on requests to the runner API made
+// by LB nodes which authenticated as such
+const runnerNodeKey = "fn_runner_node"
+
+// runnerAPIAuthWrap only lets the runner API be called by LB nodes presenting a
+// client certificate issued by the node certificate authority, if
+// WithRunnerAPIMTLS is set, or else the API root token, if WithAPITokenAuth is.
+// Without either, anyone may call it, but never gets the secrets of apps,
+// unless the API is tenant scoped: the runner API is not, so it is then
+// refused to all.
+func (s *Server) runnerAPIAuthWrap(c *gin.Context) {
+	var err error
+	switch {
+	case s.runnerAPIMTLS:
+		err = s.verifyNodeCert(c.Request.TLS)
+	case s.apiRootToken != "":
+		if subtle.ConstantTimeCompare([]byte(bearerToken(c.Request)), []byte(s.apiRootToken)) != 1 {
+			err = errors.New("no API root token")
+		}
+	case s.tenantResolver != nil:
+		err = errors.New("the runner API of a tenant scoped API requires a node credential")
+	default:
+		c.Next()
+		return
+	}
+	if err != nil {
+		common.Logger(c.Request.Context()).WithError(err).Info("runner API client rejected")
+		handleErrorResponse(c, models.ErrRunnerAPIUnauthorized)
+		c.Abort()
+		return
+	}
+	c.Set(runnerNodeKey, true)
+	c.Next()
+}
+
+func (s *Server) verifyNodeCert(state *tls.ConnectionState) error {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return errors.New("no client certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         s.nodeCertAuthority,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err
+}
+
+// handleRunnerGetApp returns an app to LB nodes, with the annotations holding
+// secrets which the /v2/apps API never returns, e.g. to pull the images of
+// its functions from their registry. Those are only returned to nodes which
+// authenticated, see runnerAPIAuthWrap.
+func (s *Server) handleRunnerGetApp(c *gin.Context) {
+	app, err := s.datastore.GetAppByID(c.Request.Context(), c.Param(api.AppID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	if !c.GetBool(runnerNodeKey) {
+		app = app.Redacted()
+	}
+	c.JSON(http.StatusOK, app)
+}
+
 // TODO: figure out what to do with this, stale interface from hybrid days but still in use
 func (s *Server) handleRunnerGetTriggerBySource(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -0,0 +1,309 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent/hybrid"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+)
+
+// testCert issues a certificate for name, self signed if parent is nil
+func testCert(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestInternalRunnerAPI(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ca, caKey := testCert(t, "ca", nil, nil)
+	node, _ := testCert(t, "lb", ca, caKey)
+	other, _ := testCert(t, "other", nil, nil)
+
+	caFile, err := ioutil.TempFile("", "node-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(caFile.Name())
+	pem.Encode(caFile, &pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})
+	caFile.Close()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID}
+	trigger := &models.Trigger{ID: "trigger_id", Name: "trigger", AppID: app.ID, FnID: fn.ID, Type: "http", Source: "/src"}
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn}, []*models.Trigger{trigger})
+	path := "/v2/runner/apps/app_id/triggerBySource/http//src"
+
+	mtls := func() []Option {
+		return []Option{WithTLS(WebServer, &tls.Config{}), WithNodeCertAuthority(caFile.Name()), WithRunnerAPIMTLS()}
+	}
+
+	for i, test := range []struct {
+		opts         []Option
+		tls          *tls.ConnectionState
+		expectedCode int
+	}{
+		{nil, nil, http.StatusOK},
+		{[]Option{WithInternalRunnerAPI(true)}, nil, http.StatusOK},
+		{[]Option{WithInternalRunnerAPI(false)}, nil, http.StatusNotFound},
+		// with mTLS only certificates issued by the node certificate authority are let through
+		{mtls(), nil, http.StatusUnauthorized},
+		{mtls(), &tls.ConnectionState{}, http.StatusUnauthorized},
+		{mtls(), &tls.ConnectionState{PeerCertificates: []*x509.Certificate{other}}, http.StatusUnauthorized},
+		{mtls(), &tls.ConnectionState{PeerCertificates: []*x509.Certificate{node}}, http.StatusOK},
+		{[]Option{WithTLS(WebServer, &tls.Config{}), WithNodeCertAuthority(caFile.Name())}, nil, http.StatusOK},
+	} {
+		srv := testServer(ds, nil, ServerTypeAPI, test.opts...)
+
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.TLS = test.tls
+		rec := httptest.NewRecorder()
+		srv.Router.ServeHTTP(rec, req)
+
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+		if test.expectedCode == http.StatusUnauthorized {
+			if resp := getErrorResponse(t, rec); resp.Message != models.ErrRunnerAPIUnauthorized.Error() {
+				t.Errorf("Test %d: expected error %s but got %s", i, models.ErrRunnerAPIUnauthorized, resp.Message)
+			}
+		}
+		if srv.runnerAPIMTLS && srv.svcConfigs[WebServer].TLSConfig.ClientAuth != tls.RequestClientCert {
+			t.Errorf("Test %d: expected the web server to request client certificates", i)
+		}
+	}
+}
+
+// writePEM writes blocks to a temp file, whose name it returns
+func writePEM(t *testing.T, blocks ...*pem.Block) string {
+	f, err := ioutil.TempFile("", "node-cert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, b := range blocks {
+		if err := pem.Encode(f, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f.Name()
+}
+
+func writeCertFiles(t *testing.T, cert *x509.Certificate, key *ecdsa.PrivateKey) (certFile, keyFile string) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), writePEM(t, &pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func TestRunnerAPIMTLSClient(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+	ctx := context.Background()
+
+	ca, caKey := testCert(t, "ca", nil, nil)
+	apiCert, apiKey := testCert(t, "api", ca, caKey)
+	lbCert, lbKey := testCert(t, "lb", ca, caKey)
+	caFile := writePEM(t, &pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})
+	apiCertFile, apiKeyFile := writeCertFiles(t, apiCert, apiKey)
+	lbCertFile, lbKeyFile := writeCertFiles(t, lbCert, lbKey)
+	for _, f := range []string{caFile, apiCertFile, apiKeyFile, lbCertFile, lbKeyFile} {
+		defer os.Remove(f)
+	}
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID}
+	trigger := &models.Trigger{ID: "trigger_id", Name: "trigger", AppID: app.ID, FnID: fn.ID, Type: "http", Source: "/src"}
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn}, []*models.Trigger{trigger})
+
+	// the API node serves the node cert, with no TLS configured on its web server
+	srv := testServer(ds, nil, ServerTypeAPI, WithNodeCertAuthority(caFile), WithNodeCertFiles(apiCertFile, apiKeyFile), WithRunnerAPIMTLS())
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", srv.svcConfigs[WebServer].TLSConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpSrv := &http.Server{Handler: srv.Router}
+	go httpSrv.Serve(ln)
+	defer httpSrv.Close()
+	apiURL := "https://" + ln.Addr().String()
+
+	// LB nodes present theirs
+	lb := new(Server)
+	for _, opt := range []Option{WithNodeCertAuthority(caFile), WithNodeCertFiles(lbCertFile, lbKeyFile)} {
+		if err := opt(ctx, lb); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lbTLS, err := lb.nodeCertTLS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl, err := hybrid.NewClient(apiURL, hybrid.WithTLSConfig(lbTLS), hybrid.WithRetryMaxElapsed(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := cl.GetTriggerBySource(ctx, app.ID, "http", "/src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != trigger.ID {
+		t.Fatalf("expected trigger %s, got %+v", trigger.ID, got)
+	}
+
+	// clients without a node cert are rejected
+	cl, err = hybrid.NewClient(apiURL, hybrid.WithTLSConfig(&tls.Config{RootCAs: lb.nodeCertRoots}), hybrid.WithRetryMaxElapsed(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cl.GetTriggerBySource(ctx, app.ID, "http", "/src"); err == nil || !strings.Contains(err.Error(), models.ErrRunnerAPIUnauthorized.Error()) {
+		t.Fatalf("expected %s without a client cert, got %v", models.ErrRunnerAPIUnauthorized, err)
+	}
+}
+
+func TestRunnerGetApp(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	app.Annotations, _ = app.Annotations.With(models.AppRegistryAuthAnnotation, map[string]interface{}{
+		"my.registry.com": map[string]string{"auth": "Y29jbzpjaGVlc2U="},
+	})
+	app.Annotations, _ = app.Annotations.With(models.AppSigningKeyAnnotation, "0123456789abcdef0123456789abcdef")
+	ds := datastore.NewMockInit([]*models.App{app})
+
+	// the API never returns secrets, LB nodes get them from the runner API,
+	// once authenticated
+	for i, test := range []struct {
+		rootToken    string
+		path         string
+		token        string
+		expectedCode int
+		withSecret   bool
+	}{
+		{"", "/v2/apps/app_id", "", http.StatusOK, false},
+		{"", "/v2/apps", "", http.StatusOK, false},
+		{"", "/v2/runner/apps/app_id", "", http.StatusOK, false},
+		{"root", "/v2/apps/app_id", "root", http.StatusOK, false},
+		{"root", "/v2/runner/apps/app_id", "", http.StatusUnauthorized, false},
+		{"root", "/v2/runner/apps/app_id", "wrong", http.StatusUnauthorized, false},
+		{"root", "/v2/runner/apps/app_id/triggerBySource/http/src", "", http.StatusUnauthorized, false},
+		{"root", "/v2/runner/apps/app_id", "root", http.StatusOK, true},
+	} {
+		srv := testServer(ds, nil, ServerTypeAPI, WithAPITokenAuth(test.rootToken))
+
+		req := createRequest(t, http.MethodGet, test.path, nil)
+		if test.token != "" {
+			req.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		_, rec := routerRequest2(t, srv.Router, req)
+		if rec.Code != test.expectedCode {
+			t.Fatalf("Test %d: expected status code %d but was %d: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+		body := rec.Body.String()
+		for _, key := range []string{models.AppRegistryAuthAnnotation, models.AppSigningKeyAnnotation} {
+			if got := strings.Contains(body, key); got != test.withSecret {
+				t.Errorf("Test %d: expected %s returned %v, got %s", i, key, test.withSecret, body)
+			}
+		}
+	}
+}
+
+func TestHybridClientAPIToken(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	app.Annotations, _ = app.Annotations.With(models.AppSigningKeyAnnotation, "0123456789abcdef0123456789abcdef")
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils"}
+	srv := testServer(datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn}), nil, ServerTypeAPI, WithAPITokenAuth("root"))
+	api := httptest.NewServer(srv.Router)
+	defer api.Close()
+	ctx := context.Background()
+
+	// LB nodes must send the root token to API nodes with API token auth
+	cl, err := hybrid.NewClient(api.URL, hybrid.WithRetryMaxElapsed(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cl.GetFnByID(ctx, fn.ID); err == nil {
+		t.Fatal("expected the lookup without a token to fail")
+	}
+
+	cl, err = hybrid.NewClient(api.URL, hybrid.WithAPIToken("root"), hybrid.WithRetryMaxElapsed(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := cl.GetAppID(ctx, app.Name); err != nil || id != app.ID {
+		t.Fatalf("expected the app id, got %q %v", id, err)
+	}
+	if _, err := cl.GetFnByID(ctx, fn.ID); err != nil {
+		t.Fatal(err)
+	}
+	got, err := cl.GetAppByID(ctx, app.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, _ := models.AppSigningKey(got); key == "" {
+		t.Fatal("expected the runner API to return the secrets of the app to the LB node")
+	}
+}
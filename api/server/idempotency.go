@@ -0,0 +1,255 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+const (
+	// idempotencyKeyHeader is the header of the key identifying the retries
+	// of a sync call, unique per app
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader is set on responses replayed for a key
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotencyKeyLength bounds the keys held in memory
+	maxIdempotencyKeyLength = 255
+	// DefaultIdempotencyMaxBody is the largest response stored for a key,
+	// unless set otherwise with WithIdempotency
+	DefaultIdempotencyMaxBody = 1 << 20
+
+	// idempotencyMaxEntries is the most keys the store in memory holds,
+	// running or with a response
+	idempotencyMaxEntries = 10000
+	// idempotencyMaxSize is the bytes of responses, with their keys and
+	// headers, the store in memory holds
+	idempotencyMaxSize = 64 << 20
+	// idempotencyReservationSlack is how long the reservation of a key
+	// outlives the timeout of its call, which may wait for a container first
+	idempotencyReservationSlack = time.Minute
+)
+
+// IdempotentResponse is a response of a function stored for an idempotency key
+type IdempotentResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// IdempotencyStore stores the responses of the sync calls made with an
+// idempotency key, per app, so that retries replay them.
+type IdempotencyStore interface {
+	// Reserve claims key for a call about to run, for up to ttl, which is at
+	// least the timeout of the call. It returns
+	// the response stored for key if any, or models.ErrIdempotencyKeyInUse
+	// if a call with key is still running. Stores which are full may return
+	// models.ErrIdempotencyStoreFull.
+	Reserve(ctx context.Context, appID, key string, ttl time.Duration) (*IdempotentResponse, error)
+	// Store stores the response of the call which reserved key, for ttl from
+	// now, replacing the reservation. If
+	// it returns an error, the response is not stored and the reservation
+	// is released.
+	Store(ctx context.Context, appID, key string, resp *IdempotentResponse, ttl time.Duration) error
+	// Release drops the reservation of key, for a call which did not complete
+	Release(ctx context.Context, appID, key string) error
+}
+
+// WithIdempotency makes sync calls to functions, from /invoke and /t, honor
+// the Idempotency-Key header. The response of the first call with a key is
+// stored for ttl, unless its body is larger than maxBody bytes, and returned
+// to later calls with the same key of the same app without running them
+// again. While the first call runs, calls with its key get a 409 Conflict
+// rather than waiting for it. Calls which fail are not stored, so they can
+// be retried. Detached calls ignore the header.
+//
+// Keys are scoped to the app only, not to its callers: any caller of the app
+// using the key of another gets its response, so keys should be unguessable,
+// such as random UUIDs.
+//
+// Responses are stored in memory, per server, for up to 10000 keys and 64MiB
+// of responses, over which calls with a new key get a 503. fn has no store
+// shared by the servers of a cluster: behind a load balancer, retries only
+// replay the response if they reach the same server, unless
+// WithIdempotencyStore sets such a store.
+func WithIdempotency(ttl time.Duration, maxBody int) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.idempotencyTTL = ttl
+		s.idempotencyMaxBody = maxBody
+		if ttl > 0 && s.idempotencyStore == nil {
+			s.idempotencyStore = newMemoryIdempotencyStore()
+		}
+		return nil
+	}
+}
+
+// WithIdempotencyStore sets the store of the responses of the calls made
+// with an idempotency key, see WithIdempotency.
+func WithIdempotencyStore(store IdempotencyStore) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.idempotencyStore = store
+		return nil
+	}
+}
+
+// idempotencyKey returns the idempotency key of a call, whose headers are
+// prefixed when made through a trigger
+func idempotencyKey(req *http.Request, trig *models.Trigger) string {
+	if trig != nil {
+		return req.Header.Get("Fn-Http-H-" + idempotencyKeyHeader)
+	}
+	return req.Header.Get(idempotencyKeyHeader)
+}
+
+// idempotencyReservationTTL returns how long the key of a call of fn is
+// reserved for: while the call may still run, so that its retries get a 409
+// rather than running it again, and at least ttl
+func idempotencyReservationTTL(fn *models.Fn, ttl time.Duration) time.Duration {
+	if running := time.Duration(fn.Timeout)*time.Second + idempotencyReservationSlack; running > ttl {
+		return running
+	}
+	return ttl
+}
+
+// replayIdempotentResponse writes a stored response
+func replayIdempotentResponse(resp http.ResponseWriter, stored *IdempotentResponse, trig *models.Trigger) {
+	h := resp.Header()
+	for k, vs := range stored.Header {
+		h[k] = vs
+	}
+	if trig != nil {
+		h.Set("Fn-Http-H-"+idempotentReplayedHeader, "true")
+	} else {
+		h.Set(idempotentReplayedHeader, "true")
+	}
+	if !hasTrailers(h) {
+		h.Set("Content-Length", strconv.Itoa(len(stored.Body)))
+	}
+	resp.WriteHeader(stored.Status)
+	resp.Write(stored.Body)
+}
+
+type idempotencyEntry struct {
+	resp    *IdempotentResponse // nil while the call runs
+	expires time.Time
+	size    int64
+}
+
+// memoryIdempotencyStore is an IdempotencyStore local to a server, holding
+// up to maxEntries keys and maxSize bytes of responses, keys and headers
+// included
+type memoryIdempotencyStore struct {
+	maxEntries int
+	maxSize    int64
+	now        func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]idempotencyEntry
+	size      int64
+	nextSweep time.Time
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{
+		maxEntries: idempotencyMaxEntries,
+		maxSize:    idempotencyMaxSize,
+		now:        time.Now,
+		entries:    make(map[string]idempotencyEntry),
+	}
+}
+
+func idempotencyEntryKey(appID, key string) string {
+	return appID + "/" + key
+}
+
+// Reserve implements IdempotencyStore
+func (m *memoryIdempotencyStore) Reserve(ctx context.Context, appID, key string, ttl time.Duration) (*IdempotentResponse, error) {
+	now := m.now()
+	k := idempotencyEntryKey(appID, key)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// drop expired entries at most once per ttl, rather than on each call,
+	// unless the store is full
+	if now.After(m.nextSweep) || len(m.entries) >= m.maxEntries {
+		m.sweep(now)
+		m.nextSweep = now.Add(ttl)
+	}
+
+	if e, ok := m.entries[k]; ok && !now.After(e.expires) {
+		if e.resp == nil {
+			return nil, models.ErrIdempotencyKeyInUse
+		}
+		return e.resp, nil
+	}
+	m.remove(k)
+	size := int64(len(k))
+	if len(m.entries) >= m.maxEntries || m.size+size > m.maxSize {
+		return nil, models.ErrIdempotencyStoreFull
+	}
+	m.entries[k] = idempotencyEntry{expires: now.Add(ttl), size: size}
+	m.size += size
+	return nil, nil
+}
+
+// Store implements IdempotencyStore
+func (m *memoryIdempotencyStore) Store(ctx context.Context, appID, key string, resp *IdempotentResponse, ttl time.Duration) error {
+	now := m.now()
+	k := idempotencyEntryKey(appID, key)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.remove(k)
+	size := idempotentResponseSize(k, resp)
+	if m.size+size > m.maxSize {
+		m.sweep(now)
+		if m.size+size > m.maxSize {
+			return models.ErrIdempotencyStoreFull
+		}
+	}
+	m.entries[k] = idempotencyEntry{resp: resp, expires: now.Add(ttl), size: size}
+	m.size += size
+	return nil
+}
+
+// Release implements IdempotencyStore
+func (m *memoryIdempotencyStore) Release(ctx context.Context, appID, key string) error {
+	m.mu.Lock()
+	m.remove(idempotencyEntryKey(appID, key))
+	m.mu.Unlock()
+	return nil
+}
+
+// sweep drops the expired entries
+func (m *memoryIdempotencyStore) sweep(now time.Time) {
+	for k, e := range m.entries {
+		if now.After(e.expires) {
+			m.remove(k)
+		}
+	}
+}
+
+func (m *memoryIdempotencyStore) remove(k string) {
+	if e, ok := m.entries[k]; ok {
+		m.size -= e.size
+		delete(m.entries, k)
+	}
+}
+
+// idempotentResponseSize returns the bytes a response stored for k is counted
+// for, with its key and headers
+func idempotentResponseSize(k string, resp *IdempotentResponse) int64 {
+	size := len(k) + len(resp.Body)
+	for hk, vs := range resp.Header {
+		size += len(hk)
+		for _, v := range vs {
+			size += len(v)
+		}
+	}
+	return int64(size)
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}}
+	trigger := &models.Trigger{ID: "trigger_id", Name: "mytrigger", AppID: app.ID, FnID: fn.ID, Type: models.TriggerTypeHTTP, Source: "/src"}
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn}, []*models.Trigger{trigger})
+
+	store := newMemoryIdempotencyStore()
+	// a call with this key is still running
+	if _, err := store.Reserve(context.Background(), app.ID, "running", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	submits := 0
+	newAgent := func(submitErr error) agent.Agent {
+		rnr := new(agent.MockAgent)
+		rnr.On("AddCallListener", mock.Anything)
+		rnr.On("GetCall", mock.Anything)
+		rnr.On("Submit", mock.Anything).Run(func(args mock.Arguments) {
+			submits++
+		}).Return(submitErr)
+		return rnr
+	}
+	ok, failing := newAgent(nil), newAgent(models.ErrCallTimeout)
+
+	srv := testServer(ds, ok, ServerTypeFull, WithIdempotencyStore(store), WithIdempotency(time.Minute, DefaultIdempotencyMaxBody))
+
+	for i, test := range []struct {
+		path             string
+		key              string
+		fail             bool
+		expectedCode     int
+		expectedSubmits  int
+		expectedReplayed string
+	}{
+		{"/invoke/fn_id", "", false, http.StatusOK, 1, ""},
+		{"/invoke/fn_id", "", false, http.StatusOK, 2, ""},
+		{"/invoke/fn_id", "a", false, http.StatusOK, 3, ""},
+		{"/invoke/fn_id", "a", false, http.StatusOK, 3, "true"},
+		{"/invoke/fn_id", "b", false, http.StatusOK, 4, ""},
+		{"/invoke/fn_id", "running", false, http.StatusConflict, 4, ""},
+		{"/invoke/fn_id", strings.Repeat("k", maxIdempotencyKeyLength+1), false, http.StatusBadRequest, 4, ""},
+		// failed calls are not stored
+		{"/invoke/fn_id", "c", true, http.StatusGatewayTimeout, 5, ""},
+		{"/invoke/fn_id", "c", false, http.StatusOK, 6, ""},
+		// the headers of trigger calls are prefixed before they are checked
+		{"/t/myapp/src", "d", false, http.StatusOK, 7, ""},
+		{"/t/myapp/src", "d", false, http.StatusOK, 7, "true"},
+	} {
+		srv.agent = ok
+		if test.fail {
+			srv.agent = failing
+		}
+
+		req := createRequest(t, http.MethodPost, test.path, strings.NewReader("body"))
+		if test.key != "" {
+			req.Header.Set("Idempotency-Key", test.key)
+		}
+		_, rec := routerRequest2(t, srv.Router, req)
+
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d", i, test.expectedCode, rec.Code)
+		}
+		if submits != test.expectedSubmits {
+			t.Errorf("Test %d: expected %d submits, got %d", i, test.expectedSubmits, submits)
+		}
+		if got := rec.Header().Get("Idempotent-Replayed"); got != test.expectedReplayed {
+			t.Errorf("Test %d: expected Idempotent-Replayed %q, got %q", i, test.expectedReplayed, got)
+		}
+	}
+}
+
+func TestMemoryIdempotencyStoreExpiry(t *testing.T) {
+	now := time.Now()
+	store := newMemoryIdempotencyStore()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if _, err := store.Reserve(ctx, "app", "key", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Store(ctx, "app", "key", &IdempotentResponse{Status: http.StatusOK}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if stored, err := store.Reserve(ctx, "app", "key", time.Minute); err != nil || stored == nil {
+		t.Fatalf("expected the stored response, got %v %v", stored, err)
+	}
+	if stored, err := store.Reserve(ctx, "other_app", "key", time.Minute); err != nil || stored != nil {
+		t.Fatalf("expected keys to be per app, got %v %v", stored, err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if stored, err := store.Reserve(ctx, "app", "key", time.Minute); err != nil || stored != nil {
+		t.Fatalf("expected the stored response to expire, got %v %v", stored, err)
+	}
+	if _, err := store.Reserve(ctx, "app", "key", time.Minute); err != models.ErrIdempotencyKeyInUse {
+		t.Fatalf("expected %v, got %v", models.ErrIdempotencyKeyInUse, err)
+	}
+	if err := store.Release(ctx, "app", "key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Reserve(ctx, "app", "key", time.Minute); err != nil {
+		t.Fatalf("expected the released key to be reserved again, got %v", err)
+	}
+}
+
+func TestMemoryIdempotencyStoreFull(t *testing.T) {
+	now := time.Now()
+	store := newMemoryIdempotencyStore()
+	store.maxEntries = 2
+	store.maxSize = 32
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b"} {
+		if _, err := store.Reserve(ctx, "app", key, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.Reserve(ctx, "app", "c", time.Minute); err != models.ErrIdempotencyStoreFull {
+		t.Fatalf("expected %v past the entries of the store, got %v", models.ErrIdempotencyStoreFull, err)
+	}
+
+	// the key and headers count toward the size of the store
+	big := &IdempotentResponse{Status: http.StatusOK, Header: http.Header{"Content-Type": {"text/plain"}}, Body: []byte("0123456789")}
+	if err := store.Store(ctx, "app", "a", big, time.Minute); err != models.ErrIdempotencyStoreFull {
+		t.Fatalf("expected %v past the size of the store, got %v", models.ErrIdempotencyStoreFull, err)
+	}
+	if err := store.Store(ctx, "app", "a", &IdempotentResponse{Status: http.StatusOK, Body: []byte("0123456789")}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	// expired keys make room for others
+	now = now.Add(2 * time.Minute)
+	if _, err := store.Reserve(ctx, "app", "c", time.Minute); err != nil {
+		t.Fatalf("expected c to be reserved once the others expired, got %v", err)
+	}
+	if len(store.entries) != 1 || store.size != int64(len("app/c")) {
+		t.Fatalf("expected only c to be held, got %d entries of %d bytes", len(store.entries), store.size)
+	}
+}
+
+// reserveTTLStore records the ttl keys are reserved for
+type reserveTTLStore struct {
+	*memoryIdempotencyStore
+	reserveTTL time.Duration
+}
+
+func (r *reserveTTLStore) Reserve(ctx context.Context, appID, key string, ttl time.Duration) (*IdempotentResponse, error) {
+	r.reserveTTL = ttl
+	return r.memoryIdempotencyStore.Reserve(ctx, appID, key, ttl)
+}
+
+func TestIdempotencyReservationTTL(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 300}}
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn})
+
+	rnr := new(agent.MockAgent)
+	rnr.On("AddCallListener", mock.Anything)
+	rnr.On("GetCall", mock.Anything)
+	rnr.On("Submit", mock.Anything).Return(nil)
+
+	store := &reserveTTLStore{memoryIdempotencyStore: newMemoryIdempotencyStore()}
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	// responses are kept for less than the call may run
+	srv := testServer(ds, rnr, ServerTypeFull, WithIdempotencyStore(store), WithIdempotency(time.Minute, DefaultIdempotencyMaxBody))
+
+	req := createRequest(t, http.MethodPost, "/invoke/fn_id", strings.NewReader("body"))
+	req.Header.Set("Idempotency-Key", "a")
+	if _, rec := routerRequest2(t, srv.Router, req); rec.Code != http.StatusOK {
+		t.Fatalf("expected status code 200 but was %d", rec.Code)
+	}
+	if store.reserveTTL < 300*time.Second {
+		t.Errorf("expected the key to be reserved for at least the timeout of the fn, got %v", store.reserveTTL)
+	}
+
+	// the response is kept for the ttl from when it was stored
+	now = now.Add(50 * time.Second)
+	if stored, err := store.Reserve(context.Background(), app.ID, "a", time.Minute); err != nil || stored == nil {
+		t.Errorf("expected the stored response, got %v %v", stored, err)
+	}
+	now = now.Add(20 * time.Second)
+	if stored, err := store.Reserve(context.Background(), app.ID, "a", time.Minute); err != nil || stored != nil {
+		t.Errorf("expected the stored response to expire, got %v %v", stored, err)
+	}
+}
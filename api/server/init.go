@@ -24,7 +24,7 @@ func init() {
 	// init logging stuff in init, in case any packages log stuff on startup
 	common.SetLogFormat(getEnv(EnvLogFormat, DefaultLogFormat))
 	common.SetLogLevel(getEnv(EnvLogLevel, DefaultLogLevel))
-	common.SetLogDest(getEnv(EnvLogDest, DefaultLogDest), getEnv(EnvLogPrefix, ""))
+	common.SetLogDests(splitList(getEnv(EnvLogDest, DefaultLogDest)), getEnv(EnvLogPrefix, ""))
 
 	// gin is not nice by default, this can get set in logging initialization
 	gin.SetMode(gin.ReleaseMode)
@@ -80,6 +80,16 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+// splitList splits a comma separated list, as found in env vars, ignoring
+// spaces.
+func splitList(list string) []string {
+	list = strings.Replace(list, " ", "", -1)
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
+
 func getEnvInt(key string, fallback int) int {
 	if value, ok := os.LookupEnv(key); ok {
 		// linter liked this better than if/else
@@ -103,6 +113,28 @@ func getEnvInt(key string, fallback int) int {
 	return fallback
 }
 
+func getEnvBool(key string, fallback bool) bool {
+	if value := strings.TrimSpace(getEnv(key, "")); value != "" {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{"string": value, "environment_key": key}).Fatal("Failed to convert string to bool")
+		}
+		return b
+	}
+	return fallback
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	if value := strings.TrimSpace(getEnv(key, "")); value != "" {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{"string": value, "environment_key": key}).Fatal("Failed to convert string to float")
+		}
+		return f
+	}
+	return fallback
+}
+
 func getEnvDuration(key string, fallback time.Duration) time.Duration {
 	var err error
 	res := fallback
@@ -143,6 +175,25 @@ func contextWithSignal(ctx context.Context, signals ...os.Signal) (context.Conte
 	return newCTX, halt
 }
 
+// reloadOnSignal reloads the config of the server on signals, rather than
+// letting them terminate the process, until ctx is done.
+func (s *Server) reloadOnSignal(ctx context.Context, signals ...os.Signal) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, signals...)
+	defer signal.Stop(c)
+	for {
+		select {
+		case <-c:
+			common.Logger(ctx).Info("Reloading config...")
+			if err := s.Reload(ctx); err != nil {
+				common.Logger(ctx).WithError(err).Error("Failed to reload config")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 // Installs a child process reaper if init process
 func installChildReaper() {
 	// assume responsibilities of init process if running as init process for Linux
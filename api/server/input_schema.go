@@ -0,0 +1,54 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// maxSchemaInputBytes bounds the bodies validated against the input schema
+// of a trigger, as they are held in memory to be decoded
+const maxSchemaInputBytes = 1 << 20
+
+var errInputNotJSON = models.NewAPIError(http.StatusBadRequest, errors.New("Invalid input, the body must be JSON"))
+
+// validateInputSchema checks the body of a call made through trigger against
+// the schema of its models.TriggerInputSchemaAnnotation, if any. Bodies larger
+// than maxSchemaInputBytes are rejected. The body is kept to be passed on to
+// the function.
+func validateInputSchema(req *http.Request, trigger *models.Trigger) error {
+	schema, err := trigger.InputSchema()
+	if err != nil || schema == nil {
+		return err
+	}
+
+	if req.ContentLength > maxSchemaInputBytes {
+		return errTooBig{req.ContentLength, maxSchemaInputBytes}
+	}
+	var b []byte
+	if body := req.Body; body != nil {
+		var err error
+		b, err = bufferCallBody(req, io.LimitReader(body, maxSchemaInputBytes+1))
+		body.Close()
+		if err != nil {
+			return err
+		}
+		if n := int64(len(b)); n > maxSchemaInputBytes {
+			return errTooBig{n, maxSchemaInputBytes}
+		}
+	}
+
+	var input interface{}
+	if err := json.Unmarshal(b, &input); err != nil {
+		return errInputNotJSON
+	}
+	if errs := schema.Validate(input); len(errs) > 0 {
+		return models.NewAPIError(http.StatusBadRequest, fmt.Errorf("Invalid input, %s", strings.Join(errs, "; ")))
+	}
+	return nil
+}
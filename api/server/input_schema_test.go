@@ -0,0 +1,90 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestTriggerInputSchema(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	schema := map[string]interface{}{
+		"type":     "object",
+		"required": []string{"name"},
+		"properties": map[string]interface{}{
+			"name": map[string]interface{}{"type": "string"},
+		},
+	}
+	annotations, err := models.Annotations{}.With(models.TriggerInputSchemaAnnotation, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}}
+	trigger := &models.Trigger{ID: "trigger_id", Name: "mytrigger", AppID: app.ID, FnID: fn.ID, Type: models.TriggerTypeHTTP, Source: "/schema", Annotations: annotations}
+	other := &models.Trigger{ID: "other_id", Name: "other", AppID: app.ID, FnID: fn.ID, Type: models.TriggerTypeHTTP, Source: "/none"}
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn}, []*models.Trigger{trigger, other})
+
+	for i, test := range []struct {
+		path            string
+		body            string
+		expectedCode    int
+		expectedMessage string
+	}{
+		{"/t/myapp/schema", `{"name": "bob"}`, http.StatusOK, ""},
+		{"/t/myapp/schema", `{"name": 1}`, http.StatusBadRequest, "Invalid input, $.name must be of type string"},
+		{"/t/myapp/schema", `{}`, http.StatusBadRequest, "Invalid input, $.name is required"},
+		{"/t/myapp/schema", `not json`, http.StatusBadRequest, errInputNotJSON.Error()},
+		{"/t/myapp/schema", `{"name": "` + strings.Repeat("a", maxSchemaInputBytes) + `"}`, http.StatusRequestEntityTooLarge, ""},
+		{"/t/myapp/none", `not json`, http.StatusOK, ""},
+	} {
+		submitted := false
+		rnr := new(agent.MockAgent)
+		rnr.On("AddCallListener", mock.Anything)
+		rnr.On("GetCall", mock.Anything)
+		rnr.On("Submit", mock.Anything).Run(func(args mock.Arguments) {
+			submitted = true
+		}).Return(nil)
+
+		srv := testServer(ds, rnr, ServerTypeFull)
+
+		req := createRequest(t, http.MethodPost, test.path, strings.NewReader(test.body))
+		_, rec := routerRequest2(t, srv.Router, req)
+
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+		if submitted != (test.expectedCode == http.StatusOK) {
+			t.Errorf("Test %d: expected submitted %v, got %v", i, test.expectedCode == http.StatusOK, submitted)
+		}
+		if test.expectedMessage != "" {
+			resp := getErrorResponse(t, rec)
+			if resp == nil || resp.Message != test.expectedMessage {
+				t.Errorf("Test %d: expected error `%s`, got %s", i, test.expectedMessage, rec.Body.String())
+			}
+		}
+	}
+
+	// a chunked body is only over the limit of the server once read
+	rnr := new(agent.MockAgent)
+	rnr.On("AddCallListener", mock.Anything)
+	srv := testServer(ds, rnr, ServerTypeFull, LimitRequestBody(8))
+	req := createRequest(t, http.MethodPost, "/t/myapp/schema", strings.NewReader(`{"name": "bob"}`))
+	req.ContentLength = -1
+	_, rec := routerRequest2(t, srv.Router, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status code 413 for a chunked body over the limit but was %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// webhookTimeout bounds each post of an invalidation to a webhook
+const webhookTimeout = 5 * time.Second
+
+type webhookInvalidationPublisher struct {
+	urls       []string
+	adminToken string
+	client     *http.Client
+}
+
+// NewWebhookInvalidationPublisher returns an InvalidationPublisher posting
+// each invalidation as JSON to every url, typically the /cache/invalidate
+// admin endpoint of each LB node, with adminToken, the admin token of the
+// nodes, as Authorization: Bearer <token>. Posts are made in the background
+// and not retried, failures are logged and left to the TTL of the caches.
+func NewWebhookInvalidationPublisher(urls []string, adminToken string) InvalidationPublisher {
+	return &webhookInvalidationPublisher{
+		urls:       urls,
+		adminToken: adminToken,
+		client:     &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+func (p *webhookInvalidationPublisher) Publish(ctx context.Context, inv DataCacheInvalidation) error {
+	body, err := json.Marshal(inv)
+	if err != nil {
+		return err
+	}
+
+	log := common.Logger(ctx)
+	for _, u := range p.urls {
+		go func(u string) {
+			if err := p.post(u, body); err != nil {
+				log.WithError(err).WithFields(logrus.Fields{"url": u, "invalidation": inv}).Error("failed to post data cache invalidation")
+			}
+		}(u)
+	}
+	return nil
+}
+
+func (p *webhookInvalidationPublisher) post(u string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.adminToken != "" {
+		req.Header.Set("Authorization", "Bearer "+p.adminToken)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// handleCacheInvalidate applies an invalidation published by another node to
+// the data cache of this server, if it has one. It is served with the admin
+// token only, see WithAdminToken.
+func (s *Server) handleCacheInvalidate(c *gin.Context) {
+	var inv DataCacheInvalidation
+	if err := c.BindJSON(&inv); err != nil {
+		handleErrorResponse(c, models.ErrInvalidJSON)
+		return
+	}
+
+	if err := s.InvalidateDataCache(inv); err != nil {
+		handleErrorResponse(c, models.NewAPIError(http.StatusBadRequest, err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
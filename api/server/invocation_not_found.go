@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// WithInvocationNotFoundHandler sets the handler responding to calls to the
+// trigger and invoke endpoints for an app, fn or trigger which does not exist,
+// e.g. with a branded page or a redirect for public function hosting. The
+// handler must write the status and body of the response itself; what it does
+// not write is sent as an empty 404. It is not used for the management API,
+// whose misses are always reported as errors. By default misses are reported
+// as errors too.
+func WithInvocationNotFoundHandler(h gin.HandlerFunc) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.invocationNotFound = h
+		return nil
+	}
+}
+
+// handleInvocationMiss responds with the invocation not found handler, if set,
+// when err says the app, fn or trigger called does not exist, returning nil
+// once it has. Other errors are returned as they are.
+func (s *Server) handleInvocationMiss(c *gin.Context, err error) error {
+	if s.invocationNotFound == nil {
+		return err
+	}
+	if e, ok := err.(models.APIError); !ok || e.Code() != http.StatusNotFound {
+		return err
+	}
+	s.serveInvocationNotFound(c)
+	return nil
+}
+
+func (s *Server) serveInvocationNotFound(c *gin.Context) {
+	c.Status(http.StatusNotFound)
+	s.invocationNotFound(c)
+	if !c.Writer.Written() {
+		c.Writer.WriteHeaderNow()
+	}
+	c.Abort()
+}
+
+// isInvocationNoRoute returns whether a request matching no route was meant
+// for the trigger or invoke endpoints of this node
+func (s *Server) isInvocationNoRoute(c *gin.Context) bool {
+	if s.invocationNotFound == nil || (s.nodeType != ServerTypeFull && s.nodeType != ServerTypeLB) {
+		return false
+	}
+	return isInvokePath(strings.TrimPrefix(c.Request.URL.Path, s.basePath))
+}
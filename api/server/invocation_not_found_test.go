@@ -0,0 +1,56 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestInvocationNotFoundHandler(t *testing.T) {
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	ds := datastore.NewMockInit([]*models.App{app})
+
+	notFound := func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "nothing to see here"})
+	}
+	redirect := func(c *gin.Context) {
+		c.Redirect(http.StatusFound, "https://example.com/functions")
+	}
+
+	for i, test := range []struct {
+		handler          gin.HandlerFunc
+		method           string
+		path             string
+		expectedCode     int
+		expectedContains string
+	}{
+		// the default error
+		{nil, http.MethodPost, "/invoke/nope", http.StatusNotFound, models.ErrFnsNotFound.Error()},
+		{notFound, http.MethodPost, "/invoke/nope", http.StatusNotFound, "nothing to see here"},
+		{notFound, http.MethodGet, "/t/nope/hello", http.StatusNotFound, "nothing to see here"},
+		{notFound, http.MethodGet, "/t/myapp/nope", http.StatusNotFound, "nothing to see here"},
+		{notFound, http.MethodPost, "/invoke/", http.StatusNotFound, "nothing to see here"},
+		{redirect, http.MethodGet, "/t/nope/hello", http.StatusFound, ""},
+		// not an invocation
+		{notFound, http.MethodGet, "/v2/apps/nope", http.StatusNotFound, models.ErrAppsNotFound.Error()},
+		{notFound, http.MethodGet, "/nope", http.StatusNotFound, models.ErrPathNotFound.Error()},
+	} {
+		rnr := new(agent.MockAgent)
+		rnr.On("AddCallListener", mock.Anything)
+		srv := testServer(ds, rnr, ServerTypeFull, WithInvocationNotFoundHandler(test.handler))
+
+		_, rec := routerRequest(t, srv.Router, test.method, test.path, nil)
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d", i, test.expectedCode, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), test.expectedContains) {
+			t.Errorf("Test %d: expected body to contain %q, got %q", i, test.expectedContains, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// InvocationTransform adapts the body of a call to, or the response of, a
+// function, e.g. to base64 wrap binary payloads for functions expecting
+// text. Errors returned fail the call with a 400.
+type InvocationTransform func(c *gin.Context, body io.Reader) (io.Reader, error)
+
+// WithInvocationTransform transforms the bodies of the calls to functions,
+// from /invoke and /t, before they are passed to the agent. It runs on the
+// hot path of every call, so it must be cheap, and should stream rather than
+// read the whole body.
+func WithInvocationTransform(t InvocationTransform) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.invocationTransform = t
+		return nil
+	}
+}
+
+// WithInvocationResponseTransform transforms the responses of sync calls to
+// functions, from /invoke and /t, before they are written to the client. As
+// WithInvocationTransform, it runs on the hot path of every call.
+func WithInvocationResponseTransform(t InvocationTransform) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.responseTransform = t
+		return nil
+	}
+}
+
+// transformRequestBody applies the invocation transform to the body of the
+// request of c, if any
+func (s *Server) transformRequestBody(c *gin.Context) error {
+	if s.invocationTransform == nil {
+		return nil
+	}
+
+	req := c.Request
+	var body io.Reader = http.NoBody
+	if req.Body != nil {
+		body = req.Body
+	}
+	transformed, err := s.invocationTransform(c, body)
+	if err != nil {
+		return models.NewAPIError(http.StatusBadRequest, err)
+	}
+
+	req.Body = transformedBody{Reader: transformed, orig: req.Body}
+	// the length of the transformed body is unknown, and the original one
+	// can't be replayed anymore
+	req.GetBody = nil
+	req.ContentLength = -1
+	req.Header.Del("Content-Length")
+	return nil
+}
+
+// transformResponseBody applies the invocation response transform to the
+// buffered response of a function, if any
+func (s *Server) transformResponseBody(c *gin.Context, buf *bytes.Buffer) error {
+	if s.responseTransform == nil {
+		return nil
+	}
+
+	transformed, err := s.responseTransform(c, bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		return models.NewAPIError(http.StatusBadRequest, err)
+	}
+	b, err := ioutil.ReadAll(transformed)
+	if err != nil {
+		return models.NewAPIError(http.StatusBadRequest, err)
+	}
+	buf.Reset()
+	buf.Write(b)
+	return nil
+}
+
+// transformedBody closes the original body of a request once read
+type transformedBody struct {
+	io.Reader
+	orig io.ReadCloser
+}
+
+func (b transformedBody) Close() error {
+	if b.orig == nil {
+		return nil
+	}
+	return b.orig.Close()
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestInvocationTransform(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}}
+	trigger := &models.Trigger{ID: "trigger_id", Name: "mytrigger", AppID: app.ID, FnID: fn.ID, Type: models.TriggerTypeHTTP, Source: "/src"}
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn}, []*models.Trigger{trigger})
+
+	var received string
+	requestTransform := func(c *gin.Context, body io.Reader) (io.Reader, error) {
+		b, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		received = string(b)
+		if received == "bad" {
+			return nil, errors.New("bad body")
+		}
+		return strings.NewReader(strings.ToUpper(received)), nil
+	}
+	responseTransform := func(c *gin.Context, body io.Reader) (io.Reader, error) {
+		return io.MultiReader(strings.NewReader("wrapped:"), body), nil
+	}
+
+	for i, test := range []struct {
+		path         string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{"/invoke/fn_id", "hello", http.StatusOK, "wrapped:"},
+		{"/t/myapp/src", "hello", http.StatusOK, "wrapped:"},
+		{"/invoke/fn_id", "bad", http.StatusBadRequest, ""},
+	} {
+		received = ""
+		submitted := false
+		rnr := new(agent.MockAgent)
+		rnr.On("AddCallListener", mock.Anything)
+		rnr.On("GetCall", mock.Anything)
+		rnr.On("Submit", mock.Anything).Run(func(args mock.Arguments) {
+			submitted = true
+		}).Return(nil)
+
+		srv := testServer(ds, rnr, ServerTypeFull, WithInvocationTransform(requestTransform), WithInvocationResponseTransform(responseTransform))
+
+		req := createRequest(t, http.MethodPost, test.path, strings.NewReader(test.body))
+		_, rec := routerRequest2(t, srv.Router, req)
+
+		if received != test.body {
+			t.Errorf("Test %d: expected the transform to read %q, got %q", i, test.body, received)
+		}
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d", i, test.expectedCode, rec.Code)
+		}
+		if submitted != (test.expectedCode == http.StatusOK) {
+			t.Errorf("Test %d: expected submitted %v, got %v", i, test.expectedCode == http.StatusOK, submitted)
+		}
+		if test.expectedCode == http.StatusOK && rec.Body.String() != test.expectedBody {
+			t.Errorf("Test %d: expected body %q, got %q", i, test.expectedBody, rec.Body.String())
+		}
+	}
+}
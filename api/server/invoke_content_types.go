@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+// WithInvokeAllowedContentTypes only lets calls with one of the given content
+// types reach functions on the trigger and invoke endpoints, e.g.
+// "application/json" or "text/*". Apps may replace the list with the
+// models.AppInvokeContentTypesAnnotation annotation. An empty list allows any
+// content type.
+func WithInvokeAllowedContentTypes(types []string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.invokeContentTypes = types
+		if len(types) > 0 {
+			logrus.Infof("Invoke content types restricted to: %s", types)
+		}
+		return nil
+	}
+}
+
+// invokeContentTypesFor returns the content types allowed to be sent to the
+// functions of app: those of the app annotation if it has one, else those of
+// the server.
+func (s *Server) invokeContentTypesFor(app *models.App) []string {
+	if list, err := app.Annotations.GetString(models.AppInvokeContentTypesAnnotation); err == nil {
+		return splitList(list)
+	}
+	return s.invokeContentTypes
+}
+
+// checkInvokeContentType rejects a call to a function of app with a content
+// type which is not allowed, with a 415. Only the Content-Type header is
+// checked, never the body, so the check is made before the body is read.
+// Calls without a body need no content type, those with a body but no
+// Content-Type are checked as application/octet-stream.
+func (s *Server) checkInvokeContentType(req *http.Request, app *models.App) error {
+	allowed := s.invokeContentTypesFor(app)
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	ct := req.Header.Get("Content-Type")
+	if ct == "" {
+		if req.ContentLength == 0 {
+			return nil
+		}
+		ct = "application/octet-stream"
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return models.NewAPIError(http.StatusUnsupportedMediaType, fmt.Errorf("Invalid Content-Type %q", ct))
+	}
+
+	for _, a := range allowed {
+		a = strings.ToLower(a)
+		if a == mediaType || a == "*/*" ||
+			(strings.HasSuffix(a, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(a, "*"))) {
+			return nil
+		}
+	}
+	return models.NewAPIError(http.StatusUnsupportedMediaType,
+		fmt.Errorf("Content-Type %s may not be sent to the functions of this app", mediaType))
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestInvokeAllowedContentTypes(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	openApp := &models.App{ID: "open_app_id", Name: "openapp"}
+	openApp.Annotations, _ = openApp.Annotations.With(models.AppInvokeContentTypesAnnotation, "*/*")
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}}
+	openFn := &models.Fn{ID: "open_fn_id", Name: "openfn", AppID: openApp.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}}
+	trigger := &models.Trigger{ID: "trigger_id", Name: "mytrigger", AppID: app.ID, FnID: fn.ID, Type: models.TriggerTypeHTTP, Source: "/src"}
+	ds := datastore.NewMockInit([]*models.App{app, openApp}, []*models.Fn{fn, openFn}, []*models.Trigger{trigger})
+
+	for i, test := range []struct {
+		path         string
+		contentType  string
+		body         string
+		expectedCode int
+	}{
+		{"/invoke/fn_id", "application/json", "{}", http.StatusOK},
+		{"/invoke/fn_id", "Application/JSON; charset=utf-8", "{}", http.StatusOK},
+		{"/invoke/fn_id", "text/plain", "hi", http.StatusOK},
+		{"/invoke/fn_id", "multipart/form-data; boundary=x", "--x--", http.StatusUnsupportedMediaType},
+		{"/invoke/fn_id", "not a type", "{}", http.StatusUnsupportedMediaType},
+		// no body needs no content type, a body without one is an octet stream
+		{"/invoke/fn_id", "", "", http.StatusOK},
+		{"/invoke/fn_id", "", "{}", http.StatusUnsupportedMediaType},
+		{"/t/myapp/src", "application/json", "{}", http.StatusOK},
+		{"/t/myapp/src", "multipart/form-data; boundary=x", "--x--", http.StatusUnsupportedMediaType},
+		// the app annotation replaces the server list
+		{"/invoke/open_fn_id", "multipart/form-data; boundary=x", "--x--", http.StatusOK},
+	} {
+		submitted := false
+		rnr := new(agent.MockAgent)
+		rnr.On("AddCallListener", mock.Anything)
+		rnr.On("GetCall", mock.Anything)
+		rnr.On("Submit", mock.Anything).Run(func(args mock.Arguments) {
+			submitted = true
+		}).Return(nil)
+
+		srv := testServer(ds, rnr, ServerTypeFull, WithInvokeAllowedContentTypes([]string{"application/json", "text/*"}))
+
+		req := createRequest(t, http.MethodPost, test.path, strings.NewReader(test.body))
+		if test.contentType != "" {
+			req.Header.Set("Content-Type", test.contentType)
+		}
+		_, rec := routerRequest2(t, srv.Router, req)
+
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+		if submitted != (test.expectedCode == http.StatusOK) {
+			t.Errorf("Test %d: expected submitted %v, got %v", i, test.expectedCode == http.StatusOK, submitted)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+const invokeCORSMaxAge = 12 * 60 * 60 // seconds
+
+var invokeCORSMethods = strings.Join([]string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD"}, ", ")
+
+// invokeCORSFromEnv returns the CORS origins and headers of the trigger and
+// invoke endpoints: those of FN_INVOKE_CORS_ORIGINS and FN_INVOKE_CORS_HEADERS
+// if the origins are set, else those of the API, which these endpoints shared
+// before they had their own.
+func invokeCORSFromEnv() (origins, headers []string) {
+	_, set := os.LookupEnv(EnvInvokeCORSOrigins)
+	_, setFile := os.LookupEnv(EnvInvokeCORSOrigins + "_FILE")
+	if set || setFile {
+		return splitList(getEnv(EnvInvokeCORSOrigins, "")), splitList(getEnv(EnvInvokeCORSHeaders, ""))
+	}
+	return splitList(getEnv(EnvAPICORSOrigins, "")), splitList(getEnv(EnvAPICORSHeaders, ""))
+}
+
+func isInvokePath(path string) bool {
+	return path == "/t" || strings.HasPrefix(path, "/t/") || strings.HasPrefix(path, "/invoke/")
+}
+
+// triggerCORSApp looks up the app of a trigger endpoint request
+func (s *Server) triggerCORSApp(c *gin.Context) (*models.App, error) {
+	ctx := c.Request.Context()
+	appID, err := s.lbReadAccess.GetAppID(ctx, c.Param(api.AppName))
+	if err != nil {
+		return nil, err
+	}
+	return s.lbReadAccess.GetAppByID(ctx, appID)
+}
+
+// fnInvokeCORSApp looks up the app of an invoke endpoint request
+func (s *Server) fnInvokeCORSApp(c *gin.Context) (*models.App, error) {
+	ctx := c.Request.Context()
+	fn, err := s.lbReadAccess.GetFnByID(ctx, c.Param(api.FnID))
+	if err != nil {
+		return nil, err
+	}
+	return s.lbReadAccess.GetAppByID(ctx, fn.AppID)
+}
+
+// invokeCORSOriginsFor returns the origins allowed to invoke the functions of
+// app: those of the app annotation if it has one, else those of the server.
+func (s *Server) invokeCORSOriginsFor(app *models.App) []string {
+	if list, err := app.Annotations.GetString(models.AppInvokeCORSOriginsAnnotation); err == nil {
+		return splitList(list)
+	}
+	return s.invokeCORSOrigins
+}
+
+// invokeCORSWrap handles CORS for browser requests to the trigger and invoke
+// endpoints. Preflight requests are answered here, without reaching the agent.
+// Requests without an Origin, or to apps without any allowed origins, are
+// passed through untouched; those from an origin that is not allowed are
+// rejected.
+func (s *Server) invokeCORSWrap(getApp func(*gin.Context) (*models.App, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		app, err := getApp(c)
+		if err != nil {
+			// let the handler report it
+			c.Next()
+			return
+		}
+
+		origins := s.invokeCORSOriginsFor(app)
+		if len(origins) == 0 {
+			c.Next()
+			return
+		}
+
+		allowOrigin := ""
+		for _, o := range origins {
+			if o == "*" {
+				allowOrigin = "*"
+				break
+			}
+			if o == origin {
+				allowOrigin = origin
+				break
+			}
+		}
+		if allowOrigin == "" {
+			common.Logger(c.Request.Context()).WithField("origin", origin).Debug("invoke CORS origin not allowed")
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Set("Access-Control-Allow-Origin", allowOrigin)
+		if allowOrigin != "*" {
+			h.Add("Vary", "Origin")
+		}
+
+		if c.Request.Method != http.MethodOptions || c.GetHeader("Access-Control-Request-Method") == "" {
+			h.Set("Access-Control-Expose-Headers", "Fn-Call-Id")
+			c.Next()
+			return
+		}
+
+		h.Set("Access-Control-Allow-Methods", invokeCORSMethods)
+		if len(s.invokeCORSHeaders) > 0 {
+			h.Set("Access-Control-Allow-Headers", strings.Join(s.invokeCORSHeaders, ", "))
+		} else if reqHeaders := c.GetHeader("Access-Control-Request-Headers"); reqHeaders != "" {
+			h.Set("Access-Control-Allow-Headers", reqHeaders)
+		}
+		h.Set("Access-Control-Max-Age", strconv.Itoa(invokeCORSMaxAge))
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestInvokeCORS(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	os.Setenv(EnvAPICORSOrigins, "http://api.example.com")
+	defer os.Unsetenv(EnvAPICORSOrigins)
+
+	annotations, err := models.EmptyAnnotations().With(models.AppInvokeCORSOriginsAnnotation, "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	openApp := &models.App{ID: "open_app_id", Name: "openapp", Annotations: annotations}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils"}
+	openFn := &models.Fn{ID: "open_fn_id", Name: "openfn", AppID: openApp.ID, Image: "fnproject/fn-test-utils"}
+	trigger := &models.Trigger{ID: "trigger_id", Name: "trigger", AppID: openApp.ID, FnID: openFn.ID, Type: "http", Source: "/src"}
+	ds := datastore.NewMockInit([]*models.App{app, openApp}, []*models.Fn{fn, openFn}, []*models.Trigger{trigger})
+
+	for i, test := range []struct {
+		method        string
+		path          string
+		origin        string
+		requestMethod string
+		expectedCode  int
+		expectedAllow string
+		expectSubmit  bool
+	}{
+		{http.MethodPost, "/invoke/fn_id", "", "", http.StatusOK, "", true},
+		{http.MethodPost, "/invoke/fn_id", "http://example.com", "", http.StatusOK, "http://example.com", true},
+		{http.MethodPost, "/invoke/fn_id", "http://evil.com", "", http.StatusForbidden, "", false},
+		{http.MethodOptions, "/invoke/fn_id", "http://example.com", http.MethodPost, http.StatusNoContent, "http://example.com", false},
+		{http.MethodOptions, "/invoke/fn_id", "http://evil.com", http.MethodPost, http.StatusForbidden, "", false},
+		{http.MethodOptions, "/invoke/fn_id", "http://example.com", "", http.StatusMethodNotAllowed, "http://example.com", false},
+		// the API origins don't apply to invoke endpoints
+		{http.MethodOptions, "/invoke/fn_id", "http://api.example.com", http.MethodPost, http.StatusForbidden, "", false},
+		// the app annotation replaces the server origins
+		{http.MethodOptions, "/invoke/open_fn_id", "http://evil.com", http.MethodPost, http.StatusNoContent, "*", false},
+		{http.MethodOptions, "/t/openapp/src", "http://evil.com", http.MethodPost, http.StatusNoContent, "*", false},
+		{http.MethodPost, "/t/openapp/src", "http://evil.com", "", http.StatusOK, "*", true},
+	} {
+		submitted := false
+		rnr := new(agent.MockAgent)
+		rnr.On("AddCallListener", mock.Anything)
+		rnr.On("GetCall", mock.Anything)
+		rnr.On("Submit", mock.Anything).Run(func(args mock.Arguments) {
+			submitted = true
+		}).Return(nil)
+
+		srv := testServer(ds, rnr, ServerTypeFull, WithInvokeCORS([]string{"http://example.com"}, nil))
+
+		req := createRequest(t, test.method, test.path, strings.NewReader(`{}`))
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+		if test.requestMethod != "" {
+			req.Header.Set("Access-Control-Request-Method", test.requestMethod)
+		}
+		_, rec := routerRequest2(t, srv.Router, req)
+
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+		if allow := rec.Header().Get("Access-Control-Allow-Origin"); allow != test.expectedAllow {
+			t.Errorf("Test %d: expected allowed origin `%s` but was `%s`", i, test.expectedAllow, allow)
+		}
+		if submitted != test.expectSubmit {
+			t.Errorf("Test %d: expected call submitted %v but was %v", i, test.expectSubmit, submitted)
+		}
+		if test.expectedCode == http.StatusNoContent && rec.Header().Get("Access-Control-Allow-Methods") == "" {
+			t.Errorf("Test %d: expected preflight response to have allowed methods", i)
+		}
+	}
+}
+
+func TestInvokeCORSFromEnv(t *testing.T) {
+	for _, key := range []string{EnvAPICORSOrigins, EnvAPICORSHeaders, EnvInvokeCORSOrigins, EnvInvokeCORSHeaders} {
+		if v, ok := os.LookupEnv(key); ok {
+			defer os.Setenv(key, v)
+		} else {
+			defer os.Unsetenv(key)
+		}
+		os.Unsetenv(key)
+	}
+	os.Setenv(EnvAPICORSOrigins, "http://api.example.com")
+	os.Setenv(EnvAPICORSHeaders, "X-Api")
+
+	// the API settings apply unless the invoke endpoints have their own
+	origins, headers := invokeCORSFromEnv()
+	if len(origins) != 1 || origins[0] != "http://api.example.com" || len(headers) != 1 || headers[0] != "X-Api" {
+		t.Fatalf("expected the API CORS settings, got %v %v", origins, headers)
+	}
+
+	os.Setenv(EnvInvokeCORSOrigins, "http://example.com")
+	origins, headers = invokeCORSFromEnv()
+	if len(origins) != 1 || origins[0] != "http://example.com" || len(headers) != 0 {
+		t.Fatalf("expected the invoke CORS settings, got %v %v", origins, headers)
+	}
+
+	// set empty, no origins are allowed
+	os.Setenv(EnvInvokeCORSOrigins, "")
+	if origins, _ = invokeCORSFromEnv(); len(origins) != 0 {
+		t.Fatalf("expected no origins, got %v", origins)
+	}
+}
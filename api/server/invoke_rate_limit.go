@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"golang.org/x/time/rate"
+)
+
+var (
+	invokeThrottledMeasure = common.MakeMeasure("server/invoke_throttled", "Number of calls rejected by the rate limit of their app", stats.UnitDimensionless)
+)
+
+// RegisterInvokeRateLimitViews registers the views for calls rejected by the
+// rate limit of their app
+func RegisterInvokeRateLimitViews(tagKeys []string) {
+	tags := []tag.Key{agent.AppIDMetricKey}
+	for _, key := range tagKeys {
+		if key != agent.AppIDMetricKey.Name() {
+			tags = append(tags, common.MakeKey(key))
+		}
+	}
+
+	err := view.Register(common.CreateViewWithTags(invokeThrottledMeasure, view.Count(), tags))
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot register view")
+	}
+}
+
+// WithInvokeRateLimit limits the calls per second the functions of each app
+// may be invoked at on the trigger and invoke endpoints to defaultRate, over
+// which calls are rejected with a 429 and a Retry-After header. Operators may
+// replace the limit of an app with the models.AppRateLimitAnnotation
+// annotation, see WithOperatorAnnotationKeys. A rate of 0 or less means no
+// limit.
+//
+// The limit is enforced by each node on its own, so a cluster of n nodes
+// behind a load balancer lets up to n times the rate through for an app.
+// Divide the cluster wide quota of apps by the number of nodes to set it.
+func WithInvokeRateLimit(defaultRate float64) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.invokeRateLimits = newInvokeRateLimits(defaultRate)
+		return nil
+	}
+}
+
+// invokeRateLimits holds a token bucket for each app with a rate limit
+type invokeRateLimits struct {
+	defaultRate float64
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func newInvokeRateLimits(defaultRate float64) *invokeRateLimits {
+	return &invokeRateLimits{defaultRate: defaultRate, limiters: make(map[string]*rate.Limiter)}
+}
+
+// rateFor returns the calls per second the functions of app may be invoked
+// at, or 0 for no limit
+func (l *invokeRateLimits) rateFor(app *models.App) float64 {
+	if v, ok := app.Annotations.Get(models.AppRateLimitAnnotation); ok {
+		var r float64
+		if json.Unmarshal(v, &r) == nil {
+			return r
+		}
+	}
+	return l.defaultRate
+}
+
+// limiter returns the token bucket of an app, with the burst of a second of calls
+func (l *invokeRateLimits) limiter(appID string, r float64) *rate.Limiter {
+	burst := int(math.Ceil(r))
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lim, ok := l.limiters[appID]
+	if !ok || lim.Limit() != rate.Limit(r) {
+		// new, or the annotation of the app changed
+		lim = rate.NewLimiter(rate.Limit(r), burst)
+		l.limiters[appID] = lim
+	}
+	return lim
+}
+
+// checkInvokeRate rejects a call to a function of app with a 429 if the app
+// is over its rate limit, setting the Retry-After header of the response to
+// when a call will be allowed again
+func (s *Server) checkInvokeRate(ctx context.Context, w http.ResponseWriter, app *models.App) error {
+	r := s.invokeRateLimits.rateFor(app)
+	if r <= 0 {
+		return nil
+	}
+
+	res := s.invokeRateLimits.limiter(app.ID, r).Reserve()
+	delay := res.Delay()
+	if delay == 0 {
+		return nil
+	}
+	// don't hold on to a token for a call which is rejected
+	res.Cancel()
+
+	ctx, err := tag.New(ctx, tag.Upsert(agent.AppIDMetricKey, app.ID))
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	stats.Record(ctx, invokeThrottledMeasure.M(1))
+
+	retryAfter := int((delay + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	return models.ErrInvokeRateLimited
+}
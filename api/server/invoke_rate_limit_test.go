@@ -0,0 +1,78 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestInvokeRateLimit(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	limited, err := models.Annotations{}.With(models.AppRateLimitAnnotation, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlimited, err := models.Annotations{}.With(models.AppRateLimitAnnotation, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &models.App{ID: "app_id", Name: "myapp", Annotations: limited}
+	defaultApp := &models.App{ID: "default_app_id", Name: "defaultapp"}
+	unlimitedApp := &models.App{ID: "unlimited_app_id", Name: "unlimitedapp", Annotations: unlimited}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}}
+	defaultFn := &models.Fn{ID: "default_fn_id", Name: "myfn", AppID: defaultApp.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}}
+	unlimitedFn := &models.Fn{ID: "unlimited_fn_id", Name: "myfn", AppID: unlimitedApp.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}}
+	trigger := &models.Trigger{ID: "trigger_id", Name: "mytrigger", AppID: app.ID, FnID: fn.ID, Type: models.TriggerTypeHTTP, Source: "/src"}
+	ds := datastore.NewMockInit([]*models.App{app, defaultApp, unlimitedApp}, []*models.Fn{fn, defaultFn, unlimitedFn}, []*models.Trigger{trigger})
+
+	rnr := new(agent.MockAgent)
+	rnr.On("AddCallListener", mock.Anything)
+	rnr.On("GetCall", mock.Anything)
+	rnr.On("Submit", mock.Anything).Return(nil)
+
+	srv := testServer(ds, rnr, ServerTypeFull, WithInvokeRateLimit(2))
+
+	for i, test := range []struct {
+		path         string
+		expectedCode int
+		retryAfter   string
+	}{
+		// a call every 2 seconds, shared by the trigger and invoke endpoints
+		{"/invoke/fn_id", http.StatusOK, ""},
+		{"/t/myapp/src", http.StatusTooManyRequests, "2"},
+		{"/invoke/fn_id", http.StatusTooManyRequests, "2"},
+		// the server default of 2 calls per second
+		{"/invoke/default_fn_id", http.StatusOK, ""},
+		{"/invoke/default_fn_id", http.StatusOK, ""},
+		{"/invoke/default_fn_id", http.StatusTooManyRequests, "1"},
+		{"/invoke/unlimited_fn_id", http.StatusOK, ""},
+		{"/invoke/unlimited_fn_id", http.StatusOK, ""},
+		{"/invoke/unlimited_fn_id", http.StatusOK, ""},
+	} {
+		req := createRequest(t, http.MethodPost, test.path, strings.NewReader(`{}`))
+		_, rec := routerRequest2(t, srv.Router, req)
+
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get("Retry-After"); got != test.retryAfter {
+			t.Errorf("Test %d: expected Retry-After %q, got %q", i, test.retryAfter, got)
+		}
+		if test.expectedCode == http.StatusTooManyRequests {
+			if resp := getErrorResponse(t, rec); resp == nil || resp.Message != models.ErrInvokeRateLimited.Error() {
+				t.Errorf("Test %d: expected error `%s`, got %s", i, models.ErrInvokeRateLimited, rec.Body.String())
+			}
+		}
+	}
+}
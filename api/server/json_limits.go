@@ -0,0 +1,79 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DefaultJSONMaxDepth is the default limit of nesting of the JSON bodies of management requests
+	DefaultJSONMaxDepth = 64
+	// DefaultJSONMaxTokens is the default limit of tokens of the JSON bodies of management requests
+	DefaultJSONMaxTokens = 100000
+)
+
+// WithJSONLimits limits the nesting depth and the number of tokens, each
+// value and object key, of the JSON bodies of the requests creating and
+// updating apps, fns, triggers and API tokens, so that a deeply nested or
+// huge body can't exhaust the CPU or memory of the server as it is decoded.
+// Bodies are checked while streaming through their tokens, before any value
+// is decoded, and rejected with a 400. A limit of 0 disables it.
+func WithJSONLimits(maxDepth, maxTokens int) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.jsonMaxDepth = maxDepth
+		s.jsonMaxTokens = maxTokens
+		return nil
+	}
+}
+
+// bindJSON is c.BindJSON, checking that the body is within the JSON limits
+// of the server first
+func (s *Server) bindJSON(c *gin.Context, obj interface{}) error {
+	if (s.jsonMaxDepth > 0 || s.jsonMaxTokens > 0) && c.Request.Body != nil {
+		body, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			return err
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		if err := checkJSONLimits(body, s.jsonMaxDepth, s.jsonMaxTokens); err != nil {
+			return err
+		}
+	}
+	return c.BindJSON(obj)
+}
+
+// checkJSONLimits returns ErrJSONTooDeep or ErrJSONTooManyTokens if body
+// exceeds either limit. Invalid JSON is left to the decoding to reject.
+func checkJSONLimits(body []byte, maxDepth, maxTokens int) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	var depth, tokens int
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			// io.EOF, or invalid JSON
+			return nil
+		}
+
+		tokens++
+		if maxTokens > 0 && tokens > maxTokens {
+			return models.ErrJSONTooManyTokens
+		}
+		if delim, ok := tok.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				depth++
+				if maxDepth > 0 && depth > maxDepth {
+					return models.ErrJSONTooDeep
+				}
+			default:
+				depth--
+			}
+		}
+	}
+}
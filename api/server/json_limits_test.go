@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestCheckJSONLimits(t *testing.T) {
+	for i, test := range []struct {
+		body      string
+		maxDepth  int
+		maxTokens int
+		expected  error
+	}{
+		{`{"name":"app","annotations":{"a":[1,2]}}`, 3, 0, nil},
+		{`{"name":"app","annotations":{"a":[1,2]}}`, 2, 0, models.ErrJSONTooDeep},
+		{`{"name":"app","annotations":{"a":[1,2]}}`, 0, 12, nil},
+		{`{"name":"app","annotations":{"a":[1,2]}}`, 0, 11, models.ErrJSONTooManyTokens},
+		{strings.Repeat("[", 100000), 64, 0, models.ErrJSONTooDeep},
+		{`{"name":`, 3, 3, nil},
+	} {
+		if err := checkJSONLimits([]byte(test.body), test.maxDepth, test.maxTokens); err != test.expected {
+			t.Errorf("Test %d: expected %v, got %v", i, test.expected, err)
+		}
+	}
+}
+
+func TestJSONLimits(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	srv := testServer(datastore.NewMock(), nil, ServerTypeAPI, WithJSONLimits(3, 100))
+	for i, test := range []struct {
+		body         string
+		expectedCode int
+		expectedErr  string
+	}{
+		{`{"name":"app","annotations":{"a":{"b":1}}}`, http.StatusOK, ""},
+		{`{"name":"app2","annotations":{"a":{"b":[1]}}}`, http.StatusBadRequest, models.ErrorCodeJSONTooDeep},
+		{`{"name":"app3","config":{` + strings.Repeat(`"k":"v",`, 100) + `"k":"v"}}`, http.StatusBadRequest, models.ErrorCodeJSONTooManyTokens},
+		{`{"name":`, http.StatusBadRequest, models.ErrorCodeInvalidJSON},
+	} {
+		_, rec := routerRequest(t, srv.Router, http.MethodPost, "/v2/apps", strings.NewReader(test.body))
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+		if test.expectedErr != "" && !strings.Contains(rec.Body.String(), test.expectedErr) {
+			t.Errorf("Test %d: expected error %s, got %s", i, test.expectedErr, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+var (
+	serviceKey = common.MakeKey("service")
+
+	connectionsMeasure = common.MakeMeasure("server/connections", "Number of currently accepted connections", stats.UnitDimensionless)
+)
+
+// RegisterConnectionViews registers the views for connections accepted by the web and admin servers
+func RegisterConnectionViews(tagKeys []string) {
+	tags := []tag.Key{serviceKey}
+	for _, key := range tagKeys {
+		if key != serviceKey.Name() {
+			tags = append(tags, common.MakeKey(key))
+		}
+	}
+
+	err := view.Register(common.CreateViewWithTags(connectionsMeasure, view.LastValue(), tags))
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot register view")
+	}
+}
+
+// serve listens on srv.Addr and serves srv, accepting at most s.maxConnections
+// connections at a time if set.
+func (s *Server) serve(service string, srv *http.Server) error {
+	addr := srv.Addr
+	if addr == "" {
+		if srv.TLSConfig != nil {
+			addr = ":https"
+		} else {
+			addr = ":http"
+		}
+	}
+
+	ln, err := s.listen(context.Background(), addr)
+	if err != nil {
+		return err
+	}
+	ln = newLimitListener(ln, s.maxConnections, service)
+
+	if srv.TLSConfig != nil {
+		return srv.ServeTLS(ln, "", "")
+	}
+	return srv.Serve(ln)
+}
+
+// limitListener counts the connections accepted by a listener and, if max is
+// set, stops accepting once max connections are open. Connections over the
+// limit are left in the listen backlog until an open connection is closed,
+// rather than being accepted and exhausting file descriptors.
+type limitListener struct {
+	net.Listener
+	ctx   context.Context
+	sem   chan struct{}
+	done  chan struct{}
+	once  sync.Once
+	count int64
+}
+
+func newLimitListener(l net.Listener, max int, service string) *limitListener {
+	ctx, err := tag.New(context.Background(), tag.Insert(serviceKey, service))
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot create tag for connection metrics")
+	}
+
+	ll := &limitListener{
+		Listener: l,
+		ctx:      ctx,
+		done:     make(chan struct{}),
+	}
+	if max > 0 {
+		ll.sem = make(chan struct{}, max)
+	}
+	return ll
+}
+
+func (l *limitListener) acquire() bool {
+	if l.sem == nil {
+		return true
+	}
+	select {
+	case <-l.done:
+		return false
+	case l.sem <- struct{}{}:
+		return true
+	}
+}
+
+func (l *limitListener) release() {
+	if l.sem != nil {
+		<-l.sem
+	}
+}
+
+// Accept implements net.Listener
+func (l *limitListener) Accept() (net.Conn, error) {
+	if !l.acquire() {
+		// the listener is closed, let the underlying listener say so
+		return l.Listener.Accept()
+	}
+
+	c, err := l.Listener.Accept()
+	if err != nil {
+		l.release()
+		return nil, err
+	}
+
+	stats.Record(l.ctx, connectionsMeasure.M(atomic.AddInt64(&l.count, 1)))
+	return &limitListenerConn{Conn: c, l: l}, nil
+}
+
+// Close implements net.Listener
+func (l *limitListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+type limitListenerConn struct {
+	net.Conn
+	l    *limitListener
+	once sync.Once
+}
+
+// Close implements net.Conn
+func (c *limitListenerConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() {
+		stats.Record(c.l.ctx, connectionsMeasure.M(atomic.AddInt64(&c.l.count, -1)))
+		c.l.release()
+	})
+	return err
+}
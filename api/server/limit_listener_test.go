@@ -0,0 +1,75 @@
+package server
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimitListener(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln := newLimitListener(inner, 1, WebServer)
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				close(accepted)
+				return
+			}
+			accepted <- c
+		}
+	}()
+
+	for i := 0; i < 2; i++ {
+		c, err := net.Dial("tcp", inner.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+	}
+
+	first := <-accepted
+	select {
+	case <-accepted:
+		t.Fatal("expected second connection to wait while the first is open")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	first.Close()
+	first.Close() // must only release once
+	select {
+	case c := <-accepted:
+		c.Close()
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected second connection to be accepted after the first closed")
+	}
+
+	if n := atomic.LoadInt64(&ln.count); n != 0 {
+		t.Fatalf("expected no open connections, got %d", n)
+	}
+
+	// a listener waiting at its limit must still stop when closed
+	c, err := net.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	<-accepted
+
+	ln.Close()
+	select {
+	case _, ok := <-accepted:
+		if ok {
+			t.Fatal("expected no connection to be accepted after close")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected accept to return after close")
+	}
+}
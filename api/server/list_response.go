@@ -0,0 +1,60 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	listStart      = []byte(`{"items":[`)
+	listSep        = []byte(",")
+	listEnd        = []byte("]")
+	listNextCursor = []byte(`,"next_cursor":`)
+	listClose      = []byte("}")
+)
+
+// writeListResponse writes a page of a list as {"items":[...],"next_cursor":"..."},
+// encoding each of the n items returned by item straight to the response rather
+// than marshalling the whole page in memory first. Once the response is started
+// errors can no longer be reported to the client, they are only logged.
+func writeListResponse(c *gin.Context, nextCursor string, n int, item func(i int) interface{}) {
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	enc := json.NewEncoder(w)
+	err := func() error {
+		if _, err := w.Write(listStart); err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				if _, err := w.Write(listSep); err != nil {
+					return err
+				}
+			}
+			if err := enc.Encode(item(i)); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(listEnd); err != nil {
+			return err
+		}
+		if nextCursor != "" {
+			if _, err := w.Write(listNextCursor); err != nil {
+				return err
+			}
+			if err := enc.Encode(nextCursor); err != nil {
+				return err
+			}
+		}
+		_, err := w.Write(listClose)
+		return err
+	}()
+	if err != nil {
+		common.Logger(c.Request.Context()).WithError(err).Error("error writing list response")
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func testAppList(n int, nextCursor string) *models.AppList {
+	apps := &models.AppList{NextCursor: nextCursor, Items: []*models.App{}}
+	for i := 0; i < n; i++ {
+		apps.Items = append(apps.Items, &models.App{
+			ID:     fmt.Sprintf("app%d", i),
+			Name:   fmt.Sprintf("app-%d", i),
+			Config: models.Config{"FOO": "<bar>"},
+		})
+	}
+	return apps
+}
+
+func TestWriteListResponse(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	for i, apps := range []*models.AppList{
+		testAppList(0, ""),
+		testAppList(1, ""),
+		testAppList(3, "cursor"),
+	} {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(http.MethodGet, "/v2/apps", nil)
+
+		writeListResponse(c, apps.NextCursor, len(apps.Items), func(i int) interface{} { return apps.Items[i] })
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Test %d: expected status code 200 but was %d", i, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+			t.Errorf("Test %d: expected json content type, got %s", i, ct)
+		}
+
+		var got models.AppList
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("Test %d: invalid json %s: %v", i, rec.Body.String(), err)
+		}
+		if !reflect.DeepEqual(&got, apps) {
+			t.Errorf("Test %d: expected %+v, got %+v", i, apps, &got)
+		}
+	}
+}
+
+// discardResponseWriter drops what is written to it, so that benchmarks only
+// count the allocations made to write a response.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardResponseWriter) WriteHeader(int)             {}
+
+// BenchmarkListResponse compares marshalling a full page of apps at once, as
+// c.JSON does, to streaming it with writeListResponse.
+func BenchmarkListResponse(b *testing.B) {
+	gin.SetMode(gin.ReleaseMode)
+	apps := testAppList(100, "cursor")
+
+	b.Run("marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			c, _ := gin.CreateTestContext(&discardResponseWriter{header: http.Header{}})
+			c.JSON(http.StatusOK, apps)
+		}
+	})
+
+	b.Run("stream", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			c, _ := gin.CreateTestContext(&discardResponseWriter{header: http.Header{}})
+			c.Request = httptest.NewRequest(http.MethodGet, "/v2/apps", nil)
+			writeListResponse(c, apps.NextCursor, len(apps.Items), func(i int) interface{} { return apps.Items[i] })
+		}
+	})
+}
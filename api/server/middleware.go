@@ -22,6 +22,12 @@ type middlewareController struct {
 func (s *Server) apiMiddlewareWrapper() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// fmt.Println("api middleware")
+		if s.apiRootToken != "" && !s.authorizeAPIToken(c) {
+			return
+		}
+		if s.tenantResolver != nil && !s.scopeTenant(c) {
+			return
+		}
 		s.runMiddleware(c, s.apiMiddlewares)
 	}
 }
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// handleMigrationStatus reports the schema version of the datastore against
+// the one this binary expects, with 409 while a migration is pending or failed
+// part way through, so that deploys can wait for the schema before rolling
+// out. Nodes without a datastore, or with one without migrations, return 501.
+// It is served with the admin token only, see WithAdminToken.
+func (s *Server) handleMigrationStatus(c *gin.Context) {
+	if s.datastore == nil {
+		handleErrorResponse(c, models.ErrMigrationsUnsupported)
+		return
+	}
+
+	status, err := models.GetMigrationStatus(c.Request.Context(), s.datastore)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	code := http.StatusOK
+	if !status.InSync() {
+		code = http.StatusConflict
+	}
+	c.JSON(code, status)
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+)
+
+type migratingDatastore struct {
+	models.Datastore
+	status models.MigrationStatus
+}
+
+func (ds *migratingDatastore) MigrationStatus(ctx context.Context) (*models.MigrationStatus, error) {
+	status := ds.status
+	return &status, nil
+}
+
+func TestMigrationStatus(t *testing.T) {
+	for i, test := range []struct {
+		status       models.MigrationStatus
+		expectedCode int
+	}{
+		{models.MigrationStatus{Current: 24, Expected: 24}, http.StatusOK},
+		{models.MigrationStatus{Current: 23, Expected: 24}, http.StatusConflict},
+		{models.MigrationStatus{Current: 24, Expected: 24, Dirty: true}, http.StatusConflict},
+	} {
+		ds := &migratingDatastore{Datastore: datastore.NewMock(), status: test.status}
+		srv := testServer(ds, nil, ServerTypeAPI, WithAdminToken("s3cret"))
+
+		_, rec := routerRequest2(t, srv.AdminRouter, migrationsRequest(t, "s3cret"))
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d", i, test.expectedCode, rec.Code)
+			continue
+		}
+		var status models.MigrationStatus
+		if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+			t.Fatal(err)
+		}
+		if status != test.status {
+			t.Errorf("Test %d: expected %+v, got %+v", i, test.status, status)
+		}
+	}
+
+	// the memory datastore has no migrations
+	srv := testServer(datastore.NewMock(), nil, ServerTypeAPI, WithAdminToken("s3cret"))
+	_, rec := routerRequest2(t, srv.AdminRouter, migrationsRequest(t, "s3cret"))
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("expected status code %d but was %d", http.StatusNotImplemented, rec.Code)
+	}
+
+	_, rec = routerRequest2(t, srv.AdminRouter, migrationsRequest(t, ""))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status code %d without the admin token but was %d", http.StatusUnauthorized, rec.Code)
+	}
+
+	// not served without an admin token
+	srv = testServer(datastore.NewMock(), nil, ServerTypeAPI)
+	_, rec = routerRequest2(t, srv.AdminRouter, migrationsRequest(t, ""))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status code %d but was %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func migrationsRequest(t *testing.T, token string) *http.Request {
+	req := createRequest(t, http.MethodGet, "/debug/migrations", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
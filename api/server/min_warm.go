@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+// how often the fns of the apps with models.AppMinWarmAnnotation are listed,
+// so that changes to the annotation and new fns are picked up
+const minWarmSyncInterval = time.Minute
+
+const minWarmPerPage = 100
+
+// syncMinWarm lists the fns to keep containers warm for, and sets them on the
+// agent, at startup then every minute, until ctx is done
+func (s *Server) syncMinWarm(ctx context.Context, warmer agent.MinWarmer) {
+	ticker := time.NewTicker(minWarmSyncInterval)
+	defer ticker.Stop()
+	for {
+		targets, err := s.minWarmTargets(ctx)
+		if err != nil {
+			common.Logger(ctx).WithError(err).Error("cannot list the fns to keep warm")
+		} else {
+			warmer.SetMinWarm(targets)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Server) minWarmTargets(ctx context.Context) ([]agent.WarmTarget, error) {
+	var targets []agent.WarmTarget
+	filter := &models.AppFilter{PerPage: minWarmPerPage}
+	for {
+		apps, err := s.datastore.GetApps(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, app := range apps.Items {
+			min, err := models.AppMinWarm(app)
+			if err != nil {
+				common.Logger(ctx).WithError(err).WithField("app_id", app.ID).Warn("ignoring invalid min warm annotation")
+				continue
+			}
+			if min == 0 {
+				continue
+			}
+			fns, err := s.appFns(ctx, app.ID)
+			if err != nil {
+				return nil, err
+			}
+			for _, fn := range fns {
+				targets = append(targets, agent.WarmTarget{App: app, Fn: fn, Min: min})
+			}
+			common.Logger(ctx).WithFields(logrus.Fields{"app_id": app.ID, "fns": len(fns), "min_warm": min}).Debug("keeping containers warm for app")
+		}
+		if apps.NextCursor == "" {
+			return targets, nil
+		}
+		filter.Cursor = apps.NextCursor
+	}
+}
+
+func (s *Server) appFns(ctx context.Context, appID string) ([]*models.Fn, error) {
+	var all []*models.Fn
+	filter := &models.FnFilter{AppID: appID, PerPage: minWarmPerPage}
+	for {
+		fns, err := s.datastore.GetFns(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, fns.Items...)
+		if fns.NextCursor == "" {
+			return all, nil
+		}
+		filter.Cursor = fns.NextCursor
+	}
+}
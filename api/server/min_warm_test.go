@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+)
+
+type minWarmRecorder chan []agent.WarmTarget
+
+func (r minWarmRecorder) SetMinWarm(targets []agent.WarmTarget) { r <- targets }
+
+func TestSyncMinWarm(t *testing.T) {
+	warm := &models.App{ID: "app_id", Name: "myapp"}
+	warm.Annotations, _ = warm.Annotations.With(models.AppMinWarmAnnotation, 2)
+	invalid := &models.App{ID: "app_id2", Name: "myapp2"}
+	invalid.Annotations, _ = invalid.Annotations.With(models.AppMinWarmAnnotation, "2")
+	cold := &models.App{ID: "app_id3", Name: "myapp3"}
+
+	ds := datastore.NewMockInit(
+		[]*models.App{warm, invalid, cold},
+		[]*models.Fn{
+			{ID: "fn_id", AppID: warm.ID, Name: "myfn", Image: "fnproject/fn-test-utils"},
+			{ID: "fn_id2", AppID: warm.ID, Name: "myfn2", Image: "fnproject/fn-test-utils"},
+			{ID: "fn_id3", AppID: invalid.ID, Name: "myfn", Image: "fnproject/fn-test-utils"},
+			{ID: "fn_id4", AppID: cold.ID, Name: "myfn", Image: "fnproject/fn-test-utils"},
+		},
+	)
+	srv := &Server{datastore: ds}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rec := make(minWarmRecorder, 1)
+	go srv.syncMinWarm(ctx, rec)
+
+	select {
+	case targets := <-rec:
+		if len(targets) != 2 {
+			t.Fatalf("expected the 2 fns of the annotated app to be kept warm, got %+v", targets)
+		}
+		for _, target := range targets {
+			if target.App.ID != warm.ID || target.Min != 2 {
+				t.Errorf("expected 2 containers of %s to be kept warm, got %+v", warm.ID, target)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the fns to keep warm to be set at startup")
+	}
+}
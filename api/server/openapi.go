@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WithPublicLBURL sets the public URL of the load balancer clients reach
+// the server through, which the OpenAPI spec served at /v2/openapi.json
+// names as its server. The spec names the base path of the server otherwise,
+// relative to where it is fetched from.
+func WithPublicLBURL(publicURL string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.publicLBURL = publicURL
+		return nil
+	}
+}
+
+// openAPIHandler serves the OpenAPI spec of the /v2 API, naming serverURL
+// as its server
+func openAPIHandler(serverURL string) gin.HandlerFunc {
+	spec, err := openAPIDocument(serverURL)
+	return func(c *gin.Context) {
+		if err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+		c.Data(http.StatusOK, "application/json", spec)
+	}
+}
+
+func openAPIDocument(serverURL string) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(openAPISpec), &doc); err != nil {
+		return nil, err
+	}
+	doc["servers"] = []map[string]string{{"url": serverURL}}
+	return json.Marshal(doc)
+}
+
+func (s *Server) openAPIServerURL() string {
+	if s.publicLBURL != "" {
+		return strings.TrimSuffix(s.publicLBURL, "/") + "/v2"
+	}
+	return s.basePath + "/v2"
+}
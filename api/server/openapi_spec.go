@@ -0,0 +1,1613 @@
+package server
+
+// openAPISpec is the OpenAPI 3 document of the /v2 API, served at
+// /v2/openapi.json with the server URL of the serving node. Update it along
+// with the routes it describes, TestOpenAPISpec fails if a route is missing.
+const openAPISpec = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "fn",
+    "description": "The open source serverless platform.",
+    "version": "2.0.0"
+  },
+  "servers": [
+    {
+      "url": "/v2"
+    }
+  ],
+  "security": [
+    {},
+    {
+      "bearerAuth": []
+    }
+  ],
+  "tags": [
+    {
+      "name": "Apps"
+    },
+    {
+      "name": "Fns"
+    },
+    {
+      "name": "Triggers"
+    },
+    {
+      "name": "Calls"
+    },
+    {
+      "name": "Tokens"
+    },
+    {
+      "name": "Version"
+    }
+  ],
+  "paths": {
+    "/version": {
+      "get": {
+        "operationId": "GetVersion",
+        "summary": "Get the version and build metadata of the server",
+        "tags": [
+          "Version"
+        ],
+        "responses": {
+          "200": {
+            "description": "Version of the server.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Version"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/apps": {
+      "get": {
+        "operationId": "ListApps",
+        "summary": "Get a list of applications",
+        "tags": [
+          "Apps"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/cursor"
+          },
+          {
+            "$ref": "#/components/parameters/perPage"
+          },
+          {
+            "name": "name",
+            "in": "query",
+            "description": "Application name to filter by.",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "A page of applications, in alphabetical order.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/AppList"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "post": {
+        "operationId": "CreateApp",
+        "summary": "Create an application",
+        "tags": [
+          "Apps"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/dryRun"
+          }
+        ],
+        "requestBody": {
+          "description": "Application to create.",
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/App"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The created application.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/App"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "409": {
+            "$ref": "#/components/responses/Conflict"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/apps/{app_id}": {
+      "parameters": [
+        {
+          "$ref": "#/components/parameters/appID"
+        }
+      ],
+      "get": {
+        "operationId": "GetApp",
+        "summary": "Get an application",
+        "tags": [
+          "Apps"
+        ],
+        "responses": {
+          "200": {
+            "description": "The application.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/App"
+                }
+              }
+            }
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "put": {
+        "operationId": "UpdateApp",
+        "summary": "Update an application",
+        "tags": [
+          "Apps"
+        ],
+        "requestBody": {
+          "description": "Fields of the application to update. Config and annotation keys set to an empty value are removed.",
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/App"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The updated application.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/App"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "delete": {
+        "operationId": "DeleteApp",
+        "summary": "Delete an application",
+        "tags": [
+          "Apps"
+        ],
+        "responses": {
+          "204": {
+            "description": "The application and its functions and triggers were deleted."
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/apps/{app_id}/stats": {
+      "parameters": [
+        {
+          "$ref": "#/components/parameters/appID"
+        }
+      ],
+      "get": {
+        "operationId": "GetAppStats",
+        "summary": "Get call statistics of an application",
+        "tags": [
+          "Apps"
+        ],
+        "parameters": [
+          {
+            "name": "window",
+            "in": "query",
+            "description": "Window of the statistics.",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "1m",
+                "5m",
+                "15m",
+                "1h",
+                "6h",
+                "24h"
+              ],
+              "default": "5m"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Statistics of the calls of the application on this server.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/AppStats"
+                }
+              }
+            }
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "501": {
+            "$ref": "#/components/responses/Unsupported"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/fns": {
+      "get": {
+        "operationId": "ListFns",
+        "summary": "Get a list of functions",
+        "tags": [
+          "Fns"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/cursor"
+          },
+          {
+            "$ref": "#/components/parameters/perPage"
+          },
+          {
+            "$ref": "#/components/parameters/appIDQuery"
+          },
+          {
+            "name": "name",
+            "in": "query",
+            "description": "Function name to filter by.",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "A page of functions, in alphabetical order.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/FnList"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "post": {
+        "operationId": "CreateFn",
+        "summary": "Create a function",
+        "tags": [
+          "Fns"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/dryRun"
+          }
+        ],
+        "requestBody": {
+          "description": "Function to create.",
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/Fn"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The created function.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Fn"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "409": {
+            "$ref": "#/components/responses/Conflict"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/fns/validate-image": {
+      "post": {
+        "operationId": "ValidateImage",
+        "summary": "Check that a function image can be pulled",
+        "tags": [
+          "Fns"
+        ],
+        "requestBody": {
+          "description": "Image to validate.",
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/ImageValidation"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "What the server found out about the image.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ImageInfo"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "501": {
+            "$ref": "#/components/responses/Unsupported"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/fns/{fn_id}": {
+      "parameters": [
+        {
+          "$ref": "#/components/parameters/fnID"
+        }
+      ],
+      "get": {
+        "operationId": "GetFn",
+        "summary": "Get a function",
+        "tags": [
+          "Fns"
+        ],
+        "responses": {
+          "200": {
+            "description": "The function.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Fn"
+                }
+              }
+            }
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "put": {
+        "operationId": "UpdateFn",
+        "summary": "Update a function",
+        "tags": [
+          "Fns"
+        ],
+        "requestBody": {
+          "description": "Fields of the function to update. Config and annotation keys set to an empty value are removed.",
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/Fn"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The updated function.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Fn"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "delete": {
+        "operationId": "DeleteFn",
+        "summary": "Delete a function",
+        "tags": [
+          "Fns"
+        ],
+        "responses": {
+          "204": {
+            "description": "The function and its triggers were deleted."
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/fns/{fn_id}/calls": {
+      "parameters": [
+        {
+          "$ref": "#/components/parameters/fnID"
+        }
+      ],
+      "get": {
+        "operationId": "ListCalls",
+        "summary": "Get a list of the calls of a function",
+        "tags": [
+          "Calls"
+        ],
+        "deprecated": true,
+        "responses": {
+          "410": {
+            "description": "Calls are no longer stored, this endpoint is gone."
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/fns/{fn_id}/calls/{call_id}": {
+      "parameters": [
+        {
+          "$ref": "#/components/parameters/fnID"
+        },
+        {
+          "$ref": "#/components/parameters/callID"
+        }
+      ],
+      "get": {
+        "operationId": "GetCall",
+        "summary": "Get a call",
+        "tags": [
+          "Calls"
+        ],
+        "deprecated": true,
+        "responses": {
+          "410": {
+            "description": "Calls are no longer stored, this endpoint is gone."
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/fns/{fn_id}/calls/{call_id}/log": {
+      "parameters": [
+        {
+          "$ref": "#/components/parameters/fnID"
+        },
+        {
+          "$ref": "#/components/parameters/callID"
+        }
+      ],
+      "get": {
+        "operationId": "GetCallLog",
+        "summary": "Get the log of a call",
+        "tags": [
+          "Calls"
+        ],
+        "deprecated": true,
+        "responses": {
+          "410": {
+            "description": "Calls are no longer stored, this endpoint is gone."
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/fns/{fn_id}/runtime": {
+      "parameters": [
+        {
+          "$ref": "#/components/parameters/fnID"
+        }
+      ],
+      "get": {
+        "operationId": "GetFnRuntime",
+        "summary": "Get the live containers and calls of a function on this server",
+        "tags": [
+          "Fns"
+        ],
+        "security": [
+          {
+            "adminAuth": []
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The containers and calls of the function on this server.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/FnRuntime"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "The admin token is missing or invalid.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Error"
+                }
+              }
+            }
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "501": {
+            "$ref": "#/components/responses/Unsupported"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/triggers": {
+      "get": {
+        "operationId": "ListTriggers",
+        "summary": "Get a list of triggers",
+        "tags": [
+          "Triggers"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/cursor"
+          },
+          {
+            "$ref": "#/components/parameters/perPage"
+          },
+          {
+            "$ref": "#/components/parameters/appIDQuery"
+          },
+          {
+            "name": "fn_id",
+            "in": "query",
+            "description": "Function ID to filter by.",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "name",
+            "in": "query",
+            "description": "Trigger name to filter by.",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "A page of triggers, in alphabetical order.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TriggerList"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "post": {
+        "operationId": "CreateTrigger",
+        "summary": "Create a trigger",
+        "tags": [
+          "Triggers"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/dryRun"
+          }
+        ],
+        "requestBody": {
+          "description": "Trigger to create.",
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/Trigger"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The created trigger.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Trigger"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "409": {
+            "$ref": "#/components/responses/Conflict"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/triggers/{trigger_id}": {
+      "parameters": [
+        {
+          "$ref": "#/components/parameters/triggerID"
+        }
+      ],
+      "get": {
+        "operationId": "GetTrigger",
+        "summary": "Get a trigger",
+        "tags": [
+          "Triggers"
+        ],
+        "responses": {
+          "200": {
+            "description": "The trigger.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Trigger"
+                }
+              }
+            }
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "put": {
+        "operationId": "UpdateTrigger",
+        "summary": "Update a trigger",
+        "tags": [
+          "Triggers"
+        ],
+        "requestBody": {
+          "description": "Fields of the trigger to update. Annotation keys set to an empty value are removed.",
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/Trigger"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The updated trigger.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Trigger"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "delete": {
+        "operationId": "DeleteTrigger",
+        "summary": "Delete a trigger",
+        "tags": [
+          "Triggers"
+        ],
+        "responses": {
+          "204": {
+            "description": "The trigger was deleted."
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/tokens": {
+      "get": {
+        "operationId": "ListTokens",
+        "summary": "Get a list of API tokens",
+        "tags": [
+          "Tokens"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/cursor"
+          },
+          {
+            "$ref": "#/components/parameters/perPage"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "A page of API tokens, ordered by ID.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TokenList"
+                }
+              }
+            }
+          },
+          "501": {
+            "$ref": "#/components/responses/Unsupported"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "post": {
+        "operationId": "CreateToken",
+        "summary": "Create an API token",
+        "tags": [
+          "Tokens"
+        ],
+        "requestBody": {
+          "description": "Token to create.",
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/Token"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The created token, with its secret.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Token"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "501": {
+            "$ref": "#/components/responses/Unsupported"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/tokens/{token_id}": {
+      "parameters": [
+        {
+          "$ref": "#/components/parameters/tokenID"
+        }
+      ],
+      "get": {
+        "operationId": "GetToken",
+        "summary": "Get an API token",
+        "tags": [
+          "Tokens"
+        ],
+        "responses": {
+          "200": {
+            "description": "The token, without its secret.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Token"
+                }
+              }
+            }
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "delete": {
+        "operationId": "DeleteToken",
+        "summary": "Delete an API token",
+        "tags": [
+          "Tokens"
+        ],
+        "responses": {
+          "204": {
+            "description": "The token was deleted."
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "App": {
+        "type": "object",
+        "required": [
+          "name"
+        ],
+        "properties": {
+          "id": {
+            "type": "string",
+            "description": "Opaque, unique Application ID.",
+            "readOnly": true
+          },
+          "name": {
+            "type": "string",
+            "description": "Unique name of the application, of letters, digits, - and _ only."
+          },
+          "tenant_id": {
+            "type": "string",
+            "description": "Tenant the application belongs to, when the server scopes the API to tenants.",
+            "readOnly": true
+          },
+          "config": {
+            "type": "object",
+            "additionalProperties": {
+              "type": "string"
+            },
+            "description": "Configuration of the application and its functions, passed to functions as environment variables."
+          },
+          "annotations": {
+            "type": "object",
+            "additionalProperties": {},
+            "description": "Annotations of the application, keys must not exceed 128 bytes and the serialized values 512 bytes."
+          },
+          "syslog_url": {
+            "type": "string",
+            "nullable": true,
+            "description": "Comma separated list of syslog URLs to send the logs of the functions to, e.g. tls://logs.example.com:6514."
+          },
+          "created_at": {
+            "type": "string",
+            "format": "date-time",
+            "description": "Time the application was created, in UTC.",
+            "readOnly": true
+          },
+          "updated_at": {
+            "type": "string",
+            "format": "date-time",
+            "description": "Time the application was last updated, in UTC.",
+            "readOnly": true
+          }
+        }
+      },
+      "AppList": {
+        "type": "object",
+        "required": [
+          "items"
+        ],
+        "properties": {
+          "next_cursor": {
+            "type": "string",
+            "description": "Cursor to send with subsequent request to receive the next page, if non-empty.",
+            "readOnly": true
+          },
+          "items": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/App"
+            }
+          }
+        }
+      },
+      "Fn": {
+        "type": "object",
+        "required": [
+          "name",
+          "app_id",
+          "image"
+        ],
+        "properties": {
+          "id": {
+            "type": "string",
+            "description": "Opaque, unique Function ID.",
+            "readOnly": true
+          },
+          "name": {
+            "type": "string",
+            "description": "Name of the function, unique within its application."
+          },
+          "app_id": {
+            "type": "string",
+            "description": "ID of the application of the function."
+          },
+          "image": {
+            "type": "string",
+            "description": "Container image of the function, e.g. fnproject/hello."
+          },
+          "memory": {
+            "type": "integer",
+            "format": "int64",
+            "description": "Maximum memory of the function, in MiB."
+          },
+          "timeout": {
+            "type": "integer",
+            "format": "int32",
+            "description": "Timeout of the calls of the function, in seconds."
+          },
+          "idle_timeout": {
+            "type": "integer",
+            "format": "int32",
+            "description": "Time an idle container of the function is kept for, in seconds."
+          },
+          "config": {
+            "type": "object",
+            "additionalProperties": {
+              "type": "string"
+            },
+            "description": "Configuration of the function, passed to functions as environment variables."
+          },
+          "annotations": {
+            "type": "object",
+            "additionalProperties": {},
+            "description": "Annotations of the function, keys must not exceed 128 bytes and the serialized values 512 bytes."
+          },
+          "created_at": {
+            "type": "string",
+            "format": "date-time",
+            "description": "Time the function was created, in UTC.",
+            "readOnly": true
+          },
+          "updated_at": {
+            "type": "string",
+            "format": "date-time",
+            "description": "Time the function was last updated, in UTC.",
+            "readOnly": true
+          }
+        }
+      },
+      "FnList": {
+        "type": "object",
+        "required": [
+          "items"
+        ],
+        "properties": {
+          "next_cursor": {
+            "type": "string",
+            "description": "Cursor to send with subsequent request to receive the next page, if non-empty.",
+            "readOnly": true
+          },
+          "items": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Fn"
+            }
+          }
+        }
+      },
+      "Trigger": {
+        "type": "object",
+        "required": [
+          "name",
+          "app_id",
+          "fn_id",
+          "type",
+          "source"
+        ],
+        "properties": {
+          "id": {
+            "type": "string",
+            "description": "Opaque, unique Trigger ID.",
+            "readOnly": true
+          },
+          "name": {
+            "type": "string",
+            "description": "Name of the trigger, unique within its application."
+          },
+          "app_id": {
+            "type": "string",
+            "description": "ID of the application of the trigger."
+          },
+          "fn_id": {
+            "type": "string",
+            "description": "ID of the function the trigger invokes."
+          },
+          "type": {
+            "type": "string",
+            "enum": [
+              "http"
+            ],
+            "description": "Type of the trigger."
+          },
+          "source": {
+            "type": "string",
+            "description": "Path of the trigger within its application, e.g. /hello."
+          },
+          "annotations": {
+            "type": "object",
+            "additionalProperties": {},
+            "description": "Annotations of the trigger, keys must not exceed 128 bytes and the serialized values 512 bytes."
+          },
+          "created_at": {
+            "type": "string",
+            "format": "date-time",
+            "description": "Time the trigger was created, in UTC.",
+            "readOnly": true
+          },
+          "updated_at": {
+            "type": "string",
+            "format": "date-time",
+            "description": "Time the trigger was last updated, in UTC.",
+            "readOnly": true
+          }
+        }
+      },
+      "TriggerList": {
+        "type": "object",
+        "required": [
+          "items"
+        ],
+        "properties": {
+          "next_cursor": {
+            "type": "string",
+            "description": "Cursor to send with subsequent request to receive the next page, if non-empty.",
+            "readOnly": true
+          },
+          "items": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Trigger"
+            }
+          }
+        }
+      },
+      "Token": {
+        "type": "object",
+        "required": [
+          "name",
+          "permission"
+        ],
+        "properties": {
+          "id": {
+            "type": "string",
+            "description": "Opaque, unique API Token ID.",
+            "readOnly": true
+          },
+          "name": {
+            "type": "string",
+            "description": "Name of the token, to tell what it is used for."
+          },
+          "app_ids": {
+            "type": "array",
+            "description": "IDs of the applications the token is scoped to. Tokens with no applications reach every resource.",
+            "items": {
+              "type": "string"
+            }
+          },
+          "permission": {
+            "type": "string",
+            "enum": [
+              "read",
+              "write"
+            ],
+            "description": "Whether the token may only read resources, or also change them."
+          },
+          "token": {
+            "type": "string",
+            "description": "Secret of the token, sent as Authorization: Bearer <token>. Only returned when the token is created.",
+            "readOnly": true
+          },
+          "created_at": {
+            "type": "string",
+            "format": "date-time",
+            "description": "Time the token was created, in UTC.",
+            "readOnly": true
+          }
+        }
+      },
+      "TokenList": {
+        "type": "object",
+        "required": [
+          "items"
+        ],
+        "properties": {
+          "next_cursor": {
+            "type": "string",
+            "description": "Cursor to send with subsequent request to receive the next page, if non-empty.",
+            "readOnly": true
+          },
+          "items": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Token"
+            }
+          }
+        }
+      },
+      "ImageValidation": {
+        "type": "object",
+        "required": [
+          "image"
+        ],
+        "properties": {
+          "image": {
+            "type": "string",
+            "description": "Container image to validate."
+          }
+        }
+      },
+      "ImageInfo": {
+        "type": "object",
+        "properties": {
+          "image": {
+            "type": "string",
+            "description": "Image that was validated.",
+            "readOnly": true
+          },
+          "reachable": {
+            "type": "boolean",
+            "description": "Whether the image could be resolved.",
+            "readOnly": true
+          },
+          "local": {
+            "type": "boolean",
+            "description": "Whether the image is already present on the server.",
+            "readOnly": true
+          },
+          "digest": {
+            "type": "string",
+            "description": "Digest of the image manifest, if known.",
+            "readOnly": true
+          },
+          "size": {
+            "type": "integer",
+            "format": "int64",
+            "description": "Size of the image, in bytes.",
+            "readOnly": true
+          },
+          "entrypoint": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            },
+            "readOnly": true
+          },
+          "cmd": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            },
+            "readOnly": true
+          },
+          "error": {
+            "type": "string",
+            "description": "Why the image could not be resolved, if it is not reachable.",
+            "readOnly": true
+          }
+        }
+      },
+      "FnRuntime": {
+        "type": "object",
+        "properties": {
+          "fn_id": {
+            "type": "string",
+            "readOnly": true
+          },
+          "node_type": {
+            "type": "string",
+            "description": "Type of the server the statistics are those of.",
+            "readOnly": true
+          },
+          "warm_containers": {
+            "type": "integer",
+            "format": "int64",
+            "description": "Number of idle, or paused, hot containers, ready for a call.",
+            "readOnly": true
+          },
+          "busy_containers": {
+            "type": "integer",
+            "format": "int64",
+            "description": "Number of hot containers executing a call.",
+            "readOnly": true
+          },
+          "starting_containers": {
+            "type": "integer",
+            "format": "int64",
+            "description": "Number of containers starting, or waiting for the resources to.",
+            "readOnly": true
+          },
+          "in_flight": {
+            "type": "integer",
+            "format": "int64",
+            "description": "Number of calls executing in a container.",
+            "readOnly": true
+          },
+          "queued": {
+            "type": "integer",
+            "format": "int64",
+            "description": "Number of calls waiting for a container.",
+            "readOnly": true
+          },
+          "cold_starts_per_minute": {
+            "type": "number",
+            "description": "Rate containers started at over the cold start window.",
+            "readOnly": true
+          },
+          "cold_start_window": {
+            "type": "string",
+            "description": "Window the cold start rate is measured over.",
+            "readOnly": true
+          }
+        }
+      },
+      "AppStats": {
+        "type": "object",
+        "properties": {
+          "app_id": {
+            "type": "string",
+            "readOnly": true
+          },
+          "window": {
+            "type": "string",
+            "description": "Window of the statistics.",
+            "readOnly": true
+          },
+          "calls": {
+            "type": "integer",
+            "format": "int64",
+            "description": "Number of calls completed within the window.",
+            "readOnly": true
+          },
+          "by_status": {
+            "type": "object",
+            "additionalProperties": {
+              "type": "integer",
+              "format": "int64"
+            },
+            "description": "Number of calls completed within the window by status, e.g. success, error or timeout.",
+            "readOnly": true
+          },
+          "error_rate": {
+            "type": "number",
+            "description": "Ratio of the calls which did not succeed, from 0 to 1.",
+            "readOnly": true
+          },
+          "latency_p50_ms": {
+            "type": "number",
+            "description": "Estimated median latency of the calls, in milliseconds.",
+            "readOnly": true
+          },
+          "latency_p95_ms": {
+            "type": "number",
+            "description": "Estimated 95th percentile latency of the calls, in milliseconds.",
+            "readOnly": true
+          }
+        }
+      },
+      "Version": {
+        "type": "object",
+        "properties": {
+          "version": {
+            "type": "string",
+            "description": "Version of the server.",
+            "readOnly": true
+          },
+          "api_versions": {
+            "type": "array",
+            "description": "Versions of the API the server serves.",
+            "items": {
+              "type": "string"
+            },
+            "readOnly": true
+          },
+          "git_commit": {
+            "type": "string",
+            "description": "Commit the server was built from, if known.",
+            "readOnly": true
+          },
+          "build_date": {
+            "type": "string",
+            "format": "date-time",
+            "description": "When the server was built, if known.",
+            "readOnly": true
+          },
+          "go_version": {
+            "type": "string",
+            "description": "Version of Go the server was built with.",
+            "readOnly": true
+          }
+        }
+      },
+      "Error": {
+        "type": "object",
+        "required": [
+          "message"
+        ],
+        "properties": {
+          "code": {
+            "type": "string",
+            "description": "Stable, machine readable code of the error, e.g. app_not_found. Unlike the message, codes do not change between releases.",
+            "readOnly": true
+          },
+          "message": {
+            "type": "string",
+            "description": "Human readable description of the error.",
+            "readOnly": true
+          }
+        }
+      }
+    },
+    "responses": {
+      "BadRequest": {
+        "description": "The request is invalid.",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/Error"
+            }
+          }
+        }
+      },
+      "NotFound": {
+        "description": "The resource does not exist.",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/Error"
+            }
+          }
+        }
+      },
+      "Conflict": {
+        "description": "A resource with the same name already exists.",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/Error"
+            }
+          }
+        }
+      },
+      "Unsupported": {
+        "description": "The server does not support this endpoint, e.g. as it does not run calls.",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/Error"
+            }
+          }
+        }
+      },
+      "Error": {
+        "description": "An error.",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/Error"
+            }
+          }
+        }
+      }
+    },
+    "parameters": {
+      "appID": {
+        "name": "app_id",
+        "in": "path",
+        "description": "Opaque, unique Application ID.",
+        "required": true,
+        "schema": {
+          "type": "string"
+        }
+      },
+      "fnID": {
+        "name": "fn_id",
+        "in": "path",
+        "description": "Opaque, unique Function ID.",
+        "required": true,
+        "schema": {
+          "type": "string"
+        }
+      },
+      "triggerID": {
+        "name": "trigger_id",
+        "in": "path",
+        "description": "Opaque, unique Trigger ID.",
+        "required": true,
+        "schema": {
+          "type": "string"
+        }
+      },
+      "tokenID": {
+        "name": "token_id",
+        "in": "path",
+        "description": "Opaque, unique API Token ID.",
+        "required": true,
+        "schema": {
+          "type": "string"
+        }
+      },
+      "callID": {
+        "name": "call_id",
+        "in": "path",
+        "description": "Opaque, unique Call ID.",
+        "required": true,
+        "schema": {
+          "type": "string"
+        }
+      },
+      "appIDQuery": {
+        "name": "app_id",
+        "in": "query",
+        "description": "ID of the application to list the resources of.",
+        "required": true,
+        "schema": {
+          "type": "string"
+        }
+      },
+      "cursor": {
+        "name": "cursor",
+        "in": "query",
+        "description": "Cursor from the next_cursor of a previous response, to get the next page.",
+        "required": false,
+        "schema": {
+          "type": "string"
+        }
+      },
+      "perPage": {
+        "name": "per_page",
+        "in": "query",
+        "description": "Number of results to return, defaults to 30, at most 100.",
+        "required": false,
+        "schema": {
+          "type": "integer",
+          "minimum": 1,
+          "maximum": 100
+        }
+      },
+      "dryRun": {
+        "name": "dry_run",
+        "in": "query",
+        "description": "Validate the resource, including conflicts with existing resources, and return it without creating it.",
+        "required": false,
+        "schema": {
+          "type": "boolean"
+        }
+      }
+    },
+    "securitySchemes": {
+      "bearerAuth": {
+        "type": "http",
+        "scheme": "bearer",
+        "description": "API token, required when the server has API token auth enabled."
+      },
+      "adminAuth": {
+        "type": "http",
+        "scheme": "bearer",
+        "description": "Admin token of the server, FN_ADMIN_TOKEN."
+      }
+    }
+  }
+}
+`
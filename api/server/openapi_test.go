@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+)
+
+var openAPIMethods = map[string]bool{"get": true, "put": true, "post": true, "delete": true, "patch": true, "head": true, "options": true}
+
+// checkOpenAPIRefs checks that every $ref of v points into doc
+func checkOpenAPIRefs(t *testing.T, doc map[string]interface{}, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if k == "$ref" {
+				ref, _ := e.(string)
+				if !strings.HasPrefix(ref, "#/") {
+					t.Errorf("expected a local $ref, got %q", ref)
+					continue
+				}
+				var target interface{} = doc
+				for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+					m, _ := target.(map[string]interface{})
+					target = m[part]
+				}
+				if target == nil {
+					t.Errorf("$ref %q does not resolve", ref)
+				}
+				continue
+			}
+			checkOpenAPIRefs(t, doc, e)
+		}
+	case []interface{}:
+		for _, e := range v {
+			checkOpenAPIRefs(t, doc, e)
+		}
+	}
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(openAPISpec), &doc); err != nil {
+		t.Fatalf("expected the spec to be JSON: %v", err)
+	}
+	if v, _ := doc["openapi"].(string); !strings.HasPrefix(v, "3.") {
+		t.Fatalf("expected an OpenAPI 3 document, got version %q", v)
+	}
+	info, _ := doc["info"].(map[string]interface{})
+	if info["title"] == nil || info["version"] == nil {
+		t.Fatalf("expected the spec to have a title and version, got %v", info)
+	}
+	checkOpenAPIRefs(t, doc, doc)
+
+	paths, _ := doc["paths"].(map[string]interface{})
+	pathParam := regexp.MustCompile(`\{([a-z_]+)\}`)
+	for path, item := range paths {
+		item, _ := item.(map[string]interface{})
+		declared := map[string]bool{}
+		params, _ := item["parameters"].([]interface{})
+		for _, p := range params {
+			ref, _ := p.(map[string]interface{})["$ref"].(string)
+			p := doc["components"].(map[string]interface{})["parameters"].(map[string]interface{})[strings.TrimPrefix(ref, "#/components/parameters/")]
+			declared[p.(map[string]interface{})["name"].(string)] = true
+		}
+		for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+			if !declared[m[1]] {
+				t.Errorf("expected path parameter %s of %s to be declared", m[1], path)
+			}
+		}
+		for method, o := range item {
+			if !openAPIMethods[method] {
+				continue
+			}
+			o, _ := o.(map[string]interface{})
+			if responses, _ := o["responses"].(map[string]interface{}); len(responses) == 0 {
+				t.Errorf("expected %s %s to have responses", method, path)
+			}
+			if o["operationId"] == nil {
+				t.Errorf("expected %s %s to have an operationId", method, path)
+			}
+		}
+	}
+
+	// every route of the /v2 API is described
+	srv := testServer(datastore.NewMock(), nil, ServerTypeAPI, WithAdminToken("s3cret"))
+	for _, route := range srv.Router.Routes() {
+		if !strings.HasPrefix(route.Path, "/v2/") || strings.HasPrefix(route.Path, "/v2/runner/") || route.Path == "/v2/openapi.json" {
+			continue
+		}
+		path := regexp.MustCompile(`:([a-z_]+)`).ReplaceAllString(strings.TrimPrefix(route.Path, "/v2"), "{$1}")
+		item, _ := paths[path].(map[string]interface{})
+		if item[strings.ToLower(route.Method)] == nil {
+			t.Errorf("expected the spec to describe %s %s", route.Method, path)
+		}
+	}
+}
+
+func TestOpenAPIEndpoint(t *testing.T) {
+	for i, test := range []struct {
+		opts              []Option
+		path              string
+		expectedServerURL string
+	}{
+		{nil, "/v2/openapi.json", "/v2"},
+		{[]Option{WithBasePath("/fn")}, "/fn/v2/openapi.json", "/fn/v2"},
+		{[]Option{WithPublicLBURL("https://fn.example.com/")}, "/v2/openapi.json", "https://fn.example.com/v2"},
+	} {
+		srv := testServer(datastore.NewMock(), nil, ServerTypeAPI, test.opts...)
+		_, rec := routerRequest(t, srv.Router, http.MethodGet, test.path, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Test %d: expected status code 200 but was %d: %s", i, rec.Code, rec.Body.String())
+		}
+
+		var doc struct {
+			OpenAPI string `json:"openapi"`
+			Servers []struct {
+				URL string `json:"url"`
+			} `json:"servers"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+			t.Fatal(err)
+		}
+		if len(doc.Servers) != 1 || doc.Servers[0].URL != test.expectedServerURL {
+			t.Errorf("Test %d: expected server %s, got %+v", i, test.expectedServerURL, doc.Servers)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"bufio"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	openMetricsType        = "application/openmetrics-text"
+	openMetricsContentType = openMetricsType + "; version=1.0.0; charset=utf-8"
+)
+
+var (
+	openMetricsHelpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	openMetricsLabelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// handleMetrics serves the metrics of the prometheus registry in the
+// OpenMetrics format if the client accepts it, e.g. for prometheus to scrape
+// exemplars, and otherwise leaves the format to the prometheus exporter.
+func (s *Server) handleMetrics(c *gin.Context) {
+	// gin negotiates its first offer when there is no Accept header
+	if s.promRegistry == nil || c.GetHeader("Accept") == "" || c.NegotiateFormat(openMetricsType) != openMetricsType {
+		s.promExporter.ServeHTTP(c.Writer, c.Request)
+		return
+	}
+
+	mfs, err := s.promRegistry.Gather()
+	if err != nil {
+		// like the prometheus handler, serve what could be gathered
+		logrus.WithError(err).Error("error gathering metrics")
+		if len(mfs) == 0 {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	c.Header("Content-Type", openMetricsContentType)
+	c.Status(http.StatusOK)
+	w := bufio.NewWriter(c.Writer)
+	writeOpenMetrics(w, mfs)
+	w.Flush()
+}
+
+// writeOpenMetrics writes metric families in the OpenMetrics text format
+func writeOpenMetrics(w *bufio.Writer, mfs []*dto.MetricFamily) {
+	for _, mf := range mfs {
+		name := mf.GetName()
+		typ := "unknown"
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			// the family of a counter is named without the _total suffix of its samples
+			typ, name = "counter", strings.TrimSuffix(name, "_total")
+		case dto.MetricType_GAUGE:
+			typ = "gauge"
+		case dto.MetricType_SUMMARY:
+			typ = "summary"
+		case dto.MetricType_HISTOGRAM:
+			typ = "histogram"
+		}
+
+		w.WriteString("# TYPE " + name + " " + typ + "\n")
+		if mf.Help != nil {
+			w.WriteString("# HELP " + name + " " + openMetricsHelpReplacer.Replace(mf.GetHelp()) + "\n")
+		}
+
+		for _, m := range mf.Metric {
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				writeOpenMetricsSample(w, name+"_total", m, "", "", m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				writeOpenMetricsSample(w, name, m, "", "", m.GetGauge().GetValue())
+			case dto.MetricType_SUMMARY:
+				for _, q := range m.GetSummary().Quantile {
+					writeOpenMetricsSample(w, name, m, "quantile", formatOpenMetricsFloat(q.GetQuantile()), q.GetValue())
+				}
+				writeOpenMetricsSample(w, name+"_sum", m, "", "", m.GetSummary().GetSampleSum())
+				writeOpenMetricsSample(w, name+"_count", m, "", "", float64(m.GetSummary().GetSampleCount()))
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				buckets := h.Bucket
+				sort.Slice(buckets, func(i, j int) bool { return buckets[i].GetUpperBound() < buckets[j].GetUpperBound() })
+				infSeen := false
+				for _, b := range buckets {
+					infSeen = infSeen || math.IsInf(b.GetUpperBound(), 1)
+					writeOpenMetricsSample(w, name+"_bucket", m, "le", formatOpenMetricsFloat(b.GetUpperBound()), float64(b.GetCumulativeCount()))
+				}
+				if !infSeen {
+					writeOpenMetricsSample(w, name+"_bucket", m, "le", "+Inf", float64(h.GetSampleCount()))
+				}
+				writeOpenMetricsSample(w, name+"_sum", m, "", "", h.GetSampleSum())
+				writeOpenMetricsSample(w, name+"_count", m, "", "", float64(h.GetSampleCount()))
+			default:
+				writeOpenMetricsSample(w, name, m, "", "", m.GetUntyped().GetValue())
+			}
+		}
+	}
+	w.WriteString("# EOF\n")
+}
+
+func writeOpenMetricsSample(w *bufio.Writer, name string, m *dto.Metric, extraName, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(m.Label) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, l := range m.Label {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(l.GetName() + `="` + openMetricsLabelReplacer.Replace(l.GetValue()) + `"`)
+		}
+		if extraName != "" {
+			if len(m.Label) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extraName + `="` + extraValue + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteString(" " + formatOpenMetricsFloat(value))
+	if m.TimestampMs != nil {
+		// OpenMetrics timestamps are in seconds
+		w.WriteString(" " + strconv.FormatFloat(float64(m.GetTimestampMs())/1000, 'f', -1, 64))
+	}
+	w.WriteByte('\n')
+}
+
+func formatOpenMetricsFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/golang/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestMetricsOpenMetrics(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	srv := testServer(datastore.NewMock(), nil, ServerTypeAPI, WithPrometheus())
+
+	for i, test := range []struct {
+		accept      string
+		contentType string
+	}{
+		{"application/openmetrics-text; version=0.0.1,text/plain;version=0.0.4;q=0.5,*/*;q=0.1", openMetricsContentType},
+		{"", "text/plain; version=0.0.4"},
+		{"text/plain", "text/plain; version=0.0.4"},
+	} {
+		req := createRequest(t, http.MethodGet, "/metrics", nil)
+		if test.accept != "" {
+			req.Header.Set("Accept", test.accept)
+		}
+		_, rec := routerRequest2(t, srv.AdminRouter, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Test %d: expected status code 200 but was %d", i, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, test.contentType) {
+			t.Errorf("Test %d: expected content type %q, got %q", i, test.contentType, ct)
+		}
+		if test.contentType == openMetricsContentType && !strings.HasSuffix(rec.Body.String(), "# EOF\n") {
+			t.Errorf("Test %d: expected OpenMetrics body to end with # EOF, got %s", i, rec.Body.String())
+		}
+	}
+}
+
+func TestWriteOpenMetrics(t *testing.T) {
+	mfs := []*dto.MetricFamily{
+		{
+			Name: proto.String("fn_calls_total"),
+			Help: proto.String("Calls\nmade"),
+			Type: dto.MetricType_COUNTER.Enum(),
+			Metric: []*dto.Metric{{
+				Label:   []*dto.LabelPair{{Name: proto.String("fn_id"), Value: proto.String(`a"b`)}},
+				Counter: &dto.Counter{Value: proto.Float64(3)},
+			}},
+		},
+		{
+			Name: proto.String("fn_latency"),
+			Type: dto.MetricType_HISTOGRAM.Enum(),
+			Metric: []*dto.Metric{{
+				Histogram: &dto.Histogram{
+					SampleCount: proto.Uint64(2),
+					SampleSum:   proto.Float64(1.5),
+					Bucket:      []*dto.Bucket{{UpperBound: proto.Float64(1), CumulativeCount: proto.Uint64(1)}},
+				},
+			}},
+		},
+	}
+
+	var out bytes.Buffer
+	w := bufio.NewWriter(&out)
+	writeOpenMetrics(w, mfs)
+	w.Flush()
+
+	expected := `# TYPE fn_calls counter
+# HELP fn_calls Calls\nmade
+fn_calls_total{fn_id="a\"b"} 3
+# TYPE fn_latency histogram
+fn_latency_bucket{le="1"} 1
+fn_latency_bucket{le="+Inf"} 2
+fn_latency_sum 1.5
+fn_latency_count 2
+# EOF
+`
+	if out.String() != expected {
+		t.Errorf("expected %s, got %s", expected, out.String())
+	}
+}
@@ -7,7 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func profilerSetup(router *gin.Engine, path string) {
+func profilerSetup(router gin.IRouter, path string) {
 	engine := router.Group(path)
 	engine.Any("/vars", gin.WrapF(expvar.Handler().ServeHTTP))
 	engine.Any("/pprof/", gin.WrapF(pprof.Index))
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/sirupsen/logrus"
+)
+
+// hotReloadableEnv are the settings Reload applies to a running server
+var hotReloadableEnv = map[string]bool{
+	EnvLogLevel:        true,
+	EnvRunnerAddresses: true,
+	EnvTraceSampleRate: true,
+}
+
+// Reload applies the settings which can change without a restart, after
+// loading the file of EnvConfigFile again if set. Start calls it on SIGHUP.
+// The hot reloadable settings are:
+//
+//   - FN_LOG_LEVEL
+//   - FN_RUNNER_ADDRESSES, on LB nodes using the default static runner pool,
+//     unless it reads runners from DNS
+//   - FN_TRACE_SAMPLE_RATE, if traces are sent to jaeger or zipkin
+//
+// Changes to other settings of the file, such as ports, are logged and
+// skipped. As the environment of a process can't be changed from outside,
+// settings are only reloaded from the file. Settings removed from the file
+// keep their value.
+func (s *Server) Reload(ctx context.Context) error {
+	log := common.Logger(ctx)
+	if path := getEnv(EnvConfigFile, ""); path != "" {
+		if err := loadConfigFile(path, true); err != nil {
+			return err
+		}
+	}
+
+	var errs []string
+	if err := WithLogLevel(getEnv(EnvLogLevel, DefaultLogLevel))(ctx, s); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if s.runnerAddressSetter != nil && runnerSRVName() == "" {
+		if addrs := getEnv(EnvRunnerAddresses, ""); addrs != "" {
+			s.runnerAddressSetter.SetRunnerAddresses(ctx, strings.Split(addrs, ","))
+		} else {
+			errs = append(errs, fmt.Sprintf("%s must not be empty", EnvRunnerAddresses))
+		}
+	}
+
+	if v := getEnv(EnvTraceSampleRate, ""); v != "" {
+		rate, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err == nil {
+			err = WithTraceSampleRate(rate)(ctx, s)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("invalid %s: %v", EnvTraceSampleRate, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to reload config: %s", strings.Join(errs, "; "))
+	}
+	log.Info("Reloaded config")
+	return nil
+}
+
+// loadConfigFile sets the KEY=VALUE lines of the file at path in the
+// environment. Blank lines and lines starting with # are ignored. When
+// reloading, changes to settings which are not hot reloadable are skipped.
+func loadConfigFile(path string, reloading bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening config file: %v", err)
+	}
+	defer f.Close()
+
+	type kv struct{ key, value string }
+	var settings []kv
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			return fmt.Errorf("invalid config file line %d, must be KEY=VALUE", n)
+		}
+		settings = append(settings, kv{strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])})
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading config file: %v", err)
+	}
+
+	// only change the environment once the whole file is valid
+	for _, s := range settings {
+		if reloading && !hotReloadableEnv[s.key] {
+			if os.Getenv(s.key) != s.value {
+				logrus.WithField("environment_key", s.key).Warn("Skipped reloading config which requires a restart")
+			}
+			continue
+		}
+		os.Setenv(s.key, s.value)
+	}
+	return nil
+}
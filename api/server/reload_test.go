@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/sirupsen/logrus"
+)
+
+func TestReload(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	dir, err := ioutil.TempDir("", "fn-reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "fn.env")
+
+	for _, key := range []string{EnvConfigFile, EnvLogLevel, EnvPort, EnvTraceSampleRate} {
+		if v, ok := os.LookupEnv(key); ok {
+			defer os.Setenv(key, v)
+		} else {
+			defer os.Unsetenv(key)
+		}
+	}
+	os.Setenv(EnvConfigFile, path)
+	os.Setenv(EnvPort, "8080")
+	defer logrus.SetLevel(logrus.GetLevel())
+
+	srv := testServer(datastore.NewMock(), nil, ServerTypeAPI)
+
+	for i, test := range []struct {
+		file  string
+		valid bool
+		level logrus.Level
+		port  string
+	}{
+		{"# comment\nFN_LOG_LEVEL=warn\n\nFN_PORT=9090\n", true, logrus.WarnLevel, "8080"},
+		{"FN_LOG_LEVEL = error", true, logrus.ErrorLevel, "8080"},
+		{"FN_LOG_LEVEL=debug\nnot a setting", false, logrus.ErrorLevel, "8080"},
+		{"FN_TRACE_SAMPLE_RATE=2", false, logrus.ErrorLevel, "8080"},
+	} {
+		if err := ioutil.WriteFile(path, []byte(test.file), 0600); err != nil {
+			t.Fatal(err)
+		}
+		err := srv.Reload(context.Background())
+		if (err == nil) != test.valid {
+			t.Errorf("Test %d: expected valid %v, got error %v", i, test.valid, err)
+		}
+		if test.valid && logrus.GetLevel() != test.level {
+			t.Errorf("Test %d: expected log level %v, got %v", i, test.level, logrus.GetLevel())
+		}
+		if port := os.Getenv(EnvPort); port != test.port {
+			t.Errorf("Test %d: expected %s to be left at %s, got %s", i, EnvPort, test.port, port)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// defaultDecompressMaxSize bounds decompressed call bodies when no max is set
+const defaultDecompressMaxSize = 10 * 1024 * 1024
+
+// WithRequestDecompression decompresses the bodies of calls to functions
+// sent with a Content-Encoding of gzip or deflate, so that functions get them
+// as they were before being compressed, with no Content-Encoding. Apps may
+// turn it on or off with the models.AppDecompressRequestsAnnotation
+// annotation, whatever enabled says. Bodies are decompressed up to maxSize
+// bytes, or 10MB if 0 or less, larger ones are rejected with a 413 so that a
+// small body can't inflate into a huge one. Other encodings are left as they
+// are.
+func WithRequestDecompression(enabled bool, maxSize int64) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.decompressRequests = enabled
+		s.decompressMaxSize = maxSize
+		if maxSize <= 0 {
+			s.decompressMaxSize = defaultDecompressMaxSize
+		}
+		return nil
+	}
+}
+
+// decompressRequestBody replaces the gzip or deflate encoded body of a call to
+// a function of app with its decompressed content, if decompression is
+// enabled for app
+func (s *Server) decompressRequestBody(req *http.Request, app *models.App) error {
+	encoding := strings.ToLower(strings.TrimSpace(req.Header.Get("Content-Encoding")))
+	if encoding == "" || req.Body == nil || !s.decompressRequestsEnabled(app) {
+		return nil
+	}
+
+	var newReader func(io.Reader) (io.ReadCloser, error)
+	switch encoding {
+	case "gzip", "x-gzip":
+		newReader = func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }
+	case "deflate":
+		// deflate in HTTP is the zlib format
+		newReader = zlib.NewReader
+	default:
+		return nil
+	}
+
+	max := s.decompressMaxSize
+	if max <= 0 {
+		max = defaultDecompressMaxSize
+	}
+
+	defer req.Body.Close()
+	zr, err := newReader(req.Body)
+	if err != nil {
+		return decodingError(encoding, err)
+	}
+	defer zr.Close()
+
+	b, err := bufferCallBody(req, io.LimitReader(zr, max+1))
+	if err != nil {
+		return decodingError(encoding, err)
+	}
+	if int64(len(b)) > max {
+		return models.NewAPIError(http.StatusRequestEntityTooLarge,
+			fmt.Errorf("Decompressed request body too large for this server, max %d bytes", max))
+	}
+
+	req.ContentLength = int64(len(b))
+	req.Header.Set("Content-Length", strconv.Itoa(len(b)))
+	req.Header.Del("Content-Encoding")
+	return nil
+}
+
+func (s *Server) decompressRequestsEnabled(app *models.App) bool {
+	if v, ok := app.Annotations.Get(models.AppDecompressRequestsAnnotation); ok {
+		var enabled bool
+		if json.Unmarshal(v, &enabled) == nil {
+			return enabled
+		}
+	}
+	return s.decompressRequests
+}
+
+// decodingError returns a 400 for err if it is caused by a body which is not
+// validly encoded, other errors, e.g. reading the body, are returned as is
+func decodingError(encoding string, err error) error {
+	var corrupt flate.CorruptInputError
+	switch {
+	case err == io.EOF, err == io.ErrUnexpectedEOF, errors.As(err, &corrupt),
+		err == gzip.ErrHeader, err == gzip.ErrChecksum,
+		err == zlib.ErrHeader, err == zlib.ErrChecksum, err == zlib.ErrDictionary:
+		return models.NewAPIError(http.StatusBadRequest, fmt.Errorf("Invalid %s encoded request body", encoding))
+	}
+	return err
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func compress(t *testing.T, encoding string, body []byte) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	if encoding == "deflate" {
+		w = zlib.NewWriter(&buf)
+	} else {
+		w = gzip.NewWriter(&buf)
+	}
+	if _, err := w.Write(body); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRequestDecompression(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	disabled, err := models.Annotations{}.With(models.AppDecompressRequestsAnnotation, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	otherApp := &models.App{ID: "other_app_id", Name: "otherapp", Annotations: disabled}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}}
+	otherFn := &models.Fn{ID: "other_fn_id", Name: "otherfn", AppID: otherApp.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}}
+	trigger := &models.Trigger{ID: "trigger_id", Name: "mytrigger", AppID: app.ID, FnID: fn.ID, Type: models.TriggerTypeHTTP, Source: "/src"}
+	ds := datastore.NewMockInit([]*models.App{app, otherApp}, []*models.Fn{fn, otherFn}, []*models.Trigger{trigger})
+
+	body := []byte(`{"hello": "world"}`)
+	gzipped := compress(t, "gzip", body)
+	// a few KB inflating past the 1KB limit of the test server
+	bomb := compress(t, "gzip", bytes.Repeat([]byte{0}, 1<<20))
+
+	for i, test := range []struct {
+		path             string
+		encoding         string
+		body             []byte
+		expectedCode     int
+		expectedBody     []byte
+		expectedEncoding string
+	}{
+		{"/invoke/fn_id", "", body, http.StatusOK, body, ""},
+		{"/invoke/fn_id", "gzip", gzipped, http.StatusOK, body, ""},
+		{"/invoke/fn_id", "deflate", compress(t, "deflate", body), http.StatusOK, body, ""},
+		{"/t/myapp/src", "gzip", gzipped, http.StatusOK, body, ""},
+		{"/invoke/fn_id", "br", body, http.StatusOK, body, "br"},
+		{"/invoke/fn_id", "gzip", body, http.StatusBadRequest, nil, ""},
+		{"/invoke/fn_id", "gzip", gzipped[:len(gzipped)-4], http.StatusBadRequest, nil, ""},
+		{"/invoke/fn_id", "gzip", bomb, http.StatusRequestEntityTooLarge, nil, ""},
+		{"/invoke/other_fn_id", "gzip", gzipped, http.StatusOK, gzipped, "gzip"},
+	} {
+		var received []byte
+		var receivedHeaders http.Header
+		rnr := new(agent.MockAgent)
+		rnr.On("AddCallListener", mock.Anything)
+		rnr.On("GetCall", mock.Anything)
+		rnr.On("Submit", mock.Anything).Run(func(args mock.Arguments) {
+			call := args.Get(0).(agent.Call)
+			receivedHeaders = call.Model().Headers
+			received, _ = ioutil.ReadAll(call.(interface{ RequestBody() io.ReadCloser }).RequestBody())
+		}).Return(nil)
+
+		srv := testServer(ds, rnr, ServerTypeFull, WithRequestDecompression(true, 1024))
+
+		req := createRequest(t, http.MethodPost, test.path, bytes.NewReader(test.body))
+		if test.encoding != "" {
+			req.Header.Set("Content-Encoding", test.encoding)
+		}
+		_, rec := routerRequest2(t, srv.Router, req)
+
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+			continue
+		}
+		if test.expectedCode != http.StatusOK {
+			if received != nil {
+				t.Errorf("Test %d: expected call not to be submitted", i)
+			}
+			continue
+		}
+		if !bytes.Equal(received, test.expectedBody) {
+			t.Errorf("Test %d: expected the function to get %q, got %q", i, test.expectedBody, received)
+		}
+		// trigger headers reach functions prefixed
+		encoding := receivedHeaders.Get("Content-Encoding") + receivedHeaders.Get("Fn-Http-H-Content-Encoding")
+		if encoding != test.expectedEncoding {
+			t.Errorf("Test %d: expected Content-Encoding %q, got %q", i, test.expectedEncoding, encoding)
+		}
+		length := receivedHeaders.Get("Content-Length") + receivedHeaders.Get("Fn-Http-H-Content-Length")
+		if length != strconv.Itoa(len(test.expectedBody)) {
+			t.Errorf("Test %d: expected the Content-Length of the body passed on, got %q", i, length)
+		}
+	}
+}
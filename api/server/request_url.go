@@ -0,0 +1,129 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// basePathKey is the gin context key of the base path the routes are mounted
+// under, for the request based annotators to include it in URLs
+const basePathKey = "fn_base_path"
+
+func basePathWrap(basePath string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(basePathKey, basePath)
+		c.Next()
+	}
+}
+
+// ParseTrustedProxies parses a list of IP addresses and CIDR ranges of the
+// proxies whose X-Forwarded-* headers are honored, e.g. "10.0.0.0/8".
+func ParseTrustedProxies(list []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, s := range list {
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy address %q", s)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy range %q: %v", s, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func isTrustedProxy(remoteAddr string, trusted []*net.IPNet) bool {
+	if len(trusted) == 0 {
+		return false
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// lastForwarded returns the value of a forwarded header, which each proxy may
+// append to, set by the proxy closest to the server, which is trusted. Those
+// before it are only as trustworthy as the client, which may send the header
+// too.
+func lastForwarded(h http.Header, key string) string {
+	vs := h[http.CanonicalHeaderKey(key)]
+	if len(vs) == 0 {
+		return ""
+	}
+	v := vs[len(vs)-1]
+	if i := strings.LastIndex(v, ","); i >= 0 {
+		v = v[i+1:]
+	}
+	return strings.TrimSpace(v)
+}
+
+// requestScheme returns the scheme a client used to reach the server, that of
+// the X-Forwarded-Proto header if the peer is a trusted proxy.
+func requestScheme(r *http.Request, trusted []*net.IPNet) string {
+	if isTrustedProxy(r.RemoteAddr, trusted) {
+		if proto := strings.ToLower(lastForwarded(r.Header, "X-Forwarded-Proto")); proto == "http" || proto == "https" {
+			return proto
+		}
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// requestBaseURL returns the scheme and host a client used to reach the
+// server, e.g. "https://my.domain". The X-Forwarded-Proto, X-Forwarded-Host
+// and X-Forwarded-Port headers are only honored if the peer is a trusted
+// proxy, otherwise the request's own scheme and host are used.
+func requestBaseURL(r *http.Request, trusted []*net.IPNet) string {
+	scheme := requestScheme(r, trusted)
+	host := r.Host
+
+	if isTrustedProxy(r.RemoteAddr, trusted) {
+		if fh := lastForwarded(r.Header, "X-Forwarded-Host"); fh != "" {
+			host = fh
+		}
+		if port := lastForwarded(r.Header, "X-Forwarded-Port"); port != "" {
+			h, _, err := net.SplitHostPort(host)
+			if err != nil {
+				h = strings.Trim(host, "[]")
+			}
+			switch {
+			case scheme == "http" && port == "80", scheme == "https" && port == "443":
+				host = h
+				if strings.Contains(h, ":") {
+					host = "[" + h + "]"
+				}
+			default:
+				host = net.JoinHostPort(h, port)
+			}
+		}
+	}
+
+	return fmt.Sprintf("%s://%s", scheme, host)
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// RequireHTTPSRedirect redirects requests over plain HTTP to HTTPS
+	RequireHTTPSRedirect = "redirect"
+	// RequireHTTPSReject rejects requests over plain HTTP with a 400
+	RequireHTTPSReject = "reject"
+)
+
+// WithTrustedProxies sets the IP addresses and ranges of the proxies whose
+// X-Forwarded-Proto header is the scheme of their requests, see
+// ParseTrustedProxies. Requests from other peers are over HTTPS only if
+// they reached the server over TLS. Of the values proxies append to the
+// X-Forwarded-* headers, only the last, appended by the trusted proxy, is used.
+func WithTrustedProxies(proxies ...*net.IPNet) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.trustedProxies = proxies
+		return nil
+	}
+}
+
+// WithRequireHTTPS makes the server only serve requests over HTTPS. With
+// RequireHTTPSRedirect, requests over plain HTTP are redirected with a 308 to
+// the same URL over https, on the default port. With RequireHTTPSReject, they
+// are rejected with a 400. An empty mode, or off, serves both.
+//
+// When TLS is terminated upstream, the proxies must be set with
+// WithTrustedProxies, or every request is over plain HTTP. Conversely, the
+// X-Forwarded-Proto of any other peer is ignored, so clients can't claim
+// HTTPS themselves.
+//
+// The ping endpoint at / and /version are always served, so that probes over
+// plain HTTP keep working. The admin server is only covered when it shares
+// the port of the API.
+func WithRequireHTTPS(mode string) Option {
+	return func(ctx context.Context, s *Server) error {
+		switch mode {
+		case "", "off":
+			s.requireHTTPS = ""
+		case RequireHTTPSRedirect, RequireHTTPSReject:
+			s.requireHTTPS = mode
+		default:
+			return fmt.Errorf("invalid require HTTPS mode %q, must be %s, %s or off", mode, RequireHTTPSRedirect, RequireHTTPSReject)
+		}
+		return nil
+	}
+}
+
+func (s *Server) requireHTTPSWrap(c *gin.Context) {
+	r := c.Request
+	if requestScheme(r, s.trustedProxies) == "https" || s.isHealthPath(r.URL.Path) {
+		c.Next()
+		return
+	}
+
+	if s.requireHTTPS == RequireHTTPSRedirect {
+		host := r.Host
+		if isTrustedProxy(r.RemoteAddr, s.trustedProxies) {
+			if fh := lastForwarded(r.Header, "X-Forwarded-Host"); fh != "" {
+				host = fh
+			}
+		}
+		// the port of plain HTTP is never that of HTTPS
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+			if ip := net.ParseIP(h); ip != nil && ip.To4() == nil {
+				host = "[" + h + "]"
+			}
+		}
+		c.Redirect(http.StatusPermanentRedirect, "https://"+host+r.URL.RequestURI())
+		c.Abort()
+		return
+	}
+
+	handleErrorResponse(c, models.ErrHTTPSRequired)
+	c.Abort()
+}
+
+// isHealthPath returns whether path is one of the endpoints probes use
+func (s *Server) isHealthPath(path string) bool {
+	switch path {
+	case s.basePath, s.basePath + "/", s.basePath + "/version":
+		return true
+	}
+	return false
+}
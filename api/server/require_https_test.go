@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestRequireHTTPS(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+
+	for i, test := range []struct {
+		mode     string
+		path     string
+		remote   string
+		headers  map[string]string
+		tls      bool
+		status   int
+		location string
+	}{
+		{RequireHTTPSReject, "/v2/apps", "192.0.2.1:1234", nil, false, http.StatusBadRequest, ""},
+		{RequireHTTPSReject, "/v2/apps", "192.0.2.1:1234", nil, true, http.StatusOK, ""},
+		// only trusted proxies may set the scheme
+		{RequireHTTPSReject, "/v2/apps", "192.0.2.1:1234", map[string]string{"X-Forwarded-Proto": "https"}, false, http.StatusBadRequest, ""},
+		{RequireHTTPSReject, "/v2/apps", "10.0.0.1:1234", map[string]string{"X-Forwarded-Proto": "https"}, false, http.StatusOK, ""},
+		{RequireHTTPSReject, "/v2/apps", "10.0.0.1:1234", map[string]string{"X-Forwarded-Proto": "http"}, true, http.StatusBadRequest, ""},
+		// the scheme is the one the trusted proxy appended, not one the client sent
+		{RequireHTTPSReject, "/v2/apps", "10.0.0.1:1234", map[string]string{"X-Forwarded-Proto": "https, http"}, false, http.StatusBadRequest, ""},
+		{RequireHTTPSReject, "/v2/apps", "10.0.0.1:1234", map[string]string{"X-Forwarded-Proto": "http,https"}, false, http.StatusOK, ""},
+		// probes over plain HTTP are served
+		{RequireHTTPSReject, "/", "192.0.2.1:1234", nil, false, http.StatusOK, ""},
+		{RequireHTTPSReject, "/version", "192.0.2.1:1234", nil, false, http.StatusOK, ""},
+		{RequireHTTPSRedirect, "/v2/apps?per_page=1", "192.0.2.1:1234", nil, false, http.StatusPermanentRedirect, "https://example.com/v2/apps?per_page=1"},
+		{RequireHTTPSRedirect, "/v2/apps", "10.0.0.1:1234", map[string]string{"X-Forwarded-Host": "fn.example.com:8080"}, false, http.StatusPermanentRedirect, "https://fn.example.com/v2/apps"},
+		{RequireHTTPSRedirect, "/v2/apps", "10.0.0.1:1234", map[string]string{"X-Forwarded-Proto": "https"}, false, http.StatusOK, ""},
+		{"off", "/v2/apps", "192.0.2.1:1234", nil, false, http.StatusOK, ""},
+	} {
+		srv := testServer(datastore.NewMock(), nil, ServerTypeAPI, WithTrustedProxies(proxies), WithRequireHTTPS(test.mode))
+
+		req, rec := newRouterRequest(t, http.MethodGet, test.path, nil)
+		req.Host = "example.com:8080"
+		req.RemoteAddr = test.remote
+		for k, v := range test.headers {
+			req.Header.Set(k, v)
+		}
+		if test.tls {
+			req.TLS = &tls.ConnectionState{}
+		}
+		srv.Router.ServeHTTP(rec, req)
+
+		if rec.Code != test.status {
+			t.Errorf("Test %d: expected status %d, got %d: %s", i, test.status, rec.Code, rec.Body.String())
+			continue
+		}
+		if loc := rec.Header().Get("Location"); loc != test.location {
+			t.Errorf("Test %d: expected location %q, got %q", i, test.location, loc)
+		}
+		if rec.Code == http.StatusBadRequest && !strings.Contains(rec.Body.String(), models.ErrorCodeHTTPSRequired) {
+			t.Errorf("Test %d: expected error code %s, got %s", i, models.ErrorCodeHTTPSRequired, rec.Body.String())
+		}
+	}
+
+	if err := WithRequireHTTPS("always")(context.Background(), &Server{}); err == nil {
+		t.Error("expected an invalid mode to be an error")
+	}
+}
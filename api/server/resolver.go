@@ -0,0 +1,34 @@
+package server
+
+import (
+	"context"
+	"net"
+)
+
+// WithResolver resolves the hosts of other nodes with resolver, rather than
+// the default resolver of Go: the API that hybrid nodes reach with
+// FN_RUNNER_API_URL, and the runners of load balancer nodes. It only applies
+// to connections between fn nodes, functions resolve the hosts they connect
+// to with the DNS configuration of their containers. Runners found with
+// FN_RUNNER_SRV are still looked up with the default resolver, only their
+// hosts are resolved with resolver. It must be set before the agent is
+// created.
+func WithResolver(resolver *net.Resolver) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.resolver = resolver
+		return nil
+	}
+}
+
+// NewDNSServerResolver returns a resolver which sends its DNS queries to the
+// server at addr, as host:port, ignoring /etc/resolv.conf. /etc/hosts is
+// still read first.
+func NewDNSServerResolver(addr string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
@@ -0,0 +1,319 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+const (
+	// DefaultResponseCacheMaxBody is the largest response body cached for a
+	// trigger, unless set otherwise with WithResponseCache
+	DefaultResponseCacheMaxBody = 1 << 20
+	// DefaultResponseCacheMaxSize is the bytes of responses, with their keys
+	// and headers, the cache in memory holds, unless set otherwise with
+	// WithResponseCache
+	DefaultResponseCacheMaxSize = 64 << 20
+
+	// responseCacheMaxEntries is the most responses the cache in memory
+	// holds, however small they are
+	responseCacheMaxEntries = 10000
+
+	// responseCacheHeader tells clients whether a response came from the cache
+	responseCacheHeader = "X-Fn-Cache"
+
+	responseCacheHit    = "hit"
+	responseCacheMiss   = "miss"
+	responseCacheBypass = "bypass"
+)
+
+var (
+	responseCacheKey = common.MakeKey("cache")
+
+	responseCacheMeasure = common.MakeMeasure("server/response_cache", "Number of GET calls through cacheable triggers, by whether the cache served them", stats.UnitDimensionless)
+)
+
+// RegisterResponseCacheViews registers the views for the calls of the
+// triggers whose responses are cached, see WithResponseCache
+func RegisterResponseCacheViews(tagKeys []string) {
+	tags := []tag.Key{agent.AppIDMetricKey, responseCacheKey}
+	for _, key := range tagKeys {
+		if key != agent.AppIDMetricKey.Name() && key != responseCacheKey.Name() {
+			tags = append(tags, common.MakeKey(key))
+		}
+	}
+
+	err := view.Register(common.CreateViewWithTags(responseCacheMeasure, view.Count(), tags))
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot register view")
+	}
+}
+
+// CachedResponse is a response of a function cached for a trigger
+type CachedResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// ResponseCache caches the responses of the GET calls of the triggers with
+// the models.TriggerCacheTTLAnnotation, by app, path and query.
+type ResponseCache interface {
+	// Get returns the response cached for key, or nil if there is none
+	Get(ctx context.Context, key string) (*CachedResponse, error)
+	// Set caches resp for key, for ttl. Caches may drop it sooner, e.g. to
+	// stay within their size.
+	Set(ctx context.Context, key string, resp *CachedResponse, ttl time.Duration) error
+}
+
+// WithResponseCache caches the responses of the GET calls made through the
+// triggers with the models.TriggerCacheTTLAnnotation, so that the same calls,
+// to the same path and query of the same app, are served from the cache for
+// the ttl of the trigger without running the fn again. Only successful
+// responses whose body is at most maxBody bytes are cached. Responses are
+// cached in memory, per server, up to maxSize bytes of responses, unless
+// WithResponseCacheStore sets a cache shared by the servers of a cluster.
+// maxSize of 0 disables the cache in memory.
+func WithResponseCache(maxBody int, maxSize int64) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.responseCacheMaxBody = maxBody
+		if maxSize > 0 && s.responseCache == nil {
+			s.responseCache = newMemoryResponseCache(maxSize)
+		}
+		return nil
+	}
+}
+
+// WithResponseCacheStore sets the cache of the responses of the cacheable
+// triggers, such as one backed by redis, see WithResponseCache.
+func WithResponseCacheStore(cache ResponseCache) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.responseCache = cache
+		return nil
+	}
+}
+
+// responseCacheKey returns the key the response of a call is cached with, and
+// how long for, or "" if it is not cacheable
+func (s *Server) responseCacheKey(req *http.Request, app *models.App, trig *models.Trigger) (string, time.Duration) {
+	if s.responseCache == nil || trig == nil || req.Method != http.MethodGet {
+		return "", 0
+	}
+	// the annotation was validated when the trigger was stored
+	ttl, _ := trig.CacheTTL()
+	if ttl <= 0 {
+		return "", 0
+	}
+	return app.ID + " " + req.URL.RequestURI(), ttl
+}
+
+// cachedResponse returns the response cached for key, unless the client asks
+// not to be served from the cache, counting the hits and misses
+func (s *Server) cachedResponse(req *http.Request, appID, key string) *CachedResponse {
+	ctx := req.Context()
+	// the headers of trigger calls are prefixed
+	if hasCacheDirective(req.Header.Get("Fn-Http-H-Cache-Control"), "no-cache") {
+		recordResponseCache(ctx, appID, responseCacheBypass)
+		return nil
+	}
+	cached, err := s.responseCache.Get(ctx, key)
+	if err != nil {
+		common.Logger(ctx).WithError(err).Error("cannot get cached response")
+	}
+	if cached == nil {
+		recordResponseCache(ctx, appID, responseCacheMiss)
+		return nil
+	}
+	recordResponseCache(ctx, appID, responseCacheHit)
+	return cached
+}
+
+// cacheResponse caches the response of a call if it succeeded, its body is
+// within the limit and the fn didn't ask for it not to be cached
+func (s *Server) cacheResponse(ctx context.Context, key string, ttl time.Duration, trig *models.Trigger, status int, h http.Header, body []byte) {
+	if status != http.StatusOK || len(body) > s.responseCacheMaxBody {
+		return
+	}
+	// the status and headers of the fn, as triggerResponseWriter reads them
+	if userStatus := h.Get("Fn-Http-Status"); userStatus != "" && userStatus != "200" {
+		return
+	}
+	if statusHeader, _ := trig.ResponseStatusHeader(); statusHeader != "" {
+		if mapped := h.Get("Fn-Http-H-" + statusHeader); mapped != "" && mapped != "200" {
+			return
+		}
+	}
+	if cc := h.Get("Fn-Http-H-Cache-Control"); hasCacheDirective(cc, "no-store") || hasCacheDirective(cc, "private") {
+		return
+	}
+
+	cached := &CachedResponse{Status: status, Header: fnResponseHeaders(h), Body: append([]byte(nil), body...)}
+	if err := s.responseCache.Set(ctx, key, cached, ttl); err != nil {
+		common.Logger(ctx).WithError(err).Error("cannot cache response")
+	}
+}
+
+// fnResponseHeaders returns a copy of the headers of a response set by the
+// fn, which are cached with it. The others are set by the server for the call
+// which got the response, such as its id or CORS headers, and are set for the
+// calls served from the cache on their own.
+func fnResponseHeaders(h http.Header) http.Header {
+	trailers := make(map[string]bool)
+	for _, vs := range h["Trailer"] {
+		for _, k := range strings.Split(vs, ",") {
+			trailers[http.CanonicalHeaderKey(strings.TrimSpace(k))] = true
+		}
+	}
+
+	fnHeaders := make(http.Header)
+	for k, vs := range h {
+		switch {
+		case strings.HasPrefix(k, "Fn-Http-"), k == "Content-Type",
+			k == "Trailer", strings.HasPrefix(k, http.TrailerPrefix), trailers[k]:
+			fnHeaders[k] = append([]string(nil), vs...)
+		}
+	}
+	return fnHeaders
+}
+
+// writeCachedResponse writes a response served from the cache, with the
+// headers the server set for the call it serves
+func writeCachedResponse(resp http.ResponseWriter, cached *CachedResponse) {
+	h := resp.Header()
+	for k, vs := range cached.Header {
+		h[k] = vs
+	}
+	h.Set("Fn-Http-H-"+responseCacheHeader, "HIT")
+	if !hasTrailers(h) {
+		h.Set("Content-Length", strconv.Itoa(len(cached.Body)))
+	}
+	resp.WriteHeader(cached.Status)
+	resp.Write(cached.Body)
+}
+
+// hasCacheDirective returns whether a Cache-Control header value has the
+// directive
+func hasCacheDirective(cacheControl, directive string) bool {
+	for _, d := range strings.Split(cacheControl, ",") {
+		if i := strings.IndexByte(d, '='); i >= 0 {
+			d = d[:i]
+		}
+		if strings.EqualFold(strings.TrimSpace(d), directive) {
+			return true
+		}
+	}
+	return false
+}
+
+func recordResponseCache(ctx context.Context, appID, result string) {
+	ctx, err := tag.New(ctx, tag.Upsert(agent.AppIDMetricKey, appID), tag.Upsert(responseCacheKey, result))
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	stats.Record(ctx, responseCacheMeasure.M(1))
+}
+
+type responseCacheEntry struct {
+	resp    *CachedResponse
+	expires time.Time
+	size    int64
+}
+
+// memoryResponseCache is a ResponseCache local to a server, holding up to
+// maxSize bytes of responses, keys and headers included, and up to
+// maxEntries of them. Past either, expired responses are dropped, and if
+// that's not enough responses are not cached until others expire.
+type memoryResponseCache struct {
+	maxSize    int64
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]responseCacheEntry
+	size    int64
+}
+
+func newMemoryResponseCache(maxSize int64) *memoryResponseCache {
+	return &memoryResponseCache{
+		maxSize:    maxSize,
+		maxEntries: responseCacheMaxEntries,
+		now:        time.Now,
+		entries:    make(map[string]responseCacheEntry),
+	}
+}
+
+// Get implements ResponseCache
+func (m *memoryResponseCache) Get(ctx context.Context, key string) (*CachedResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	if m.now().After(e.expires) {
+		m.remove(key)
+		return nil, nil
+	}
+	return e.resp, nil
+}
+
+// Set implements ResponseCache
+func (m *memoryResponseCache) Set(ctx context.Context, key string, resp *CachedResponse, ttl time.Duration) error {
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.remove(key)
+	size := cachedResponseSize(key, resp)
+	if !m.fits(size) {
+		for k, e := range m.entries {
+			if now.After(e.expires) {
+				m.remove(k)
+			}
+		}
+		if !m.fits(size) {
+			return nil
+		}
+	}
+	m.entries[key] = responseCacheEntry{resp: resp, expires: now.Add(ttl), size: size}
+	m.size += size
+	return nil
+}
+
+// fits returns whether a response of size bytes may be added to the cache
+func (m *memoryResponseCache) fits(size int64) bool {
+	return m.size+size <= m.maxSize && len(m.entries) < m.maxEntries
+}
+
+func (m *memoryResponseCache) remove(key string) {
+	if e, ok := m.entries[key]; ok {
+		m.size -= e.size
+		delete(m.entries, key)
+	}
+}
+
+// cachedResponseSize returns the bytes a response cached for key is counted
+// for, with its key and headers
+func cachedResponseSize(key string, resp *CachedResponse) int64 {
+	size := len(key) + len(resp.Body)
+	for k, vs := range resp.Header {
+		size += len(k)
+		for _, v := range vs {
+			size += len(v)
+		}
+	}
+	return int64(size)
+}
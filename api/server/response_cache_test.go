@@ -0,0 +1,258 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestResponseCache(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}}
+	cached := &models.Trigger{ID: "trigger_id", Name: "cached", AppID: app.ID, FnID: fn.ID, Type: models.TriggerTypeHTTP, Source: "/cached"}
+	cached.Annotations, _ = cached.Annotations.With(models.TriggerCacheTTLAnnotation, "1m")
+	uncached := &models.Trigger{ID: "trigger_id2", Name: "uncached", AppID: app.ID, FnID: fn.ID, Type: models.TriggerTypeHTTP, Source: "/uncached"}
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn}, []*models.Trigger{cached, uncached})
+
+	submits := 0
+	newAgent := func(submitErr error) agent.Agent {
+		rnr := new(agent.MockAgent)
+		rnr.On("AddCallListener", mock.Anything)
+		rnr.On("GetCall", mock.Anything)
+		rnr.On("Submit", mock.Anything).Run(func(args mock.Arguments) {
+			submits++
+		}).Return(submitErr)
+		return rnr
+	}
+	ok, failing := newAgent(nil), newAgent(models.ErrCallTimeout)
+
+	srv := testServer(ds, ok, ServerTypeFull, WithResponseCache(DefaultResponseCacheMaxBody, DefaultResponseCacheMaxSize))
+
+	for i, test := range []struct {
+		method          string
+		path            string
+		cacheControl    string
+		fail            bool
+		expectedCode    int
+		expectedSubmits int
+		expectedCache   string
+	}{
+		// failed calls are not cached
+		{http.MethodGet, "/t/myapp/cached?q=1", "", true, http.StatusGatewayTimeout, 1, ""},
+		{http.MethodGet, "/t/myapp/cached?q=1", "", false, http.StatusOK, 2, "MISS"},
+		{http.MethodGet, "/t/myapp/cached?q=1", "", false, http.StatusOK, 2, "HIT"},
+		{http.MethodGet, "/t/myapp/cached?q=2", "", false, http.StatusOK, 3, "MISS"},
+		{http.MethodGet, "/t/myapp/cached?q=1", "max-age=0, no-cache", false, http.StatusOK, 4, "MISS"},
+		{http.MethodGet, "/t/myapp/cached?q=1", "", false, http.StatusOK, 4, "HIT"},
+		{http.MethodPost, "/t/myapp/cached?q=1", "", false, http.StatusOK, 5, ""},
+		{http.MethodGet, "/t/myapp/uncached?q=1", "", false, http.StatusOK, 6, ""},
+		{http.MethodGet, "/t/myapp/uncached?q=1", "", false, http.StatusOK, 7, ""},
+		{http.MethodPost, "/invoke/fn_id", "", false, http.StatusOK, 8, ""},
+	} {
+		srv.agent = ok
+		if test.fail {
+			srv.agent = failing
+		}
+
+		req := createRequest(t, test.method, test.path, nil)
+		if test.cacheControl != "" {
+			req.Header.Set("Cache-Control", test.cacheControl)
+		}
+		_, rec := routerRequest2(t, srv.Router, req)
+
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d", i, test.expectedCode, rec.Code)
+		}
+		if submits != test.expectedSubmits {
+			t.Errorf("Test %d: expected %d submits, got %d", i, test.expectedSubmits, submits)
+		}
+		if got := rec.Header().Get(responseCacheHeader); got != test.expectedCache {
+			t.Errorf("Test %d: expected %s %q, got %q", i, responseCacheHeader, test.expectedCache, got)
+		}
+	}
+}
+
+func TestResponseCacheServerHeaders(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}}
+	trig := &models.Trigger{ID: "trigger_id", Name: "cached", AppID: app.ID, FnID: fn.ID, Type: models.TriggerTypeHTTP, Source: "/cached"}
+	trig.Annotations, _ = trig.Annotations.With(models.TriggerCacheTTLAnnotation, "1m")
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn}, []*models.Trigger{trig})
+
+	rnr := new(agent.MockAgent)
+	rnr.On("AddCallListener", mock.Anything)
+	rnr.On("GetCall", mock.Anything)
+	rnr.On("Submit", mock.Anything).Return(nil)
+
+	srv := testServer(ds, rnr, ServerTypeFull,
+		WithResponseCache(DefaultResponseCacheMaxBody, DefaultResponseCacheMaxSize),
+		WithInvokeCORS([]string{"http://a.example", "http://b.example"}, nil))
+
+	for i, test := range []struct {
+		origin        string
+		expectedCache string
+	}{
+		{"http://a.example", "MISS"},
+		// the CORS headers of the call which got the response are not replayed
+		{"http://b.example", "HIT"},
+		{"", "HIT"},
+	} {
+		req := createRequest(t, http.MethodGet, "/t/myapp/cached", nil)
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+		_, rec := routerRequest2(t, srv.Router, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Test %d: expected status code 200 but was %d", i, rec.Code)
+		}
+		if got := rec.Header().Get(responseCacheHeader); got != test.expectedCache {
+			t.Errorf("Test %d: expected %s %q, got %q", i, responseCacheHeader, test.expectedCache, got)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != test.origin {
+			t.Errorf("Test %d: expected Access-Control-Allow-Origin %q, got %q", i, test.origin, got)
+		}
+		if test.origin != "" && len(rec.Header()["Vary"]) != 1 {
+			t.Errorf("Test %d: expected a single Vary header, got %v", i, rec.Header()["Vary"])
+		}
+		if test.expectedCache == "HIT" && rec.Header().Get("Fn-Call-Id") != "" {
+			t.Errorf("Test %d: expected no call id on a cached response", i)
+		}
+	}
+}
+
+func TestFnResponseHeaders(t *testing.T) {
+	h := http.Header{
+		"Fn-Http-H-X-Custom":          {"custom"},
+		"Fn-Http-Status":              {"200"},
+		"Content-Type":                {"text/plain"},
+		"Trailer":                     {"Fn-Checksum, X-Other"},
+		"Fn-Checksum":                 {"abc"},
+		"Fn-Call-Id":                  {"call_id"},
+		"Access-Control-Allow-Origin": {"http://a.example"},
+		"Vary":                        {"Origin"},
+		"X-Ratelimit-Remaining":       {"9"},
+	}
+	got := fnResponseHeaders(h)
+	for _, k := range []string{"Fn-Http-H-X-Custom", "Fn-Http-Status", "Content-Type", "Trailer", "Fn-Checksum"} {
+		if got.Get(k) != h.Get(k) {
+			t.Errorf("expected %s to be kept, got %v", k, got)
+		}
+	}
+	for _, k := range []string{"Fn-Call-Id", "Access-Control-Allow-Origin", "Vary", "X-Ratelimit-Remaining"} {
+		if _, ok := got[k]; ok {
+			t.Errorf("expected %s to be dropped, got %v", k, got)
+		}
+	}
+}
+
+func TestMemoryResponseCache(t *testing.T) {
+	now := time.Now()
+	cache := newMemoryResponseCache(10)
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "a", &CachedResponse{Status: http.StatusOK, Body: []byte("123456")}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	// over the size of the cache, until a expires
+	if err := cache.Set(ctx, "b", &CachedResponse{Status: http.StatusOK, Body: []byte("123456")}, 2*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if resp, _ := cache.Get(ctx, "a"); resp == nil {
+		t.Fatal("expected a to be cached")
+	}
+	if resp, _ := cache.Get(ctx, "b"); resp != nil {
+		t.Fatal("expected b not to be cached past the size of the cache")
+	}
+
+	now = now.Add(90 * time.Second)
+	if err := cache.Set(ctx, "b", &CachedResponse{Status: http.StatusOK, Body: []byte("123456")}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if resp, _ := cache.Get(ctx, "a"); resp != nil {
+		t.Fatal("expected a to expire")
+	}
+	if resp, _ := cache.Get(ctx, "b"); resp == nil {
+		t.Fatal("expected b to be cached once a expired")
+	}
+	// the key counts too
+	if cache.size != 7 {
+		t.Fatalf("expected the cache to hold 7 bytes, got %d", cache.size)
+	}
+
+	// so do the headers, c fits in the cache but its headers don't
+	withHeaders := &CachedResponse{Status: http.StatusOK, Header: http.Header{"Content-Type": {"text/plain"}}, Body: []byte("1")}
+	if err := cache.Set(ctx, "c", withHeaders, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if resp, _ := cache.Get(ctx, "c"); resp != nil {
+		t.Fatal("expected c not to be cached past the size of the cache")
+	}
+}
+
+func TestMemoryResponseCacheMaxEntries(t *testing.T) {
+	now := time.Now()
+	cache := newMemoryResponseCache(DefaultResponseCacheMaxSize)
+	cache.maxEntries = 2
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := cache.Set(ctx, key, &CachedResponse{Status: http.StatusOK}, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if resp, _ := cache.Get(ctx, "c"); resp != nil {
+		t.Fatal("expected c not to be cached past the entries of the cache")
+	}
+
+	now = now.Add(90 * time.Second)
+	if err := cache.Set(ctx, "c", &CachedResponse{Status: http.StatusOK}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if resp, _ := cache.Get(ctx, "c"); resp == nil {
+		t.Fatal("expected c to be cached once the others expired")
+	}
+	if len(cache.entries) != 1 {
+		t.Fatalf("expected the expired entries to be dropped, got %d entries", len(cache.entries))
+	}
+}
+
+func TestHasCacheDirective(t *testing.T) {
+	for i, test := range []struct {
+		cacheControl string
+		directive    string
+		expected     bool
+	}{
+		{"no-cache", "no-cache", true},
+		{"max-age=0, No-Cache", "no-cache", true},
+		{`no-cache="Set-Cookie"`, "no-cache", true},
+		{"no-store", "no-cache", false},
+		{"", "no-cache", false},
+	} {
+		if got := hasCacheDirective(test.cacheControl, test.directive); got != test.expected {
+			t.Errorf("Test %d: expected %v, got %v", i, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// hstsHeader is only sent on responses to requests over TLS
+const hstsHeader = "Strict-Transport-Security"
+
+// defaultSecurityHeaders are the headers set by WithSecurityHeaders
+var defaultSecurityHeaders = map[string]string{
+	"X-Content-Type-Options": "nosniff",
+	"X-Frame-Options":        "DENY",
+	hstsHeader:               "max-age=31536000; includeSubDomains",
+}
+
+// WithResponseHeaders sets headers on every response of the /v2 API and the
+// admin server. Function output, from /invoke and /t, is left as is. Headers
+// set by handlers take precedence. Strict-Transport-Security is only sent on
+// responses to requests over TLS.
+func WithResponseHeaders(headers map[string]string) Option {
+	return func(ctx context.Context, s *Server) error {
+		if s.responseHeaders == nil {
+			s.responseHeaders = make(http.Header, len(headers))
+		}
+		for k, v := range headers {
+			s.responseHeaders.Set(k, v)
+		}
+		return nil
+	}
+}
+
+// WithSecurityHeaders sets the X-Content-Type-Options, X-Frame-Options and
+// Strict-Transport-Security headers, as WithResponseHeaders does. Headers
+// also set with WithResponseHeaders keep their value.
+func WithSecurityHeaders() Option {
+	return func(ctx context.Context, s *Server) error {
+		headers := make(map[string]string, len(defaultSecurityHeaders))
+		for k, v := range defaultSecurityHeaders {
+			if s.responseHeaders.Get(k) == "" {
+				headers[k] = v
+			}
+		}
+		return WithResponseHeaders(headers)(ctx, s)
+	}
+}
+
+// responseHeadersWrap sets the configured headers before handlers run, so
+// handlers may replace them.
+func responseHeadersWrap(headers http.Header) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		for k, vs := range headers {
+			if k == hstsHeader && c.Request.TLS == nil {
+				continue
+			}
+			if _, ok := h[k]; !ok {
+				h[k] = vs
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+)
+
+func TestResponseHeaders(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	srv := testServer(datastore.NewMock(), nil, ServerTypeAPI,
+		WithResponseHeaders(map[string]string{"X-Frame-Options": "SAMEORIGIN", "X-Custom": "custom"}),
+		WithSecurityHeaders(),
+	)
+
+	for i, test := range []struct {
+		router   http.Handler
+		path     string
+		tls      bool
+		expected map[string]string
+	}{
+		{srv.Router, "/v2/apps", false, map[string]string{
+			"X-Content-Type-Options":    "nosniff",
+			"X-Frame-Options":           "SAMEORIGIN",
+			"X-Custom":                  "custom",
+			"Strict-Transport-Security": "",
+		}},
+		{srv.Router, "/v2/apps", true, map[string]string{
+			"X-Content-Type-Options":    "nosniff",
+			"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+		}},
+		{srv.Router, "/v2/apps/nope", false, map[string]string{"X-Content-Type-Options": "nosniff"}},
+		{srv.AdminRouter, "/version", false, map[string]string{"X-Content-Type-Options": "nosniff"}},
+		{srv.Router, "/", false, map[string]string{"X-Content-Type-Options": ""}},
+	} {
+		req, rec := newRouterRequest(t, http.MethodGet, test.path, nil)
+		if test.tls {
+			req.TLS = &tls.ConnectionState{}
+		}
+		test.router.ServeHTTP(rec, req)
+		for k, v := range test.expected {
+			if got := rec.Header().Get(k); got != v {
+				t.Errorf("Test %d: expected header %s to be %q, got %q", i, k, v, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+const (
+	truncatedBody    = "body"
+	truncatedHeaders = "headers"
+)
+
+var (
+	truncatedKey = common.MakeKey("truncated")
+
+	responseTruncatedMeasure = common.MakeMeasure("server/response_truncated", "Number of function responses truncated to the response limits", stats.UnitDimensionless)
+)
+
+// RegisterResponseLimitViews registers the views for the function responses
+// truncated by WithMaxResponse
+func RegisterResponseLimitViews(tagKeys []string) {
+	tags := []tag.Key{agent.AppIDMetricKey, truncatedKey}
+	for _, key := range tagKeys {
+		if key != agent.AppIDMetricKey.Name() && key != truncatedKey.Name() {
+			tags = append(tags, common.MakeKey(key))
+		}
+	}
+
+	err := view.Register(common.CreateViewWithTags(responseTruncatedMeasure, view.Count(), tags))
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot register view")
+	}
+}
+
+// WithMaxResponse caps the responses of functions to sync calls, to /invoke
+// and /t, which LB nodes otherwise forward from runners as they are. Bodies
+// over maxSize bytes are truncated to maxSize bytes, and the headers set by
+// the function past maxHeaders values are dropped, keeping those of the
+// alphabetically first headers. The call still succeeds, with its status, and
+// each truncation is logged as a warning and counted. 0 means no limit.
+//
+// Nodes running functions also fail calls whose body is over
+// FN_MAX_RESPONSE_SIZE, or whose headers are over FN_MAX_HDR_RESPONSE_SIZE
+// bytes, with a 502, before it is reached.
+func WithMaxResponse(maxSize uint64, maxHeaders int) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.maxResponseSize = maxSize
+		s.maxResponseHeaders = maxHeaders
+		return nil
+	}
+}
+
+// Write implements io.Writer, discarding the body past the max, if any, while
+// reporting it as written so that the call still succeeds
+func (s *syncResponseWriter) Write(p []byte) (int, error) {
+	if s.max == 0 || uint64(s.Len()+len(p)) <= s.max {
+		return s.Buffer.Write(p)
+	}
+	s.truncated = true
+	if remaining := int(s.max) - s.Len(); remaining > 0 {
+		s.Buffer.Write(p[:remaining])
+	}
+	return len(p), nil
+}
+
+// WriteString implements io.StringWriter, as Write does
+func (s *syncResponseWriter) WriteString(str string) (int, error) {
+	return s.Write([]byte(str))
+}
+
+// ReadFrom implements io.ReaderFrom, as Write does
+func (s *syncResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if s.max == 0 {
+		return s.Buffer.ReadFrom(r)
+	}
+	return io.Copy(struct{ io.Writer }{s}, r)
+}
+
+// limitResponseHeaders drops the values of headers not in platform, those the
+// function set, past max, and returns whether it dropped any
+func limitResponseHeaders(h http.Header, platform map[string]bool, max int) bool {
+	if max <= 0 {
+		return false
+	}
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		if !platform[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	dropped := false
+	for _, k := range keys {
+		switch vs := h[k]; {
+		case max <= 0:
+			delete(h, k)
+			dropped = true
+		case len(vs) > max:
+			h[k] = vs[:max]
+			dropped = true
+			max = 0
+		default:
+			max -= len(vs)
+		}
+	}
+	return dropped
+}
+
+// headerKeys returns the keys of h, those set before the function ran
+func headerKeys(h http.Header) map[string]bool {
+	keys := make(map[string]bool, len(h))
+	for k := range h {
+		keys[k] = true
+	}
+	return keys
+}
+
+// recordResponseTruncated logs and counts a function response truncated to
+// the response limits
+func recordResponseTruncated(ctx context.Context, appID, truncated string) {
+	common.Logger(ctx).WithField("truncated", truncated).Warn("function response over the response limits, truncated")
+	ctx, err := tag.New(ctx, tag.Upsert(agent.AppIDMetricKey, appID), tag.Upsert(truncatedKey, truncated))
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	stats.Record(ctx, responseTruncatedMeasure.M(1))
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResponseBodyLimit(t *testing.T) {
+	for i, test := range []struct {
+		max       uint64
+		writes    []string
+		expected  string
+		truncated bool
+	}{
+		{0, []string{"hello", "world"}, "helloworld", false},
+		{10, []string{"hello", "world"}, "helloworld", false},
+		{9, []string{"hello", "world"}, "helloworl", true},
+		{5, []string{"hello", "world"}, "hello", true},
+		{4, []string{"hello", "world"}, "hell", true},
+	} {
+		w := &syncResponseWriter{headers: make(http.Header), Buffer: new(bytes.Buffer), max: test.max}
+		for _, s := range test.writes {
+			// the function must see its whole response written
+			if n, err := w.Write([]byte(s)); n != len(s) || err != nil {
+				t.Errorf("Test %d: expected %d bytes written, got %d %v", i, len(s), n, err)
+			}
+		}
+		if w.String() != test.expected || w.truncated != test.truncated {
+			t.Errorf("Test %d: expected body %q truncated %v, got %q %v", i, test.expected, test.truncated, w.String(), w.truncated)
+		}
+
+		w = &syncResponseWriter{headers: make(http.Header), Buffer: new(bytes.Buffer), max: test.max}
+		if _, err := io.Copy(w, strings.NewReader(strings.Join(test.writes, ""))); err != nil {
+			t.Errorf("Test %d: unexpected error %v", i, err)
+		}
+		if w.String() != test.expected || w.truncated != test.truncated {
+			t.Errorf("Test %d: expected copied body %q truncated %v, got %q %v", i, test.expected, test.truncated, w.String(), w.truncated)
+		}
+	}
+}
+
+func TestResponseHeadersLimit(t *testing.T) {
+	for i, test := range []struct {
+		max      int
+		expected http.Header
+		dropped  bool
+	}{
+		{0, http.Header{"Fn-Call-Id": {"1"}, "A": {"a"}, "B": {"b1", "b2"}, "C": {"c"}}, false},
+		{4, http.Header{"Fn-Call-Id": {"1"}, "A": {"a"}, "B": {"b1", "b2"}, "C": {"c"}}, false},
+		{3, http.Header{"Fn-Call-Id": {"1"}, "A": {"a"}, "B": {"b1", "b2"}}, true},
+		{2, http.Header{"Fn-Call-Id": {"1"}, "A": {"a"}, "B": {"b1"}}, true},
+		{1, http.Header{"Fn-Call-Id": {"1"}, "A": {"a"}}, true},
+	} {
+		h := http.Header{"Fn-Call-Id": {"1"}}
+		platform := headerKeys(h)
+		h["A"], h["B"], h["C"] = []string{"a"}, []string{"b1", "b2"}, []string{"c"}
+
+		if dropped := limitResponseHeaders(h, platform, test.max); dropped != test.dropped || !reflect.DeepEqual(h, test.expected) {
+			t.Errorf("Test %d: expected headers %v dropped %v, got %v %v", i, test.expected, test.dropped, h, dropped)
+		}
+	}
+}
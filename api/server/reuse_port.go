@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"net"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+)
+
+// WithReusePort sets SO_REUSEPORT on the listeners of the web and admin
+// servers, so that several fn processes on a host may listen on the same
+// port, and the kernel balances the connections accepted across them
+// without a load balancer in front. This is only supported on Linux (3.9 and
+// later), elsewhere a warning is logged and the listeners are opened as usual.
+func WithReusePort() Option {
+	return func(ctx context.Context, s *Server) error {
+		s.reusePort = true
+		return nil
+	}
+}
+
+// listen opens a TCP listener on addr, with SO_REUSEPORT if enabled and
+// supported
+func (s *Server) listen(ctx context.Context, addr string) (net.Listener, error) {
+	if !s.reusePort {
+		return net.Listen("tcp", addr)
+	}
+
+	var sockErr error
+	lc := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			err := c.Control(func(fd uintptr) {
+				sockErr = setReusePort(fd)
+			})
+			if err != nil {
+				sockErr = err
+			}
+			return sockErr
+		},
+	}
+	ln, err := lc.Listen(ctx, "tcp", addr)
+	if sockErr != nil {
+		logrus.WithError(sockErr).WithField("addr", addr).Warn("SO_REUSEPORT not supported, listening without it")
+		return net.Listen("tcp", addr)
+	}
+	return ln, err
+}
@@ -0,0 +1,9 @@
+// +build !linux
+
+package server
+
+import "errors"
+
+func setReusePort(fd uintptr) error {
+	return errors.New("SO_REUSEPORT is only supported on Linux")
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"runtime"
+	"testing"
+)
+
+func TestReusePort(t *testing.T) {
+	ctx := context.Background()
+	srv := &Server{}
+	if err := WithReusePort()(ctx, srv); err != nil {
+		t.Fatal(err)
+	}
+
+	ln1, err := srv.listen(ctx, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln1.Close()
+
+	// a second process would listen on the same port
+	ln2, err := srv.listen(ctx, ln1.Addr().String())
+	if runtime.GOOS != "linux" {
+		if err == nil {
+			ln2.Close()
+			t.Fatal("expected the listener to fall back to one without SO_REUSEPORT")
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("expected both listeners on the same port, got %v", err)
+	}
+	ln2.Close()
+
+	// and without it, the port is in use
+	if ln3, err := new(Server).listen(ctx, ln1.Addr().String()); err == nil {
+		ln3.Close()
+		t.Fatal("expected the port to be in use without SO_REUSEPORT")
+	}
+}
@@ -2,10 +2,12 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net/http"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/fnproject/fn/api"
 	"github.com/fnproject/fn/api/agent"
@@ -35,6 +37,10 @@ type syncResponseWriter struct {
 	headers http.Header
 	status  int
 	*bytes.Buffer
+
+	// max is the size the body is truncated to, if not 0, see WithMaxResponse
+	max       uint64
+	truncated bool
 }
 
 var _ http.ResponseWriter = new(syncResponseWriter) // nice compiler errors
@@ -60,12 +66,12 @@ func (s *Server) handleFnInvokeCall2(c *gin.Context) error {
 	ctx := c.Request.Context()
 	fn, err := s.lbReadAccess.GetFnByID(ctx, c.Param(api.FnID))
 	if err != nil {
-		return err
+		return s.handleInvocationMiss(c, err)
 	}
 
 	app, err := s.lbReadAccess.GetAppByID(ctx, fn.AppID)
 	if err != nil {
-		return err
+		return s.handleInvocationMiss(c, err)
 	}
 
 	err = s.ServeFnInvoke(c, app, fn)
@@ -84,10 +90,42 @@ func (s *Server) handleFnInvokeCall2(c *gin.Context) error {
 }
 
 func (s *Server) ServeFnInvoke(c *gin.Context, app *models.App, fn *models.Fn) error {
-	return s.fnInvoke(c.Writer, c.Request, app, fn, nil)
+	// the invoke endpoint reaches the same fns as the app's triggers, so it
+	// can't be a way around their signatures
+	if err := verifyTriggerSignature(c.Request, app, time.Now()); err != nil {
+		return err
+	}
+	if err := s.checkInvokeRate(c.Request.Context(), c.Writer, app); err != nil {
+		return err
+	}
+	if err := s.checkInvokeContentType(c.Request, app); err != nil {
+		return err
+	}
+	if err := s.limitCallBody(c, app); err != nil {
+		return err
+	}
+	fn, err := s.applyCallOverrides(c.Request, app, fn)
+	if err != nil {
+		return err
+	}
+	if err := verifyBodyChecksum(c.Request, app); err != nil {
+		return err
+	}
+	if err := s.decompressRequestBody(c.Request, app); err != nil {
+		return err
+	}
+	if err := s.checkWebSocket(c); err != nil {
+		return err
+	}
+	if err := s.transformRequestBody(c); err != nil {
+		return err
+	}
+	return s.fnInvoke(c, c.Writer, c.Request, app, fn, nil)
 }
 
-func (s *Server) fnInvoke(resp http.ResponseWriter, req *http.Request, app *models.App, fn *models.Fn, trig *models.Trigger) error {
+func (s *Server) fnInvoke(c *gin.Context, resp http.ResponseWriter, req *http.Request, app *models.App, fn *models.Fn, trig *models.Trigger) (err error) {
+	defer func() { s.recordFnInvocation(req.Context(), fn.ID, err) }()
+
 	// TODO: we should get rid of the buffers, and stream back (saves memory (+splice), faster (splice), allows streaming, don't have to cap resp size)
 	// buffer the response before writing it out to client to prevent partials from trying to stream
 	buf := bufPool.Get().(*bytes.Buffer)
@@ -102,9 +140,68 @@ func (s *Server) fnInvoke(resp http.ResponseWriter, req *http.Request, app *mode
 			headers: resp.Header(),
 			status:  200,
 			Buffer:  buf,
+			max:     s.maxResponseSize,
+		}
+	}
+
+	// the server max bounds how long a caller is held, detached calls hold no one
+	clamped := false
+	if !isDetached && s.syncCallMaxTimeout > 0 && fn.Timeout > s.syncCallMaxTimeout {
+		fn = fn.Clone()
+		fn.Timeout = s.syncCallMaxTimeout
+		clamped = true
+	}
+
+	// the responses of cacheable triggers are served from the cache, websocket upgrades aside
+	var cacheKey string
+	var cacheTTL time.Duration
+	if !isDetached && !c.GetBool(webSocketKey) {
+		cacheKey, cacheTTL = s.responseCacheKey(req, app, trig)
+	}
+	if cacheKey != "" {
+		if cached := s.cachedResponse(req, app.ID, cacheKey); cached != nil {
+			writeCachedResponse(resp, cached)
+			return nil
 		}
 	}
+
+	// detached calls have no response to replay
+	var idemKey string
+	if !isDetached && s.idempotencyTTL > 0 {
+		idemKey = idempotencyKey(req, trig)
+	}
+	if idemKey != "" {
+		if len(idemKey) > maxIdempotencyKeyLength {
+			return models.ErrIdempotencyKeyTooLong
+		}
+		stored, err := s.idempotencyStore.Reserve(req.Context(), app.ID, idemKey, idempotencyReservationTTL(fn, s.idempotencyTTL))
+		if err != nil {
+			return err
+		}
+		if stored != nil {
+			replayIdempotentResponse(resp, stored, trig)
+			return nil
+		}
+		// released unless the response gets stored below
+		defer func() {
+			if idemKey != "" {
+				// even if the client disconnected, so that the key may be retried
+				s.idempotencyStore.Release(common.BackgroundContext(req.Context()), app.ID, idemKey)
+			}
+		}()
+	}
+
+	upgrade := !isDetached && c.GetBool(webSocketKey)
+	var capture *pendingDebugCapture
+	if !upgrade {
+		capture = s.debugCaptures.begin(app.ID, req)
+		defer func() { capture.finish(err) }()
+	}
+
 	opts := getCallOptions(req, app, fn, trig, writer)
+	if upgrade {
+		opts = append(opts, agent.WithUpgrade("websocket", c.Writer))
+	}
 
 	call, err := s.agent.GetCall(opts...)
 	if err != nil {
@@ -113,14 +210,67 @@ func (s *Server) fnInvoke(resp http.ResponseWriter, req *http.Request, app *mode
 
 	// add this before submit, always tie a call id to the response at this point
 	writer.Header().Add("Fn-Call-Id", call.Model().ID)
+	capture.setCallID(call.Model().ID)
+	platformHeaders := headerKeys(writer.Header())
 
 	err = s.agent.Submit(call)
+	if upgrade && c.Writer.Written() {
+		// the connection was hijacked, there is nothing left to write to it
+		if err != nil {
+			common.Logger(req.Context()).WithError(err).Info("websocket call ended with error")
+		}
+		return nil
+	}
+	if !isDetached && clientCancelled(req.Context(), app.ID) {
+		// there is no one left to respond to
+		return context.Canceled
+	}
 	if err != nil {
+		// errors from runners may not be the same value, compare the message
+		if clamped && err.Error() == models.ErrCallTimeout.Error() {
+			return models.ErrCallTimeoutServerMax
+		}
 		return err
 	}
 
-	// because we can...
-	writer.Header().Set("Content-Length", strconv.Itoa(int(buf.Len())))
+	if !isDetached {
+		if writer.(*syncResponseWriter).truncated {
+			recordResponseTruncated(req.Context(), app.ID, truncatedBody)
+		}
+		if limitResponseHeaders(writer.Header(), platformHeaders, s.maxResponseHeaders) {
+			recordResponseTruncated(req.Context(), app.ID, truncatedHeaders)
+		}
+		if err := s.transformResponseBody(c, buf); err != nil {
+			return err
+		}
+	}
+
+	// because we can... unless the function sends trailers, which need a chunked response
+	if !hasTrailers(writer.Header()) {
+		writer.Header().Set("Content-Length", strconv.Itoa(int(buf.Len())))
+	}
+
+	if idemKey != "" && buf.Len() <= s.idempotencyMaxBody {
+		stored := &IdempotentResponse{
+			Status: writer.Status(),
+			Header: writer.Header().Clone(),
+			Body:   append([]byte(nil), buf.Bytes()...),
+		}
+		if err := s.idempotencyStore.Store(req.Context(), app.ID, idemKey, stored, s.idempotencyTTL); err == nil {
+			idemKey = ""
+		}
+	}
+
+	if cacheKey != "" {
+		s.cacheResponse(req.Context(), cacheKey, cacheTTL, trig, writer.Status(), writer.Header(), buf.Bytes())
+		writer.Header().Set("Fn-Http-H-"+responseCacheHeader, "MISS")
+	}
+
+	if s.serverTiming && !isDetached {
+		setServerTiming(writer.Header(), agent.GetCallTimings(call))
+	}
+
+	capture.setResponse(writer.Status(), writer.Header(), buf.Bytes())
 
 	// buffered response writer traps status (so we can add headers), we need to write it still
 	if writer.Status() > 0 {
@@ -136,6 +286,12 @@ func (s *Server) fnInvoke(resp http.ResponseWriter, req *http.Request, app *mode
 	return nil
 }
 
+// hasTrailers returns whether a response declares trailers, which are sent
+// after its body
+func hasTrailers(h http.Header) bool {
+	return len(h["Trailer"]) > 0
+}
+
 func getCallOptions(req *http.Request, app *models.App, fn *models.Fn, trig *models.Trigger, rw http.ResponseWriter) []agent.CallOpt {
 	var opts []agent.CallOpt
 	opts = append(opts, agent.WithWriter(rw)) // XXX (reed): order matters [for now]
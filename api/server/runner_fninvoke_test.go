@@ -8,9 +8,12 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/fnproject/fn/api/agent"
 	"github.com/fnproject/fn/api/datastore"
 	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestBadRequests(t *testing.T) {
@@ -379,6 +382,105 @@ func TestInvokeRunnerTimeout(t *testing.T) {
 	}
 }
 
+func TestInvokeSyncCallMaxTimeout(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	longFn := &models.Fn{ID: "long", Name: "long", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 300}}
+	shortFn := &models.Fn{ID: "short", Name: "short", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 10}}
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{longFn, shortFn})
+
+	for i, test := range []struct {
+		path            string
+		detached        bool
+		expectedTimeout int32
+		expectedError   error
+	}{
+		{"/invoke/long", false, 30, models.ErrCallTimeoutServerMax},
+		{"/invoke/short", false, 10, models.ErrCallTimeout},
+		{"/invoke/long", true, 300, models.ErrCallTimeout},
+	} {
+		var timeout int32
+		rnr := new(agent.MockAgent)
+		rnr.On("AddCallListener", mock.Anything)
+		rnr.On("GetCall", mock.Anything)
+		rnr.On("Submit", mock.Anything).Run(func(args mock.Arguments) {
+			timeout = args.Get(0).(agent.Call).Model().Timeout
+		}).Return(models.ErrCallTimeout)
+
+		srv := testServer(ds, rnr, ServerTypeFull, WithSyncCallMaxTimeout(30*time.Second))
+
+		req := createRequest(t, http.MethodPost, test.path, strings.NewReader(`{}`))
+		if test.detached {
+			req.Header.Set("Fn-Invoke-Type", models.TypeDetached)
+		}
+		_, rec := routerRequest2(t, srv.Router, req)
+
+		if timeout != test.expectedTimeout {
+			t.Errorf("Test %d: expected call timeout %d, got %d", i, test.expectedTimeout, timeout)
+		}
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Errorf("Test %d: expected status code 504 but was %d", i, rec.Code)
+		}
+		resp := getErrorResponse(t, rec)
+		if resp == nil || resp.Message != test.expectedError.Error() {
+			t.Errorf("Test %d: expected error `%s`, got %s", i, test.expectedError, rec.Body.String())
+		}
+	}
+}
+
+func TestInvokeTrailers(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}}
+	trigger := &models.Trigger{ID: "trigger_id", Name: "mytrigger", AppID: app.ID, FnID: fn.ID, Type: models.TriggerTypeHTTP, Source: "/src"}
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn}, []*models.Trigger{trigger})
+
+	for i, path := range []string{"/invoke/fn_id", "/t/myapp/src"} {
+		rnr := new(agent.MockAgent)
+		rnr.On("AddCallListener", mock.Anything)
+		rnr.On("GetCall", mock.Anything)
+		rnr.On("Submit", mock.Anything).Run(func(args mock.Arguments) {
+			// as the agent writes a streamed response which fails partway
+			rw := args.Get(0).(interface{ ResponseWriter() http.ResponseWriter }).ResponseWriter()
+			rw.Header().Set("Trailer", "Fn-Stream-Status")
+			rw.WriteHeader(http.StatusOK)
+			rw.Write([]byte("partial"))
+			rw.Header().Set(http.TrailerPrefix+"Fn-Stream-Status", "error")
+		}).Return(nil)
+
+		srv := testServer(ds, rnr, ServerTypeFull)
+
+		req := createRequest(t, http.MethodPost, path, strings.NewReader(`{}`))
+		_, rec := routerRequest2(t, srv.Router, req)
+		resp := rec.Result()
+
+		if rec.Code != http.StatusOK || rec.Body.String() != "partial" {
+			t.Errorf("Test %d: expected status code 200 and the body, got %d %q", i, rec.Code, rec.Body.String())
+		}
+		if resp.Header.Get("Trailer") != "Fn-Stream-Status" {
+			t.Errorf("Test %d: expected the trailer to be declared, got %v", i, resp.Header)
+		}
+		if resp.Header.Get("Fn-Stream-Status") != "" || resp.Header.Get("Content-Length") != "" {
+			t.Errorf("Test %d: expected the trailer to be sent after a chunked body, got %v", i, resp.Header)
+		}
+		if resp.Trailer.Get("Fn-Stream-Status") != "error" {
+			t.Errorf("Test %d: expected the trailing status, got %v", i, resp.Trailer)
+		}
+	}
+}
+
 // Minimal test that checks the possibility of invoking concurrent hot sync functions.
 func TestInvokeRunnerMinimalConcurrentHotSync(t *testing.T) {
 	buf := setLogBuffer()
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fnproject/fn/api"
 	"github.com/fnproject/fn/api/common"
@@ -34,12 +35,12 @@ func (s *Server) handleTriggerHTTPFunctionCall2(c *gin.Context) error {
 
 	appID, err := s.lbReadAccess.GetAppID(ctx, appName)
 	if err != nil {
-		return err
+		return s.handleInvocationMiss(c, err)
 	}
 
 	app, err := s.lbReadAccess.GetAppByID(ctx, appID)
 	if err != nil {
-		return err
+		return s.handleInvocationMiss(c, err)
 	}
 
 	routePath := p
@@ -47,12 +48,15 @@ func (s *Server) handleTriggerHTTPFunctionCall2(c *gin.Context) error {
 	trigger, err := s.lbReadAccess.GetTriggerBySource(ctx, appID, "http", routePath)
 
 	if err != nil {
-		return err
+		return s.handleInvocationMiss(c, err)
 	}
 
-	fn, err := s.lbReadAccess.GetFnByID(ctx, trigger.FnID)
+	fn, err := s.triggerFn(ctx, appID, trigger)
 	if err != nil {
-		return err
+		return s.handleInvocationMiss(c, err)
+	}
+	if s.debugHeaders {
+		c.Header(fnIDHeader, fn.ID)
 	}
 	// gin sets this to 404 on NoRoute, so we'll just ensure it's 200 by default.
 	c.Status(200) // this doesn't write the header yet
@@ -75,6 +79,8 @@ func (s *Server) handleTriggerHTTPFunctionCall2(c *gin.Context) error {
 type triggerResponseWriter struct {
 	inner     http.ResponseWriter
 	committed bool
+	// the header of models.TriggerResponseStatusHeaderAnnotation, if any
+	statusHeader string
 }
 
 func (trw *triggerResponseWriter) Header() http.Header {
@@ -94,15 +100,18 @@ func (trw *triggerResponseWriter) WriteHeader(serviceStatus int) {
 	}
 	trw.committed = true
 
-	userStatus := 0
+	userStatus, mappedStatus := 0, 0
 	realHeaders := trw.Header()
 	gwHeaders := make(http.Header, len(realHeaders))
+	corsHeaders := make(http.Header)
 	for k, vs := range realHeaders {
 		switch {
 		case strings.HasPrefix(k, "Fn-Http-H-"):
 			gwHeader := strings.TrimPrefix(k, "Fn-Http-H-")
-			if gwHeader != "" { // case where header is exactly the prefix
-				gwHeaders[gwHeader] = vs
+			if gwHeader != "" && gwHeader == trw.statusHeader {
+				mappedStatus = responseStatus(vs)
+			} else if gwHeader != "" { // case where header is exactly the prefix
+				gwHeaders[gwHeader] = append(gwHeaders[gwHeader], vs...)
 			}
 		case k == "Fn-Http-Status":
 			if len(vs) > 0 {
@@ -111,7 +120,21 @@ func (trw *triggerResponseWriter) WriteHeader(serviceStatus int) {
 					userStatus = statusInt
 				}
 			}
-		case k == "Content-Type", k == "Fn-Call-Id":
+		case k == "Content-Type", k == "Fn-Call-Id", k == fnIDHeader:
+			gwHeaders[k] = vs
+		case k == serverTimingHeader:
+			// set by the server, the function may send its own timings too
+			gwHeaders[k] = append(gwHeaders[k], vs...)
+		case k == "Trailer", strings.HasPrefix(k, http.TrailerPrefix):
+			// trailers of the function are sent as they are, after the body
+			gwHeaders[k] = vs
+		case strings.HasPrefix(k, "Access-Control-"), k == "Vary":
+			// set by invokeCORSWrap, unless the function sets its own
+			corsHeaders[k] = vs
+		}
+	}
+	for k, vs := range corsHeaders {
+		if _, ok := gwHeaders[k]; !ok {
 			gwHeaders[k] = vs
 		}
 	}
@@ -128,6 +151,8 @@ func (trw *triggerResponseWriter) WriteHeader(serviceStatus int) {
 	finalStatus := 200
 	if serviceStatus >= 400 {
 		finalStatus = serviceStatus
+	} else if mappedStatus > 0 {
+		finalStatus = mappedStatus
 	} else if userStatus > 0 {
 		finalStatus = userStatus
 	}
@@ -135,6 +160,19 @@ func (trw *triggerResponseWriter) WriteHeader(serviceStatus int) {
 	trw.inner.WriteHeader(finalStatus)
 }
 
+// responseStatus returns the status of the values of a response status
+// header, or 0 if it isn't a status from 200 to 599
+func responseStatus(vs []string) int {
+	if len(vs) == 0 {
+		return 0
+	}
+	status, err := strconv.Atoi(strings.TrimSpace(vs[0]))
+	if err != nil || status < 200 || status > 599 {
+		return 0
+	}
+	return status
+}
+
 func reqURL(req *http.Request) string {
 	if req.URL.Scheme == "" {
 		if req.TLS == nil {
@@ -152,6 +190,43 @@ func reqURL(req *http.Request) string {
 // ServeHTTPTrigger serves an HTTP trigger for a given app/fn/trigger based on the current request
 // This is exported to allow extensions to handle their own trigger naming and publishing
 func (s *Server) ServeHTTPTrigger(c *gin.Context, app *models.App, fn *models.Fn, trigger *models.Trigger) error {
+	// before the rate limit, so that unsigned calls don't use up that of the app
+	if err := verifyTriggerSignature(c.Request, app, time.Now()); err != nil {
+		return err
+	}
+	if err := s.checkInvokeRate(c.Request.Context(), c.Writer, app); err != nil {
+		return err
+	}
+	if err := s.checkInvokeContentType(c.Request, app); err != nil {
+		return err
+	}
+	if err := s.limitCallBody(c, app); err != nil {
+		return err
+	}
+	// before the headers are transposed, so that they never reach the function
+	fn, err := s.applyCallOverrides(c.Request, app, fn)
+	if err != nil {
+		return err
+	}
+	// check the body before its checksum headers are transposed
+	if err := verifyBodyChecksum(c.Request, app); err != nil {
+		return err
+	}
+	// before the body is validated, once its checksum was checked
+	if err := s.decompressRequestBody(c.Request, app); err != nil {
+		return err
+	}
+	if err := validateInputSchema(c.Request, trigger); err != nil {
+		return err
+	}
+	// before the upgrade headers are stripped
+	if err := s.checkWebSocket(c); err != nil {
+		return err
+	}
+	if err := s.transformRequestBody(c); err != nil {
+		return err
+	}
+
 	// transpose trigger headers into the request
 	req := c.Request
 	headers := make(http.Header, len(req.Header))
@@ -175,7 +250,9 @@ func (s *Server) ServeHTTPTrigger(c *gin.Context, app *models.App, fn *models.Fn
 	req.Header = headers
 
 	// trap the headers and rewrite them for http trigger
-	rw := &triggerResponseWriter{inner: c.Writer}
+	// the annotation was validated when the trigger was stored
+	statusHeader, _ := trigger.ResponseStatusHeader()
+	rw := &triggerResponseWriter{inner: c.Writer, statusHeader: statusHeader}
 
-	return s.fnInvoke(rw, req, app, fn, trigger)
+	return s.fnInvoke(c, rw, req, app, fn, trigger)
 }
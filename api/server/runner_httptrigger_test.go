@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
 	"strings"
@@ -47,6 +48,41 @@ func checkLogs(t *testing.T, tnum int, callID string, expected []string) bool {
 	return true
 }
 
+func TestResponseStatusHeader(t *testing.T) {
+	for i, test := range []struct {
+		statusHeader  string
+		headers       map[string]string
+		serviceStatus int
+		expected      int
+	}{
+		{"", map[string]string{"Fn-Http-Status": "201"}, 200, 201},
+		{"X-Status", map[string]string{"Fn-Http-Status": "200", "Fn-Http-H-X-Status": "422"}, 200, 422},
+		{"X-Status", map[string]string{"Fn-Http-H-X-Status": "404"}, 200, 404},
+		// platform errors keep their status
+		{"X-Status", map[string]string{"Fn-Http-H-X-Status": "200"}, 502, 502},
+		// invalid statuses are ignored
+		{"X-Status", map[string]string{"Fn-Http-Status": "201", "Fn-Http-H-X-Status": "99"}, 200, 201},
+		{"X-Status", map[string]string{"Fn-Http-H-X-Status": "600"}, 200, 200},
+		{"X-Status", map[string]string{"Fn-Http-H-X-Status": "teapot"}, 200, 200},
+		// without the annotation, the header is just a header
+		{"", map[string]string{"Fn-Http-H-X-Status": "422"}, 200, 200},
+	} {
+		rec := httptest.NewRecorder()
+		rw := &triggerResponseWriter{inner: rec, statusHeader: test.statusHeader}
+		for k, v := range test.headers {
+			rw.Header().Set(k, v)
+		}
+		rw.WriteHeader(test.serviceStatus)
+
+		if rec.Code != test.expected {
+			t.Errorf("Test %d: expected status %d, got %d", i, test.expected, rec.Code)
+		}
+		if v := rec.Header().Get("X-Status"); test.statusHeader != "" && v != "" {
+			t.Errorf("Test %d: expected the status header to be removed, got %q", i, v)
+		}
+	}
+}
+
 func TestTriggerRunnerGet(t *testing.T) {
 	buf := setLogBuffer()
 	app := &models.App{ID: "app_id", Name: "myapp", Config: models.Config{}}
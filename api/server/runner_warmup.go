@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// WithRunnerWarmup makes LB nodes probe all their runners once the runner
+// pool is built, so that connections are set up before the first calls are
+// placed. It runs in the background and runners which can't be reached are
+// only logged. It must come before WithAgentFromEnv.
+func WithRunnerWarmup() Option {
+	return func(ctx context.Context, s *Server) error {
+		s.runnerWarmup = true
+		return nil
+	}
+}
+
+// warmRunners probes the runners of the LB runner pool with a Status call,
+// and logs how many of them answered.
+func (s *Server) warmRunners(ctx context.Context) {
+	log := common.Logger(ctx)
+	runners, err := s.lbRunnerPool.InspectRunners(ctx)
+	if err != nil {
+		log.WithError(err).Error("Failed to warm up runners")
+		return
+	}
+
+	warmed := 0
+	for _, r := range runners {
+		if r.Healthy {
+			warmed++
+		} else {
+			log.WithField("runner_addr", r.Address).WithField("error", r.Error).Warn("Failed to warm up runner")
+		}
+	}
+	log.WithField("warmed", warmed).WithField("runners", len(runners)).Info("Warmed up runners")
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"net/http"
+
+	pool "github.com/fnproject/fn/api/runnerpool"
+	"github.com/gin-gonic/gin"
+)
+
+type runnersResponse struct {
+	NodeType string            `json:"node_type"`
+	Runners  []pool.RunnerInfo `json:"runners"`
+}
+
+// handleRunnerList lists the runners an LB node places calls on, with their
+// health and the calls placed on each. Other node types have no runners.
+func (s *Server) handleRunnerList(c *gin.Context) {
+	resp := runnersResponse{
+		NodeType: s.nodeType.String(),
+		Runners:  []pool.RunnerInfo{},
+	}
+
+	if s.lbRunnerPool != nil {
+		runners, err := s.lbRunnerPool.InspectRunners(c.Request.Context())
+		if err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+		resp.Runners = runners
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
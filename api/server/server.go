@@ -4,9 +4,12 @@ import (
 	"bufio"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"path"
@@ -14,13 +17,16 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 	"unicode"
 
 	"contrib.go.opencensus.io/exporter/jaeger"
 	"contrib.go.opencensus.io/exporter/prometheus"
 	"contrib.go.opencensus.io/exporter/zipkin"
 	"github.com/gin-gonic/gin"
+	"github.com/openzipkin/zipkin-go/model"
 	zipkinhttp "github.com/openzipkin/zipkin-go/reporter/http"
 	promclient "github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
@@ -50,27 +56,55 @@ const (
 	// EnvLogLevel sets the stderr logging level
 	EnvLogLevel = "FN_LOG_LEVEL"
 
-	// EnvLogDest is a url of a log destination:
+	// EnvLogDest is a url of a log destination, or a comma separated list of them to send logs to all:
 	// possible schemes: { udp, tcp, file }
 	// file url must contain only a path, syslog must contain only a host[:port]
 	// expect: [scheme://][host][:port][/path]
 	// default scheme to udp:// if none given
+	// A failing destination does not stop logs from being sent to the others, see common.SetLogDests.
 	EnvLogDest = "FN_LOG_DEST"
 
 	// EnvLogPrefix is a prefix to affix to each log line.
 	EnvLogPrefix = "FN_LOG_PREFIX"
 
 	// EnvDBURL is a url to a db service:
-	// possible schemes: { postgres, sqlite3, mysql }
+	// possible schemes: { postgres, sqlite3, mysql, memory }
+	// memory:// keeps everything in memory, it is lost on restart.
 	EnvDBURL = "FN_DB_URL"
 
+	// EnvDependencyWaitTimeout sets how long to retry connecting to the db on startup, for it to come up,
+	// before giving up. Defaults to 0, failing on the first error.
+	EnvDependencyWaitTimeout = "FN_DEPENDENCY_WAIT_TIMEOUT"
+
+	// EnvDBMaxOpenConns caps the connections each node opens to the db, 0, the default, means no cap.
+	// Times the number of API and LB nodes, it should stay below the max_connections of the db server.
+	EnvDBMaxOpenConns = "FN_DB_MAX_OPEN_CONNS"
+
+	// EnvDBMaxIdleConns sets the idle connections to the db each node keeps, 256 by default.
+	EnvDBMaxIdleConns = "FN_DB_MAX_IDLE_CONNS"
+
+	// EnvDBConnMaxLifetime sets how long connections to the db are reused for, e.g. to rebalance them
+	// behind a proxy. 0, the default, means they are reused forever.
+	EnvDBConnMaxLifetime = "FN_DB_CONN_MAX_LIFETIME"
+
 	// EnvRunnerURL is a url pointing to an Fn API service.
 	EnvRunnerURL = "FN_RUNNER_API_URL"
 
+	// EnvDNSServer is the host:port of a DNS server to resolve the hosts of the API and runners with, see WithResolver.
+	EnvDNSServer = "FN_DNS_SERVER"
+
 	// EnvRunnerAddresses is a list of runner urls for an lb to use.
+	// A dns+srv:// URL, e.g. dns+srv://_fn-runner._tcp.runners.svc, reads them from DNS as EnvRunnerSRV.
 	EnvRunnerAddresses = "FN_RUNNER_ADDRESSES"
 
-	// EnvPublicLoadBalancerURL is the url to inject into trigger responses to get a public url.
+	// EnvRunnerSRV is a DNS SRV name the runner urls of an lb are resolved from, instead of EnvRunnerAddresses.
+	EnvRunnerSRV = "FN_RUNNER_SRV"
+
+	// EnvRunnerSRVRefresh is how often the runner urls of EnvRunnerSRV are resolved again, in the format
+	// of the timeouts below.
+	EnvRunnerSRVRefresh = "FN_RUNNER_SRV_REFRESH"
+
+	// EnvPublicLoadBalancerURL is the url to inject into trigger responses to get a public url, and the server of the OpenAPI spec.
 	EnvPublicLoadBalancerURL = "FN_PUBLIC_LB_URL"
 
 	// EnvNodeType defines the runtime mode for fn to run in, options
@@ -83,33 +117,161 @@ const (
 	// EnvGRPCPort is the port to run the grpc server on for a pure-runner node.
 	EnvGRPCPort = "FN_GRPC_PORT"
 
+	// EnvNodeCert, EnvNodeCertKey and EnvNodeCertAuthority are the PEM files of the cert, its key and the
+	// CA of the client certs of the grpc server of a pure-runner node. Rotated files are used by new
+	// connections without a restart. LB nodes present the cert to the runner API of API nodes, and trust
+	// the certs the CA issued.
+	EnvNodeCert          = "FN_NODE_CERT"
+	EnvNodeCertKey       = "FN_NODE_CERT_KEY"
+	EnvNodeCertAuthority = "FN_NODE_CERT_AUTHORITY"
+
+	// EnvRunnerAPIMTLS only serves the runner API of an API node to LB nodes presenting a cert issued by
+	// FN_NODE_CERT_AUTHORITY. The web server is then served over TLS with FN_NODE_CERT.
+	EnvRunnerAPIMTLS = "FN_RUNNER_API_MTLS"
+
 	// EnvAPICORSOrigins is the list of CORS origins to allow.
 	EnvAPICORSOrigins = "FN_API_CORS_ORIGINS"
 
 	// EnvAPICORSHeaders is the list of CORS headers allowed.
 	EnvAPICORSHeaders = "FN_API_CORS_HEADERS"
 
+	// EnvInvokeCORSOrigins is the list of CORS origins to allow on the trigger and invoke endpoints. If it is not
+	// set, they allow those of FN_API_CORS_ORIGINS, with the headers of FN_API_CORS_HEADERS; set it empty to
+	// allow none.
+	EnvInvokeCORSOrigins = "FN_INVOKE_CORS_ORIGINS"
+
+	// EnvInvokeCORSHeaders is the list of CORS headers allowed on the trigger and invoke endpoints.
+	EnvInvokeCORSHeaders = "FN_INVOKE_CORS_HEADERS"
+
+	// EnvInvokeAllowedContentTypes is the comma separated list of content types, e.g. "application/json,text/*",
+	// which may be sent to functions on the trigger and invoke endpoints. Empty allows any.
+	EnvInvokeAllowedContentTypes = "FN_INVOKE_ALLOWED_CONTENT_TYPES"
+
 	// EnvZipkinURL is the url of a zipkin node to send traces to.
 	EnvZipkinURL = "FN_ZIPKIN_URL"
 
 	// EnvJaegerURL is the url of a jaeger node to send traces to.
 	EnvJaegerURL = "FN_JAEGER_URL"
 
+	// EnvTraceServiceName is the service name of the spans sent to jaeger or zipkin.
+	EnvTraceServiceName = "FN_TRACE_SERVICE_NAME"
+
+	// EnvTraceTags is a comma separated list of key=val tags added to every span
+	// sent to jaeger or zipkin, e.g. "cluster=prod,region=us-phoenix-1".
+	EnvTraceTags = "FN_TRACE_TAGS"
+
+	// EnvTraceSampleRate is the rate, from 0 to 1, of the traces sent to jaeger or zipkin.
+	EnvTraceSampleRate = "FN_TRACE_SAMPLE_RATE"
+
+	// EnvConfigFile is a file of KEY=VALUE lines, e.g. FN_LOG_LEVEL=debug, loaded
+	// over the environment at startup and on SIGHUP. See (*Server).Reload.
+	EnvConfigFile = "FN_CONFIG_FILE"
+
+	// EnvMetricNamespace prefixes the names of the prometheus metrics, defaulting to fn for full nodes and to
+	// fn_<node type> for the others, e.g. fn_lb. Changing it renames all the metrics, see WithMetricNamespace.
+	EnvMetricNamespace = "FN_METRIC_NAMESPACE"
+
+	// EnvStatsDAddr is the host:port of a StatsD or DogStatsD server to push metrics to.
+	EnvStatsDAddr = "FN_STATSD_ADDR"
+
+	// EnvAccessLogSampleRate sets the fraction, from 0 to 1, of successful requests which are logged.
+	// Requests ending with an error are always logged. Defaults to 1.
+	EnvAccessLogSampleRate = "FN_ACCESS_LOG_SAMPLE_RATE"
+
 	// EnvRIDHeader is the header name of the incoming request which holds the request ID
 	EnvRIDHeader = "FN_RID_HEADER"
 
 	// EnvProcessCollectorList is the list of procid's to collect metrics for.
 	EnvProcessCollectorList = "FN_PROCESS_COLLECTOR_LIST"
 
-	// EnvLBPlacementAlg is the algorithm to place fn calls to fn runners in lb.[0w
+	// EnvLBPlacementAlg is the algorithm to place fn calls to fn runners in lb, naive by default, ch, or
+	// the name a custom placer is registered with, see runnerpool.RegisterPlacer.
 	EnvLBPlacementAlg = "FN_PLACER"
 
+	// EnvLBQueueWait is how long lb holds a call while all runners are busy, retrying to place it,
+	// before failing it with a 503. It is set in the same format as the timeouts below.
+	EnvLBQueueWait = "FN_LB_QUEUE_WAIT"
+
+	// EnvLBQueueMaxDepth is the maximum number of calls lb holds at once while all runners are busy,
+	// calls beyond it are rejected with a 429. 0, the default, is unbounded.
+	EnvLBQueueMaxDepth = "FN_LB_QUEUE_MAX_DEPTH"
+
+	// EnvCircuitFailureThreshold is the failure rate of the calls tried on a runner
+	// that opens its circuit in lb, between 0 and 1. The circuit breakers are
+	// disabled if it is not set.
+	EnvCircuitFailureThreshold = "FN_CIRCUIT_FAILURE_THRESHOLD"
+
+	// EnvCircuitCooldown is how long runners with an open circuit are skipped in lb,
+	// before a call probes them.
+	EnvCircuitCooldown = "FN_CIRCUIT_COOLDOWN"
+
+	// EnvRunnerHealthType is how LB nodes probe the health of their runners, with their gRPC status
+	// call, with grpc, the default, or with an HTTP GET of FN_RUNNER_HEALTH_PATH, with http.
+	EnvRunnerHealthType = "FN_RUNNER_HEALTH_TYPE"
+
+	// EnvRunnerHealthPath is the path of the http runner health check, / by default.
+	EnvRunnerHealthPath = "FN_RUNNER_HEALTH_PATH"
+
+	// EnvRunnerHealthPort is the port of the http runner health check, e.g. that of a sidecar,
+	// defaulting to the port of the runner.
+	EnvRunnerHealthPort = "FN_RUNNER_HEALTH_PORT"
+
+	// EnvRunnerHealthStatus is the status healthy runners answer the http runner health check with, 200 by default.
+	EnvRunnerHealthStatus = "FN_RUNNER_HEALTH_STATUS"
+
+	// EnvRunnerWarmup sets whether LB nodes probe all their runners at startup, so that connections are
+	// set up before the first calls are placed.
+	EnvRunnerWarmup = "FN_RUNNER_WARMUP"
+
 	// EnvMaxRequestSize sets the limit in bytes for any API request body's length.
 	EnvMaxRequestSize = "FN_MAX_REQUEST_SIZE"
 
+	// EnvMaxAppRequestSize sets the ceiling in bytes of the limits apps set on the bodies of the calls to their
+	// functions with the fn.max-body annotation, 100MiB by default, see WithMaxAppRequestSize.
+	EnvMaxAppRequestSize = "FN_MAX_APP_REQUEST_SIZE"
+
+	// EnvJSONMaxDepth sets the limit of nesting of the JSON bodies of requests creating and updating apps, fns
+	// and triggers, see WithJSONLimits. 0 disables it.
+	EnvJSONMaxDepth = "FN_JSON_MAX_DEPTH"
+
+	// EnvJSONMaxTokens sets the limit of tokens, values and keys, of the JSON bodies of requests creating and
+	// updating apps, fns and triggers, see WithJSONLimits. 0 disables it.
+	EnvJSONMaxTokens = "FN_JSON_MAX_TOKENS"
+
+	// EnvDecompressRequests decompresses the gzip or deflate encoded bodies of calls before passing them to functions.
+	EnvDecompressRequests = "FN_DECOMPRESS_REQUESTS"
+
+	// EnvDecompressMaxSize sets the limit in bytes of decompressed call bodies, see WithRequestDecompression.
+	EnvDecompressMaxSize = "FN_DECOMPRESS_MAX_SIZE"
+
 	// EnvMaxHeaderSize sets the limit in bytes for any API request body's length.
 	EnvMaxHeaderSize = "FN_MAX_REQUEST_HEADER_SIZE"
 
+	// EnvMaxConnections sets the limit of concurrently accepted connections for each of the web and admin servers.
+	EnvMaxConnections = "FN_MAX_CONNECTIONS"
+
+	// EnvReusePort sets SO_REUSEPORT on the listeners of the web and admin servers, Linux only.
+	EnvReusePort = "FN_REUSE_PORT"
+
+	// EnvServerTiming adds a Server-Timing header with the time spent in each phase to invocation responses.
+	EnvServerTiming = "FN_SERVER_TIMING"
+
+	// EnvDebugHeaders adds headers reporting how calls through triggers were routed to their responses, such as
+	// the fn that served them, see WithDebugHeaders.
+	EnvDebugHeaders = "FN_DEBUG_HEADERS"
+
+	// EnvBackpressureInFlight sets the number of calls in flight on a node over which new calls are rejected
+	// with a 503 and a Retry-After header, see WithBackpressure.
+	EnvBackpressureInFlight = "FN_BACKPRESSURE_INFLIGHT"
+
+	// EnvBackpressureMemory sets the bytes of heap in use by the server over which new calls are rejected
+	// with a 503 and a Retry-After header, see WithBackpressure.
+	EnvBackpressureMemory = "FN_BACKPRESSURE_MEMORY"
+
+	// EnvDefaultInvokeRate sets the calls per second the functions of each app may be invoked at on each node,
+	// see WithInvokeRateLimit.
+	EnvDefaultInvokeRate = "FN_DEFAULT_INVOKE_RATE"
+
 	// The following 4 env-vars (FN_REQUEST_BODY_READ_TIMEOUT, FN_REQUEST_HEADER_READ_TIMEOUT, FN_RESPONSE_WRITE_TIMEOUT, FN_HTTP_IDLE_TIMEOUT)
 	// need to be set as strings that are either :
 	// 1. Valid integral values of duration in seconds ("120", "125")
@@ -127,6 +289,144 @@ const (
 	// EnvHTTPIdleTimeout maximum amount of time to wait for the next request.
 	EnvHTTPIdleTimeout = "FN_HTTP_IDLE_TIMEOUT"
 
+	// EnvShutdownTimeout bounds how long the server waits, on shutdown, for the requests and calls in flight
+	// to complete before cancelling them, see WithShutdownTimeout. It is set in the same format as the timeouts
+	// above, and waits as long as they take by default.
+	EnvShutdownTimeout = "FN_SHUTDOWN_TIMEOUT"
+
+	// EnvSyncCallMaxTimeout caps the timeout of synchronous calls, regardless of the timeout of the function.
+	// It is set in the same format as the timeouts above.
+	EnvSyncCallMaxTimeout = "FN_SYNC_CALL_MAX_TIMEOUT"
+
+	// EnvMaxResponseHeaders caps the number of header values functions may respond to sync calls with,
+	// past which they are dropped. FN_MAX_RESPONSE_SIZE also truncates the bodies they respond with on
+	// every node, see WithMaxResponse.
+	EnvMaxResponseHeaders = "FN_MAX_RESPONSE_HEADERS"
+
+	// EnvPerFunctionMetricsMax sets the number of fns whose invocations are counted under their own
+	// fn_id label, those of other fns are counted under __other__. 0, the default, disables the counts.
+	EnvPerFunctionMetricsMax = "FN_PER_FUNCTION_METRICS_MAX"
+
+	// EnvMaxMemoryMB caps the memory, in MB, privileged calls may override the memory of their function with,
+	// see WithCallOverrides.
+	EnvMaxMemoryMB = "FN_MAX_MEMORY_MB"
+
+	// EnvIdempotencyTTL sets how long the responses of sync calls made with an Idempotency-Key header are
+	// replayed for. It is set in the same format as the timeouts above, 0 disables idempotency keys. Responses
+	// are stored in memory, per server: behind a load balancer, retries reaching another server run again, see
+	// WithIdempotency.
+	EnvIdempotencyTTL = "FN_IDEMPOTENCY_TTL"
+
+	// EnvIdempotencyMaxBody sets the size in bytes of the largest response stored for an idempotency key.
+	EnvIdempotencyMaxBody = "FN_IDEMPOTENCY_MAX_BODY"
+
+	// EnvResponseCacheMaxBody sets the size in bytes of the largest response cached for the triggers with the
+	// fn.cache-ttl annotation, see WithResponseCache.
+	EnvResponseCacheMaxBody = "FN_RESPONSE_CACHE_MAX_BODY"
+
+	// EnvResponseCacheMaxSize sets the bytes of responses, with their keys and headers, cached in memory, 64MiB by
+	// default, for up to 10000 responses. 0 disables the response cache.
+	EnvResponseCacheMaxSize = "FN_RESPONSE_CACHE_MAX_SIZE"
+
+	// EnvEnableWebSocket sets whether calls to functions may upgrade to WebSocket connections, proxied to the
+	// hot containers running them. Only full nodes support it.
+	EnvEnableWebSocket = "FN_ENABLE_WEBSOCKET"
+
+	// EnvAPIRequestTimeout sets the timeout limit for handling a request to the API, not including the trigger
+	// and invoke endpoints. It is set in the same format as the timeouts above.
+	EnvAPIRequestTimeout = "FN_API_REQUEST_TIMEOUT"
+
+	// EnvHybridRetryMaxElapsed sets how long LB nodes retry failed requests to the API for.
+	// It is set in the same format as the timeouts above, 0 disables retries.
+	EnvHybridRetryMaxElapsed = "FN_HYBRID_RETRY_MAX_ELAPSED"
+
+	// EnvDataCacheTTL sets how long apps, fns and triggers read to route and run calls are cached for.
+	// It is set in the same format as the timeouts above, 0 disables caching.
+	EnvDataCacheTTL = "FN_DATA_CACHE_TTL"
+
+	// EnvInvalidationWebhookURLs sets a comma separated list of URLs, the /cache/invalidate admin
+	// endpoints of LB nodes, which API nodes post data cache invalidations to on each change. The
+	// endpoint is served with an admin token only, the API nodes post with their FN_ADMIN_TOKEN,
+	// so API and LB nodes must share it.
+	EnvInvalidationWebhookURLs = "FN_INVALIDATION_WEBHOOK_URLS"
+
+	// EnvBasePath is a path prefix all the routes are mounted under, e.g. /fn
+	// when fronted by an ingress which does not strip it.
+	EnvBasePath = "FN_BASE_PATH"
+
+	// EnvTrustedProxies sets a comma separated list of the IP addresses and CIDR ranges of proxies whose
+	// X-Forwarded-Proto, X-Forwarded-Host and X-Forwarded-Port headers are used to build endpoint URLs. Only
+	// the last value of each header, the one appended by the trusted proxy, is used.
+	EnvTrustedProxies = "FN_TRUSTED_PROXIES"
+
+	// EnvRequireHTTPS sets whether requests over plain HTTP are redirected to HTTPS, with redirect, or
+	// rejected, with reject. The scheme of requests from FN_TRUSTED_PROXIES is their X-Forwarded-Proto.
+	EnvRequireHTTPS = "FN_REQUIRE_HTTPS"
+
+	// EnvSecurityHeaders sets whether the responses of the /v2 API and the admin server carry the
+	// X-Content-Type-Options, X-Frame-Options and, over TLS, Strict-Transport-Security headers.
+	EnvSecurityHeaders = "FN_SECURITY_HEADERS"
+
+	// EnvReservedAnnotationPrefixes sets a comma separated list of the annotation key prefixes reserved for
+	// the platform, which clients may not set through the API. Defaults to fn., empty reserves none.
+	EnvReservedAnnotationPrefixes = "FN_RESERVED_ANNOTATION_PREFIXES"
+
+	// EnvAllowedAnnotationPrefixes sets a comma separated list of the only annotation key prefixes clients
+	// may set through the API, besides the documented app annotations. By default any prefix not reserved is.
+	EnvAllowedAnnotationPrefixes = "FN_ALLOWED_ANNOTATION_PREFIXES"
+
+	// EnvOperatorAnnotationKeys sets a comma separated list of the annotation keys only requests made with
+	// FN_API_ROOT_TOKEN may set through the API, whatever the prefixes above, see WithOperatorAnnotationKeys.
+	EnvOperatorAnnotationKeys = "FN_OPERATOR_ANNOTATION_KEYS"
+
+	// EnvEnableRunnerAPI sets whether API and full nodes serve the internal /v2/runner API, which
+	// only LB nodes call, to look up apps and triggers. Defaults to true. It requires FN_RUNNER_API_MTLS
+	// client certificates, or else FN_API_ROOT_TOKEN if set, to return the secrets of apps.
+	EnvEnableRunnerAPI = "FN_ENABLE_RUNNER_API"
+
+	// EnvAPIRootToken sets a token allowed every request to the /v2 API. When set, requests to the /v2 API
+	// must carry an API token, either this one or one created with the /v2/tokens endpoints, and LB nodes
+	// must send it with FN_RUNNER_API_TOKEN.
+	EnvAPIRootToken = "FN_API_ROOT_TOKEN"
+
+	// EnvRunnerAPIToken sets the API token LB nodes send to the API nodes of FN_RUNNER_API_URL. It is
+	// required if they set FN_API_ROOT_TOKEN, whose value it must then be.
+	EnvRunnerAPIToken = "FN_RUNNER_API_TOKEN"
+
+	// EnvAdminToken sets the token the admin server endpoints that change running calls, such as
+	// /debug/calls, change the caches or tracing of the node, such as /cache/invalidate and /debug/trace,
+	// or report on it, such as /debug/runners and /debug/migrations, and /v2/fns/:fn_id/runtime require as
+	// Authorization: Bearer <token>. They are not served when it is not set.
+	EnvAdminToken = "FN_ADMIN_TOKEN"
+
+	// EnvDebugCaptureRedactHeaders is a comma separated list of the request and response headers
+	// redacted from the calls captured with POST /debug/capture, on top of Authorization, Cookie and
+	// the like, see WithDebugCapture.
+	EnvDebugCaptureRedactHeaders = "FN_DEBUG_CAPTURE_REDACT_HEADERS"
+
+	// EnvDebugCaptureMaxBody sets the bytes of the request and response bodies kept per captured call.
+	EnvDebugCaptureMaxBody = "FN_DEBUG_CAPTURE_MAX_BODY"
+
+	// EnvEnableDashboard sets whether the admin server serves a read-only dashboard of the apps, fns and
+	// triggers at /ui/, see WithDashboard. Defaults to false, and requires an admin token.
+	EnvEnableDashboard = "FN_ENABLE_DASHBOARD"
+
+	// EnvTenantHeader scopes the /v2 API to the tenant named by this request header, see WithTenantScoping.
+	EnvTenantHeader = "FN_TENANT_HEADER"
+
+	// EnvMaxFnsPerApp sets the limit of functions in each app, as a guardrail for multi-tenant clusters.
+	EnvMaxFnsPerApp = "FN_MAX_FNS_PER_APP"
+
+	// EnvMaxTriggersPerApp sets the limit of triggers in each app, as a guardrail for multi-tenant clusters.
+	EnvMaxTriggersPerApp = "FN_MAX_TRIGGERS_PER_APP"
+
+	// EnvMaxConfigKeys sets the limit of keys in the config of each app and function.
+	EnvMaxConfigKeys = "FN_MAX_CONFIG_KEYS"
+
+	// EnvMaxConfigBytes sets the limit of the total size of the keys and values in the config of
+	// each app and function.
+	EnvMaxConfigBytes = "FN_MAX_CONFIG_BYTES"
+
 	// DefaultLogFormat is text
 	DefaultLogFormat = "text"
 
@@ -203,17 +503,94 @@ type Server struct {
 	svcConfigs map[string]*http.Server
 
 	lbReadAccess           agent.ReadDataAccess
+	dataCache              agent.DataCache
+	dataCacheTTL           time.Duration
+	dbPool                 datastore.PoolConfig
+	dependencyWait         time.Duration
+	invalidationPublisher  InvalidationPublisher
+	accessLogSampleRate    float64
+	lbRunnerPool           *pool.TrackedRunnerPool
+	runnerWarmup           bool
+	shutdownTimeout        time.Duration
 	noHTTTPTriggerEndpoint bool
 	noFnInvokeEndpoint     bool
 	noProfilerEndpoint     bool
+	noRunnerAPI            bool
+	apiRootToken           string
+	adminToken             string
+	debugCaptures          *debugCaptures
+	dashboard              bool
+	tenantResolver         func(*gin.Context) string
+	invocationNotFound     gin.HandlerFunc
+	runnerAPIMTLS          bool
+	nodeCertAuthority      *x509.CertPool
+	nodeCertRoots          *x509.CertPool
+	nodeCertFile           string
+	nodeCertKeyFile        string
 	noWebServer            bool
 	noAdminServer          bool
+	maxConnections         int
+	reusePort              bool
+	serverTiming           bool
+	debugHeaders           bool
+	jsonMaxDepth           int
+	jsonMaxTokens          int
+	decompressRequests     bool
+	decompressMaxSize      int64
+	backpressure           *backpressure
+	invokeRateLimits       *invokeRateLimits
+	resolver               *net.Resolver
+	syncCallMaxTimeout     int32
+	maxRequestSize         int64
+	maxAppRequestSize      int64
+	maxResponseSize        uint64
+	maxResponseHeaders     int
+	fnMetrics              *fnMetricLabels
+	overrideMaxMemory      uint64
+	idempotencyTTL         time.Duration
+	idempotencyMaxBody     int
+	idempotencyStore       IdempotencyStore
+	responseCacheMaxBody   int
+	responseCache          ResponseCache
+	enableWebSocket        bool
+	apiRequestTimeout      time.Duration
+	maxFnsPerApp           int
+	maxTriggersPerApp      int
+	maxConfigKeys          int
+	maxConfigBytes         int
+	appLocks               appLocks
+	invokeCORSOrigins      []string
+	invokeCORSHeaders      []string
+	invokeContentTypes     []string
 	appListeners           *appListeners
 	fnListeners            *fnListeners
 	triggerListeners       *triggerListeners
 	rootMiddlewares        []fnext.Middleware
 	apiMiddlewares         []fnext.Middleware
 	promExporter           *prometheus.Exporter
+	promRegistry           *promclient.Registry
+	metricNamespace        string
+	traceServiceName       string
+	traceTags              map[string]string
+	traceMu                sync.Mutex
+	traceSampler           trace.Sampler
+	traceSampleRate        float64
+	traceFlushers          []func()
+	traceExporting         bool
+	runnerAddressSetter    agent.RunnerAddressSetter
+	basePath               string
+	shutdownHalt           context.CancelFunc
+	publicLBURL            string
+	responseHeaders        http.Header
+	trustedProxies         []*net.IPNet
+	requireHTTPS           string
+	handlerWrappers        []func(http.Handler) http.Handler
+	invocationTransform    InvocationTransform
+	responseTransform      InvocationTransform
+	reservedAnnotations    []string
+	operatorAnnotations    map[string]bool
+	allowedAnnotations     []string
+	appStats               *appStatsCollector
 	triggerAnnotator       TriggerAnnotator
 	fnAnnotator            FnAnnotator
 
@@ -236,6 +613,11 @@ func nodeTypeFromString(value string) NodeType {
 
 // NewFromEnv creates a new Functions server based on env vars.
 func NewFromEnv(ctx context.Context, opts ...Option) *Server {
+	if path := getEnv(EnvConfigFile, ""); path != "" {
+		if err := loadConfigFile(path, false); err != nil {
+			logrus.WithError(err).Fatalf("invalid %s", EnvConfigFile)
+		}
+	}
 	curDir := pwd()
 	var defaultDB string
 	nodeType := nodeTypeFromString(getEnv(EnvNodeType, "")) // default to full
@@ -248,24 +630,114 @@ func NewFromEnv(ctx context.Context, opts ...Option) *Server {
 	}
 	opts = append(opts, WithWebPort(getEnvInt(EnvPort, DefaultPort)))
 	opts = append(opts, WithGRPCPort(getEnvInt(EnvGRPCPort, DefaultGRPCPort)))
+	if cert := getEnv(EnvNodeCert, ""); cert != "" {
+		opts = append(opts, WithGRPCCertFiles(cert, getEnv(EnvNodeCertKey, ""), getEnv(EnvNodeCertAuthority, "")))
+		opts = append(opts, WithNodeCertFiles(cert, getEnv(EnvNodeCertKey, "")))
+	}
+	if ca := getEnv(EnvNodeCertAuthority, ""); ca != "" {
+		opts = append(opts, WithNodeCertAuthority(ca))
+	}
+	if getEnvBool(EnvRunnerAPIMTLS, false) {
+		opts = append(opts, WithRunnerAPIMTLS())
+	}
+	opts = append(opts, WithTraceServiceName(getEnv(EnvTraceServiceName, defaultTraceServiceName)))
+	traceTags, err := parseTraceTags(getEnv(EnvTraceTags, ""))
+	if err != nil {
+		logrus.WithError(err).Fatalf("invalid %s", EnvTraceTags)
+	}
+	opts = append(opts, WithTraceGlobalTags(traceTags))
+	opts = append(opts, WithTraceSampleRate(getEnvFloat(EnvTraceSampleRate, 1)))
 	opts = append(opts, WithZipkin(getEnv(EnvZipkinURL, "")))
 	opts = append(opts, WithJaeger(getEnv(EnvJaegerURL, "")))
+	opts = append(opts, WithMetricNamespace(getEnv(EnvMetricNamespace, defaultMetricNamespace(nodeType))))
 	opts = append(opts, WithPrometheus()) // TODO option to turn this off?
+	opts = append(opts, WithStatsD(getEnv(EnvStatsDAddr, "")))
+	opts = append(opts, WithAccessLogSampling(getEnvFloat(EnvAccessLogSampleRate, 1)))
+	opts = append(opts, WithInternalRunnerAPI(getEnvBool(EnvEnableRunnerAPI, true)))
+	opts = append(opts, WithDataCacheTTL(getEnvDuration(EnvDataCacheTTL, agent.DefaultDataCacheTTL)))
+	if urls := splitList(getEnv(EnvInvalidationWebhookURLs, "")); len(urls) > 0 {
+		opts = append(opts, WithInvalidationPublisher(NewWebhookInvalidationPublisher(urls, getEnv(EnvAdminToken, ""))))
+	}
+	opts = append(opts, WithDependencyWaitTimeout(getEnvDuration(EnvDependencyWaitTimeout, 0)))
+	opts = append(opts, WithDBPool(getEnvInt(EnvDBMaxOpenConns, 0), getEnvInt(EnvDBMaxIdleConns, datastore.DefaultMaxIdleConns), getEnvDuration(EnvDBConnMaxLifetime, 0)))
 	opts = append(opts, WithDBURL(getEnv(EnvDBURL, defaultDB)))
 	opts = append(opts, WithType(nodeType))
+	opts = append(opts, WithBasePath(getEnv(EnvBasePath, "")))
+	if getEnvBool(EnvRunnerWarmup, false) {
+		opts = append(opts, WithRunnerWarmup())
+	}
+	opts = append(opts, WithReservedAnnotationPrefixes(splitList(getEnv(EnvReservedAnnotationPrefixes, strings.Join(defaultReservedAnnotationPrefixes, ",")))))
+	opts = append(opts, WithAllowedAnnotationPrefixes(splitList(getEnv(EnvAllowedAnnotationPrefixes, ""))))
+	opts = append(opts, WithOperatorAnnotationKeys(splitList(getEnv(EnvOperatorAnnotationKeys, strings.Join(defaultOperatorAnnotationKeys, ",")))))
+	if getEnvBool(EnvSecurityHeaders, false) {
+		opts = append(opts, WithSecurityHeaders())
+	}
 
 	opts = append(opts, LimitRequestBody(int64(getEnvInt(EnvMaxRequestSize, 0))))
+	opts = append(opts, WithMaxAppRequestSize(int64(getEnvInt(EnvMaxAppRequestSize, DefaultMaxAppRequestSize))))
+	opts = append(opts, WithJSONLimits(getEnvInt(EnvJSONMaxDepth, DefaultJSONMaxDepth), getEnvInt(EnvJSONMaxTokens, DefaultJSONMaxTokens)))
+	opts = append(opts, WithRequestDecompression(getEnvBool(EnvDecompressRequests, false), int64(getEnvInt(EnvDecompressMaxSize, 0))))
+	opts = append(opts, WithMaxConnections(getEnvInt(EnvMaxConnections, 0)))
+	if getEnvBool(EnvReusePort, false) {
+		opts = append(opts, WithReusePort())
+	}
+	if getEnvBool(EnvServerTiming, false) {
+		opts = append(opts, WithServerTiming())
+	}
+	if getEnvBool(EnvDebugHeaders, false) {
+		opts = append(opts, WithDebugHeaders())
+	}
+	opts = append(opts, WithBackpressure(getEnvInt(EnvBackpressureInFlight, 0), int64(getEnvInt(EnvBackpressureMemory, 0))))
+	opts = append(opts, WithInvokeRateLimit(getEnvFloat(EnvDefaultInvokeRate, 0)))
+	opts = append(opts, WithSyncCallMaxTimeout(getEnvDuration(EnvSyncCallMaxTimeout, 0)))
+	opts = append(opts, WithPerFunctionMetrics(getEnvInt(EnvPerFunctionMetricsMax, 0)))
+	opts = append(opts, WithMaxResponse(uint64(getEnvInt(agent.EnvMaxResponseSize, 0)), getEnvInt(EnvMaxResponseHeaders, 0)))
+	opts = append(opts, WithCallOverrides(uint64(getEnvInt(EnvMaxMemoryMB, 0))))
+	if getEnvBool(EnvEnableWebSocket, false) {
+		opts = append(opts, WithWebSocket())
+	}
+	opts = append(opts, WithIdempotency(getEnvDuration(EnvIdempotencyTTL, 0), getEnvInt(EnvIdempotencyMaxBody, DefaultIdempotencyMaxBody)))
+	opts = append(opts, WithResponseCache(getEnvInt(EnvResponseCacheMaxBody, DefaultResponseCacheMaxBody), int64(getEnvInt(EnvResponseCacheMaxSize, DefaultResponseCacheMaxSize))))
+	opts = append(opts, WithAPIRequestTimeout(getEnvDuration(EnvAPIRequestTimeout, 0)))
+	opts = append(opts, WithAPITokenAuth(getEnv(EnvAPIRootToken, "")))
+	opts = append(opts, WithAdminToken(getEnv(EnvAdminToken, "")))
+	opts = append(opts, WithDebugCapture(getEnvInt(EnvDebugCaptureMaxBody, DefaultDebugCaptureMaxBody), splitList(getEnv(EnvDebugCaptureRedactHeaders, ""))))
+	if getEnvBool(EnvEnableDashboard, false) {
+		opts = append(opts, WithDashboard())
+	}
+	if header := getEnv(EnvTenantHeader, ""); header != "" {
+		opts = append(opts, WithTenantScoping(func(c *gin.Context) string { return c.GetHeader(header) }))
+	}
+	opts = append(opts, WithMaxFnsPerApp(getEnvInt(EnvMaxFnsPerApp, 0)))
+	opts = append(opts, WithMaxTriggersPerApp(getEnvInt(EnvMaxTriggersPerApp, 0)))
+	opts = append(opts, WithMaxConfig(getEnvInt(EnvMaxConfigKeys, 0), getEnvInt(EnvMaxConfigBytes, 0)))
+	opts = append(opts, WithInvokeCORS(invokeCORSFromEnv()))
+	opts = append(opts, WithInvokeAllowedContentTypes(splitList(getEnv(EnvInvokeAllowedContentTypes, ""))))
+
+	trustedProxies, err := ParseTrustedProxies(splitList(getEnv(EnvTrustedProxies, "")))
+	if err != nil {
+		logrus.WithError(err).Fatalf("invalid %s", EnvTrustedProxies)
+	}
+	opts = append(opts, WithTrustedProxies(trustedProxies...))
+	opts = append(opts, WithRequireHTTPS(getEnv(EnvRequireHTTPS, "")))
 
 	publicLBURL := getEnv(EnvPublicLoadBalancerURL, "")
 	if publicLBURL != "" {
 		logrus.Infof("using LB Base URL: '%s'", publicLBURL)
 		opts = append(opts, WithTriggerAnnotator(NewStaticURLTriggerAnnotator(publicLBURL)))
 		opts = append(opts, WithFnAnnotator(NewStaticURLFnAnnotator(publicLBURL)))
+		opts = append(opts, WithPublicLBURL(publicLBURL))
 	} else {
-		opts = append(opts, WithTriggerAnnotator(NewRequestBasedTriggerAnnotator()))
-		opts = append(opts, WithFnAnnotator(NewRequestBasedFnAnnotator()))
+		opts = append(opts, WithTriggerAnnotator(NewRequestBasedTriggerAnnotator(trustedProxies...)))
+		opts = append(opts, WithFnAnnotator(NewRequestBasedFnAnnotator(trustedProxies...)))
 	}
 
+	if dnsServer := getEnv(EnvDNSServer, ""); dnsServer != "" {
+		opts = append(opts, WithResolver(NewDNSServerResolver(dnsServer)))
+	}
+
+	opts = append(opts, WithShutdownTimeout(getEnvDuration(EnvShutdownTimeout, 0)))
+
 	// Agent handling depends on node type and several other options so it must be the last processed option.
 	// Also we only need to create an agent if this is not an API node.
 	if nodeType != ServerTypeAPI {
@@ -330,11 +802,35 @@ func WithLogDest(dst, prefix string) Option {
 	}
 }
 
-// WithDBURL maps EnvDBURL
+// WithLogDests sends logs to every destination of dsts, see common.SetLogDests
+func WithLogDests(dsts []string, prefix string) Option {
+	return func(ctx context.Context, s *Server) error {
+		common.SetLogDests(dsts, prefix)
+		return nil
+	}
+}
+
+// WithDBPool sizes the connection pool of the db datastore created by
+// WithDBURL, so it must be given before it, see datastore.PoolConfig. maxOpen
+// and maxLifetime of 0 mean no limit.
+func WithDBPool(maxOpen, maxIdle int, maxLifetime time.Duration) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.dbPool = datastore.PoolConfig{MaxOpenConns: maxOpen, MaxIdleConns: maxIdle, ConnMaxLifetime: maxLifetime}
+		return nil
+	}
+}
+
+// WithDBURL maps EnvDBURL, retrying to connect for up to the timeout of
+// WithDependencyWaitTimeout
 func WithDBURL(dbURL string) Option {
 	return func(ctx context.Context, s *Server) error {
 		if dbURL != "" {
-			ds, err := datastore.New(ctx, dbURL)
+			var ds models.Datastore
+			err := s.waitForDependency(ctx, "db", func() error {
+				var err error
+				ds, err = datastore.New(datastore.WithPoolConfig(ctx, s.dbPool), dbURL)
+				return err
+			})
 			if err != nil {
 				return err
 			}
@@ -360,10 +856,45 @@ func WithTLS(service string, tlsCfg *tls.Config) Option {
 	}
 }
 
+// WithGRPCCertFiles serves the grpc server of a pure-runner node over TLS with
+// the cert and key files, requiring client certs issued by the CA of caFile
+// if it is set. The files are read again when they change, so rotated certs
+// are used by new connections without a restart.
+func WithGRPCCertFiles(certFile, keyFile, caFile string) Option {
+	return func(ctx context.Context, s *Server) error {
+		tlsCfg, err := common.NewTLSReloading(certFile, keyFile, caFile)
+		if err != nil {
+			return err
+		}
+		return WithTLS(GRPCServer, tlsCfg)(ctx, s)
+	}
+}
+
 // WithReadDataAccess overrides the LB read DataAccess for a server
 func WithReadDataAccess(ds agent.ReadDataAccess) Option {
 	return func(ctx context.Context, s *Server) error {
 		s.lbReadAccess = agent.NewMetricReadDataAccess(ds)
+		s.dataCache, _ = ds.(agent.DataCache)
+		return nil
+	}
+}
+
+// WithDataCacheTTL sets how long the read data access created by the datastore
+// and agent options caches apps, fns and triggers for, so it must be given
+// before them. A ttl of 0 or less disables caching.
+func WithDataCacheTTL(ttl time.Duration) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.dataCacheTTL = ttl
+		return nil
+	}
+}
+
+// WithInvalidationPublisher sets the transport data cache invalidations are
+// published to other nodes with, on changes made through the API of this
+// server. By default they are not published.
+func WithInvalidationPublisher(p InvalidationPublisher) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.invalidationPublisher = p
 		return nil
 	}
 }
@@ -375,7 +906,7 @@ func WithDatastore(ds models.Datastore) Option {
 		s.datastore = datastore.Wrap(s.datastore)
 		s.datastore = fnext.NewDatastore(s.datastore, s.appListeners, s.fnListeners, s.triggerListeners)
 		if s.lbReadAccess == nil {
-			return WithReadDataAccess(agent.NewCachedDataAccess(s.datastore))(ctx, s)
+			return WithReadDataAccess(agent.NewCachedDataAccess(s.datastore, agent.WithDataCacheTTL(s.dataCacheTTL)))(ctx, s)
 		}
 		return nil
 	}
@@ -389,12 +920,42 @@ func WithAgent(agent agent.Agent) Option {
 	}
 }
 
-func (s *Server) defaultRunnerPool() (pool.RunnerPool, error) {
+// runnerSRVPrefix marks the runner addresses read from a DNS SRV name
+const runnerSRVPrefix = "dns+srv://"
+
+// defaultRunnerSRVRefresh is how often the runners of a DNS SRV name are
+// resolved again, unless EnvRunnerSRVRefresh says otherwise
+const defaultRunnerSRVRefresh = 30 * time.Second
+
+// runnerSRVName returns the DNS SRV name runners are read from, if any
+func runnerSRVName() string {
+	if name := getEnv(EnvRunnerSRV, ""); name != "" {
+		return name
+	}
+	if addrs := getEnv(EnvRunnerAddresses, ""); strings.HasPrefix(addrs, runnerSRVPrefix) {
+		return strings.TrimPrefix(addrs, runnerSRVPrefix)
+	}
+	return ""
+}
+
+func (s *Server) defaultRunnerPool(ctx context.Context) (pool.RunnerPool, error) {
+	if name := runnerSRVName(); name != "" {
+		addrs, err := agent.ResolveRunnerSRV(ctx, name)
+		if err != nil {
+			// the pool starts empty, until DNS answers
+			logrus.WithError(err).WithField("runner_srv", name).Warn("Failed to resolve runners")
+		}
+		runnerPool := agent.NewStaticRunnerPoolWithResolver(addrs, nil, s.resolver)
+		refresh := getEnvDuration(EnvRunnerSRVRefresh, defaultRunnerSRVRefresh)
+		go agent.WatchRunnerSRV(ctx, runnerPool.(agent.RunnerAddressSetter), name, addrs, refresh)
+		return runnerPool, nil
+	}
+
 	runnerAddresses := getEnv(EnvRunnerAddresses, "")
 	if runnerAddresses == "" {
 		return nil, errors.New("must provide FN_RUNNER_ADDRESSES  when running in default load-balanced mode")
 	}
-	return agent.DefaultStaticRunnerPool(strings.Split(runnerAddresses, ",")), nil
+	return agent.NewStaticRunnerPoolWithResolver(strings.Split(runnerAddresses, ","), nil, s.resolver), nil
 }
 
 // WithFullAgent is a shorthand for WithAgent(... create a full agent here ...)
@@ -415,7 +976,8 @@ func WithAgentFromEnv() Option {
 			return errors.New("should not initialize an agent for an Fn API node")
 		case ServerTypePureRunner:
 			cancelCtx, cancel := context.WithCancel(ctx)
-			prAgent, err := agent.DefaultPureRunner(cancel, s.svcConfigs[GRPCServer].Addr, s.svcConfigs[GRPCServer].TLSConfig)
+			prAgent, err := agent.DefaultPureRunner(cancel, s.svcConfigs[GRPCServer].Addr, s.svcConfigs[GRPCServer].TLSConfig,
+				agent.PureRunnerWithShutdownTimeout(s.shutdownTimeout))
 			if err != nil {
 				return err
 			}
@@ -428,31 +990,66 @@ func WithAgentFromEnv() Option {
 				return errors.New("no FN_RUNNER_API_URL provided for an Fn NuLB node")
 			}
 
-			cl, err := hybrid.NewClient(runnerURL)
+			clientOpts := []hybrid.ClientOption{hybrid.WithRetryMaxElapsed(getEnvDuration(EnvHybridRetryMaxElapsed, hybrid.DefaultRetryMaxElapsed))}
+			if s.resolver != nil {
+				clientOpts = append(clientOpts, hybrid.WithResolver(s.resolver))
+			}
+			if token := getEnv(EnvRunnerAPIToken, ""); token != "" {
+				clientOpts = append(clientOpts, hybrid.WithAPIToken(token))
+			}
+			if s.nodeCertFile != "" {
+				tlsCfg, err := s.nodeCertTLS()
+				if err != nil {
+					return err
+				}
+				clientOpts = append(clientOpts, hybrid.WithTLSConfig(tlsCfg))
+			}
+			cl, err := hybrid.NewClient(runnerURL, clientOpts...)
 			if err != nil {
 				return err
 			}
 
-			runnerPool, err := s.defaultRunnerPool()
+			runnerPool, err := s.defaultRunnerPool(ctx)
 			if err != nil {
 				return err
 			}
+			s.runnerAddressSetter, _ = runnerPool.(agent.RunnerAddressSetter)
+			if threshold := getEnvFloat(EnvCircuitFailureThreshold, 0); threshold > 0 {
+				if threshold > 1 {
+					return fmt.Errorf("%s must be between 0 and 1, got %v", EnvCircuitFailureThreshold, threshold)
+				}
+				circuitCfg := pool.NewCircuitBreakerConfig()
+				circuitCfg.FailureThreshold = threshold
+				circuitCfg.Cooldown = getEnvDuration(EnvCircuitCooldown, circuitCfg.Cooldown)
+				runnerPool = pool.NewCircuitBreakerPool(runnerPool, circuitCfg)
+			}
+			var healthChecker pool.HealthChecker = pool.GRPCHealthChecker{}
+			switch healthType := getEnv(EnvRunnerHealthType, pool.HealthCheckGRPC); healthType {
+			case pool.HealthCheckGRPC:
+			case pool.HealthCheckHTTP:
+				healthChecker = pool.NewHTTPHealthChecker(getEnv(EnvRunnerHealthPath, "/"), getEnv(EnvRunnerHealthPort, ""), getEnvInt(EnvRunnerHealthStatus, http.StatusOK))
+			default:
+				return fmt.Errorf("%s must be %s or %s, got %s", EnvRunnerHealthType, pool.HealthCheckGRPC, pool.HealthCheckHTTP, healthType)
+			}
+			s.lbRunnerPool = pool.NewTrackedRunnerPoolWithHealthChecker(runnerPool, healthChecker)
+			if s.runnerWarmup {
+				go s.warmRunners(ctx)
+			}
 
 			// Select the placement algorithm
 			placerCfg := pool.NewPlacerConfig()
-			var placer pool.Placer
-			switch getEnv(EnvLBPlacementAlg, "") {
-			case "ch":
-				placer = pool.NewCHPlacer(&placerCfg)
-			default:
-				placer = pool.NewNaivePlacer(&placerCfg)
+			placerCfg.PlacerTimeout = getEnvDuration(EnvLBQueueWait, placerCfg.PlacerTimeout)
+			placerCfg.MaxQueueDepth = getEnvInt(EnvLBQueueMaxDepth, 0)
+			placer, err := pool.NewPlacer(getEnv(EnvLBPlacementAlg, ""), &placerCfg)
+			if err != nil {
+				return err
 			}
 
-			err = WithReadDataAccess(agent.NewCachedDataAccess(cl))(ctx, s)
+			err = WithReadDataAccess(agent.NewCachedDataAccess(cl, agent.WithDataCacheTTL(s.dataCacheTTL)))(ctx, s)
 			if err != nil {
 				return errors.New("LBAgent creation failed")
 			}
-			s.agent, err = agent.NewLBAgent(runnerPool, placer)
+			s.agent, err = agent.NewLBAgent(s.lbRunnerPool, placer)
 			if err != nil {
 				return errors.New("LBAgent creation failed")
 			}
@@ -471,6 +1068,17 @@ func WithExtraCtx(extraCtx context.Context) Option {
 	}
 }
 
+// WithShutdownTimeout bounds how long the server waits, once it is told to
+// stop, for the requests it is serving and, on runner nodes, the calls in
+// flight to complete, before cancelling them. 0, the default, waits for as
+// long as they take. It must come before WithAgentFromEnv.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.shutdownTimeout = timeout
+		return nil
+	}
+}
+
 //WithTriggerAnnotator adds a trigggerEndpoint provider to the server
 func WithTriggerAnnotator(provider TriggerAnnotator) Option {
 	return func(ctx context.Context, s *Server) error {
@@ -527,9 +1135,19 @@ func New(ctx context.Context, opts ...Option) *Server {
 			GRPCServer:  &http.Server{},
 		},
 		// MUST initialize these before opts
-		appListeners:     new(appListeners),
-		fnListeners:      new(fnListeners),
-		triggerListeners: new(triggerListeners),
+		appListeners:        new(appListeners),
+		fnListeners:         new(fnListeners),
+		triggerListeners:    new(triggerListeners),
+		dataCacheTTL:        agent.DefaultDataCacheTTL,
+		dbPool:              datastore.PoolConfig{MaxIdleConns: datastore.DefaultMaxIdleConns},
+		accessLogSampleRate: 1,
+
+		reservedAnnotations: defaultReservedAnnotationPrefixes,
+		operatorAnnotations: keySet(defaultOperatorAnnotationKeys),
+		invokeRateLimits:    newInvokeRateLimits(0),
+		jsonMaxDepth:        DefaultJSONMaxDepth,
+		jsonMaxTokens:       DefaultJSONMaxTokens,
+		debugCaptures:       newDebugCaptures(DefaultDebugCaptureMaxBody, nil),
 
 		// Almost everything else is configured through opts (see NewFromEnv for ex.) or below
 	}
@@ -544,6 +1162,23 @@ func New(ctx context.Context, opts ...Option) *Server {
 		}
 	}
 
+	if s.dataCache != nil || s.invalidationPublisher != nil {
+		publisher := s.invalidationPublisher
+		if publisher == nil {
+			publisher = nopInvalidationPublisher{}
+		}
+		invalidator := &dataCacheInvalidator{cache: s.dataCache, publisher: publisher}
+		s.AddAppListener(invalidator)
+		s.AddFnListener(invalidator)
+		s.AddTriggerListener(invalidator)
+	}
+
+	if s.nodeType == ServerTypeFull && s.agent != nil {
+		// only full nodes both run calls and serve the API to get app stats
+		s.appStats = newAppStatsCollector()
+		s.AddCallListener(s.appStats)
+	}
+
 	if s.svcConfigs[WebServer].Addr == "" {
 		s.svcConfigs[WebServer].Addr = fmt.Sprintf(":%d", DefaultPort)
 	}
@@ -591,8 +1226,30 @@ func New(ctx context.Context, opts ...Option) *Server {
 
 	}
 
-	s.Router.Use(loggerWrap, traceWrap) // TODO should be opts
-	optionalCorsWrap(s.Router)          // TODO should be an opt
+	if s.runnerAPIMTLS {
+		if s.nodeCertAuthority == nil {
+			log.Fatal("Invalid configuration, a node certificate authority must be configured for runner API mTLS")
+		}
+		webTLS := s.svcConfigs[WebServer].TLSConfig
+		if webTLS == nil && s.nodeCertFile != "" {
+			var err error
+			webTLS, err = s.nodeCertTLS()
+			if err != nil {
+				log.WithError(err).Fatal("Invalid configuration, cannot load the node certificate")
+			}
+			s.svcConfigs[WebServer].TLSConfig = webTLS
+		}
+		if webTLS == nil {
+			log.Fatal("Invalid configuration, TLS must be configured on the web server for runner API mTLS")
+		}
+		if webTLS.ClientAuth == tls.NoClientCert {
+			// certificates are verified by the runner API, other clients don't need one
+			webTLS.ClientAuth = tls.RequestClientCert
+		}
+	}
+
+	s.Router.Use(loggerWrap, accessLogWrap(s.accessLogSampleRate), traceWrap) // TODO should be opts
+	optionalCorsWrap(s.Router, s.basePath)                                    // TODO should be an opt
 	apiMetricsWrap(s)
 	// panicWrap is last, specifically so that logging, tracing, cors, metrics, etc wrappers run
 	s.Router.Use(panicWrap)
@@ -602,15 +1259,43 @@ func New(ctx context.Context, opts ...Option) *Server {
 	return s
 }
 
+// WithMetricNamespace prefixes the names of the prometheus metrics with ns,
+// sanitized to the characters prometheus allows, rather than with fn, to
+// tell apart the roles of a cluster scraped into one prometheus. Changing it
+// renames all the metrics, so dashboards and alerts must be updated along.
+// fn_build_info keeps its name, to chart the versions across a cluster. It
+// must be set before WithPrometheus.
+func WithMetricNamespace(ns string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.metricNamespace = promSanitizeMetricName(ns)
+		return nil
+	}
+}
+
+// defaultMetricNamespace is fn for full nodes, and fn_<node type> for the
+// others, e.g. fn_lb
+func defaultMetricNamespace(t NodeType) string {
+	if t == ServerTypeFull {
+		return "fn"
+	}
+	return promSanitizeMetricName("fn_" + t.String())
+}
+
 // WithPrometheus activates the prometheus collection and /metrics endpoint
 func WithPrometheus() Option {
 	return func(ctx context.Context, s *Server) error {
+		namespace := s.metricNamespace
+		if namespace == "" {
+			namespace = "fn"
+		}
+
 		reg := promclient.NewRegistry()
 		reg.MustRegister(promclient.NewProcessCollector(promclient.ProcessCollectorOpts{
 			PidFn:     func() (int, error) { return os.Getpid(), nil },
-			Namespace: "fn",
+			Namespace: namespace,
 		}),
 			promclient.NewGoCollector(),
+			buildInfoCollector{s},
 		)
 
 		for _, exeName := range getMonitoredCmdNames() {
@@ -626,7 +1311,7 @@ func WithPrometheus() Option {
 		}
 
 		exporter, err := prometheus.NewExporter(prometheus.Options{
-			Namespace: "fn",
+			Namespace: namespace,
 			Registry:  reg,
 			OnError:   func(err error) { logrus.WithError(err).Error("opencensus prometheus exporter err") },
 		})
@@ -634,8 +1319,112 @@ func WithPrometheus() Option {
 			return fmt.Errorf("error starting prometheus exporter: %v", err)
 		}
 		s.promExporter = exporter
+		s.promRegistry = reg
+		view.RegisterExporter(exporter)
+
+		return nil
+	}
+}
+
+// WithStatsD pushes the opencensus views to the StatsD server at addr, with
+// their tags in the DogStatsD format. It may be used along with or instead of
+// WithPrometheus.
+func WithStatsD(addr string) Option {
+	return func(ctx context.Context, s *Server) error {
+		if addr == "" {
+			return nil
+		}
+		exporter, err := newStatsDExporter(addr, "fn.", func(err error) { logrus.WithError(err).Error("opencensus statsd exporter err") })
+		if err != nil {
+			return fmt.Errorf("error starting statsd exporter: %v", err)
+		}
 		view.RegisterExporter(exporter)
+		return nil
+	}
+}
+
+// WithAccessLogSampling logs only about rate, from 0 to 1, of the requests
+// which succeed. Requests with a non 2xx status are always logged.
+func WithAccessLogSampling(rate float64) Option {
+	return func(ctx context.Context, s *Server) error {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("invalid access log sample rate %v, must be between 0 and 1", rate)
+		}
+		s.accessLogSampleRate = rate
+		return nil
+	}
+}
+
+// WithInternalRunnerAPI sets whether an API or full node serves the internal
+// /v2/runner API. Only API nodes that LB nodes are configured to use need it,
+// others may disable it so that it can't be abused, or protect it with
+// WithRunnerAPIMTLS or WithAPITokenAuth. Unprotected, it never returns the
+// annotations of apps holding secrets, so LB nodes can't pass them on.
+func WithInternalRunnerAPI(enabled bool) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.noRunnerAPI = !enabled
+		return nil
+	}
+}
+
+// WithNodeCertAuthority sets the certificate authority that issues the
+// certificates of the nodes of the cluster, from a file of PEM certificates.
+func WithNodeCertAuthority(caFile string) Option {
+	return func(ctx context.Context, s *Server) error {
+		pem, err := ioutil.ReadFile(filepath.Clean(caFile))
+		if err != nil {
+			return fmt.Errorf("error reading node certificate authority: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in node certificate authority %s", caFile)
+		}
+		s.nodeCertAuthority = pool
+
+		// the certificates of API nodes may be issued by it or a public CA
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		roots.AppendCertsFromPEM(pem)
+		s.nodeCertRoots = roots
+		return nil
+	}
+}
 
+// WithNodeCertFiles sets the certificate of the node and its key, from PEM
+// files read again when they change. LB nodes present it to API nodes as
+// their client certificate, for WithRunnerAPIMTLS, and trust the certificates
+// issued by the node certificate authority on top of the system ones. API
+// nodes with WithRunnerAPIMTLS serve it over TLS if no TLS is configured on
+// the web server. It must come before WithAgentFromEnv.
+func WithNodeCertFiles(certFile, keyFile string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.nodeCertFile = certFile
+		s.nodeCertKeyFile = keyFile
+		return nil
+	}
+}
+
+// nodeCertTLS returns the TLS config of the certificate of the node
+func (s *Server) nodeCertTLS() (*tls.Config, error) {
+	cfg, err := common.NewTLSReloading(s.nodeCertFile, s.nodeCertKeyFile, "")
+	if err != nil {
+		return nil, err
+	}
+	cfg.RootCAs = s.nodeCertRoots
+	return cfg, nil
+}
+
+// WithRunnerAPIMTLS only serves the internal runner API to clients presenting
+// a certificate issued by the node certificate authority, others get a 401.
+// It requires WithNodeCertAuthority and TLS on the web server, or
+// WithNodeCertFiles, and the web server is made to request client
+// certificates if it doesn't already. LB nodes present the certificate of
+// WithNodeCertFiles.
+func WithRunnerAPIMTLS() Option {
+	return func(ctx context.Context, s *Server) error {
+		s.runnerAPIMTLS = true
 		return nil
 	}
 }
@@ -690,20 +1479,26 @@ func WithJaeger(jaegerURL string) Option {
 			return nil
 		}
 
+		var tags []jaeger.Tag
+		for k, v := range s.traceTags {
+			tags = append(tags, jaeger.StringTag(k, v))
+		}
+
 		exporter, err := jaeger.NewExporter(jaeger.Options{
 			CollectorEndpoint: jaegerURL,
-			Process:           jaeger.Process{ServiceName: "fnserver"},
+			Process:           jaeger.Process{ServiceName: s.traceService(), Tags: tags},
 			OnError:           func(err error) { logrus.WithError(err).Error("Error when uploading spans to Jaeger") },
 		})
 		if err != nil {
 			return fmt.Errorf("error connecting to jaeger: %v", err)
 		}
 		trace.RegisterExporter(exporter)
+		s.traceFlushers = append(s.traceFlushers, exporter.Flush)
 		logrus.WithFields(logrus.Fields{"url": jaegerURL}).Info("exporting spans to jaeger")
 
 		// TODO don't do this. testing parity.
 		// TODO switch to per span sampling, set to NeverSample by default
-		trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+		s.applyTraceSampler()
 		return nil
 	}
 }
@@ -718,12 +1513,15 @@ func WithZipkin(zipkinURL string) Option {
 		}
 
 		reporter := zipkinhttp.NewReporter(zipkinURL, zipkinhttp.MaxBacklog(10000))
-		exporter := zipkin.NewExporter(reporter, nil)
+		var exporter trace.Exporter = zipkin.NewExporter(reporter, &model.Endpoint{ServiceName: s.traceService()})
+		if len(s.traceTags) > 0 {
+			exporter = &taggingSpanExporter{Exporter: exporter, tags: s.traceTags}
+		}
 		trace.RegisterExporter(exporter)
 		logrus.WithFields(logrus.Fields{"url": zipkinURL}).Info("exporting spans to zipkin")
 
 		// TODO don't do this. testing parity.
-		trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+		s.applyTraceSampler()
 		return nil
 	}
 }
@@ -837,6 +1635,7 @@ func extractFields(c *gin.Context) logrus.Fields {
 // Start will block until the context is cancelled or times out.
 func (s *Server) Start(ctx context.Context) {
 	newctx, cancel := contextWithSignal(ctx, os.Interrupt, syscall.SIGTERM)
+	go s.reloadOnSignal(newctx, syscall.SIGHUP)
 	s.startGears(newctx, cancel)
 }
 
@@ -854,30 +1653,30 @@ func (s *Server) startGears(ctx context.Context, cancel context.CancelFunc) {
 
 	installChildReaper()
 
+	// full nodes keep containers warm for the fns of the apps asking for it
+	if warmer, ok := s.agent.(agent.MinWarmer); ok && s.nodeType == ServerTypeFull {
+		go s.syncMinWarm(ctx, warmer)
+	}
+
 	server := s.svcConfigs[WebServer]
 	if server.Handler == nil {
-		server.Handler = &ochttp.Handler{
+		server.Handler = s.wrapHandler(&ochttp.Handler{
 			Handler: s.Router,
 			GetStartOptions: func(r *http.Request) trace.StartOptions {
 				startOptions := trace.StartOptions{}
 				// TODO: Add list of url paths to exclude
-				if r.URL.Path == "/" {
+				if r.URL.Path == s.basePath+"/" {
 					startOptions.Sampler = trace.NeverSample()
 				}
 				return startOptions
 			},
 			// TODO: add FormatSpanName to clean up trace exporter operations dash
-		}
+		})
 	}
 
 	if !s.noWebServer {
 		go func() {
-			var err error
-			if server.TLSConfig != nil {
-				err = server.ListenAndServeTLS("", "")
-			} else {
-				err = server.ListenAndServe()
-			}
+			err := s.serve(WebServer, server)
 			if err != nil && err != http.ErrServerClosed {
 				logrus.WithError(err).Error("server error")
 				cancel()
@@ -891,16 +1690,11 @@ func (s *Server) startGears(ctx context.Context, cancel context.CancelFunc) {
 		logrus.WithField("type", s.nodeType).Infof("Fn Admin serving on `%v`", s.svcConfigs[AdminServer].Addr)
 		adminServer := s.svcConfigs[AdminServer]
 		if adminServer.Handler == nil {
-			adminServer.Handler = s.AdminRouter
+			adminServer.Handler = s.wrapHandler(s.AdminRouter)
 		}
 
 		go func() {
-			var err error
-			if adminServer.TLSConfig != nil {
-				err = adminServer.ListenAndServeTLS("", "")
-			} else {
-				err = adminServer.ListenAndServe()
-			}
+			err := s.serve(AdminServer, adminServer)
 			if err != nil && err != http.ErrServerClosed {
 				logrus.WithError(err).Error("server error")
 				cancel()
@@ -935,8 +1729,13 @@ func (s *Server) startGears(ctx context.Context, cancel context.CancelFunc) {
 	}
 
 	if !s.noWebServer {
-		// TODO: do not wait forever during graceful shutdown (add graceful shutdown timeout)
-		if err := server.Shutdown(context.Background()); err != nil {
+		shutdownCtx := context.Background()
+		if s.shutdownTimeout > 0 {
+			var cancel context.CancelFunc
+			shutdownCtx, cancel = context.WithTimeout(shutdownCtx, s.shutdownTimeout)
+			defer cancel()
+		}
+		if err := server.Shutdown(shutdownCtx); err != nil {
 			logrus.WithError(err).Error("server shutdown error")
 		}
 	}
@@ -955,28 +1754,73 @@ func (s *Server) goneResponse(c *gin.Context) {
 
 func (s *Server) bindHandlers(ctx context.Context) {
 	engine := s.Router
-	admin := s.AdminRouter
+	if s.requireHTTPS != "" {
+		engine.Use(s.requireHTTPSWrap)
+	}
 	// now for extensible middleware
 	engine.Use(s.rootMiddlewareWrapper())
 
-	engine.GET("/", handlePing)
+	// every route is mounted under the base path, if any
+	if s.basePath != "" {
+		engine.Use(basePathWrap(s.basePath))
+	}
+	root := engine.Group(s.basePath)
+	admin := s.AdminRouter.Group(s.basePath)
+	if len(s.responseHeaders) > 0 {
+		admin.Use(responseHeadersWrap(s.responseHeaders))
+	}
+
+	root.GET("/", handlePing)
+	if s.shutdownHalt != nil {
+		root.GET("/shutdown", s.handleShutdown(s.shutdownHalt))
+	}
 	admin.GET("/version", handleVersion)
 
 	if s.promExporter != nil {
-		admin.GET("/metrics", gin.WrapH(s.promExporter))
+		admin.GET("/metrics", s.handleMetrics)
 	}
 
 	if !s.noProfilerEndpoint {
 		profilerSetup(admin, "/debug")
 	}
+	if s.adminToken != "" {
+		calls := admin.Group("/debug/calls", adminAuthWrap(s.adminToken))
+		calls.GET("", s.handleActiveCallList)
+		calls.DELETE("/:call_id", s.handleActiveCallCancel)
+
+		admin.GET("/debug/runners", adminAuthWrap(s.adminToken), s.handleRunnerList)
+		admin.POST("/cache/invalidate", adminAuthWrap(s.adminToken), s.handleCacheInvalidate)
+		admin.POST("/debug/trace", adminAuthWrap(s.adminToken), s.handleTraceConfig)
+		admin.GET("/debug/migrations", adminAuthWrap(s.adminToken), s.handleMigrationStatus)
+
+		capture := admin.Group("/debug/capture", adminAuthWrap(s.adminToken))
+		capture.POST("", s.handleDebugCaptureStart)
+		capture.GET("/:app_id", s.handleDebugCaptureGet)
+		capture.DELETE("/:app_id", s.handleDebugCaptureStop)
+
+		if s.dashboard {
+			admin.GET("/ui", func(c *gin.Context) { c.Redirect(http.StatusMovedPermanently, s.basePath+"/ui/") })
+			admin.Group("/ui", dashboardAuthWrap(s.adminToken)).GET("/*asset", handleDashboardAsset)
+		}
+	} else if s.dashboard {
+		logrus.Warn("The dashboard is not served without an admin token")
+	}
 
 	// Pure runners don't have any route, they have grpc
 	switch s.nodeType {
 
 	case ServerTypeFull, ServerTypeAPI:
-		cleanv2 := engine.Group("/v2")
+		cleanv2 := root.Group("/v2")
+		if len(s.responseHeaders) > 0 {
+			cleanv2.Use(responseHeadersWrap(s.responseHeaders))
+		}
+		if s.apiRequestTimeout > 0 {
+			cleanv2.Use(apiRequestTimeoutWrap(s.apiRequestTimeout))
+		}
 		v2 := cleanv2.Group("")
 		v2.Use(s.apiMiddlewareWrapper())
+		v2.GET("/version", handleBuildInfo)
+		v2.GET("/openapi.json", openAPIHandler(s.openAPIServerURL()))
 
 		{
 			v2.GET("/apps", s.handleAppList)
@@ -984,9 +1828,11 @@ func (s *Server) bindHandlers(ctx context.Context) {
 			v2.GET("/apps/:app_id", s.handleAppGet)
 			v2.PUT("/apps/:app_id", s.handleAppUpdate)
 			v2.DELETE("/apps/:app_id", s.handleAppDelete)
+			v2.GET("/apps/:app_id/stats", s.handleAppStats)
 
 			v2.GET("/fns", s.handleFnList)
 			v2.POST("/fns", s.handleFnCreate)
+			v2.POST("/fns/validate-image", s.handleFnValidateImage)
 			v2.GET("/fns/:fn_id", s.handleFnGet)
 			v2.PUT("/fns/:fn_id", s.handleFnUpdate)
 			v2.DELETE("/fns/:fn_id", s.handleFnDelete)
@@ -998,44 +1844,76 @@ func (s *Server) bindHandlers(ctx context.Context) {
 			v2.DELETE("/triggers/:trigger_id", s.handleTriggerDelete)
 		}
 
+		if s.apiRootToken != "" {
+			v2.GET("/tokens", s.handleTokenList)
+			v2.POST("/tokens", s.handleTokenCreate)
+			v2.GET("/tokens/:token_id", s.handleTokenGet)
+			v2.DELETE("/tokens/:token_id", s.handleTokenDelete)
+		}
+
 		// TODO remove these in 30 days or something
 		v2.GET("/fns/:fn_id/calls", s.goneResponse)
 		v2.GET("/fns/:fn_id/calls/:call_id", s.goneResponse)
 		v2.GET("/fns/:fn_id/calls/:call_id/log", s.goneResponse)
 
+		// node internals, for operators rather than the clients of the API
+		if s.adminToken != "" {
+			cleanv2.GET("/fns/:fn_id/runtime", adminAuthWrap(s.adminToken), s.handleFnRuntime)
+		}
+
 		// TODO figure out how to deprecate
-		runner := cleanv2.Group("/runner")
-		runnerAppAPI := runner.Group("/apps/:app_id")
-		runnerAppAPI.GET("/triggerBySource/:trigger_type/*trigger_source", s.handleRunnerGetTriggerBySource)
+		if !s.noRunnerAPI {
+			runner := cleanv2.Group("/runner")
+			runner.Use(s.runnerAPIAuthWrap)
+			runnerAppAPI := runner.Group("/apps/:app_id")
+			runnerAppAPI.GET("", s.handleRunnerGetApp)
+			runnerAppAPI.GET("/triggerBySource/:trigger_type/*trigger_source", s.handleRunnerGetTriggerBySource)
+		}
 	}
 
 	switch s.nodeType {
 	case ServerTypeFull, ServerTypeLB:
 		if !s.noHTTTPTriggerEndpoint {
-			lbTriggerGroup := engine.Group("/t")
+			lbTriggerGroup := root.Group("/t")
+			lbTriggerGroup.Use(s.invokeCORSWrap(s.triggerCORSApp))
+			if s.backpressure != nil {
+				lbTriggerGroup.Use(s.backpressureWrap)
+			}
 			lbTriggerGroup.Any("/:app_name", s.handleHTTPTriggerCall)
 			lbTriggerGroup.Any("/:app_name/*trigger_source", s.handleHTTPTriggerCall)
 		}
 
 		if !s.noFnInvokeEndpoint {
-			lbFnInvokeGroup := engine.Group("/invoke")
+			lbFnInvokeGroup := root.Group("/invoke")
+			lbFnInvokeGroup.Use(s.invokeCORSWrap(s.fnInvokeCORSApp))
+			if s.backpressure != nil {
+				lbFnInvokeGroup.Use(s.backpressureWrap)
+			}
 			lbFnInvokeGroup.POST("/:fn_id", s.handleFnInvokeCall)
+			// only reached by requests the CORS middleware did not answer as a preflight
+			lbFnInvokeGroup.OPTIONS("/:fn_id", handleMethodNotAllowed)
 		}
 	}
 
 	engine.NoRoute(func(c *gin.Context) {
+		if s.isInvocationNoRoute(c) {
+			s.serveInvocationNotFound(c)
+			return
+		}
 		var e models.APIError = models.ErrPathNotFound
 		err := models.NewAPIError(e.Code(), fmt.Errorf("%v: %s %s", e.Error(), c.Request.Method, c.Request.URL.Path))
 		handleErrorResponse(c, err)
 	})
 
 	engine.HandleMethodNotAllowed = true
-	engine.NoMethod(func(c *gin.Context) {
-		var e models.APIError = models.ErrMethodNotAllowed
-		err := models.NewAPIError(e.Code(), fmt.Errorf("%v: %s %s", e.Error(), c.Request.Method, c.Request.URL.Path))
-		handleErrorResponse(c, err)
-	})
+	engine.NoMethod(handleMethodNotAllowed)
+
+}
 
+func handleMethodNotAllowed(c *gin.Context) {
+	var e models.APIError = models.ErrMethodNotAllowed
+	err := models.NewAPIError(e.Code(), fmt.Errorf("%v: %s %s", e.Error(), c.Request.Method, c.Request.URL.Path))
+	handleErrorResponse(c, err)
 }
 
 // Datastore implements fnext.ExtServer
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/fnproject/fn/api/common"
 	"github.com/gin-gonic/gin"
@@ -40,24 +42,146 @@ func withRIDProvider(ridp *RIDProvider) func(c *gin.Context) {
 	}
 }
 
-// EnableShutdownEndpoint adds /shutdown to initiate a shutdown of an fn server.
+// EnableShutdownEndpoint adds /shutdown, under the base path if any, to
+// initiate a shutdown of an fn server.
 func EnableShutdownEndpoint(ctx context.Context, halt context.CancelFunc) Option {
 	return func(ctx context.Context, s *Server) error {
-		s.Router.GET("/shutdown", s.handleShutdown(halt))
+		s.shutdownHalt = halt
 		return nil
 	}
 }
 
 // LimitRequestBody wraps every http request to limit its size to the specified max bytes.
+// The calls to functions are limited once their app is known, which may
+// replace the limit with the models.AppMaxBodyAnnotation annotation, see
+// WithMaxAppRequestSize.
 func LimitRequestBody(max int64) Option {
 	return func(ctx context.Context, s *Server) error {
+		s.maxRequestSize = max
 		if max > 0 {
-			s.Router.Use(limitRequestBody(max))
+			s.Router.Use(s.limitNonCallRequestBody(max))
 		}
 		return nil
 	}
 }
 
+// WithMaxConnections limits the number of connections each of the web and admin
+// servers accept at a time, further connections wait to be accepted until one is
+// closed. A limit of 0 or less means no limit.
+func WithMaxConnections(max int) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.maxConnections = max
+		return nil
+	}
+}
+
+// WithSyncCallMaxTimeout caps the timeout of synchronous calls at max, rounded up
+// to the second, calls of functions with a longer timeout are cut short at max.
+// Detached calls are not affected. A max of 0 or less means no cap.
+func WithSyncCallMaxTimeout(max time.Duration) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.syncCallMaxTimeout = 0
+		if max > 0 {
+			s.syncCallMaxTimeout = int32((max + time.Second - 1) / time.Second)
+		}
+		return nil
+	}
+}
+
+// WithAPIRequestTimeout limits the time spent handling each request to the API
+// to timeout, after which the datastore queries of the request are cancelled
+// and it fails with a 504. It does not apply to the trigger and invoke
+// endpoints, see WithSyncCallMaxTimeout. A timeout of 0 or less means no limit.
+func WithAPIRequestTimeout(timeout time.Duration) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.apiRequestTimeout = timeout
+		return nil
+	}
+}
+
+// WithMaxFnsPerApp limits the number of functions each app may have, creating
+// a function in an app at the limit fails with a 409. This is a guardrail for
+// multi-tenant clusters, where a single app could otherwise take up an
+// unbounded share of the datastore. A max of 0 or less means no limit.
+func WithMaxFnsPerApp(max int) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.maxFnsPerApp = max
+		return nil
+	}
+}
+
+// WithMaxTriggersPerApp limits the number of triggers each app may have, as
+// WithMaxFnsPerApp does for functions. It also bounds the triggers matched
+// against when routing the app's requests. A max of 0 or less means no limit.
+func WithMaxTriggersPerApp(max int) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.maxTriggersPerApp = max
+		return nil
+	}
+}
+
+// WithMaxConfig limits the config of each app and function, which is passed
+// to every container, to maxKeys keys and maxBytes bytes of keys and values. A
+// limit of 0 or less means no limit.
+func WithMaxConfig(maxKeys, maxBytes int) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.maxConfigKeys = maxKeys
+		s.maxConfigBytes = maxBytes
+		return nil
+	}
+}
+
+// WithBasePath mounts all the routes, including the admin ones, under the
+// path prefix, e.g. "/fn" to serve /fn/v2/apps. The URLs of the request based
+// annotators include it, while static URLs are expected to already do so.
+func WithBasePath(prefix string) Option {
+	return func(ctx context.Context, s *Server) error {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if prefix != "" && !strings.HasPrefix(prefix, "/") {
+			prefix = "/" + prefix
+		}
+		s.basePath = prefix
+		return nil
+	}
+}
+
+// WithInvokeCORS enables CORS on the trigger and invoke endpoints for the given
+// origins ("*" allows any origin), independently of the CORS settings of the API.
+// Apps may replace the origins with the models.AppInvokeCORSOriginsAnnotation
+// annotation. If headers is empty, preflight requests are allowed any headers
+// they ask for.
+func WithInvokeCORS(origins, headers []string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.invokeCORSOrigins = origins
+		s.invokeCORSHeaders = headers
+		if len(origins) > 0 {
+			logrus.Infof("Invoke CORS enabled for domains: %s", origins)
+		}
+		return nil
+	}
+}
+
+// WithHandlerWrapper wraps the outermost handlers of the web and admin
+// servers, around tracing and the routers, e.g. to put a WAF or an auth proxy
+// in front of fn. Wrappers see all traffic, including health checks and
+// metrics scrapes. They compose in registration order, the first one
+// registered being the first to see requests. Handlers set with WithHTTPConfig
+// are not wrapped.
+func WithHandlerWrapper(wrapper func(http.Handler) http.Handler) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.handlerWrappers = append(s.handlerWrappers, wrapper)
+		return nil
+	}
+}
+
+// wrapHandler applies the handler wrappers to h
+func (s *Server) wrapHandler(h http.Handler) http.Handler {
+	for i := len(s.handlerWrappers) - 1; i >= 0; i-- {
+		h = s.handlerWrappers[i](h)
+	}
+	return h
+}
+
 func limitRequestBody(max int64) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		cl := int64(c.Request.ContentLength)
@@ -9,9 +9,11 @@ import (
 	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/fnproject/fn/api/agent"
 	_ "github.com/fnproject/fn/api/agent/drivers/docker"
+	"github.com/fnproject/fn/api/datastore"
 	_ "github.com/fnproject/fn/api/datastore/sql/sqlite"
 	"github.com/fnproject/fn/api/models"
 	"github.com/gin-gonic/gin"
@@ -86,3 +88,98 @@ func getErrorResponse(t *testing.T, rec *httptest.ResponseRecorder) *models.Erro
 	}
 	return &err
 }
+
+func TestErrorResponseCode(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	srv := testServer(datastore.NewMock(), nil, ServerTypeAPI)
+
+	for i, test := range []struct {
+		method       string
+		path         string
+		body         string
+		expectedCode string
+	}{
+		{http.MethodGet, "/v2/apps/missing", ``, models.ErrorCodeAppNotFound},
+		{http.MethodPost, "/v2/apps", `{`, models.ErrorCodeInvalidJSON},
+		{http.MethodGet, "/v2/fns/missing", ``, models.ErrorCodeFnNotFound},
+	} {
+		_, rec := routerRequest(t, srv.Router, test.method, test.path, bytes.NewBufferString(test.body))
+		resp := getErrorResponse(t, rec)
+		if resp.Code != test.expectedCode {
+			t.Errorf("Test %d: expected error code %s, got %s", i, test.expectedCode, resp.Code)
+		}
+	}
+}
+
+// slowAppsDatastore blocks listing apps until the request is cancelled
+type slowAppsDatastore struct {
+	models.Datastore
+}
+
+func (ds *slowAppsDatastore) GetApps(ctx context.Context, filter *models.AppFilter) (*models.AppList, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestAPIRequestTimeout(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ds := &slowAppsDatastore{datastore.NewMock()}
+	srv := testServer(ds, nil, ServerTypeAPI, WithAPIRequestTimeout(50*time.Millisecond))
+
+	_, rec := routerRequest(t, srv.Router, http.MethodGet, "/v2/apps", nil)
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected status code 504 but was %d: %s", rec.Code, rec.Body.String())
+	}
+	resp := getErrorResponse(t, rec)
+	if resp.Code != models.ErrorCodeAPIRequestTimeout {
+		t.Errorf("expected error code %s, got %s", models.ErrorCodeAPIRequestTimeout, resp.Code)
+	}
+
+	// requests completing in time are unaffected
+	_, rec = routerRequest(t, srv.Router, http.MethodGet, "/v2/apps/missing", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status code 404 but was %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRunnerList(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	// not served without an admin token
+	srv := testServer(datastore.NewMock(), nil, ServerTypeAPI)
+	if _, rec := routerRequest(t, srv.AdminRouter, http.MethodGet, "/debug/runners", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status code 404 but was %d: %s", rec.Code, rec.Body.String())
+	}
+
+	srv = testServer(datastore.NewMock(), nil, ServerTypeAPI, WithAdminToken("s3cret"))
+	if _, rec := routerRequest(t, srv.AdminRouter, http.MethodGet, "/debug/runners", nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status code 401 but was %d: %s", rec.Code, rec.Body.String())
+	}
+
+	req := createRequest(t, http.MethodGet, "/debug/runners", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	_, rec := routerRequest2(t, srv.AdminRouter, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status code 200 but was %d: %s", rec.Code, rec.Body.String())
+	}
+	if body := rec.Body.String(); body != `{"node_type":"api","runners":[]}` {
+		t.Errorf("expected no runners on an api node, got %s", body)
+	}
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+)
+
+const serverTimingHeader = "Server-Timing"
+
+// WithServerTiming adds a Server-Timing header to the responses of sync
+// invocations, breaking down the time spent waiting for a container (queue),
+// starting one for the call (cold-start) and running it (exec), for clients
+// to see where their latency goes. It is off by default, as it exposes how
+// the calls of a node are scheduled.
+func WithServerTiming() Option {
+	return func(ctx context.Context, s *Server) error {
+		s.serverTiming = true
+		return nil
+	}
+}
+
+// setServerTiming adds the timings of a call to h, in milliseconds, leaving
+// out the phases the call did not go through
+func setServerTiming(h http.Header, timings agent.CallTimings) {
+	metrics := make([]string, 0, 3)
+	for _, m := range []struct {
+		name string
+		dur  time.Duration
+	}{
+		{"queue", timings.Queue},
+		{"cold-start", timings.ColdStart},
+		{"exec", timings.Exec},
+	} {
+		if m.dur > 0 {
+			metrics = append(metrics, fmt.Sprintf("%s;dur=%.3f", m.name, float64(m.dur)/float64(time.Millisecond)))
+		}
+	}
+	if len(metrics) > 0 {
+		h.Add(serverTimingHeader, strings.Join(metrics, ", "))
+	}
+}
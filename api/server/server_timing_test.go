@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSetServerTiming(t *testing.T) {
+	for i, test := range []struct {
+		timings  agent.CallTimings
+		expected string
+	}{
+		{agent.CallTimings{}, ""},
+		{
+			agent.CallTimings{Queue: 1500 * time.Microsecond, Exec: 20 * time.Millisecond},
+			"queue;dur=1.500, exec;dur=20.000",
+		},
+		{
+			agent.CallTimings{Queue: 812 * time.Millisecond, ColdStart: 800 * time.Millisecond, Exec: 3 * time.Microsecond},
+			"queue;dur=812.000, cold-start;dur=800.000, exec;dur=0.003",
+		},
+	} {
+		h := make(http.Header)
+		setServerTiming(h, test.timings)
+		if got := h.Get("Server-Timing"); got != test.expected {
+			t.Errorf("Test %d: expected Server-Timing %q, got %q", i, test.expected, got)
+		}
+	}
+}
+
+func TestTriggerServerTiming(t *testing.T) {
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}}
+	trigger := &models.Trigger{ID: "trigger_id", Name: "mytrigger", AppID: app.ID, FnID: fn.ID, Type: models.TriggerTypeHTTP, Source: "/src"}
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn}, []*models.Trigger{trigger})
+
+	rnr := new(agent.MockAgent)
+	rnr.On("AddCallListener", mock.Anything)
+	rnr.On("GetCall", mock.Anything)
+	rnr.On("Submit", mock.Anything).Run(func(args mock.Arguments) {
+		rw := args.Get(0).(interface{ ResponseWriter() http.ResponseWriter }).ResponseWriter()
+		rw.Header().Set("Fn-Http-H-Server-Timing", "db;dur=2")
+		rw.Write([]byte("ok"))
+	}).Return(nil)
+
+	srv := testServer(ds, rnr, ServerTypeFull, WithServerTiming())
+	req := createRequest(t, http.MethodPost, "/t/myapp/src", strings.NewReader(`{}`))
+	_, rec := routerRequest2(t, srv.Router, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status code 200, got %d", rec.Code)
+	}
+	// the mock agent measures no phases, only the timings of the function are sent
+	if timing := rec.Header()["Server-Timing"]; len(timing) != 1 || timing[0] != "db;dur=2" {
+		t.Fatalf("expected the timings of the function to be kept, got %v", timing)
+	}
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// statsdMaxPacket keeps packets under the usual MTU, as StatsD servers drop
+// truncated datagrams
+const statsdMaxPacket = 1432
+
+var (
+	statsdReplacer      = strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", ",", "_", " ", "_", "\n", "_")
+	statsdValueReplacer = strings.NewReplacer("|", "_", ",", "_", "\n", "_")
+)
+
+// statsdExporter is an opencensus view exporter pushing views to a StatsD
+// server over UDP, with their tags in the DogStatsD format. As views are
+// cumulative, count and sum views are sent as counters of their change since
+// the last export, distributions as counters of their count and sum, and last
+// value views as gauges.
+type statsdExporter struct {
+	conn    net.Conn
+	prefix  string
+	onError func(error)
+
+	mu   sync.Mutex
+	last map[string]float64 // cumulative values sent by metric and tags
+}
+
+func newStatsDExporter(addr, prefix string, onError func(error)) (*statsdExporter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &statsdExporter{
+		conn:    conn,
+		prefix:  prefix,
+		onError: onError,
+		last:    make(map[string]float64),
+	}, nil
+}
+
+// ExportView implements view.Exporter
+func (e *statsdExporter) ExportView(vd *view.Data) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	name := e.prefix + statsdReplacer.Replace(vd.View.Name)
+	var packet bytes.Buffer
+	for _, row := range vd.Rows {
+		tags := statsdTags(row.Tags)
+		switch data := row.Data.(type) {
+		case *view.CountData:
+			e.counter(&packet, name, tags, float64(data.Value))
+		case *view.SumData:
+			e.counter(&packet, name, tags, data.Value)
+		case *view.DistributionData:
+			e.counter(&packet, name+".count", tags, float64(data.Count))
+			e.counter(&packet, name+".sum", tags, float64(data.Count)*data.Mean)
+		case *view.LastValueData:
+			e.write(&packet, name, data.Value, "g", tags)
+		}
+	}
+	e.flush(&packet)
+}
+
+// counter sends the change of a cumulative value since it was last sent
+func (e *statsdExporter) counter(packet *bytes.Buffer, name, tags string, value float64) {
+	key := name + tags
+	delta := value - e.last[key]
+	if delta < 0 {
+		// the view was reset
+		delta = value
+	}
+	e.last[key] = value
+	if delta != 0 {
+		e.write(packet, name, delta, "c", tags)
+	}
+}
+
+func (e *statsdExporter) write(packet *bytes.Buffer, name string, value float64, typ, tags string) {
+	line := name + ":" + strconv.FormatFloat(value, 'f', -1, 64) + "|" + typ + tags
+	if packet.Len() > 0 && packet.Len()+1+len(line) > statsdMaxPacket {
+		e.flush(packet)
+	}
+	if packet.Len() > 0 {
+		packet.WriteByte('\n')
+	}
+	packet.WriteString(line)
+}
+
+func (e *statsdExporter) flush(packet *bytes.Buffer) {
+	if packet.Len() == 0 {
+		return
+	}
+	if _, err := e.conn.Write(packet.Bytes()); err != nil && e.onError != nil {
+		e.onError(err)
+	}
+	packet.Reset()
+}
+
+func statsdTags(tags []tag.Tag) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(tags))
+	for _, t := range tags {
+		parts = append(parts, statsdReplacer.Replace(t.Key.Name())+":"+statsdValueReplacer.Replace(t.Value))
+	}
+	return "|#" + strings.Join(parts, ",")
+}
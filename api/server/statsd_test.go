@@ -0,0 +1,74 @@
+package server
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+func TestStatsDExporter(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	e, err := newStatsDExporter(conn.LocalAddr().String(), "fn.", func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func() []string {
+		buf := make([]byte, statsdMaxPacket)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Split(string(buf[:n]), "\n")
+	}
+
+	key, _ := tag.NewKey("fn_id")
+	measure := stats.Int64("test", "test", stats.UnitDimensionless)
+	data := func(count int64, sum, last float64) *view.Data {
+		tags := []tag.Tag{{Key: key, Value: "fn|1"}}
+		return &view.Data{
+			View: &view.View{Name: "test_calls", Measure: measure},
+			Rows: []*view.Row{
+				{Tags: tags, Data: &view.CountData{Value: count}},
+				{Data: &view.SumData{Value: sum}},
+				{Data: &view.LastValueData{Value: last}},
+				{Data: &view.DistributionData{Count: count, Mean: 2}},
+			},
+		}
+	}
+
+	e.ExportView(data(3, 1.5, 7))
+	expected := []string{
+		"fn.test_calls:3|c|#fn_id:fn_1",
+		"fn.test_calls:1.5|c",
+		"fn.test_calls:7|g",
+		"fn.test_calls.count:3|c",
+		"fn.test_calls.sum:6|c",
+	}
+	if got := read(); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+
+	// counters are sent as the change since the last export
+	e.ExportView(data(5, 1.5, 7))
+	expected = []string{
+		"fn.test_calls:2|c|#fn_id:fn_1",
+		"fn.test_calls:7|g",
+		"fn.test_calls.count:2|c",
+		"fn.test_calls.sum:4|c",
+	}
+	if got := read(); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+}
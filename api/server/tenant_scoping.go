@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// tenantKey is the gin context key of the tenant of a request
+const tenantKey = "fn_tenant_id"
+
+// WithTenantScoping scopes the /v2 API to the tenant resolver derives from
+// each request, e.g. from a header or its API token. Apps are created with
+// the tenant of the request as their tenant_id, apps are only listed for
+// their tenant, and requests to the apps of another tenant, or to their fns
+// and triggers, get a 404 as if they did not exist. Requests with no tenant
+// are rejected with a 401.
+//
+// Apps created before tenant scoping was enabled have an empty tenant_id,
+// which no tenant may reach. Assign them to their tenants in the datastore
+// before enabling it, e.g. UPDATE apps SET tenant_id='acme' WHERE id IN (...).
+// App names stay unique across tenants, and API tokens are not scoped to a
+// tenant, so only the root token should manage them.
+//
+// The internal /v2/runner API is not scoped either: LB nodes look up the apps
+// and triggers of every tenant with it. With tenant scoping, it is only served
+// to nodes authenticated with WithRunnerAPIMTLS or the root token of
+// WithAPITokenAuth, and refused to all if neither is set.
+func WithTenantScoping(resolver func(*gin.Context) string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.tenantResolver = resolver
+		return nil
+	}
+}
+
+// scopeTenant resolves the tenant of a request to the /v2 API and checks
+// that the app it is made to belongs to it, or aborts it
+func (s *Server) scopeTenant(c *gin.Context) bool {
+	tenant := s.tenantResolver(c)
+	if tenant == "" {
+		handleErrorResponse(c, models.ErrTenantRequired)
+		c.Abort()
+		return false
+	}
+	c.Set(tenantKey, tenant)
+
+	appID, err := s.requestAppID(c)
+	if err == nil && appID != "" {
+		var app *models.App
+		app, err = s.datastore.GetAppByID(c.Request.Context(), appID)
+		if err == nil && app.TenantID != tenant {
+			err = models.ErrAppsNotFound
+		}
+	}
+	if err == models.ErrAppsNotFound {
+		// the resource of the path is not found, not whether its app exists
+		switch {
+		case c.Param(api.FnID) != "":
+			err = models.ErrFnsNotFound
+		case c.Param(api.TriggerID) != "":
+			err = models.ErrTriggerNotFound
+		}
+	}
+	if err != nil {
+		handleErrorResponse(c, err)
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// requestTenant returns the tenant of a request, or an empty one if tenant
+// scoping is disabled
+func requestTenant(c *gin.Context) string {
+	return c.GetString(tenantKey)
+}
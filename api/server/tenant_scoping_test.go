@@ -0,0 +1,126 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore/memory"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestTenantScoping(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ctx := context.Background()
+	ds := memory.New()
+	appA, err := ds.InsertApp(ctx, &models.App{Name: "acme", TenantID: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appB, err := ds.InsertApp(ctx, &models.App{Name: "globex", TenantID: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.InsertApp(ctx, &models.App{Name: "legacy"}); err != nil {
+		t.Fatal(err)
+	}
+	fnB := &models.Fn{Name: "fn", AppID: appB.ID, Image: "fnproject/hello"}
+	fnB.SetDefaults()
+	fnB, err = ds.InsertFn(ctx, fnB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	triggerB := &models.Trigger{Name: "trigger", AppID: appB.ID, FnID: fnB.ID, Type: "http", Source: "/trigger"}
+	if _, err := ds.InsertTrigger(ctx, triggerB); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := testServer(ds, nil, ServerTypeAPI, WithTenantScoping(func(c *gin.Context) string {
+		return c.GetHeader("X-Tenant")
+	}))
+
+	request := func(tenant, method, path, body string) (int, *bytes.Buffer) {
+		req := createRequest(t, method, path, bytes.NewBufferString(body))
+		if tenant != "" {
+			req.Header.Set("X-Tenant", tenant)
+		}
+		_, rec := routerRequest2(t, srv.Router, req)
+		return rec.Code, rec.Body
+	}
+
+	for i, test := range []struct {
+		tenant       string
+		method       string
+		path         string
+		body         string
+		expectedCode int
+		expectedErr  string
+	}{
+		{"", http.MethodGet, "/v2/apps", "", http.StatusUnauthorized, models.ErrTenantRequired.Error()},
+		{"a", http.MethodGet, "/v2/apps/" + appA.ID, "", http.StatusOK, ""},
+		{"a", http.MethodGet, "/v2/apps/" + appB.ID, "", http.StatusNotFound, models.ErrAppsNotFound.Error()},
+		{"a", http.MethodPut, "/v2/apps/" + appB.ID, `{"config": {"A": "a"}}`, http.StatusNotFound, models.ErrAppsNotFound.Error()},
+		{"a", http.MethodDelete, "/v2/apps/" + appB.ID, "", http.StatusNotFound, models.ErrAppsNotFound.Error()},
+		{"a", http.MethodGet, "/v2/fns/" + fnB.ID, "", http.StatusNotFound, models.ErrFnsNotFound.Error()},
+		{"a", http.MethodGet, "/v2/fns?app_id=" + appB.ID, "", http.StatusNotFound, models.ErrAppsNotFound.Error()},
+		{"a", http.MethodPost, "/v2/fns", `{"app_id": "` + appB.ID + `", "name": "fn2", "image": "fnproject/hello"}`, http.StatusNotFound, models.ErrAppsNotFound.Error()},
+		{"a", http.MethodGet, "/v2/fns", "", http.StatusBadRequest, models.ErrFnsMissingAppID.Error()},
+		{"a", http.MethodGet, "/v2/triggers", "", http.StatusBadRequest, models.ErrTriggerMissingAppID.Error()},
+		{"a", http.MethodGet, "/v2/triggers?app_id=" + appB.ID, "", http.StatusNotFound, models.ErrAppsNotFound.Error()},
+		{"b", http.MethodGet, "/v2/fns/" + fnB.ID, "", http.StatusOK, ""},
+		{"b", http.MethodGet, "/v2/triggers?app_id=" + appB.ID, "", http.StatusOK, ""},
+		{"b", http.MethodGet, "/v2/fns?app_id=" + appB.ID, "", http.StatusOK, ""},
+		// the runner API is not scoped, so it is not served without a node credential
+		{"a", http.MethodGet, "/v2/runner/apps/" + appB.ID, "", http.StatusUnauthorized, models.ErrRunnerAPIUnauthorized.Error()},
+		{"a", http.MethodGet, "/v2/runner/apps/" + appB.ID + "/triggerBySource/http/trigger", "", http.StatusUnauthorized, models.ErrRunnerAPIUnauthorized.Error()},
+	} {
+		code, body := request(test.tenant, test.method, test.path, test.body)
+		if code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d for %s %s but was %d: %s", i, test.expectedCode, test.method, test.path, code, body)
+		}
+		if test.expectedErr != "" && !strings.Contains(body.String(), test.expectedErr) {
+			t.Errorf("Test %d: expected error %q, got %s", i, test.expectedErr, body)
+		}
+	}
+
+	// apps are listed for their tenant only, leaving out unscoped ones
+	code, body := request("a", http.MethodGet, "/v2/apps", "")
+	var apps struct {
+		Items []*models.App `json:"items"`
+	}
+	if code != http.StatusOK {
+		t.Fatalf("expected status code 200 listing apps but was %d: %s", code, body)
+	}
+	if err := json.NewDecoder(body).Decode(&apps); err != nil {
+		t.Fatal(err)
+	}
+	if len(apps.Items) != 1 || apps.Items[0].ID != appA.ID {
+		t.Fatalf("expected only the app of the tenant to be listed, got %+v", apps.Items)
+	}
+
+	// apps are created for the tenant of the request, whatever their body says
+	code, body = request("a", http.MethodPost, "/v2/apps", `{"name": "initech", "tenant_id": "b"}`)
+	if code != http.StatusOK {
+		t.Fatalf("expected status code 200 creating an app but was %d: %s", code, body)
+	}
+	var created models.App
+	if err := json.NewDecoder(body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if created.TenantID != "a" {
+		t.Fatalf("expected the app to be created for tenant a, got %q", created.TenantID)
+	}
+	if code, body := request("b", http.MethodGet, "/v2/apps/"+created.ID, ""); code != http.StatusNotFound {
+		t.Fatalf("expected the app not to be found by another tenant, got %d: %s", code, body)
+	}
+}
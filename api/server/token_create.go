@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleTokenCreate(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	tokens, err := models.GetTokenDatastore(s.datastore)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	token := &models.APIToken{}
+	err = s.bindJSON(c, token)
+	if err != nil {
+		if models.IsAPIError(err) {
+			handleErrorResponse(c, err)
+		} else {
+			handleErrorResponse(c, models.ErrInvalidJSON)
+		}
+		return
+	}
+	if token.ID != "" {
+		handleErrorResponse(c, models.ErrTokenIDProvided)
+		return
+	}
+
+	for _, appID := range token.AppIDs {
+		if appID == "" {
+			handleErrorResponse(c, models.ErrTokenInvalidAppID)
+			return
+		}
+		if _, err := s.datastore.GetAppByID(ctx, appID); err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+	}
+
+	secret, hash, err := models.NewAPITokenSecret()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	token.Hash = hash
+
+	tokenCreated, err := tokens.InsertToken(ctx, token)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	// the secret is only ever returned here, only its hash is stored
+	tokenCreated.Token = secret
+	c.JSON(http.StatusOK, tokenCreated)
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleTokenDelete(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	tokens, err := models.GetTokenDatastore(s.datastore)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	err = tokens.RemoveToken(ctx, c.Param(api.TokenID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleTokenGet(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	tokens, err := models.GetTokenDatastore(s.datastore)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	token, err := tokens.GetTokenByID(ctx, c.Param(api.TokenID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, token)
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleTokenList(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	tokens, err := models.GetTokenDatastore(s.datastore)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	filter := &models.TokenFilter{}
+	filter.Cursor, filter.PerPage = pageParams(c)
+
+	list, err := tokens.GetTokens(ctx, filter)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	writeListResponse(c, list.NextCursor, len(list.Items), func(i int) interface{} { return list.Items[i] })
+}
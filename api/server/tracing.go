@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+	"go.opencensus.io/trace"
+)
+
+// defaultTraceServiceName is the service name of the spans exported by fn
+// unless set with WithTraceServiceName
+const defaultTraceServiceName = "fnserver"
+
+// WithTraceServiceName sets the service name of the spans exported to jaeger
+// and zipkin, e.g. to tell fn clusters apart in a shared tracing backend. It
+// must come before WithJaeger and WithZipkin.
+func WithTraceServiceName(name string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.traceServiceName = name
+		return nil
+	}
+}
+
+// WithTraceGlobalTags attaches static tags, such as the cluster, region or
+// node type, to every span exported to jaeger and zipkin. It must come before
+// WithJaeger and WithZipkin.
+func WithTraceGlobalTags(tags map[string]string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.traceTags = tags
+		return nil
+	}
+}
+
+// WithTraceSampleRate samples about rate, from 0 to 1, of the traces exported
+// to jaeger and zipkin, instead of all of them.
+func WithTraceSampleRate(rate float64) Option {
+	return func(ctx context.Context, s *Server) error {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("invalid trace sample rate %v, must be between 0 and 1", rate)
+		}
+		s.traceMu.Lock()
+		defer s.traceMu.Unlock()
+		s.traceSampleRate = rate
+		s.traceSampler = trace.ProbabilitySampler(rate)
+		if s.traceExporting {
+			s.applyTraceSampler()
+		}
+		return nil
+	}
+}
+
+// applyTraceSampler makes the sampler of the server the default one, once
+// spans are exported
+func (s *Server) applyTraceSampler() {
+	s.traceExporting = true
+	sampler := s.traceSampler
+	if sampler == nil {
+		sampler = trace.AlwaysSample()
+	}
+	trace.ApplyConfig(trace.Config{DefaultSampler: sampler})
+}
+
+// traceRate is the sample rate of the exported traces
+func (s *Server) traceRate() float64 {
+	s.traceMu.Lock()
+	defer s.traceMu.Unlock()
+	if s.traceSampler == nil {
+		return 1
+	}
+	return s.traceSampleRate
+}
+
+// traceConfig is the body of the /debug/trace admin endpoint
+type traceConfig struct {
+	SampleRate *float64 `json:"sample_rate,omitempty"`
+	Flush      bool     `json:"flush,omitempty"`
+}
+
+// handleTraceConfig changes the trace sample rate of this server, e.g. to
+// sample more while investigating an issue on one node, and flushes the
+// buffered spans of the exporters supporting it, e.g. before a node is shut
+// down. Only the jaeger exporter can be flushed, zipkin sends its backlog
+// every second. It returns the sample rate in use. It is served with the admin
+// token only, see WithAdminToken.
+func (s *Server) handleTraceConfig(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var conf traceConfig
+	if err := c.BindJSON(&conf); err != nil {
+		handleErrorResponse(c, models.ErrInvalidJSON)
+		return
+	}
+
+	if conf.SampleRate != nil {
+		if err := WithTraceSampleRate(*conf.SampleRate)(ctx, s); err != nil {
+			handleErrorResponse(c, models.NewAPIError(http.StatusBadRequest, err))
+			return
+		}
+		common.Logger(ctx).WithField("sample_rate", *conf.SampleRate).Info("Changed trace sample rate")
+	}
+
+	if conf.Flush {
+		for _, flush := range s.traceFlushers {
+			flush()
+		}
+	}
+
+	rate := s.traceRate()
+	c.JSON(http.StatusOK, traceConfig{SampleRate: &rate})
+}
+
+func (s *Server) traceService() string {
+	if s.traceServiceName == "" {
+		return defaultTraceServiceName
+	}
+	return s.traceServiceName
+}
+
+// parseTraceTags parses a comma separated list of key=val tags
+func parseTraceTags(list string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, kv := range splitList(list) {
+		i := strings.Index(kv, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid trace tag %q, must be key=val", kv)
+		}
+		tags[strings.TrimSpace(kv[:i])] = strings.TrimSpace(kv[i+1:])
+	}
+	return tags, nil
+}
+
+// taggingSpanExporter adds the global trace tags to the attributes of the
+// spans exported by exporters with no notion of process tags.
+type taggingSpanExporter struct {
+	trace.Exporter
+	tags map[string]string
+}
+
+func (e *taggingSpanExporter) ExportSpan(sd *trace.SpanData) {
+	tagged := *sd
+	tagged.Attributes = make(map[string]interface{}, len(sd.Attributes)+len(e.tags))
+	for k, v := range e.tags {
+		tagged.Attributes[k] = v
+	}
+	// tags of the span itself win
+	for k, v := range sd.Attributes {
+		tagged.Attributes[k] = v
+	}
+	e.Exporter.ExportSpan(&tagged)
+}
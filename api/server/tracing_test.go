@@ -0,0 +1,115 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"go.opencensus.io/trace"
+)
+
+type recordingSpanExporter struct {
+	spans []*trace.SpanData
+}
+
+func (e *recordingSpanExporter) ExportSpan(sd *trace.SpanData) { e.spans = append(e.spans, sd) }
+
+func TestParseTraceTags(t *testing.T) {
+	for i, test := range []struct {
+		list     string
+		expected map[string]string
+		valid    bool
+	}{
+		{"", map[string]string{}, true},
+		{"cluster=prod, region = us-1", map[string]string{"cluster": "prod", "region": "us-1"}, true},
+		{"node=a=b", map[string]string{"node": "a=b"}, true},
+		{"cluster", nil, false},
+		{"=prod", nil, false},
+	} {
+		tags, err := parseTraceTags(test.list)
+		if (err == nil) != test.valid {
+			t.Errorf("Test %d: expected valid %v, got error %v", i, test.valid, err)
+			continue
+		}
+		if test.valid && !reflect.DeepEqual(tags, test.expected) {
+			t.Errorf("Test %d: expected %v, got %v", i, test.expected, tags)
+		}
+	}
+}
+
+func TestTaggingSpanExporter(t *testing.T) {
+	rec := &recordingSpanExporter{}
+	e := &taggingSpanExporter{Exporter: rec, tags: map[string]string{"cluster": "prod", "fn_id": "global"}}
+
+	sd := &trace.SpanData{Name: "span", Attributes: map[string]interface{}{"fn_id": "fn1"}}
+	e.ExportSpan(sd)
+
+	expected := map[string]interface{}{"cluster": "prod", "fn_id": "fn1"}
+	if len(rec.spans) != 1 || !reflect.DeepEqual(rec.spans[0].Attributes, expected) {
+		t.Fatalf("expected a span with attributes %v, got %v", expected, rec.spans)
+	}
+	if len(sd.Attributes) != 1 {
+		t.Errorf("expected the exported span to be left unchanged, got %v", sd.Attributes)
+	}
+}
+
+func TestTraceConfig(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	// not served without an admin token
+	srv := testServer(datastore.NewMock(), nil, ServerTypeAPI)
+	if _, rec := routerRequest(t, srv.AdminRouter, http.MethodPost, "/debug/trace", bytes.NewBufferString(`{}`)); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status code 404 but was %d: %s", rec.Code, rec.Body.String())
+	}
+
+	srv = testServer(datastore.NewMock(), nil, ServerTypeAPI, WithAdminToken("s3cret"))
+	if _, rec := routerRequest(t, srv.AdminRouter, http.MethodPost, "/debug/trace", bytes.NewBufferString(`{"sample_rate": 0}`)); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status code 401 but was %d: %s", rec.Code, rec.Body.String())
+	}
+	flushes := 0
+	srv.traceFlushers = append(srv.traceFlushers, func() { flushes++ })
+
+	for i, test := range []struct {
+		body            string
+		expectedCode    int
+		expectedRate    float64
+		expectedFlushes int
+	}{
+		{`{}`, http.StatusOK, 1, 0},
+		{`{"sample_rate": 0.1}`, http.StatusOK, 0.1, 0},
+		{`{"flush": true}`, http.StatusOK, 0.1, 1},
+		{`{"sample_rate": 0, "flush": true}`, http.StatusOK, 0, 2},
+		{`{"sample_rate": 2}`, http.StatusBadRequest, 0, 2},
+		{`not json`, http.StatusBadRequest, 0, 2},
+	} {
+		req := createRequest(t, http.MethodPost, "/debug/trace", bytes.NewBufferString(test.body))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		_, rec := routerRequest2(t, srv.AdminRouter, req)
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d but was %d", i, test.expectedCode, rec.Code)
+		}
+		if test.expectedCode == http.StatusOK {
+			var conf traceConfig
+			if err := json.NewDecoder(rec.Body).Decode(&conf); err != nil {
+				t.Fatal(err)
+			}
+			if conf.SampleRate == nil || *conf.SampleRate != test.expectedRate {
+				t.Errorf("Test %d: expected sample rate %v, got %v", i, test.expectedRate, conf.SampleRate)
+			}
+		}
+		if rate := srv.traceRate(); rate != test.expectedRate {
+			t.Errorf("Test %d: expected active sample rate %v, got %v", i, test.expectedRate, rate)
+		}
+		if flushes != test.expectedFlushes {
+			t.Errorf("Test %d: expected %d flushes, got %d", i, test.expectedFlushes, flushes)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/fnproject/fn/api/models"
 	"github.com/gin-gonic/gin"
+	"net"
 	"strings"
 )
 
@@ -13,7 +14,9 @@ type TriggerAnnotator interface {
 	AnnotateTrigger(ctx *gin.Context, a *models.App, t *models.Trigger) (*models.Trigger, error)
 }
 
-type requestBasedTriggerAnnotator struct{}
+type requestBasedTriggerAnnotator struct {
+	trustedProxies []*net.IPNet
+}
 
 func annotateTriggerWithBaseURL(baseURL string, app *models.App, t *models.Trigger) (*models.Trigger, error) {
 	if t.Type != models.TriggerTypeHTTP {
@@ -34,19 +37,14 @@ func annotateTriggerWithBaseURL(baseURL string, app *models.App, t *models.Trigg
 }
 
 func (tp *requestBasedTriggerAnnotator) AnnotateTrigger(ctx *gin.Context, app *models.App, t *models.Trigger) (*models.Trigger, error) {
-
-	//No, I don't feel good about myself either
-	scheme := "http"
-	if ctx.Request.TLS != nil {
-		scheme = "https"
-	}
-
-	return annotateTriggerWithBaseURL(fmt.Sprintf("%s://%s", scheme, ctx.Request.Host), app, t)
+	return annotateTriggerWithBaseURL(requestBaseURL(ctx.Request, tp.trustedProxies)+ctx.GetString(basePathKey), app, t)
 }
 
 //NewRequestBasedTriggerAnnotator creates a TriggerAnnotator that inspects the incoming request host and port, and uses this to generate http trigger endpoint URLs based on those
-func NewRequestBasedTriggerAnnotator() TriggerAnnotator {
-	return &requestBasedTriggerAnnotator{}
+//
+//X-Forwarded-Proto, X-Forwarded-Host and X-Forwarded-Port headers are used instead when the request comes from one of trustedProxies
+func NewRequestBasedTriggerAnnotator(trustedProxies ...*net.IPNet) TriggerAnnotator {
+	return &requestBasedTriggerAnnotator{trustedProxies: trustedProxies}
 }
 
 type staticURLTriggerAnnotator struct {
@@ -158,3 +158,82 @@ func TestStaticUrlTriggerAnnotator(t *testing.T) {
 	}
 
 }
+
+func TestForwardedTriggerAnnotator(t *testing.T) {
+	app := &models.App{
+		ID:   "app_id",
+		Name: "myApp",
+	}
+
+	tr := &models.Trigger{
+		Name:   "myTrigger",
+		Type:   "http",
+		AppID:  app.ID,
+		Source: "/url/to/somewhere",
+	}
+
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		// not proxied
+		{"10.1.2.3:1234", nil, "http://internal:8080"},
+		// proxied by a trusted proxy
+		{"10.1.2.3:1234", map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "my.domain"}, "https://my.domain"},
+		{"192.168.1.1:1234", map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "my.domain", "X-Forwarded-Port": "8443"}, "https://my.domain:8443"},
+		{"10.1.2.3:1234", map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Port": "443"}, "https://internal"},
+		{"10.1.2.3:1234", map[string]string{"X-Forwarded-Host": "evil.domain, my.domain:9000"}, "http://my.domain:9000"},
+		{"10.1.2.3:1234", map[string]string{"X-Forwarded-Proto": "javascript"}, "http://internal:8080"},
+		// headers from untrusted peers are ignored
+		{"192.168.1.2:1234", map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.domain", "X-Forwarded-Port": "1"}, "http://internal:8080"},
+	} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/v2/triggers", nil)
+		c.Request.Host = "internal:8080"
+		c.Request.RemoteAddr = test.remoteAddr
+		for k, v := range test.headers {
+			c.Request.Header.Set(k, v)
+		}
+
+		newT, err := NewRequestBasedTriggerAnnotator(trusted...).AnnotateTrigger(c, app, tr)
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+
+		var annot string
+		bytes, _ := newT.Annotations.Get(models.TriggerHTTPEndpointAnnotation)
+		if err := json.Unmarshal(bytes, &annot); err != nil {
+			t.Fatalf("Test %d: couldn't get annotation", i)
+		}
+
+		expected := test.expected + "/t/myApp/url/to/somewhere"
+		if annot != expected {
+			t.Errorf("Test %d: expected annotation to be %s but was %s", i, expected, annot)
+		}
+	}
+
+	// without trusted proxies the headers are never used
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/v2/triggers", nil)
+	c.Request.Host = "internal:8080"
+	c.Request.RemoteAddr = "10.1.2.3:1234"
+	c.Request.Header.Set("X-Forwarded-Host", "my.domain")
+	newT, err := NewRequestBasedTriggerAnnotator().AnnotateTrigger(c, app, tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bytes, _ := newT.Annotations.Get(models.TriggerHTTPEndpointAnnotation)
+	if expected := `"http://internal:8080/t/myApp/url/to/somewhere"`; string(bytes) != expected {
+		t.Errorf("expected annotation to be %s but was %s", expected, bytes)
+	}
+
+	if _, err := ParseTrustedProxies([]string{"not-an-ip"}); err == nil {
+		t.Error("expected an invalid trusted proxy to fail to parse")
+	}
+}
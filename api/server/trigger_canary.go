@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+// fnIDHeader reports the fn a call through a trigger went to, see
+// WithDebugHeaders
+const fnIDHeader = "Fn-Fn-Id"
+
+// WithDebugHeaders adds headers to the responses of calls through triggers
+// reporting how they were routed: Fn-Fn-Id is the ID of the fn that served
+// the call, which is another fn than that of the trigger for the calls split
+// off by models.TriggerCanaryAnnotation. It is off by default, as it exposes
+// the fns behind triggers.
+func WithDebugHeaders() Option {
+	return func(ctx context.Context, s *Server) error {
+		s.debugHeaders = true
+		return nil
+	}
+}
+
+// triggerFn returns the fn a call through trigger goes to: the fn of the
+// trigger, or one of the fns its canary annotation splits calls off to
+func (s *Server) triggerFn(ctx context.Context, appID string, trigger *models.Trigger) (*models.Fn, error) {
+	// the annotation was validated when the trigger was stored
+	weights, _ := trigger.Canary()
+	fnID := pickCanaryFn(trigger.FnID, weights, rand.Intn)
+	if fnID == trigger.FnID {
+		return s.lbReadAccess.GetFnByID(ctx, fnID)
+	}
+
+	fn, err := s.lbReadAccess.GetFnByID(ctx, fnID)
+	if err == nil && fn.AppID == appID {
+		return fn, nil
+	}
+	common.Logger(ctx).WithError(err).WithFields(logrus.Fields{"trigger_id": trigger.ID, "fn_id": fnID}).
+		Warn("canary fn of trigger not found in its app, calling the fn of the trigger")
+	return s.lbReadAccess.GetFnByID(ctx, trigger.FnID)
+}
+
+// pickCanaryFn picks the fn of weights, in percentages of the calls, a call
+// goes to, with intn returning a random int from 0 to n-1, or defaultFnID for
+// the calls left over by the weights
+func pickCanaryFn(defaultFnID string, weights map[string]int, intn func(n int) int) string {
+	if len(weights) == 0 {
+		return defaultFnID
+	}
+	// the same number picks the same fn, whatever the order of the map
+	fnIDs := make([]string, 0, len(weights))
+	for fnID := range weights {
+		fnIDs = append(fnIDs, fnID)
+	}
+	sort.Strings(fnIDs)
+
+	n, cumulative := intn(100), 0
+	for _, fnID := range fnIDs {
+		cumulative += weights[fnID]
+		if n < cumulative {
+			return fnID
+		}
+	}
+	return defaultFnID
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPickCanaryFn(t *testing.T) {
+	weights := map[string]int{"fn_b": 10, "fn_a": 20}
+	for n, expected := range map[int]string{0: "fn_a", 19: "fn_a", 20: "fn_b", 29: "fn_b", 30: "trigger_fn", 99: "trigger_fn"} {
+		if fnID := pickCanaryFn("trigger_fn", weights, func(int) int { return n }); fnID != expected {
+			t.Errorf("expected %d to pick %s, got %s", n, expected, fnID)
+		}
+	}
+	if fnID := pickCanaryFn("trigger_fn", nil, func(int) int { return 0 }); fnID != "trigger_fn" {
+		t.Errorf("expected calls to go to the fn of the trigger without canary, got %s", fnID)
+	}
+}
+
+func TestTriggerCanaryFn(t *testing.T) {
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	ds := datastore.NewMockInit(
+		[]*models.App{app, {ID: "app_id2", Name: "myapp2"}},
+		[]*models.Fn{
+			{ID: "fn_id", AppID: app.ID, Name: "myfn", Image: "fnproject/fn-test-utils"},
+			{ID: "fn_id2", AppID: app.ID, Name: "myfn2", Image: "fnproject/fn-test-utils"},
+			{ID: "fn_id3", AppID: "app_id2", Name: "myfn", Image: "fnproject/fn-test-utils"},
+		},
+	)
+	ag := &cancelerAgent{}
+	ag.On("AddCallListener", mock.Anything)
+	srv := testServer(ds, ag, ServerTypeFull)
+
+	for i, test := range []struct {
+		canary   map[string]int
+		expected string
+	}{
+		{nil, "fn_id"},
+		{map[string]int{"fn_id2": 100}, "fn_id2"},
+		{map[string]int{"fn_id2": 0}, "fn_id"},
+		// fns gone or of other apps are never called
+		{map[string]int{"fn_missing": 100}, "fn_id"},
+		{map[string]int{"fn_id3": 100}, "fn_id"},
+	} {
+		trigger := &models.Trigger{ID: "trigger_id", AppID: app.ID, FnID: "fn_id"}
+		if test.canary != nil {
+			trigger.Annotations, _ = trigger.Annotations.With(models.TriggerCanaryAnnotation, test.canary)
+		}
+		fn, err := srv.triggerFn(context.Background(), app.ID, trigger)
+		if err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		if fn.ID != test.expected {
+			t.Errorf("Test %d: expected the call to go to %s, got %s", i, test.expected, fn.ID)
+		}
+	}
+}
@@ -14,7 +14,7 @@ func (s *Server) handleTriggerCreate(c *gin.Context) {
 	trigger := &models.Trigger{}
 	log := common.Logger(ctx)
 
-	err := c.BindJSON(trigger)
+	err := s.bindJSON(c, trigger)
 	if err != nil {
 		if models.IsAPIError(err) {
 			handleErrorResponse(c, err)
@@ -24,7 +24,28 @@ func (s *Server) handleTriggerCreate(c *gin.Context) {
 		return
 	}
 
+	if err := s.checkAnnotations(c, trigger.Annotations); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	if isDryRun(c) {
+		triggerValid, err := s.dryRunInsertTrigger(ctx, trigger)
+		if err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, triggerValid)
+		return
+	}
+
+	release, err := s.reserveTrigger(ctx, trigger.AppID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
 	triggerCreated, err := s.datastore.InsertTrigger(ctx, trigger)
+	release()
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
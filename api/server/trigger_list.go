@@ -1,8 +1,6 @@
 package server
 
 import (
-	"net/http"
-
 	"fmt"
 
 	"github.com/fnproject/fn/api/models"
@@ -19,6 +17,7 @@ func (s *Server) handleTriggerList(c *gin.Context) {
 
 	if filter.AppID == "" {
 		handleErrorResponse(c, models.ErrTriggerMissingAppID)
+		return
 	}
 
 	filter.FnID = c.Query("fn_id")
@@ -54,5 +53,5 @@ func (s *Server) handleTriggerList(c *gin.Context) {
 		triggers.Items[idx] = newT
 	}
 
-	c.JSON(http.StatusOK, triggers)
+	writeListResponse(c, triggers.NextCursor, len(triggers.Items), func(i int) interface{} { return triggers.Items[i] })
 }
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// verifyTriggerSignature rejects a call through a trigger of app, or to the
+// invoke endpoint of one of its fns, with a 403 if the app has a signing key,
+// unless the URL of the call is signed with it and has not expired, see
+// models.SignTriggerURL. The signature parameters are then dropped from the
+// URL, so that they don't reach the function.
+func verifyTriggerSignature(req *http.Request, app *models.App, now time.Time) error {
+	// the annotation was validated when the app was stored
+	key, _ := models.AppSigningKey(app)
+	if key == "" {
+		return nil
+	}
+
+	q := req.URL.Query()
+	expires, err := strconv.ParseInt(q.Get(models.SignatureExpiresParam), 10, 64)
+	if err != nil {
+		return models.ErrTriggerSignatureInvalid
+	}
+	sig, err := hex.DecodeString(q.Get(models.SignatureParam))
+	if err != nil || len(sig) == 0 {
+		return models.ErrTriggerSignatureInvalid
+	}
+	expected, _ := hex.DecodeString(models.TriggerURLSignature(key, req.URL.Path, expires))
+	if !hmac.Equal(sig, expected) {
+		return models.ErrTriggerSignatureInvalid
+	}
+	// checked once the signature is, so as not to tell whether forged URLs expired
+	if now.Unix() > expires {
+		return models.ErrTriggerSignatureExpired
+	}
+
+	req.URL.RawQuery = withoutQueryParams(req.URL.RawQuery, models.SignatureExpiresParam, models.SignatureParam)
+	return nil
+}
+
+// withoutQueryParams drops the parameters named from a raw query, keeping the
+// others as they are, in order
+func withoutQueryParams(rawQuery string, names ...string) string {
+	parts := strings.Split(rawQuery, "&")
+	kept := parts[:0]
+	for _, p := range parts {
+		name := p
+		if i := strings.IndexByte(p, '='); i >= 0 {
+			name = p[:i]
+		}
+		if name, err := url.QueryUnescape(name); err == nil {
+			drop := false
+			for _, n := range names {
+				drop = drop || name == n
+			}
+			if drop {
+				continue
+			}
+		}
+		kept = append(kept, p)
+	}
+	return strings.Join(kept, "&")
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+const testSigningKey = "0123456789abcdef0123456789abcdef"
+
+func TestTriggerSignature(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	signedApp := &models.App{ID: "signed_id", Name: "signed"}
+	signedApp.Annotations, _ = signedApp.Annotations.With(models.AppSigningKeyAnnotation, testSigningKey)
+	openApp := &models.App{ID: "open_id", Name: "open"}
+	fns := []*models.Fn{
+		{ID: "signed_fn", Name: "myfn", AppID: signedApp.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}},
+		{ID: "open_fn", Name: "myfn", AppID: openApp.ID, Image: "fnproject/fn-test-utils", ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30}},
+	}
+	triggers := []*models.Trigger{
+		{ID: "signed_trigger", Name: "hello", AppID: signedApp.ID, FnID: "signed_fn", Type: models.TriggerTypeHTTP, Source: "/hello"},
+		{ID: "other_trigger", Name: "other", AppID: signedApp.ID, FnID: "signed_fn", Type: models.TriggerTypeHTTP, Source: "/other"},
+		{ID: "open_trigger", Name: "hello", AppID: openApp.ID, FnID: "open_fn", Type: models.TriggerTypeHTTP, Source: "/hello"},
+	}
+	ds := datastore.NewMockInit([]*models.App{signedApp, openApp}, fns, triggers)
+
+	rnr := new(agent.MockAgent)
+	rnr.On("AddCallListener", mock.Anything)
+	rnr.On("GetCall", mock.Anything)
+	rnr.On("Submit", mock.Anything).Return(nil)
+
+	srv := testServer(ds, rnr, ServerTypeFull)
+
+	sign := func(path string, expires time.Time) string {
+		signed, err := models.SignTriggerURL(path, testSigningKey, expires)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	valid := sign("/t/signed/hello?name=bob", time.Now().Add(time.Minute))
+	validExpires := strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)
+
+	for i, test := range []struct {
+		path         string
+		expectedCode int
+		expectedBody string
+	}{
+		{valid, http.StatusOK, ""},
+		{"/t/open/hello", http.StatusOK, ""},
+		{"/t/signed/hello", http.StatusForbidden, models.ErrorCodeTriggerSignatureInvalid},
+		{sign("/t/signed/hello", time.Now().Add(-time.Second)), http.StatusForbidden, models.ErrorCodeTriggerSignatureExpired},
+		// signed for another trigger
+		{strings.Replace(valid, "/hello", "/other", 1), http.StatusForbidden, models.ErrorCodeTriggerSignatureInvalid},
+		// expiry extended
+		{strings.Replace(valid, "expires=", "expires=1", 1), http.StatusForbidden, models.ErrorCodeTriggerSignatureInvalid},
+		{"/t/signed/hello?expires=" + validExpires + "&signature=" + strings.Repeat("0", 64), http.StatusForbidden, models.ErrorCodeTriggerSignatureInvalid},
+		{"/t/signed/hello?expires=" + validExpires + "&signature=zz", http.StatusForbidden, models.ErrorCodeTriggerSignatureInvalid},
+		{"/t/signed/hello?expires=never&signature=" + strings.Repeat("0", 64), http.StatusForbidden, models.ErrorCodeTriggerSignatureInvalid},
+		// unsigned parameters may be changed
+		{strings.Replace(valid, "name=bob", "name=alice", 1), http.StatusOK, ""},
+	} {
+		_, rec := routerRequest(t, srv.Router, http.MethodGet, test.path, nil)
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: expected status code %d for %s but was %d: %s", i, test.expectedCode, test.path, rec.Code, rec.Body.String())
+		}
+		if body := rec.Body.String(); !strings.Contains(body, test.expectedBody) {
+			t.Errorf("Test %d: expected body to contain %s, got %s", i, test.expectedBody, body)
+		}
+	}
+
+	// the invoke endpoint of the app's fns takes the same signatures
+	for i, test := range []struct {
+		path         string
+		expectedCode int
+		expectedBody string
+	}{
+		{sign("/invoke/signed_fn", time.Now().Add(time.Minute)), http.StatusOK, ""},
+		{"/invoke/open_fn", http.StatusOK, ""},
+		{"/invoke/signed_fn", http.StatusForbidden, models.ErrorCodeTriggerSignatureInvalid},
+		{sign("/invoke/signed_fn", time.Now().Add(-time.Second)), http.StatusForbidden, models.ErrorCodeTriggerSignatureExpired},
+		// signed for a trigger of the fn
+		{strings.Replace(valid, "/t/signed/hello", "/invoke/signed_fn", 1), http.StatusForbidden, models.ErrorCodeTriggerSignatureInvalid},
+	} {
+		_, rec := routerRequest(t, srv.Router, http.MethodPost, test.path, strings.NewReader("{}"))
+		if rec.Code != test.expectedCode {
+			t.Errorf("Invoke test %d: expected status code %d for %s but was %d: %s", i, test.expectedCode, test.path, rec.Code, rec.Body.String())
+		}
+		if body := rec.Body.String(); !strings.Contains(body, test.expectedBody) {
+			t.Errorf("Invoke test %d: expected body to contain %s, got %s", i, test.expectedBody, body)
+		}
+	}
+}
+
+func TestVerifyTriggerSignatureStripsParams(t *testing.T) {
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	app.Annotations, _ = app.Annotations.With(models.AppSigningKeyAnnotation, testSigningKey)
+
+	expires := time.Now().Add(time.Minute).Unix()
+	sig := models.TriggerURLSignature(testSigningKey, "/t/myapp/hello", expires)
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/t/myapp/hello?b=2&expires="+strconv.FormatInt(expires, 10)+"&a=1&signature="+sig, nil)
+
+	if err := verifyTriggerSignature(req, app, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if req.URL.RawQuery != "b=2&a=1" {
+		t.Fatalf("expected the signature to be dropped from the query, got %q", req.URL.RawQuery)
+	}
+}
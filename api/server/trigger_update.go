@@ -11,7 +11,7 @@ import (
 func (s *Server) handleTriggerUpdate(c *gin.Context) {
 	trigger := &models.Trigger{}
 
-	err := c.BindJSON(trigger)
+	err := s.bindJSON(c, trigger)
 	if err != nil {
 		if models.IsAPIError(err) {
 			handleErrorResponse(c, err)
@@ -21,6 +21,11 @@ func (s *Server) handleTriggerUpdate(c *gin.Context) {
 		return
 	}
 
+	if err := s.checkAnnotations(c, trigger.Annotations); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
 	pathTriggerID := c.Param(api.TriggerID)
 
 	if trigger.ID == "" {
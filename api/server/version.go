@@ -5,8 +5,34 @@ import (
 
 	"github.com/fnproject/fn/api/version"
 	"github.com/gin-gonic/gin"
+	promclient "github.com/prometheus/client_golang/prometheus"
 )
 
 func handleVersion(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"version": version.Version})
 }
+
+// handleBuildInfo returns the version, build metadata and API versions of
+// the server on the main router, for clients to detect its capabilities
+func handleBuildInfo(c *gin.Context) {
+	c.JSON(http.StatusOK, version.BuildInfo())
+}
+
+var buildInfoDesc = promclient.NewDesc("fn_build_info",
+	"A metric with a constant '1' value labeled by the version and node type of the server",
+	[]string{"version", "node_type"}, nil)
+
+// buildInfoCollector exports fn_build_info, to chart the versions running
+// across a cluster. The node type is read on collection, as it may be set by
+// an option given after WithPrometheus.
+type buildInfoCollector struct {
+	s *Server
+}
+
+func (b buildInfoCollector) Describe(ch chan<- *promclient.Desc) {
+	ch <- buildInfoDesc
+}
+
+func (b buildInfoCollector) Collect(ch chan<- promclient.Metric) {
+	ch <- promclient.MustNewConstMetric(buildInfoDesc, promclient.GaugeValue, 1, version.Version, b.s.nodeType.String())
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/version"
+)
+
+func TestBuildInfoMetric(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	srv := testServer(datastore.NewMock(), nil, ServerTypeAPI, WithPrometheus())
+
+	_, rec := routerRequest(t, srv.AdminRouter, http.MethodGet, "/metrics", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status code 200 but was %d", rec.Code)
+	}
+
+	expected := fmt.Sprintf(`fn_build_info{node_type="api",version="%s"} 1`, version.Version)
+	if !strings.Contains(rec.Body.String(), expected) {
+		t.Errorf("expected metrics to contain %s, got %s", expected, rec.Body.String())
+	}
+}
+
+func TestBuildInfo(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	srv := testServer(datastore.NewMock(), nil, ServerTypeAPI)
+
+	_, rec := routerRequest(t, srv.Router, http.MethodGet, "/v2/version", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status code 200 but was %d", rec.Code)
+	}
+
+	var info version.Info
+	if err := json.NewDecoder(rec.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	expected := version.Info{Version: version.Version, APIVersions: []string{"v2"}, GoVersion: runtime.Version()}
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("expected build info %+v, got %+v", expected, info)
+	}
+
+	// the admin endpoint is kept as is
+	_, rec = routerRequest(t, srv.AdminRouter, http.MethodGet, "/version", nil)
+	if body := strings.TrimSpace(rec.Body.String()); body != fmt.Sprintf(`{"version":"%s"}`, version.Version) {
+		t.Errorf("expected admin version endpoint to be unchanged, got %s", body)
+	}
+}
+
+func TestMetricNamespace(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	for nodeType, expected := range map[NodeType]string{
+		ServerTypeFull:       "fn",
+		ServerTypeLB:         "fn_lb",
+		ServerTypePureRunner: "fn_pure_runner",
+	} {
+		if ns := defaultMetricNamespace(nodeType); ns != expected {
+			t.Errorf("expected the namespace of %s nodes to be %s, got %s", nodeType, expected, ns)
+		}
+	}
+
+	srv := testServer(datastore.NewMock(), nil, ServerTypeAPI, WithMetricNamespace("fn-api"), WithPrometheus())
+
+	_, rec := routerRequest(t, srv.AdminRouter, http.MethodGet, "/metrics", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status code 200 but was %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "fn_api_process_start_time_seconds") || strings.Contains(body, "\nfn_process_start_time_seconds") {
+		t.Errorf("expected the process metrics to be in the fn_api namespace, got %s", body)
+	}
+	if !strings.Contains(body, "fn_build_info{") {
+		t.Errorf("expected fn_build_info to keep its name, got %s", body)
+	}
+}
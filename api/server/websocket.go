@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// webSocketKey marks the calls upgrading to a websocket in their gin context
+const webSocketKey = "fn_websocket"
+
+// WithWebSocket lets calls to functions, from /invoke and /t, upgrade to
+// WebSocket connections. An upgrade request is passed to the function as a
+// GET with the Connection and Upgrade headers, and if it answers with a 101
+// Switching Protocols, the connection of the client is piped to and from the
+// hot container running it, until either side closes it or the timeout of
+// the function expires. Frames are passed through as is, without buffering,
+// so the response transform, idempotency keys and response size limits don't
+// apply to them. Only full nodes run calls, LB nodes refuse upgrades as their
+// runners can't proxy connections.
+func WithWebSocket() Option {
+	return func(ctx context.Context, s *Server) error {
+		s.enableWebSocket = true
+		return nil
+	}
+}
+
+// checkWebSocket marks the call of c as upgrading to a websocket, if it asks
+// to and websockets are enabled
+func (s *Server) checkWebSocket(c *gin.Context) error {
+	if !s.enableWebSocket || !isWebSocketUpgrade(c.Request) {
+		return nil
+	}
+	if s.nodeType != ServerTypeFull {
+		return models.ErrWebSocketUnsupported
+	}
+	c.Set(webSocketKey, true)
+	return nil
+}
+
+func isWebSocketUpgrade(req *http.Request) bool {
+	if !strings.EqualFold(req.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, v := range req.Header["Connection"] {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCheckWebSocket(t *testing.T) {
+	for i, test := range []struct {
+		enabled    bool
+		nodeType   NodeType
+		upgrade    string
+		connection string
+		expected   bool
+		err        error
+	}{
+		{true, ServerTypeFull, "websocket", "Upgrade", true, nil},
+		{true, ServerTypeFull, "WebSocket", "keep-alive, upgrade", true, nil},
+		{true, ServerTypeFull, "websocket", "keep-alive", false, nil},
+		{true, ServerTypeFull, "h2c", "Upgrade", false, nil},
+		{true, ServerTypeFull, "", "", false, nil},
+		{false, ServerTypeFull, "websocket", "Upgrade", false, nil},
+		{true, ServerTypeLB, "websocket", "Upgrade", false, models.ErrWebSocketUnsupported},
+		{true, ServerTypeLB, "", "", false, nil},
+	} {
+		s := &Server{enableWebSocket: test.enabled, nodeType: test.nodeType}
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/invoke/fn_id", nil)
+		c.Request.Header.Set("Upgrade", test.upgrade)
+		c.Request.Header.Set("Connection", test.connection)
+
+		if err := s.checkWebSocket(c); err != test.err {
+			t.Errorf("Test %d: expected error %v, got %v", i, test.err, err)
+		}
+		if got := c.GetBool(webSocketKey); got != test.expected {
+			t.Errorf("Test %d: expected upgrade %v, got %v", i, test.expected, got)
+		}
+	}
+}
@@ -1,4 +1,38 @@
 package version
 
+import "runtime"
+
 // Version of Functions
 var Version = "0.3.749"
+
+// GitCommit and BuildDate are set when building, with
+// -ldflags "-X github.com/fnproject/fn/api/version.GitCommit=..."
+var (
+	// GitCommit is the commit fn was built from
+	GitCommit = ""
+	// BuildDate is when fn was built, in RFC 3339
+	BuildDate = ""
+)
+
+// APIVersions are the versions of the API served
+var APIVersions = []string{"v2"}
+
+// Info is the build metadata of fn
+type Info struct {
+	Version     string   `json:"version"`
+	APIVersions []string `json:"api_versions"`
+	GitCommit   string   `json:"git_commit,omitempty"`
+	BuildDate   string   `json:"build_date,omitempty"`
+	GoVersion   string   `json:"go_version"`
+}
+
+// BuildInfo returns the build metadata of fn
+func BuildInfo() Info {
+	return Info{
+		Version:     Version,
+		APIVersions: APIVersions,
+		GitCommit:   GitCommit,
+		BuildDate:   BuildDate,
+		GoVersion:   runtime.Version(),
+	}
+}
@@ -5,6 +5,9 @@ import (
 
 	"github.com/fnproject/fn/api/agent"
 	"github.com/fnproject/fn/api/agent/drivers/docker"
+	"github.com/fnproject/fn/api/agent/hybrid"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/runnerpool"
 	"github.com/fnproject/fn/api/server"
 
 	// The trace package is imported in several places by different dependencies and if we don't import explicity here it is
@@ -42,6 +45,7 @@ func registerViews() {
 
 	agent.RegisterRunnerViews(keys, latencyDist)
 	agent.RegisterAgentViews(keys, latencyDist)
+	agent.RegisterDataAccessViews(keys)
 	agent.RegisterDockerViews(keys, latencyDist, ioDist, ioDist, memoryDist, cpuDist)
 
 	// container views have additional metrics, optional to turn on
@@ -52,5 +56,19 @@ func registerViews() {
 	// Register docker client views
 	docker.RegisterViews(keys, latencyDist)
 
+	// Register hybrid client views
+	hybrid.RegisterViews(keys)
+
+	// Register runner circuit breaker views
+	runnerpool.RegisterCircuitBreakerViews(keys)
+
 	server.RegisterAPIViews(keys, latencyDist)
+	server.RegisterConnectionViews(keys)
+	server.RegisterBackpressureViews(keys)
+	server.RegisterClientCancelViews(keys)
+	server.RegisterInvokeRateLimitViews(keys)
+	server.RegisterResponseLimitViews(keys)
+	server.RegisterResponseCacheViews(keys)
+	server.RegisterFunctionViews(keys)
+	datastore.RegisterPoolViews(keys)
 }
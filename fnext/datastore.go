@@ -200,3 +200,50 @@ func (e *extds) RemoveFn(ctx context.Context, fnID string) error {
 	}
 	return nil
 }
+
+// MigrationStatus passes the schema version of the wrapped Datastore through
+func (e *extds) MigrationStatus(ctx context.Context) (*models.MigrationStatus, error) {
+	return models.GetMigrationStatus(ctx, e.Datastore)
+}
+
+// The API token methods pass the tokens of the wrapped Datastore through
+
+func (e *extds) InsertToken(ctx context.Context, token *models.APIToken) (*models.APIToken, error) {
+	tokens, err := models.GetTokenDatastore(e.Datastore)
+	if err != nil {
+		return nil, err
+	}
+	return tokens.InsertToken(ctx, token)
+}
+
+func (e *extds) GetTokenByID(ctx context.Context, tokenID string) (*models.APIToken, error) {
+	tokens, err := models.GetTokenDatastore(e.Datastore)
+	if err != nil {
+		return nil, err
+	}
+	return tokens.GetTokenByID(ctx, tokenID)
+}
+
+func (e *extds) GetTokenByHash(ctx context.Context, hash string) (*models.APIToken, error) {
+	tokens, err := models.GetTokenDatastore(e.Datastore)
+	if err != nil {
+		return nil, err
+	}
+	return tokens.GetTokenByHash(ctx, hash)
+}
+
+func (e *extds) GetTokens(ctx context.Context, filter *models.TokenFilter) (*models.TokenList, error) {
+	tokens, err := models.GetTokenDatastore(e.Datastore)
+	if err != nil {
+		return nil, err
+	}
+	return tokens.GetTokens(ctx, filter)
+}
+
+func (e *extds) RemoveToken(ctx context.Context, tokenID string) error {
+	tokens, err := models.GetTokenDatastore(e.Datastore)
+	if err != nil {
+		return err
+	}
+	return tokens.RemoveToken(ctx, tokenID)
+}
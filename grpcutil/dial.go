@@ -15,18 +15,24 @@ import (
 
 // DialWithBackoff creates a grpc connection using backoff strategy for reconnections
 func DialWithBackoff(ctx context.Context, address string, creds credentials.TransportCredentials, timeout time.Duration, backoffCfg grpc.BackoffConfig, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	return DialWithResolver(ctx, address, creds, timeout, backoffCfg, nil, opts...)
+}
+
+// DialWithResolver is DialWithBackoff, resolving the host of address with
+// resolver rather than the default resolver of Go, if not nil
+func DialWithResolver(ctx context.Context, address string, creds credentials.TransportCredentials, timeout time.Duration, backoffCfg grpc.BackoffConfig, resolver *net.Resolver, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
 	opts = append(opts, grpc.WithBackoffConfig(backoffCfg))
-	return dial(ctx, address, creds, timeout, opts...)
+	return dial(ctx, address, creds, timeout, resolver, opts...)
 }
 
 // uses grpc connection backoff protocol https://github.com/grpc/grpc/blob/master/doc/connection-backoff.md
-func dial(ctx context.Context, address string, creds credentials.TransportCredentials, timeoutDialer time.Duration, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+func dial(ctx context.Context, address string, creds credentials.TransportCredentials, timeoutDialer time.Duration, resolver *net.Resolver, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
 	dialer := func(address string, timeout time.Duration) (net.Conn, error) {
 		log := common.Logger(ctx).WithField("grpc_addr", address)
 
 		ctx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
-		conn, err := (&net.Dialer{Cancel: ctx.Done(), Timeout: timeoutDialer}).Dial("tcp", address)
+		conn, err := (&net.Dialer{Cancel: ctx.Done(), Timeout: timeoutDialer, Resolver: resolver}).Dial("tcp", address)
 		if err != nil {
 			log.WithError(err).Debug("Failed to dial grpc connection")
 			return nil, err